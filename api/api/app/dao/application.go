@@ -1,12 +1,22 @@
 package dao
 
 import (
+	"context"
 	"dodevops-api/api/app/model"
+	"dodevops-api/pkg/datascope"
 	"gorm.io/gorm"
 )
 
+func init() {
+	// 应用按业务组或业务部门过滤数据权限
+	datascope.RegisterTable(model.Application{}.TableName(), datascope.ByGroupOrDept("business_group_id", "business_dept_id"))
+}
+
 // IApplicationDao 应用DAO接口
 type IApplicationDao interface {
+	// 绑定请求上下文，按当前用户的数据权限过滤应用
+	WithContext(ctx context.Context) IApplicationDao
+
 	// 应用管理
 	CreateApplication(app *model.Application) error
 	GetApplicationByID(id uint) (*model.Application, error)
@@ -35,6 +45,11 @@ func NewApplicationDao(db *gorm.DB) IApplicationDao {
 	return &ApplicationDao{db: db}
 }
 
+// WithContext 绑定请求上下文，按当前用户的数据权限过滤应用
+func (d *ApplicationDao) WithContext(ctx context.Context) IApplicationDao {
+	return &ApplicationDao{db: d.db.WithContext(ctx)}
+}
+
 // CreateApplication 创建应用
 func (d *ApplicationDao) CreateApplication(app *model.Application) error {
 	return d.db.Create(app).Error
@@ -6,10 +6,13 @@ import (
 	"fmt"
 	"dodevops-api/api/app/dao"
 	"dodevops-api/api/app/model"
+	cmdbdao "dodevops-api/api/cmdb/dao"
+	cmdbmodel "dodevops-api/api/cmdb/model"
 	ccdao "dodevops-api/api/configcenter/dao"
 	ccmodel "dodevops-api/api/configcenter/model"
 	"dodevops-api/common/result"
 	"dodevops-api/common/util"
+	"dodevops-api/pkg/datascope"
 	"net/http"
 	"net/http/cookiejar"
 	"net/url"
@@ -103,6 +106,12 @@ func (s *ApplicationService) CreateApplication(c *gin.Context, req *model.Create
 		})
 	}
 
+	// 只能在有数据权限的业务组或部门下创建应用
+	if !datascope.AllowGroup(c, req.BusinessGroupID) && !datascope.AllowDept(c, req.BusinessDeptID) {
+		result.Failed(c, 403, "没有该业务组或部门的数据权限")
+		return
+	}
+
 	// 构建应用对象
 	app := &model.Application{
 		Name:            req.Name,
@@ -163,7 +172,7 @@ func (s *ApplicationService) GetApplicationList(c *gin.Context, req *model.Appli
 		req.PageSize = 10
 	}
 
-	apps, total, err := s.appDao.GetApplicationList(req)
+	apps, total, err := s.appDao.WithContext(c).GetApplicationList(req)
 	if err != nil {
 		result.Failed(c, 500, "获取应用列表失败: "+err.Error())
 		return
@@ -179,7 +188,7 @@ func (s *ApplicationService) GetApplicationList(c *gin.Context, req *model.Appli
 
 // GetApplicationDetail 获取应用详情
 func (s *ApplicationService) GetApplicationDetail(c *gin.Context, id uint) {
-	app, err := s.appDao.GetApplicationByID(id)
+	app, err := s.appDao.WithContext(c).GetApplicationByID(id)
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			result.Failed(c, 404, "应用不存在")
@@ -195,7 +204,7 @@ func (s *ApplicationService) GetApplicationDetail(c *gin.Context, id uint) {
 // UpdateApplication 更新应用
 func (s *ApplicationService) UpdateApplication(c *gin.Context, id uint, req *model.UpdateApplicationRequest) {
 	// 检查应用是否存在
-	_, err := s.appDao.GetApplicationByID(id)
+	app, err := s.appDao.WithContext(c).GetApplicationByID(id)
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			result.Failed(c, 404, "应用不存在")
@@ -205,6 +214,21 @@ func (s *ApplicationService) UpdateApplication(c *gin.Context, id uint, req *mod
 		return
 	}
 
+	// 调整归属时目标业务组或部门也需要有数据权限
+	if req.BusinessGroupID != nil || req.BusinessDeptID != nil {
+		groupId, deptId := app.BusinessGroupID, app.BusinessDeptID
+		if req.BusinessGroupID != nil {
+			groupId = *req.BusinessGroupID
+		}
+		if req.BusinessDeptID != nil {
+			deptId = *req.BusinessDeptID
+		}
+		if !datascope.AllowGroup(c, groupId) && !datascope.AllowDept(c, deptId) {
+			result.Failed(c, 403, "没有该业务组或部门的数据权限")
+			return
+		}
+	}
+
 	// 构建更新字段
 	updates := make(map[string]interface{})
 	if req.Name != nil {
@@ -260,7 +284,7 @@ func (s *ApplicationService) UpdateApplication(c *gin.Context, id uint, req *mod
 	}
 
 	// 更新应用
-	if err := s.appDao.UpdateApplication(id, updates); err != nil {
+	if err := s.appDao.WithContext(c).UpdateApplication(id, updates); err != nil {
 		result.Failed(c, 500, "更新应用失败: "+err.Error())
 		return
 	}
@@ -274,14 +298,14 @@ func (s *ApplicationService) UpdateApplication(c *gin.Context, id uint, req *mod
 	}
 
 	// 返回更新后的应用信息
-	updatedApp, _ := s.appDao.GetApplicationByID(id)
+	updatedApp, _ := s.appDao.WithContext(c).GetApplicationByID(id)
 	result.Success(c, updatedApp)
 }
 
 // DeleteApplication 删除应用
 func (s *ApplicationService) DeleteApplication(c *gin.Context, id uint) {
 	// 检查应用是否存在
-	app, err := s.appDao.GetApplicationByID(id)
+	app, err := s.appDao.WithContext(c).GetApplicationByID(id)
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			result.Failed(c, 404, "应用不存在")
@@ -299,18 +323,22 @@ func (s *ApplicationService) DeleteApplication(c *gin.Context, id uint) {
 	}
 
 	// 删除应用
-	if err := s.appDao.DeleteApplication(id); err != nil {
+	if err := s.appDao.WithContext(c).DeleteApplication(id); err != nil {
 		result.Failed(c, 500, "删除应用失败: "+err.Error())
 		return
 	}
 
+	// 删除应用在CMDB中的配置项关系
+	relationDao := cmdbdao.NewCmdbRelationDao()
+	relationDao.DeleteRelationsByCI(cmdbmodel.CIKindApp, id)
+
 	result.Success(c, fmt.Sprintf("应用 '%s' 及其关联的Jenkins环境配置删除成功", app.Name))
 }
 
 // CreateJenkinsEnv 创建Jenkins环境配置
 func (s *ApplicationService) CreateJenkinsEnv(c *gin.Context, req *model.CreateJenkinsEnvRequest) {
 	// 检查应用是否存在
-	_, err := s.appDao.GetApplicationByID(req.AppID)
+	_, err := s.appDao.WithContext(c).GetApplicationByID(req.AppID)
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			result.Failed(c, 404, "应用不存在")
@@ -353,7 +381,7 @@ func (s *ApplicationService) CreateJenkinsEnv(c *gin.Context, req *model.CreateJ
 // UpdateAppJenkinsEnv 更新应用的Jenkins环境配置
 func (s *ApplicationService) UpdateAppJenkinsEnv(c *gin.Context, appID uint, envID uint, req *model.UpdateJenkinsEnvRequest) {
 	// 检查应用是否存在
-	_, err := s.appDao.GetApplicationByID(appID)
+	_, err := s.appDao.WithContext(c).GetApplicationByID(appID)
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			result.Failed(c, 404, "应用不存在")
@@ -420,7 +448,7 @@ func (s *ApplicationService) UpdateAppJenkinsEnv(c *gin.Context, appID uint, env
 // DeleteAppJenkinsEnv 删除应用的Jenkins环境配置
 func (s *ApplicationService) DeleteAppJenkinsEnv(c *gin.Context, appID uint, envID uint) {
 	// 检查应用是否存在
-	_, err := s.appDao.GetApplicationByID(appID)
+	_, err := s.appDao.WithContext(c).GetApplicationByID(appID)
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			result.Failed(c, 404, "应用不存在")
@@ -464,7 +492,7 @@ func (s *ApplicationService) DeleteAppJenkinsEnv(c *gin.Context, appID uint, env
 // GetAppJenkinsEnvs 获取应用的所有Jenkins环境配置
 func (s *ApplicationService) GetAppJenkinsEnvs(c *gin.Context, appID uint) {
 	// 检查应用是否存在
-	_, err := s.appDao.GetApplicationByID(appID)
+	_, err := s.appDao.WithContext(c).GetApplicationByID(appID)
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			result.Failed(c, 404, "应用不存在")
@@ -514,7 +542,7 @@ func (s *ApplicationService) GetApplicationsForDeployment(c *gin.Context, req *m
 	}
 
 	// 获取指定业务组和部门下的所有已激活应用
-	apps, _, err := s.appDao.GetApplicationList(&model.ApplicationListRequest{
+	apps, _, err := s.appDao.WithContext(c).GetApplicationList(&model.ApplicationListRequest{
 		Page:            1,
 		PageSize:        1000, // 获取所有应用
 		BusinessGroupID: &req.BusinessGroupID,
@@ -618,7 +646,7 @@ func (s *ApplicationService) CreateQuickDeployment(c *gin.Context, req *model.Cr
 	var tasks []model.QuickDeploymentTask
 	for index, appReq := range req.Applications {
 		// 获取应用信息
-		app, err := s.appDao.GetApplicationByID(appReq.AppID)
+		app, err := s.appDao.WithContext(c).GetApplicationByID(appReq.AppID)
 		if err != nil {
 			tx.Rollback()
 			result.Failed(c, 400, fmt.Sprintf("应用ID %d 不存在", appReq.AppID))
@@ -1333,7 +1361,7 @@ func (s *ApplicationService) GetServiceTree(c *gin.Context, req *model.GetServic
 	fmt.Printf("DEBUG: Environment = %s\n", req.Environment)
 
 	// 构建查询条件
-	query := s.db.Model(&model.Application{}).
+	query := s.db.WithContext(c).Model(&model.Application{}).
 		Preload("JenkinsEnvs").
 		Select("app_application.*, COUNT(app_jenkins_env.id) as jenkins_env_count").
 		Joins("LEFT JOIN app_jenkins_env ON app_application.id = app_jenkins_env.app_id").
@@ -1464,6 +1492,7 @@ func (s *ApplicationService) executeJenkinsJob(serverID uint, jobName string) (b
 	if account.Type != 4 { // Jenkins account type
 		return false, 0, "", fmt.Errorf("账号类型不是Jenkins")
 	}
+	ccdao.MarkAccountAuthUsed(account.ID)
 
 	password, err := account.DecryptPassword()
 	if err != nil {
@@ -1872,7 +1901,7 @@ func (s *ApplicationService) GetBusinessGroupOptions(c *gin.Context) {
 // GetAppEnvironment 获取单个应用的环境配置
 func (s *ApplicationService) GetAppEnvironment(c *gin.Context, req *model.GetAppEnvironmentRequest) {
 	// 获取应用信息
-	app, err := s.appDao.GetApplicationByID(req.AppID)
+	app, err := s.appDao.WithContext(c).GetApplicationByID(req.AppID)
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			result.Failed(c, 404, "应用不存在")
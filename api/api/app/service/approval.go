@@ -0,0 +1,29 @@
+package service
+
+import (
+	"dodevops-api/api/app/model"
+	"dodevops-api/common"
+	"dodevops-api/pkg/approval"
+	"encoding/json"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ProdDeploymentCondition 审批条件：快速发布包含生产环境任务
+const ProdDeploymentCondition = "prod_deployment"
+
+func init() {
+	approval.RegisterCondition(ProdDeploymentCondition, isProdQuickDeployment)
+}
+
+// isProdQuickDeployment 判断执行的快速发布是否包含生产环境任务，请求体无法解析时按需要审批处理
+func isProdQuickDeployment(c *gin.Context, body []byte) bool {
+	var req model.ExecuteQuickDeploymentRequest
+	if err := json.Unmarshal(body, &req); err != nil {
+		return true
+	}
+	var count int64
+	common.GetDB().Model(&model.QuickDeploymentTask{}).
+		Where("deployment_id = ? AND environment = ?", req.DeploymentID, "prod").Count(&count)
+	return count > 0
+}
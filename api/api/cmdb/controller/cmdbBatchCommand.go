@@ -0,0 +1,99 @@
+package controller
+
+import (
+	"dodevops-api/api/cmdb/model"
+	"dodevops-api/api/cmdb/service"
+	"dodevops-api/common/result"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// @Summary 批量执行命令
+// @Produce json
+// @Tags CMDB资产管理
+// @Description 在主机ID、资产分组（含子分组）和标签选择器选中的主机上并发执行命令，后台执行，返回执行记录，通过实时输出接口查看每台主机的输出
+// @Param data body model.CmdbBatchCommandDto true "data"
+// @Success 200 {object} result.Result
+// @router /api/v1/cmdb/batch/execute [post]
+// @Security ApiKeyAuth
+func ExecuteCmdbBatchCommand(c *gin.Context) {
+	var dto model.CmdbBatchCommandDto
+	if err := c.BindJSON(&dto); err != nil {
+		result.Failed(c, int(result.ApiCode.FAILED), "参数错误")
+		return
+	}
+	service.GetCmdbBatchCommandService().ExecuteCommand(c, dto)
+}
+
+// @Summary 重新执行批量命令
+// @Produce json
+// @Tags CMDB资产管理
+// @Description 按原执行记录的主机、资产分组和标签选择器重新选择主机执行，failedOnly 为 true 时只在上次未成功的主机上执行
+// @Param data body model.CmdbBatchCommandRerunDto true "data"
+// @Success 200 {object} result.Result
+// @router /api/v1/cmdb/batch/rerun [post]
+// @Security ApiKeyAuth
+func RerunCmdbBatchCommand(c *gin.Context) {
+	var dto model.CmdbBatchCommandRerunDto
+	if err := c.BindJSON(&dto); err != nil {
+		result.Failed(c, int(result.ApiCode.FAILED), "参数错误")
+		return
+	}
+	service.GetCmdbBatchCommandService().RerunCommand(c, dto)
+}
+
+// @Summary 分页查询批量命令执行记录
+// @Produce json
+// @Tags CMDB资产管理
+// @Description 分页查询批量命令执行记录
+// @Param page query int false "页码"
+// @Param pageSize query int false "每页数量"
+// @Success 200 {object} result.Result
+// @router /api/v1/cmdb/batch/list [get]
+// @Security ApiKeyAuth
+func GetCmdbBatchCommandList(c *gin.Context) {
+	page, err := strconv.Atoi(c.Query("page"))
+	if err != nil || page <= 0 {
+		page = 1
+	}
+	pageSize, err := strconv.Atoi(c.Query("pageSize"))
+	if err != nil || pageSize <= 0 {
+		pageSize = 10
+	}
+	service.GetCmdbBatchCommandService().GetCommandList(c, page, pageSize)
+}
+
+// @Summary 查询批量命令执行详情
+// @Produce json
+// @Tags CMDB资产管理
+// @Description 查询批量命令执行详情，包含每台主机的状态、退出码、输出和按退出码汇总
+// @Param id query int true "执行记录ID"
+// @Success 200 {object} result.Result
+// @router /api/v1/cmdb/batch/info [get]
+// @Security ApiKeyAuth
+func GetCmdbBatchCommandInfo(c *gin.Context) {
+	id, err := strconv.Atoi(c.Query("id"))
+	if err != nil || id <= 0 {
+		result.Failed(c, int(result.ApiCode.FAILED), "参数错误")
+		return
+	}
+	service.GetCmdbBatchCommandService().GetCommandInfo(c, uint(id))
+}
+
+// @Summary 批量命令实时输出(SSE)
+// @Produce text/event-stream
+// @Tags CMDB资产管理
+// @Description 通过SSE推送每台主机的输出：output 事件为一行输出，host 事件为主机状态变化，complete 事件为全部主机结束后的汇总
+// @Param id path int true "执行记录ID"
+// @Success 200 {object} string "SSE格式的实时输出"
+// @router /api/v1/cmdb/batch/stream/{id} [get]
+// @Security ApiKeyAuth
+func StreamCmdbBatchCommand(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil || id <= 0 {
+		result.Failed(c, int(result.ApiCode.FAILED), "参数错误")
+		return
+	}
+	service.GetCmdbBatchCommandService().StreamCommand(c, uint(id))
+}
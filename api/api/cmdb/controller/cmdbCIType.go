@@ -0,0 +1,148 @@
+package controller
+
+import (
+	"dodevops-api/api/cmdb/model"
+	"dodevops-api/api/cmdb/service"
+	"dodevops-api/common/result"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// @Summary 查询CI类型列表
+// @Produce json
+// @Tags CMDB资产管理
+// @Description 查询全部CI类型及其自定义属性定义
+// @Success 200 {object} result.Result
+// @router /api/v1/cmdb/citype/list [get]
+// @Security ApiKeyAuth
+func GetCmdbCITypeList(c *gin.Context) {
+	service.GetCmdbCITypeService().GetCITypeList(c)
+}
+
+// @Summary 新增CI类型
+// @Produce json
+// @Tags CMDB资产管理
+// @Description 新增CI类型，如物理服务器、网络设备
+// @Param data body model.CmdbCITypeDto true "data"
+// @Success 200 {object} result.Result
+// @router /api/v1/cmdb/citype/add [post]
+// @Security ApiKeyAuth
+func CreateCmdbCIType(c *gin.Context) {
+	var dto model.CmdbCITypeDto
+	if err := c.BindJSON(&dto); err != nil {
+		result.Failed(c, int(result.ApiCode.FAILED), "参数错误")
+		return
+	}
+	service.GetCmdbCITypeService().CreateCIType(c, dto)
+}
+
+// @Summary 修改CI类型
+// @Produce json
+// @Tags CMDB资产管理
+// @Description 修改CI类型名称、编码和备注
+// @Param data body model.CmdbCITypeDto true "data"
+// @Success 200 {object} result.Result
+// @router /api/v1/cmdb/citype/update [put]
+// @Security ApiKeyAuth
+func UpdateCmdbCIType(c *gin.Context) {
+	var dto model.CmdbCITypeDto
+	if err := c.BindJSON(&dto); err != nil {
+		result.Failed(c, int(result.ApiCode.FAILED), "参数错误")
+		return
+	}
+	service.GetCmdbCITypeService().UpdateCIType(c, dto)
+}
+
+// @Summary 删除CI类型
+// @Produce json
+// @Tags CMDB资产管理
+// @Description 删除CI类型及其自定义属性定义，已有主机使用时不能删除
+// @Param data body model.CmdbCITypeIdDto true "data"
+// @Success 200 {object} result.Result
+// @router /api/v1/cmdb/citype/delete [delete]
+// @Security ApiKeyAuth
+func DeleteCmdbCIType(c *gin.Context) {
+	var dto model.CmdbCITypeIdDto
+	if err := c.BindJSON(&dto); err != nil {
+		result.Failed(c, int(result.ApiCode.FAILED), "参数错误")
+		return
+	}
+	service.GetCmdbCITypeService().DeleteCIType(c, dto.Id)
+}
+
+// @Summary 新增自定义属性
+// @Produce json
+// @Tags CMDB资产管理
+// @Description 为CI类型新增自定义属性，类型:1->字符串,2->枚举,3->日期,4->引用主机
+// @Param data body model.CmdbCIFieldDto true "data"
+// @Success 200 {object} result.Result
+// @router /api/v1/cmdb/citype/field/add [post]
+// @Security ApiKeyAuth
+func CreateCmdbCIField(c *gin.Context) {
+	var dto model.CmdbCIFieldDto
+	if err := c.BindJSON(&dto); err != nil {
+		result.Failed(c, int(result.ApiCode.FAILED), "参数错误")
+		return
+	}
+	service.GetCmdbCITypeService().CreateField(c, dto)
+}
+
+// @Summary 修改自定义属性
+// @Produce json
+// @Tags CMDB资产管理
+// @Description 修改自定义属性的名称、是否必填、可选值、引用类型和排序，属性键和类型不可修改
+// @Param data body model.CmdbCIFieldDto true "data"
+// @Success 200 {object} result.Result
+// @router /api/v1/cmdb/citype/field/update [put]
+// @Security ApiKeyAuth
+func UpdateCmdbCIField(c *gin.Context) {
+	var dto model.CmdbCIFieldDto
+	if err := c.BindJSON(&dto); err != nil {
+		result.Failed(c, int(result.ApiCode.FAILED), "参数错误")
+		return
+	}
+	service.GetCmdbCITypeService().UpdateField(c, dto)
+}
+
+// @Summary 删除自定义属性
+// @Produce json
+// @Tags CMDB资产管理
+// @Description 删除自定义属性及全部主机上的取值
+// @Param data body model.CmdbCITypeIdDto true "data"
+// @Success 200 {object} result.Result
+// @router /api/v1/cmdb/citype/field/delete [delete]
+// @Security ApiKeyAuth
+func DeleteCmdbCIField(c *gin.Context) {
+	var dto model.CmdbCITypeIdDto
+	if err := c.BindJSON(&dto); err != nil {
+		result.Failed(c, int(result.ApiCode.FAILED), "参数错误")
+		return
+	}
+	service.GetCmdbCITypeService().DeleteField(c, dto.Id)
+}
+
+// @Summary 按自定义属性查询主机
+// @Produce json
+// @Tags CMDB资产管理
+// @Description 按CI类型、自定义属性条件和关键字分页查询主机，条件可传多个且需同时满足，格式为 属性键=值、属性键!=值、属性键~包含的值，日期属性支持 >= 和 <=
+// @Param ciTypeId query int false "CI类型ID，按自定义属性查询时必填"
+// @Param filter query []string false "自定义属性条件，如 owner=alice、warranty<=2026-12-31" collectionFormat(multi)
+// @Param keyword query string false "关键字，匹配主机名称、IP和自定义属性值"
+// @Param page query int false "页码"
+// @Param pageSize query int false "每页数量"
+// @Success 200 {object} result.Result
+// @router /api/v1/cmdb/hostsearch [get]
+// @Security ApiKeyAuth
+func SearchCmdbHostsByAttrs(c *gin.Context) {
+	ciTypeId, _ := strconv.Atoi(c.Query("ciTypeId"))
+	page, err := strconv.Atoi(c.Query("page"))
+	if err != nil || page <= 0 {
+		page = 1
+	}
+	pageSize, err := strconv.Atoi(c.Query("pageSize"))
+	if err != nil || pageSize <= 0 || pageSize > 100 {
+		pageSize = 10
+	}
+	service.GetCmdbCITypeService().SearchHosts(c, uint(ciTypeId), c.QueryArray("filter"), c.Query("keyword"), page, pageSize)
+}
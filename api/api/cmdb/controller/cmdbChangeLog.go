@@ -0,0 +1,63 @@
+package controller
+
+import (
+	"dodevops-api/api/cmdb/model"
+	"dodevops-api/api/cmdb/service"
+	"dodevops-api/common/result"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// @Summary 查询资产变更历史
+// @Produce json
+// @Tags CMDB资产管理
+// @Description 按时间倒序分页查询主机、分组、数据库的字段级变更记录，来源 user->用户操作,cloud->云平台同步,agent->主机信息采集,system->其他后台任务
+// @Param type query string true "资产类型:host、group、database"
+// @Param id query int true "资产ID"
+// @Param field query string false "字段"
+// @Param source query string false "来源"
+// @Param page query int false "页码"
+// @Param pageSize query int false "每页数量"
+// @Success 200 {object} result.Result
+// @router /api/v1/cmdb/history/list [get]
+// @Security ApiKeyAuth
+func GetCmdbChangeHistory(c *gin.Context) {
+	var query model.CmdbChangeLogQueryDto
+	if err := c.ShouldBindQuery(&query); err != nil {
+		result.Failed(c, int(result.ApiCode.FAILED), "参数错误")
+		return
+	}
+	service.GetCmdbChangeLogService().GetHistory(c, query)
+}
+
+// @Summary 查询资产在某一时刻的状态
+// @Produce json
+// @Tags CMDB资产管理
+// @Description 根据变更记录还原资产在指定时刻的各字段取值
+// @Param type query string true "资产类型:host、group、database"
+// @Param id query int true "资产ID"
+// @Param time query string true "时间点，格式 2006-01-02 15:04:05"
+// @Success 200 {object} result.Result
+// @router /api/v1/cmdb/history/asof [get]
+// @Security ApiKeyAuth
+func GetCmdbSnapshot(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Query("id"))
+	service.GetCmdbChangeLogService().GetSnapshot(c, c.Query("type"), uint(id), c.Query("time"))
+}
+
+// @Summary 对比资产两个时刻的状态
+// @Produce json
+// @Tags CMDB资产管理
+// @Description 还原资产在两个时刻的状态并返回变化的字段
+// @Param type query string true "资产类型:host、group、database"
+// @Param id query int true "资产ID"
+// @Param from query string true "起始时间，格式 2006-01-02 15:04:05"
+// @Param to query string true "结束时间，格式 2006-01-02 15:04:05"
+// @Success 200 {object} result.Result
+// @router /api/v1/cmdb/history/diff [get]
+// @Security ApiKeyAuth
+func GetCmdbSnapshotDiff(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Query("id"))
+	service.GetCmdbChangeLogService().GetSnapshotDiff(c, c.Query("type"), uint(id), c.Query("from"), c.Query("to"))
+}
@@ -0,0 +1,146 @@
+package controller
+
+import (
+	"dodevops-api/api/cmdb/model"
+	"dodevops-api/api/cmdb/service"
+	"dodevops-api/common/result"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// @Summary 查询凭证轮换策略列表
+// @Produce json
+// @Tags CMDB资产管理
+// @Description 查询凭证轮换策略列表
+// @Success 200 {object} result.Result
+// @router /api/v1/cmdb/credential/rotation/list [get]
+// @Security ApiKeyAuth
+func GetCmdbCredentialRotationList(c *gin.Context) {
+	service.GetCmdbCredentialRotationService().GetRotationList(c)
+}
+
+// @Summary 新增凭证轮换策略
+// @Produce json
+// @Tags CMDB资产管理
+// @Description 新增凭证轮换策略，mode 1->轮换密码,2->轮换密钥，cronExpr 为空时仅支持手动执行
+// @Param data body model.CmdbCredentialRotation true "data"
+// @Success 200 {object} result.Result
+// @router /api/v1/cmdb/credential/rotation/add [post]
+// @Security ApiKeyAuth
+func CreateCmdbCredentialRotation(c *gin.Context) {
+	var rotation model.CmdbCredentialRotation
+	if err := c.BindJSON(&rotation); err != nil {
+		result.Failed(c, int(result.ApiCode.FAILED), "参数错误")
+		return
+	}
+	service.GetCmdbCredentialRotationService().CreateRotation(c, rotation)
+}
+
+// @Summary 修改凭证轮换策略
+// @Produce json
+// @Tags CMDB资产管理
+// @Description 修改凭证轮换策略
+// @Param data body model.CmdbCredentialRotation true "data"
+// @Success 200 {object} result.Result
+// @router /api/v1/cmdb/credential/rotation/update [put]
+// @Security ApiKeyAuth
+func UpdateCmdbCredentialRotation(c *gin.Context) {
+	var rotation model.CmdbCredentialRotation
+	if err := c.BindJSON(&rotation); err != nil {
+		result.Failed(c, int(result.ApiCode.FAILED), "参数错误")
+		return
+	}
+	service.GetCmdbCredentialRotationService().UpdateRotation(c, rotation)
+}
+
+// @Summary 删除凭证轮换策略
+// @Produce json
+// @Tags CMDB资产管理
+// @Description 删除凭证轮换策略
+// @Param data body model.CmdbCredentialRotationIdDto true "策略ID"
+// @Success 200 {object} result.Result
+// @router /api/v1/cmdb/credential/rotation/delete [delete]
+// @Security ApiKeyAuth
+func DeleteCmdbCredentialRotation(c *gin.Context) {
+	var dto model.CmdbCredentialRotationIdDto
+	if err := c.BindJSON(&dto); err != nil {
+		result.Failed(c, int(result.ApiCode.FAILED), "参数错误")
+		return
+	}
+	service.GetCmdbCredentialRotationService().DeleteRotation(c, dto.Id)
+}
+
+// @Summary 立即执行凭证轮换
+// @Produce json
+// @Tags CMDB资产管理
+// @Description 立即执行凭证轮换策略，后台执行，返回执行记录，通过执行记录详情查看每台主机的结果
+// @Param data body model.CmdbCredentialRotationIdDto true "策略ID"
+// @Success 200 {object} result.Result
+// @router /api/v1/cmdb/credential/rotation/run [post]
+// @Security ApiKeyAuth
+func RunCmdbCredentialRotation(c *gin.Context) {
+	var dto model.CmdbCredentialRotationIdDto
+	if err := c.BindJSON(&dto); err != nil {
+		result.Failed(c, int(result.ApiCode.FAILED), "参数错误")
+		return
+	}
+	service.GetCmdbCredentialRotationService().RunRotation(c, dto.Id)
+}
+
+// @Summary 分发公钥到资产分组
+// @Produce json
+// @Tags CMDB资产管理
+// @Description 使用主机现有凭证登录，将密钥凭证的公钥写入分组内主机的 authorized_keys 并验证登录，后台执行，返回执行记录
+// @Param data body model.CmdbCredentialDistributeDto true "data"
+// @Success 200 {object} result.Result
+// @router /api/v1/cmdb/credential/distribute [post]
+// @Security ApiKeyAuth
+func DistributeCmdbCredential(c *gin.Context) {
+	var dto model.CmdbCredentialDistributeDto
+	if err := c.BindJSON(&dto); err != nil {
+		result.Failed(c, int(result.ApiCode.FAILED), "参数错误")
+		return
+	}
+	service.GetCmdbCredentialRotationService().DistributePublicKey(c, dto)
+}
+
+// @Summary 分页查询凭证轮换执行记录
+// @Produce json
+// @Tags CMDB资产管理
+// @Description 分页查询凭证轮换执行记录
+// @Param rotationId query int false "策略ID，不传查询全部"
+// @Param page query int false "页码"
+// @Param pageSize query int false "每页数量"
+// @Success 200 {object} result.Result
+// @router /api/v1/cmdb/credential/rotation/runlist [get]
+// @Security ApiKeyAuth
+func GetCmdbCredentialRotationRunList(c *gin.Context) {
+	rotationId, _ := strconv.Atoi(c.Query("rotationId"))
+	page, err := strconv.Atoi(c.Query("page"))
+	if err != nil || page <= 0 {
+		page = 1
+	}
+	pageSize, err := strconv.Atoi(c.Query("pageSize"))
+	if err != nil || pageSize <= 0 {
+		pageSize = 10
+	}
+	service.GetCmdbCredentialRotationService().GetRunList(c, uint(rotationId), page, pageSize)
+}
+
+// @Summary 查询凭证轮换执行记录详情
+// @Produce json
+// @Tags CMDB资产管理
+// @Description 查询凭证轮换执行记录详情及每台主机的轮换报告
+// @Param id query int true "执行记录ID"
+// @Success 200 {object} result.Result
+// @router /api/v1/cmdb/credential/rotation/runinfo [get]
+// @Security ApiKeyAuth
+func GetCmdbCredentialRotationRunInfo(c *gin.Context) {
+	id, err := strconv.Atoi(c.Query("id"))
+	if err != nil || id <= 0 {
+		result.Failed(c, int(result.ApiCode.FAILED), "参数错误")
+		return
+	}
+	service.GetCmdbCredentialRotationService().GetRunInfo(c, uint(id))
+}
@@ -0,0 +1,89 @@
+package controller
+
+import (
+	"dodevops-api/api/cmdb/model"
+	"dodevops-api/api/cmdb/service"
+	"dodevops-api/common/result"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// @Summary 查询动态分组
+// @Produce json
+// @Tags CMDB资产管理
+// @Description 查询动态主机分组及当前匹配的主机数
+// @Success 200 {object} result.Result
+// @router /api/v1/cmdb/dynamicgroup/list [get]
+// @Security ApiKeyAuth
+func GetCmdbDynamicGroupList(c *gin.Context) {
+	service.GetCmdbDynamicGroupService().GetDynamicGroupList(c)
+}
+
+// @Summary 新增动态分组
+// @Produce json
+// @Tags CMDB资产管理
+// @Description 保存标签选择器为动态分组，分组成员在使用时按主机当前的标签计算，可作为Ansible任务、批量命令、Agent部署的目标和告警规则的约束条件
+// @Param data body model.CmdbDynamicGroupDto true "data"
+// @Success 200 {object} result.Result
+// @router /api/v1/cmdb/dynamicgroup/add [post]
+// @Security ApiKeyAuth
+func CreateCmdbDynamicGroup(c *gin.Context) {
+	var dto model.CmdbDynamicGroupDto
+	if err := c.BindJSON(&dto); err != nil {
+		result.Failed(c, int(result.ApiCode.FAILED), "参数错误")
+		return
+	}
+	service.GetCmdbDynamicGroupService().CreateDynamicGroup(c, dto)
+}
+
+// @Summary 修改动态分组
+// @Produce json
+// @Tags CMDB资产管理
+// @Description 修改动态分组的名称、标签选择器和备注
+// @Param data body model.CmdbDynamicGroupDto true "data"
+// @Success 200 {object} result.Result
+// @router /api/v1/cmdb/dynamicgroup/update [put]
+// @Security ApiKeyAuth
+func UpdateCmdbDynamicGroup(c *gin.Context) {
+	var dto model.CmdbDynamicGroupDto
+	if err := c.BindJSON(&dto); err != nil {
+		result.Failed(c, int(result.ApiCode.FAILED), "参数错误")
+		return
+	}
+	service.GetCmdbDynamicGroupService().UpdateDynamicGroup(c, dto)
+}
+
+// @Summary 删除动态分组
+// @Produce json
+// @Tags CMDB资产管理
+// @Description 删除动态分组
+// @Param data body model.CmdbDynamicGroupIdDto true "data"
+// @Success 200 {object} result.Result
+// @router /api/v1/cmdb/dynamicgroup/delete [delete]
+// @Security ApiKeyAuth
+func DeleteCmdbDynamicGroup(c *gin.Context) {
+	var dto model.CmdbDynamicGroupIdDto
+	if err := c.BindJSON(&dto); err != nil {
+		result.Failed(c, int(result.ApiCode.FAILED), "参数错误")
+		return
+	}
+	service.GetCmdbDynamicGroupService().DeleteDynamicGroup(c, dto.Id)
+}
+
+// @Summary 查询动态分组当前匹配的主机
+// @Produce json
+// @Tags CMDB资产管理
+// @Description 按动态分组的标签选择器查询当前匹配且有数据权限的主机
+// @Param id query int true "动态分组ID"
+// @Success 200 {object} result.Result
+// @router /api/v1/cmdb/dynamicgroup/hosts [get]
+// @Security ApiKeyAuth
+func GetCmdbDynamicGroupHosts(c *gin.Context) {
+	id, err := strconv.Atoi(c.Query("id"))
+	if err != nil || id <= 0 {
+		result.Failed(c, int(result.ApiCode.FAILED), "参数错误")
+		return
+	}
+	service.GetCmdbDynamicGroupService().GetDynamicGroupHosts(c, uint(id))
+}
@@ -26,6 +26,9 @@ func NewCmdbHostController() *CmdbHostController {
 	}
 }
 
+// 导入模板中主机连接信息的列数，之后的列为自定义属性
+const hostExcelImportColumns = 5
+
 // 分页参数
 type PageParams struct {
 	Page     int `form:"page" binding:"required,min=1"`
@@ -100,12 +103,13 @@ func (c *CmdbHostController) CreateCmdbHost(ctx *gin.Context) {
 
 // 从Excel导入主机
 // @Summary 从Excel导入主机
-// @Description 通过上传Excel模板批量导入主机（Excel列顺序：主机别名、SSH地址、SSH端口、SSH用户、备注）
+// @Description 通过上传Excel模板批量导入主机（Excel列顺序：主机别名、SSH地址、SSH端口、SSH用户、备注），指定CI类型时之后的列按表头匹配自定义属性
 // @Tags CMDB资产管理
 // @Accept multipart/form-data
 // @Produce json
 // @Param file formData file true "Excel文件"
 // @Param groupId formData int true "分组ID"
+// @Param ciTypeId formData int false "CI类型ID"
 // @Success 200 {object} result.Result
 // @Router /api/v1/cmdb/hostimport [post]
 // @Security ApiKeyAuth
@@ -161,8 +165,10 @@ func (c *CmdbHostController) ImportHostsFromExcel(ctx *gin.Context) {
 
 	// 解析Excel数据
 	var hosts []model.ExcelHostTemplate
+	var header []string
 	for i, row := range rows {
-		if i == 0 { // 跳过表头
+		if i == 0 { // 表头用于匹配自定义属性列
+			header = row
 			continue
 		}
 		// Excel列顺序: 主机别名, SSH地址, SSH端口, SSH用户, 备注
@@ -182,18 +188,81 @@ func (c *CmdbHostController) ImportHostsFromExcel(ctx *gin.Context) {
 			SSHPort:   util.StringToInt(row[2]),
 			SSHName:   strings.TrimSpace(row[3]),
 			Remark:    remark,
+			Attrs:     map[string]string{},
+		}
+		// 备注之后的列按表头作为自定义属性
+		for j := hostExcelImportColumns; j < len(row) && j < len(header); j++ {
+			if column := strings.TrimSpace(header[j]); column != "" {
+				host.Attrs[column] = strings.TrimSpace(row[j])
+			}
 		}
 		hosts = append(hosts, host)
 	}
 
 	// 调用服务层批量导入
 	dto := model.ImportHostsFromExcelDto{
-		GroupID: groupId,
-		File:    file.Filename,
+		GroupID:  groupId,
+		File:     file.Filename,
+		CITypeID: util.StringToUint(ctx.PostForm("ciTypeId")),
 	}
 	c.service.ImportHostsFromExcel(ctx, &dto, hosts)
 }
 
+// 按条件导出主机
+// @Summary 按条件导出主机(XLSX/CSV/JSON)
+// @Description 导出主机的分组路径、SSH凭据名称、跳板机、标签和自定义属性，导出的文件修改后可以通过导入接口更新主机
+// @Tags CMDB资产管理
+// @Produce octet-stream
+// @Param format query string false "格式:xlsx、csv、json，默认xlsx"
+// @Param groupId query int false "分组ID(包含子分组)"
+// @Param ciTypeId query int false "CI类型ID"
+// @Param status query int false "状态:1->认证成功,2->未认证,3->认证失败"
+// @Param keyword query string false "按名称、主机名和IP模糊匹配"
+// @Param selector query string false "标签选择器，如 env=prod,role in (web,api)"
+// @Success 200 {file} file
+// @Router /api/v1/cmdb/hostexport [get]
+// @Security ApiKeyAuth
+func (c *CmdbHostController) ExportHosts(ctx *gin.Context) {
+	var query model.CmdbHostExportQuery
+	if err := ctx.ShouldBindQuery(&query); err != nil {
+		result.Failed(ctx, constant.INVALID_PARAMS, "参数错误")
+		return
+	}
+	c.service.ExportHosts(ctx, query)
+}
+
+// 导入主机(新增或更新)
+// @Summary 导入主机(按实例ID/SSH地址更新)
+// @Description 导入按条件导出的文件，按实例ID或SSH地址匹配已有主机并更新文件中出现的列，未匹配到的新增(需要新增主机的权限)；默认只预览每一行的变化，dryRun=false 时才导入
+// @Tags CMDB资产管理
+// @Accept multipart/form-data
+// @Produce json
+// @Param file formData file true "导入文件(.xlsx/.csv/.json)"
+// @Param format formData string false "文件格式，默认按文件扩展名"
+// @Param dryRun formData bool false "是否只预览，默认true"
+// @Success 200 {object} result.Result{data=model.CmdbHostImportVo}
+// @Router /api/v1/cmdb/hosttransfer/import [post]
+// @Security ApiKeyAuth
+func (c *CmdbHostController) ImportHosts(ctx *gin.Context) {
+	file, err := ctx.FormFile("file")
+	if err != nil {
+		result.Failed(ctx, constant.INVALID_PARAMS, "请上传文件")
+		return
+	}
+	format := strings.ToLower(strings.TrimPrefix(filepath.Ext(file.Filename), "."))
+	if value := ctx.PostForm("format"); value != "" {
+		format = strings.ToLower(value)
+	}
+	src, err := file.Open()
+	if err != nil {
+		result.Failed(ctx, int(result.ApiCode.FAILED), "读取文件失败")
+		return
+	}
+	defer src.Close()
+	dryRun := ctx.PostForm("dryRun") != "false" && ctx.PostForm("dryRun") != "0"
+	c.service.ImportHosts(ctx, format, src, dryRun)
+}
+
 // 更新主机
 // @Summary 更新主机
 // @Description 更新主机
@@ -0,0 +1,80 @@
+package controller
+
+import (
+	"dodevops-api/api/cmdb/model"
+	"dodevops-api/api/cmdb/service"
+	"dodevops-api/common/result"
+
+	"github.com/gin-gonic/gin"
+)
+
+// @Summary 分页查询主机密钥
+// @Produce json
+// @Tags CMDB资产管理
+// @Description 分页查询主机SSH密钥记录，status 1->已信任,2->密钥变更,3->待确认
+// @Param hostId query int false "主机ID"
+// @Param status query int false "状态"
+// @Param page query int false "页码"
+// @Param pageSize query int false "每页数量"
+// @Success 200 {object} result.Result
+// @router /api/v1/cmdb/hostkey/list [get]
+// @Security ApiKeyAuth
+func GetCmdbHostKeyList(c *gin.Context) {
+	var query model.CmdbHostKeyQueryDto
+	if err := c.ShouldBindQuery(&query); err != nil {
+		result.Failed(c, int(result.ApiCode.FAILED), "参数错误")
+		return
+	}
+	service.GetCmdbHostKeyService().GetHostKeyList(c, query)
+}
+
+// @Summary 确认主机密钥
+// @Produce json
+// @Tags CMDB资产管理
+// @Description 将密钥变更或待确认的主机密钥设为信任的密钥
+// @Param data body model.CmdbHostKeyIdDto true "主机密钥ID"
+// @Success 200 {object} result.Result
+// @router /api/v1/cmdb/hostkey/accept [post]
+// @Security ApiKeyAuth
+func AcceptCmdbHostKey(c *gin.Context) {
+	var dto model.CmdbHostKeyIdDto
+	if err := c.BindJSON(&dto); err != nil {
+		result.Failed(c, int(result.ApiCode.FAILED), "参数错误")
+		return
+	}
+	service.GetCmdbHostKeyService().AcceptHostKey(c, dto.Id)
+}
+
+// @Summary 固定主机密钥
+// @Produce json
+// @Tags CMDB资产管理
+// @Description 将主机信任的密钥固定为指定公钥，公钥为空时扫描主机当前的密钥
+// @Param data body model.CmdbHostKeyPinDto true "data"
+// @Success 200 {object} result.Result
+// @router /api/v1/cmdb/hostkey/pin [post]
+// @Security ApiKeyAuth
+func PinCmdbHostKey(c *gin.Context) {
+	var dto model.CmdbHostKeyPinDto
+	if err := c.BindJSON(&dto); err != nil {
+		result.Failed(c, int(result.ApiCode.FAILED), "参数错误")
+		return
+	}
+	service.GetCmdbHostKeyService().PinHostKey(c, dto)
+}
+
+// @Summary 删除主机密钥
+// @Produce json
+// @Tags CMDB资产管理
+// @Description 删除主机密钥记录，下次连接时按校验策略重新处理
+// @Param data body model.CmdbHostKeyIdDto true "主机密钥ID"
+// @Success 200 {object} result.Result
+// @router /api/v1/cmdb/hostkey/delete [delete]
+// @Security ApiKeyAuth
+func DeleteCmdbHostKey(c *gin.Context) {
+	var dto model.CmdbHostKeyIdDto
+	if err := c.BindJSON(&dto); err != nil {
+		result.Failed(c, int(result.ApiCode.FAILED), "参数错误")
+		return
+	}
+	service.GetCmdbHostKeyService().DeleteHostKey(c, dto.Id)
+}
@@ -0,0 +1,67 @@
+package controller
+
+import (
+	"dodevops-api/api/cmdb/model"
+	"dodevops-api/api/cmdb/service"
+	"dodevops-api/common/result"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// @Summary 查询主机标签
+// @Produce json
+// @Tags CMDB资产管理
+// @Description 查询主机的键值对标签
+// @Param hostId query int true "主机ID"
+// @Success 200 {object} result.Result
+// @router /api/v1/cmdb/hostlabel/list [get]
+// @Security ApiKeyAuth
+func GetCmdbHostLabels(c *gin.Context) {
+	hostId, err := strconv.Atoi(c.Query("hostId"))
+	if err != nil || hostId <= 0 {
+		result.Failed(c, int(result.ApiCode.FAILED), "参数错误")
+		return
+	}
+	service.GetCmdbHostLabelService().GetHostLabels(c, uint(hostId))
+}
+
+// @Summary 设置主机标签
+// @Produce json
+// @Tags CMDB资产管理
+// @Description 设置主机的键值对标签，覆盖主机原有的全部标签，标签可用于批量命令的标签选择器
+// @Param data body model.CmdbHostLabelDto true "data"
+// @Success 200 {object} result.Result
+// @router /api/v1/cmdb/hostlabel/update [put]
+// @Security ApiKeyAuth
+func UpdateCmdbHostLabels(c *gin.Context) {
+	var dto model.CmdbHostLabelDto
+	if err := c.BindJSON(&dto); err != nil {
+		result.Failed(c, int(result.ApiCode.FAILED), "参数错误")
+		return
+	}
+	service.GetCmdbHostLabelService().UpdateHostLabels(c, dto)
+}
+
+// @Summary 查询已使用的标签
+// @Produce json
+// @Tags CMDB资产管理
+// @Description 查询当前用户可见的主机上已使用的标签键及其取值，用于编写标签选择器
+// @Success 200 {object} result.Result
+// @router /api/v1/cmdb/hostlabel/values [get]
+// @Security ApiKeyAuth
+func GetCmdbHostLabelValues(c *gin.Context) {
+	service.GetCmdbHostLabelService().GetLabelValues(c)
+}
+
+// @Summary 按标签选择器查询主机
+// @Produce json
+// @Tags CMDB资产管理
+// @Description 条件以逗号分隔且同时满足，支持 key=value、key!=value、key in (a,b)、key notin (a,b)、key、!key
+// @Param selector query string true "标签选择器，如 env=prod,role in (web,api)"
+// @Success 200 {object} result.Result
+// @router /api/v1/cmdb/hostlabel/select [get]
+// @Security ApiKeyAuth
+func SelectCmdbHostsByLabels(c *gin.Context) {
+	service.GetCmdbHostLabelService().SelectHosts(c, c.Query("selector"))
+}
@@ -2,8 +2,13 @@ package controller
 
 import (
 	"fmt"
+	"dodevops-api/api/cmdb/dao"
 	"dodevops-api/api/cmdb/service"
+	systemmodel "dodevops-api/api/system/model"
+	systemservice "dodevops-api/api/system/service"
+	"dodevops-api/common/constant"
 	"dodevops-api/common/result"
+	websocketutil "dodevops-api/common/util/websocket"
 	"dodevops-api/pkg/jwt"
 	"log"
 	"net"
@@ -104,12 +109,13 @@ func (c *CmdbHostSSHController) ConnectTerminal(ctx *gin.Context) {
 		}
 		token = authHeader[7:]
 	}
-	_, err = jwt.ValidateToken(token)
+	admin, err := jwt.ValidateToken(token)
 	if err != nil {
 		log.Printf("令牌验证失败: %v", err)
 		ctx.String(http.StatusUnauthorized, "令牌验证失败")
 		return
 	}
+	ctx.Set(constant.ContextKeyUserObj, admin)
 
 	// 升级WebSocket连接
 	wsConn, err := upgrader.Upgrade(ctx.Writer, ctx.Request, nil)
@@ -129,6 +135,36 @@ func (c *CmdbHostSSHController) ConnectTerminal(ctx *gin.Context) {
 		return
 	}
 
+	// 开始录制终端会话，无法录制时不允许连接
+	host, err := dao.NewCmdbHostSSHDao().WithContext(ctx).GetHostSSHInfo(uint(id))
+	if err != nil {
+		log.Printf("获取主机信息失败: %v", err)
+		webSSH.Close()
+		wsConn.Close()
+		return
+	}
+	recorder, err := systemservice.StartSysTerminalSession(ctx, systemmodel.SysTerminalSessionStartDto{
+		Kind:       systemmodel.TerminalKindHost,
+		TargetId:   host.ID,
+		TargetName: host.HostName,
+		Address:    net.JoinHostPort(host.SSHIP, strconv.Itoa(host.SSHPort)),
+		Width:      websocketutil.DefaultCols,
+		Height:     websocketutil.DefaultRows,
+	})
+	if err != nil {
+		log.Printf("终端会话录制失败: %v", err)
+		wsConn.WriteMessage(websocket.TextMessage, []byte("终端会话录制失败，连接已拒绝\r\n"))
+		webSSH.Close()
+		wsConn.Close()
+		return
+	}
+	defer recorder.Close()
+	webSSH.SetRecorder(recorder)
+	recorder.OnTerminate(webSSH.Terminate)
+	if checker := systemservice.NewTerminalCommandChecker(ctx, host.GroupID, host.HostName); checker != nil {
+		webSSH.SetCommandChecker(checker)
+	}
+
 	// 连接WebSocket
 	if err := webSSH.Connect(wsConn); err != nil {
 		log.Printf("WebSSH连接失败: %v", err)
@@ -148,8 +184,8 @@ func (c *CmdbHostSSHController) ConnectTerminal(ctx *gin.Context) {
 		wsConn.Close()
 	}()
 
-	// 保持连接
-	select {}
+	// 保持连接直到终端结束
+	<-webSSH.Done()
 }
 
 // ExecuteCommand 执行命令
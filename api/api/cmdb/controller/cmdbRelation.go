@@ -0,0 +1,100 @@
+package controller
+
+import (
+	"dodevops-api/api/cmdb/model"
+	"dodevops-api/api/cmdb/service"
+	"dodevops-api/common/result"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// @Summary 新增配置项关系
+// @Produce json
+// @Tags CMDB资产管理
+// @Description 新增配置项之间的关系，配置项类型:host、database、cluster、app，关系类型:runs-on、depends-on、belongs-to、connects-to
+// @Param data body model.CmdbRelationDto true "data"
+// @Success 200 {object} result.Result
+// @router /api/v1/cmdb/relation/add [post]
+// @Security ApiKeyAuth
+func CreateCmdbRelation(c *gin.Context) {
+	var dto model.CmdbRelationDto
+	if err := c.BindJSON(&dto); err != nil {
+		result.Failed(c, int(result.ApiCode.FAILED), "参数错误")
+		return
+	}
+	service.GetCmdbRelationService().CreateRelation(c, dto)
+}
+
+// @Summary 删除配置项关系
+// @Produce json
+// @Tags CMDB资产管理
+// @Description 删除配置项关系，自动发现的关系会在下次发现时重新生成
+// @Param data body model.CmdbRelationIdDto true "data"
+// @Success 200 {object} result.Result
+// @router /api/v1/cmdb/relation/delete [delete]
+// @Security ApiKeyAuth
+func DeleteCmdbRelation(c *gin.Context) {
+	var dto model.CmdbRelationIdDto
+	if err := c.BindJSON(&dto); err != nil {
+		result.Failed(c, int(result.ApiCode.FAILED), "参数错误")
+		return
+	}
+	service.GetCmdbRelationService().DeleteRelation(c, dto.Id)
+}
+
+// @Summary 查询配置项关系图
+// @Produce json
+// @Tags CMDB资产管理
+// @Description 从配置项出发沿关系双向展开N跳，返回途经的配置项和关系
+// @Param type query string true "配置项类型:host、database、cluster、app"
+// @Param id query int true "配置项ID"
+// @Param depth query int false "展开跳数，默认1，最大5"
+// @Param relationType query string false "只沿该类型的关系展开"
+// @Success 200 {object} result.Result
+// @router /api/v1/cmdb/relation/graph [get]
+// @Security ApiKeyAuth
+func GetCmdbRelationGraph(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Query("id"))
+	depth, err := strconv.Atoi(c.Query("depth"))
+	if err != nil || depth <= 0 {
+		depth = 1
+	}
+	if depth > 5 {
+		depth = 5
+	}
+	service.GetCmdbRelationService().GetGraph(c, c.Query("type"), uint(id), depth, c.Query("relationType"))
+}
+
+// @Summary 配置项影响分析
+// @Produce json
+// @Tags CMDB资产管理
+// @Description 分析配置项故障后受影响的配置项和应用，如主机宕机后哪些应用不可用
+// @Param type query string true "配置项类型:host、database、cluster、app"
+// @Param id query int true "配置项ID"
+// @Param depth query int false "影响传递的最大跳数，默认5，最大10"
+// @Success 200 {object} result.Result
+// @router /api/v1/cmdb/relation/impact [get]
+// @Security ApiKeyAuth
+func GetCmdbRelationImpact(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Query("id"))
+	depth, err := strconv.Atoi(c.Query("depth"))
+	if err != nil || depth <= 0 {
+		depth = 5
+	}
+	if depth > 10 {
+		depth = 10
+	}
+	service.GetCmdbRelationService().GetImpact(c, c.Query("type"), uint(id), depth)
+}
+
+// @Summary 自动发现配置项关系
+// @Produce json
+// @Tags CMDB资产管理
+// @Description 按应用关联的主机和数据库、快速发布记录以及K8s集群节点IP重新生成自动发现的关系，手工维护的关系不受影响
+// @Success 200 {object} result.Result
+// @router /api/v1/cmdb/relation/discover [post]
+// @Security ApiKeyAuth
+func DiscoverCmdbRelations(c *gin.Context) {
+	service.GetCmdbRelationService().Discover(c)
+}
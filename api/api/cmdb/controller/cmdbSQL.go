@@ -38,7 +38,7 @@ func (c *CmdbSQLController) CreateDatabase(ctx *gin.Context) {
 		return
 	}
 
-	if err := c.service.CreateDatabase(&db); err != nil {
+	if err := c.service.WithContext(ctx).CreateDatabase(&db); err != nil {
 		result.Failed(ctx, 500, "创建失败: "+err.Error())
 		return
 	}
@@ -78,7 +78,7 @@ func (c *CmdbSQLController) UpdateDatabase(ctx *gin.Context) {
 	db.CreatedAt = existing.CreatedAt
 	db.UpdatedAt = util.HTime{Time: time.Now()}
 
-	if err := c.service.UpdateDatabase(&db); err != nil {
+	if err := c.service.WithContext(ctx).UpdateDatabase(&db); err != nil {
 		result.Failed(ctx, 500, "更新失败: "+err.Error())
 		return
 	}
@@ -124,7 +124,7 @@ func (c *CmdbSQLController) DeleteDatabase(ctx *gin.Context) {
 		return
 	}
 
-	if err := c.service.DeleteDatabase(uint(id)); err != nil {
+	if err := c.service.WithContext(ctx).DeleteDatabase(uint(id)); err != nil {
 		result.Failed(ctx, 500, "删除失败: "+err.Error())
 		return
 	}
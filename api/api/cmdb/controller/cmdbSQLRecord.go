@@ -3,6 +3,7 @@ package controller
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"fmt"
 	"net"
 	"reflect"
@@ -16,6 +17,7 @@ import (
 	"dodevops-api/common/util"
 	"dodevops-api/common/constant"
 	"dodevops-api/common/result"
+	"dodevops-api/pkg/approval"
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
 	_ "github.com/go-sql-driver/mysql"
@@ -96,6 +98,7 @@ func (c *CmdbSQLRecordController) getDBConnectionInfo(req SQLRequest) (*model.Cm
 	if err != nil {
 		return nil, nil, "", fmt.Errorf("解密密码失败: %v", err)
 	}
+	configService.NewAccountAuthService().MarkUsed(dbInfo.AccountID)
 
 	return dbInfo, account, decrypted, nil
 }
@@ -111,6 +114,22 @@ func validateSQLType(sql string, allowedTypes []string) bool {
 	return false
 }
 
+// DDLCondition 审批条件：执行 DDL 语句
+const DDLCondition = "ddl"
+
+func init() {
+	approval.RegisterCondition(DDLCondition, isDDLRequest)
+}
+
+// isDDLRequest 判断SQL请求是否为DDL语句，请求体无法解析时按需要审批处理
+func isDDLRequest(ctx *gin.Context, body []byte) bool {
+	var req SQLRequest
+	if err := json.Unmarshal(body, &req); err != nil {
+		return true
+	}
+	return validateSQLType(req.SQL, []string{"CREATE", "ALTER", "DROP", "TRUNCATE", "RENAME"})
+}
+
 // 获取当前用户名
 func getCurrentUsername(ctx *gin.Context) (string, error) {
 	userObj, exists := ctx.Get(constant.ContextKeyUserObj)
@@ -577,6 +596,7 @@ func (c *CmdbSQLRecordController) GetDatabaseList(ctx *gin.Context) {
 		result.FailedWithCode(ctx, DatabaseError, "解密密码失败")
 		return
 	}
+	configService.NewAccountAuthService().MarkUsed(dbInfo.AccountID)
 
 	// 连接到MySQL服务器(不指定数据库)
 	connStr := fmt.Sprintf("%s:%s@tcp(%s:%d)/?timeout=60s", 
@@ -0,0 +1,106 @@
+// 批量命令执行 数据层
+// author xiaoRui
+
+package dao
+
+import (
+	"context"
+	"dodevops-api/api/cmdb/model"
+	"dodevops-api/common"
+	"dodevops-api/pkg/datascope"
+
+	"gorm.io/gorm"
+)
+
+type CmdbBatchCommandDao struct {
+	db *gorm.DB
+}
+
+func init() {
+	// 执行记录按操作人所属部门过滤，主机结果按主机所在资产分组过滤
+	datascope.RegisterTable(model.CmdbBatchCommand{}.TableName(), datascope.ByDept("dept_id"))
+	datascope.RegisterTable(model.CmdbBatchCommandHost{}.TableName(), datascope.ByGroup("group_id"))
+}
+
+func NewCmdbBatchCommandDao() CmdbBatchCommandDao {
+	return CmdbBatchCommandDao{
+		db: common.GetDB(),
+	}
+}
+
+// 绑定请求上下文
+func (d CmdbBatchCommandDao) WithContext(ctx context.Context) *CmdbBatchCommandDao {
+	return &CmdbBatchCommandDao{db: d.db.WithContext(ctx)}
+}
+
+// 新增执行记录及主机结果
+func (d *CmdbBatchCommandDao) CreateCommand(command *model.CmdbBatchCommand, hosts []*model.CmdbBatchCommandHost) error {
+	return d.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(command).Error; err != nil {
+			return err
+		}
+		for _, host := range hosts {
+			host.CommandID = command.ID
+		}
+		if len(hosts) == 0 {
+			return nil
+		}
+		return tx.Create(hosts).Error
+	})
+}
+
+// 保存执行结果
+func (d *CmdbBatchCommandDao) FinishCommand(command *model.CmdbBatchCommand) error {
+	return d.db.Model(&model.CmdbBatchCommand{}).Where("id = ?", command.ID).Updates(map[string]interface{}{
+		"status":        command.Status,
+		"success_count": command.SuccessCount,
+		"failed_count":  command.FailedCount,
+		"end_time":      command.EndTime,
+	}).Error
+}
+
+// 保存主机执行结果
+func (d *CmdbBatchCommandDao) UpdateCommandHost(host *model.CmdbBatchCommandHost) error {
+	return d.db.Model(&model.CmdbBatchCommandHost{}).Where("id = ?", host.ID).Updates(map[string]interface{}{
+		"status":     host.Status,
+		"exit_code":  host.ExitCode,
+		"output":     host.Output,
+		"message":    host.Message,
+		"duration":   host.Duration,
+		"start_time": host.StartTime,
+		"end_time":   host.EndTime,
+	}).Error
+}
+
+// 分页查询执行记录
+func (d *CmdbBatchCommandDao) GetCommandListWithPage(page, pageSize int) ([]model.CmdbBatchCommand, int64) {
+	var list []model.CmdbBatchCommand
+	var total int64
+	db := d.db.Model(&model.CmdbBatchCommand{})
+	db.Count(&total)
+	db.Order("id desc").Offset((page - 1) * pageSize).Limit(pageSize).Find(&list)
+	return list, total
+}
+
+// 根据ID查询执行记录
+func (d *CmdbBatchCommandDao) GetCommandById(id uint) (model.CmdbBatchCommand, error) {
+	var command model.CmdbBatchCommand
+	err := d.db.Where("id = ?", id).First(&command).Error
+	return command, err
+}
+
+// 查询执行记录的主机结果
+func (d *CmdbBatchCommandDao) GetCommandHostList(commandId uint) []model.CmdbBatchCommandHost {
+	var list []model.CmdbBatchCommandHost
+	d.db.Where("command_id = ?", commandId).Order("id").Find(&list)
+	return list
+}
+
+// 服务重启时将未结束的执行记录标记为失败
+func (d *CmdbBatchCommandDao) AbortRunningCommands(message string) {
+	d.db.Model(&model.CmdbBatchCommandHost{}).
+		Where("status IN ?", []int{model.BatchCommandHostWaiting, model.BatchCommandHostRunning}).
+		Updates(map[string]interface{}{"status": model.BatchCommandHostError, "exit_code": -1, "message": message})
+	d.db.Model(&model.CmdbBatchCommand{}).Where("status = ?", model.BatchCommandRunning).
+		Update("status", model.BatchCommandFailed)
+}
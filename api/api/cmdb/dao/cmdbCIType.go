@@ -0,0 +1,188 @@
+// CI类型（自定义属性模型） 数据层
+// author xiaoRui
+
+package dao
+
+import (
+	"dodevops-api/api/cmdb/model"
+	"dodevops-api/common"
+	"strconv"
+
+	"gorm.io/gorm"
+)
+
+type CmdbCITypeDao struct {
+	db *gorm.DB
+}
+
+func NewCmdbCITypeDao() CmdbCITypeDao {
+	return CmdbCITypeDao{
+		db: common.GetDB(),
+	}
+}
+
+// 在事务中操作CI类型和主机的自定义属性
+func (d CmdbCITypeDao) WithTx(tx *gorm.DB) *CmdbCITypeDao {
+	return &CmdbCITypeDao{db: tx}
+}
+
+// 查询全部CI类型及其自定义属性
+func (d *CmdbCITypeDao) GetCITypeList() []model.CmdbCIType {
+	var list []model.CmdbCIType
+	d.db.Order("id").Find(&list)
+	var fields []model.CmdbCIField
+	d.db.Order("sort, id").Find(&fields)
+	for i := range list {
+		list[i].Fields = []model.CmdbCIField{}
+		for _, field := range fields {
+			if field.CITypeID == list[i].ID {
+				list[i].Fields = append(list[i].Fields, field)
+			}
+		}
+	}
+	return list
+}
+
+// 根据ID查询CI类型及其自定义属性
+func (d *CmdbCITypeDao) GetCITypeById(id uint) (model.CmdbCIType, error) {
+	var ciType model.CmdbCIType
+	if err := d.db.Where("id = ?", id).First(&ciType).Error; err != nil {
+		return ciType, err
+	}
+	ciType.Fields = d.GetFieldsByCITypeId(id)
+	return ciType, nil
+}
+
+// 检查名称或编码是否已被其他CI类型使用
+func (d *CmdbCITypeDao) CheckCITypeExists(name, code string, excludeId uint) bool {
+	var count int64
+	d.db.Model(&model.CmdbCIType{}).Where("(name = ? OR code = ?) AND id <> ?", name, code, excludeId).Count(&count)
+	return count > 0
+}
+
+// 新增CI类型
+func (d *CmdbCITypeDao) CreateCIType(ciType *model.CmdbCIType) error {
+	return d.db.Create(ciType).Error
+}
+
+// 修改CI类型
+func (d *CmdbCITypeDao) UpdateCIType(ciType *model.CmdbCIType) error {
+	return d.db.Model(&model.CmdbCIType{}).Where("id = ?", ciType.ID).Updates(map[string]interface{}{
+		"name":        ciType.Name,
+		"code":        ciType.Code,
+		"remark":      ciType.Remark,
+		"update_time": ciType.UpdateTime,
+	}).Error
+}
+
+// 删除CI类型及其自定义属性定义
+func (d *CmdbCITypeDao) DeleteCIType(id uint) error {
+	return d.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("ci_type_id = ?", id).Delete(&model.CmdbCIField{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("id = ?", id).Delete(&model.CmdbCIType{}).Error
+	})
+}
+
+// 统计使用CI类型的主机数
+func (d *CmdbCITypeDao) CountHostsByCITypeId(id uint) int64 {
+	var count int64
+	d.db.Model(&model.CmdbHost{}).Where("ci_type_id = ?", id).Count(&count)
+	return count
+}
+
+// 查询CI类型的自定义属性
+func (d *CmdbCITypeDao) GetFieldsByCITypeId(ciTypeId uint) []model.CmdbCIField {
+	list := []model.CmdbCIField{}
+	d.db.Where("ci_type_id = ?", ciTypeId).Order("sort, id").Find(&list)
+	return list
+}
+
+// 根据ID查询自定义属性
+func (d *CmdbCITypeDao) GetFieldById(id uint) (model.CmdbCIField, error) {
+	var field model.CmdbCIField
+	err := d.db.Where("id = ?", id).First(&field).Error
+	return field, err
+}
+
+// 检查属性键或名称是否已被同一CI类型的其他属性使用
+func (d *CmdbCITypeDao) CheckFieldExists(ciTypeId uint, key, name string, excludeId uint) bool {
+	var count int64
+	d.db.Model(&model.CmdbCIField{}).Where("ci_type_id = ? AND (field_key = ? OR name = ?) AND id <> ?", ciTypeId, key, name, excludeId).Count(&count)
+	return count > 0
+}
+
+// 新增自定义属性
+func (d *CmdbCITypeDao) CreateField(field *model.CmdbCIField) error {
+	return d.db.Create(field).Error
+}
+
+// 修改自定义属性，键和类型不可修改
+func (d *CmdbCITypeDao) UpdateField(field *model.CmdbCIField) error {
+	return d.db.Model(&model.CmdbCIField{}).Where("id = ?", field.ID).Updates(map[string]interface{}{
+		"name":           field.Name,
+		"required":       field.Required,
+		"options":        field.Options,
+		"ref_ci_type_id": field.RefCITypeID,
+		"sort":           field.Sort,
+	}).Error
+}
+
+// 删除自定义属性及其全部取值
+func (d *CmdbCITypeDao) DeleteField(id uint) error {
+	return d.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("field_id = ?", id).Delete(&model.CmdbHostAttr{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("id = ?", id).Delete(&model.CmdbCIField{}).Error
+	})
+}
+
+// 查询自定义属性的全部取值
+func (d *CmdbCITypeDao) GetFieldAttrs(fieldId uint) []model.CmdbHostAttr {
+	var list []model.CmdbHostAttr
+	d.db.Where("field_id = ?", fieldId).Find(&list)
+	return list
+}
+
+// 查询主机的自定义属性值
+func (d *CmdbCITypeDao) GetHostAttrs(hostId uint) []model.CmdbHostAttr {
+	var list []model.CmdbHostAttr
+	d.db.Where("host_id = ?", hostId).Find(&list)
+	return list
+}
+
+// 查询多台主机的自定义属性值
+func (d *CmdbCITypeDao) GetHostAttrsByHostIds(hostIds []uint) []model.CmdbHostAttr {
+	var list []model.CmdbHostAttr
+	if len(hostIds) == 0 {
+		return list
+	}
+	d.db.Where("host_id IN ?", hostIds).Find(&list)
+	return list
+}
+
+// 覆盖主机的全部自定义属性值
+func (d *CmdbCITypeDao) ReplaceHostAttrs(hostId uint, attrs []model.CmdbHostAttr) error {
+	return d.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("host_id = ?", hostId).Delete(&model.CmdbHostAttr{}).Error; err != nil {
+			return err
+		}
+		if len(attrs) == 0 {
+			return nil
+		}
+		return tx.Create(&attrs).Error
+	})
+}
+
+// 删除主机的自定义属性值，以及其他主机引用该主机的属性值
+func (d *CmdbCITypeDao) DeleteHostAttrs(hostId uint) error {
+	return d.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("host_id = ?", hostId).Delete(&model.CmdbHostAttr{}).Error; err != nil {
+			return err
+		}
+		refFields := tx.Model(&model.CmdbCIField{}).Select("id").Where("type = ?", model.CIFieldReference)
+		return tx.Where("field_id IN (?) AND value = ?", refFields, strconv.Itoa(int(hostId))).Delete(&model.CmdbHostAttr{}).Error
+	})
+}
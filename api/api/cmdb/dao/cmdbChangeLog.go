@@ -0,0 +1,75 @@
+// CMDB资产变更历史 数据层
+// author xiaoRui
+
+package dao
+
+import (
+	"dodevops-api/api/cmdb/model"
+	"dodevops-api/common"
+	"dodevops-api/pkg/changelog"
+
+	"gorm.io/gorm"
+)
+
+type CmdbChangeLogDao struct {
+	db *gorm.DB
+}
+
+func NewCmdbChangeLogDao() CmdbChangeLogDao {
+	return CmdbChangeLogDao{
+		db: common.GetDB(),
+	}
+}
+
+// 资产类型对应的模型和表名
+func changeRecordModel(recordType string) (interface{}, string, bool) {
+	switch recordType {
+	case model.ChangeRecordHost:
+		return &model.CmdbHost{}, model.CmdbHost{}.TableName(), true
+	case model.ChangeRecordGroup:
+		return &model.CmdbGroup{}, model.CmdbGroup{}.TableName(), true
+	case model.ChangeRecordDatabase:
+		return &model.CmdbSQL{}, model.CmdbSQL{}.TableName(), true
+	}
+	return nil, "", false
+}
+
+// 分页查询资产的变更记录，按时间倒序
+func (d *CmdbChangeLogDao) GetChangeLogListWithPage(query model.CmdbChangeLogQueryDto) ([]model.CmdbChangeLog, int64) {
+	var list []model.CmdbChangeLog
+	var total int64
+	db := d.db.Model(&model.CmdbChangeLog{}).Where("record_type = ? AND record_id = ?", query.RecordType, query.RecordID)
+	if query.Field != "" {
+		db = db.Where("field = ?", query.Field)
+	}
+	if query.Source != "" {
+		db = db.Where("source = ?", query.Source)
+	}
+	db.Count(&total)
+	db.Order("id desc").Offset((query.Page - 1) * query.PageSize).Limit(query.PageSize).Find(&list)
+	return list, total
+}
+
+// 查询资产的全部变更记录，按时间倒序
+func (d *CmdbChangeLogDao) GetChangeLogs(recordType string, recordId uint) []model.CmdbChangeLog {
+	var list []model.CmdbChangeLog
+	d.db.Where("record_type = ? AND record_id = ?", recordType, recordId).Order("id desc").Find(&list)
+	return list
+}
+
+// 查询资产当前各字段的值，不记录变更的字段不返回，资产不存在时返回 false
+func (d *CmdbChangeLogDao) GetCurrentFields(recordType string, recordId uint) (map[string]string, bool) {
+	value, table, ok := changeRecordModel(recordType)
+	if !ok {
+		return nil, false
+	}
+	fields, exists := changelog.CurrentValues(d.db, value, recordId)
+	if !exists {
+		return nil, false
+	}
+	delete(fields, "id")
+	for column := range changelog.IgnoredColumns(table) {
+		delete(fields, column)
+	}
+	return fields, true
+}
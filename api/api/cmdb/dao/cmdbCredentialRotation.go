@@ -0,0 +1,130 @@
+// 主机凭证轮换 数据层
+// author xiaoRui
+
+package dao
+
+import (
+	"context"
+	"dodevops-api/api/cmdb/model"
+	"dodevops-api/common"
+	"dodevops-api/pkg/datascope"
+
+	"gorm.io/gorm"
+)
+
+type CmdbCredentialRotationDao struct {
+	db *gorm.DB
+}
+
+func init() {
+	// 轮换策略和执行记录按资产分组过滤数据权限
+	datascope.RegisterTable(model.CmdbCredentialRotation{}.TableName(), datascope.ByGroup("group_id"))
+	datascope.RegisterTable(model.CmdbCredentialRotationRun{}.TableName(), datascope.ByGroup("group_id"))
+}
+
+func NewCmdbCredentialRotationDao() CmdbCredentialRotationDao {
+	return CmdbCredentialRotationDao{
+		db: common.GetDB(),
+	}
+}
+
+// 绑定请求上下文，按当前用户的数据权限过滤
+func (d CmdbCredentialRotationDao) WithContext(ctx context.Context) *CmdbCredentialRotationDao {
+	return &CmdbCredentialRotationDao{db: d.db.WithContext(ctx)}
+}
+
+// 查询轮换策略列表
+func (d *CmdbCredentialRotationDao) GetRotationList() []model.CmdbCredentialRotation {
+	var list []model.CmdbCredentialRotation
+	d.db.Order("id").Find(&list)
+	return list
+}
+
+// 查询启用的定时轮换策略
+func (d *CmdbCredentialRotationDao) GetScheduledRotationList() []model.CmdbCredentialRotation {
+	var list []model.CmdbCredentialRotation
+	d.db.Where("status = ? AND cron_expr <> ''", 1).Find(&list)
+	return list
+}
+
+// 根据ID查询轮换策略
+func (d *CmdbCredentialRotationDao) GetRotationById(id uint) (model.CmdbCredentialRotation, error) {
+	var rotation model.CmdbCredentialRotation
+	err := d.db.Where("id = ?", id).First(&rotation).Error
+	return rotation, err
+}
+
+// 新增轮换策略
+func (d *CmdbCredentialRotationDao) CreateRotation(rotation *model.CmdbCredentialRotation) error {
+	return d.db.Create(rotation).Error
+}
+
+// 修改轮换策略
+func (d *CmdbCredentialRotationDao) UpdateRotation(rotation *model.CmdbCredentialRotation) error {
+	return d.db.Model(&model.CmdbCredentialRotation{}).Where("id = ?", rotation.ID).Select(
+		"name", "group_id", "mode", "key_type", "key_bits", "password_length", "cron_expr", "status", "remark").
+		Updates(rotation).Error
+}
+
+// 删除轮换策略
+func (d *CmdbCredentialRotationDao) DeleteRotation(id uint) error {
+	return d.db.Delete(&model.CmdbCredentialRotation{}, id).Error
+}
+
+// 更新上次执行时间
+func (d *CmdbCredentialRotationDao) UpdateLastRunTime(id uint, run *model.CmdbCredentialRotationRun) {
+	d.db.Model(&model.CmdbCredentialRotation{}).Where("id = ?", id).Update("last_run_time", run.StartTime)
+}
+
+// 新增执行记录
+func (d *CmdbCredentialRotationDao) CreateRun(run *model.CmdbCredentialRotationRun) error {
+	return d.db.Create(run).Error
+}
+
+// 保存执行结果
+func (d *CmdbCredentialRotationDao) FinishRun(run *model.CmdbCredentialRotationRun) error {
+	return d.db.Model(&model.CmdbCredentialRotationRun{}).Where("id = ?", run.ID).Updates(map[string]interface{}{
+		"status":        run.Status,
+		"total":         run.Total,
+		"success_count": run.SuccessCount,
+		"failed_count":  run.FailedCount,
+		"end_time":      run.EndTime,
+	}).Error
+}
+
+// 分页查询执行记录，rotationId 为 0 时查询全部
+func (d *CmdbCredentialRotationDao) GetRunListWithPage(rotationId uint, page, pageSize int) ([]model.CmdbCredentialRotationRun, int64) {
+	var list []model.CmdbCredentialRotationRun
+	var total int64
+	db := d.db.Model(&model.CmdbCredentialRotationRun{})
+	if rotationId > 0 {
+		db = db.Where("rotation_id = ?", rotationId)
+	}
+	db.Count(&total)
+	db.Order("id desc").Offset((page - 1) * pageSize).Limit(pageSize).Find(&list)
+	return list, total
+}
+
+// 根据ID查询执行记录
+func (d *CmdbCredentialRotationDao) GetRunById(id uint) (model.CmdbCredentialRotationRun, error) {
+	var run model.CmdbCredentialRotationRun
+	err := d.db.Where("id = ?", id).First(&run).Error
+	return run, err
+}
+
+// 新增主机报告
+func (d *CmdbCredentialRotationDao) CreateRunHost(host *model.CmdbCredentialRotationHost) error {
+	return d.db.Create(host).Error
+}
+
+// 查询执行记录的主机报告
+func (d *CmdbCredentialRotationDao) GetRunHostList(runId uint) []model.CmdbCredentialRotationHost {
+	var list []model.CmdbCredentialRotationHost
+	d.db.Where("run_id = ?", runId).Order("id").Find(&list)
+	return list
+}
+
+// 将主机的SSH凭证切换为新凭证
+func (d *CmdbCredentialRotationDao) UpdateHostSSHKey(hostId, authId uint) error {
+	return d.db.Model(&model.CmdbHost{}).Where("id = ?", hostId).Update("ssh_key_id", authId).Error
+}
@@ -0,0 +1,58 @@
+// 动态主机分组 数据层
+// author xiaoRui
+
+package dao
+
+import (
+	"dodevops-api/api/cmdb/model"
+	"dodevops-api/common"
+
+	"gorm.io/gorm"
+)
+
+type CmdbDynamicGroupDao struct {
+	db *gorm.DB
+}
+
+func NewCmdbDynamicGroupDao() CmdbDynamicGroupDao {
+	return CmdbDynamicGroupDao{
+		db: common.GetDB(),
+	}
+}
+
+// 查询全部动态分组
+func (d *CmdbDynamicGroupDao) GetDynamicGroupList() []model.CmdbDynamicGroup {
+	var list []model.CmdbDynamicGroup
+	d.db.Order("id").Find(&list)
+	return list
+}
+
+// 根据ID查询动态分组
+func (d *CmdbDynamicGroupDao) GetDynamicGroupById(id uint) (model.CmdbDynamicGroup, error) {
+	var group model.CmdbDynamicGroup
+	err := d.db.Where("id = ?", id).First(&group).Error
+	return group, err
+}
+
+// 检查名称是否已被其他动态分组使用
+func (d *CmdbDynamicGroupDao) CheckNameExists(name string, excludeId uint) bool {
+	var count int64
+	d.db.Model(&model.CmdbDynamicGroup{}).Where("name = ? AND id <> ?", name, excludeId).Count(&count)
+	return count > 0
+}
+
+// 新增动态分组
+func (d *CmdbDynamicGroupDao) CreateDynamicGroup(group *model.CmdbDynamicGroup) error {
+	return d.db.Create(group).Error
+}
+
+// 修改动态分组
+func (d *CmdbDynamicGroupDao) UpdateDynamicGroup(group *model.CmdbDynamicGroup) error {
+	return d.db.Model(&model.CmdbDynamicGroup{}).Where("id = ?", group.ID).
+		Updates(map[string]interface{}{"name": group.Name, "selector": group.Selector, "remark": group.Remark, "update_time": group.UpdateTime}).Error
+}
+
+// 删除动态分组
+func (d *CmdbDynamicGroupDao) DeleteDynamicGroup(id uint) error {
+	return d.db.Where("id = ?", id).Delete(&model.CmdbDynamicGroup{}).Error
+}
@@ -1,8 +1,10 @@
 package dao
 
 import (
+	"context"
 	"dodevops-api/api/cmdb/model"
 	"dodevops-api/common"
+	"dodevops-api/pkg/changelog"
 
 	"gorm.io/gorm"
 )
@@ -11,12 +13,22 @@ type CmdbGroupDao struct {
 	db *gorm.DB
 }
 
+func init() {
+	// 分组记录变更历史
+	changelog.RegisterTable(model.CmdbGroup{}.TableName(), model.ChangeRecordGroup)
+}
+
 func NewCmdbGroupDao() CmdbGroupDao {
 	return CmdbGroupDao{
 		db: common.GetDB(),
 	}
 }
 
+// 绑定请求上下文，变更历史按上下文记录操作人
+func (d CmdbGroupDao) WithContext(ctx context.Context) *CmdbGroupDao {
+	return &CmdbGroupDao{db: d.db.WithContext(ctx)}
+}
+
 func (d *CmdbGroupDao) GetCmdbGroupById(id uint) (model.CmdbGroup, error) {
 	var group model.CmdbGroup
 	err := d.db.Where("id = ?", id).First(&group).Error
@@ -43,6 +55,18 @@ func (d *CmdbGroupDao) UpdateCmdbGroup(id uint, group *model.CmdbGroup) error {
 	return d.db.Model(&model.CmdbGroup{}).Where("id = ?", id).Updates(group).Error
 }
 
+// 更新分组的跳板机，0 表示使用上级分组的跳板机
+func (d *CmdbGroupDao) UpdateCmdbGroupProxy(id, proxyHostId uint) error {
+	return d.db.Model(&model.CmdbGroup{}).Where("id = ?", id).Update("proxy_host_id", proxyHostId).Error
+}
+
+// 统计使用指定主机作为跳板机的分组数量
+func (d *CmdbGroupDao) CountCmdbGroupsByProxyHostId(proxyHostId uint) int64 {
+	var count int64
+	d.db.Model(&model.CmdbGroup{}).Where("proxy_host_id = ?", proxyHostId).Count(&count)
+	return count
+}
+
 func (d *CmdbGroupDao) DeleteCmdbGroup(id uint) error {
 	return d.db.Delete(&model.CmdbGroup{}, id).Error
 }
@@ -1,8 +1,11 @@
 package dao
 
 import (
+	"context"
 	"dodevops-api/api/cmdb/model"
 	"dodevops-api/common"
+	"dodevops-api/pkg/changelog"
+	"dodevops-api/pkg/datascope"
 	"time"
 
 	"gorm.io/gorm"
@@ -17,12 +20,29 @@ type CmdbHostDao struct {
 	db *gorm.DB
 }
 
+func init() {
+	// 主机按所属分组过滤数据权限
+	datascope.RegisterTable(model.CmdbHost{}.TableName(), datascope.ByGroup("group_id"))
+	// 主机记录变更历史，忽略更新时间和采集时频繁变化的状态
+	changelog.RegisterTable(model.CmdbHost{}.TableName(), model.ChangeRecordHost, "update_time", "status")
+}
+
 func NewCmdbHostDao() CmdbHostDao {
 	return CmdbHostDao{
 		db: common.GetDB(),
 	}
 }
 
+// 绑定请求上下文，按当前用户的数据权限过滤主机
+func (d CmdbHostDao) WithContext(ctx context.Context) *CmdbHostDao {
+	return &CmdbHostDao{db: d.db.WithContext(ctx)}
+}
+
+// 在事务中操作主机
+func (d CmdbHostDao) WithTx(tx *gorm.DB) *CmdbHostDao {
+	return &CmdbHostDao{db: tx}
+}
+
 // 获取主机列表(分页)
 func (d *CmdbHostDao) GetCmdbHostListWithPage(page, pageSize int) ([]model.CmdbHost, int64) {
 	var list []model.CmdbHost
@@ -50,6 +70,18 @@ func (d *CmdbHostDao) GetCmdbHostById(id uint) (model.CmdbHost, error) {
 	return host, err
 }
 
+// 根据SSH连接地址获取主机，多个主机使用相同地址时取最早创建的
+func (d *CmdbHostDao) GetCmdbHostByAddress(ip string, port int) (model.CmdbHost, error) {
+	var host model.CmdbHost
+	err := d.db.Where("ssh_ip = ? AND ssh_port = ?", ip, port).Order("id").First(&host).Error
+	return host, err
+}
+
+// 更新主机的跳板机，0 表示使用分组的跳板机
+func (d *CmdbHostDao) UpdateCmdbHostProxy(id, proxyHostId uint) error {
+	return d.db.Model(&model.CmdbHost{}).Where("id = ?", id).Update("proxy_host_id", proxyHostId).Error
+}
+
 // 根据名称获取主机
 func (d *CmdbHostDao) GetCmdbHostByName(name string) (model.CmdbHost, error) {
 	var host model.CmdbHost
@@ -81,6 +113,23 @@ func (d *CmdbHostDao) UpdateCmdbHost(id uint, host *model.CmdbHost) error {
 	return d.db.Model(&model.CmdbHost{}).Where("id = ?", id).Updates(host).Error
 }
 
+// 按列更新主机，与 UpdateCmdbHost 不同，可以把字段更新为空值
+func (d *CmdbHostDao) UpdateCmdbHostFields(id uint, fields map[string]interface{}) error {
+	return d.db.Model(&model.CmdbHost{}).Where("id = ?", id).Updates(fields).Error
+}
+
+// 统计使用指定主机作为跳板机的主机数量
+func (d *CmdbHostDao) CountCmdbHostsByProxyHostId(proxyHostId uint) int64 {
+	var count int64
+	d.db.Model(&model.CmdbHost{}).Where("proxy_host_id = ?", proxyHostId).Count(&count)
+	return count
+}
+
+// 更新主机的CI类型，0 表示不指定类型
+func (d *CmdbHostDao) UpdateCmdbHostCIType(id, ciTypeId uint) error {
+	return d.db.Model(&model.CmdbHost{}).Where("id = ?", id).Update("ci_type_id", ciTypeId).Error
+}
+
 // 删除主机
 func (d *CmdbHostDao) DeleteCmdbHost(id uint) error {
 	return d.db.Delete(&model.CmdbHost{}, id).Error
@@ -176,3 +225,34 @@ func (d *CmdbHostDao) GetCmdbHostsByIds(ids []uint) ([]model.CmdbHost, error) {
 	err := d.db.Preload("Group").Where("id IN ?", ids).Find(&hosts).Error
 	return hosts, err
 }
+
+// 按CI类型、自定义属性条件和关键字查询主机(分页)，关键字匹配主机名称、IP和自定义属性值
+func (d *CmdbHostDao) SearchCmdbHostsByAttrs(ciTypeId uint, filters []model.CmdbHostAttrFilter, keyword string, page, pageSize int) ([]model.CmdbHost, int64) {
+	var list []model.CmdbHost
+	var total int64
+	attrs := common.GetDB().Model(&model.CmdbHostAttr{}).Select("host_id")
+	db := d.db.Model(&model.CmdbHost{})
+	if ciTypeId > 0 {
+		db = db.Where("ci_type_id = ?", ciTypeId)
+	}
+	for _, filter := range filters {
+		switch filter.Operator {
+		case "!=":
+			db = db.Where("id NOT IN (?)", attrs.Session(&gorm.Session{}).Where("field_id = ? AND value = ?", filter.FieldID, filter.Value))
+		case "~":
+			db = db.Where("id IN (?)", attrs.Session(&gorm.Session{}).Where("field_id = ? AND value LIKE ?", filter.FieldID, "%"+filter.Value+"%"))
+		case ">=", "<=":
+			db = db.Where("id IN (?)", attrs.Session(&gorm.Session{}).Where("field_id = ? AND value "+filter.Operator+" ?", filter.FieldID, filter.Value))
+		default:
+			db = db.Where("id IN (?)", attrs.Session(&gorm.Session{}).Where("field_id = ? AND value = ?", filter.FieldID, filter.Value))
+		}
+	}
+	if keyword != "" {
+		like := "%" + keyword + "%"
+		db = db.Where("(host_name LIKE ? OR ssh_ip LIKE ? OR private_ip LIKE ? OR public_ip LIKE ? OR id IN (?))", like, like, like, like,
+			attrs.Session(&gorm.Session{}).Where("value LIKE ?", like))
+	}
+	db.Count(&total)
+	db.Preload("Group").Order("id").Offset((page - 1) * pageSize).Limit(pageSize).Find(&list)
+	return list, total
+}
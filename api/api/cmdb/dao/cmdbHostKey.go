@@ -0,0 +1,87 @@
+// 主机SSH密钥 数据层
+// author xiaoRui
+
+package dao
+
+import (
+	"dodevops-api/api/cmdb/model"
+	"dodevops-api/common"
+	"dodevops-api/common/util"
+
+	"gorm.io/gorm"
+)
+
+type CmdbHostKeyDao struct {
+	db *gorm.DB
+}
+
+func NewCmdbHostKeyDao() CmdbHostKeyDao {
+	return CmdbHostKeyDao{
+		db: common.GetDB(),
+	}
+}
+
+// 根据SSH连接地址查询主机，不存在时返回空主机
+func (d *CmdbHostKeyDao) GetHostByAddress(ip string, port int) model.CmdbHost {
+	var host model.CmdbHost
+	d.db.Select("id", "host_name").Where("ssh_ip = ? AND ssh_port = ?", ip, port).Order("id").Limit(1).Find(&host)
+	return host
+}
+
+// 查询主机的密钥记录，不在CMDB中的地址按连接地址查询
+func (d *CmdbHostKeyDao) GetHostKey(hostId uint, address string) (model.CmdbHostKey, error) {
+	var hostKey model.CmdbHostKey
+	db := d.db.Where("host_id = ?", hostId)
+	if hostId == 0 {
+		db = db.Where("address = ?", address)
+	}
+	err := db.Order("id").First(&hostKey).Error
+	return hostKey, err
+}
+
+// 根据ID查询密钥记录
+func (d *CmdbHostKeyDao) GetHostKeyById(id uint) (model.CmdbHostKey, error) {
+	var hostKey model.CmdbHostKey
+	err := d.db.Where("id = ?", id).First(&hostKey).Error
+	return hostKey, err
+}
+
+// 新增密钥记录
+func (d *CmdbHostKeyDao) CreateHostKey(hostKey *model.CmdbHostKey) error {
+	return d.db.Create(hostKey).Error
+}
+
+// 保存密钥记录
+func (d *CmdbHostKeyDao) SaveHostKey(hostKey *model.CmdbHostKey) error {
+	return d.db.Save(hostKey).Error
+}
+
+// 校验通过后更新连接地址和最后校验时间
+func (d *CmdbHostKeyDao) UpdateLastSeen(id uint, address string, seenTime util.HTime) {
+	d.db.Model(&model.CmdbHostKey{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"address":        address,
+		"last_seen_time": seenTime,
+	})
+}
+
+// 删除密钥记录
+func (d *CmdbHostKeyDao) DeleteHostKey(id uint) error {
+	return d.db.Delete(&model.CmdbHostKey{}, id).Error
+}
+
+// 分页查询密钥记录，附带主机名称
+func (d *CmdbHostKeyDao) GetHostKeyListWithPage(query model.CmdbHostKeyQueryDto) ([]model.CmdbHostKeyVo, int64) {
+	var list []model.CmdbHostKeyVo
+	var total int64
+	db := d.db.Table("cmdb_host_key k").Joins("LEFT JOIN cmdb_host h ON h.id = k.host_id")
+	if query.HostID > 0 {
+		db = db.Where("k.host_id = ?", query.HostID)
+	}
+	if query.Status > 0 {
+		db = db.Where("k.status = ?", query.Status)
+	}
+	db.Count(&total)
+	db.Select("k.*, h.host_name").Order("k.status desc, k.id desc").
+		Offset((query.Page - 1) * query.PageSize).Limit(query.PageSize).Scan(&list)
+	return list, total
+}
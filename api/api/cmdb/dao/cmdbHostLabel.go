@@ -0,0 +1,61 @@
+// 主机标签 数据层
+// author xiaoRui
+
+package dao
+
+import (
+	"dodevops-api/api/cmdb/model"
+	"dodevops-api/common"
+
+	"gorm.io/gorm"
+)
+
+type CmdbHostLabelDao struct {
+	db *gorm.DB
+}
+
+func NewCmdbHostLabelDao() CmdbHostLabelDao {
+	return CmdbHostLabelDao{
+		db: common.GetDB(),
+	}
+}
+
+// 在事务中操作主机标签
+func (d CmdbHostLabelDao) WithTx(tx *gorm.DB) *CmdbHostLabelDao {
+	return &CmdbHostLabelDao{db: tx}
+}
+
+// 查询主机的标签
+func (d *CmdbHostLabelDao) GetLabelsByHostId(hostId uint) []model.CmdbHostLabel {
+	var list []model.CmdbHostLabel
+	d.db.Where("host_id = ?", hostId).Order("label_key").Find(&list)
+	return list
+}
+
+// 查询多台主机的标签
+func (d *CmdbHostLabelDao) GetLabelsByHostIds(hostIds []uint) []model.CmdbHostLabel {
+	var list []model.CmdbHostLabel
+	if len(hostIds) == 0 {
+		return list
+	}
+	d.db.Where("host_id IN ?", hostIds).Find(&list)
+	return list
+}
+
+// 覆盖主机的全部标签
+func (d *CmdbHostLabelDao) ReplaceHostLabels(hostId uint, labels []model.CmdbHostLabel) error {
+	return d.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("host_id = ?", hostId).Delete(&model.CmdbHostLabel{}).Error; err != nil {
+			return err
+		}
+		if len(labels) == 0 {
+			return nil
+		}
+		return tx.Create(&labels).Error
+	})
+}
+
+// 删除主机的全部标签
+func (d *CmdbHostLabelDao) DeleteHostLabels(hostId uint) error {
+	return d.db.Where("host_id = ?", hostId).Delete(&model.CmdbHostLabel{}).Error
+}
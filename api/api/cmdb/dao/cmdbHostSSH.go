@@ -1,21 +1,30 @@
 package dao
 
 import (
+	"context"
 	"errors"
 	cmdbModel "dodevops-api/api/cmdb/model"
+	configDao "dodevops-api/api/configcenter/dao"
 	configModel "dodevops-api/api/configcenter/model"
 	"dodevops-api/common"
 )
 
-type CmdbHostSSHDao struct{}
+type CmdbHostSSHDao struct {
+	ctx context.Context
+}
 
 func NewCmdbHostSSHDao() *CmdbHostSSHDao {
-	return &CmdbHostSSHDao{}
+	return &CmdbHostSSHDao{ctx: context.Background()}
+}
+
+// 绑定请求上下文，按当前用户的数据权限过滤主机
+func (d *CmdbHostSSHDao) WithContext(ctx context.Context) *CmdbHostSSHDao {
+	return &CmdbHostSSHDao{ctx: ctx}
 }
 
 func (d *CmdbHostSSHDao) GetHostSSHInfo(hostID uint) (*cmdbModel.CmdbHost, error) {
 	var host cmdbModel.CmdbHost
-	if err := common.GetDB().Where("id = ?", hostID).First(&host).Error; err != nil {
+	if err := common.GetDB().WithContext(d.ctx).Where("id = ?", hostID).First(&host).Error; err != nil {
 		return nil, err
 	}
 	return &host, nil
@@ -26,6 +35,10 @@ func (d *CmdbHostSSHDao) GetSSHCredentials(keyID uint) (string, error) {
 	if err := common.GetDB().Table("config_ecsauth").Where("id = ?", keyID).First(&auth).Error; err != nil {
 		return "", err
 	}
+	if err := auth.ResolveSecrets(); err != nil {
+		return "", err
+	}
+	configDao.MarkEcsAuthUsed(keyID)
 
 	switch auth.Type {
 	case 1: // 密码认证
@@ -0,0 +1,165 @@
+// CMDB配置项关系 数据层
+// author xiaoRui
+
+package dao
+
+import (
+	appmodel "dodevops-api/api/app/model"
+	"dodevops-api/api/cmdb/model"
+	k8smodel "dodevops-api/api/k8s/model"
+	"dodevops-api/common"
+
+	"gorm.io/gorm"
+)
+
+type CmdbRelationDao struct {
+	db *gorm.DB
+}
+
+func NewCmdbRelationDao() CmdbRelationDao {
+	return CmdbRelationDao{
+		db: common.GetDB(),
+	}
+}
+
+// 根据ID查询关系
+func (d *CmdbRelationDao) GetRelationById(id uint) (model.CmdbRelation, error) {
+	var relation model.CmdbRelation
+	err := d.db.Where("id = ?", id).First(&relation).Error
+	return relation, err
+}
+
+// 检查关系是否已存在
+func (d *CmdbRelationDao) CheckRelationExists(relation model.CmdbRelation) bool {
+	var count int64
+	d.db.Model(&model.CmdbRelation{}).Where("source_type = ? AND source_id = ? AND target_type = ? AND target_id = ? AND relation_type = ?",
+		relation.SourceType, relation.SourceID, relation.TargetType, relation.TargetID, relation.RelationType).Count(&count)
+	return count > 0
+}
+
+// 新增关系
+func (d *CmdbRelationDao) CreateRelation(relation *model.CmdbRelation) error {
+	return d.db.Create(relation).Error
+}
+
+// 批量新增关系
+func (d *CmdbRelationDao) CreateRelations(relations []model.CmdbRelation) error {
+	if len(relations) == 0 {
+		return nil
+	}
+	return d.db.CreateInBatches(&relations, 100).Error
+}
+
+// 删除关系
+func (d *CmdbRelationDao) DeleteRelation(id uint) error {
+	return d.db.Where("id = ?", id).Delete(&model.CmdbRelation{}).Error
+}
+
+// 批量删除关系
+func (d *CmdbRelationDao) DeleteRelationsByIds(ids []uint) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	return d.db.Where("id IN ?", ids).Delete(&model.CmdbRelation{}).Error
+}
+
+// 删除配置项的全部关系，配置项被删除时调用
+func (d *CmdbRelationDao) DeleteRelationsByCI(kind string, id uint) error {
+	return d.db.Where("(source_type = ? AND source_id = ?) OR (target_type = ? AND target_id = ?)", kind, id, kind, id).
+		Delete(&model.CmdbRelation{}).Error
+}
+
+// 查询与一组同类配置项相连的全部关系（包括作为源和作为目标）
+func (d *CmdbRelationDao) GetRelationsByCIs(kind string, ids []uint) []model.CmdbRelation {
+	var list []model.CmdbRelation
+	if len(ids) == 0 {
+		return list
+	}
+	d.db.Where("(source_type = ? AND source_id IN ?) OR (target_type = ? AND target_id IN ?)", kind, ids, kind, ids).
+		Order("id").Find(&list)
+	return list
+}
+
+// 查询指向一组同类配置项的关系
+func (d *CmdbRelationDao) GetRelationsByTargets(kind string, ids []uint) []model.CmdbRelation {
+	var list []model.CmdbRelation
+	if len(ids) == 0 {
+		return list
+	}
+	d.db.Where("target_type = ? AND target_id IN ?", kind, ids).Order("id").Find(&list)
+	return list
+}
+
+// 查询一组同类配置项发出的关系
+func (d *CmdbRelationDao) GetRelationsBySources(kind string, ids []uint) []model.CmdbRelation {
+	var list []model.CmdbRelation
+	if len(ids) == 0 {
+		return list
+	}
+	d.db.Where("source_type = ? AND source_id IN ?", kind, ids).Order("id").Find(&list)
+	return list
+}
+
+// 查询全部关系
+func (d *CmdbRelationDao) GetAllRelations() []model.CmdbRelation {
+	var list []model.CmdbRelation
+	d.db.Order("id").Find(&list)
+	return list
+}
+
+// 查询配置项名称，不存在的配置项不会出现在结果中
+func (d *CmdbRelationDao) GetCINames(kind string, ids []uint) map[uint]string {
+	names := make(map[uint]string)
+	if len(ids) == 0 {
+		return names
+	}
+	var rows []struct {
+		ID   uint
+		Name string
+	}
+	switch kind {
+	case model.CIKindHost:
+		d.db.Model(&model.CmdbHost{}).Select("id, host_name AS name").Where("id IN ?", ids).Scan(&rows)
+	case model.CIKindDatabase:
+		d.db.Model(&model.CmdbSQL{}).Select("id, name").Where("id IN ?", ids).Scan(&rows)
+	case model.CIKindCluster:
+		d.db.Model(&k8smodel.KubeCluster{}).Select("id, name").Where("id IN ?", ids).Scan(&rows)
+	case model.CIKindApp:
+		d.db.Model(&appmodel.Application{}).Select("id, name").Where("id IN ?", ids).Scan(&rows)
+	}
+	for _, row := range rows {
+		names[row.ID] = row.Name
+	}
+	return names
+}
+
+// 查询全部应用及其关联的主机和数据库
+func (d *CmdbRelationDao) GetApplications() []appmodel.Application {
+	var list []appmodel.Application
+	d.db.Select("id, name, hosts, `databases`").Order("id").Find(&list)
+	return list
+}
+
+// 查询有发布成功记录的应用ID
+func (d *CmdbRelationDao) GetDeployedAppIds() []uint {
+	var ids []uint
+	d.db.Model(&appmodel.QuickDeploymentTask{}).Where("status = ?", 3).Distinct().Pluck("app_id", &ids)
+	return ids
+}
+
+// 查询配置了kubeconfig的集群
+func (d *CmdbRelationDao) GetClustersWithCredential() []k8smodel.KubeCluster {
+	var list []k8smodel.KubeCluster
+	d.db.Where("credential IS NOT NULL AND credential <> ''").Order("id").Find(&list)
+	return list
+}
+
+// 按SSH地址、内网IP或公网IP查询主机
+func (d *CmdbRelationDao) GetHostsByIPs(ips []string) []model.CmdbHost {
+	var list []model.CmdbHost
+	if len(ips) == 0 {
+		return list
+	}
+	d.db.Where("ssh_ip IN ? OR private_ip IN ? OR public_ip IN ?", ips, ips, ips).Order("id").Find(&list)
+	return list
+}
@@ -1,10 +1,17 @@
 package dao
 
 import (
+	"context"
 	"dodevops-api/api/cmdb/model"
+	"dodevops-api/pkg/changelog"
 	"gorm.io/gorm"
 )
 
+func init() {
+	// 数据库记录变更历史，忽略维护时间
+	changelog.RegisterTable(model.CmdbSQL{}.TableName(), model.ChangeRecordDatabase, "created_at", "updated_at")
+}
+
 type CmdbSQLDao struct {
 	db *gorm.DB
 }
@@ -13,6 +20,11 @@ func NewCmdbSQLDao(db *gorm.DB) *CmdbSQLDao {
 	return &CmdbSQLDao{db: db}
 }
 
+// WithContext 绑定请求上下文，变更历史按上下文记录操作人
+func (d *CmdbSQLDao) WithContext(ctx context.Context) *CmdbSQLDao {
+	return &CmdbSQLDao{db: d.db.WithContext(ctx)}
+}
+
 // Create 创建数据库记录
 func (d *CmdbSQLDao) Create(db *model.CmdbSQL) error {
 	return d.db.Create(db).Error
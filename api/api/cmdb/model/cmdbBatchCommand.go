@@ -0,0 +1,114 @@
+// 批量命令执行相关模型
+// author xiaoRui
+
+package model
+
+import "dodevops-api/common/util"
+
+// 批量命令执行状态
+const (
+	BatchCommandRunning = 1 // 执行中
+	BatchCommandSuccess = 2 // 全部成功
+	BatchCommandPartial = 3 // 部分成功
+	BatchCommandFailed  = 4 // 全部失败
+)
+
+// 单台主机执行状态
+const (
+	BatchCommandHostWaiting = 1 // 等待执行
+	BatchCommandHostRunning = 2 // 执行中
+	BatchCommandHostSuccess = 3 // 成功，退出码为0
+	BatchCommandHostFailed  = 4 // 失败，退出码非0
+	BatchCommandHostTimeout = 5 // 超时
+	BatchCommandHostError   = 6 // 连接失败等无法执行
+)
+
+// 批量命令执行记录：目标主机由主机ID、资产分组（含子分组）、标签选择器和动态分组合并得到，重新执行时按当前的分组和标签重新选择主机
+type CmdbBatchCommand struct {
+	ID              uint        `gorm:"column:id;comment:'主键';primaryKey;NOT NULL" json:"id"`                                    // ID
+	Command         string      `gorm:"column:command;type:text;comment:'命令';NOT NULL" json:"command"`                           // 命令
+	HostIds         string      `gorm:"column:host_ids;type:text;comment:'主机ID，逗号分隔'" json:"hostIds"`                            // 指定的主机ID，逗号分隔
+	GroupIds        string      `gorm:"column:group_ids;type:varchar(255);comment:'资产分组ID，逗号分隔'" json:"groupIds"`                // 指定的资产分组ID，逗号分隔
+	Selector        string      `gorm:"column:selector;type:varchar(500);comment:'标签选择器'" json:"selector"`                       // 标签选择器
+	DynamicGroupIds string      `gorm:"column:dynamic_group_ids;type:varchar(255);comment:'动态分组ID，逗号分隔'" json:"dynamicGroupIds"` // 指定的动态分组ID，逗号分隔
+	Concurrency     int         `gorm:"column:concurrency;comment:'并发数'" json:"concurrency"`                                     // 并发数
+	Timeout         int         `gorm:"column:timeout;comment:'单台主机超时时间(秒)'" json:"timeout"`                                     // 单台主机超时时间(秒)
+	RerunID         uint        `gorm:"column:rerun_id;default:0;comment:'重新执行的原记录ID'" json:"rerunId"`                           // 重新执行的原记录ID，0 表示首次执行
+	Operator        string      `gorm:"column:operator;type:varchar(64);comment:'操作人'" json:"operator"`                          // 操作人
+	DeptId          uint        `gorm:"column:dept_id;default:0;index;comment:'归属部门ID'" json:"deptId"`                           // 归属部门ID，执行时操作人所属部门
+	Status          int         `gorm:"column:status;comment:'状态:1->执行中,2->成功,3->部分成功,4->失败'" json:"status"`                     // 状态：1->执行中,2->成功,3->部分成功,4->失败
+	Total           int         `gorm:"column:total;comment:'主机数'" json:"total"`                                                 // 主机数
+	SuccessCount    int         `gorm:"column:success_count;comment:'成功数'" json:"successCount"`                                  // 成功数
+	FailedCount     int         `gorm:"column:failed_count;comment:'失败数'" json:"failedCount"`                                    // 失败数（含超时和无法执行）
+	StartTime       util.HTime  `gorm:"column:start_time;comment:'开始时间';NOT NULL" json:"startTime"`                              // 开始时间
+	EndTime         *util.HTime `gorm:"column:end_time;comment:'结束时间'" json:"endTime"`                                           // 结束时间
+}
+
+func (CmdbBatchCommand) TableName() string {
+	return "cmdb_batch_command"
+}
+
+// 批量命令单台主机执行结果
+type CmdbBatchCommandHost struct {
+	ID        uint        `gorm:"column:id;comment:'主键';primaryKey;NOT NULL" json:"id"`               // ID
+	CommandID uint        `gorm:"column:command_id;index;comment:'执行记录ID';NOT NULL" json:"commandId"` // 执行记录ID
+	HostID    uint        `gorm:"column:host_id;comment:'主机ID';NOT NULL" json:"hostId"`               // 主机ID
+	GroupID   uint        `gorm:"column:group_id;default:0;index;comment:'资产分组ID'" json:"groupId"`    // 执行时主机所在资产分组ID
+	HostName  string      `gorm:"column:host_name;type:varchar(64);comment:'主机名称'" json:"hostName"`   // 主机名称
+	SSHIP     string      `gorm:"column:ssh_ip;type:varchar(64);comment:'SSH连接IP'" json:"sshIp"`      // SSH连接IP
+	Status    int         `gorm:"column:status;comment:'状态'" json:"status"`                           // 状态：1->等待,2->执行中,3->成功,4->失败,5->超时,6->无法执行
+	ExitCode  int         `gorm:"column:exit_code;comment:'退出码'" json:"exitCode"`                     // 退出码，未能获取时为 -1
+	Output    string      `gorm:"column:output;type:mediumtext;comment:'输出'" json:"output"`           // 标准输出和标准错误
+	Message   string      `gorm:"column:message;type:varchar(500);comment:'执行信息'" json:"message"`     // 执行信息，如连接失败原因
+	Duration  int64       `gorm:"column:duration;comment:'耗时(毫秒)'" json:"duration"`                   // 耗时(毫秒)
+	StartTime *util.HTime `gorm:"column:start_time;comment:'开始时间'" json:"startTime"`                  // 开始时间
+	EndTime   *util.HTime `gorm:"column:end_time;comment:'结束时间'" json:"endTime"`                      // 结束时间
+}
+
+func (CmdbBatchCommandHost) TableName() string {
+	return "cmdb_batch_command_host"
+}
+
+// 批量执行命令参数，主机ID、资产分组、标签选择器和动态分组至少指定一项，选中的主机取并集
+type CmdbBatchCommandDto struct {
+	Command         string `json:"command"`         // 命令
+	HostIds         []uint `json:"hostIds"`         // 主机ID
+	GroupIds        []uint `json:"groupIds"`        // 资产分组ID，包含子分组
+	Selector        string `json:"selector"`        // 标签选择器，如 env=prod,role in (web,api)
+	DynamicGroupIds []uint `json:"dynamicGroupIds"` // 动态分组ID，按分组当前匹配的主机执行
+	Concurrency     int    `json:"concurrency"`     // 并发数，默认 10
+	Timeout         int    `json:"timeout"`         // 单台主机超时时间(秒)，默认 60
+	Confirmed       bool   `json:"confirmed"`       // 命中需要确认的命令规则时，确认执行
+}
+
+// 重新执行参数
+type CmdbBatchCommandRerunDto struct {
+	Id         uint `json:"id"`         // 执行记录ID
+	FailedOnly bool `json:"failedOnly"` // 只在上次未成功的主机上重新执行
+	Confirmed  bool `json:"confirmed"`  // 命中需要确认的命令规则时，确认执行
+}
+
+// 相同退出码的主机
+type CmdbBatchCommandExitCodeVo struct {
+	ExitCode int      `json:"exitCode"` // 退出码
+	Count    int      `json:"count"`    // 主机数
+	Hosts    []string `json:"hosts"`    // 主机名称
+}
+
+// 执行记录详情，包含每台主机的结果和按退出码汇总
+type CmdbBatchCommandVo struct {
+	CmdbBatchCommand
+	Hosts     []CmdbBatchCommandHost       `json:"hosts"`     // 主机执行结果
+	ExitCodes []CmdbBatchCommandExitCodeVo `json:"exitCodes"` // 按退出码汇总，未执行结束的主机不计入
+}
+
+// 批量命令实时输出事件，type 为 output（一行输出）、host（主机状态变化）或 complete（全部结束）
+type CmdbBatchCommandEvent struct {
+	Type     string              `json:"type"`               // 事件类型
+	HostID   uint                `json:"hostId,omitempty"`   // 主机ID
+	HostName string              `json:"hostName,omitempty"` // 主机名称
+	Data     string              `json:"data,omitempty"`     // 输出内容
+	Status   int                 `json:"status,omitempty"`   // 主机状态
+	ExitCode int                 `json:"exitCode"`           // 主机退出码
+	Result   *CmdbBatchCommandVo `json:"result,omitempty"`   // 执行结束时的汇总
+}
@@ -0,0 +1,92 @@
+// CI类型（自定义属性模型）相关模型
+// author xiaoRui
+
+package model
+
+import "dodevops-api/common/util"
+
+// 自定义属性类型
+const (
+	CIFieldString    = 1 // 字符串
+	CIFieldEnum      = 2 // 枚举，取值必须是可选值之一
+	CIFieldDate      = 3 // 日期，格式 2006-01-02
+	CIFieldReference = 4 // 引用其他主机，保存主机ID
+)
+
+// CI类型：管理员定义的资产模型，如物理服务器、网络设备，每种类型有自己的自定义属性
+type CmdbCIType struct {
+	ID         uint          `gorm:"column:id;comment:'主键';primaryKey;NOT NULL" json:"id"`                         // ID
+	Name       string        `gorm:"column:name;type:varchar(64);uniqueIndex;comment:'类型名称';NOT NULL" json:"name"` // 类型名称
+	Code       string        `gorm:"column:code;type:varchar(64);uniqueIndex;comment:'类型编码';NOT NULL" json:"code"` // 类型编码
+	Remark     string        `gorm:"column:remark;type:varchar(500);comment:'备注'" json:"remark"`                   // 备注
+	CreateTime util.HTime    `gorm:"column:create_time;comment:'创建时间';NOT NULL" json:"createTime"`                 // 创建时间
+	UpdateTime util.HTime    `gorm:"column:update_time;comment:'更新时间'" json:"updateTime"`                          // 更新时间
+	Fields     []CmdbCIField `gorm:"-" json:"fields"`                                                              // 自定义属性
+}
+
+func (CmdbCIType) TableName() string {
+	return "cmdb_ci_type"
+}
+
+// CI类型的自定义属性定义
+type CmdbCIField struct {
+	ID          uint       `gorm:"column:id;comment:'主键';primaryKey;NOT NULL" json:"id"`                                        // ID
+	CITypeID    uint       `gorm:"column:ci_type_id;uniqueIndex:uk_ci_field;comment:'CI类型ID';NOT NULL" json:"ciTypeId"`         // CI类型ID
+	Key         string     `gorm:"column:field_key;type:varchar(64);uniqueIndex:uk_ci_field;comment:'属性键';NOT NULL" json:"key"` // 属性键，创建后不可修改
+	Name        string     `gorm:"column:name;type:varchar(64);comment:'属性名称';NOT NULL" json:"name"`                            // 属性名称，也是Excel导入导出的列名
+	Type        int        `gorm:"column:type;comment:'类型:1->字符串,2->枚举,3->日期,4->引用主机';NOT NULL" json:"type"`                    // 类型:1->字符串,2->枚举,3->日期,4->引用主机，创建后不可修改
+	Required    bool       `gorm:"column:required;default:false;comment:'是否必填'" json:"required"`                                // 是否必填
+	Options     string     `gorm:"column:options;type:varchar(1000);comment:'枚举可选值，逗号分隔'" json:"options"`                       // 枚举可选值，逗号分隔
+	RefCITypeID uint       `gorm:"column:ref_ci_type_id;default:0;comment:'引用主机的CI类型ID'" json:"refCiTypeId"`                    // 引用属性只能引用该CI类型的主机，0 表示不限
+	Sort        int        `gorm:"column:sort;default:0;comment:'排序'" json:"sort"`                                              // 排序，数值小的在前
+	CreateTime  util.HTime `gorm:"column:create_time;comment:'创建时间';NOT NULL" json:"createTime"`                                // 创建时间
+}
+
+func (CmdbCIField) TableName() string {
+	return "cmdb_ci_field"
+}
+
+// 主机的自定义属性值，日期保存为 2006-01-02，引用保存为主机ID，便于按值查询
+type CmdbHostAttr struct {
+	ID      uint   `gorm:"column:id;comment:'主键';primaryKey;NOT NULL" json:"id"`                                                  // ID
+	HostID  uint   `gorm:"column:host_id;uniqueIndex:uk_host_attr;comment:'主机ID';NOT NULL" json:"hostId"`                         // 主机ID
+	FieldID uint   `gorm:"column:field_id;uniqueIndex:uk_host_attr;index:idx_field_value;comment:'属性ID';NOT NULL" json:"fieldId"` // 属性ID
+	Value   string `gorm:"column:value;type:varchar(500);index:idx_field_value;comment:'属性值'" json:"value"`                       // 属性值
+}
+
+func (CmdbHostAttr) TableName() string {
+	return "cmdb_host_attr"
+}
+
+// 新增/修改CI类型参数
+type CmdbCITypeDto struct {
+	Id     uint   `json:"id"`     // ID，修改时必填
+	Name   string `json:"name"`   // 类型名称
+	Code   string `json:"code"`   // 类型编码，字母、数字、下划线和中划线
+	Remark string `json:"remark"` // 备注
+}
+
+// 新增/修改自定义属性参数，修改时不能修改键和类型
+type CmdbCIFieldDto struct {
+	Id          uint     `json:"id"`          // ID，修改时必填
+	CITypeID    uint     `json:"ciTypeId"`    // CI类型ID
+	Key         string   `json:"key"`         // 属性键，字母开头，字母、数字和下划线
+	Name        string   `json:"name"`        // 属性名称
+	Type        int      `json:"type"`        // 类型:1->字符串,2->枚举,3->日期,4->引用主机
+	Required    bool     `json:"required"`    // 是否必填
+	Options     []string `json:"options"`     // 枚举可选值
+	RefCITypeID uint     `json:"refCiTypeId"` // 引用属性只能引用该CI类型的主机，0 表示不限
+	Sort        int      `json:"sort"`        // 排序
+}
+
+// CI类型或自定义属性ID参数
+type CmdbCITypeIdDto struct {
+	Id uint `json:"id"` // ID
+}
+
+// 主机自定义属性查询条件
+type CmdbHostAttrFilter struct {
+	FieldID  uint   // 属性ID
+	Operator string // =、!=、~（包含）、>=、<=
+	Value    string // 已按属性类型转换后的值
+}
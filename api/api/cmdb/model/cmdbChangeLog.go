@@ -0,0 +1,79 @@
+// CMDB资产变更历史相关模型
+// author xiaoRui
+
+package model
+
+import "dodevops-api/common/util"
+
+// 变更记录的资产类型
+const (
+	ChangeRecordHost     = "host"     // 主机 cmdb_host
+	ChangeRecordGroup    = "group"    // 资产分组 cmdb_group
+	ChangeRecordDatabase = "database" // 数据库 cmdb_sql
+)
+
+// 变更操作
+const (
+	ChangeActionCreate = "create" // 新增，NewValue 为新增后的完整记录(JSON)
+	ChangeActionUpdate = "update" // 修改，每个变化的字段一条记录
+	ChangeActionDelete = "delete" // 删除，OldValue 为删除前的完整记录(JSON)
+)
+
+// 变更来源
+const (
+	ChangeSourceUser   = "user"   // 用户操作
+	ChangeSourceCloud  = "cloud"  // 云平台同步
+	ChangeSourceAgent  = "agent"  // 主机信息采集
+	ChangeSourceSystem = "system" // 其他后台任务
+)
+
+// 资产字段级变更记录
+type CmdbChangeLog struct {
+	ID         uint       `gorm:"column:id;comment:'主键';primaryKey;NOT NULL" json:"id"`                                                 // ID
+	RecordType string     `gorm:"column:record_type;type:varchar(16);index:idx_change_record;comment:'资产类型';NOT NULL" json:"recordType"` // 资产类型:host、group、database
+	RecordID   uint       `gorm:"column:record_id;index:idx_change_record;comment:'资产ID';NOT NULL" json:"recordId"`                      // 资产ID
+	Action     string     `gorm:"column:action;type:varchar(16);comment:'操作:create、update、delete';NOT NULL" json:"action"`              // 操作:create、update、delete
+	Field      string     `gorm:"column:field;type:varchar(64);comment:'字段'" json:"field"`                                              // 字段(列名)，新增和删除为空
+	OldValue   string     `gorm:"column:old_value;type:text;comment:'旧值'" json:"oldValue"`                                               // 旧值
+	NewValue   string     `gorm:"column:new_value;type:text;comment:'新值'" json:"newValue"`                                               // 新值
+	Source     string     `gorm:"column:source;type:varchar(16);comment:'来源:user、cloud、agent、system'" json:"source"`                     // 来源:user、cloud、agent、system
+	Operator   string     `gorm:"column:operator;type:varchar(64);comment:'操作人'" json:"operator"`                                       // 操作人，用户操作为用户名，后台任务为任务说明
+	CreateTime util.HTime `gorm:"column:create_time;index;comment:'变更时间';NOT NULL" json:"createTime"`                                  // 变更时间
+}
+
+func (CmdbChangeLog) TableName() string {
+	return "cmdb_change_log"
+}
+
+// 查询变更历史参数
+type CmdbChangeLogQueryDto struct {
+	RecordType string `form:"type"`     // 资产类型:host、group、database
+	RecordID   uint   `form:"id"`       // 资产ID
+	Field      string `form:"field"`    // 字段(可选)
+	Source     string `form:"source"`   // 来源(可选)
+	Page       int    `form:"page"`     // 页码
+	PageSize   int    `form:"pageSize"` // 每页数量
+}
+
+// 资产在某一时刻的状态
+type CmdbSnapshotVo struct {
+	RecordType string            `json:"recordType"` // 资产类型
+	RecordID   uint              `json:"recordId"`   // 资产ID
+	Time       util.HTime        `json:"time"`       // 时间点
+	Exists     bool              `json:"exists"`     // 该时刻资产是否存在
+	Fields     map[string]string `json:"fields"`     // 各字段的值，键为列名
+}
+
+// 两个时刻之间变化的字段
+type CmdbSnapshotDiffItem struct {
+	Field string `json:"field"` // 字段(列名)
+	From  string `json:"from"`  // 起始时刻的值
+	To    string `json:"to"`    // 结束时刻的值
+}
+
+// 两个时刻的状态对比
+type CmdbSnapshotDiffVo struct {
+	From    CmdbSnapshotVo         `json:"from"`    // 起始时刻的状态
+	To      CmdbSnapshotVo         `json:"to"`      // 结束时刻的状态
+	Changes []CmdbSnapshotDiffItem `json:"changes"` // 变化的字段
+}
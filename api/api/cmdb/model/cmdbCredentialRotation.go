@@ -0,0 +1,106 @@
+// 主机凭证轮换相关模型
+// author xiaoRui
+
+package model
+
+import "dodevops-api/common/util"
+
+// 凭证轮换方式
+const (
+	CredentialRotationModePassword   = 1 // 轮换密码
+	CredentialRotationModeKey        = 2 // 轮换密钥
+	CredentialRotationModeDistribute = 3 // 分发已有密钥凭证的公钥
+)
+
+// 凭证轮换执行状态
+const (
+	CredentialRotationRunRunning = 1 // 执行中
+	CredentialRotationRunSuccess = 2 // 全部成功
+	CredentialRotationRunPartial = 3 // 部分成功
+	CredentialRotationRunFailed  = 4 // 全部失败
+)
+
+// 单台主机轮换结果
+const (
+	CredentialRotationHostSuccess        = 1 // 成功
+	CredentialRotationHostRolledBack     = 2 // 失败，已回滚
+	CredentialRotationHostRollbackFailed = 3 // 失败，回滚失败，需要人工处理
+	CredentialRotationHostFailed         = 4 // 失败，主机未做修改
+)
+
+// 凭证轮换策略：按 cron 表达式定时轮换分组（含子分组）内主机的密码或密钥
+type CmdbCredentialRotation struct {
+	ID             uint        `gorm:"column:id;comment:'主键';primaryKey;NOT NULL" json:"id"`                    // ID
+	Name           string      `gorm:"column:name;type:varchar(64);comment:'策略名称';NOT NULL" json:"name"`        // 策略名称
+	GroupID        uint        `gorm:"column:group_id;index;comment:'资产分组ID';NOT NULL" json:"groupId"`          // 资产分组ID，包含子分组
+	Mode           int         `gorm:"column:mode;comment:'轮换方式:1->密码,2->密钥';NOT NULL" json:"mode"`             // 轮换方式：1->密码,2->密钥
+	KeyType        string      `gorm:"column:key_type;type:varchar(16);comment:'密钥类型'" json:"keyType"`          // 密钥类型：ed25519、rsa
+	KeyBits        int         `gorm:"column:key_bits;comment:'RSA密钥长度'" json:"keyBits"`                        // RSA 密钥长度
+	PasswordLength int         `gorm:"column:password_length;comment:'密码长度'" json:"passwordLength"`             // 密码长度，默认 20
+	CronExpr       string      `gorm:"column:cron_expr;type:varchar(64);comment:'cron表达式'" json:"cronExpr"`     // cron 表达式，为空表示仅手动执行
+	Status         int         `gorm:"column:status;default:1;comment:'状态:1->启用,2->禁用';NOT NULL" json:"status"` // 状态：1->启用,2->禁用
+	LastRunTime    *util.HTime `gorm:"column:last_run_time;comment:'上次执行时间'" json:"lastRunTime"`                // 上次执行时间
+	Remark         string      `gorm:"column:remark;type:varchar(500);comment:'备注'" json:"remark"`              // 备注
+	CreateTime     util.HTime  `gorm:"column:create_time;comment:'创建时间';NOT NULL" json:"createTime"`            // 创建时间
+}
+
+func (CmdbCredentialRotation) TableName() string {
+	return "cmdb_credential_rotation"
+}
+
+// 凭证轮换执行记录
+type CmdbCredentialRotationRun struct {
+	ID           uint        `gorm:"column:id;comment:'主键';primaryKey;NOT NULL" json:"id"`                // ID
+	RotationID   uint        `gorm:"column:rotation_id;index;comment:'轮换策略ID'" json:"rotationId"`         // 轮换策略ID，分发公钥时为 0
+	GroupID      uint        `gorm:"column:group_id;index;comment:'资产分组ID';NOT NULL" json:"groupId"`      // 资产分组ID
+	Mode         int         `gorm:"column:mode;comment:'轮换方式';NOT NULL" json:"mode"`                     // 轮换方式：1->密码,2->密钥,3->分发公钥
+	Trigger      int         `gorm:"column:trigger_type;comment:'触发方式:1->手动,2->定时'" json:"trigger"`       // 触发方式：1->手动,2->定时
+	Operator     string      `gorm:"column:operator;type:varchar(64);comment:'操作人'" json:"operator"`      // 操作人
+	Status       int         `gorm:"column:status;comment:'状态:1->执行中,2->成功,3->部分成功,4->失败'" json:"status"` // 状态：1->执行中,2->成功,3->部分成功,4->失败
+	Total        int         `gorm:"column:total;comment:'主机数'" json:"total"`                             // 主机数
+	SuccessCount int         `gorm:"column:success_count;comment:'成功数'" json:"successCount"`              // 成功数
+	FailedCount  int         `gorm:"column:failed_count;comment:'失败数'" json:"failedCount"`                // 失败数
+	StartTime    util.HTime  `gorm:"column:start_time;comment:'开始时间';NOT NULL" json:"startTime"`          // 开始时间
+	EndTime      *util.HTime `gorm:"column:end_time;comment:'结束时间'" json:"endTime"`                       // 结束时间
+}
+
+func (CmdbCredentialRotationRun) TableName() string {
+	return "cmdb_credential_rotation_run"
+}
+
+// 凭证轮换主机报告
+type CmdbCredentialRotationHost struct {
+	ID         uint       `gorm:"column:id;comment:'主键';primaryKey;NOT NULL" json:"id"`             // ID
+	RunID      uint       `gorm:"column:run_id;index;comment:'执行记录ID';NOT NULL" json:"runId"`       // 执行记录ID
+	HostID     uint       `gorm:"column:host_id;comment:'主机ID';NOT NULL" json:"hostId"`             // 主机ID
+	HostName   string     `gorm:"column:host_name;type:varchar(64);comment:'主机名称'" json:"hostName"` // 主机名称
+	SSHIP      string     `gorm:"column:ssh_ip;type:varchar(64);comment:'SSH连接IP'" json:"sshIp"`    // SSH连接IP
+	OldAuthID  uint       `gorm:"column:old_auth_id;comment:'原凭证ID'" json:"oldAuthId"`              // 原凭证ID
+	NewAuthID  uint       `gorm:"column:new_auth_id;comment:'新凭证ID'" json:"newAuthId"`              // 新凭证ID
+	Status     int        `gorm:"column:status;comment:'结果'" json:"status"`                         // 结果：1->成功,2->失败已回滚,3->回滚失败,4->失败未修改
+	Message    string     `gorm:"column:message;type:text;comment:'执行信息'" json:"message"`           // 执行信息
+	Duration   int64      `gorm:"column:duration;comment:'耗时(毫秒)'" json:"duration"`                 // 耗时(毫秒)
+	CreateTime util.HTime `gorm:"column:create_time;comment:'创建时间';NOT NULL" json:"createTime"`     // 创建时间
+}
+
+func (CmdbCredentialRotationHost) TableName() string {
+	return "cmdb_credential_rotation_host"
+}
+
+// 轮换策略ID参数
+type CmdbCredentialRotationIdDto struct {
+	Id uint `json:"id"` // ID
+}
+
+// 分发公钥参数
+type CmdbCredentialDistributeDto struct {
+	GroupID    uint `json:"groupId"`    // 资产分组ID，包含子分组
+	EcsAuthID  uint `json:"ecsAuthId"`  // 密钥认证凭证ID
+	SwitchAuth bool `json:"switchAuth"` // 分发并验证成功后是否将主机的SSH凭证切换为该凭证
+}
+
+// 执行记录详情，包含每台主机的轮换报告
+type CmdbCredentialRotationRunVo struct {
+	CmdbCredentialRotationRun
+	Hosts []CmdbCredentialRotationHost `json:"hosts"` // 主机报告
+}
@@ -0,0 +1,47 @@
+// 动态主机分组相关模型
+// author xiaoRui
+
+package model
+
+import "dodevops-api/common/util"
+
+// 动态主机分组：保存标签选择器，成员在使用时按主机当前的标签计算，
+// 可作为Ansible任务、批量命令、Agent部署的目标以及告警规则的约束条件
+type CmdbDynamicGroup struct {
+	ID         uint       `gorm:"column:id;comment:'主键';primaryKey;NOT NULL" json:"id"`                         // ID
+	Name       string     `gorm:"column:name;type:varchar(64);uniqueIndex;comment:'分组名称';NOT NULL" json:"name"` // 分组名称
+	Selector   string     `gorm:"column:selector;type:varchar(500);comment:'标签选择器';NOT NULL" json:"selector"`   // 标签选择器，如 env=prod,role in (web,api)
+	Remark     string     `gorm:"column:remark;type:varchar(255);comment:'备注'" json:"remark"`                   // 备注
+	Operator   string     `gorm:"column:operator;type:varchar(64);comment:'创建人'" json:"operator"`               // 创建人
+	CreateTime util.HTime `gorm:"column:create_time;comment:'创建时间';NOT NULL" json:"createTime"`                 // 创建时间
+	UpdateTime util.HTime `gorm:"column:update_time;comment:'更新时间'" json:"updateTime"`                          // 更新时间
+	HostCount  int        `gorm:"-" json:"hostCount"`                                                           // 当前匹配的主机数（当前用户可见的主机）
+}
+
+func (CmdbDynamicGroup) TableName() string {
+	return "cmdb_dynamic_group"
+}
+
+// 新增/修改动态分组参数
+type CmdbDynamicGroupDto struct {
+	Id       uint   `json:"id"`       // ID，修改时必填
+	Name     string `json:"name"`     // 分组名称
+	Selector string `json:"selector"` // 标签选择器
+	Remark   string `json:"remark"`   // 备注
+}
+
+// 动态分组ID参数
+type CmdbDynamicGroupIdDto struct {
+	Id uint `json:"id"` // ID
+}
+
+// 按标签选择器匹配的主机
+type CmdbSelectedHostVo struct {
+	ID       uint              `json:"id"`       // 主机ID
+	HostName string            `json:"hostName"` // 主机名称
+	Name     string            `json:"name"`     // 系统主机名
+	GroupID  uint              `json:"groupId"`  // 资产分组ID
+	SSHIP    string            `json:"sshIp"`    // SSH连接IP
+	Status   int               `json:"status"`   // 状态
+	Labels   map[string]string `json:"labels"`   // 标签
+}
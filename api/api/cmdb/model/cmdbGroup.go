@@ -5,13 +5,15 @@ package model
 import "dodevops-api/common/util"
 
 type CmdbGroup struct {
-	ID         uint        `gorm:"column:id;comment:'主键';primaryKey;NOT NULL" json:"id"`                 // 主键ID
-	ParentID   uint        `gorm:"column:parent_id;default:0;comment:'父级分组ID';NOT NULL" json:"parentId"` // 父级分组ID（0 表示根分组）
-	Name       string      `gorm:"column:name;varchar(50);comment:'分组名称';NOT NULL" json:"name"`          // 分组名称
-	CreateTime util.HTime  `gorm:"column:create_time;comment:'创建时间';NOT NULL" json:"createTime"`         // 创建时间
-	Children   []CmdbGroup `json:"children" gorm:"-"`                                                    // 子分组（虚拟字段，用于树形展示）
-	Hosts      []CmdbHost  `gorm:"foreignKey:GroupID" json:"hosts"`                                      // 关联的主机列表
-	HostCount  int         `gorm:"-" json:"hostCount"`                                                   // 主机数量（虚拟字段，包含所有子分组的主机数量）
+	ID          uint        `gorm:"column:id;comment:'主键';primaryKey;NOT NULL" json:"id"`                 // 主键ID
+	ParentID    uint        `gorm:"column:parent_id;default:0;comment:'父级分组ID';NOT NULL" json:"parentId"` // 父级分组ID（0 表示根分组）
+	DeptId      uint        `gorm:"column:dept_id;default:0;comment:'归属部门ID'" json:"deptId"`              // 归属部门ID，按部门划分数据权限，0 表示未分配
+	Name        string      `gorm:"column:name;varchar(50);comment:'分组名称';NOT NULL" json:"name"`          // 分组名称
+	ProxyHostID uint        `gorm:"column:proxy_host_id;default:0;comment:'跳板机主机ID'" json:"proxyHostId"`  // 跳板机主机ID，分组内未单独配置跳板机的主机经过该跳板机连接，0 表示使用上级分组的跳板机
+	CreateTime  util.HTime  `gorm:"column:create_time;comment:'创建时间';NOT NULL" json:"createTime"`         // 创建时间
+	Children    []CmdbGroup `json:"children" gorm:"-"`                                                    // 子分组（虚拟字段，用于树形展示）
+	Hosts       []CmdbHost  `gorm:"foreignKey:GroupID" json:"hosts"`                                      // 关联的主机列表
+	HostCount   int         `gorm:"-" json:"hostCount"`                                                   // 主机数量（虚拟字段，包含所有子分组的主机数量）
 }
 
 func (CmdbGroup) TableName() string {
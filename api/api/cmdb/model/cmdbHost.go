@@ -16,6 +16,8 @@ type CmdbHost struct {
 	SSHName     string     `gorm:"column:ssh_name;varchar(64);comment:'SSH用户名'" json:"sshName"`
 	SSHKeyID    uint       `gorm:"column:ssh_key_id;comment:'SSH凭据ID'" json:"sshKeyId"`
 	SSHPort     int        `gorm:"column:ssh_port;comment:'SSH端口';default:22" json:"sshPort"`
+	ProxyHostID uint       `gorm:"column:proxy_host_id;default:0;comment:'跳板机主机ID'" json:"proxyHostId"` // 跳板机主机ID，0 表示使用分组的跳板机
+	CITypeID    uint       `gorm:"column:ci_type_id;default:0;index;comment:'CI类型ID'" json:"ciTypeId"`      // CI类型ID，0 表示未指定类型，没有自定义属性
 	Remark      string     `gorm:"column:remark;varchar(500);comment:'备注'" json:"remark"`
 	Vendor      int        `gorm:"column:vendor;varchar(32);comment:'1->自建,2->阿里云,3->腾讯云'" json:"vendor"`
 	Region      string     `gorm:"column:region;varchar(64);comment:'区域'" json:"region"`
@@ -44,7 +46,10 @@ type CreateCmdbHostDto struct {
 	SSHIP     string `validate:"required" json:"sshIp"`         // SSH连接IP(公网或私网IP)
 	SSHPort   int    `json:"sshPort"`                          // SSH端口(默认22)
 	SSHKeyID  uint   `validate:"required" json:"sshKeyId"`     // SSH凭据ID(从ecsAuth表获取)
+	ProxyHostID uint `json:"proxyHostId"`                      // 跳板机主机ID(可选，为空时使用分组的跳板机)
 	Remark    string `json:"remark"`                            // 备注信息(可选)
+	CITypeID  uint   `json:"ciTypeId"`                          // CI类型ID(可选)
+	Attrs     map[string]string `json:"attrs"`                // 自定义属性，键为属性键(可选)
 }
 
 // 更新主机DTO - 仅需提供必要连接信息
@@ -56,8 +61,11 @@ type UpdateCmdbHostDto struct {
 	SSHName    string `validate:"required" json:"sshName"`     // SSH登录用户名
 	SSHKeyID   uint   `validate:"required" json:"sshKeyId"`    // SSH凭据ID(从ecsAuth表获取)
 	SSHPort    int    `json:"sshPort"`                          // SSH端口(默认22)
+	ProxyHostID uint  `json:"proxyHostId"`                      // 跳板机主机ID(可选，为空时使用分组的跳板机)
 	Vendor     int    `json:"vendor"`                           // 厂商类型:1->自建,2->阿里云,3->腾讯云
 	Remark     string `json:"remark"`                           // 备注信息(可选)
+	CITypeID   uint   `json:"ciTypeId"`                         // CI类型ID，0 表示不指定类型
+	Attrs      map[string]string `json:"attrs"`                 // 自定义属性，不传时保留原有属性，传入时覆盖全部属性
 }
 
 // 主机ID DTO
@@ -94,6 +102,7 @@ type BatchImportTencentHostsDto struct {
 type ImportHostsFromExcelDto struct {
 	GroupID uint `validate:"required" json:"groupId"` // 分组ID
 	File    string `validate:"required" json:"file"`   // 上传的文件路径
+	CITypeID uint  `json:"ciTypeId"`                     // CI类型ID(可选)，指定时导入Excel中的自定义属性列
 }
 
 // Excel主机模板行数据
@@ -103,6 +112,7 @@ type ExcelHostTemplate struct {
 	SSHPort   int    // SSH端口
 	SSHName   string // SSH用户
 	Remark    string // 备注
+	Attrs     map[string]string // 自定义属性，按列名匹配CI类型的属性名称或属性键
 }
 
 // 主机VO
@@ -119,6 +129,10 @@ type CmdbHostVo struct {
 	SSHKeyID    uint       `json:"sshKeyId"`
 	SSHKeyName  string     `json:"sshKeyName"`
 	SSHPort     int        `json:"sshPort"`
+	ProxyHostID uint       `json:"proxyHostId"` // 跳板机主机ID
+	CITypeID    uint       `json:"ciTypeId"`    // CI类型ID
+	CITypeName  string     `json:"ciTypeName,omitempty"` // CI类型名称，仅详情返回
+	Attrs       map[string]string `json:"attrs,omitempty"` // 自定义属性，仅详情返回，引用属性为主机ID
 	Remark      string     `json:"remark"`    // 备注
 	Vendor      string     `json:"vendor"`
 	Region      string     `json:"region"`
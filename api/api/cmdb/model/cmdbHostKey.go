@@ -0,0 +1,69 @@
+// 主机SSH密钥相关模型
+// author xiaoRui
+
+package model
+
+import "dodevops-api/common/util"
+
+// 主机密钥状态
+const (
+	HostKeyStatusTrusted = 1 // 已信任
+	HostKeyStatusChanged = 2 // 密钥变更，等待管理员确认
+	HostKeyStatusPending = 3 // 未信任，严格模式下首次连接等待管理员确认
+)
+
+// 主机密钥来源
+const (
+	HostKeySourceTofu   = 1 // 首次连接自动信任
+	HostKeySourceAccept = 2 // 管理员确认
+	HostKeySourcePin    = 3 // 管理员手动固定
+)
+
+// 主机SSH密钥：每台主机记录一个信任的密钥，主机提供的密钥与之不一致时拒绝连接，新密钥记为待确认
+type CmdbHostKey struct {
+	ID                 uint        `gorm:"column:id;comment:'主键';primaryKey;NOT NULL" json:"id"`                                     // ID
+	HostID             uint        `gorm:"column:host_id;index;comment:'主机ID，0表示不在CMDB中的地址'" json:"hostId"`                          // 主机ID，0 表示不在 CMDB 中的地址
+	Address            string      `gorm:"column:address;type:varchar(128);index;comment:'连接地址';NOT NULL" json:"address"`            // 连接地址 ip:port
+	KeyType            string      `gorm:"column:key_type;type:varchar(64);comment:'密钥类型'" json:"keyType"`                           // 信任的密钥类型
+	PublicKey          string      `gorm:"column:public_key;type:text;comment:'信任的公钥'" json:"publicKey"`                             // 信任的公钥，authorized_keys 格式
+	Fingerprint        string      `gorm:"column:fingerprint;type:varchar(128);comment:'信任的公钥指纹'" json:"fingerprint"`                // 信任的公钥 SHA256 指纹
+	Source             int         `gorm:"column:source;comment:'来源:1->首次连接,2->管理员确认,3->手动固定'" json:"source"`                        // 来源：1->首次连接,2->管理员确认,3->手动固定
+	Status             int         `gorm:"column:status;comment:'状态:1->已信任,2->密钥变更,3->待确认';NOT NULL" json:"status"`                  // 状态：1->已信任,2->密钥变更,3->待确认
+	PendingKeyType     string      `gorm:"column:pending_key_type;type:varchar(64);comment:'待确认密钥类型'" json:"pendingKeyType"`         // 待确认的密钥类型
+	PendingPublicKey   string      `gorm:"column:pending_public_key;type:text;comment:'待确认公钥'" json:"pendingPublicKey"`              // 待确认的公钥
+	PendingFingerprint string      `gorm:"column:pending_fingerprint;type:varchar(128);comment:'待确认公钥指纹'" json:"pendingFingerprint"` // 待确认的公钥指纹
+	MismatchCount      int         `gorm:"column:mismatch_count;default:0;comment:'密钥不一致次数'" json:"mismatchCount"`                   // 密钥不一致被拒绝的次数
+	LastSeenTime       *util.HTime `gorm:"column:last_seen_time;comment:'最后校验通过时间'" json:"lastSeenTime"`                             // 最后校验通过时间
+	ChangedTime        *util.HTime `gorm:"column:changed_time;comment:'最近密钥不一致时间'" json:"changedTime"`                               // 最近密钥不一致时间
+	Operator           string      `gorm:"column:operator;type:varchar(64);comment:'确认或固定的操作人'" json:"operator"`                     // 确认或固定密钥的操作人
+	CreateTime         util.HTime  `gorm:"column:create_time;comment:'创建时间';NOT NULL" json:"createTime"`                             // 创建时间
+}
+
+func (CmdbHostKey) TableName() string {
+	return "cmdb_host_key"
+}
+
+// 主机密钥查询参数
+type CmdbHostKeyQueryDto struct {
+	HostID   uint `form:"hostId"`   // 主机ID
+	Status   int  `form:"status"`   // 状态
+	Page     int  `form:"page"`     // 页码
+	PageSize int  `form:"pageSize"` // 每页数量
+}
+
+// 主机密钥ID参数
+type CmdbHostKeyIdDto struct {
+	Id uint `json:"id"` // ID
+}
+
+// 固定主机密钥参数，公钥为空时扫描主机当前密钥固定
+type CmdbHostKeyPinDto struct {
+	HostID    uint   `json:"hostId"`    // 主机ID
+	PublicKey string `json:"publicKey"` // 公钥，authorized_keys 或 known_hosts 格式
+}
+
+// 主机密钥VO
+type CmdbHostKeyVo struct {
+	CmdbHostKey
+	HostName string `json:"hostName"` // 主机名称
+}
@@ -0,0 +1,22 @@
+// 主机标签相关模型
+// author xiaoRui
+
+package model
+
+// 主机标签：键值对，用于按标签选择器（如 env=prod,role in (web,api)）选择主机
+type CmdbHostLabel struct {
+	ID     uint   `gorm:"column:id;comment:'主键';primaryKey;NOT NULL" json:"id"`                                          // ID
+	HostID uint   `gorm:"column:host_id;uniqueIndex:uk_host_label;comment:'主机ID';NOT NULL" json:"hostId"`                // 主机ID
+	Key    string `gorm:"column:label_key;type:varchar(63);uniqueIndex:uk_host_label;comment:'标签键';NOT NULL" json:"key"` // 标签键
+	Value  string `gorm:"column:label_value;type:varchar(255);index;comment:'标签值'" json:"value"`                         // 标签值
+}
+
+func (CmdbHostLabel) TableName() string {
+	return "cmdb_host_label"
+}
+
+// 设置主机标签参数，覆盖主机原有的全部标签
+type CmdbHostLabelDto struct {
+	HostID uint              `json:"hostId"` // 主机ID
+	Labels map[string]string `json:"labels"` // 标签
+}
@@ -0,0 +1,59 @@
+// 主机完整导出和导入（按实例ID/SSH地址更新已有主机）相关模型
+// author xiaoRui
+
+package model
+
+// 导出和导入的文件格式
+const (
+	HostTransferXLSX = "xlsx"
+	HostTransferCSV  = "csv"
+	HostTransferJSON = "json"
+)
+
+// 导入时每一行的处理方式
+const (
+	HostImportCreate    = "create"    // 新增主机
+	HostImportUpdate    = "update"    // 更新已有主机
+	HostImportUnchanged = "unchanged" // 与已有主机一致，不修改
+	HostImportError     = "error"     // 校验失败，不导入
+)
+
+// 导出主机的筛选条件
+type CmdbHostExportQuery struct {
+	Format   string `form:"format"`   // 格式:xlsx、csv、json，默认 xlsx
+	GroupID  uint   `form:"groupId"`  // 分组ID，包含子分组
+	CITypeID uint   `form:"ciTypeId"` // CI类型ID
+	Status   int    `form:"status"`   // 状态:1->认证成功,2->未认证,3->认证失败
+	Keyword  string `form:"keyword"`  // 按名称、主机名和IP模糊匹配
+	Selector string `form:"selector"` // 标签选择器，如 env=prod,role in (web,api)
+}
+
+// 导入结果中的字段变化
+type CmdbHostImportChange struct {
+	Field string `json:"field"` // 字段，自定义属性为 attr.属性键
+	From  string `json:"from"`  // 原值，新增主机时为空
+	To    string `json:"to"`    // 新值
+}
+
+// 导入结果中的一行
+type CmdbHostImportRowVo struct {
+	Row      int                    `json:"row"`      // 行号，XLSX/CSV为文件中的行号（表头为第1行），JSON为数组中的序号（从1开始）
+	Action   string                 `json:"action"`   // 处理方式:create、update、unchanged、error
+	HostID   uint                   `json:"hostId"`   // 匹配到的主机ID，新增的主机在导入后返回ID
+	HostName string                 `json:"hostName"` // 主机名称
+	SSHIP    string                 `json:"sshIp"`    // SSH地址
+	MatchBy  string                 `json:"matchBy"`  // 匹配已有主机的字段:instanceId、sshIp
+	Changes  []CmdbHostImportChange `json:"changes"`  // 字段变化
+	Error    string                 `json:"error"`    // 错误信息
+}
+
+// 导入结果，预览时只计算变化，不修改数据
+type CmdbHostImportVo struct {
+	DryRun    bool                  `json:"dryRun"`    // 是否为预览
+	Total     int                   `json:"total"`     // 总行数
+	Created   int                   `json:"created"`   // 新增数
+	Updated   int                   `json:"updated"`   // 更新数
+	Unchanged int                   `json:"unchanged"` // 无变化数
+	Failed    int                   `json:"failed"`    // 失败数
+	Rows      []CmdbHostImportRowVo `json:"rows"`      // 每一行的处理结果
+}
@@ -0,0 +1,96 @@
+// CMDB配置项关系相关模型
+// author xiaoRui
+
+package model
+
+import "dodevops-api/common/util"
+
+// 配置项类型
+const (
+	CIKindHost     = "host"     // 主机 cmdb_host
+	CIKindDatabase = "database" // 数据库 cmdb_sql
+	CIKindCluster  = "cluster"  // K8s集群 k8s_cluster
+	CIKindApp      = "app"      // 应用 app_application
+)
+
+// 关系类型，方向均为 源 -> 目标
+const (
+	RelationRunsOn     = "runs-on"     // 源运行在目标上，如 应用 runs-on 主机
+	RelationDependsOn  = "depends-on"  // 源依赖目标，如 应用 depends-on 数据库
+	RelationBelongsTo  = "belongs-to"  // 源属于目标，如 主机 belongs-to 集群
+	RelationConnectsTo = "connects-to" // 源与目标网络互通，仅作记录，不参与影响分析
+)
+
+// 关系来源
+const (
+	RelationOriginManual = 1 // 手工维护
+	RelationOriginAuto   = 2 // 自动发现，重新发现时会按现有数据覆盖
+)
+
+// 配置项之间的有向关系
+type CmdbRelation struct {
+	ID           uint       `gorm:"column:id;comment:'主键';primaryKey;NOT NULL" json:"id"`                                                                               // ID
+	SourceType   string     `gorm:"column:source_type;type:varchar(16);uniqueIndex:uk_relation;comment:'源配置项类型';NOT NULL" json:"sourceType"`                            // 源配置项类型
+	SourceID     uint       `gorm:"column:source_id;uniqueIndex:uk_relation;comment:'源配置项ID';NOT NULL" json:"sourceId"`                                                 // 源配置项ID
+	TargetType   string     `gorm:"column:target_type;type:varchar(16);uniqueIndex:uk_relation;index:idx_relation_target;comment:'目标配置项类型';NOT NULL" json:"targetType"` // 目标配置项类型
+	TargetID     uint       `gorm:"column:target_id;uniqueIndex:uk_relation;index:idx_relation_target;comment:'目标配置项ID';NOT NULL" json:"targetId"`                      // 目标配置项ID
+	RelationType string     `gorm:"column:relation_type;type:varchar(32);uniqueIndex:uk_relation;comment:'关系类型';NOT NULL" json:"relationType"`                          // 关系类型
+	Origin       int        `gorm:"column:origin;default:1;comment:'来源:1->手工,2->自动发现'" json:"origin"`                                                                   // 来源:1->手工,2->自动发现
+	Remark       string     `gorm:"column:remark;type:varchar(255);comment:'备注'" json:"remark"`                                                                         // 备注，自动发现的关系记录发现依据
+	CreateTime   util.HTime `gorm:"column:create_time;comment:'创建时间';NOT NULL" json:"createTime"`                                                                       // 创建时间
+}
+
+func (CmdbRelation) TableName() string {
+	return "cmdb_relation"
+}
+
+// 新增关系参数
+type CmdbRelationDto struct {
+	SourceType   string `json:"sourceType"`   // 源配置项类型:host、database、cluster、app
+	SourceID     uint   `json:"sourceId"`     // 源配置项ID
+	TargetType   string `json:"targetType"`   // 目标配置项类型:host、database、cluster、app
+	TargetID     uint   `json:"targetId"`     // 目标配置项ID
+	RelationType string `json:"relationType"` // 关系类型:runs-on、depends-on、belongs-to、connects-to
+	Remark       string `json:"remark"`       // 备注
+}
+
+// 关系ID参数
+type CmdbRelationIdDto struct {
+	Id uint `json:"id"` // ID
+}
+
+// 关系图中的配置项
+type CmdbCINode struct {
+	Type  string `json:"type"`  // 配置项类型
+	ID    uint   `json:"id"`    // 配置项ID
+	Name  string `json:"name"`  // 配置项名称
+	Depth int    `json:"depth"` // 距起点的跳数
+}
+
+// 关系图，包含起点N跳内的配置项及它们之间的关系
+type CmdbRelationGraphVo struct {
+	Nodes     []CmdbCINode   `json:"nodes"`     // 配置项
+	Edges     []CmdbRelation `json:"edges"`     // 关系
+	Truncated bool           `json:"truncated"` // 配置项过多时停止展开
+}
+
+// 受影响的配置项
+type CmdbImpactNode struct {
+	CmdbCINode
+	Relation string   `json:"relation"` // 受影响的关系类型
+	Path     []string `json:"path"`     // 从故障配置项到该配置项经过的配置项名称
+}
+
+// 影响分析结果
+type CmdbImpactVo struct {
+	Root     CmdbCINode       `json:"root"`     // 故障配置项
+	Affected []CmdbImpactNode `json:"affected"` // 全部受影响的配置项，按跳数排序
+	Apps     []CmdbCINode     `json:"apps"`     // 受影响的应用
+}
+
+// 自动发现结果
+type CmdbRelationDiscoverVo struct {
+	Created int      `json:"created"` // 新增的关系数
+	Removed int      `json:"removed"` // 删除的过期关系数
+	Errors  []string `json:"errors"`  // 未能发现的数据源，如无法连接的集群
+}
@@ -1,6 +1,7 @@
 package service
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"strconv"
@@ -11,6 +12,7 @@ import (
 	"dodevops-api/api/cmdb/model"
 	"dodevops-api/common/result"
 	"dodevops-api/common/util"
+	"dodevops-api/pkg/changelog"
 
 	"github.com/gin-gonic/gin"
 )
@@ -40,7 +42,7 @@ func (s *CmdbHostCloudServiceImpl) CreateAliyunHost(c *gin.Context, dto *model.C
 	// 异步处理导入
 	go func() {
 		// 初始化DAO
-		hostDao := cmdbDao.NewCmdbHostDao()
+		hostDao := cloudSyncHostDao("阿里云导入")
 		
 		hosts, err := s.createAliyunHosts(dto)
 		if err != nil {
@@ -154,7 +156,7 @@ func (s *CmdbHostCloudServiceImpl) CreateTencentHost(c *gin.Context, dto *model.
 	// 异步处理导入
 	go func() {
 		// 初始化DAO
-		hostDao := cmdbDao.NewCmdbHostDao()
+		hostDao := cloudSyncHostDao("腾讯云导入")
 		
 		hosts, err := s.createTencentHosts(dto)
 		if err != nil {
@@ -259,7 +261,7 @@ func (s *CmdbHostCloudServiceImpl) CreateBaiduHost(c *gin.Context, dto *model.Cr
 	// 异步处理导入
 	go func() {
 		// 初始化DAO
-		hostDao := cmdbDao.NewCmdbHostDao()
+		hostDao := cloudSyncHostDao("百度云导入")
 
 		hosts, err := s.createBaiduHosts(dto)
 		if err != nil {
@@ -390,3 +392,9 @@ func GetCmdbHostCloudService() CmdbHostCloudServiceInterface {
 		hostDao: cmdbDao.NewCmdbHostDao(),
 	}
 }
+
+// 云平台导入主机使用的DAO，变更历史记为云平台同步
+func cloudSyncHostDao(operator string) *cmdbDao.CmdbHostDao {
+	hostDao := cmdbDao.NewCmdbHostDao()
+	return hostDao.WithContext(changelog.WithSource(context.Background(), model.ChangeSourceCloud, operator))
+}
@@ -0,0 +1,724 @@
+// 批量命令执行 服务层
+// author xiaoRui
+
+package service
+
+import (
+	"dodevops-api/api/cmdb/dao"
+	"dodevops-api/api/cmdb/model"
+	configDao "dodevops-api/api/configcenter/dao"
+	systemservice "dodevops-api/api/system/service"
+	"dodevops-api/common/result"
+	"dodevops-api/common/util"
+	wsutil "dodevops-api/common/util/websocket"
+	"dodevops-api/pkg/datascope"
+	"dodevops-api/pkg/log"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/crypto/ssh"
+)
+
+type CmdbBatchCommandServiceInterface interface {
+	ExecuteCommand(c *gin.Context, dto model.CmdbBatchCommandDto)    // 批量执行命令
+	RerunCommand(c *gin.Context, dto model.CmdbBatchCommandRerunDto) // 重新执行
+	GetCommandList(c *gin.Context, page, pageSize int)               // 执行记录列表
+	GetCommandInfo(c *gin.Context, id uint)                          // 执行记录详情
+	StreamCommand(c *gin.Context, id uint)                           // 实时输出(SSE)
+}
+
+type CmdbBatchCommandServiceImpl struct{}
+
+const (
+	defaultBatchConcurrency = 10
+	maxBatchConcurrency     = 50
+	defaultBatchTimeout     = 60   // 秒
+	maxBatchTimeout         = 3600 // 秒
+	batchOutputLimit        = 256 * 1024
+	batchEventBufferSize    = 1024
+)
+
+// 执行中的批量命令，用于推送实时输出，多实例部署时只能看到当前实例上执行的命令的实时输出
+var runningBatchCommands sync.Map
+
+// 服务启动时将上次未执行结束的记录标记为失败
+func InitCmdbBatchCommand() {
+	commandDao := dao.NewCmdbBatchCommandDao()
+	commandDao.AbortRunningCommands("服务重启，执行已中断")
+}
+
+// 批量执行命令，后台执行，返回执行记录
+func (s CmdbBatchCommandServiceImpl) ExecuteCommand(c *gin.Context, dto model.CmdbBatchCommandDto) {
+	if len(dto.HostIds) == 0 && len(dto.GroupIds) == 0 && strings.TrimSpace(dto.Selector) == "" && len(dto.DynamicGroupIds) == 0 {
+		result.Failed(c, int(result.ApiCode.FAILED), "请选择主机、资产分组、动态分组或填写标签选择器")
+		return
+	}
+	hosts, err := resolveBatchCommandHosts(c, dto.HostIds, dto.GroupIds, dto.DynamicGroupIds, dto.Selector)
+	if err != nil {
+		result.Failed(c, int(result.ApiCode.FAILED), err.Error())
+		return
+	}
+	record := &model.CmdbBatchCommand{
+		Command:         dto.Command,
+		HostIds:         joinIds(dto.HostIds),
+		GroupIds:        joinIds(dto.GroupIds),
+		Selector:        strings.TrimSpace(dto.Selector),
+		DynamicGroupIds: joinIds(dto.DynamicGroupIds),
+		Concurrency:     dto.Concurrency,
+		Timeout:         dto.Timeout,
+	}
+	startBatchCommand(c, record, hosts, dto.Confirmed)
+}
+
+// 重新执行：按原记录的目标重新选择主机，或只在上次未成功的主机上执行
+func (s CmdbBatchCommandServiceImpl) RerunCommand(c *gin.Context, dto model.CmdbBatchCommandRerunDto) {
+	commandDao := dao.NewCmdbBatchCommandDao().WithContext(c)
+	original, err := commandDao.GetCommandById(dto.Id)
+	if err != nil {
+		result.Failed(c, int(result.ApiCode.FAILED), "执行记录不存在")
+		return
+	}
+	var hosts []model.CmdbHost
+	if dto.FailedOnly {
+		if original.Status == model.BatchCommandRunning {
+			result.Failed(c, int(result.ApiCode.FAILED), "执行记录尚未结束")
+			return
+		}
+		var hostIds []uint
+		for _, host := range commandDao.GetCommandHostList(original.ID) {
+			if host.Status != model.BatchCommandHostSuccess {
+				hostIds = append(hostIds, host.HostID)
+			}
+		}
+		if len(hostIds) == 0 {
+			result.Failed(c, int(result.ApiCode.FAILED), "上次执行没有未成功的主机")
+			return
+		}
+		hosts, err = resolveBatchCommandHosts(c, hostIds, nil, nil, "")
+	} else {
+		hosts, err = resolveBatchCommandHosts(c, splitIds(original.HostIds), splitIds(original.GroupIds), splitIds(original.DynamicGroupIds), original.Selector)
+	}
+	if err != nil {
+		result.Failed(c, int(result.ApiCode.FAILED), err.Error())
+		return
+	}
+	record := &model.CmdbBatchCommand{
+		Command:         original.Command,
+		HostIds:         original.HostIds,
+		GroupIds:        original.GroupIds,
+		Selector:        original.Selector,
+		DynamicGroupIds: original.DynamicGroupIds,
+		Concurrency:     original.Concurrency,
+		Timeout:         original.Timeout,
+		RerunID:         original.ID,
+	}
+	if dto.FailedOnly {
+		record.HostIds, record.GroupIds, record.Selector, record.DynamicGroupIds = joinIds(hostIdsOf(hosts)), "", "", ""
+	}
+	startBatchCommand(c, record, hosts, dto.Confirmed)
+}
+
+// 分页查询执行记录
+func (s CmdbBatchCommandServiceImpl) GetCommandList(c *gin.Context, page, pageSize int) {
+	commandDao := dao.NewCmdbBatchCommandDao().WithContext(c)
+	list, total := commandDao.GetCommandListWithPage(page, pageSize)
+	result.Success(c, result.PageResult{List: list, Total: total, Page: page, PageSize: pageSize})
+}
+
+// 查询执行记录详情，包含每台主机的结果和按退出码汇总
+func (s CmdbBatchCommandServiceImpl) GetCommandInfo(c *gin.Context, id uint) {
+	commandDao := dao.NewCmdbBatchCommandDao().WithContext(c)
+	record, err := commandDao.GetCommandById(id)
+	if err != nil {
+		result.Failed(c, int(result.ApiCode.FAILED), "执行记录不存在")
+		return
+	}
+	result.Success(c, batchCommandVo(record, commandDao.GetCommandHostList(record.ID)))
+}
+
+// 通过SSE推送执行输出：先补发已有的输出，执行中时继续推送实时输出，全部主机结束后发送 complete 事件
+func (s CmdbBatchCommandServiceImpl) StreamCommand(c *gin.Context, id uint) {
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+
+	commandDao := dao.NewCmdbBatchCommandDao().WithContext(c)
+	record, err := commandDao.GetCommandById(id)
+	if err != nil {
+		fmt.Fprintf(c.Writer, "event: error\ndata: 执行记录不存在\n\n")
+		c.Writer.Flush()
+		return
+	}
+
+	// 只推送当前用户数据权限内的主机
+	hostList := commandDao.GetCommandHostList(record.ID)
+	visible := map[uint]bool{}
+	for _, host := range hostList {
+		visible[host.HostID] = true
+	}
+	var events chan model.CmdbBatchCommandEvent
+	var replay []model.CmdbBatchCommandEvent
+	if job, ok := runningBatchCommands.Load(id); ok {
+		events, replay = job.(*batchCommandJob).subscribe()
+		defer job.(*batchCommandJob).unsubscribe(events)
+	}
+	if events == nil {
+		vo := batchCommandVo(record, hostList)
+		replay = append(batchReplayEvents(vo.Hosts, nil), model.CmdbBatchCommandEvent{Type: "complete", Result: &vo})
+	}
+	for _, event := range replay {
+		if event, ok := visibleBatchEvent(event, visible); ok {
+			writeBatchEvent(c.Writer, event)
+		}
+	}
+	c.Writer.Flush()
+	if events == nil {
+		return
+	}
+
+	heartbeat := time.NewTicker(15 * time.Second)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-c.Request.Context().Done():
+			return
+		case event, ok := <-events:
+			if !ok {
+				return
+			}
+			if event, ok := visibleBatchEvent(event, visible); ok {
+				writeBatchEvent(c.Writer, event)
+				c.Writer.Flush()
+			}
+			if event.Type == "complete" {
+				return
+			}
+		case <-heartbeat.C:
+			fmt.Fprintf(c.Writer, ": heartbeat\n\n")
+			c.Writer.Flush()
+		}
+	}
+}
+
+// 过滤数据权限外的主机的事件，执行结束的汇总只保留可见的主机
+func visibleBatchEvent(event model.CmdbBatchCommandEvent, visible map[uint]bool) (model.CmdbBatchCommandEvent, bool) {
+	if event.HostID != 0 {
+		return event, visible[event.HostID]
+	}
+	if event.Result != nil {
+		hosts := make([]model.CmdbBatchCommandHost, 0, len(event.Result.Hosts))
+		for _, host := range event.Result.Hosts {
+			if visible[host.HostID] {
+				hosts = append(hosts, host)
+			}
+		}
+		vo := batchCommandVo(event.Result.CmdbBatchCommand, hosts)
+		event.Result = &vo
+	}
+	return event, true
+}
+
+func writeBatchEvent(w http.ResponseWriter, event model.CmdbBatchCommandEvent) {
+	data, _ := json.Marshal(event)
+	fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event.Type, data)
+}
+
+// 合并主机ID、资产分组（含子分组）、动态分组和标签选择器选中的主机，按当前用户的数据权限过滤
+func resolveBatchCommandHosts(c *gin.Context, hostIds, groupIds, dynamicGroupIds []uint, selector string) ([]model.CmdbHost, error) {
+	hostDao := dao.NewCmdbHostDao()
+	selected := map[uint]model.CmdbHost{}
+	if len(hostIds) > 0 {
+		hosts, err := hostDao.WithContext(c).GetCmdbHostsByIds(hostIds)
+		if err != nil {
+			return nil, err
+		}
+		for _, host := range hosts {
+			selected[host.ID] = host
+		}
+		for _, id := range hostIds {
+			if _, ok := selected[id]; !ok {
+				return nil, fmt.Errorf("主机(ID=%d)不存在或没有数据权限", id)
+			}
+		}
+	}
+	for _, groupId := range groupIds {
+		if !datascope.AllowGroup(c, groupId) {
+			return nil, fmt.Errorf("没有资产分组(ID=%d)的数据权限", groupId)
+		}
+		for _, host := range hostDao.WithContext(c).GetCmdbHostsByGroupId(groupId) {
+			selected[host.ID] = host
+		}
+	}
+	for _, dynamicGroupId := range dynamicGroupIds {
+		hosts, err := ResolveDynamicGroupHosts(c, dynamicGroupId)
+		if err != nil {
+			return nil, err
+		}
+		for _, host := range hosts {
+			selected[host.ID] = host
+		}
+	}
+	if strings.TrimSpace(selector) != "" {
+		hosts, err := selectHostsByLabels(c, selector)
+		if err != nil {
+			return nil, err
+		}
+		for _, host := range hosts {
+			selected[host.ID] = host
+		}
+	}
+	hosts := make([]model.CmdbHost, 0, len(selected))
+	for _, host := range selected {
+		hosts = append(hosts, host)
+	}
+	sort.Slice(hosts, func(i, j int) bool { return hosts[i].ID < hosts[j].ID })
+	return hosts, nil
+}
+
+// 校验参数和命令规则，创建执行记录并在后台执行
+func startBatchCommand(c *gin.Context, record *model.CmdbBatchCommand, hosts []model.CmdbHost, confirmed bool) {
+	record.Command = strings.TrimSpace(record.Command)
+	if record.Command == "" {
+		result.Failed(c, int(result.ApiCode.FAILED), "命令不能为空")
+		return
+	}
+	if len(hosts) == 0 {
+		result.Failed(c, int(result.ApiCode.FAILED), "没有选中任何主机")
+		return
+	}
+	if record.Concurrency <= 0 {
+		record.Concurrency = defaultBatchConcurrency
+	} else if record.Concurrency > maxBatchConcurrency {
+		record.Concurrency = maxBatchConcurrency
+	}
+	if record.Timeout <= 0 {
+		record.Timeout = defaultBatchTimeout
+	} else if record.Timeout > maxBatchTimeout {
+		record.Timeout = maxBatchTimeout
+	}
+	if err := checkBatchCommandRules(c, record.Command, hosts, confirmed); err != nil {
+		result.Failed(c, int(result.ApiCode.FAILED), err.Error())
+		return
+	}
+	record.Operator = operatorName(c)
+	record.DeptId = datascope.CurrentDept(c)
+	job, err := newBatchCommandJob(record, hosts)
+	if err != nil {
+		result.Failed(c, int(result.ApiCode.FAILED), err.Error())
+		return
+	}
+	go job.execute()
+	result.Success(c, job.record)
+}
+
+// 按主机所在分组检查终端命令规则：命中拦截规则时拒绝执行，命中需要确认的规则时需确认后执行
+func checkBatchCommandRules(c *gin.Context, command string, hosts []model.CmdbHost, confirmed bool) error {
+	checked := map[uint]bool{}
+	for _, host := range hosts {
+		if checked[host.GroupID] {
+			continue
+		}
+		checked[host.GroupID] = true
+		checker := systemservice.NewTerminalCommandChecker(c, host.GroupID, host.HostName)
+		if checker == nil {
+			continue
+		}
+		decision := checker.Check(command)
+		switch decision.Action {
+		case wsutil.CommandDeny:
+			checker.Report(command, decision, false)
+			return fmt.Errorf("命令在主机 %s 上被规则[%s]拦截", host.HostName, decision.Rule)
+		case wsutil.CommandConfirm:
+			if !confirmed {
+				return fmt.Errorf("命令命中规则[%s]，需要确认后执行", decision.Rule)
+			}
+			checker.Report(command, decision, true)
+		}
+	}
+	return nil
+}
+
+// 执行记录详情，按退出码汇总已结束的主机
+func batchCommandVo(record model.CmdbBatchCommand, hosts []model.CmdbBatchCommandHost) model.CmdbBatchCommandVo {
+	vo := model.CmdbBatchCommandVo{CmdbBatchCommand: record, Hosts: hosts, ExitCodes: []model.CmdbBatchCommandExitCodeVo{}}
+	index := map[int]int{}
+	for _, host := range hosts {
+		if host.EndTime == nil {
+			continue
+		}
+		i, ok := index[host.ExitCode]
+		if !ok {
+			i = len(vo.ExitCodes)
+			index[host.ExitCode] = i
+			vo.ExitCodes = append(vo.ExitCodes, model.CmdbBatchCommandExitCodeVo{ExitCode: host.ExitCode})
+		}
+		vo.ExitCodes[i].Count++
+		vo.ExitCodes[i].Hosts = append(vo.ExitCodes[i].Hosts, host.HostName)
+	}
+	sort.Slice(vo.ExitCodes, func(i, j int) bool { return vo.ExitCodes[i].ExitCode < vo.ExitCodes[j].ExitCode })
+	return vo
+}
+
+// 按已有的输出和状态生成补发事件，pending 为各主机尚未推送的不完整行
+func batchReplayEvents(hosts []model.CmdbBatchCommandHost, pending []string) []model.CmdbBatchCommandEvent {
+	var events []model.CmdbBatchCommandEvent
+	for i, host := range hosts {
+		output := host.Output
+		if pending != nil {
+			output = strings.TrimSuffix(output, pending[i])
+		}
+		if output != "" {
+			for _, line := range strings.Split(strings.TrimSuffix(output, "\n"), "\n") {
+				events = append(events, model.CmdbBatchCommandEvent{Type: "output", HostID: host.HostID, HostName: host.HostName,
+					Data: strings.TrimSuffix(line, "\r")})
+			}
+		}
+		if host.Status != model.BatchCommandHostWaiting {
+			events = append(events, batchHostEvent(host))
+		}
+	}
+	return events
+}
+
+func batchHostEvent(host model.CmdbBatchCommandHost) model.CmdbBatchCommandEvent {
+	return model.CmdbBatchCommandEvent{Type: "host", HostID: host.HostID, HostName: host.HostName,
+		Data: host.Message, Status: host.Status, ExitCode: host.ExitCode}
+}
+
+// 一次批量命令执行
+type batchCommandJob struct {
+	dao        dao.CmdbBatchCommandDao
+	ecsAuthDao configDao.EcsAuthDao
+	ssh        *util.SSHUtil
+	record     *model.CmdbBatchCommand
+	hosts      []model.CmdbHost
+
+	mu          sync.Mutex // 保护以下字段
+	results     []*model.CmdbBatchCommandHost
+	pending     []string // 各主机尚未输出完整的行
+	truncated   []bool
+	finished    bool
+	subscribers map[chan model.CmdbBatchCommandEvent]bool
+}
+
+// 创建执行记录和主机结果
+func newBatchCommandJob(record *model.CmdbBatchCommand, hosts []model.CmdbHost) (*batchCommandJob, error) {
+	job := &batchCommandJob{
+		dao:         dao.NewCmdbBatchCommandDao(),
+		ecsAuthDao:  configDao.NewEcsAuthDao(),
+		ssh:         util.NewSSHUtil(),
+		record:      record,
+		hosts:       hosts,
+		pending:     make([]string, len(hosts)),
+		truncated:   make([]bool, len(hosts)),
+		subscribers: map[chan model.CmdbBatchCommandEvent]bool{},
+	}
+	record.ID = 0
+	record.Status = model.BatchCommandRunning
+	record.Total = len(hosts)
+	record.StartTime = util.HTime{Time: time.Now()}
+	for _, host := range hosts {
+		job.results = append(job.results, &model.CmdbBatchCommandHost{
+			HostID:   host.ID,
+			GroupID:  host.GroupID,
+			HostName: host.HostName,
+			SSHIP:    host.SSHIP,
+			Status:   model.BatchCommandHostWaiting,
+			ExitCode: -1,
+		})
+	}
+	if err := job.dao.CreateCommand(record, job.results); err != nil {
+		return nil, err
+	}
+	runningBatchCommands.Store(record.ID, job)
+	return job, nil
+}
+
+// 按并发数在各主机上执行命令，全部结束后保存汇总结果
+func (j *batchCommandJob) execute() {
+	defer runningBatchCommands.Delete(j.record.ID)
+
+	slots := make(chan struct{}, j.record.Concurrency)
+	var wg sync.WaitGroup
+	for i := range j.hosts {
+		slots <- struct{}{}
+		wg.Add(1)
+		go func(i int) {
+			defer func() {
+				<-slots
+				wg.Done()
+			}()
+			j.runHost(i)
+		}(i)
+	}
+	wg.Wait()
+
+	j.mu.Lock()
+	switch {
+	case j.record.FailedCount == 0:
+		j.record.Status = model.BatchCommandSuccess
+	case j.record.SuccessCount == 0:
+		j.record.Status = model.BatchCommandFailed
+	default:
+		j.record.Status = model.BatchCommandPartial
+	}
+	j.record.EndTime = &util.HTime{Time: time.Now()}
+	if err := j.dao.FinishCommand(j.record); err != nil {
+		log.Log().Errorf("保存批量命令执行结果失败: %v", err)
+	}
+	hosts := make([]model.CmdbBatchCommandHost, 0, len(j.results))
+	for _, host := range j.results {
+		hosts = append(hosts, *host)
+	}
+	vo := batchCommandVo(*j.record, hosts)
+	j.publish(model.CmdbBatchCommandEvent{Type: "complete", Result: &vo})
+	j.finished = true
+	for events := range j.subscribers {
+		delete(j.subscribers, events)
+		close(events)
+	}
+	j.mu.Unlock()
+	log.Log().Infof("批量命令 #%d 执行完成：%d 台主机，成功 %d 台，失败 %d 台", j.record.ID, j.record.Total, j.record.SuccessCount, j.record.FailedCount)
+}
+
+// 在单台主机上执行命令并保存结果
+func (j *batchCommandJob) runHost(i int) {
+	start := time.Now()
+	j.mu.Lock()
+	hostResult := j.results[i]
+	hostResult.Status = model.BatchCommandHostRunning
+	hostResult.StartTime = &util.HTime{Time: start}
+	j.publish(batchHostEvent(*hostResult))
+	snapshot := *hostResult
+	j.mu.Unlock()
+	if err := j.dao.UpdateCommandHost(&snapshot); err != nil {
+		log.Log().Errorf("保存主机 %s 批量命令状态失败: %v", snapshot.HostName, err)
+	}
+
+	status, exitCode, message := j.execHost(i)
+
+	j.mu.Lock()
+	if j.pending[i] != "" {
+		j.publishLine(i, j.pending[i])
+		j.pending[i] = ""
+	}
+	hostResult.Status, hostResult.ExitCode, hostResult.Message = status, exitCode, message
+	hostResult.Duration = time.Since(start).Milliseconds()
+	hostResult.EndTime = &util.HTime{Time: time.Now()}
+	if status == model.BatchCommandHostSuccess {
+		j.record.SuccessCount++
+	} else {
+		j.record.FailedCount++
+	}
+	j.publish(batchHostEvent(*hostResult))
+	snapshot = *hostResult
+	j.mu.Unlock()
+	if err := j.dao.UpdateCommandHost(&snapshot); err != nil {
+		log.Log().Errorf("保存主机 %s 批量命令结果失败: %v", snapshot.HostName, err)
+	}
+}
+
+// 连接主机并执行命令，超时后断开连接，返回主机状态、退出码和执行信息
+func (j *batchCommandJob) execHost(i int) (int, int, string) {
+	host := j.hosts[i]
+	if host.SSHKeyID == 0 {
+		return model.BatchCommandHostError, -1, "主机未配置SSH凭据"
+	}
+	auth, err := j.ecsAuthDao.GetById(host.SSHKeyID)
+	if err != nil {
+		return model.BatchCommandHostError, -1, "SSH凭据不存在"
+	}
+	if err := auth.ResolveSecrets(); err != nil {
+		return model.BatchCommandHostError, -1, "读取SSH凭据失败: " + err.Error()
+	}
+
+	type outcome struct {
+		status, exitCode int
+		message          string
+	}
+	finished := make(chan outcome, 1)
+	var mu sync.Mutex
+	var client *ssh.Client
+	timedOut := false
+	go func() {
+		conn, err := j.ssh.TerminalLogin(hostSSHConfig(host, &auth))
+		if err != nil {
+			finished <- outcome{model.BatchCommandHostError, -1, "连接失败: " + err.Error()}
+			return
+		}
+		mu.Lock()
+		if timedOut {
+			mu.Unlock()
+			conn.Close()
+			return
+		}
+		client = conn
+		mu.Unlock()
+		defer conn.Close()
+		configDao.MarkEcsAuthUsed(auth.ID)
+
+		session, err := conn.NewSession()
+		if err != nil {
+			finished <- outcome{model.BatchCommandHostError, -1, "创建SSH会话失败: " + err.Error()}
+			return
+		}
+		defer session.Close()
+		writer := &batchOutputWriter{job: j, index: i}
+		session.Stdout, session.Stderr = writer, writer
+		err = session.Run(j.record.Command)
+		var exitErr *ssh.ExitError
+		var missingErr *ssh.ExitMissingError
+		switch {
+		case err == nil:
+			finished <- outcome{model.BatchCommandHostSuccess, 0, ""}
+		case errors.As(err, &exitErr):
+			message := ""
+			if exitErr.Signal() != "" {
+				message = "被信号 " + exitErr.Signal() + " 终止"
+			}
+			finished <- outcome{model.BatchCommandHostFailed, exitErr.ExitStatus(), message}
+		case errors.As(err, &missingErr):
+			finished <- outcome{model.BatchCommandHostFailed, -1, "未获取到退出码"}
+		default:
+			finished <- outcome{model.BatchCommandHostError, -1, "执行失败: " + err.Error()}
+		}
+	}()
+
+	timer := time.NewTimer(time.Duration(j.record.Timeout) * time.Second)
+	defer timer.Stop()
+	select {
+	case o := <-finished:
+		return o.status, o.exitCode, o.message
+	case <-timer.C:
+		mu.Lock()
+		timedOut = true
+		if client != nil {
+			client.Close()
+		}
+		mu.Unlock()
+		return model.BatchCommandHostTimeout, -1, fmt.Sprintf("执行超过 %d 秒，已中断", j.record.Timeout)
+	}
+}
+
+// 保存主机输出并按行推送，超过长度限制的输出不再保存
+func (j *batchCommandJob) output(i int, p []byte) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	hostResult := j.results[i]
+	if hostResult.EndTime != nil {
+		return // 已超时
+	}
+	data := string(p)
+	if remain := batchOutputLimit - len(hostResult.Output); remain < len(data) {
+		if !j.truncated[i] {
+			cut := remain
+			if cut < 0 {
+				cut = 0
+			}
+			for cut > 0 && !utf8.RuneStart(data[cut]) {
+				cut--
+			}
+			hostResult.Output += data[:cut] + "\n...(输出过长，已截断)\n"
+			j.truncated[i] = true
+		}
+	} else {
+		hostResult.Output += data
+	}
+	lines := strings.Split(j.pending[i]+data, "\n")
+	for _, line := range lines[:len(lines)-1] {
+		j.publishLine(i, line)
+	}
+	j.pending[i] = lines[len(lines)-1]
+}
+
+func (j *batchCommandJob) publishLine(i int, line string) {
+	j.publish(model.CmdbBatchCommandEvent{Type: "output", HostID: j.results[i].HostID, HostName: j.results[i].HostName,
+		Data: strings.TrimSuffix(line, "\r")})
+}
+
+// 推送事件给订阅者，接收过慢的订阅者会被断开，调用方需持有 mu 锁
+func (j *batchCommandJob) publish(event model.CmdbBatchCommandEvent) {
+	for events := range j.subscribers {
+		select {
+		case events <- event:
+		default:
+			delete(j.subscribers, events)
+			close(events)
+		}
+	}
+}
+
+// 订阅实时输出，返回已有输出的补发事件，执行已结束时返回 nil
+func (j *batchCommandJob) subscribe() (chan model.CmdbBatchCommandEvent, []model.CmdbBatchCommandEvent) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.finished {
+		return nil, nil
+	}
+	hosts := make([]model.CmdbBatchCommandHost, 0, len(j.results))
+	for _, host := range j.results {
+		hosts = append(hosts, *host)
+	}
+	events := make(chan model.CmdbBatchCommandEvent, batchEventBufferSize)
+	j.subscribers[events] = true
+	return events, batchReplayEvents(hosts, j.pending)
+}
+
+func (j *batchCommandJob) unsubscribe(events chan model.CmdbBatchCommandEvent) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.subscribers[events] {
+		delete(j.subscribers, events)
+		close(events)
+	}
+}
+
+// 主机的标准输出和标准错误
+type batchOutputWriter struct {
+	job   *batchCommandJob
+	index int
+}
+
+func (w *batchOutputWriter) Write(p []byte) (int, error) {
+	w.job.output(w.index, p)
+	return len(p), nil
+}
+
+func hostIdsOf(hosts []model.CmdbHost) []uint {
+	ids := make([]uint, 0, len(hosts))
+	for _, host := range hosts {
+		ids = append(ids, host.ID)
+	}
+	return ids
+}
+
+func joinIds(ids []uint) string {
+	values := make([]string, 0, len(ids))
+	for _, id := range ids {
+		values = append(values, strconv.Itoa(int(id)))
+	}
+	return strings.Join(values, ",")
+}
+
+func splitIds(value string) []uint {
+	var ids []uint
+	for _, part := range strings.Split(value, ",") {
+		if id, err := strconv.Atoi(strings.TrimSpace(part)); err == nil && id > 0 {
+			ids = append(ids, uint(id))
+		}
+	}
+	return ids
+}
+
+func GetCmdbBatchCommandService() CmdbBatchCommandServiceInterface {
+	return CmdbBatchCommandServiceImpl{}
+}
@@ -0,0 +1,511 @@
+// CI类型（自定义属性模型） 服务层
+// author xiaoRui
+
+package service
+
+import (
+	"dodevops-api/api/cmdb/dao"
+	"dodevops-api/api/cmdb/model"
+	"dodevops-api/common/result"
+	"dodevops-api/common/util"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+)
+
+type CmdbCITypeServiceInterface interface {
+	GetCITypeList(c *gin.Context)                                                                    // CI类型列表
+	CreateCIType(c *gin.Context, dto model.CmdbCITypeDto)                                            // 新增CI类型
+	UpdateCIType(c *gin.Context, dto model.CmdbCITypeDto)                                            // 修改CI类型
+	DeleteCIType(c *gin.Context, id uint)                                                            // 删除CI类型
+	CreateField(c *gin.Context, dto model.CmdbCIFieldDto)                                            // 新增自定义属性
+	UpdateField(c *gin.Context, dto model.CmdbCIFieldDto)                                            // 修改自定义属性
+	DeleteField(c *gin.Context, id uint)                                                             // 删除自定义属性
+	SearchHosts(c *gin.Context, ciTypeId uint, filters []string, keyword string, page, pageSize int) // 按自定义属性查询主机
+}
+
+type CmdbCITypeServiceImpl struct{}
+
+var (
+	ciTypeCodeRegexp = regexp.MustCompile(`^[A-Za-z][-A-Za-z0-9_]{0,63}$`)
+	ciFieldKeyRegexp = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_]{0,63}$`)
+)
+
+// 主机导入导出Excel的固定列，自定义属性名称不能与之相同
+var hostExcelColumns = []string{"主机别名", "SSH地址", "SSH端口", "SSH用户", "备注", "分组", "内网IP", "公网IP", "操作系统"}
+
+// 日期属性支持的输入格式，统一保存为 2006-01-02
+var ciDateLayouts = []string{"2006-01-02", "2006/01/02", "2006-1-2", "2006/1/2"}
+
+// 查询CI类型列表，包含自定义属性
+func (s CmdbCITypeServiceImpl) GetCITypeList(c *gin.Context) {
+	ciTypeDao := dao.NewCmdbCITypeDao()
+	result.Success(c, ciTypeDao.GetCITypeList())
+}
+
+// 新增CI类型
+func (s CmdbCITypeServiceImpl) CreateCIType(c *gin.Context, dto model.CmdbCITypeDto) {
+	ciType, err := checkCIType(dto, 0)
+	if err != nil {
+		result.Failed(c, int(result.ApiCode.FAILED), err.Error())
+		return
+	}
+	ciType.CreateTime = util.HTime{Time: time.Now()}
+	ciTypeDao := dao.NewCmdbCITypeDao()
+	if err := ciTypeDao.CreateCIType(&ciType); err != nil {
+		result.Failed(c, int(result.ApiCode.FAILED), err.Error())
+		return
+	}
+	ciType.Fields = []model.CmdbCIField{}
+	result.Success(c, ciType)
+}
+
+// 修改CI类型
+func (s CmdbCITypeServiceImpl) UpdateCIType(c *gin.Context, dto model.CmdbCITypeDto) {
+	ciTypeDao := dao.NewCmdbCITypeDao()
+	if _, err := ciTypeDao.GetCITypeById(dto.Id); err != nil {
+		result.Failed(c, int(result.ApiCode.FAILED), "CI类型不存在")
+		return
+	}
+	ciType, err := checkCIType(dto, dto.Id)
+	if err != nil {
+		result.Failed(c, int(result.ApiCode.FAILED), err.Error())
+		return
+	}
+	ciType.ID = dto.Id
+	ciType.UpdateTime = util.HTime{Time: time.Now()}
+	if err := ciTypeDao.UpdateCIType(&ciType); err != nil {
+		result.Failed(c, int(result.ApiCode.FAILED), err.Error())
+		return
+	}
+	result.Success(c, true)
+}
+
+// 删除CI类型，已有主机使用或被其他类型的引用属性使用时不能删除
+func (s CmdbCITypeServiceImpl) DeleteCIType(c *gin.Context, id uint) {
+	ciTypeDao := dao.NewCmdbCITypeDao()
+	if _, err := ciTypeDao.GetCITypeById(id); err != nil {
+		result.Failed(c, int(result.ApiCode.FAILED), "CI类型不存在")
+		return
+	}
+	if count := ciTypeDao.CountHostsByCITypeId(id); count > 0 {
+		result.Failed(c, int(result.ApiCode.FAILED), fmt.Sprintf("CI类型已被 %d 台主机使用，不能删除", count))
+		return
+	}
+	for _, ciType := range ciTypeDao.GetCITypeList() {
+		for _, field := range ciType.Fields {
+			if field.RefCITypeID == id && ciType.ID != id {
+				result.Failed(c, int(result.ApiCode.FAILED), fmt.Sprintf("CI类型被 %s 的属性[%s]引用，不能删除", ciType.Name, field.Name))
+				return
+			}
+		}
+	}
+	if err := ciTypeDao.DeleteCIType(id); err != nil {
+		result.Failed(c, int(result.ApiCode.FAILED), err.Error())
+		return
+	}
+	result.Success(c, true)
+}
+
+// 新增自定义属性
+func (s CmdbCITypeServiceImpl) CreateField(c *gin.Context, dto model.CmdbCIFieldDto) {
+	ciTypeDao := dao.NewCmdbCITypeDao()
+	if _, err := ciTypeDao.GetCITypeById(dto.CITypeID); err != nil {
+		result.Failed(c, int(result.ApiCode.FAILED), "CI类型不存在")
+		return
+	}
+	field, err := checkCIField(dto, 0)
+	if err != nil {
+		result.Failed(c, int(result.ApiCode.FAILED), err.Error())
+		return
+	}
+	field.CITypeID = dto.CITypeID
+	field.CreateTime = util.HTime{Time: time.Now()}
+	if err := ciTypeDao.CreateField(&field); err != nil {
+		result.Failed(c, int(result.ApiCode.FAILED), err.Error())
+		return
+	}
+	result.Success(c, field)
+}
+
+// 修改自定义属性，键和类型不可修改，枚举可选值不能删除已被主机使用的值
+func (s CmdbCITypeServiceImpl) UpdateField(c *gin.Context, dto model.CmdbCIFieldDto) {
+	ciTypeDao := dao.NewCmdbCITypeDao()
+	original, err := ciTypeDao.GetFieldById(dto.Id)
+	if err != nil {
+		result.Failed(c, int(result.ApiCode.FAILED), "自定义属性不存在")
+		return
+	}
+	dto.CITypeID, dto.Key, dto.Type = original.CITypeID, original.Key, original.Type
+	field, err := checkCIField(dto, original.ID)
+	if err != nil {
+		result.Failed(c, int(result.ApiCode.FAILED), err.Error())
+		return
+	}
+	if field.Type == model.CIFieldEnum {
+		options := strings.Split(field.Options, ",")
+		for _, attr := range ciTypeDao.GetFieldAttrs(original.ID) {
+			if !containsString(options, attr.Value) {
+				result.Failed(c, int(result.ApiCode.FAILED), fmt.Sprintf("可选值[%s]已被主机使用，不能删除", attr.Value))
+				return
+			}
+		}
+	}
+	field.ID = original.ID
+	if err := ciTypeDao.UpdateField(&field); err != nil {
+		result.Failed(c, int(result.ApiCode.FAILED), err.Error())
+		return
+	}
+	result.Success(c, true)
+}
+
+// 删除自定义属性及其全部取值
+func (s CmdbCITypeServiceImpl) DeleteField(c *gin.Context, id uint) {
+	ciTypeDao := dao.NewCmdbCITypeDao()
+	if _, err := ciTypeDao.GetFieldById(id); err != nil {
+		result.Failed(c, int(result.ApiCode.FAILED), "自定义属性不存在")
+		return
+	}
+	if err := ciTypeDao.DeleteField(id); err != nil {
+		result.Failed(c, int(result.ApiCode.FAILED), err.Error())
+		return
+	}
+	result.Success(c, true)
+}
+
+// 按CI类型、自定义属性条件和关键字分页查询主机，条件格式为 属性键=值、!=、~（包含）、>=、<=（仅日期）
+func (s CmdbCITypeServiceImpl) SearchHosts(c *gin.Context, ciTypeId uint, filters []string, keyword string, page, pageSize int) {
+	var fields []model.CmdbCIField
+	if ciTypeId > 0 {
+		ciTypeDao := dao.NewCmdbCITypeDao()
+		ciType, err := ciTypeDao.GetCITypeById(ciTypeId)
+		if err != nil {
+			result.Failed(c, int(result.ApiCode.FAILED), "CI类型不存在")
+			return
+		}
+		fields = ciType.Fields
+	} else if len(filters) > 0 {
+		result.Failed(c, int(result.ApiCode.FAILED), "按自定义属性查询时需指定CI类型")
+		return
+	}
+	var conditions []model.CmdbHostAttrFilter
+	for _, filter := range filters {
+		condition, err := parseHostAttrFilter(fields, filter)
+		if err != nil {
+			result.Failed(c, int(result.ApiCode.FAILED), err.Error())
+			return
+		}
+		conditions = append(conditions, condition)
+	}
+	hostDao := dao.NewCmdbHostDao()
+	list, total := hostDao.WithContext(c).SearchCmdbHostsByAttrs(ciTypeId, conditions, strings.TrimSpace(keyword), page, pageSize)
+	hostIds := make([]uint, 0, len(list))
+	for _, host := range list {
+		hostIds = append(hostIds, host.ID)
+	}
+	attrs := getHostAttrMap(hostIds, false)
+	vos := make([]model.CmdbHostVo, 0, len(list))
+	for _, host := range list {
+		vos = append(vos, model.CmdbHostVo{
+			ID:          host.ID,
+			HostName:    host.HostName,
+			Name:        host.Name,
+			GroupID:     host.GroupID,
+			GroupName:   host.Group.Name,
+			PrivateIP:   host.PrivateIP,
+			PublicIP:    host.PublicIP,
+			SSHIP:       host.SSHIP,
+			SSHName:     host.SSHName,
+			SSHKeyID:    host.SSHKeyID,
+			SSHPort:     host.SSHPort,
+			ProxyHostID: host.ProxyHostID,
+			CITypeID:    host.CITypeID,
+			Attrs:       attrs[host.ID],
+			Remark:      host.Remark,
+			Vendor:      fmt.Sprintf("%d", host.Vendor),
+			Region:      host.Region,
+			InstanceID:  host.InstanceID,
+			OS:          host.OS,
+			Status:      host.Status,
+			CPU:         host.CPU,
+			Memory:      host.Memory,
+			Disk:        host.Disk,
+			BillingType: host.BillingType,
+			CreateTime:  host.CreateTime,
+			ExpireTime:  host.ExpireTime,
+			UpdateTime:  host.UpdateTime,
+		})
+	}
+	result.SuccessWithPage(c, vos, total, page, pageSize)
+}
+
+func checkCIType(dto model.CmdbCITypeDto, id uint) (model.CmdbCIType, error) {
+	ciType := model.CmdbCIType{Name: strings.TrimSpace(dto.Name), Code: strings.TrimSpace(dto.Code), Remark: dto.Remark}
+	if ciType.Name == "" || utf8.RuneCountInString(ciType.Name) > 64 {
+		return ciType, fmt.Errorf("类型名称不能为空，最长64个字符")
+	}
+	if !ciTypeCodeRegexp.MatchString(ciType.Code) {
+		return ciType, fmt.Errorf("类型编码只能包含字母、数字、下划线和中划线，且以字母开头")
+	}
+	ciTypeDao := dao.NewCmdbCITypeDao()
+	if ciTypeDao.CheckCITypeExists(ciType.Name, ciType.Code, id) {
+		return ciType, fmt.Errorf("类型名称或编码已存在")
+	}
+	return ciType, nil
+}
+
+func checkCIField(dto model.CmdbCIFieldDto, id uint) (model.CmdbCIField, error) {
+	field := model.CmdbCIField{
+		Key:      strings.TrimSpace(dto.Key),
+		Name:     strings.TrimSpace(dto.Name),
+		Type:     dto.Type,
+		Required: dto.Required,
+		Sort:     dto.Sort,
+	}
+	if !ciFieldKeyRegexp.MatchString(field.Key) {
+		return field, fmt.Errorf("属性键只能包含字母、数字和下划线，且以字母开头")
+	}
+	if field.Name == "" || utf8.RuneCountInString(field.Name) > 64 {
+		return field, fmt.Errorf("属性名称不能为空，最长64个字符")
+	}
+	if containsString(hostExcelColumns, field.Name) {
+		return field, fmt.Errorf("属性名称[%s]与主机固定字段重复", field.Name)
+	}
+	ciTypeDao := dao.NewCmdbCITypeDao()
+	if ciTypeDao.CheckFieldExists(dto.CITypeID, field.Key, field.Name, id) {
+		return field, fmt.Errorf("属性键或名称已存在")
+	}
+	switch field.Type {
+	case model.CIFieldString, model.CIFieldDate:
+	case model.CIFieldEnum:
+		var options []string
+		for _, option := range dto.Options {
+			option = strings.TrimSpace(option)
+			if option == "" || containsString(options, option) {
+				continue
+			}
+			if strings.Contains(option, ",") {
+				return field, fmt.Errorf("可选值[%s]不能包含逗号", option)
+			}
+			options = append(options, option)
+		}
+		if len(options) == 0 {
+			return field, fmt.Errorf("枚举属性至少需要一个可选值")
+		}
+		field.Options = strings.Join(options, ",")
+		if len(field.Options) > 1000 {
+			return field, fmt.Errorf("可选值过多")
+		}
+	case model.CIFieldReference:
+		if dto.RefCITypeID > 0 {
+			if _, err := ciTypeDao.GetCITypeById(dto.RefCITypeID); err != nil {
+				return field, fmt.Errorf("引用的CI类型不存在")
+			}
+		}
+		field.RefCITypeID = dto.RefCITypeID
+	default:
+		return field, fmt.Errorf("属性类型错误")
+	}
+	return field, nil
+}
+
+// 校验并转换主机的自定义属性，属性按属性键或属性名称匹配，返回待保存的属性值
+func resolveHostAttrs(ciTypeId uint, attrs map[string]string) ([]model.CmdbHostAttr, error) {
+	if ciTypeId == 0 {
+		for key, value := range attrs {
+			if strings.TrimSpace(value) != "" {
+				return nil, fmt.Errorf("未指定CI类型，不能设置自定义属性[%s]", key)
+			}
+		}
+		return nil, nil
+	}
+	ciTypeDao := dao.NewCmdbCITypeDao()
+	ciType, err := ciTypeDao.GetCITypeById(ciTypeId)
+	if err != nil {
+		return nil, fmt.Errorf("CI类型不存在")
+	}
+	values := map[uint]string{}
+	for key, value := range attrs {
+		field, ok := findCIField(ciType.Fields, key)
+		if !ok {
+			return nil, fmt.Errorf("CI类型 %s 没有自定义属性[%s]", ciType.Name, key)
+		}
+		values[field.ID] = strings.TrimSpace(value)
+	}
+	var list []model.CmdbHostAttr
+	for _, field := range ciType.Fields {
+		value := values[field.ID]
+		if value == "" {
+			if field.Required {
+				return nil, fmt.Errorf("属性[%s]不能为空", field.Name)
+			}
+			continue
+		}
+		value, err := normalizeCIValue(field, value)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, model.CmdbHostAttr{FieldID: field.ID, Value: value})
+	}
+	return list, nil
+}
+
+// 保存主机的自定义属性值
+func saveHostAttrs(hostId uint, attrs []model.CmdbHostAttr) error {
+	for i := range attrs {
+		attrs[i].HostID = hostId
+	}
+	ciTypeDao := dao.NewCmdbCITypeDao()
+	return ciTypeDao.ReplaceHostAttrs(hostId, attrs)
+}
+
+// 查询主机的自定义属性，键为属性键；refName 为 true 时引用属性返回被引用主机的名称，否则返回主机ID
+func getHostAttrs(hostId uint, refName bool) map[string]string {
+	return getHostAttrMap([]uint{hostId}, refName)[hostId]
+}
+
+// 批量查询主机的自定义属性，返回主机ID到属性的映射
+func getHostAttrMap(hostIds []uint, refName bool) map[uint]map[string]string {
+	ciTypeDao := dao.NewCmdbCITypeDao()
+	attrs := ciTypeDao.GetHostAttrsByHostIds(hostIds)
+	values := map[uint]map[string]string{}
+	if len(attrs) == 0 {
+		return values
+	}
+	fields := map[uint]model.CmdbCIField{}
+	for _, ciType := range ciTypeDao.GetCITypeList() {
+		for _, field := range ciType.Fields {
+			fields[field.ID] = field
+		}
+	}
+	refNames := map[string]string{}
+	if refName {
+		var refIds []uint
+		for _, attr := range attrs {
+			if fields[attr.FieldID].Type == model.CIFieldReference {
+				id, _ := strconv.Atoi(attr.Value)
+				refIds = append(refIds, uint(id))
+			}
+		}
+		if len(refIds) > 0 {
+			hostDao := dao.NewCmdbHostDao()
+			hosts, _ := hostDao.GetCmdbHostsByIds(refIds)
+			for _, host := range hosts {
+				refNames[strconv.Itoa(int(host.ID))] = host.HostName
+			}
+		}
+	}
+	for _, attr := range attrs {
+		field, ok := fields[attr.FieldID]
+		if !ok {
+			continue
+		}
+		value := attr.Value
+		if name, ok := refNames[value]; ok && field.Type == model.CIFieldReference {
+			value = name
+		}
+		if values[attr.HostID] == nil {
+			values[attr.HostID] = map[string]string{}
+		}
+		values[attr.HostID][field.Key] = value
+	}
+	return values
+}
+
+// 按属性键或属性名称查找自定义属性
+func findCIField(fields []model.CmdbCIField, key string) (model.CmdbCIField, bool) {
+	key = strings.TrimSpace(key)
+	for _, field := range fields {
+		if field.Key == key {
+			return field, true
+		}
+	}
+	for _, field := range fields {
+		if field.Name == key {
+			return field, true
+		}
+	}
+	return model.CmdbCIField{}, false
+}
+
+// 按属性类型校验并转换取值：日期统一为 2006-01-02，引用属性支持主机ID或主机名称，保存为主机ID
+func normalizeCIValue(field model.CmdbCIField, value string) (string, error) {
+	switch field.Type {
+	case model.CIFieldEnum:
+		if !containsString(strings.Split(field.Options, ","), value) {
+			return "", fmt.Errorf("属性[%s]的值 %q 不在可选值 %s 中", field.Name, value, field.Options)
+		}
+	case model.CIFieldDate:
+		for _, layout := range ciDateLayouts {
+			if date, err := time.Parse(layout, value); err == nil {
+				return date.Format("2006-01-02"), nil
+			}
+		}
+		return "", fmt.Errorf("属性[%s]的值 %q 不是有效日期，格式为 2006-01-02", field.Name, value)
+	case model.CIFieldReference:
+		hostDao := dao.NewCmdbHostDao()
+		host, err := hostDao.GetCmdbHostByName(value)
+		if id, convErr := strconv.Atoi(value); err != nil && convErr == nil && id > 0 {
+			host, err = hostDao.GetCmdbHostById(uint(id))
+		}
+		if err != nil {
+			return "", fmt.Errorf("属性[%s]引用的主机 %q 不存在", field.Name, value)
+		}
+		if field.RefCITypeID > 0 && host.CITypeID != field.RefCITypeID {
+			return "", fmt.Errorf("属性[%s]引用的主机 %s 类型不符", field.Name, host.HostName)
+		}
+		return strconv.Itoa(int(host.ID)), nil
+	default:
+		if utf8.RuneCountInString(value) > 500 {
+			return "", fmt.Errorf("属性[%s]的值最长500个字符", field.Name)
+		}
+	}
+	return value, nil
+}
+
+// 解析自定义属性查询条件，如 owner=alice、rack~A0、warranty<=2026-12-31，取最先出现的运算符
+func parseHostAttrFilter(fields []model.CmdbCIField, filter string) (model.CmdbHostAttrFilter, error) {
+	index, operator := -1, ""
+	for _, op := range []string{"!=", ">=", "<=", "~", "="} {
+		if i := strings.Index(filter, op); i >= 0 && (index < 0 || i < index) {
+			index, operator = i, op
+		}
+	}
+	if index <= 0 {
+		return model.CmdbHostAttrFilter{}, fmt.Errorf("查询条件 %q 格式错误", filter)
+	}
+	field, ok := findCIField(fields, filter[:index])
+	if !ok {
+		return model.CmdbHostAttrFilter{}, fmt.Errorf("查询条件 %q 中的属性不存在", filter)
+	}
+	value := strings.TrimSpace(filter[index+len(operator):])
+	if (operator == ">=" || operator == "<=") && field.Type != model.CIFieldDate {
+		return model.CmdbHostAttrFilter{}, fmt.Errorf("查询条件 %q 错误，只有日期属性支持范围查询", filter)
+	}
+	if operator != "~" {
+		normalized, err := normalizeCIValue(field, value)
+		if err != nil {
+			return model.CmdbHostAttrFilter{}, err
+		}
+		value = normalized
+	}
+	return model.CmdbHostAttrFilter{FieldID: field.ID, Operator: operator, Value: value}, nil
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func GetCmdbCITypeService() CmdbCITypeServiceInterface {
+	return CmdbCITypeServiceImpl{}
+}
@@ -0,0 +1,175 @@
+// CMDB资产变更历史 服务层
+// author xiaoRui
+
+package service
+
+import (
+	"dodevops-api/api/cmdb/dao"
+	"dodevops-api/api/cmdb/model"
+	"dodevops-api/common/result"
+	"dodevops-api/common/util"
+	"dodevops-api/pkg/datascope"
+	"encoding/json"
+	"errors"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type CmdbChangeLogServiceInterface interface {
+	GetHistory(c *gin.Context, query model.CmdbChangeLogQueryDto)                // 分页查询资产变更时间线
+	GetSnapshot(c *gin.Context, recordType string, id uint, at string)           // 查询资产在某一时刻的状态
+	GetSnapshotDiff(c *gin.Context, recordType string, id uint, from, to string) // 对比资产两个时刻的状态
+}
+
+type CmdbChangeLogServiceImpl struct{}
+
+var changeRecordTypes = []string{model.ChangeRecordHost, model.ChangeRecordGroup, model.ChangeRecordDatabase}
+
+// 分页查询资产变更时间线
+func (s CmdbChangeLogServiceImpl) GetHistory(c *gin.Context, query model.CmdbChangeLogQueryDto) {
+	if err := checkChangeRecord(c, query.RecordType, query.RecordID); err != nil {
+		result.Failed(c, int(result.ApiCode.FAILED), err.Error())
+		return
+	}
+	if query.Page <= 0 {
+		query.Page = 1
+	}
+	if query.PageSize <= 0 {
+		query.PageSize = 10
+	}
+	changeLogDao := dao.NewCmdbChangeLogDao()
+	list, total := changeLogDao.GetChangeLogListWithPage(query)
+	result.Success(c, result.PageResult{List: list, Total: total, Page: query.Page, PageSize: query.PageSize})
+}
+
+// 查询资产在某一时刻的状态
+func (s CmdbChangeLogServiceImpl) GetSnapshot(c *gin.Context, recordType string, id uint, at string) {
+	if err := checkChangeRecord(c, recordType, id); err != nil {
+		result.Failed(c, int(result.ApiCode.FAILED), err.Error())
+		return
+	}
+	t, err := parseSnapshotTime(at)
+	if err != nil {
+		result.Failed(c, int(result.ApiCode.FAILED), err.Error())
+		return
+	}
+	result.Success(c, snapshotAt(recordType, id, t))
+}
+
+// 对比资产两个时刻的状态，返回变化的字段
+func (s CmdbChangeLogServiceImpl) GetSnapshotDiff(c *gin.Context, recordType string, id uint, from, to string) {
+	if err := checkChangeRecord(c, recordType, id); err != nil {
+		result.Failed(c, int(result.ApiCode.FAILED), err.Error())
+		return
+	}
+	fromTime, err := parseSnapshotTime(from)
+	if err != nil {
+		result.Failed(c, int(result.ApiCode.FAILED), err.Error())
+		return
+	}
+	toTime, err := parseSnapshotTime(to)
+	if err != nil {
+		result.Failed(c, int(result.ApiCode.FAILED), err.Error())
+		return
+	}
+	diff := model.CmdbSnapshotDiffVo{
+		From:    snapshotAt(recordType, id, fromTime),
+		To:      snapshotAt(recordType, id, toTime),
+		Changes: []model.CmdbSnapshotDiffItem{},
+	}
+	columns := make(map[string]bool)
+	for column := range diff.From.Fields {
+		columns[column] = true
+	}
+	for column := range diff.To.Fields {
+		columns[column] = true
+	}
+	for column := range columns {
+		if diff.From.Fields[column] != diff.To.Fields[column] {
+			diff.Changes = append(diff.Changes, model.CmdbSnapshotDiffItem{Field: column, From: diff.From.Fields[column], To: diff.To.Fields[column]})
+		}
+	}
+	sort.Slice(diff.Changes, func(i, j int) bool { return diff.Changes[i].Field < diff.Changes[j].Field })
+	result.Success(c, diff)
+}
+
+// 校验资产类型，主机按所属分组校验数据权限，已删除的主机按删除前的分组校验
+func checkChangeRecord(c *gin.Context, recordType string, id uint) error {
+	if !containsString(changeRecordTypes, recordType) {
+		return errors.New("资产类型只能是 host、group、database")
+	}
+	if id == 0 {
+		return errors.New("资产ID不能为空")
+	}
+	if recordType != model.ChangeRecordHost {
+		return nil
+	}
+	changeLogDao := dao.NewCmdbChangeLogDao()
+	fields, exists := changeLogDao.GetCurrentFields(recordType, id)
+	if !exists {
+		for _, changeLog := range changeLogDao.GetChangeLogs(recordType, id) {
+			if changeLog.Action == model.ChangeActionDelete {
+				fields = decodeChangeRecord(changeLog.OldValue)
+				break
+			}
+		}
+	}
+	if fields == nil {
+		return errors.New("资产不存在或没有变更记录")
+	}
+	groupId, _ := strconv.ParseUint(fields["group_id"], 10, 64)
+	if !datascope.AllowGroup(c, uint(groupId)) {
+		return errors.New("无权查看该主机的变更历史")
+	}
+	return nil
+}
+
+// 解析时间点，格式为 2006-01-02 15:04:05
+func parseSnapshotTime(value string) (time.Time, error) {
+	t, err := time.ParseInLocation("2006-01-02 15:04:05", value, time.Local)
+	if err != nil {
+		return t, errors.New("时间格式应为 2006-01-02 15:04:05")
+	}
+	return t, nil
+}
+
+// 从当前状态开始按时间倒序回放该时刻之后的变更，得到资产在该时刻的状态
+func snapshotAt(recordType string, id uint, t time.Time) model.CmdbSnapshotVo {
+	changeLogDao := dao.NewCmdbChangeLogDao()
+	fields, exists := changeLogDao.GetCurrentFields(recordType, id)
+	for _, changeLog := range changeLogDao.GetChangeLogs(recordType, id) {
+		// 变更时间精确到秒，同一秒内的变更视为在该时刻之前发生
+		if !changeLog.CreateTime.Truncate(time.Second).After(t) {
+			break
+		}
+		switch changeLog.Action {
+		case model.ChangeActionUpdate:
+			if fields == nil {
+				fields = make(map[string]string)
+			}
+			fields[changeLog.Field] = changeLog.OldValue
+		case model.ChangeActionDelete:
+			fields, exists = decodeChangeRecord(changeLog.OldValue), true
+		case model.ChangeActionCreate:
+			fields, exists = nil, false
+		}
+	}
+	if !exists || fields == nil {
+		fields = map[string]string{}
+	}
+	return model.CmdbSnapshotVo{RecordType: recordType, RecordID: id, Time: util.HTime{Time: t}, Exists: exists, Fields: fields}
+}
+
+// 解析新增、删除记录中保存的完整记录
+func decodeChangeRecord(value string) map[string]string {
+	fields := make(map[string]string)
+	_ = json.Unmarshal([]byte(value), &fields)
+	return fields
+}
+
+func GetCmdbChangeLogService() CmdbChangeLogServiceInterface {
+	return &CmdbChangeLogServiceImpl{}
+}
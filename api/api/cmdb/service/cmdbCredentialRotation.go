@@ -0,0 +1,571 @@
+// 主机凭证轮换 服务层
+// author xiaoRui
+
+package service
+
+import (
+	"dodevops-api/api/cmdb/dao"
+	"dodevops-api/api/cmdb/model"
+	configDao "dodevops-api/api/configcenter/dao"
+	configModel "dodevops-api/api/configcenter/model"
+	"dodevops-api/common/result"
+	"dodevops-api/common/util"
+	"dodevops-api/pkg/datascope"
+	"dodevops-api/pkg/jwt"
+	"dodevops-api/pkg/log"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/robfig/cron/v3"
+	"golang.org/x/crypto/ssh"
+)
+
+type CmdbCredentialRotationServiceInterface interface {
+	GetRotationList(c *gin.Context)                                            // 轮换策略列表
+	CreateRotation(c *gin.Context, rotation model.CmdbCredentialRotation)      // 新增轮换策略
+	UpdateRotation(c *gin.Context, rotation model.CmdbCredentialRotation)      // 修改轮换策略
+	DeleteRotation(c *gin.Context, id uint)                                    // 删除轮换策略
+	RunRotation(c *gin.Context, id uint)                                       // 立即执行轮换策略
+	DistributePublicKey(c *gin.Context, dto model.CmdbCredentialDistributeDto) // 分发密钥凭证的公钥
+	GetRunList(c *gin.Context, rotationId uint, page, pageSize int)            // 执行记录列表
+	GetRunInfo(c *gin.Context, id uint)                                        // 执行记录详情及主机报告
+}
+
+type CmdbCredentialRotationServiceImpl struct{}
+
+// 执行中的资产分组，同一分组同时只允许一个轮换任务
+var rotatingGroups sync.Map
+
+// 查询轮换策略列表
+func (s CmdbCredentialRotationServiceImpl) GetRotationList(c *gin.Context) {
+	result.Success(c, dao.NewCmdbCredentialRotationDao().WithContext(c).GetRotationList())
+}
+
+// 新增轮换策略
+func (s CmdbCredentialRotationServiceImpl) CreateRotation(c *gin.Context, rotation model.CmdbCredentialRotation) {
+	if err := checkCredentialRotation(c, &rotation); err != nil {
+		result.Failed(c, int(result.ApiCode.FAILED), err.Error())
+		return
+	}
+	rotation.ID = 0
+	rotation.LastRunTime = nil
+	rotation.CreateTime = util.HTime{Time: time.Now()}
+	if err := dao.NewCmdbCredentialRotationDao().WithContext(c).CreateRotation(&rotation); err != nil {
+		result.Failed(c, int(result.ApiCode.FAILED), err.Error())
+		return
+	}
+	scheduleCredentialRotation(rotation)
+	result.Success(c, rotation)
+}
+
+// 修改轮换策略
+func (s CmdbCredentialRotationServiceImpl) UpdateRotation(c *gin.Context, rotation model.CmdbCredentialRotation) {
+	rotationDao := dao.NewCmdbCredentialRotationDao().WithContext(c)
+	if _, err := rotationDao.GetRotationById(rotation.ID); err != nil {
+		result.Failed(c, int(result.ApiCode.FAILED), "轮换策略不存在")
+		return
+	}
+	if err := checkCredentialRotation(c, &rotation); err != nil {
+		result.Failed(c, int(result.ApiCode.FAILED), err.Error())
+		return
+	}
+	if err := rotationDao.UpdateRotation(&rotation); err != nil {
+		result.Failed(c, int(result.ApiCode.FAILED), err.Error())
+		return
+	}
+	scheduleCredentialRotation(rotation)
+	result.Success(c, true)
+}
+
+// 删除轮换策略
+func (s CmdbCredentialRotationServiceImpl) DeleteRotation(c *gin.Context, id uint) {
+	rotationDao := dao.NewCmdbCredentialRotationDao().WithContext(c)
+	rotation, err := rotationDao.GetRotationById(id)
+	if err != nil {
+		result.Failed(c, int(result.ApiCode.FAILED), "轮换策略不存在")
+		return
+	}
+	if err := rotationDao.DeleteRotation(id); err != nil {
+		result.Failed(c, int(result.ApiCode.FAILED), err.Error())
+		return
+	}
+	rotation.Status = 2
+	scheduleCredentialRotation(rotation)
+	result.Success(c, true)
+}
+
+// 立即执行轮换策略，后台执行，返回执行记录
+func (s CmdbCredentialRotationServiceImpl) RunRotation(c *gin.Context, id uint) {
+	rotation, err := dao.NewCmdbCredentialRotationDao().WithContext(c).GetRotationById(id)
+	if err != nil {
+		result.Failed(c, int(result.ApiCode.FAILED), "轮换策略不存在")
+		return
+	}
+	job, err := newCredentialRotationJob(rotation, nil, false, 1, operatorName(c))
+	if err != nil {
+		result.Failed(c, int(result.ApiCode.FAILED), err.Error())
+		return
+	}
+	go job.execute()
+	result.Success(c, job.run)
+}
+
+// 将密钥凭证的公钥分发到分组内主机，后台执行，返回执行记录
+func (s CmdbCredentialRotationServiceImpl) DistributePublicKey(c *gin.Context, dto model.CmdbCredentialDistributeDto) {
+	if !datascope.AllowGroup(c, dto.GroupID) {
+		result.Failed(c, int(result.ApiCode.FAILED), "无权操作该资产分组")
+		return
+	}
+	ecsAuthDao := configDao.NewEcsAuthDao()
+	target, err := ecsAuthDao.GetById(dto.EcsAuthID)
+	if err != nil || target.Type != 2 {
+		result.Failed(c, int(result.ApiCode.FAILED), "请选择密钥认证凭据")
+		return
+	}
+	if err := target.ResolveSecrets(); err != nil {
+		result.Failed(c, int(result.ApiCode.FAILED), err.Error())
+		return
+	}
+	rotation := model.CmdbCredentialRotation{GroupID: dto.GroupID, Mode: model.CredentialRotationModeDistribute}
+	job, err := newCredentialRotationJob(rotation, &target, dto.SwitchAuth, 1, operatorName(c))
+	if err != nil {
+		result.Failed(c, int(result.ApiCode.FAILED), err.Error())
+		return
+	}
+	go job.execute()
+	result.Success(c, job.run)
+}
+
+// 分页查询执行记录
+func (s CmdbCredentialRotationServiceImpl) GetRunList(c *gin.Context, rotationId uint, page, pageSize int) {
+	list, total := dao.NewCmdbCredentialRotationDao().WithContext(c).GetRunListWithPage(rotationId, page, pageSize)
+	result.Success(c, result.PageResult{List: list, Total: total, Page: page, PageSize: pageSize})
+}
+
+// 查询执行记录详情及主机报告
+func (s CmdbCredentialRotationServiceImpl) GetRunInfo(c *gin.Context, id uint) {
+	rotationDao := dao.NewCmdbCredentialRotationDao().WithContext(c)
+	run, err := rotationDao.GetRunById(id)
+	if err != nil {
+		result.Failed(c, int(result.ApiCode.FAILED), "执行记录不存在")
+		return
+	}
+	result.Success(c, model.CmdbCredentialRotationRunVo{
+		CmdbCredentialRotationRun: run,
+		Hosts:                     rotationDao.GetRunHostList(run.ID),
+	})
+}
+
+// 校验轮换策略参数
+func checkCredentialRotation(c *gin.Context, rotation *model.CmdbCredentialRotation) error {
+	if rotation.Name == "" || rotation.GroupID == 0 {
+		return errors.New("策略名称和资产分组不能为空")
+	}
+	if !datascope.AllowGroup(c, rotation.GroupID) {
+		return errors.New("无权操作该资产分组")
+	}
+	switch rotation.Mode {
+	case model.CredentialRotationModePassword:
+	case model.CredentialRotationModeKey:
+		if rotation.KeyType != "" && rotation.KeyType != util.SSHKeyTypeEd25519 && rotation.KeyType != util.SSHKeyTypeRSA {
+			return fmt.Errorf("不支持的密钥类型: %s", rotation.KeyType)
+		}
+	default:
+		return errors.New("轮换方式错误")
+	}
+	if rotation.CronExpr != "" {
+		if _, err := cron.ParseStandard(rotation.CronExpr); err != nil {
+			return fmt.Errorf("cron表达式错误: %v", err)
+		}
+	}
+	if rotation.Status == 0 {
+		rotation.Status = 1
+	}
+	return nil
+}
+
+func operatorName(c *gin.Context) string {
+	if admin, err := jwt.GetAdmin(c); err == nil {
+		return admin.Username
+	}
+	return ""
+}
+
+// 一次凭证轮换任务
+type credentialRotationJob struct {
+	dao        dao.CmdbCredentialRotationDao
+	ecsAuthDao configDao.EcsAuthDao
+	ssh        *util.SSHUtil
+	rotation   model.CmdbCredentialRotation
+	target     *configModel.EcsAuth // 分发公钥时使用的密钥凭证
+	switchAuth bool                 // 分发公钥后是否切换主机凭证
+	hosts      []model.CmdbHost
+	run        *model.CmdbCredentialRotationRun
+}
+
+// 创建执行记录，分组正在轮换时返回错误
+func newCredentialRotationJob(rotation model.CmdbCredentialRotation, target *configModel.EcsAuth, switchAuth bool, trigger int, operator string) (*credentialRotationJob, error) {
+	if _, running := rotatingGroups.LoadOrStore(rotation.GroupID, true); running {
+		return nil, errors.New("该资产分组正在执行凭证轮换，请稍后再试")
+	}
+	job := &credentialRotationJob{
+		dao:        dao.NewCmdbCredentialRotationDao(),
+		ecsAuthDao: configDao.NewEcsAuthDao(),
+		ssh:        util.NewSSHUtil(),
+		rotation:   rotation,
+		target:     target,
+		switchAuth: switchAuth || rotation.Mode != model.CredentialRotationModeDistribute,
+	}
+	hostDao := dao.NewCmdbHostDao()
+	job.hosts = hostDao.GetCmdbHostsByGroupId(rotation.GroupID)
+	job.run = &model.CmdbCredentialRotationRun{
+		RotationID: rotation.ID,
+		GroupID:    rotation.GroupID,
+		Mode:       rotation.Mode,
+		Trigger:    trigger,
+		Operator:   operator,
+		Status:     model.CredentialRotationRunRunning,
+		Total:      len(job.hosts),
+		StartTime:  util.HTime{Time: time.Now()},
+	}
+	if err := job.dao.CreateRun(job.run); err != nil {
+		rotatingGroups.Delete(rotation.GroupID)
+		return nil, err
+	}
+	if rotation.ID > 0 {
+		job.dao.UpdateLastRunTime(rotation.ID, job.run)
+	}
+	return job, nil
+}
+
+// 按原凭证分批轮换：使用同一凭证的主机共用一个新凭证，成功的主机切换到新凭证，失败的主机保留原凭证
+func (j *credentialRotationJob) execute() {
+	defer rotatingGroups.Delete(j.rotation.GroupID)
+
+	hostsByAuth := map[uint][]model.CmdbHost{}
+	var authIds []uint
+	for _, host := range j.hosts {
+		if _, ok := hostsByAuth[host.SSHKeyID]; !ok {
+			authIds = append(authIds, host.SSHKeyID)
+		}
+		hostsByAuth[host.SSHKeyID] = append(hostsByAuth[host.SSHKeyID], host)
+	}
+
+	for _, authId := range authIds {
+		hosts := hostsByAuth[authId]
+		oldAuth, err := j.ecsAuthDao.GetById(authId)
+		if authId == 0 || err != nil {
+			j.failHosts(hosts, 0, "主机未配置SSH凭据或凭据不存在")
+			continue
+		}
+		if err := oldAuth.ResolveSecrets(); err != nil {
+			j.failHosts(hosts, oldAuth.ID, "读取原凭证失败: "+err.Error())
+			continue
+		}
+		newAuth, err := j.newCredential(oldAuth)
+		if err != nil {
+			j.failHosts(hosts, oldAuth.ID, "生成新凭证失败: "+err.Error())
+			continue
+		}
+		used := false
+		for _, host := range hosts {
+			if j.rotateHost(host, &oldAuth, newAuth) {
+				used = true
+			}
+		}
+		// 全部失败时删除本次生成的凭证
+		if !used && j.target == nil {
+			if err := j.ecsAuthDao.DeleteEcsAuth(newAuth.ID); err != nil {
+				log.Log().Warnf("删除未使用的轮换凭证 %d 失败: %v", newAuth.ID, err)
+			}
+		}
+	}
+
+	switch {
+	case j.run.FailedCount == 0:
+		j.run.Status = model.CredentialRotationRunSuccess
+	case j.run.SuccessCount == 0:
+		j.run.Status = model.CredentialRotationRunFailed
+	default:
+		j.run.Status = model.CredentialRotationRunPartial
+	}
+	j.run.EndTime = &util.HTime{Time: time.Now()}
+	if err := j.dao.FinishRun(j.run); err != nil {
+		log.Log().Errorf("保存凭证轮换结果失败: %v", err)
+	}
+	log.Log().Infof("凭证轮换 #%d 完成：分组 %d，成功 %d 台，失败 %d 台", j.run.ID, j.run.GroupID, j.run.SuccessCount, j.run.FailedCount)
+}
+
+// 为原凭证生成新凭证，分发公钥时直接使用指定的密钥凭证
+func (j *credentialRotationJob) newCredential(oldAuth configModel.EcsAuth) (*configModel.EcsAuth, error) {
+	if j.target != nil {
+		return j.target, nil
+	}
+	name := oldAuth.Name
+	if i := strings.LastIndex(name, "-rotated-"); i > 0 {
+		name = name[:i] // 多次轮换时不重复追加后缀
+	}
+	auth := &configModel.EcsAuth{
+		Name:       fmt.Sprintf("%s-rotated-%d", name, j.run.ID),
+		Username:   oldAuth.Username,
+		Port:       oldAuth.Port,
+		CreateTime: util.HTime{Time: time.Now()},
+		Remark:     fmt.Sprintf("凭证轮换 #%d 由凭据 %s 轮换生成", j.run.ID, oldAuth.Name),
+	}
+	if j.rotation.Mode == model.CredentialRotationModePassword {
+		length := j.rotation.PasswordLength
+		if length == 0 {
+			length = 20
+		}
+		password, err := util.GenerateSSHPassword(length)
+		if err != nil {
+			return nil, err
+		}
+		auth.Type, auth.Password = 1, password
+	} else {
+		privateKey, _, err := util.GenerateSSHKeyPair(j.rotation.KeyType, j.rotation.KeyBits, "autoops-"+auth.Name)
+		if err != nil {
+			return nil, err
+		}
+		auth.Type, auth.PublicKey = 2, privateKey
+	}
+	if err := j.ecsAuthDao.CreateEcsAuth(auth); err != nil {
+		return nil, err
+	}
+	return auth, nil
+}
+
+func (j *credentialRotationJob) failHosts(hosts []model.CmdbHost, oldAuthId uint, message string) {
+	for _, host := range hosts {
+		j.saveHost(host, oldAuthId, 0, model.CredentialRotationHostFailed, message, time.Now())
+	}
+}
+
+func (j *credentialRotationJob) saveHost(host model.CmdbHost, oldAuthId, newAuthId uint, status int, message string, start time.Time) {
+	if status == model.CredentialRotationHostSuccess {
+		j.run.SuccessCount++
+	} else {
+		j.run.FailedCount++
+	}
+	record := &model.CmdbCredentialRotationHost{
+		RunID:      j.run.ID,
+		HostID:     host.ID,
+		HostName:   host.HostName,
+		SSHIP:      host.SSHIP,
+		OldAuthID:  oldAuthId,
+		NewAuthID:  newAuthId,
+		Status:     status,
+		Message:    message,
+		Duration:   time.Since(start).Milliseconds(),
+		CreateTime: util.HTime{Time: time.Now()},
+	}
+	if err := j.dao.CreateRunHost(record); err != nil {
+		log.Log().Errorf("保存主机 %s 凭证轮换报告失败: %v", host.HostName, err)
+	}
+}
+
+// 轮换单台主机：使用原凭证连接并写入新凭证，使用新凭证验证登录，失败时通过原连接回滚；返回新凭证是否生效
+func (j *credentialRotationJob) rotateHost(host model.CmdbHost, oldAuth, newAuth *configModel.EcsAuth) bool {
+	start := time.Now()
+	var steps []string
+	finish := func(status int, step string) bool {
+		steps = append(steps, step)
+		j.saveHost(host, oldAuth.ID, newAuth.ID, status, strings.Join(steps, "\n"), start)
+		return status == model.CredentialRotationHostSuccess
+	}
+	if oldAuth.ID == newAuth.ID {
+		return finish(model.CredentialRotationHostSuccess, "主机已使用该凭证")
+	}
+
+	client, err := j.ssh.TerminalLogin(hostSSHConfig(host, oldAuth))
+	if err != nil {
+		return finish(model.CredentialRotationHostFailed, "使用原凭证连接失败: "+err.Error())
+	}
+	defer client.Close()
+	configDao.MarkEcsAuthUsed(oldAuth.ID)
+
+	// 写入新凭证，并返回对应的回滚命令
+	var apply, rollback string
+	if newAuth.Type == 1 {
+		apply = chpasswdCommand(host.SSHName, newAuth.Password)
+		if oldAuth.Type == 1 {
+			rollback = chpasswdCommand(host.SSHName, oldAuth.Password)
+		}
+	} else {
+		publicKey, err := util.SSHPublicKeyFromPrivate(newAuth.PublicKey)
+		if err != nil {
+			return finish(model.CredentialRotationHostFailed, "解析新密钥失败: "+err.Error())
+		}
+		apply = addAuthorizedKeyCommand(publicKey + " autoops-" + newAuth.Name)
+		rollback = removeAuthorizedKeyCommand(publicKey)
+	}
+	if _, err := j.ssh.RunCommand(client, apply); err != nil {
+		steps = append(steps, "写入新凭证失败: "+err.Error())
+		return j.rollbackHost(client, rollback, finish)
+	}
+	steps = append(steps, "已写入新凭证")
+
+	newClient, err := j.ssh.TerminalLogin(hostSSHConfig(host, newAuth))
+	if err != nil {
+		steps = append(steps, "使用新凭证登录验证失败: "+err.Error())
+		return j.rollbackHost(client, rollback, finish)
+	}
+	defer newClient.Close()
+	if _, err := j.ssh.RunCommand(newClient, "true"); err != nil {
+		steps = append(steps, "使用新凭证执行命令验证失败: "+err.Error())
+		return j.rollbackHost(client, rollback, finish)
+	}
+	steps = append(steps, "使用新凭证登录验证成功")
+
+	// 先切换主机凭证再移除原密钥，切换失败时主机仍保留原密钥
+	if j.switchAuth {
+		if err := j.dao.UpdateHostSSHKey(host.ID, newAuth.ID); err != nil {
+			steps = append(steps, "更新主机凭证失败: "+err.Error())
+			return j.rollbackHost(client, rollback, finish)
+		}
+		steps = append(steps, "主机凭证已切换为 "+newAuth.Name)
+	}
+
+	// 轮换密钥时移除原密钥，失败时恢复主机凭证并重新写入原密钥
+	if j.rotation.Mode == model.CredentialRotationModeKey && oldAuth.Type == 2 {
+		oldPublicKey, err := util.SSHPublicKeyFromPrivate(oldAuth.PublicKey)
+		if err == nil {
+			if _, err = j.ssh.RunCommand(newClient, removeAuthorizedKeyCommand(oldPublicKey)); err != nil {
+				rollback += "; " + addAuthorizedKeyCommand(oldPublicKey)
+			}
+		}
+		if err != nil {
+			steps = append(steps, "移除原密钥失败: "+err.Error())
+			if j.switchAuth {
+				if err := j.dao.UpdateHostSSHKey(host.ID, oldAuth.ID); err != nil {
+					return finish(model.CredentialRotationHostSuccess, "恢复主机凭证失败，主机继续使用新凭证，请人工移除原密钥: "+err.Error())
+				}
+			}
+			return j.rollbackHost(client, rollback, finish)
+		}
+		steps = append(steps, "已移除原密钥")
+	}
+	return finish(model.CredentialRotationHostSuccess, "轮换成功")
+}
+
+// 通过原凭证建立的连接回滚
+func (j *credentialRotationJob) rollbackHost(client *ssh.Client, rollback string, finish func(int, string) bool) bool {
+	if rollback == "" {
+		return finish(model.CredentialRotationHostRollbackFailed, "原凭证为非密码认证，无法恢复原密码，请人工处理")
+	}
+	if _, err := j.ssh.RunCommand(client, rollback); err != nil {
+		return finish(model.CredentialRotationHostRollbackFailed, "回滚失败，请人工处理: "+err.Error())
+	}
+	return finish(model.CredentialRotationHostRolledBack, "已回滚，主机继续使用原凭证")
+}
+
+// 主机的SSH连接配置，用户名和端口以主机为准
+func hostSSHConfig(host model.CmdbHost, auth *configModel.EcsAuth) *util.SSHConfig {
+	port := host.SSHPort
+	if port == 0 {
+		port = auth.Port
+	}
+	return &util.SSHConfig{
+		IP:        host.SSHIP,
+		Port:      port,
+		Type:      auth.Type,
+		Username:  host.SSHName,
+		Password:  auth.Password,
+		PublicKey: auth.PublicKey,
+		Timeout:   30 * time.Second,
+	}
+}
+
+// 修改密码命令，非 root 用户通过 sudo 执行
+func chpasswdCommand(username, password string) string {
+	command := "echo " + util.ShellQuote(username+":"+password) + " | "
+	if username != "root" {
+		command += "sudo -n "
+	}
+	return command + "chpasswd"
+}
+
+// 追加公钥到 authorized_keys，已存在时跳过
+func addAuthorizedKeyCommand(publicKey string) string {
+	blob := util.ShellQuote(authorizedKeyBlob(publicKey))
+	return "umask 077; mkdir -p ~/.ssh && touch ~/.ssh/authorized_keys && " +
+		"(grep -qF " + blob + " ~/.ssh/authorized_keys || echo " + util.ShellQuote(publicKey) + " >> ~/.ssh/authorized_keys)"
+}
+
+// 从 authorized_keys 中移除公钥
+func removeAuthorizedKeyCommand(publicKey string) string {
+	blob := util.ShellQuote(authorizedKeyBlob(publicKey))
+	return "umask 077; f=~/.ssh/authorized_keys; [ -f \"$f\" ] || exit 0; " +
+		"grep -vF " + blob + " \"$f\" > \"$f.autoops\"; cat \"$f.autoops\" > \"$f\" && rm -f \"$f.autoops\""
+}
+
+// 公钥的 base64 部分，用于匹配 authorized_keys 中的行
+func authorizedKeyBlob(publicKey string) string {
+	if fields := strings.Fields(publicKey); len(fields) > 1 {
+		return fields[1]
+	}
+	return publicKey
+}
+
+// 定时轮换调度
+var credentialRotationCron struct {
+	sync.Mutex
+	cron    *cron.Cron
+	entries map[uint]cron.EntryID
+}
+
+// 启动凭证轮换定时任务
+func StartCmdbCredentialRotationScheduler() {
+	credentialRotationCron.Lock()
+	credentialRotationCron.cron = cron.New()
+	credentialRotationCron.entries = map[uint]cron.EntryID{}
+	credentialRotationCron.Unlock()
+	rotationDao := dao.NewCmdbCredentialRotationDao()
+	for _, rotation := range rotationDao.GetScheduledRotationList() {
+		scheduleCredentialRotation(rotation)
+	}
+	credentialRotationCron.cron.Start()
+}
+
+// 按策略更新定时任务，禁用或未配置 cron 表达式时移除
+func scheduleCredentialRotation(rotation model.CmdbCredentialRotation) {
+	credentialRotationCron.Lock()
+	defer credentialRotationCron.Unlock()
+	if credentialRotationCron.cron == nil {
+		return
+	}
+	if entryId, ok := credentialRotationCron.entries[rotation.ID]; ok {
+		credentialRotationCron.cron.Remove(entryId)
+		delete(credentialRotationCron.entries, rotation.ID)
+	}
+	if rotation.Status != 1 || rotation.CronExpr == "" {
+		return
+	}
+	id := rotation.ID
+	entryId, err := credentialRotationCron.cron.AddFunc(rotation.CronExpr, func() {
+		rotationDao := dao.NewCmdbCredentialRotationDao()
+		current, err := rotationDao.GetRotationById(id)
+		if err != nil || current.Status != 1 {
+			return
+		}
+		job, err := newCredentialRotationJob(current, nil, false, 2, "system")
+		if err != nil {
+			log.Log().Warnf("凭证轮换策略 %s 未执行: %v", current.Name, err)
+			return
+		}
+		job.execute()
+	})
+	if err != nil {
+		log.Log().Errorf("添加凭证轮换策略 %s 定时任务失败: %v", rotation.Name, err)
+		return
+	}
+	credentialRotationCron.entries[id] = entryId
+}
+
+func GetCmdbCredentialRotationService() CmdbCredentialRotationServiceInterface {
+	return CmdbCredentialRotationServiceImpl{}
+}
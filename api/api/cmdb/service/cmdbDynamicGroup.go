@@ -0,0 +1,158 @@
+// 动态主机分组 服务层
+// author xiaoRui
+
+package service
+
+import (
+	"context"
+	"dodevops-api/api/cmdb/dao"
+	"dodevops-api/api/cmdb/model"
+	monitorService "dodevops-api/api/monitor/service"
+	"dodevops-api/common/result"
+	"dodevops-api/common/util"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+)
+
+type CmdbDynamicGroupServiceInterface interface {
+	GetDynamicGroupList(c *gin.Context)                               // 查询动态分组及当前匹配的主机数
+	CreateDynamicGroup(c *gin.Context, dto model.CmdbDynamicGroupDto) // 新增动态分组
+	UpdateDynamicGroup(c *gin.Context, dto model.CmdbDynamicGroupDto) // 修改动态分组
+	DeleteDynamicGroup(c *gin.Context, id uint)                       // 删除动态分组
+	GetDynamicGroupHosts(c *gin.Context, id uint)                     // 查询动态分组当前匹配的主机
+}
+
+type CmdbDynamicGroupServiceImpl struct{}
+
+func init() {
+	// 告警规则和Agent部署通过动态分组选择主机，监控模块不能直接依赖本模块
+	monitorService.DynamicGroupHosts = ResolveDynamicGroupHosts
+}
+
+// 查询动态分组，主机数按当前用户可见的主机计算
+func (s CmdbDynamicGroupServiceImpl) GetDynamicGroupList(c *gin.Context) {
+	groupDao := dao.NewCmdbDynamicGroupDao()
+	list := groupDao.GetDynamicGroupList()
+	for i := range list {
+		if hosts, err := selectHostsByLabels(c, list[i].Selector); err == nil {
+			list[i].HostCount = len(hosts)
+		}
+	}
+	result.Success(c, list)
+}
+
+// 新增动态分组
+func (s CmdbDynamicGroupServiceImpl) CreateDynamicGroup(c *gin.Context, dto model.CmdbDynamicGroupDto) {
+	group := model.CmdbDynamicGroup{
+		Name:       strings.TrimSpace(dto.Name),
+		Selector:   strings.TrimSpace(dto.Selector),
+		Remark:     dto.Remark,
+		Operator:   operatorName(c),
+		CreateTime: util.HTime{Time: time.Now()},
+		UpdateTime: util.HTime{Time: time.Now()},
+	}
+	if err := checkDynamicGroup(group); err != nil {
+		result.Failed(c, int(result.ApiCode.FAILED), err.Error())
+		return
+	}
+	groupDao := dao.NewCmdbDynamicGroupDao()
+	if groupDao.CheckNameExists(group.Name, 0) {
+		result.Failed(c, int(result.ApiCode.FAILED), "动态分组名称已存在")
+		return
+	}
+	if err := groupDao.CreateDynamicGroup(&group); err != nil {
+		result.Failed(c, int(result.ApiCode.FAILED), err.Error())
+		return
+	}
+	result.Success(c, group)
+}
+
+// 修改动态分组，引用该分组的任务和规则在下次使用时按新的选择器选择主机
+func (s CmdbDynamicGroupServiceImpl) UpdateDynamicGroup(c *gin.Context, dto model.CmdbDynamicGroupDto) {
+	groupDao := dao.NewCmdbDynamicGroupDao()
+	group, err := groupDao.GetDynamicGroupById(dto.Id)
+	if err != nil {
+		result.Failed(c, int(result.ApiCode.FAILED), "动态分组不存在")
+		return
+	}
+	group.Name = strings.TrimSpace(dto.Name)
+	group.Selector = strings.TrimSpace(dto.Selector)
+	group.Remark = dto.Remark
+	group.UpdateTime = util.HTime{Time: time.Now()}
+	if err := checkDynamicGroup(group); err != nil {
+		result.Failed(c, int(result.ApiCode.FAILED), err.Error())
+		return
+	}
+	if groupDao.CheckNameExists(group.Name, group.ID) {
+		result.Failed(c, int(result.ApiCode.FAILED), "动态分组名称已存在")
+		return
+	}
+	if err := groupDao.UpdateDynamicGroup(&group); err != nil {
+		result.Failed(c, int(result.ApiCode.FAILED), err.Error())
+		return
+	}
+	result.Success(c, true)
+}
+
+// 删除动态分组，引用该分组的任务和规则在使用时报错
+func (s CmdbDynamicGroupServiceImpl) DeleteDynamicGroup(c *gin.Context, id uint) {
+	groupDao := dao.NewCmdbDynamicGroupDao()
+	if _, err := groupDao.GetDynamicGroupById(id); err != nil {
+		result.Failed(c, int(result.ApiCode.FAILED), "动态分组不存在")
+		return
+	}
+	if err := groupDao.DeleteDynamicGroup(id); err != nil {
+		result.Failed(c, int(result.ApiCode.FAILED), err.Error())
+		return
+	}
+	result.Success(c, true)
+}
+
+// 查询动态分组当前匹配的主机
+func (s CmdbDynamicGroupServiceImpl) GetDynamicGroupHosts(c *gin.Context, id uint) {
+	hosts, err := ResolveDynamicGroupHosts(c, id)
+	if err != nil {
+		result.Failed(c, int(result.ApiCode.FAILED), err.Error())
+		return
+	}
+	result.Success(c, buildSelectedHostVos(hosts))
+}
+
+func checkDynamicGroup(group model.CmdbDynamicGroup) error {
+	if group.Name == "" || utf8.RuneCountInString(group.Name) > 64 {
+		return fmt.Errorf("动态分组名称不能为空，最长64个字符")
+	}
+	if len(group.Selector) > 500 {
+		return fmt.Errorf("标签选择器最长500个字符")
+	}
+	if _, err := parseLabelSelector(group.Selector); err != nil {
+		return err
+	}
+	if utf8.RuneCountInString(group.Remark) > 255 {
+		return fmt.Errorf("备注最长255个字符")
+	}
+	return nil
+}
+
+// 按动态分组的标签选择器查询当前匹配的主机，ctx 中有登录用户时按其数据权限过滤，
+// 定时任务等后台调用不限制
+func ResolveDynamicGroupHosts(ctx context.Context, id uint) ([]model.CmdbHost, error) {
+	groupDao := dao.NewCmdbDynamicGroupDao()
+	group, err := groupDao.GetDynamicGroupById(id)
+	if err != nil {
+		return nil, fmt.Errorf("动态分组(ID=%d)不存在", id)
+	}
+	hosts, err := selectHostsByLabels(ctx, group.Selector)
+	if err != nil {
+		return nil, fmt.Errorf("动态分组 %s 的标签选择器错误: %v", group.Name, err)
+	}
+	return hosts, nil
+}
+
+func GetCmdbDynamicGroupService() CmdbDynamicGroupServiceInterface {
+	return CmdbDynamicGroupServiceImpl{}
+}
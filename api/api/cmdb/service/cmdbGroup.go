@@ -25,11 +25,15 @@ type CmdbGroupServiceImpl struct{}
 
 // 新增分组
 func (s CmdbGroupServiceImpl) CreateCmdbGroup(c *gin.Context, group model.CmdbGroup) {
-	dao := dao.NewCmdbGroupDao()
+	dao := dao.NewCmdbGroupDao().WithContext(c)
 	if dao.CheckNameExists(group.Name) {
 		result.FailedWithCode(c, constant.GROUP_EXIST, "分组已存在无法创建")
 		return
 	}
+	if err := checkGroupProxy(0, group.ProxyHostID); err != nil {
+		result.Failed(c, int(result.ApiCode.FAILED), err.Error())
+		return
+	}
 	group.CreateTime = util.HTime{Time: time.Now()}
 	err := dao.CreateCmdbGroup(&group)
 	if err != nil {
@@ -47,7 +51,7 @@ func (s CmdbGroupServiceImpl) GetAllCmdbGroups(c *gin.Context) {
 	// 获取所有分组
 	groups := groupDao.GetCmdbGroupList()
 	// 获取所有主机
-	hosts := hostDao.GetCmdbHostList()
+	hosts := hostDao.WithContext(c).GetCmdbHostList()
 	
 	result.Success(c, model.BuildCmdbGroupTreeWithHostCount(groups, hosts))
 }
@@ -60,33 +64,34 @@ func (s CmdbGroupServiceImpl) GetAllCmdbGroupsWithHosts(c *gin.Context) {
 	// 获取所有分组
 	groups := groupDao.GetCmdbGroupList()
 	// 获取所有主机并转换为VO
-	hosts := hostDao.GetCmdbHostList()
+	hosts := hostDao.WithContext(c).GetCmdbHostList()
 	var hostVos []model.CmdbHostVo
 	for _, host := range hosts {
 		hostVos = append(hostVos, model.CmdbHostVo{
-			ID:         host.ID,
-			HostName:   host.HostName,
-			Name:       host.Name,
-			GroupID:    host.GroupID,
-			PrivateIP:  host.PrivateIP,
-			PublicIP:   host.PublicIP,
-			SSHIP:      host.SSHIP,
-			SSHName:    host.SSHName,
-			SSHKeyID:   host.SSHKeyID,
-			SSHPort:    host.SSHPort,
-			Remark:     host.Remark,
-			Vendor:     getVendorName(host.Vendor),
-			Region:     host.Region,
-			InstanceID: host.InstanceID,
-			OS:         host.OS,
-			Status:     host.Status,
-			CPU:        host.CPU,
-			Memory:     host.Memory,
-			Disk:       host.Disk,
+			ID:          host.ID,
+			HostName:    host.HostName,
+			Name:        host.Name,
+			GroupID:     host.GroupID,
+			PrivateIP:   host.PrivateIP,
+			PublicIP:    host.PublicIP,
+			SSHIP:       host.SSHIP,
+			SSHName:     host.SSHName,
+			SSHKeyID:    host.SSHKeyID,
+			SSHPort:     host.SSHPort,
+			ProxyHostID: host.ProxyHostID,
+			Remark:      host.Remark,
+			Vendor:      getVendorName(host.Vendor),
+			Region:      host.Region,
+			InstanceID:  host.InstanceID,
+			OS:          host.OS,
+			Status:      host.Status,
+			CPU:         host.CPU,
+			Memory:      host.Memory,
+			Disk:        host.Disk,
 			BillingType: host.BillingType,
-			CreateTime: host.CreateTime,
-			ExpireTime: host.ExpireTime,
-			UpdateTime: host.UpdateTime,
+			CreateTime:  host.CreateTime,
+			ExpireTime:  host.ExpireTime,
+			UpdateTime:  host.UpdateTime,
 		})
 	}
 
@@ -109,8 +114,16 @@ func getVendorName(vendor int) string {
 
 // 更新分组
 func (s CmdbGroupServiceImpl) UpdateCmdbGroup(c *gin.Context, group model.CmdbGroup) {
-	dao := dao.NewCmdbGroupDao()
+	dao := dao.NewCmdbGroupDao().WithContext(c)
+	if err := checkGroupProxy(group.ID, group.ProxyHostID); err != nil {
+		result.Failed(c, int(result.ApiCode.FAILED), err.Error())
+		return
+	}
 	err := dao.UpdateCmdbGroup(group.ID, &group)
+	if err == nil {
+		// 跳板机可以清空，单独更新
+		err = dao.UpdateCmdbGroupProxy(group.ID, group.ProxyHostID)
+	}
 	if err != nil {
 		result.FailedWithCode(c, constant.GROUP_EXIST, err.Error())
 		return
@@ -120,7 +133,7 @@ func (s CmdbGroupServiceImpl) UpdateCmdbGroup(c *gin.Context, group model.CmdbGr
 
 // 删除分组
 func (s CmdbGroupServiceImpl) DeleteCmdbGroup(c *gin.Context, id uint) {
-	dao := dao.NewCmdbGroupDao()
+	dao := dao.NewCmdbGroupDao().WithContext(c)
 	err := dao.DeleteCmdbGroup(id)
 	if err != nil {
 		result.FailedWithCode(c, constant.GROUP_EXIST, err.Error())
@@ -1,13 +1,18 @@
 package service
 
 import (
+	"context"
 	"fmt"
+	"io"
 	cmdbDao "dodevops-api/api/cmdb/dao"
 	"dodevops-api/api/cmdb/model"
 	configDao "dodevops-api/api/configcenter/dao"
 	"dodevops-api/common/constant"
 	"dodevops-api/common/result"
 	"dodevops-api/common/util"
+	"dodevops-api/pkg/changelog"
+	"dodevops-api/pkg/datascope"
+	"dodevops-api/pkg/jwt"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -26,6 +31,8 @@ type CmdbHostServiceInterface interface {
 	GetCmdbHostsByIP(c *gin.Context, ip string)                                                               // 根据IP查询(内网/公网/SSH)
 	GetCmdbHostsByStatus(c *gin.Context, status int)                                                          // 根据状态查询
 	ImportHostsFromExcel(c *gin.Context, dto *model.ImportHostsFromExcelDto, hosts []model.ExcelHostTemplate) // 从Excel导入主机
+	ExportHosts(c *gin.Context, query model.CmdbHostExportQuery)                                              // 按条件导出主机(XLSX/CSV/JSON)
+	ImportHosts(c *gin.Context, format string, reader io.Reader, dryRun bool)                                 // 按实例ID/SSH地址新增或更新主机
 	SyncHostInfo(c *gin.Context, id uint)                                                                     // 同步主机基本信息
 }
 
@@ -42,6 +49,17 @@ func (s *CmdbHostServiceImpl) ImportHostsFromExcel(c *gin.Context, dto *model.Im
 		result.FailedWithCode(c, constant.CMDB_HOST_NOT_FOUND, "分组不存在")
 		return
 	}
+	if !datascope.AllowGroup(c, group.ID) {
+		result.Failed(c, int(result.ApiCode.NOPERMISSION), "没有该分组的数据权限")
+		return
+	}
+	if dto.CITypeID > 0 {
+		ciTypeDao := cmdbDao.NewCmdbCITypeDao()
+		if _, err := ciTypeDao.GetCITypeById(dto.CITypeID); err != nil {
+			result.Failed(c, int(result.ApiCode.FAILED), "CI类型不存在")
+			return
+		}
+	}
 	fmt.Printf("导入主机到分组: ID=%d, Name=%s\n", group.ID, group.Name)
 
 	// 批量创建主机
@@ -64,6 +82,23 @@ func (s *CmdbHostServiceImpl) ImportHostsFromExcel(c *gin.Context, dto *model.Im
 			continue
 		}
 
+		// 校验自定义属性，导出文件中的主机固定字段列不导入
+		var attrs []model.CmdbHostAttr
+		if dto.CITypeID > 0 {
+			values := map[string]string{}
+			for column, value := range excelHost.Attrs {
+				if !containsString(hostExcelColumns, column) {
+					values[column] = value
+				}
+			}
+			var err error
+			if attrs, err = resolveHostAttrs(dto.CITypeID, values); err != nil {
+				failedHosts = append(failedHosts, fmt.Sprintf("%s(%s)", excelHost.HostAlias, err.Error()))
+				failCount++
+				continue
+			}
+		}
+
 		// 创建主机
 		host := model.CmdbHost{
 			HostName:   excelHost.HostAlias, // 使用主机别名作为主机名称
@@ -73,6 +108,7 @@ func (s *CmdbHostServiceImpl) ImportHostsFromExcel(c *gin.Context, dto *model.Im
 			SSHName:    excelHost.SSHName,
 			SSHPort:    excelHost.SSHPort,
 			Remark:     excelHost.Remark,
+			CITypeID:   dto.CITypeID,
 			CreateTime: util.HTime{Time: time.Now()},
 			Vendor:     1, // 默认创建主机都是为自建主机
 			Status:     2, // 初始状态设为未认证
@@ -80,16 +116,20 @@ func (s *CmdbHostServiceImpl) ImportHostsFromExcel(c *gin.Context, dto *model.Im
 
 		// 保存主机信息
 		fmt.Printf("尝试创建主机: %+v\n", host)
-		if err := s.dao.CreateCmdbHost(&host); err != nil {
+		if err := s.dao.WithContext(c).CreateCmdbHost(&host); err != nil {
 			fmt.Printf("创建主机失败: %v\n主机信息: %+v\n", err, host)
 			failedHosts = append(failedHosts, excelHost.HostAlias)
 			failCount++
 			continue
 		}
+		if err := saveHostAttrs(host.ID, attrs); err != nil {
+			fmt.Printf("保存主机自定义属性失败: %v\n", err)
+		}
 		fmt.Printf("主机创建成功, ID=%d\n", host.ID)
 		successCount++
 
 		// 异步执行SSH采集（使用Type=3公钥免认证）
+		collectDao := s.collectDao(c)
 		go func(h model.CmdbHost) {
 			fmt.Printf("开始SSH采集(公钥免认证): %s@%s:%d\n", h.SSHName, h.SSHIP, h.SSHPort)
 
@@ -107,7 +147,7 @@ func (s *CmdbHostServiceImpl) ImportHostsFromExcel(c *gin.Context, dto *model.Im
 			if err != nil {
 				// 更新状态为认证失败
 				fmt.Printf("主机ID %d SSH连接失败(公钥免认证): %v\n", h.ID, err)
-				s.dao.UpdateCmdbHost(h.ID, &model.CmdbHost{Status: 3})
+				collectDao.UpdateCmdbHost(h.ID, &model.CmdbHost{Status: 3})
 				resultChan <- hostResult{hostID: h.ID, err: err}
 				return
 			}
@@ -124,7 +164,7 @@ func (s *CmdbHostServiceImpl) ImportHostsFromExcel(c *gin.Context, dto *model.Im
 				Status:     1, // 认证成功
 				UpdateTime: util.HTime{Time: time.Now()},
 			}
-			if err := s.dao.UpdateCmdbHost(h.ID, &updateData); err != nil {
+			if err := collectDao.UpdateCmdbHost(h.ID, &updateData); err != nil {
 				fmt.Printf("主机ID %d 更新信息失败: %v\n", h.ID, err)
 				resultChan <- hostResult{hostID: h.ID, err: err}
 				return
@@ -160,9 +200,17 @@ func (s *CmdbHostServiceImpl) ImportHostsFromExcel(c *gin.Context, dto *model.Im
 	result.Success(c, responseData)
 }
 
+func toInterfaces(values []string) []interface{} {
+	list := make([]interface{}, 0, len(values))
+	for _, value := range values {
+		list = append(list, value)
+	}
+	return list
+}
+
 // 获取主机列表(分页)
 func (s *CmdbHostServiceImpl) GetCmdbHostListWithPage(c *gin.Context, page, pageSize int) {
-	list, total := s.dao.GetCmdbHostListWithPage(page, pageSize)
+	list, total := s.dao.WithContext(c).GetCmdbHostListWithPage(page, pageSize)
 	var vos []model.CmdbHostVo
 	for _, host := range list {
 		group, _ := s.groupDao.GetCmdbGroupById(host.GroupID)
@@ -178,6 +226,8 @@ func (s *CmdbHostServiceImpl) GetCmdbHostListWithPage(c *gin.Context, page, page
 			SSHName:     host.SSHName,
 			SSHKeyID:    host.SSHKeyID,
 			SSHPort:     host.SSHPort,
+			ProxyHostID: host.ProxyHostID,
+			CITypeID:    host.CITypeID,
 			Remark:      host.Remark,
 			Vendor:      fmt.Sprintf("%d", host.Vendor),
 			Region:      host.Region,
@@ -198,7 +248,7 @@ func (s *CmdbHostServiceImpl) GetCmdbHostListWithPage(c *gin.Context, page, page
 
 // 获取主机列表
 func (s *CmdbHostServiceImpl) GetCmdbHostList(c *gin.Context) {
-	list := s.dao.GetCmdbHostList()
+	list := s.dao.WithContext(c).GetCmdbHostList()
 	var vos []model.CmdbHostVo
 	for _, host := range list {
 		group, _ := s.groupDao.GetCmdbGroupById(host.GroupID)
@@ -214,6 +264,8 @@ func (s *CmdbHostServiceImpl) GetCmdbHostList(c *gin.Context) {
 			SSHName:     host.SSHName,
 			SSHKeyID:    host.SSHKeyID,
 			SSHPort:     host.SSHPort,
+			ProxyHostID: host.ProxyHostID,
+			CITypeID:    host.CITypeID,
 			Remark:      host.Remark,
 			Vendor:      fmt.Sprintf("%d", host.Vendor),
 			Region:      host.Region,
@@ -239,77 +291,48 @@ func (s *CmdbHostServiceImpl) CreateCmdbHost(c *gin.Context, dto *model.CreateCm
 		result.FailedWithCode(c, constant.CMDB_HOST_NAME_EXISTS, "主机名称已存在")
 		return
 	}
-
-	// 获取SSH凭据 (前端已确保SSHKeyID有效)
-	authDao := configDao.NewEcsAuthDao()
-	auth, _ := authDao.GetEcsAuthById(dto.SSHKeyID)
+	if !datascope.AllowGroup(c, dto.GroupID) {
+		result.Failed(c, int(result.ApiCode.NOPERMISSION), "没有该分组的数据权限")
+		return
+	}
+	if err := checkHostProxy(0, dto.GroupID, dto.ProxyHostID); err != nil {
+		result.Failed(c, int(result.ApiCode.FAILED), err.Error())
+		return
+	}
+	attrs, err := resolveHostAttrs(dto.CITypeID, dto.Attrs)
+	if err != nil {
+		result.Failed(c, int(result.ApiCode.FAILED), err.Error())
+		return
+	}
 
 	// 初始保存连接信息
 	host := model.CmdbHost{
-		HostName:   dto.HostName,
-		GroupID:    dto.GroupID,
-		SSHIP:      dto.SSHIP,
-		SSHName:    dto.SSHName,
-		SSHKeyID:   dto.SSHKeyID,
-		SSHPort:    dto.SSHPort,
-		Remark:     dto.Remark,
-		CreateTime: util.HTime{Time: time.Now()},
-		Vendor:     1, // 默认创建主机都是为自建主机
-		Status:     2, // 初始状态设为未认证
+		HostName:    dto.HostName,
+		GroupID:     dto.GroupID,
+		SSHIP:       dto.SSHIP,
+		SSHName:     dto.SSHName,
+		SSHKeyID:    dto.SSHKeyID,
+		SSHPort:     dto.SSHPort,
+		ProxyHostID: dto.ProxyHostID,
+		CITypeID:    dto.CITypeID,
+		Remark:      dto.Remark,
+		CreateTime:  util.HTime{Time: time.Now()},
+		Vendor:      1, // 默认创建主机都是为自建主机
+		Status:      2, // 初始状态设为未认证
 	}
 
 	// 先保存基本信息
-	if err := s.dao.CreateCmdbHost(&host); err != nil {
+	if err := s.dao.WithContext(c).CreateCmdbHost(&host); err != nil {
+		result.FailedWithCode(c, constant.CMDB_HOST_CREATE_FAILED, err.Error())
+		return
+	}
+	if err := saveHostAttrs(host.ID, attrs); err != nil {
 		result.FailedWithCode(c, constant.CMDB_HOST_CREATE_FAILED, err.Error())
 		return
 	}
 
 	// 立即返回成功响应，后台异步执行SSH操作
-	go func() {
-		// 准备SSH配置
-		sshConfig := util.SSHConfig{
-			IP:        dto.SSHIP,
-			Port:      dto.SSHPort,
-			Type:      auth.Type,
-			Username:  dto.SSHName,
-			Password:  auth.Password,
-			PublicKey: auth.PublicKey,
-		}
-
-		// 获取系统信息
-		fmt.Println("开始尝试SSH连接获取系统信息...")
-		fmt.Printf("SSH配置: %+v\n", sshConfig)
-
-		sshUtil := util.NewSSHUtil()
-		systemInfo, err := sshUtil.GetSystemInfo(&sshConfig)
-		if err != nil {
-			fmt.Printf("SSH获取系统信息失败: %v\n", err)
-			// 更新状态为认证失败
-			s.dao.UpdateCmdbHost(host.ID, &model.CmdbHost{Status: 3})
-			return
-		}
-
-		fmt.Printf("成功获取系统信息: %+v\n", systemInfo)
-
-		// 验证必要字段是否存在
-		if systemInfo["privateIp"] == "" || systemInfo["os"] == "" {
-			fmt.Println("警告: 获取的系统信息不完整")
-		}
-
-		// 更新主机信息
-		updateData := model.CmdbHost{
-			PrivateIP:  systemInfo["privateIp"],
-			PublicIP:   systemInfo["publicIp"],
-			Name:       systemInfo["name"], // 添加name字段
-			OS:         systemInfo["os"],
-			CPU:        systemInfo["cpu"],
-			Memory:     systemInfo["memory"],
-			Disk:       systemInfo["disk"],
-			Status:     1, // 认证成功
-			UpdateTime: util.HTime{Time: time.Now()},
-		}
-		s.dao.UpdateCmdbHost(host.ID, &updateData)
-	}()
+	s.collectHostSystemInfo(c, host)
 
 	// 返回成功响应，前端可以通过轮询获取最新状态
 	result.Success(c, gin.H{
@@ -322,6 +345,28 @@ func (s *CmdbHostServiceImpl) CreateCmdbHost(c *gin.Context, dto *model.CreateCm
 // 更新主机
 func (s *CmdbHostServiceImpl) UpdateCmdbHost(c *gin.Context, id uint, dto *model.UpdateCmdbHostDto) {
 	// 不再需要查询认证凭证信息，直接从dto获取SSHName和SSHPort
+	original, err := s.dao.WithContext(c).GetCmdbHostById(id)
+	if err != nil {
+		result.FailedWithCode(c, constant.CMDB_HOST_NOT_FOUND, "主机不存在")
+		return
+	}
+	if !datascope.AllowGroup(c, dto.GroupID) {
+		result.Failed(c, int(result.ApiCode.NOPERMISSION), "没有该分组的数据权限")
+		return
+	}
+	if err := checkHostProxy(id, dto.GroupID, dto.ProxyHostID); err != nil {
+		result.Failed(c, int(result.ApiCode.FAILED), err.Error())
+		return
+	}
+	// 未传自定义属性且CI类型不变时保留原有属性
+	updateAttrs := dto.Attrs != nil || dto.CITypeID != original.CITypeID
+	var attrs []model.CmdbHostAttr
+	if updateAttrs {
+		if attrs, err = resolveHostAttrs(dto.CITypeID, dto.Attrs); err != nil {
+			result.Failed(c, int(result.ApiCode.FAILED), err.Error())
+			return
+		}
+	}
 
 	host := model.CmdbHost{
 		HostName: dto.HostName,
@@ -333,7 +378,17 @@ func (s *CmdbHostServiceImpl) UpdateCmdbHost(c *gin.Context, id uint, dto *model
 		Vendor:   dto.Vendor,
 		Remark:   dto.Remark,
 	}
-	err := s.dao.UpdateCmdbHost(id, &host)
+	err = s.dao.WithContext(c).UpdateCmdbHost(id, &host)
+	if err == nil {
+		// 跳板机可以清空，单独更新
+		err = s.dao.WithContext(c).UpdateCmdbHostProxy(id, dto.ProxyHostID)
+	}
+	if err == nil && updateAttrs {
+		// CI类型可以清空，单独更新
+		if err = s.dao.WithContext(c).UpdateCmdbHostCIType(id, dto.CITypeID); err == nil {
+			err = saveHostAttrs(id, attrs)
+		}
+	}
 	if err != nil {
 		result.FailedWithCode(c, constant.CMDB_HOST_UPDATE_FAILED, err.Error())
 		return
@@ -343,13 +398,15 @@ func (s *CmdbHostServiceImpl) UpdateCmdbHost(c *gin.Context, id uint, dto *model
 
 // 根据ID获取主机
 func (s *CmdbHostServiceImpl) GetCmdbHostById(c *gin.Context, id uint) {
-	host, err := s.dao.GetCmdbHostById(id)
+	host, err := s.dao.WithContext(c).GetCmdbHostById(id)
 	if err != nil {
 		result.FailedWithCode(c, constant.CMDB_HOST_NOT_FOUND, "主机不存在")
 		return
 	}
 
 	group, _ := s.groupDao.GetCmdbGroupById(host.GroupID)
+	ciTypeDao := cmdbDao.NewCmdbCITypeDao()
+	ciType, _ := ciTypeDao.GetCITypeById(host.CITypeID)
 	vo := model.CmdbHostVo{
 		ID:          host.ID,
 		HostName:    host.HostName,
@@ -362,6 +419,10 @@ func (s *CmdbHostServiceImpl) GetCmdbHostById(c *gin.Context, id uint) {
 		SSHName:     host.SSHName,
 		SSHKeyID:    host.SSHKeyID,
 		SSHPort:     host.SSHPort,
+		ProxyHostID: host.ProxyHostID,
+		CITypeID:    host.CITypeID,
+		CITypeName:  ciType.Name,
+		Attrs:       getHostAttrs(host.ID, false),
 		Remark:      host.Remark,
 		Vendor:      fmt.Sprintf("%d", host.Vendor),
 		Region:      host.Region,
@@ -381,7 +442,7 @@ func (s *CmdbHostServiceImpl) GetCmdbHostById(c *gin.Context, id uint) {
 
 // 根据名称获取主机
 func (s *CmdbHostServiceImpl) GetCmdbHostByName(c *gin.Context, name string) {
-	host, err := s.dao.GetCmdbHostByName(name)
+	host, err := s.dao.WithContext(c).GetCmdbHostByName(name)
 	if err != nil {
 		result.FailedWithCode(c, constant.CMDB_HOST_NOT_FOUND, "主机不存在")
 		return
@@ -400,6 +461,8 @@ func (s *CmdbHostServiceImpl) GetCmdbHostByName(c *gin.Context, name string) {
 		SSHName:     host.SSHName,
 		SSHKeyID:    host.SSHKeyID,
 		SSHPort:     host.SSHPort,
+		ProxyHostID: host.ProxyHostID,
+		CITypeID:    host.CITypeID,
 		Remark:      host.Remark,
 		Vendor:      fmt.Sprintf("%d", host.Vendor),
 		Region:      host.Region,
@@ -419,17 +482,34 @@ func (s *CmdbHostServiceImpl) GetCmdbHostByName(c *gin.Context, name string) {
 
 // 删除主机
 func (s *CmdbHostServiceImpl) DeleteCmdbHost(c *gin.Context, id uint) {
-	err := s.dao.DeleteCmdbHost(id)
+	if _, err := s.dao.WithContext(c).GetCmdbHostById(id); err != nil {
+		result.FailedWithCode(c, constant.CMDB_HOST_NOT_FOUND, "主机不存在")
+		return
+	}
+	// 仍被作为跳板机使用的主机需先修改引用它的主机和分组，引用按全部数据统计
+	hostCount := s.dao.CountCmdbHostsByProxyHostId(id)
+	groupCount := s.groupDao.CountCmdbGroupsByProxyHostId(id)
+	if hostCount > 0 || groupCount > 0 {
+		result.FailedWithCode(c, constant.CMDB_HOST_IN_USE, fmt.Sprintf("主机正在被 %d 台主机和 %d 个分组作为跳板机使用，请修改跳板机配置后再删除", hostCount, groupCount))
+		return
+	}
+	err := s.dao.WithContext(c).DeleteCmdbHost(id)
 	if err != nil {
 		result.FailedWithCode(c, constant.CMDB_HOST_DELETE_FAILED, err.Error())
 		return
 	}
+	labelDao := cmdbDao.NewCmdbHostLabelDao()
+	labelDao.DeleteHostLabels(id)
+	ciTypeDao := cmdbDao.NewCmdbCITypeDao()
+	ciTypeDao.DeleteHostAttrs(id)
+	relationDao := cmdbDao.NewCmdbRelationDao()
+	relationDao.DeleteRelationsByCI(model.CIKindHost, id)
 	result.Success(c, true)
 }
 
 // 根据分组ID获取主机列表
 func (s *CmdbHostServiceImpl) GetCmdbHostsByGroupId(c *gin.Context, groupId uint) {
-	list := s.dao.GetCmdbHostsByGroupId(groupId)
+	list := s.dao.WithContext(c).GetCmdbHostsByGroupId(groupId)
 	var vos []model.CmdbHostVo
 	for _, host := range list {
 		group, _ := s.groupDao.GetCmdbGroupById(host.GroupID)
@@ -445,6 +525,8 @@ func (s *CmdbHostServiceImpl) GetCmdbHostsByGroupId(c *gin.Context, groupId uint
 			SSHName:     host.SSHName,
 			SSHKeyID:    host.SSHKeyID,
 			SSHPort:     host.SSHPort,
+			ProxyHostID: host.ProxyHostID,
+			CITypeID:    host.CITypeID,
 			Remark:      host.Remark,
 			Vendor:      fmt.Sprintf("%d", host.Vendor),
 			Region:      host.Region,
@@ -465,7 +547,7 @@ func (s *CmdbHostServiceImpl) GetCmdbHostsByGroupId(c *gin.Context, groupId uint
 
 // 根据主机名称模糊查询
 func (s *CmdbHostServiceImpl) GetCmdbHostsByHostNameLike(c *gin.Context, name string) {
-	list := s.dao.GetCmdbHostsByHostNameLike(name)
+	list := s.dao.WithContext(c).GetCmdbHostsByHostNameLike(name)
 	var vos []model.CmdbHostVo
 	for _, host := range list {
 		group, _ := s.groupDao.GetCmdbGroupById(host.GroupID)
@@ -481,6 +563,8 @@ func (s *CmdbHostServiceImpl) GetCmdbHostsByHostNameLike(c *gin.Context, name st
 			SSHName:     host.SSHName,
 			SSHKeyID:    host.SSHKeyID,
 			SSHPort:     host.SSHPort,
+			ProxyHostID: host.ProxyHostID,
+			CITypeID:    host.CITypeID,
 			Remark:      host.Remark,
 			Vendor:      fmt.Sprintf("%d", host.Vendor),
 			Region:      host.Region,
@@ -501,7 +585,7 @@ func (s *CmdbHostServiceImpl) GetCmdbHostsByHostNameLike(c *gin.Context, name st
 
 // 根据IP查询(内网/公网/SSH)
 func (s *CmdbHostServiceImpl) GetCmdbHostsByIP(c *gin.Context, ip string) {
-	list := s.dao.GetCmdbHostsByIP(ip)
+	list := s.dao.WithContext(c).GetCmdbHostsByIP(ip)
 	var vos []model.CmdbHostVo
 	for _, host := range list {
 		group, _ := s.groupDao.GetCmdbGroupById(host.GroupID)
@@ -517,6 +601,8 @@ func (s *CmdbHostServiceImpl) GetCmdbHostsByIP(c *gin.Context, ip string) {
 			SSHName:     host.SSHName,
 			SSHKeyID:    host.SSHKeyID,
 			SSHPort:     host.SSHPort,
+			ProxyHostID: host.ProxyHostID,
+			CITypeID:    host.CITypeID,
 			Remark:      host.Remark,
 			Vendor:      fmt.Sprintf("%d", host.Vendor),
 			Region:      host.Region,
@@ -537,7 +623,7 @@ func (s *CmdbHostServiceImpl) GetCmdbHostsByIP(c *gin.Context, ip string) {
 
 // 根据状态查询
 func (s *CmdbHostServiceImpl) GetCmdbHostsByStatus(c *gin.Context, status int) {
-	list := s.dao.GetCmdbHostsByStatus(status)
+	list := s.dao.WithContext(c).GetCmdbHostsByStatus(status)
 	var vos []model.CmdbHostVo
 	for _, host := range list {
 		group, _ := s.groupDao.GetCmdbGroupById(host.GroupID)
@@ -553,6 +639,8 @@ func (s *CmdbHostServiceImpl) GetCmdbHostsByStatus(c *gin.Context, status int) {
 			SSHName:     host.SSHName,
 			SSHKeyID:    host.SSHKeyID,
 			SSHPort:     host.SSHPort,
+			ProxyHostID: host.ProxyHostID,
+			CITypeID:    host.CITypeID,
 			Remark:      host.Remark,
 			Vendor:      fmt.Sprintf("%d", host.Vendor),
 			Region:      host.Region,
@@ -571,6 +659,74 @@ func (s *CmdbHostServiceImpl) GetCmdbHostsByStatus(c *gin.Context, status int) {
 	result.Success(c, vos)
 }
 
+// 后台通过SSH采集主机系统信息并更新主机状态，前端可以通过轮询获取最新状态
+func (s *CmdbHostServiceImpl) collectHostSystemInfo(c *gin.Context, host model.CmdbHost) {
+	// 获取SSH凭据 (前端已确保SSHKeyID有效)
+	authDao := configDao.NewEcsAuthDao()
+	auth, _ := authDao.GetEcsAuthById(host.SSHKeyID)
+	collectDao := s.collectDao(c)
+	go func() {
+		if err := auth.ResolveSecrets(); err != nil {
+			fmt.Printf("读取SSH凭据失败: %v\n", err)
+			collectDao.UpdateCmdbHost(host.ID, &model.CmdbHost{Status: 3})
+			return
+		}
+
+		// 准备SSH配置
+		sshConfig := util.SSHConfig{
+			IP:        host.SSHIP,
+			Port:      host.SSHPort,
+			Type:      auth.Type,
+			Username:  host.SSHName,
+			Password:  auth.Password,
+			PublicKey: auth.PublicKey,
+		}
+
+		// 获取系统信息
+		fmt.Println("开始尝试SSH连接获取系统信息...")
+		fmt.Printf("SSH配置: %+v\n", sshConfig)
+
+		sshUtil := util.NewSSHUtil()
+		systemInfo, err := sshUtil.GetSystemInfo(&sshConfig)
+		if err != nil {
+			fmt.Printf("SSH获取系统信息失败: %v\n", err)
+			// 更新状态为认证失败
+			collectDao.UpdateCmdbHost(host.ID, &model.CmdbHost{Status: 3})
+			return
+		}
+
+		fmt.Printf("成功获取系统信息: %+v\n", systemInfo)
+
+		// 验证必要字段是否存在
+		if systemInfo["privateIp"] == "" || systemInfo["os"] == "" {
+			fmt.Println("警告: 获取的系统信息不完整")
+		}
+
+		// 更新主机信息
+		updateData := model.CmdbHost{
+			PrivateIP:  systemInfo["privateIp"],
+			PublicIP:   systemInfo["publicIp"],
+			Name:       systemInfo["name"], // 添加name字段
+			OS:         systemInfo["os"],
+			CPU:        systemInfo["cpu"],
+			Memory:     systemInfo["memory"],
+			Disk:       systemInfo["disk"],
+			Status:     1, // 认证成功
+			UpdateTime: util.HTime{Time: time.Now()},
+		}
+		collectDao.UpdateCmdbHost(host.ID, &updateData)
+	}()
+}
+
+// 主机信息采集使用的DAO，变更历史记为采集来源，操作人为发起采集的用户
+func (s *CmdbHostServiceImpl) collectDao(c *gin.Context) *cmdbDao.CmdbHostDao {
+	operator, err := jwt.GetAdminName(c)
+	if err != nil {
+		operator = "主机信息采集"
+	}
+	return s.dao.WithContext(changelog.WithSource(context.Background(), model.ChangeSourceAgent, operator))
+}
+
 func GetCmdbHostService() CmdbHostServiceInterface {
 	return &CmdbHostServiceImpl{
 		dao:      cmdbDao.NewCmdbHostDao(),
@@ -581,7 +737,7 @@ func GetCmdbHostService() CmdbHostServiceInterface {
 // 同步主机基本信息
 func (s *CmdbHostServiceImpl) SyncHostInfo(c *gin.Context, id uint) {
 	// 1. 验证主机是否存在
-	host, err := s.dao.GetCmdbHostById(id)
+	host, err := s.dao.WithContext(c).GetCmdbHostById(id)
 	if err != nil {
 		result.FailedWithCode(c, constant.CMDB_HOST_NOT_FOUND, "主机不存在")
 		return
@@ -600,6 +756,11 @@ func (s *CmdbHostServiceImpl) SyncHostInfo(c *gin.Context, id uint) {
 		result.FailedWithCode(c, constant.CMDB_HOST_NOT_FOUND, "SSH认证凭据不存在")
 		return
 	}
+	if err := auth.ResolveSecrets(); err != nil {
+		result.FailedWithCode(c, constant.CMDB_HOST_NOT_FOUND, err.Error())
+		return
+	}
+	configDao.MarkEcsAuthUsed(auth.ID)
 
 	// 4. 立即返回成功响应，后台异步执行同步操作
 	result.Success(c, gin.H{
@@ -609,11 +770,12 @@ func (s *CmdbHostServiceImpl) SyncHostInfo(c *gin.Context, id uint) {
 	})
 
 	// 5. 异步执行同步操作
+	collectDao := s.collectDao(c)
 	go func() {
 		fmt.Printf("开始同步主机信息: ID=%d, Name=%s\n", host.ID, host.HostName)
 
 		// 设置状态为同步中（使用状态2表示同步中）
-		s.dao.UpdateCmdbHost(host.ID, &model.CmdbHost{Status: 2})
+		collectDao.UpdateCmdbHost(host.ID, &model.CmdbHost{Status: 2})
 
 		// 准备SSH配置
 		sshConfig := util.SSHConfig{
@@ -632,7 +794,7 @@ func (s *CmdbHostServiceImpl) SyncHostInfo(c *gin.Context, id uint) {
 		if err != nil {
 			fmt.Printf("SSH获取系统信息失败: %v\n", err)
 			// 更新状态为同步失败（使用状态3表示同步失败）
-			s.dao.UpdateCmdbHost(host.ID, &model.CmdbHost{Status: 3})
+			collectDao.UpdateCmdbHost(host.ID, &model.CmdbHost{Status: 3})
 			return
 		}
 
@@ -651,10 +813,10 @@ func (s *CmdbHostServiceImpl) SyncHostInfo(c *gin.Context, id uint) {
 			UpdateTime: util.HTime{Time: time.Now()},
 		}
 
-		if err := s.dao.UpdateCmdbHost(host.ID, &updateData); err != nil {
+		if err := collectDao.UpdateCmdbHost(host.ID, &updateData); err != nil {
 			fmt.Printf("更新主机信息失败: %v\n", err)
 			// 更新状态为同步失败
-			s.dao.UpdateCmdbHost(host.ID, &model.CmdbHost{Status: 3})
+			collectDao.UpdateCmdbHost(host.ID, &model.CmdbHost{Status: 3})
 			return
 		}
 
@@ -0,0 +1,349 @@
+// 主机SSH密钥校验 服务层
+// author xiaoRui
+
+package service
+
+import (
+	"dodevops-api/api/cmdb/dao"
+	"dodevops-api/api/cmdb/model"
+	monitorService "dodevops-api/api/monitor/service"
+	"dodevops-api/common/config"
+	"dodevops-api/common/result"
+	"dodevops-api/common/util"
+	"dodevops-api/pkg/log"
+	"errors"
+	"fmt"
+	"html"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+	"gorm.io/gorm"
+)
+
+type CmdbHostKeyServiceInterface interface {
+	GetHostKeyList(c *gin.Context, query model.CmdbHostKeyQueryDto) // 主机密钥列表
+	AcceptHostKey(c *gin.Context, id uint)                          // 确认变更后的主机密钥
+	PinHostKey(c *gin.Context, dto model.CmdbHostKeyPinDto)         // 固定主机密钥
+	DeleteHostKey(c *gin.Context, id uint)                          // 删除主机密钥，下次连接按校验策略重新处理
+}
+
+type CmdbHostKeyServiceImpl struct{}
+
+// 分页查询主机密钥
+func (s CmdbHostKeyServiceImpl) GetHostKeyList(c *gin.Context, query model.CmdbHostKeyQueryDto) {
+	if query.Page <= 0 {
+		query.Page = 1
+	}
+	if query.PageSize <= 0 {
+		query.PageSize = 10
+	}
+	hostKeyDao := dao.NewCmdbHostKeyDao()
+	list, total := hostKeyDao.GetHostKeyListWithPage(query)
+	result.Success(c, result.PageResult{List: list, Total: total, Page: query.Page, PageSize: query.PageSize})
+}
+
+// 将待确认的密钥设为信任的密钥
+func (s CmdbHostKeyServiceImpl) AcceptHostKey(c *gin.Context, id uint) {
+	hostKeyMu.Lock()
+	defer hostKeyMu.Unlock()
+	hostKeyDao := dao.NewCmdbHostKeyDao()
+	hostKey, err := hostKeyDao.GetHostKeyById(id)
+	if err != nil {
+		result.Failed(c, int(result.ApiCode.FAILED), "主机密钥记录不存在")
+		return
+	}
+	if hostKey.PendingPublicKey == "" {
+		result.Failed(c, int(result.ApiCode.FAILED), "没有待确认的主机密钥")
+		return
+	}
+	hostKey.KeyType = hostKey.PendingKeyType
+	hostKey.PublicKey = hostKey.PendingPublicKey
+	hostKey.Fingerprint = hostKey.PendingFingerprint
+	hostKey.Source = model.HostKeySourceAccept
+	hostKey.Status = model.HostKeyStatusTrusted
+	hostKey.PendingKeyType, hostKey.PendingPublicKey, hostKey.PendingFingerprint = "", "", ""
+	hostKey.MismatchCount = 0
+	hostKey.Operator = operatorName(c)
+	if err := hostKeyDao.SaveHostKey(&hostKey); err != nil {
+		result.Failed(c, int(result.ApiCode.FAILED), err.Error())
+		return
+	}
+	log.Log().Infof("%s 确认了主机 %s 的新密钥 %s", hostKey.Operator, hostKey.Address, hostKey.Fingerprint)
+	result.Success(c, hostKey)
+}
+
+// 固定主机密钥，未提供公钥时扫描主机当前的密钥
+func (s CmdbHostKeyServiceImpl) PinHostKey(c *gin.Context, dto model.CmdbHostKeyPinDto) {
+	host, err := dao.NewCmdbHostSSHDao().WithContext(c).GetHostSSHInfo(dto.HostID)
+	if err != nil {
+		result.Failed(c, int(result.ApiCode.FAILED), "主机不存在")
+		return
+	}
+	address := net.JoinHostPort(host.SSHIP, strconv.Itoa(host.SSHPort))
+	var key ssh.PublicKey
+	if strings.TrimSpace(dto.PublicKey) == "" {
+		key, err = util.ScanHostKey(address, 10*time.Second)
+		if err != nil {
+			result.Failed(c, int(result.ApiCode.FAILED), fmt.Sprintf("获取主机密钥失败: %v", err))
+			return
+		}
+	} else if key, err = parseHostPublicKey(dto.PublicKey); err != nil {
+		result.Failed(c, int(result.ApiCode.FAILED), "公钥格式错误")
+		return
+	}
+
+	hostKeyMu.Lock()
+	defer hostKeyMu.Unlock()
+	hostKeyDao := dao.NewCmdbHostKeyDao()
+	hostKey, err := hostKeyDao.GetHostKey(host.ID, address)
+	if err != nil {
+		hostKey = model.CmdbHostKey{HostID: host.ID, CreateTime: util.HTime{Time: time.Now()}}
+	}
+	hostKey.Address = address
+	setTrustedHostKey(&hostKey, key)
+	hostKey.Source = model.HostKeySourcePin
+	hostKey.Status = model.HostKeyStatusTrusted
+	hostKey.PendingKeyType, hostKey.PendingPublicKey, hostKey.PendingFingerprint = "", "", ""
+	hostKey.MismatchCount = 0
+	hostKey.Operator = operatorName(c)
+	if err := hostKeyDao.SaveHostKey(&hostKey); err != nil {
+		result.Failed(c, int(result.ApiCode.FAILED), err.Error())
+		return
+	}
+	log.Log().Infof("%s 固定了主机 %s 的密钥 %s", hostKey.Operator, address, hostKey.Fingerprint)
+	result.Success(c, hostKey)
+}
+
+// 删除主机密钥记录
+func (s CmdbHostKeyServiceImpl) DeleteHostKey(c *gin.Context, id uint) {
+	hostKeyDao := dao.NewCmdbHostKeyDao()
+	if _, err := hostKeyDao.GetHostKeyById(id); err != nil {
+		result.Failed(c, int(result.ApiCode.FAILED), "主机密钥记录不存在")
+		return
+	}
+	if err := hostKeyDao.DeleteHostKey(id); err != nil {
+		result.Failed(c, int(result.ApiCode.FAILED), err.Error())
+		return
+	}
+	result.Success(c, true)
+}
+
+// 解析 authorized_keys 或 known_hosts 格式的公钥
+func parseHostPublicKey(text string) (ssh.PublicKey, error) {
+	if key, _, _, _, err := ssh.ParseAuthorizedKey([]byte(text)); err == nil {
+		return key, nil
+	}
+	_, _, key, _, _, err := ssh.ParseKnownHosts([]byte(text))
+	return key, err
+}
+
+func setTrustedHostKey(hostKey *model.CmdbHostKey, key ssh.PublicKey) {
+	hostKey.KeyType = key.Type()
+	hostKey.PublicKey = strings.TrimSpace(string(ssh.MarshalAuthorizedKey(key)))
+	hostKey.Fingerprint = ssh.FingerprintSHA256(key)
+}
+
+func setPendingHostKey(hostKey *model.CmdbHostKey, key ssh.PublicKey) {
+	hostKey.PendingKeyType = key.Type()
+	hostKey.PendingPublicKey = strings.TrimSpace(string(ssh.MarshalAuthorizedKey(key)))
+	hostKey.PendingFingerprint = ssh.FingerprintSHA256(key)
+}
+
+// 主机密钥校验，密钥记录的读写串行执行，避免并发首次连接重复记录
+var (
+	hostKeyMu      sync.Mutex
+	hostKeyConfig  config.HostKeyConfig
+	hostKeyEnabled bool
+)
+
+// 按配置注册主机密钥校验器，所有SSH连接通过 util.HostKeyCallback 校验主机密钥
+func InitCmdbHostKeyVerifier(cfg config.HostKeyConfig) {
+	hostKeyMu.Lock()
+	hostKeyConfig = cfg
+	hostKeyEnabled = cfg.Policy != config.HostKeyPolicyOff
+	hostKeyMu.Unlock()
+	if cfg.Policy == config.HostKeyPolicyOff {
+		util.SetHostKeyVerifier(nil)
+		return
+	}
+	util.SetHostKeyVerifier(verifyHostKey)
+}
+
+// 校验主机密钥：没有记录时按策略首次信任或记为待确认，与信任的密钥不一致时拒绝连接并告警
+func verifyHostKey(addr string, key ssh.PublicKey) error {
+	ip, portStr, err := net.SplitHostPort(addr)
+	if err != nil {
+		return &util.HostKeyError{Addr: addr, Reason: "连接地址格式错误"}
+	}
+	port, _ := strconv.Atoi(portStr)
+	address := net.JoinHostPort(ip, portStr)
+	fingerprint := ssh.FingerprintSHA256(key)
+	now := util.HTime{Time: time.Now()}
+
+	hostKeyMu.Lock()
+	defer hostKeyMu.Unlock()
+	hostKeyDao := dao.NewCmdbHostKeyDao()
+	host := hostKeyDao.GetHostByAddress(ip, port)
+	hostKey, err := hostKeyDao.GetHostKey(host.ID, address)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return &util.HostKeyError{Addr: address, Fingerprint: fingerprint, Reason: fmt.Sprintf("查询主机密钥失败: %v", err)}
+	}
+
+	if err != nil {
+		hostKey = model.CmdbHostKey{HostID: host.ID, Address: address, CreateTime: now}
+		if hostKeyConfig.Policy == config.HostKeyPolicyStrict {
+			setPendingHostKey(&hostKey, key)
+			hostKey.Status = model.HostKeyStatusPending
+			hostKey.MismatchCount = 1
+			hostKey.ChangedTime = &now
+			if err := hostKeyDao.CreateHostKey(&hostKey); err != nil {
+				log.Log().Errorf("保存主机 %s 待确认密钥失败: %v", address, err)
+			}
+			return &util.HostKeyError{Addr: address, Fingerprint: fingerprint, Reason: "主机密钥未经确认，请在主机密钥管理中确认后再连接"}
+		}
+		setTrustedHostKey(&hostKey, key)
+		hostKey.Source = model.HostKeySourceTofu
+		hostKey.Status = model.HostKeyStatusTrusted
+		hostKey.LastSeenTime = &now
+		if err := hostKeyDao.CreateHostKey(&hostKey); err != nil {
+			return &util.HostKeyError{Addr: address, Fingerprint: fingerprint, Reason: fmt.Sprintf("保存主机密钥失败: %v", err)}
+		}
+		log.Log().Infof("首次连接主机 %s，已信任主机密钥 %s", address, fingerprint)
+		return nil
+	}
+
+	if hostKey.Fingerprint == fingerprint {
+		hostKeyDao.UpdateLastSeen(hostKey.ID, address, now)
+		return nil
+	}
+
+	// 与信任的密钥不一致，记录新密钥等待管理员确认，同一个新密钥只告警一次
+	changed := hostKey.PendingFingerprint != fingerprint
+	setPendingHostKey(&hostKey, key)
+	if hostKey.Status == model.HostKeyStatusTrusted {
+		hostKey.Status = model.HostKeyStatusChanged
+	}
+	hostKey.MismatchCount++
+	hostKey.ChangedTime = &now
+	if err := hostKeyDao.SaveHostKey(&hostKey); err != nil {
+		log.Log().Errorf("保存主机 %s 待确认密钥失败: %v", address, err)
+	}
+	if hostKey.Fingerprint == "" {
+		return &util.HostKeyError{Addr: address, Fingerprint: fingerprint, Reason: "主机密钥未经确认，请在主机密钥管理中确认后再连接"}
+	}
+	log.Log().Warnf("主机 %s 密钥不一致，信任的密钥为 %s，主机提供的密钥为 %s，已拒绝连接", address, hostKey.Fingerprint, fingerprint)
+	if changed {
+		go notifyHostKeyChanged(hostKey, host.HostName)
+	}
+	return &util.HostKeyError{Addr: address, Fingerprint: fingerprint, Reason: "主机密钥与信任的密钥不一致，可能存在中间人攻击，确认主机重装或更换密钥后请在主机密钥管理中确认新密钥"}
+}
+
+// 通过配置的钉钉、飞书和邮件发送主机密钥变更告警
+func notifyHostKeyChanged(hostKey model.CmdbHostKey, hostName string) {
+	hostKeyMu.Lock()
+	cfg := hostKeyConfig
+	hostKeyMu.Unlock()
+	logsign := fmt.Sprintf("[hostkey-%d]", hostKey.ID)
+	title := fmt.Sprintf("[主机密钥变更] %s %s", hostName, hostKey.Address)
+	text := fmt.Sprintf("### %s\n\n- 主机：%s\n- 地址：%s\n- 信任的密钥：%s %s\n- 主机提供的密钥：%s %s\n- 时间：%s\n\n连接已被拒绝，请核实主机是否重装或更换了密钥，确认后在主机密钥管理中确认新密钥。",
+		title, hostName, hostKey.Address, hostKey.KeyType, hostKey.Fingerprint, hostKey.PendingKeyType, hostKey.PendingFingerprint,
+		hostKey.ChangedTime.Format("2006-01-02 15:04:05"))
+	if cfg.DingDingUrl != "" {
+		monitorService.PostToDingDing(title, text, cfg.DingDingUrl, "", logsign)
+	}
+	if cfg.FeiShuUrl != "" {
+		monitorService.PostToFS(title, text, cfg.FeiShuUrl, "", logsign)
+	}
+	if cfg.Emails != "" {
+		body := strings.ReplaceAll(html.EscapeString(text), "\n", "<br/>")
+		if res := monitorService.NewAlertService().SendEmail(body, cfg.Emails, title, logsign); !strings.HasPrefix(res, "email send ok") {
+			log.Log().Warnf("%s 主机密钥变更告警邮件发送失败: %s", logsign, res)
+		}
+	}
+}
+
+// 为调用系统 ssh 命令的场景（如 ansible）准备SSH配置：扫描并校验主机及其跳板机当前的密钥，
+// 将校验通过的密钥写入 dir 下的 known_hosts 文件，并生成强制使用该文件校验、携带跳板机私钥的 ssh_config，
+// 返回让 ansible 使用该配置的环境变量和未通过校验的主机信息，未启用主机密钥校验且没有跳板机时返回空；
+// dir 中会保存解密后的跳板机私钥，调用方应使用临时目录并在执行结束后删除
+func PrepareKnownHosts(hostIds []uint, dir string) ([]string, []string, error) {
+	hostKeyMu.Lock()
+	enabled := hostKeyEnabled
+	hostKeyMu.Unlock()
+	jumpHosts, sshConfig, warnings, err := prepareProxyJumps(hostIds, dir)
+	if err != nil {
+		return nil, warnings, err
+	}
+	if !enabled && len(sshConfig) == 0 {
+		return nil, warnings, nil
+	}
+	var env []string
+	if enabled {
+		lines, scanWarnings := scanKnownHosts(append(append([]uint{}, hostIds...), jumpHosts...))
+		warnings = append(warnings, scanWarnings...)
+		path, err := filepath.Abs(filepath.Join(dir, "known_hosts"))
+		if err != nil {
+			return nil, warnings, err
+		}
+		if err := os.WriteFile(path, []byte(strings.Join(lines, "\n")+"\n"), 0600); err != nil {
+			return nil, warnings, err
+		}
+		sshConfig = append(sshConfig, fmt.Sprintf("Host *\n    UserKnownHostsFile %s\n    StrictHostKeyChecking yes", path))
+		env = append(env, "ANSIBLE_HOST_KEY_CHECKING=True")
+	}
+	configPath, err := filepath.Abs(filepath.Join(dir, "ssh_config"))
+	if err != nil {
+		return nil, warnings, err
+	}
+	if err := os.WriteFile(configPath, []byte(strings.Join(sshConfig, "\n")+"\n"), 0600); err != nil {
+		return nil, warnings, err
+	}
+	// 跳板机的 ssh 进程同样使用 -F 指定的配置文件，保留 ansible 默认的连接复用参数
+	env = append(env, fmt.Sprintf("ANSIBLE_SSH_ARGS=-C -o ControlMaster=auto -o ControlPersist=60s -F %s", configPath))
+	return env, warnings, nil
+}
+
+// 扫描并校验主机当前的密钥，返回校验通过的 known_hosts 记录和未通过校验的主机信息
+func scanKnownHosts(hostIds []uint) ([]string, []string) {
+	hostDao := dao.NewCmdbHostDao()
+	hostKeyDao := dao.NewCmdbHostKeyDao()
+	callback := util.HostKeyCallback()
+	var lines, warnings []string
+	scanned := map[uint]bool{}
+	for _, id := range hostIds {
+		if scanned[id] {
+			continue
+		}
+		scanned[id] = true
+		host, err := hostDao.GetCmdbHostById(id)
+		if err != nil || host.SSHIP == "" {
+			continue
+		}
+		address := net.JoinHostPort(host.SSHIP, strconv.Itoa(host.SSHPort))
+		key, err := util.ScanHostKey(address, 10*time.Second)
+		if err != nil {
+			warnings = append(warnings, fmt.Sprintf("%s(%s) 获取主机密钥失败: %v", host.HostName, address, err))
+			continue
+		}
+		if err := callback(address, nil, key); err != nil {
+			warnings = append(warnings, fmt.Sprintf("%s(%s) %v", host.HostName, address, err))
+			continue
+		}
+		if hostKey, err := hostKeyDao.GetHostKey(host.ID, address); err == nil && hostKey.Fingerprint == ssh.FingerprintSHA256(key) {
+			lines = append(lines, knownhosts.Line([]string{address}, key))
+		}
+	}
+	return lines, warnings
+}
+
+func GetCmdbHostKeyService() CmdbHostKeyServiceInterface {
+	return CmdbHostKeyServiceImpl{}
+}
@@ -0,0 +1,278 @@
+// 主机标签 服务层
+// author xiaoRui
+
+package service
+
+import (
+	"context"
+	"dodevops-api/api/cmdb/dao"
+	"dodevops-api/api/cmdb/model"
+	"dodevops-api/common/result"
+	"dodevops-api/pkg/datascope"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+type CmdbHostLabelServiceInterface interface {
+	GetHostLabels(c *gin.Context, hostId uint)                   // 查询主机标签
+	UpdateHostLabels(c *gin.Context, dto model.CmdbHostLabelDto) // 设置主机标签
+	GetLabelValues(c *gin.Context)                               // 查询已使用的标签键和取值
+	SelectHosts(c *gin.Context, selector string)                 // 按标签选择器查询主机
+}
+
+type CmdbHostLabelServiceImpl struct{}
+
+// 标签键：字母数字开头和结尾，中间可包含 - _ . /
+var labelKeyRegexp = regexp.MustCompile(`^[A-Za-z0-9]([-A-Za-z0-9_./]*[A-Za-z0-9])?$`)
+
+// 选择器中的集合条件，如 role in (web,api)
+var labelSetRegexp = regexp.MustCompile(`^(\S+)\s+(in|notin)\s*\((.*)\)$`)
+
+// 查询主机标签
+func (s CmdbHostLabelServiceImpl) GetHostLabels(c *gin.Context, hostId uint) {
+	hostDao := dao.NewCmdbHostDao()
+	if _, err := hostDao.WithContext(c).GetCmdbHostById(hostId); err != nil {
+		result.Failed(c, int(result.ApiCode.FAILED), "主机不存在")
+		return
+	}
+	labelDao := dao.NewCmdbHostLabelDao()
+	labels := map[string]string{}
+	for _, label := range labelDao.GetLabelsByHostId(hostId) {
+		labels[label.Key] = label.Value
+	}
+	result.Success(c, labels)
+}
+
+// 设置主机标签，覆盖主机原有的全部标签
+func (s CmdbHostLabelServiceImpl) UpdateHostLabels(c *gin.Context, dto model.CmdbHostLabelDto) {
+	hostDao := dao.NewCmdbHostDao()
+	host, err := hostDao.WithContext(c).GetCmdbHostById(dto.HostID)
+	if err != nil {
+		result.Failed(c, int(result.ApiCode.FAILED), "主机不存在")
+		return
+	}
+	if !datascope.AllowGroup(c, host.GroupID) {
+		result.Failed(c, int(result.ApiCode.NOPERMISSION), "没有该分组的数据权限")
+		return
+	}
+	labels := make([]model.CmdbHostLabel, 0, len(dto.Labels))
+	for key, value := range dto.Labels {
+		key, value = strings.TrimSpace(key), strings.TrimSpace(value)
+		if err := checkLabel(key, value); err != nil {
+			result.Failed(c, int(result.ApiCode.FAILED), err.Error())
+			return
+		}
+		labels = append(labels, model.CmdbHostLabel{HostID: host.ID, Key: key, Value: value})
+	}
+	sort.Slice(labels, func(i, j int) bool { return labels[i].Key < labels[j].Key })
+	labelDao := dao.NewCmdbHostLabelDao()
+	if err := labelDao.ReplaceHostLabels(host.ID, labels); err != nil {
+		result.Failed(c, int(result.ApiCode.FAILED), err.Error())
+		return
+	}
+	result.Success(c, true)
+}
+
+// 查询当前用户可见主机上已使用的标签键和取值，用于编写选择器
+func (s CmdbHostLabelServiceImpl) GetLabelValues(c *gin.Context) {
+	hostDao := dao.NewCmdbHostDao()
+	hosts := hostDao.WithContext(c).GetCmdbHostList()
+	hostIds := make([]uint, 0, len(hosts))
+	for _, host := range hosts {
+		hostIds = append(hostIds, host.ID)
+	}
+	labelDao := dao.NewCmdbHostLabelDao()
+	values := map[string][]string{}
+	for _, label := range labelDao.GetLabelsByHostIds(hostIds) {
+		if !containsString(values[label.Key], label.Value) {
+			values[label.Key] = append(values[label.Key], label.Value)
+		}
+	}
+	for key := range values {
+		sort.Strings(values[key])
+	}
+	result.Success(c, values)
+}
+
+// 按标签选择器查询主机，用于预览选择器匹配的主机
+func (s CmdbHostLabelServiceImpl) SelectHosts(c *gin.Context, selector string) {
+	hosts, err := selectHostsByLabels(c, selector)
+	if err != nil {
+		result.Failed(c, int(result.ApiCode.FAILED), err.Error())
+		return
+	}
+	result.Success(c, buildSelectedHostVos(hosts))
+}
+
+// 主机及其标签
+func buildSelectedHostVos(hosts []model.CmdbHost) []model.CmdbSelectedHostVo {
+	hostIds := make([]uint, 0, len(hosts))
+	for _, host := range hosts {
+		hostIds = append(hostIds, host.ID)
+	}
+	labelDao := dao.NewCmdbHostLabelDao()
+	labels := map[uint]map[string]string{}
+	for _, label := range labelDao.GetLabelsByHostIds(hostIds) {
+		if labels[label.HostID] == nil {
+			labels[label.HostID] = map[string]string{}
+		}
+		labels[label.HostID][label.Key] = label.Value
+	}
+	vos := make([]model.CmdbSelectedHostVo, 0, len(hosts))
+	for _, host := range hosts {
+		vos = append(vos, model.CmdbSelectedHostVo{ID: host.ID, HostName: host.HostName, Name: host.Name, GroupID: host.GroupID,
+			SSHIP: host.SSHIP, Status: host.Status, Labels: labels[host.ID]})
+	}
+	return vos
+}
+
+func checkLabel(key, value string) error {
+	if len(key) > 63 || !labelKeyRegexp.MatchString(key) {
+		return fmt.Errorf("标签键 %q 格式错误，只能包含字母、数字和 - _ . /，且以字母或数字开头和结尾，最长63个字符", key)
+	}
+	if len(value) > 255 || strings.ContainsAny(value, ",()=! ") {
+		return fmt.Errorf("标签 %s 的值 %q 格式错误，不能包含空格和 , ( ) = !，最长255个字符", key, value)
+	}
+	return nil
+}
+
+// 标签选择器中的一个条件
+type labelRequirement struct {
+	key      string
+	operator string // =、!=、in、notin、exists（存在该标签）、!（不存在该标签）
+	values   []string
+}
+
+// 解析标签选择器，多个条件用逗号分隔且需同时满足，支持 key=value、key!=value、key in (a,b)、key notin (a,b)、key、!key
+func parseLabelSelector(selector string) ([]labelRequirement, error) {
+	var requirements []labelRequirement
+	for _, part := range splitLabelSelector(selector) {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		requirement, err := parseLabelRequirement(part)
+		if err != nil {
+			return nil, err
+		}
+		requirements = append(requirements, requirement)
+	}
+	if len(requirements) == 0 {
+		return nil, fmt.Errorf("标签选择器 %q 为空", selector)
+	}
+	return requirements, nil
+}
+
+// 按括号外的逗号拆分条件
+func splitLabelSelector(selector string) []string {
+	var parts []string
+	depth, start := 0, 0
+	for i, ch := range selector {
+		switch ch {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		case ',':
+			if depth == 0 {
+				parts = append(parts, selector[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(parts, selector[start:])
+}
+
+func parseLabelRequirement(part string) (labelRequirement, error) {
+	var requirement labelRequirement
+	switch {
+	case strings.HasPrefix(part, "!") && !strings.Contains(part, "="):
+		requirement = labelRequirement{key: strings.TrimSpace(part[1:]), operator: "!"}
+	case labelSetRegexp.MatchString(part):
+		match := labelSetRegexp.FindStringSubmatch(part)
+		requirement = labelRequirement{key: match[1], operator: match[2]}
+		for _, value := range strings.Split(match[3], ",") {
+			if value = strings.TrimSpace(value); value != "" {
+				requirement.values = append(requirement.values, value)
+			}
+		}
+		if len(requirement.values) == 0 {
+			return requirement, fmt.Errorf("标签条件 %q 缺少取值", part)
+		}
+	case strings.Contains(part, "!="):
+		kv := strings.SplitN(part, "!=", 2)
+		requirement = labelRequirement{key: strings.TrimSpace(kv[0]), operator: "!=", values: []string{strings.TrimSpace(kv[1])}}
+	case strings.Contains(part, "="):
+		kv := strings.SplitN(strings.Replace(part, "==", "=", 1), "=", 2)
+		requirement = labelRequirement{key: strings.TrimSpace(kv[0]), operator: "=", values: []string{strings.TrimSpace(kv[1])}}
+	default:
+		requirement = labelRequirement{key: part, operator: "exists"}
+	}
+	if err := checkLabel(requirement.key, strings.Join(requirement.values, "")); err != nil {
+		return requirement, fmt.Errorf("标签条件 %q 错误: %v", part, err)
+	}
+	return requirement, nil
+}
+
+func (r labelRequirement) matches(labels map[string]string) bool {
+	value, ok := labels[r.key]
+	switch r.operator {
+	case "exists":
+		return ok
+	case "!":
+		return !ok
+	case "=":
+		return ok && value == r.values[0]
+	case "!=":
+		return !ok || value != r.values[0]
+	case "in":
+		return ok && containsString(r.values, value)
+	case "notin":
+		return !ok || !containsString(r.values, value)
+	}
+	return false
+}
+
+// 按标签选择器查询主机，ctx 中有登录用户时按其数据权限过滤
+func selectHostsByLabels(ctx context.Context, selector string) ([]model.CmdbHost, error) {
+	requirements, err := parseLabelSelector(selector)
+	if err != nil {
+		return nil, err
+	}
+	hostDao := dao.NewCmdbHostDao()
+	hosts := hostDao.WithContext(ctx).GetCmdbHostList()
+	hostIds := make([]uint, 0, len(hosts))
+	for _, host := range hosts {
+		hostIds = append(hostIds, host.ID)
+	}
+	labelDao := dao.NewCmdbHostLabelDao()
+	labels := map[uint]map[string]string{}
+	for _, label := range labelDao.GetLabelsByHostIds(hostIds) {
+		if labels[label.HostID] == nil {
+			labels[label.HostID] = map[string]string{}
+		}
+		labels[label.HostID][label.Key] = label.Value
+	}
+	var selected []model.CmdbHost
+	for _, host := range hosts {
+		matched := true
+		for _, requirement := range requirements {
+			if !requirement.matches(labels[host.ID]) {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			selected = append(selected, host)
+		}
+	}
+	return selected, nil
+}
+
+func GetCmdbHostLabelService() CmdbHostLabelServiceInterface {
+	return CmdbHostLabelServiceImpl{}
+}
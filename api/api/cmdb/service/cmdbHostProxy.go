@@ -0,0 +1,216 @@
+// 主机跳板机 服务层
+// author xiaoRui
+
+package service
+
+import (
+	"dodevops-api/api/cmdb/dao"
+	"dodevops-api/api/cmdb/model"
+	configDao "dodevops-api/api/configcenter/dao"
+	"dodevops-api/common/util"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// 跳板机最多层级
+const maxProxyJumps = 5
+
+// 注册跳板机查询，所有SSH连接通过 util.DialSSH 按主机和分组的跳板机配置透明地经过跳板机
+func InitCmdbHostProxyResolver() {
+	util.SetSSHProxyResolver(resolveSSHProxy)
+}
+
+// 按连接地址查询CMDB主机需要经过的跳板机，不在CMDB中的地址直接连接
+func resolveSSHProxy(ip string, port int) ([]util.SSHConfig, error) {
+	hostDao := dao.NewCmdbHostDao()
+	host, err := hostDao.GetCmdbHostByAddress(ip, port)
+	if err != nil {
+		return nil, nil
+	}
+	jumps, err := newHostProxyResolver().chain(host)
+	if err != nil {
+		return nil, err
+	}
+	return jumpSSHConfigs(jumps)
+}
+
+// 将跳板机主机转换为SSH连接配置
+func jumpSSHConfigs(jumps []model.CmdbHost) ([]util.SSHConfig, error) {
+	ecsAuthDao := configDao.NewEcsAuthDao()
+	configs := make([]util.SSHConfig, 0, len(jumps))
+	for _, jump := range jumps {
+		auth, err := ecsAuthDao.GetById(jump.SSHKeyID)
+		if err != nil {
+			return nil, fmt.Errorf("跳板机 %s 未配置SSH凭据", jump.HostName)
+		}
+		if err := auth.ResolveSecrets(); err != nil {
+			return nil, fmt.Errorf("读取跳板机 %s 的SSH凭据失败: %v", jump.HostName, err)
+		}
+		configDao.MarkEcsAuthUsed(auth.ID)
+		configs = append(configs, util.SSHConfig{
+			IP:        jump.SSHIP,
+			Port:      jump.SSHPort,
+			Type:      auth.Type,
+			Username:  jump.SSHName,
+			Password:  auth.Password,
+			PublicKey: auth.PublicKey,
+		})
+	}
+	return configs, nil
+}
+
+// 查询主机的跳板机链，主机未单独配置跳板机时使用所在分组或上级分组的跳板机
+type hostProxyResolver struct {
+	hostDao dao.CmdbHostDao
+	groups  map[uint]model.CmdbGroup
+}
+
+func newHostProxyResolver() *hostProxyResolver {
+	groupDao := dao.NewCmdbGroupDao()
+	groups := map[uint]model.CmdbGroup{}
+	for _, group := range groupDao.GetCmdbGroupList() {
+		groups[group.ID] = group
+	}
+	return &hostProxyResolver{hostDao: dao.NewCmdbHostDao(), groups: groups}
+}
+
+// 主机实际使用的跳板机ID，跳板机就是主机自身时（跳板机在配置了跳板机的分组内）直接连接
+func (r *hostProxyResolver) proxyHostID(host model.CmdbHost) uint {
+	proxyHostId := host.ProxyHostID
+	visited := map[uint]bool{}
+	for groupId := host.GroupID; proxyHostId == 0 && groupId != 0 && !visited[groupId]; {
+		visited[groupId] = true
+		group, ok := r.groups[groupId]
+		if !ok {
+			break
+		}
+		proxyHostId = group.ProxyHostID
+		groupId = group.ParentID
+	}
+	if proxyHostId == host.ID {
+		return 0
+	}
+	return proxyHostId
+}
+
+// 按连接顺序返回主机需要经过的跳板机，跳板机自身配置了跳板机时组成多级跳板
+func (r *hostProxyResolver) chain(host model.CmdbHost) ([]model.CmdbHost, error) {
+	var jumps []model.CmdbHost
+	names := []string{host.HostName}
+	visited := map[uint]bool{host.ID: true}
+	for proxyHostId := r.proxyHostID(host); proxyHostId != 0; {
+		if visited[proxyHostId] {
+			return nil, fmt.Errorf("跳板机配置存在循环: %s", strings.Join(names, " -> "))
+		}
+		if len(jumps) >= maxProxyJumps {
+			return nil, fmt.Errorf("跳板机层级超过 %d 级", maxProxyJumps)
+		}
+		visited[proxyHostId] = true
+		proxy, err := r.hostDao.GetCmdbHostById(proxyHostId)
+		if err != nil {
+			return nil, fmt.Errorf("主机 %s 的跳板机(ID=%d)不存在", names[len(names)-1], proxyHostId)
+		}
+		jumps = append([]model.CmdbHost{proxy}, jumps...)
+		names = append(names, proxy.HostName)
+		proxyHostId = r.proxyHostID(proxy)
+	}
+	return jumps, nil
+}
+
+// 校验主机的跳板机配置，hostId 为 0 表示新建主机
+func checkHostProxy(hostId, groupId, proxyHostId uint) error {
+	if proxyHostId == 0 {
+		return nil
+	}
+	if proxyHostId == hostId {
+		return errors.New("不能将主机自身设为跳板机")
+	}
+	_, err := newHostProxyResolver().chain(model.CmdbHost{ID: hostId, HostName: "当前主机", GroupID: groupId, ProxyHostID: proxyHostId})
+	return err
+}
+
+// 校验分组的跳板机配置，按修改后的配置检查跳板机自身的跳板机链
+func checkGroupProxy(groupId, proxyHostId uint) error {
+	if proxyHostId == 0 {
+		return nil
+	}
+	resolver := newHostProxyResolver()
+	group := resolver.groups[groupId]
+	group.ProxyHostID = proxyHostId
+	resolver.groups[groupId] = group
+	proxy, err := resolver.hostDao.GetCmdbHostById(proxyHostId)
+	if err != nil {
+		return errors.New("跳板机主机不存在")
+	}
+	_, err = resolver.chain(proxy)
+	return err
+}
+
+// 生成主机的 ssh ProxyJump 参数 user@ip:port,user@ip:port，不需要跳板机时返回空，供生成 ansible inventory 使用
+func HostProxyJump(host model.CmdbHost) (string, error) {
+	jumps, err := newHostProxyResolver().chain(host)
+	if err != nil {
+		return "", err
+	}
+	var hops []string
+	for _, jump := range jumps {
+		hops = append(hops, jump.SSHName+"@"+net.JoinHostPort(jump.SSHIP, strconv.Itoa(jump.SSHPort)))
+	}
+	return strings.Join(hops, ","), nil
+}
+
+// 为系统 ssh 命令准备跳板机的认证：私钥凭据写入 dir 下的私钥文件并生成对应的 ssh_config 配置，
+// 返回所有跳板机主机ID、ssh_config 配置和无法使用的跳板机信息
+func prepareProxyJumps(hostIds []uint, dir string) ([]uint, []string, []string, error) {
+	resolver := newHostProxyResolver()
+	ecsAuthDao := configDao.NewEcsAuthDao()
+	var jumpHostIds []uint
+	var sshConfig, warnings []string
+	prepared := map[uint]bool{}
+	for _, id := range hostIds {
+		host, err := resolver.hostDao.GetCmdbHostById(id)
+		if err != nil {
+			continue
+		}
+		jumps, err := resolver.chain(host)
+		if err != nil {
+			warnings = append(warnings, fmt.Sprintf("%s %v", host.HostName, err))
+			continue
+		}
+		for _, jump := range jumps {
+			if prepared[jump.ID] {
+				continue
+			}
+			prepared[jump.ID] = true
+			jumpHostIds = append(jumpHostIds, jump.ID)
+			auth, err := ecsAuthDao.GetById(jump.SSHKeyID)
+			if err != nil {
+				warnings = append(warnings, fmt.Sprintf("跳板机 %s 未配置SSH凭据", jump.HostName))
+				continue
+			}
+			configDao.MarkEcsAuthUsed(auth.ID)
+			switch auth.Type {
+			case 1: // 密码认证
+				warnings = append(warnings, fmt.Sprintf("跳板机 %s 使用密码认证，ansible 无法通过密码登录跳板机，请改用私钥或公钥免认证凭据", jump.HostName))
+			case 2: // 私钥认证
+				if err := auth.ResolveSecrets(); err != nil {
+					return nil, nil, warnings, fmt.Errorf("读取跳板机 %s 的SSH凭据失败: %v", jump.HostName, err)
+				}
+				keyPath, err := filepath.Abs(filepath.Join(dir, fmt.Sprintf("jump_%d_key", jump.ID)))
+				if err != nil {
+					return nil, nil, warnings, err
+				}
+				if err := os.WriteFile(keyPath, []byte(strings.TrimSpace(auth.PublicKey)+"\n"), 0600); err != nil {
+					return nil, nil, warnings, err
+				}
+				sshConfig = append(sshConfig, fmt.Sprintf("Host %s\n    IdentityFile %s", jump.SSHIP, keyPath))
+			}
+		}
+	}
+	return jumpHostIds, sshConfig, warnings, nil
+}
@@ -31,7 +31,7 @@ type CmdbHostSSHServiceInterface interface {
 type CmdbHostSSHServiceImpl struct{}
 
 func (s *CmdbHostSSHServiceImpl) ConnectTerminal(c *gin.Context, hostID uint) (*websocket.WebSSH, error) {
-	host, err := dao.NewCmdbHostSSHDao().GetHostSSHInfo(hostID)
+	host, err := dao.NewCmdbHostSSHDao().WithContext(c).GetHostSSHInfo(hostID)
 	if err != nil {
 		log.Printf("获取主机ID=%d信息失败: %v", hostID, err)
 		return nil, fmt.Errorf("获取主机信息失败: %v", err)
@@ -104,7 +104,7 @@ type CommandResponse struct {
 
 func (s *CmdbHostSSHServiceImpl) ExecuteCommand(c *gin.Context, hostID uint, command string) (*CommandResponse, error) {
 	// 创建独立的SSH连接，不使用WebSSH
-	host, err := dao.NewCmdbHostSSHDao().GetHostSSHInfo(hostID)
+	host, err := dao.NewCmdbHostSSHDao().WithContext(c).GetHostSSHInfo(hostID)
 	if err != nil {
 		return nil, fmt.Errorf("获取主机信息失败: %v", err)
 	}
@@ -155,11 +155,11 @@ func (s *CmdbHostSSHServiceImpl) ExecuteCommand(c *gin.Context, hostID uint, com
 	config := &ssh.ClientConfig{
 		User:            host.SSHName,
 		Auth:            []ssh.AuthMethod{authMethod},
-		HostKeyCallback: ssh.InsecureIgnoreHostKey(),
+		HostKeyCallback: util.HostKeyCallback(),
 		Timeout:         15 * time.Second,
 	}
 
-	client, err := ssh.Dial("tcp", fmt.Sprintf("%s:%d", host.SSHIP, host.SSHPort), config)
+	client, err := util.DialSSHAddr(fmt.Sprintf("%s:%d", host.SSHIP, host.SSHPort), config)
 	if err != nil {
 		return nil, fmt.Errorf("创建SSH连接失败: %v", err)
 	}
@@ -205,7 +205,7 @@ func (s *CmdbHostSSHServiceImpl) UploadFile(c *gin.Context, hostID uint, filePat
 		return fmt.Errorf("文件不存在: %s", filePath)
 	}
 
-	host, err := dao.NewCmdbHostSSHDao().GetHostSSHInfo(hostID)
+	host, err := dao.NewCmdbHostSSHDao().WithContext(c).GetHostSSHInfo(hostID)
 	if err != nil {
 		log.Printf("获取主机信息失败: %v", err)
 		return fmt.Errorf("获取主机信息失败: %v", err)
@@ -295,14 +295,14 @@ func (s *CmdbHostSSHServiceImpl) UploadFile(c *gin.Context, hostID uint, filePat
 		Auth: []ssh.AuthMethod{
 			ssh.Password(password),
 		},
-		HostKeyCallback: ssh.InsecureIgnoreHostKey(),
+		HostKeyCallback: util.HostKeyCallback(),
 		Timeout:         30 * time.Second, // 增加SSH连接超时时间
 	}
 
 	addr := fmt.Sprintf("%s:%d", host.SSHIP, host.SSHPort)
 
 	// 先测试基本SSH连接
-	testClient, err := ssh.Dial("tcp", addr, config)
+	testClient, err := util.DialSSHAddr(addr, config)
 	if err != nil {
 		log.Printf("SSH连接测试失败: %v", err)
 		return fmt.Errorf("无法连接到SSH服务器(%s): %v (请检查网络连接和服务器状态)", addr, err)
@@ -323,7 +323,7 @@ func (s *CmdbHostSSHServiceImpl) UploadFile(c *gin.Context, hostID uint, filePat
 	}
 
 	// 创建正式连接
-	client, err := ssh.Dial("tcp", addr, config)
+	client, err := util.DialSSHAddr(addr, config)
 	if err != nil {
 		log.Printf("创建SSH连接失败: %v", err)
 		return fmt.Errorf("创建SSH连接失败: %v", err)
@@ -0,0 +1,938 @@
+// 主机完整导出和导入 服务层
+// author xiaoRui
+
+package service
+
+import (
+	"bytes"
+	cmdbDao "dodevops-api/api/cmdb/dao"
+	"dodevops-api/api/cmdb/model"
+	configDao "dodevops-api/api/configcenter/dao"
+	systemservice "dodevops-api/api/system/service"
+	"dodevops-api/common"
+	"dodevops-api/common/result"
+	"dodevops-api/common/util"
+	"dodevops-api/pkg/datascope"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+	"github.com/xuri/excelize/v2"
+	"gorm.io/gorm"
+)
+
+// 导出和导入的列，导入时表头可以是列名或字段名，更新已有主机时文件中没有的列保持原值
+type hostTransferColumn struct {
+	key      string // 字段名，也是JSON格式中的键
+	title    string // 列名
+	column   string // 数据库列，为空表示只导出，导入时忽略
+	readOnly bool
+}
+
+var hostTransferColumns = []hostTransferColumn{
+	{key: "id", title: "ID", readOnly: true},
+	{key: "hostName", title: "主机名称", column: "host_name"},
+	{key: "name", title: "主机名", column: "name"},
+	{key: "groupPath", title: "分组", column: "group_id"},
+	{key: "sshIp", title: "SSH地址", column: "ssh_ip"},
+	{key: "sshPort", title: "SSH端口", column: "ssh_port"},
+	{key: "sshName", title: "SSH用户", column: "ssh_name"},
+	{key: "credential", title: "SSH凭据", column: "ssh_key_id"},
+	{key: "proxyHost", title: "跳板机", column: "proxy_host_id"},
+	{key: "privateIp", title: "内网IP", column: "private_ip"},
+	{key: "publicIp", title: "公网IP", column: "public_ip"},
+	{key: "instanceId", title: "实例ID", column: "instance_id"},
+	{key: "vendor", title: "厂商", column: "vendor"},
+	{key: "region", title: "地域", column: "region"},
+	{key: "os", title: "操作系统", column: "os"},
+	{key: "cpu", title: "CPU", readOnly: true},
+	{key: "memory", title: "内存", readOnly: true},
+	{key: "disk", title: "磁盘", readOnly: true},
+	{key: "status", title: "状态", readOnly: true},
+	{key: "ciType", title: "CI类型", column: "ci_type_id"},
+	{key: "labels", title: "标签"},
+	{key: "remark", title: "备注", column: "remark"},
+}
+
+// 自定义属性列的前缀，列名为 attr.属性键
+const hostAttrColumnPrefix = "attr."
+
+// 导入接口按更新主机授权，新增主机的行还需要新增主机的权限
+const hostCreatePermission = "cmdb:ecs:add"
+
+// 一次最多导入的行数
+const maxHostImportRows = 5000
+
+// 导入文件中的一行，只包含文件中出现的列
+type hostImportRow struct {
+	row    int
+	fields map[string]string
+}
+
+// 一行的导入计划
+type hostImportPlan struct {
+	action      string
+	matchBy     string
+	host        model.CmdbHost
+	fields      map[string]interface{} // 更新的数据库列
+	labels      map[string]string      // nil 表示不修改标签
+	attrs       []model.CmdbHostAttr
+	updateAttrs bool
+	changes     []model.CmdbHostImportChange
+}
+
+// 分组路径、凭据名称和CI类型的查找表
+type hostTransferLookup struct {
+	groupPaths map[uint]string
+	groupIds   map[string]uint
+	authNames  map[uint]string
+	authIds    map[string]uint
+	ciTypes    []model.CmdbCIType
+}
+
+func newHostTransferLookup(groupDao cmdbDao.CmdbGroupDao) *hostTransferLookup {
+	lookup := &hostTransferLookup{
+		groupPaths: map[uint]string{},
+		groupIds:   map[string]uint{},
+		authNames:  map[uint]string{},
+		authIds:    map[string]uint{},
+	}
+	groups := map[uint]model.CmdbGroup{}
+	for _, group := range groupDao.GetCmdbGroupList() {
+		groups[group.ID] = group
+	}
+	for id := range groups {
+		var names []string
+		// 按父分组向上查找，限制层级避免错误数据导致死循环
+		for current, depth := groups[id], 0; depth < 32; depth++ {
+			names = append([]string{current.Name}, names...)
+			parent, ok := groups[current.ParentID]
+			if current.ParentID == 0 || !ok {
+				break
+			}
+			current = parent
+		}
+		path := strings.Join(names, "/")
+		lookup.groupPaths[id] = path
+		if existId, ok := lookup.groupIds[path]; !ok || id < existId {
+			lookup.groupIds[path] = id
+		}
+	}
+	authDao := configDao.NewEcsAuthDao()
+	for _, auth := range authDao.GetEcsAuthList() {
+		lookup.authNames[auth.ID] = auth.Name
+		lookup.authIds[auth.Name] = auth.ID
+	}
+	ciTypeDao := cmdbDao.NewCmdbCITypeDao()
+	lookup.ciTypes = ciTypeDao.GetCITypeList()
+	return lookup
+}
+
+// 按编码或名称查找CI类型
+func (l *hostTransferLookup) findCIType(value string) (model.CmdbCIType, bool) {
+	for _, ciType := range l.ciTypes {
+		if ciType.Code == value || ciType.Name == value {
+			return ciType, true
+		}
+	}
+	return model.CmdbCIType{}, false
+}
+
+func (l *hostTransferLookup) ciTypeById(id uint) (model.CmdbCIType, bool) {
+	for _, ciType := range l.ciTypes {
+		if ciType.ID == id {
+			return ciType, true
+		}
+	}
+	return model.CmdbCIType{}, false
+}
+
+// 将主机转换为导出的行，返回用到的自定义属性列
+func (l *hostTransferLookup) records(hosts []model.CmdbHost) ([]map[string]string, []string) {
+	hostIds := make([]uint, 0, len(hosts))
+	for _, host := range hosts {
+		hostIds = append(hostIds, host.ID)
+	}
+	labels := map[uint]map[string]string{}
+	labelDao := cmdbDao.NewCmdbHostLabelDao()
+	for _, label := range labelDao.GetLabelsByHostIds(hostIds) {
+		if labels[label.HostID] == nil {
+			labels[label.HostID] = map[string]string{}
+		}
+		labels[label.HostID][label.Key] = label.Value
+	}
+	attrs := getHostAttrMap(hostIds, true)
+	// 跳板机导出为其SSH地址，跳板机可能在其他分组中，不按数据权限过滤
+	var proxyIds []uint
+	for _, host := range hosts {
+		if host.ProxyHostID > 0 {
+			proxyIds = append(proxyIds, host.ProxyHostID)
+		}
+	}
+	proxyAddrs := map[uint]string{}
+	if len(proxyIds) > 0 {
+		hostDao := cmdbDao.NewCmdbHostDao()
+		proxies, _ := hostDao.GetCmdbHostsByIds(proxyIds)
+		for _, proxy := range proxies {
+			proxyAddrs[proxy.ID] = proxy.SSHIP
+		}
+	}
+	ciTypeIds := map[uint]bool{}
+	records := make([]map[string]string, 0, len(hosts))
+	for _, host := range hosts {
+		ciTypeIds[host.CITypeID] = true
+		record := map[string]string{
+			"id":         strconv.Itoa(int(host.ID)),
+			"hostName":   host.HostName,
+			"name":       host.Name,
+			"groupPath":  l.groupPaths[host.GroupID],
+			"sshIp":      host.SSHIP,
+			"sshPort":    strconv.Itoa(host.SSHPort),
+			"sshName":    host.SSHName,
+			"credential": l.authNames[host.SSHKeyID],
+			"proxyHost":  proxyAddrs[host.ProxyHostID],
+			"privateIp":  host.PrivateIP,
+			"publicIp":   host.PublicIP,
+			"instanceId": host.InstanceID,
+			"vendor":     strconv.Itoa(host.Vendor),
+			"region":     host.Region,
+			"os":         host.OS,
+			"cpu":        host.CPU,
+			"memory":     host.Memory,
+			"disk":       host.Disk,
+			"status":     strconv.Itoa(host.Status),
+			"ciType":     "",
+			"labels":     formatHostLabels(labels[host.ID]),
+			"remark":     host.Remark,
+		}
+		if ciType, ok := l.ciTypeById(host.CITypeID); ok {
+			record["ciType"] = ciType.Code
+		}
+		for key, value := range attrs[host.ID] {
+			record[hostAttrColumnPrefix+key] = value
+		}
+		records = append(records, record)
+	}
+	// 自定义属性列按CI类型和属性的顺序排列
+	var attrColumns []string
+	for _, ciType := range l.ciTypes {
+		if !ciTypeIds[ciType.ID] {
+			continue
+		}
+		for _, field := range ciType.Fields {
+			if column := hostAttrColumnPrefix + field.Key; !containsString(attrColumns, column) {
+				attrColumns = append(attrColumns, column)
+			}
+		}
+	}
+	return records, attrColumns
+}
+
+// 按条件导出主机，包含分组路径、凭据名称、标签和自定义属性，导出的文件可以修改后重新导入
+func (s *CmdbHostServiceImpl) ExportHosts(c *gin.Context, query model.CmdbHostExportQuery) {
+	format := strings.ToLower(strings.TrimSpace(query.Format))
+	if format == "" {
+		format = model.HostTransferXLSX
+	}
+	if format != model.HostTransferXLSX && format != model.HostTransferCSV && format != model.HostTransferJSON {
+		result.Failed(c, int(result.ApiCode.FAILED), "导出格式只能是 xlsx、csv、json")
+		return
+	}
+	var hosts []model.CmdbHost
+	if query.GroupID > 0 {
+		if !datascope.AllowGroup(c, query.GroupID) {
+			result.Failed(c, int(result.ApiCode.NOPERMISSION), "没有该分组的数据权限")
+			return
+		}
+		hosts = s.dao.WithContext(c).GetCmdbHostsByGroupId(query.GroupID)
+	} else {
+		hosts = s.dao.WithContext(c).GetCmdbHostList()
+	}
+	var selected map[uint]bool
+	if strings.TrimSpace(query.Selector) != "" {
+		matched, err := selectHostsByLabels(c, query.Selector)
+		if err != nil {
+			result.Failed(c, int(result.ApiCode.FAILED), err.Error())
+			return
+		}
+		selected = map[uint]bool{}
+		for _, host := range matched {
+			selected[host.ID] = true
+		}
+	}
+	keyword := strings.TrimSpace(query.Keyword)
+	var filtered []model.CmdbHost
+	for _, host := range hosts {
+		if query.CITypeID > 0 && host.CITypeID != query.CITypeID {
+			continue
+		}
+		if query.Status > 0 && host.Status != query.Status {
+			continue
+		}
+		if selected != nil && !selected[host.ID] {
+			continue
+		}
+		if keyword != "" && !strings.Contains(host.HostName, keyword) && !strings.Contains(host.Name, keyword) &&
+			!strings.Contains(host.SSHIP, keyword) && !strings.Contains(host.PrivateIP, keyword) && !strings.Contains(host.PublicIP, keyword) {
+			continue
+		}
+		filtered = append(filtered, host)
+	}
+	sort.Slice(filtered, func(i, j int) bool { return filtered[i].ID < filtered[j].ID })
+
+	lookup := newHostTransferLookup(s.groupDao)
+	records, attrColumns := lookup.records(filtered)
+	filename := fmt.Sprintf("hosts_%s.%s", time.Now().Format("20060102150405"), format)
+	var buf bytes.Buffer
+	var err error
+	switch format {
+	case model.HostTransferXLSX:
+		err = writeHostTransferXLSX(&buf, records, attrColumns)
+		c.Header("Content-Type", "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet")
+	case model.HostTransferCSV:
+		err = writeHostTransferCSV(&buf, records, attrColumns)
+		c.Header("Content-Type", "text/csv; charset=utf-8")
+	case model.HostTransferJSON:
+		err = writeHostTransferJSON(&buf, records)
+		c.Header("Content-Type", "application/json; charset=utf-8")
+	}
+	if err != nil {
+		result.Failed(c, int(result.ApiCode.FAILED), "导出主机失败: "+err.Error())
+		return
+	}
+	c.Header("Content-Disposition", "attachment; filename="+filename)
+	c.Status(200)
+	_, _ = c.Writer.Write(buf.Bytes())
+}
+
+// 导出文件的表头和数据行
+func hostTransferTable(records []map[string]string, attrColumns []string) [][]string {
+	header := make([]string, 0, len(hostTransferColumns)+len(attrColumns))
+	keys := make([]string, 0, cap(header))
+	for _, column := range hostTransferColumns {
+		header = append(header, column.title)
+		keys = append(keys, column.key)
+	}
+	header = append(header, attrColumns...)
+	keys = append(keys, attrColumns...)
+	table := [][]string{header}
+	for _, record := range records {
+		row := make([]string, 0, len(keys))
+		for _, key := range keys {
+			row = append(row, record[key])
+		}
+		table = append(table, row)
+	}
+	return table
+}
+
+func writeHostTransferXLSX(w io.Writer, records []map[string]string, attrColumns []string) error {
+	f := excelize.NewFile()
+	defer f.Close()
+	for i, row := range hostTransferTable(records, attrColumns) {
+		cell, _ := excelize.CoordinatesToCellName(1, i+1)
+		values := toInterfaces(row)
+		if err := f.SetSheetRow("Sheet1", cell, &values); err != nil {
+			return err
+		}
+	}
+	return f.Write(w)
+}
+
+// CSV 以 UTF-8 BOM 开头，便于 Excel 正确识别中文
+func writeHostTransferCSV(w io.Writer, records []map[string]string, attrColumns []string) error {
+	if _, err := w.Write([]byte("\xEF\xBB\xBF")); err != nil {
+		return err
+	}
+	writer := csv.NewWriter(w)
+	if err := writer.WriteAll(hostTransferTable(records, attrColumns)); err != nil {
+		return err
+	}
+	return writer.Error()
+}
+
+// JSON 中数值列为数字，标签和自定义属性为对象
+func writeHostTransferJSON(w io.Writer, records []map[string]string) error {
+	items := make([]map[string]interface{}, 0, len(records))
+	for _, record := range records {
+		item := map[string]interface{}{}
+		for _, column := range hostTransferColumns {
+			value := record[column.key]
+			switch column.key {
+			case "id", "sshPort", "vendor", "status":
+				item[column.key], _ = strconv.Atoi(value)
+			case "labels":
+				item[column.key], _ = parseHostLabels(value)
+			default:
+				item[column.key] = value
+			}
+		}
+		attrs := map[string]string{}
+		for key, value := range record {
+			if strings.HasPrefix(key, hostAttrColumnPrefix) {
+				attrs[strings.TrimPrefix(key, hostAttrColumnPrefix)] = value
+			}
+		}
+		item["attrs"] = attrs
+		items = append(items, item)
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(items)
+}
+
+// 导入主机：按实例ID匹配已有主机，没有实例ID或未匹配到时按SSH地址匹配，匹配到的主机更新文件中出现的列，
+// 未匹配到的新增；dryRun 为 true 时只返回每一行的变化，不修改数据
+func (s *CmdbHostServiceImpl) ImportHosts(c *gin.Context, format string, reader io.Reader, dryRun bool) {
+	rows, err := parseHostTransferFile(format, reader)
+	if err != nil {
+		result.Failed(c, int(result.ApiCode.FAILED), err.Error())
+		return
+	}
+	if len(rows) == 0 {
+		result.Failed(c, int(result.ApiCode.FAILED), "文件中没有主机数据")
+		return
+	}
+	if len(rows) > maxHostImportRows {
+		result.Failed(c, int(result.ApiCode.FAILED), fmt.Sprintf("一次最多导入%d台主机", maxHostImportRows))
+		return
+	}
+	lookup := newHostTransferLookup(s.groupDao)
+	vo := model.CmdbHostImportVo{DryRun: dryRun, Total: len(rows), Rows: make([]model.CmdbHostImportRowVo, 0, len(rows))}
+	seen := map[string]int{}
+	for _, row := range rows {
+		rowVo := s.importHostRow(c, lookup, row, seen, dryRun)
+		switch rowVo.Action {
+		case model.HostImportCreate:
+			vo.Created++
+		case model.HostImportUpdate:
+			vo.Updated++
+		case model.HostImportUnchanged:
+			vo.Unchanged++
+		default:
+			vo.Failed++
+		}
+		vo.Rows = append(vo.Rows, rowVo)
+	}
+	result.Success(c, vo)
+}
+
+func (s *CmdbHostServiceImpl) importHostRow(c *gin.Context, lookup *hostTransferLookup, row hostImportRow, seen map[string]int, dryRun bool) model.CmdbHostImportRowVo {
+	vo := model.CmdbHostImportRowVo{Row: row.row, HostName: row.fields["hostName"], SSHIP: row.fields["sshIp"], Changes: []model.CmdbHostImportChange{}}
+	plan, err := s.planHostImport(c, lookup, row, seen)
+	if err != nil {
+		vo.Action, vo.Error = model.HostImportError, err.Error()
+		return vo
+	}
+	if plan.action == model.HostImportCreate && !systemservice.HasRequestPermission(c, hostCreatePermission) {
+		vo.Action, vo.Error = model.HostImportError, "没有新增主机的权限"
+		return vo
+	}
+	vo.Action, vo.MatchBy, vo.Changes = plan.action, plan.matchBy, plan.changes
+	vo.HostID, vo.HostName, vo.SSHIP = plan.host.ID, plan.host.HostName, plan.host.SSHIP
+	if dryRun || plan.action == model.HostImportUnchanged {
+		return vo
+	}
+	if err := s.applyHostImport(c, &plan); err != nil {
+		vo.Action, vo.Error = model.HostImportError, err.Error()
+		return vo
+	}
+	vo.HostID = plan.host.ID
+	// 与新增主机一样在提交后采集系统信息，没有SSH凭据的主机无法登录，保持未认证状态
+	if plan.action == model.HostImportCreate && plan.host.SSHKeyID > 0 {
+		s.collectHostSystemInfo(c, plan.host)
+	}
+	return vo
+}
+
+// 校验一行数据并计算与已有主机的差异
+func (s *CmdbHostServiceImpl) planHostImport(c *gin.Context, lookup *hostTransferLookup, row hostImportRow, seen map[string]int) (hostImportPlan, error) {
+	var plan hostImportPlan
+	fields := row.fields
+	instanceId := strings.TrimSpace(fields["instanceId"])
+	sshIp := strings.TrimSpace(fields["sshIp"])
+
+	// 匹配已有主机，不按数据权限过滤，避免重复创建其他分组中的主机
+	var existing *model.CmdbHost
+	if instanceId != "" {
+		if host, err := s.dao.GetCmdbHostByInstanceID(instanceId); err == nil {
+			existing, plan.matchBy = host, "instanceId"
+		}
+	}
+	if existing == nil && sshIp != "" {
+		if host := s.dao.GetCmdbHostBySSHIP(sshIp); host != nil {
+			if instanceId != "" && host.InstanceID != "" && host.InstanceID != instanceId {
+				return plan, fmt.Errorf("SSH地址 %s 已被实例 %s 使用", sshIp, host.InstanceID)
+			}
+			existing, plan.matchBy = host, "sshIp"
+		}
+	}
+	if existing != nil && sshIp != "" && sshIp != existing.SSHIP {
+		if other := s.dao.GetCmdbHostBySSHIP(sshIp); other != nil && other.ID != existing.ID {
+			return plan, fmt.Errorf("SSH地址 %s 已被主机 %s 使用", sshIp, other.HostName)
+		}
+	}
+	if existing != nil && !datascope.AllowGroup(c, existing.GroupID) {
+		return plan, fmt.Errorf("没有主机 %s 所在分组的数据权限", existing.HostName)
+	}
+	seenKey := "ssh:" + sshIp
+	if existing != nil {
+		seenKey = fmt.Sprintf("host:%d", existing.ID)
+	}
+	if first, ok := seen[seenKey]; ok {
+		return plan, fmt.Errorf("与第%d行是同一台主机", first)
+	}
+
+	var old map[string]string
+	if existing != nil {
+		plan.action, plan.host = model.HostImportUpdate, *existing
+		records, _ := lookup.records([]model.CmdbHost{*existing})
+		old = records[0]
+	} else {
+		if strings.TrimSpace(fields["hostName"]) == "" || strings.TrimSpace(fields["groupPath"]) == "" || sshIp == "" {
+			return plan, fmt.Errorf("新增主机时主机名称、分组和SSH地址不能为空")
+		}
+		plan.action, plan.matchBy = model.HostImportCreate, ""
+		plan.host = model.CmdbHost{SSHPort: 22, Vendor: 1, Status: 2}
+		old = map[string]string{}
+	}
+
+	// 按文件中出现的列设置新值
+	values := map[string]string{}
+	plan.fields = map[string]interface{}{}
+	host := &plan.host
+	for _, column := range hostTransferColumns {
+		raw, ok := fields[column.key]
+		if !ok || column.readOnly {
+			continue
+		}
+		value := strings.TrimSpace(raw)
+		var dbValue interface{} = value
+		switch column.key {
+		case "hostName":
+			if value == "" || utf8.RuneCountInString(value) > 64 {
+				return plan, fmt.Errorf("主机名称不能为空，最长64个字符")
+			}
+			host.HostName = value
+		case "name":
+			host.Name = value
+		case "groupPath":
+			groupId, ok := lookup.groupIds[strings.Trim(value, "/")]
+			if !ok {
+				return plan, fmt.Errorf("分组 %s 不存在", value)
+			}
+			if !datascope.AllowGroup(c, groupId) {
+				return plan, fmt.Errorf("没有分组 %s 的数据权限", value)
+			}
+			host.GroupID, dbValue, value = groupId, groupId, lookup.groupPaths[groupId]
+		case "sshIp":
+			if value == "" {
+				return plan, fmt.Errorf("SSH地址不能为空")
+			}
+			host.SSHIP = value
+		case "sshPort":
+			port := 22
+			if value != "" {
+				var err error
+				if port, err = strconv.Atoi(value); err != nil || port <= 0 || port > 65535 {
+					return plan, fmt.Errorf("SSH端口 %s 错误", value)
+				}
+			}
+			host.SSHPort, dbValue, value = port, port, strconv.Itoa(port)
+		case "sshName":
+			host.SSHName = value
+		case "credential":
+			var authId uint
+			if value != "" {
+				if authId, ok = lookup.authIds[value]; !ok {
+					return plan, fmt.Errorf("SSH凭据 %s 不存在", value)
+				}
+			}
+			host.SSHKeyID, dbValue = authId, authId
+		case "proxyHost":
+			var proxyId uint
+			if value != "" {
+				proxy := s.dao.GetCmdbHostBySSHIP(value)
+				if proxy == nil {
+					return plan, fmt.Errorf("跳板机 %s 不存在", value)
+				}
+				proxyId = proxy.ID
+			}
+			host.ProxyHostID, dbValue = proxyId, proxyId
+		case "privateIp":
+			host.PrivateIP = value
+		case "publicIp":
+			host.PublicIP = value
+		case "instanceId":
+			host.InstanceID = value
+		case "vendor":
+			vendor := 1
+			if value != "" {
+				var err error
+				if vendor, err = strconv.Atoi(value); err != nil || vendor <= 0 {
+					return plan, fmt.Errorf("厂商 %s 错误，1->自建,2->阿里云,3->腾讯云", value)
+				}
+			}
+			host.Vendor, dbValue, value = vendor, vendor, strconv.Itoa(vendor)
+		case "region":
+			host.Region = value
+		case "os":
+			host.OS = value
+		case "ciType":
+			var ciTypeId uint
+			if value != "" {
+				ciType, ok := lookup.findCIType(value)
+				if !ok {
+					return plan, fmt.Errorf("CI类型 %s 不存在", value)
+				}
+				ciTypeId, value = ciType.ID, ciType.Code
+			}
+			host.CITypeID, dbValue = ciTypeId, ciTypeId
+		case "labels":
+			labels, err := parseHostLabels(value)
+			if err != nil {
+				return plan, err
+			}
+			plan.labels, value = labels, formatHostLabels(labels)
+		case "remark":
+			host.Remark = value
+		}
+		values[column.key] = value
+		if value != old[column.key] || existing == nil && value != "" {
+			plan.changes = append(plan.changes, model.CmdbHostImportChange{Field: column.key, From: old[column.key], To: value})
+			if column.column != "" {
+				plan.fields[column.column] = dbValue
+			}
+		}
+	}
+	if existing == nil && host.Name == "" {
+		host.Name = host.HostName
+	}
+	// 跳板机或分组变化时按修改后的配置检查跳板机链
+	_, proxyChanged := plan.fields["proxy_host_id"]
+	_, groupChanged := plan.fields["group_id"]
+	if proxyChanged || groupChanged {
+		if err := checkHostProxy(host.ID, host.GroupID, host.ProxyHostID); err != nil {
+			return plan, err
+		}
+	}
+	if existing != nil && plan.labels != nil && values["labels"] == old["labels"] {
+		plan.labels = nil
+	}
+
+	// 自定义属性：CI类型不变时在原有属性上修改，CI类型变化时只保留文件中的属性
+	attrValues := map[string]string{}
+	attrPresent := false
+	ciTypeChanged := existing != nil && host.CITypeID != existing.CITypeID
+	if !ciTypeChanged {
+		for key, value := range old {
+			if strings.HasPrefix(key, hostAttrColumnPrefix) {
+				attrValues[strings.TrimPrefix(key, hostAttrColumnPrefix)] = value
+			}
+		}
+	}
+	ciType, _ := lookup.ciTypeById(host.CITypeID)
+	for key, value := range fields {
+		if strings.HasPrefix(key, hostAttrColumnPrefix) {
+			// 属性也可以按名称填写，统一为属性键后比较
+			name := strings.TrimPrefix(key, hostAttrColumnPrefix)
+			if field, ok := findCIField(ciType.Fields, name); ok {
+				name = field.Key
+			}
+			attrPresent = true
+			attrValues[name] = strings.TrimSpace(value)
+		}
+	}
+	if attrPresent || ciTypeChanged || existing == nil && host.CITypeID > 0 {
+		attrs, err := resolveHostAttrs(host.CITypeID, attrValues)
+		if err != nil {
+			return plan, err
+		}
+		var attrKeys []string
+		for key := range attrValues {
+			attrKeys = append(attrKeys, key)
+		}
+		for key := range old {
+			if name := strings.TrimPrefix(key, hostAttrColumnPrefix); name != key && !containsString(attrKeys, name) {
+				attrKeys = append(attrKeys, name)
+			}
+		}
+		sort.Strings(attrKeys)
+		for _, key := range attrKeys {
+			column := hostAttrColumnPrefix + key
+			if attrValues[key] != old[column] {
+				plan.changes = append(plan.changes, model.CmdbHostImportChange{Field: column, From: old[column], To: attrValues[key]})
+				plan.updateAttrs = true
+			}
+		}
+		plan.attrs = attrs
+		plan.updateAttrs = plan.updateAttrs || ciTypeChanged
+	}
+
+	if plan.changes == nil {
+		plan.changes = []model.CmdbHostImportChange{}
+	}
+	if existing != nil && len(plan.changes) == 0 {
+		plan.action = model.HostImportUnchanged
+	}
+	seen[seenKey] = row.row
+	return plan, nil
+}
+
+// 按导入计划新增或更新主机，记录变更历史；主机、标签和自定义属性在同一事务中保存，任一失败时整行不生效
+func (s *CmdbHostServiceImpl) applyHostImport(c *gin.Context, plan *hostImportPlan) error {
+	host := plan.host
+	labelDao := cmdbDao.NewCmdbHostLabelDao()
+	ciTypeDao := cmdbDao.NewCmdbCITypeDao()
+	err := common.GetDB().WithContext(c).Transaction(func(tx *gorm.DB) error {
+		hostDao := s.dao.WithTx(tx)
+		if plan.action == model.HostImportCreate {
+			host.CreateTime = util.HTime{Time: time.Now()}
+			if err := hostDao.CreateCmdbHost(&host); err != nil {
+				return fmt.Errorf("新增主机失败: %v", err)
+			}
+		} else if len(plan.fields) > 0 {
+			plan.fields["update_time"] = util.HTime{Time: time.Now()}
+			if err := hostDao.UpdateCmdbHostFields(host.ID, plan.fields); err != nil {
+				return fmt.Errorf("更新主机失败: %v", err)
+			}
+		}
+		if plan.labels != nil {
+			labels := make([]model.CmdbHostLabel, 0, len(plan.labels))
+			for key, value := range plan.labels {
+				labels = append(labels, model.CmdbHostLabel{HostID: host.ID, Key: key, Value: value})
+			}
+			sort.Slice(labels, func(i, j int) bool { return labels[i].Key < labels[j].Key })
+			if err := labelDao.WithTx(tx).ReplaceHostLabels(host.ID, labels); err != nil {
+				return fmt.Errorf("保存标签失败: %v", err)
+			}
+		}
+		if plan.updateAttrs || plan.action == model.HostImportCreate && len(plan.attrs) > 0 {
+			for i := range plan.attrs {
+				plan.attrs[i].HostID = host.ID
+			}
+			if err := ciTypeDao.WithTx(tx).ReplaceHostAttrs(host.ID, plan.attrs); err != nil {
+				return fmt.Errorf("保存自定义属性失败: %v", err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	plan.host = host
+	return nil
+}
+
+// 解析导入文件，XLSX 读取第一个工作表
+func parseHostTransferFile(format string, reader io.Reader) ([]hostImportRow, error) {
+	switch strings.ToLower(format) {
+	case model.HostTransferXLSX:
+		f, err := excelize.OpenReader(reader)
+		if err != nil {
+			return nil, fmt.Errorf("打开Excel文件失败")
+		}
+		defer f.Close()
+		sheets := f.GetSheetList()
+		if len(sheets) == 0 {
+			return nil, fmt.Errorf("Excel文件中没有工作表")
+		}
+		table, err := f.GetRows(sheets[0])
+		if err != nil {
+			return nil, fmt.Errorf("读取Excel数据失败")
+		}
+		return parseHostTransferTable(table)
+	case model.HostTransferCSV:
+		data, err := io.ReadAll(reader)
+		if err != nil {
+			return nil, fmt.Errorf("读取CSV文件失败")
+		}
+		csvReader := csv.NewReader(bytes.NewReader(bytes.TrimPrefix(data, []byte("\xEF\xBB\xBF"))))
+		csvReader.FieldsPerRecord = -1
+		table, err := csvReader.ReadAll()
+		if err != nil {
+			return nil, fmt.Errorf("解析CSV文件失败: %v", err)
+		}
+		return parseHostTransferTable(table)
+	case model.HostTransferJSON:
+		var items []map[string]interface{}
+		if err := json.NewDecoder(reader).Decode(&items); err != nil {
+			return nil, fmt.Errorf("解析JSON文件失败，内容应为主机对象数组: %v", err)
+		}
+		return parseHostTransferJSON(items)
+	}
+	return nil, fmt.Errorf("文件格式只能是 xlsx、csv、json")
+}
+
+// 第一行为表头，空行忽略
+func parseHostTransferTable(table [][]string) ([]hostImportRow, error) {
+	if len(table) == 0 {
+		return nil, nil
+	}
+	keys := make([]string, len(table[0]))
+	for i, title := range table[0] {
+		title = strings.TrimSpace(title)
+		if title == "" {
+			continue
+		}
+		key, ok := hostTransferColumnKey(title)
+		if !ok {
+			return nil, fmt.Errorf("第%d列的表头 %q 无法识别", i+1, title)
+		}
+		if containsString(keys, key) {
+			return nil, fmt.Errorf("第%d列的表头 %q 重复", i+1, title)
+		}
+		keys[i] = key
+	}
+	var rows []hostImportRow
+	for i, cells := range table[1:] {
+		fields := map[string]string{}
+		empty := true
+		for j, key := range keys {
+			if key == "" || isReadOnlyHostColumn(key) {
+				continue
+			}
+			value := ""
+			if j < len(cells) {
+				value = cells[j]
+			}
+			if strings.TrimSpace(value) != "" {
+				empty = false
+			}
+			fields[key] = value
+		}
+		if !empty {
+			rows = append(rows, hostImportRow{row: i + 2, fields: fields})
+		}
+	}
+	return rows, nil
+}
+
+// JSON 中标签和自定义属性可以是对象，也可以与表格一样使用字符串和 attr.属性键
+func parseHostTransferJSON(items []map[string]interface{}) ([]hostImportRow, error) {
+	rows := make([]hostImportRow, 0, len(items))
+	for i, item := range items {
+		fields := map[string]string{}
+		for key, value := range item {
+			switch {
+			case key == "labels":
+				if labels, ok := value.(map[string]interface{}); ok {
+					values := map[string]string{}
+					for k, v := range labels {
+						text, err := jsonScalarString(v)
+						if err != nil {
+							return nil, fmt.Errorf("第%d条的标签 %s 错误: %v", i+1, k, err)
+						}
+						values[k] = text
+					}
+					fields[key] = formatHostLabels(values)
+					continue
+				}
+			case key == "attrs":
+				attrs, ok := value.(map[string]interface{})
+				if !ok && value != nil {
+					return nil, fmt.Errorf("第%d条的 attrs 应为对象", i+1)
+				}
+				for k, v := range attrs {
+					text, err := jsonScalarString(v)
+					if err != nil {
+						return nil, fmt.Errorf("第%d条的自定义属性 %s 错误: %v", i+1, k, err)
+					}
+					fields[hostAttrColumnPrefix+k] = text
+				}
+				continue
+			}
+			column, ok := hostTransferColumnKey(key)
+			if !ok {
+				return nil, fmt.Errorf("第%d条的字段 %q 无法识别", i+1, key)
+			}
+			if isReadOnlyHostColumn(column) {
+				continue
+			}
+			text, err := jsonScalarString(value)
+			if err != nil {
+				return nil, fmt.Errorf("第%d条的字段 %s 错误: %v", i+1, key, err)
+			}
+			fields[column] = text
+		}
+		rows = append(rows, hostImportRow{row: i + 1, fields: fields})
+	}
+	return rows, nil
+}
+
+func jsonScalarString(value interface{}) (string, error) {
+	switch v := value.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return v, nil
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	}
+	return "", fmt.Errorf("应为字符串或数字")
+}
+
+// 按列名或字段名查找列，自定义属性列为 attr.属性键
+func hostTransferColumnKey(title string) (string, bool) {
+	if strings.HasPrefix(title, hostAttrColumnPrefix) && len(title) > len(hostAttrColumnPrefix) {
+		return title, true
+	}
+	for _, column := range hostTransferColumns {
+		if title == column.title || strings.EqualFold(title, column.key) {
+			return column.key, true
+		}
+	}
+	return "", false
+}
+
+func isReadOnlyHostColumn(key string) bool {
+	for _, column := range hostTransferColumns {
+		if column.key == key {
+			return column.readOnly
+		}
+	}
+	return false
+}
+
+// 解析标签，格式为 key=value，多个标签用逗号分隔
+func parseHostLabels(value string) (map[string]string, error) {
+	labels := map[string]string{}
+	for _, part := range strings.Split(value, ",") {
+		if part = strings.TrimSpace(part); part == "" {
+			continue
+		}
+		kv := strings.SplitN(part, "=", 2)
+		if len(kv) != 2 {
+			return nil, fmt.Errorf("标签 %q 格式错误，应为 key=value", part)
+		}
+		key, labelValue := strings.TrimSpace(kv[0]), strings.TrimSpace(kv[1])
+		if err := checkLabel(key, labelValue); err != nil {
+			return nil, err
+		}
+		labels[key] = labelValue
+	}
+	return labels, nil
+}
+
+// 标签按键排序后格式化为 key=value,key=value
+func formatHostLabels(labels map[string]string) string {
+	keys := make([]string, 0, len(labels))
+	for key := range labels {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	parts := make([]string, 0, len(keys))
+	for _, key := range keys {
+		parts = append(parts, key+"="+labels[key])
+	}
+	return strings.Join(parts, ",")
+}
@@ -0,0 +1,421 @@
+// CMDB配置项关系 服务层
+// author xiaoRui
+
+package service
+
+import (
+	"context"
+	"dodevops-api/api/cmdb/dao"
+	"dodevops-api/api/cmdb/model"
+	"dodevops-api/common/result"
+	"dodevops-api/common/util"
+	"fmt"
+	"sort"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+type CmdbRelationServiceInterface interface {
+	CreateRelation(c *gin.Context, dto model.CmdbRelationDto)                      // 新增关系
+	DeleteRelation(c *gin.Context, id uint)                                        // 删除关系
+	GetGraph(c *gin.Context, kind string, id uint, depth int, relationType string) // 查询配置项N跳内的关系图
+	GetImpact(c *gin.Context, kind string, id uint, depth int)                     // 影响分析
+	Discover(c *gin.Context)                                                       // 按现有数据自动发现关系
+}
+
+type CmdbRelationServiceImpl struct{}
+
+// 关系图最多展开的配置项数
+const relationGraphMaxNodes = 500
+
+// 配置项标识
+type ciKey struct {
+	Kind string
+	ID   uint
+}
+
+var ciKinds = []string{model.CIKindHost, model.CIKindDatabase, model.CIKindCluster, model.CIKindApp}
+
+var relationTypes = []string{model.RelationRunsOn, model.RelationDependsOn, model.RelationBelongsTo, model.RelationConnectsTo}
+
+// 新增关系
+func (s CmdbRelationServiceImpl) CreateRelation(c *gin.Context, dto model.CmdbRelationDto) {
+	if !containsString(relationTypes, dto.RelationType) {
+		result.Failed(c, int(result.ApiCode.FAILED), "关系类型只能是 runs-on、depends-on、belongs-to、connects-to")
+		return
+	}
+	if dto.SourceType == dto.TargetType && dto.SourceID == dto.TargetID {
+		result.Failed(c, int(result.ApiCode.FAILED), "不能建立配置项到自身的关系")
+		return
+	}
+	if utf8.RuneCountInString(dto.Remark) > 255 {
+		result.Failed(c, int(result.ApiCode.FAILED), "备注最长255个字符")
+		return
+	}
+	for _, key := range []ciKey{{dto.SourceType, dto.SourceID}, {dto.TargetType, dto.TargetID}} {
+		if _, err := getCINode(c, key); err != nil {
+			result.Failed(c, int(result.ApiCode.FAILED), err.Error())
+			return
+		}
+	}
+	relation := model.CmdbRelation{
+		SourceType:   dto.SourceType,
+		SourceID:     dto.SourceID,
+		TargetType:   dto.TargetType,
+		TargetID:     dto.TargetID,
+		RelationType: dto.RelationType,
+		Origin:       model.RelationOriginManual,
+		Remark:       dto.Remark,
+		CreateTime:   util.HTime{Time: time.Now()},
+	}
+	relationDao := dao.NewCmdbRelationDao()
+	if relationDao.CheckRelationExists(relation) {
+		result.Failed(c, int(result.ApiCode.FAILED), "关系已存在")
+		return
+	}
+	if err := relationDao.CreateRelation(&relation); err != nil {
+		result.Failed(c, int(result.ApiCode.FAILED), err.Error())
+		return
+	}
+	result.Success(c, relation)
+}
+
+// 删除关系，自动发现的关系删除后会在下次发现时重新生成
+func (s CmdbRelationServiceImpl) DeleteRelation(c *gin.Context, id uint) {
+	relationDao := dao.NewCmdbRelationDao()
+	if _, err := relationDao.GetRelationById(id); err != nil {
+		result.Failed(c, int(result.ApiCode.FAILED), "关系不存在")
+		return
+	}
+	if err := relationDao.DeleteRelation(id); err != nil {
+		result.Failed(c, int(result.ApiCode.FAILED), err.Error())
+		return
+	}
+	result.Success(c, true)
+}
+
+// 从起点按关系双向展开N跳，返回途经的配置项和关系，relationType 不为空时只沿该类型的关系展开
+func (s CmdbRelationServiceImpl) GetGraph(c *gin.Context, kind string, id uint, depth int, relationType string) {
+	if relationType != "" && !containsString(relationTypes, relationType) {
+		result.Failed(c, int(result.ApiCode.FAILED), "关系类型只能是 runs-on、depends-on、belongs-to、connects-to")
+		return
+	}
+	root, err := getCINode(c, ciKey{kind, id})
+	if err != nil {
+		result.Failed(c, int(result.ApiCode.FAILED), err.Error())
+		return
+	}
+	relationDao := dao.NewCmdbRelationDao()
+	depths := map[ciKey]int{{kind, id}: 0}
+	edges := make(map[uint]model.CmdbRelation)
+	frontier := []ciKey{{kind, id}}
+	truncated := false
+	for level := 1; level <= depth && len(frontier) > 0; level++ {
+		var next []ciKey
+		for _, group := range groupCIKeys(frontier) {
+			for _, relation := range relationDao.GetRelationsByCIs(group.Kind, group.IDs) {
+				if relationType != "" && relation.RelationType != relationType {
+					continue
+				}
+				edges[relation.ID] = relation
+				for _, key := range []ciKey{{relation.SourceType, relation.SourceID}, {relation.TargetType, relation.TargetID}} {
+					if _, ok := depths[key]; ok {
+						continue
+					}
+					if len(depths) >= relationGraphMaxNodes {
+						truncated = true
+						continue
+					}
+					depths[key] = level
+					next = append(next, key)
+				}
+			}
+		}
+		frontier = next
+	}
+
+	// 查询名称，已删除的配置项及其关系不返回
+	var keys []ciKey
+	for key := range depths {
+		keys = append(keys, key)
+	}
+	names := getCINames(keys)
+	graph := model.CmdbRelationGraphVo{Nodes: []model.CmdbCINode{root}, Edges: []model.CmdbRelation{}, Truncated: truncated}
+	for key, d := range depths {
+		if name, ok := names[key]; ok && d > 0 {
+			graph.Nodes = append(graph.Nodes, model.CmdbCINode{Type: key.Kind, ID: key.ID, Name: name, Depth: d})
+		}
+	}
+	sort.Slice(graph.Nodes, func(i, j int) bool {
+		a, b := graph.Nodes[i], graph.Nodes[j]
+		if a.Depth != b.Depth {
+			return a.Depth < b.Depth
+		}
+		if a.Type != b.Type {
+			return a.Type < b.Type
+		}
+		return a.ID < b.ID
+	})
+	for _, relation := range edges {
+		_, sourceOk := names[ciKey{relation.SourceType, relation.SourceID}]
+		_, targetOk := names[ciKey{relation.TargetType, relation.TargetID}]
+		if sourceOk && targetOk {
+			graph.Edges = append(graph.Edges, relation)
+		}
+	}
+	sort.Slice(graph.Edges, func(i, j int) bool { return graph.Edges[i].ID < graph.Edges[j].ID })
+	result.Success(c, graph)
+}
+
+// 影响分析：配置项故障后，运行在其上(runs-on)或依赖它(depends-on)的配置项受影响，
+// 它所属(belongs-to)的配置项受影响，影响沿关系逐跳传递
+func (s CmdbRelationServiceImpl) GetImpact(c *gin.Context, kind string, id uint, depth int) {
+	root, err := getCINode(c, ciKey{kind, id})
+	if err != nil {
+		result.Failed(c, int(result.ApiCode.FAILED), err.Error())
+		return
+	}
+	type impact struct {
+		key      ciKey
+		depth    int
+		relation string
+		parent   ciKey
+	}
+	relationDao := dao.NewCmdbRelationDao()
+	rootKey := ciKey{kind, id}
+	impacts := map[ciKey]impact{rootKey: {key: rootKey}}
+	var order []ciKey
+	frontier := []ciKey{rootKey}
+	for level := 1; level <= depth && len(frontier) > 0; level++ {
+		var next []ciKey
+		add := func(key ciKey, relation string, parent ciKey) {
+			if _, ok := impacts[key]; ok || len(impacts) >= relationGraphMaxNodes {
+				return
+			}
+			impacts[key] = impact{key: key, depth: level, relation: relation, parent: parent}
+			order = append(order, key)
+			next = append(next, key)
+		}
+		for _, group := range groupCIKeys(frontier) {
+			for _, relation := range relationDao.GetRelationsByTargets(group.Kind, group.IDs) {
+				if relation.RelationType == model.RelationRunsOn || relation.RelationType == model.RelationDependsOn {
+					add(ciKey{relation.SourceType, relation.SourceID}, relation.RelationType, ciKey{relation.TargetType, relation.TargetID})
+				}
+			}
+			for _, relation := range relationDao.GetRelationsBySources(group.Kind, group.IDs) {
+				if relation.RelationType == model.RelationBelongsTo {
+					add(ciKey{relation.TargetType, relation.TargetID}, relation.RelationType, ciKey{relation.SourceType, relation.SourceID})
+				}
+			}
+		}
+		frontier = next
+	}
+
+	names := getCINames(append(order, rootKey))
+	vo := model.CmdbImpactVo{Root: root, Affected: []model.CmdbImpactNode{}, Apps: []model.CmdbCINode{}}
+	for _, key := range order {
+		name, ok := names[key]
+		if !ok {
+			continue
+		}
+		item := impacts[key]
+		node := model.CmdbCINode{Type: key.Kind, ID: key.ID, Name: name, Depth: item.depth}
+		var path []string
+		for cur := key; cur != rootKey; cur = impacts[cur].parent {
+			path = append([]string{names[cur]}, path...)
+		}
+		vo.Affected = append(vo.Affected, model.CmdbImpactNode{CmdbCINode: node, Relation: item.relation, Path: append([]string{root.Name}, path...)})
+		if key.Kind == model.CIKindApp {
+			vo.Apps = append(vo.Apps, node)
+		}
+	}
+	result.Success(c, vo)
+}
+
+// 按现有数据自动发现关系：
+// 应用关联的主机(runs-on)和数据库(depends-on)，有发布成功记录的应用的主机视为快速发布的目标主机；
+// 通过kubeconfig查询集群节点IP，与主机的SSH地址、内网IP或公网IP匹配的主机属于该集群(belongs-to)。
+// 已不存在的自动发现关系会被删除，无法连接的集群保留原有关系，手工维护的关系不受影响
+func (s CmdbRelationServiceImpl) Discover(c *gin.Context) {
+	relationDao := dao.NewCmdbRelationDao()
+	now := util.HTime{Time: time.Now()}
+	found := make(map[model.CmdbRelation]bool)
+	var discovered []model.CmdbRelation
+	add := func(source, target ciKey, relationType, remark string) {
+		relation := model.CmdbRelation{SourceType: source.Kind, SourceID: source.ID, TargetType: target.Kind, TargetID: target.ID, RelationType: relationType}
+		if found[relation] {
+			return
+		}
+		found[relation] = true
+		relation.Origin, relation.Remark, relation.CreateTime = model.RelationOriginAuto, remark, now
+		discovered = append(discovered, relation)
+	}
+
+	apps := relationDao.GetApplications()
+	deployed := make(map[uint]bool)
+	for _, appId := range relationDao.GetDeployedAppIds() {
+		deployed[appId] = true
+	}
+	var hostIds, databaseIds []uint
+	for _, app := range apps {
+		hostIds = append(hostIds, app.Hosts...)
+		databaseIds = append(databaseIds, app.Databases...)
+	}
+	hostNames := relationDao.GetCINames(model.CIKindHost, hostIds)
+	databaseNames := relationDao.GetCINames(model.CIKindDatabase, databaseIds)
+	for _, app := range apps {
+		remark := "应用关联主机"
+		if deployed[app.ID] {
+			remark = "快速发布目标主机"
+		}
+		for _, hostId := range app.Hosts {
+			if _, ok := hostNames[hostId]; ok {
+				add(ciKey{model.CIKindApp, app.ID}, ciKey{model.CIKindHost, hostId}, model.RelationRunsOn, remark)
+			}
+		}
+		for _, databaseId := range app.Databases {
+			if _, ok := databaseNames[databaseId]; ok {
+				add(ciKey{model.CIKindApp, app.ID}, ciKey{model.CIKindDatabase, databaseId}, model.RelationDependsOn, "应用关联数据库")
+			}
+		}
+	}
+
+	vo := model.CmdbRelationDiscoverVo{Errors: []string{}}
+	failedClusters := make(map[uint]bool)
+	for _, cluster := range relationDao.GetClustersWithCredential() {
+		ips, err := listClusterNodeIPs(c, cluster.Credential)
+		if err != nil {
+			failedClusters[cluster.ID] = true
+			vo.Errors = append(vo.Errors, fmt.Sprintf("集群[%s]: %v", cluster.Name, err))
+			continue
+		}
+		for _, host := range relationDao.GetHostsByIPs(ips) {
+			add(ciKey{model.CIKindHost, host.ID}, ciKey{model.CIKindCluster, cluster.ID}, model.RelationBelongsTo, "集群节点IP")
+		}
+	}
+
+	existing := make(map[model.CmdbRelation]bool)
+	var removeIds []uint
+	for _, relation := range relationDao.GetAllRelations() {
+		key := model.CmdbRelation{SourceType: relation.SourceType, SourceID: relation.SourceID, TargetType: relation.TargetType, TargetID: relation.TargetID, RelationType: relation.RelationType}
+		existing[key] = true
+		if relation.Origin != model.RelationOriginAuto || found[key] {
+			continue
+		}
+		if relation.RelationType == model.RelationBelongsTo && relation.TargetType == model.CIKindCluster && failedClusters[relation.TargetID] {
+			continue
+		}
+		removeIds = append(removeIds, relation.ID)
+	}
+	var creates []model.CmdbRelation
+	for _, relation := range discovered {
+		key := relation
+		key.Origin, key.Remark, key.CreateTime = 0, "", util.HTime{}
+		if !existing[key] {
+			creates = append(creates, relation)
+		}
+	}
+	if err := relationDao.DeleteRelationsByIds(removeIds); err != nil {
+		result.Failed(c, int(result.ApiCode.FAILED), err.Error())
+		return
+	}
+	if err := relationDao.CreateRelations(creates); err != nil {
+		result.Failed(c, int(result.ApiCode.FAILED), err.Error())
+		return
+	}
+	vo.Created, vo.Removed = len(creates), len(removeIds)
+	result.Success(c, vo)
+}
+
+// 查询配置项，主机按当前用户的数据权限校验
+func getCINode(c *gin.Context, key ciKey) (model.CmdbCINode, error) {
+	if !containsString(ciKinds, key.Kind) {
+		return model.CmdbCINode{}, fmt.Errorf("配置项类型只能是 host、database、cluster、app")
+	}
+	if key.Kind == model.CIKindHost {
+		hostDao := dao.NewCmdbHostDao()
+		host, err := hostDao.WithContext(c).GetCmdbHostById(key.ID)
+		if err != nil {
+			return model.CmdbCINode{}, fmt.Errorf("主机不存在")
+		}
+		return model.CmdbCINode{Type: key.Kind, ID: key.ID, Name: host.HostName}, nil
+	}
+	relationDao := dao.NewCmdbRelationDao()
+	name, ok := relationDao.GetCINames(key.Kind, []uint{key.ID})[key.ID]
+	if !ok {
+		return model.CmdbCINode{}, fmt.Errorf("配置项[%s:%d]不存在", key.Kind, key.ID)
+	}
+	return model.CmdbCINode{Type: key.Kind, ID: key.ID, Name: name}, nil
+}
+
+// 按类型分组的配置项ID
+type ciKeyGroup struct {
+	Kind string
+	IDs  []uint
+}
+
+func groupCIKeys(keys []ciKey) []ciKeyGroup {
+	var groups []ciKeyGroup
+	for _, kind := range ciKinds {
+		group := ciKeyGroup{Kind: kind}
+		for _, key := range keys {
+			if key.Kind == kind {
+				group.IDs = append(group.IDs, key.ID)
+			}
+		}
+		if len(group.IDs) > 0 {
+			groups = append(groups, group)
+		}
+	}
+	return groups
+}
+
+// 批量查询配置项名称，已删除的配置项不在结果中
+func getCINames(keys []ciKey) map[ciKey]string {
+	relationDao := dao.NewCmdbRelationDao()
+	names := make(map[ciKey]string)
+	for _, group := range groupCIKeys(keys) {
+		for id, name := range relationDao.GetCINames(group.Kind, group.IDs) {
+			names[ciKey{group.Kind, id}] = name
+		}
+	}
+	return names
+}
+
+// 通过kubeconfig查询集群全部节点的内网IP和外网IP
+func listClusterNodeIPs(ctx context.Context, kubeconfig string) ([]string, error) {
+	config, err := clientcmd.RESTConfigFromKubeConfig([]byte(kubeconfig))
+	if err != nil {
+		return nil, fmt.Errorf("解析kubeconfig失败: %v", err)
+	}
+	config.Timeout = 5 * time.Second
+	clientset, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return nil, fmt.Errorf("创建K8s客户端失败: %v", err)
+	}
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	nodes, err := clientset.CoreV1().Nodes().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("获取节点列表失败: %v", err)
+	}
+	var ips []string
+	for _, node := range nodes.Items {
+		for _, addr := range node.Status.Addresses {
+			if addr.Type == corev1.NodeInternalIP || addr.Type == corev1.NodeExternalIP {
+				ips = append(ips, addr.Address)
+			}
+		}
+	}
+	return ips, nil
+}
+
+func GetCmdbRelationService() CmdbRelationServiceInterface {
+	return CmdbRelationServiceImpl{}
+}
@@ -1,6 +1,7 @@
 package service
 
 import (
+	"context"
 	"errors"
 	"dodevops-api/api/cmdb/dao"
 	"dodevops-api/api/cmdb/model"
@@ -14,6 +15,11 @@ func NewCmdbSQLService(dao *dao.CmdbSQLDao) *CmdbSQLService {
 	return &CmdbSQLService{dao: dao}
 }
 
+// WithContext 绑定请求上下文，变更历史按上下文记录操作人
+func (s *CmdbSQLService) WithContext(ctx context.Context) *CmdbSQLService {
+	return &CmdbSQLService{dao: s.dao.WithContext(ctx)}
+}
+
 // CreateDatabase 创建数据库记录
 func (s *CmdbSQLService) CreateDatabase(db *model.CmdbSQL) error {
 	// 验证类型值
@@ -34,7 +40,11 @@ func (s *CmdbSQLService) UpdateDatabase(db *model.CmdbSQL) error {
 
 // DeleteDatabase 删除数据库记录
 func (s *CmdbSQLService) DeleteDatabase(id uint) error {
-	return s.dao.Delete(id)
+	if err := s.dao.Delete(id); err != nil {
+		return err
+	}
+	relationDao := dao.NewCmdbRelationDao()
+	return relationDao.DeleteRelationsByCI(model.CIKindDatabase, id)
 }
 
 // GetDatabase 获取单个数据库详情
@@ -65,18 +65,20 @@ func (c *AccountAuthController) Update(ctx *gin.Context) {
 // @Summary 删除账号认证信息
 // @Tags Config配置中心
 // @Param id query uint true "账号ID"
+// @Param reassignTo query uint false "迁移账号ID，账号仍被数据库或Jenkins环境引用时必填"
 // @Success 200 {object} result.Result
 // @Router /api/v1/config/accountauth [delete]
 // @Security ApiKeyAuth
 func (c *AccountAuthController) Delete(ctx *gin.Context) {
 	var req struct {
-		ID uint `form:"id" binding:"required"`
+		ID         uint `form:"id" binding:"required"`
+		ReassignTo uint `form:"reassignTo"`
 	}
 	if err := ctx.ShouldBindQuery(&req); err != nil {
 		result.Failed(ctx, 400, "参数错误: "+err.Error())
 		return
 	}
-	if err := c.service.Delete(req.ID); err != nil {
+	if err := c.service.Delete(req.ID, req.ReassignTo); err != nil {
 		result.Failed(ctx, 500, "删除失败: "+err.Error())
 		return
 	}
@@ -197,3 +199,27 @@ func (c *AccountAuthController) GetByAlias(ctx *gin.Context) {
 	}
 	result.Success(ctx, account)
 }
+
+// GetUsage 获取账号的引用和使用情况
+// @Summary 获取账号的引用和使用情况
+// @Tags Config配置中心
+// @Param id query uint true "账号ID"
+// @Success 200 {object} result.Result{data=model.CredentialUsageVo}
+// @Router /api/v1/config/accountauth/usage [get]
+// @Security ApiKeyAuth
+func (c *AccountAuthController) GetUsage(ctx *gin.Context) {
+	var req struct {
+		ID uint `form:"id" binding:"required"`
+	}
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		result.Failed(ctx, 400, "参数错误: "+err.Error())
+		return
+	}
+	usage, err := c.service.GetUsage(req.ID)
+	if err != nil {
+		result.Failed(ctx, 500, "查询失败: "+err.Error())
+		return
+	}
+
+	result.Success(ctx, usage)
+}
@@ -93,17 +93,18 @@ func (c *EcsAuthController) UpdateEcsAuth(ctx *gin.Context) {
 // DeleteEcsAuth 删除凭据
 // @Summary 删除凭据
 // @Tags Config配置中心
-// @Param data body model.EcsAuthIdDto true "凭据ID"
+// @Description 凭据被主机引用时需指定 reassignTo，将主机迁移到该凭据后再删除
+// @Param data body model.DeleteEcsAuthDto true "凭据ID"
 // @Success 200 {object} result.Result
 // @Router /api/v1/config/ecsauthdelete [delete]
 // @Security ApiKeyAuth
 func (c *EcsAuthController) DeleteEcsAuth(ctx *gin.Context) {
-	var idDto model.EcsAuthIdDto
-	if err := ctx.ShouldBindJSON(&idDto); err != nil {
+	var dto model.DeleteEcsAuthDto
+	if err := ctx.ShouldBindJSON(&dto); err != nil {
 		result.Failed(ctx, int(result.ApiCode.FAILED), err.Error())
 		return
 	}
-	c.service.DeleteEcsAuth(ctx, idDto.Id)
+	c.service.DeleteEcsAuth(ctx, &dto)
 }
 
 // GetEcsAuthById 根据ID获取凭据详情
@@ -126,3 +127,52 @@ func (c *EcsAuthController) GetEcsAuthById(ctx *gin.Context) {
 	}
 	c.service.GetEcsAuthById(ctx, uint(id))
 }
+
+// GenerateEcsKeyAuth 生成密钥对并创建密钥认证凭据
+// @Summary 生成密钥对并创建密钥认证凭据
+// @Tags Config配置中心
+// @Param data body model.GenerateEcsKeyAuthDto true "凭据信息"
+// @Success 200 {object} result.Result{data=model.EcsAuthPublicKeyVo}
+// @Router /api/v1/config/ecsauthgenerate [post]
+// @Security ApiKeyAuth
+func (c *EcsAuthController) GenerateEcsKeyAuth(ctx *gin.Context) {
+	var dto model.GenerateEcsKeyAuthDto
+	if err := ctx.ShouldBindJSON(&dto); err != nil {
+		result.Failed(ctx, int(result.ApiCode.FAILED), err.Error())
+		return
+	}
+	c.service.GenerateEcsKeyAuth(ctx, &dto)
+}
+
+// GetEcsAuthPublicKey 获取密钥认证凭据的公钥
+// @Summary 获取密钥认证凭据的公钥
+// @Tags Config配置中心
+// @Param id query int true "凭据ID"
+// @Success 200 {object} result.Result{data=model.EcsAuthPublicKeyVo}
+// @Router /api/v1/config/ecsauthpubkey [get]
+// @Security ApiKeyAuth
+func (c *EcsAuthController) GetEcsAuthPublicKey(ctx *gin.Context) {
+	id, err := strconv.ParseUint(ctx.Query("id"), 10, 64)
+	if err != nil {
+		result.Failed(ctx, int(result.ApiCode.FAILED), "id参数格式错误")
+		return
+	}
+	c.service.GetEcsAuthPublicKey(ctx, uint(id))
+}
+
+// GetEcsAuthUsage 获取凭据的引用和使用情况
+// @Summary 获取凭据的引用和使用情况
+// @Tags Config配置中心
+// @Description 返回引用该凭据的主机，以及通过主机间接使用该凭据的Agent、任务作业和Ansible任务，和最后使用时间、使用次数
+// @Param id query int true "凭据ID"
+// @Success 200 {object} result.Result{data=model.CredentialUsageVo}
+// @Router /api/v1/config/ecsauthusage [get]
+// @Security ApiKeyAuth
+func (c *EcsAuthController) GetEcsAuthUsage(ctx *gin.Context) {
+	id, err := strconv.ParseUint(ctx.Query("id"), 10, 64)
+	if err != nil {
+		result.Failed(ctx, int(result.ApiCode.FAILED), "id参数格式错误")
+		return
+	}
+	c.service.GetEcsAuthUsage(ctx, uint(id))
+}
@@ -1,10 +1,12 @@
 package dao
 
 import (
+	"context"
 	"time"
 	"dodevops-api/api/configcenter/model"
 	"dodevops-api/common"
 	"dodevops-api/common/util"
+	"dodevops-api/pkg/secretstore"
 )
 
 type AccountAuthDao struct{}
@@ -18,7 +20,11 @@ func (d *AccountAuthDao) Create(account *model.AccountAuth) error {
 	if err := account.EncryptPassword(); err != nil {
 		return err
 	}
-	return common.GetDB().Create(account).Error
+	if err := common.GetDB().Create(account).Error; err != nil {
+		releaseSecrets(secretstore.Release(context.Background(), account.Password))
+		return err
+	}
+	return nil
 }
 
 // Update 更新账号
@@ -29,7 +35,9 @@ func (d *AccountAuthDao) Update(account *model.AccountAuth) error {
 	// 设置当前时间为更新时间
 	account.UpdatedAt = util.HTime{Time: time.Now()}
 	
-	return common.GetDB().Model(account).Updates(map[string]interface{}{
+	db := common.GetDB()
+	refs := secretstore.References(db, account.TableName(), account.ID)
+	err := db.Model(account).Updates(map[string]interface{}{
 		"alias":     account.Alias,
 		"host":      account.Host,
 		"port":      account.Port,
@@ -39,11 +47,23 @@ func (d *AccountAuthDao) Update(account *model.AccountAuth) error {
 		"remark":    account.Remark,
 		"updated_at": account.UpdatedAt,
 	}).Error
+	if err != nil {
+		releaseSecrets(secretstore.Release(context.Background(), account.Password))
+		return err
+	}
+	releaseSecrets(secretstore.ReleaseUnused(context.Background(), db, account.TableName(), account.ID, refs))
+	return nil
 }
 
 // Delete 删除账号
 func (d *AccountAuthDao) Delete(id uint) error {
-	return common.GetDB().Delete(&model.AccountAuth{}, id).Error
+	db := common.GetDB()
+	refs := secretstore.References(db, model.AccountAuth{}.TableName(), id)
+	if err := db.Delete(&model.AccountAuth{}, id).Error; err != nil {
+		return err
+	}
+	releaseSecrets(secretstore.Release(context.Background(), refs...))
+	return nil
 }
 
 // GetByID 根据ID查询账号
@@ -0,0 +1,167 @@
+// 凭证引用关系和使用记录 数据层
+// author xiaoRui
+
+package dao
+
+import (
+	appModel "dodevops-api/api/app/model"
+	cmdbModel "dodevops-api/api/cmdb/model"
+	"dodevops-api/api/configcenter/model"
+	monitorModel "dodevops-api/api/monitor/model"
+	taskModel "dodevops-api/api/task/model"
+	"dodevops-api/common"
+	"dodevops-api/common/util"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"gorm.io/gorm"
+)
+
+type CredentialUsageDao struct {
+	db *gorm.DB
+}
+
+func NewCredentialUsageDao() CredentialUsageDao {
+	return CredentialUsageDao{
+		db: common.GetDB(),
+	}
+}
+
+// 记录ECS凭据的使用时间和次数，在建立SSH连接时调用
+func MarkEcsAuthUsed(id uint) {
+	markUsed(model.EcsAuth{}.TableName(), id)
+}
+
+// 记录账号的使用时间和次数，在连接数据库或Jenkins时调用
+func MarkAccountAuthUsed(id uint) {
+	markUsed(model.AccountAuth{}.TableName(), id)
+}
+
+func markUsed(table string, id uint) {
+	db := common.GetDB()
+	if id == 0 || db == nil {
+		return
+	}
+	db.Table(table).Where("id = ?", id).UpdateColumns(map[string]interface{}{
+		"last_used_time": util.HTime{Time: time.Now()},
+		"use_count":      gorm.Expr("use_count + 1"),
+	})
+}
+
+// 统计每个ECS凭据被主机引用的数量
+func (d *CredentialUsageDao) EcsAuthRefCounts() map[uint]int {
+	return d.refCounts(&cmdbModel.CmdbHost{}, "ssh_key_id")
+}
+
+// 统计每个账号被数据库和Jenkins环境引用的数量
+func (d *CredentialUsageDao) AccountAuthRefCounts() map[uint]int {
+	counts := d.refCounts(&cmdbModel.CmdbSQL{}, "account_id")
+	for id, count := range d.refCounts(&appModel.JenkinsEnv{}, "jenkins_server_id") {
+		counts[id] += count
+	}
+	return counts
+}
+
+func (d *CredentialUsageDao) refCounts(table interface{}, column string) map[uint]int {
+	var rows []struct {
+		ID    uint
+		Count int
+	}
+	d.db.Model(table).Select(column + " AS id, COUNT(*) AS count").Where(column + " > 0").Group(column).Scan(&rows)
+	counts := map[uint]int{}
+	for _, row := range rows {
+		counts[row.ID] = row.Count
+	}
+	return counts
+}
+
+// 查询引用ECS凭据的主机，以及通过这些主机间接引用的Agent、任务作业和Ansible任务
+func (d *CredentialUsageDao) EcsAuthReferences(id uint) []model.CredentialReference {
+	var hosts []cmdbModel.CmdbHost
+	d.db.Select("id", "host_name").Where("ssh_key_id = ?", id).Order("id").Find(&hosts)
+	refs := make([]model.CredentialReference, 0, len(hosts))
+	hostIds := map[uint]bool{}
+	for _, host := range hosts {
+		hostIds[host.ID] = true
+		refs = append(refs, model.CredentialReference{Type: model.CredentialRefHost, ID: host.ID, Name: host.HostName, Direct: true})
+	}
+	if len(hosts) == 0 {
+		return refs
+	}
+
+	var agents []monitorModel.Agent
+	d.db.Select("id", "host_id", "host_name").Where("host_id IN ?", keys(hostIds)).Order("id").Find(&agents)
+	for _, agent := range agents {
+		refs = append(refs, model.CredentialReference{Type: model.CredentialRefAgent, ID: agent.ID, Name: agent.HostName})
+	}
+	// 任务的主机ID保存为逗号分隔或JSON数组，逐个解析匹配
+	var tasks []taskModel.Task
+	d.db.Select("id", "name", "host_ids").Where("host_ids <> ''").Order("id").Find(&tasks)
+	for _, task := range tasks {
+		if containsAny(task.HostIDs, hostIds) {
+			refs = append(refs, model.CredentialReference{Type: model.CredentialRefTask, ID: task.ID, Name: task.Name})
+		}
+	}
+	var ansibleTasks []taskModel.TaskAnsible
+	d.db.Select("id", "name", "all_host_ids").Order("id").Find(&ansibleTasks)
+	for _, task := range ansibleTasks {
+		if containsAny(task.AllHostIDs, hostIds) {
+			refs = append(refs, model.CredentialReference{Type: model.CredentialRefAnsible, ID: task.ID, Name: task.Name})
+		}
+	}
+	return refs
+}
+
+// 查询引用账号的数据库和Jenkins环境
+func (d *CredentialUsageDao) AccountAuthReferences(id uint) []model.CredentialReference {
+	var databases []cmdbModel.CmdbSQL
+	d.db.Select("id", "name").Where("account_id = ?", id).Order("id").Find(&databases)
+	refs := make([]model.CredentialReference, 0, len(databases))
+	for _, database := range databases {
+		refs = append(refs, model.CredentialReference{Type: model.CredentialRefDatabase, ID: database.ID, Name: database.Name, Direct: true})
+	}
+	var envs []appModel.JenkinsEnv
+	d.db.Preload("Application").Where("jenkins_server_id = ?", id).Order("id").Find(&envs)
+	for _, env := range envs {
+		name := env.EnvName
+		if env.Application.Name != "" {
+			name = env.Application.Name + "/" + env.EnvName
+		}
+		refs = append(refs, model.CredentialReference{Type: model.CredentialRefJenkins, ID: env.ID, Name: name, Direct: true})
+	}
+	return refs
+}
+
+// 将引用ECS凭据的主机迁移到新凭据
+func (d *CredentialUsageDao) ReassignEcsAuth(from, to uint) error {
+	return d.db.Model(&cmdbModel.CmdbHost{}).Where("ssh_key_id = ?", from).Update("ssh_key_id", to).Error
+}
+
+// 将引用账号的数据库和Jenkins环境迁移到新账号
+func (d *CredentialUsageDao) ReassignAccountAuth(from, to uint) error {
+	return d.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&cmdbModel.CmdbSQL{}).Where("account_id = ?", from).Update("account_id", to).Error; err != nil {
+			return err
+		}
+		return tx.Model(&appModel.JenkinsEnv{}).Where("jenkins_server_id = ?", from).Update("jenkins_server_id", to).Error
+	})
+}
+
+func keys(set map[uint]bool) []uint {
+	list := make([]uint, 0, len(set))
+	for id := range set {
+		list = append(list, id)
+	}
+	return list
+}
+
+func containsAny(hostIds string, set map[uint]bool) bool {
+	for _, field := range strings.FieldsFunc(hostIds, func(r rune) bool { return !unicode.IsDigit(r) }) {
+		if id, err := strconv.ParseUint(field, 10, 64); err == nil && set[uint(id)] {
+			return true
+		}
+	}
+	return false
+}
@@ -1,8 +1,11 @@
 package dao
 
 import (
+	"context"
+	"errors"
 	"dodevops-api/api/configcenter/model"
 	"dodevops-api/common"
+	"dodevops-api/pkg/secretstore"
 
 	"gorm.io/gorm"
 )
@@ -48,15 +51,50 @@ func (d *EcsAuthDao) GetEcsAuthByName(name string) (model.EcsAuth, error) {
 }
 
 func (d *EcsAuthDao) CreateEcsAuth(auth *model.EcsAuth) error {
+	if err := checkSecretRefs(nil, auth.Password, auth.PublicKey); err != nil {
+		return err
+	}
 	return d.db.Create(auth).Error
 }
 
 func (d *EcsAuthDao) UpdateEcsAuth(id uint, auth *model.EcsAuth) error {
-	return d.db.Model(&model.EcsAuth{}).Where("id = ?", id).Updates(auth).Error
+	refs := secretstore.References(d.db, auth.TableName(), id)
+	if err := checkSecretRefs(refs, auth.Password, auth.PublicKey); err != nil {
+		return err
+	}
+	if err := d.db.Model(&model.EcsAuth{}).Where("id = ?", id).Updates(auth).Error; err != nil {
+		return err
+	}
+	releaseSecrets(secretstore.ReleaseUnused(context.Background(), d.db, auth.TableName(), id, refs))
+	return nil
 }
 
 func (d *EcsAuthDao) DeleteEcsAuth(id uint) error {
-	return d.db.Delete(&model.EcsAuth{}, id).Error
+	refs := secretstore.References(d.db, model.EcsAuth{}.TableName(), id)
+	if err := d.db.Delete(&model.EcsAuth{}, id).Error; err != nil {
+		return err
+	}
+	releaseSecrets(secretstore.Release(context.Background(), refs...))
+	return nil
+}
+
+// 外部存储的引用只能是记录原有的引用（读取后原样提交），不能指向其他记录的凭证
+func checkSecretRefs(current []string, values ...string) error {
+	for _, value := range values {
+		if secretstore.IsReference(value) && !containsRef(current, value) {
+			return errors.New("凭据内容无效")
+		}
+	}
+	return nil
+}
+
+func containsRef(refs []string, ref string) bool {
+	for _, r := range refs {
+		if r == ref {
+			return true
+		}
+	}
+	return false
 }
 
 func (d *EcsAuthDao) GetById(id uint) (model.EcsAuth, error) {
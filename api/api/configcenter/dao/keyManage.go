@@ -1,10 +1,13 @@
 package dao
 
 import (
+	"context"
 	"time"
 	"dodevops-api/api/configcenter/model"
 	"dodevops-api/common"
 	"dodevops-api/common/util"
+	"dodevops-api/pkg/log"
+	"dodevops-api/pkg/secretstore"
 )
 
 type KeyManageDao struct{}
@@ -18,7 +21,11 @@ func (d *KeyManageDao) Create(keyManage *model.KeyManage) error {
 	if err := keyManage.EncryptKeys(); err != nil {
 		return err
 	}
-	return common.GetDB().Create(keyManage).Error
+	if err := common.GetDB().Create(keyManage).Error; err != nil {
+		releaseSecrets(secretstore.Release(context.Background(), keyManage.KeyID, keyManage.KeySecret))
+		return err
+	}
+	return nil
 }
 
 // Update 更新密钥
@@ -29,18 +36,39 @@ func (d *KeyManageDao) Update(keyManage *model.KeyManage) error {
 	// 设置当前时间为更新时间
 	keyManage.UpdatedAt = util.HTime{Time: time.Now()}
 	
-	return common.GetDB().Model(keyManage).Updates(map[string]interface{}{
+	db := common.GetDB()
+	refs := secretstore.References(db, keyManage.TableName(), keyManage.ID)
+	err := db.Model(keyManage).Updates(map[string]interface{}{
 		"key_type":   keyManage.KeyType,
 		"key_id":     keyManage.KeyID,
 		"key_secret": keyManage.KeySecret,
 		"remark":     keyManage.Remark,
 		"updated_at": keyManage.UpdatedAt,
 	}).Error
+	if err != nil {
+		releaseSecrets(secretstore.Release(context.Background(), keyManage.KeyID, keyManage.KeySecret))
+		return err
+	}
+	releaseSecrets(secretstore.ReleaseUnused(context.Background(), db, keyManage.TableName(), keyManage.ID, refs))
+	return nil
 }
 
 // Delete 删除密钥
 func (d *KeyManageDao) Delete(id uint) error {
-	return common.GetDB().Delete(&model.KeyManage{}, id).Error
+	db := common.GetDB()
+	refs := secretstore.References(db, model.KeyManage{}.TableName(), id)
+	if err := db.Delete(&model.KeyManage{}, id).Error; err != nil {
+		return err
+	}
+	releaseSecrets(secretstore.Release(context.Background(), refs...))
+	return nil
+}
+
+// 外部存储中的凭证删除失败不影响业务，记录日志后由管理员清理
+func releaseSecrets(err error) {
+	if err != nil {
+		log.Log().Warnf("释放外部存储凭证失败: %v", err)
+	}
 }
 
 // GetByID 根据ID查询密钥
@@ -1,9 +1,16 @@
 package model
 
 import (
+	"context"
 	"dodevops-api/common/util"
+	"dodevops-api/pkg/envelope"
+	"dodevops-api/pkg/secretstore"
 )
 
+func init() {
+	secretstore.RegisterColumns("config_account", envelope.LegacyCBC, "password")
+}
+
 type AccountAuth struct {
 	ID        uint      `gorm:"primaryKey" json:"id"`
 	Alias     string    `gorm:"size:128;not null" json:"alias"`      // 别名
@@ -15,6 +22,9 @@ type AccountAuth struct {
 	Remark    string    `gorm:"type:text" json:"remark"`             // 备注
 	CreatedAt util.HTime `json:"createdAt"`                          // 创建时间
 	UpdatedAt util.HTime `json:"updatedAt"`                          // 更新时间
+	LastUsedTime *util.HTime `gorm:"column:last_used_time" json:"lastUsedTime"` // 最后使用时间，连接数据库或Jenkins时更新
+	UseCount     int64       `gorm:"default:0" json:"useCount"`               // 使用次数
+	RefCount     int         `gorm:"-" json:"refCount"`                       // 引用该账号的数据库和Jenkins环境数
 }
 // 表名
 func (AccountAuth) TableName() string {
@@ -23,7 +33,7 @@ func (AccountAuth) TableName() string {
 
 // EncryptPassword 加密密码
 func (a *AccountAuth) EncryptPassword() error {
-	encrypted, err := util.AESEncrypt(a.Password)
+	encrypted, err := secretstore.Seal(context.Background(), a.TableName(), "password", a.Password)
 	if err != nil {
 		return err
 	}
@@ -33,5 +43,5 @@ func (a *AccountAuth) EncryptPassword() error {
 
 // DecryptPassword 解密密码
 func (a *AccountAuth) DecryptPassword() (string, error) {
-	return util.AESDecrypt(a.Password)
+	return secretstore.Open(context.Background(), a.Password, envelope.LegacyCBC)
 }
@@ -0,0 +1,35 @@
+// 凭证引用关系模型
+// author xiaoRui
+
+package model
+
+import "dodevops-api/common/util"
+
+// 凭证引用类型
+const (
+	CredentialRefHost     = "host"     // CMDB主机，直接引用 ECS 凭据
+	CredentialRefAgent    = "agent"    // 监控Agent，通过主机间接引用
+	CredentialRefTask     = "task"     // 任务作业，通过主机间接引用
+	CredentialRefAnsible  = "ansible"  // Ansible任务，通过主机间接引用
+	CredentialRefDatabase = "database" // CMDB数据库，直接引用账号
+	CredentialRefJenkins  = "jenkins"  // 应用Jenkins环境，直接引用账号
+)
+
+// 引用凭证的资源
+type CredentialReference struct {
+	Type   string `json:"type"`   // 引用类型
+	ID     uint   `json:"id"`     // 资源ID
+	Name   string `json:"name"`   // 资源名称
+	Direct bool   `json:"direct"` // 是否直接引用，直接引用的资源需迁移后才能删除凭证
+}
+
+// 凭证使用情况VO
+type CredentialUsageVo struct {
+	ID           uint                  `json:"id"`           // 凭证ID
+	Name         string                `json:"name"`         // 凭证名称
+	LastUsedTime *util.HTime           `json:"lastUsedTime"` // 最后使用时间
+	UseCount     int64                 `json:"useCount"`     // 使用次数
+	RefCount     int                   `json:"refCount"`     // 直接引用数
+	Counts       map[string]int        `json:"counts"`       // 按引用类型统计
+	References   []CredentialReference `json:"references"`   // 引用明细
+}
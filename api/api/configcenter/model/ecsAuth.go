@@ -3,26 +3,50 @@
 package model
 
 import (
+	"context"
 	"dodevops-api/common/util"
+	"dodevops-api/pkg/envelope"
+	"dodevops-api/pkg/secretstore"
 )
 
+// 密码和私钥通过 secret 序列化器保存到凭证存储后端，历史明文数据由主密钥轮换任务加密
+func init() {
+	secretstore.RegisterColumns("config_ecsauth", envelope.LegacyPlain, "password", "public_key")
+}
+
 // ECS认证凭证模型
 type EcsAuth struct {
-	ID         uint       `gorm:"column:id;comment:'主键';primaryKey;NOT NULL" json:"id"`
-	Name       string     `gorm:"column:name;varchar(64);comment:'凭证名称';NOT NULL" json:"name"`
-	Type       int        `gorm:"column:type;comment:'认证类型:1->密码,2->私钥,3->公钥(免认证)';NOT NULL" json:"type"`
-	Username   string     `gorm:"column:username;varchar(64);comment:'用户名'" json:"username"`
-	Password   string     `gorm:"column:password;varchar(256);comment:'密码(type=1时使用)'" json:"password"`
-	PublicKey  string     `gorm:"column:public_key;type:text;comment:'私钥内容(type=2时使用，字段名历史原因)'" json:"publicKey"` // 实际存储私钥
-	Port       int        `gorm:"column:port;comment:'端口号';default:22" json:"port"`
-	CreateTime util.HTime `gorm:"column:create_time;comment:'创建时间';NOT NULL" json:"createTime"`
-	Remark     string     `gorm:"column:remark;varchar(500);comment:'备注'" json:"remark"`
+	ID           uint        `gorm:"column:id;comment:'主键';primaryKey;NOT NULL" json:"id"`
+	Name         string      `gorm:"column:name;varchar(64);comment:'凭证名称';NOT NULL" json:"name"`
+	Type         int         `gorm:"column:type;comment:'认证类型:1->密码,2->私钥,3->公钥(免认证)';NOT NULL" json:"type"`
+	Username     string      `gorm:"column:username;varchar(64);comment:'用户名'" json:"username"`
+	Password     string      `gorm:"column:password;varchar(256);serializer:secret;comment:'密码(type=1时使用)'" json:"password"`
+	PublicKey    string      `gorm:"column:public_key;type:text;serializer:secret;comment:'私钥内容(type=2时使用，字段名历史原因)'" json:"publicKey"` // 实际存储私钥
+	Port         int         `gorm:"column:port;comment:'端口号';default:22" json:"port"`
+	CreateTime   util.HTime  `gorm:"column:create_time;comment:'创建时间';NOT NULL" json:"createTime"`
+	Remark       string      `gorm:"column:remark;varchar(500);comment:'备注'" json:"remark"`
+	LastUsedTime *util.HTime `gorm:"column:last_used_time;comment:'最后使用时间'" json:"lastUsedTime"` // SSH 连接时更新
+	UseCount     int64       `gorm:"column:use_count;default:0;comment:'使用次数'" json:"useCount"`
 }
 
 func (EcsAuth) TableName() string {
 	return "config_ecsauth"
 }
 
+// ResolveSecrets 读取保存在外部存储中的密码和私钥，读库时只保留引用，使用凭证前调用
+func (a *EcsAuth) ResolveSecrets() error {
+	password, err := secretstore.Resolve(context.Background(), a.Password)
+	if err != nil {
+		return err
+	}
+	privateKey, err := secretstore.Resolve(context.Background(), a.PublicKey)
+	if err != nil {
+		return err
+	}
+	a.Password, a.PublicKey = password, privateKey
+	return nil
+}
+
 // 创建ECS密码认证DTO
 type CreateEcsPasswordAuthDto struct {
 	Name      string `validate:"required"` // 凭证名称
@@ -53,11 +77,34 @@ type CreateEcsPublicKeyAuthDto struct {
 	Remark   string // 备注
 }
 
+// 生成ECS密钥认证DTO
+type GenerateEcsKeyAuthDto struct {
+	Name     string `validate:"required" json:"name"`     // 凭证名称
+	KeyType  string `json:"keyType"`                      // 密钥类型：ed25519(默认)、rsa
+	Bits     int    `json:"bits"`                         // RSA 密钥长度，默认 4096
+	Username string `validate:"required" json:"username"` // 用户名
+	Port     int    `json:"port"`                         // 端口号，默认 22
+	Remark   string `json:"remark"`                       // 备注
+}
+
+// 密钥认证公钥VO，用于分发到主机 authorized_keys
+type EcsAuthPublicKeyVo struct {
+	ID        uint   `json:"id"`        // 凭证ID
+	Name      string `json:"name"`      // 凭证名称
+	PublicKey string `json:"publicKey"` // authorized_keys 格式公钥
+}
+
 // ID参数
 type EcsAuthIdDto struct {
 	Id uint `json:"id"` // ID
 }
 
+// 删除ECS认证DTO
+type DeleteEcsAuthDto struct {
+	Id         uint `json:"id"`         // ID
+	ReassignTo uint `json:"reassignTo"` // 凭据仍被主机引用时，将主机迁移到该凭据后再删除
+}
+
 // 更新ECS认证DTO
 type UpdateEcsAuthDto struct {
 	EcsAuthIdDto
@@ -66,13 +113,16 @@ type UpdateEcsAuthDto struct {
 
 // 认证凭证列表VO
 type EcsAuthVo struct {
-	ID         uint       `json:"id"`
-	Name       string     `json:"name"`
-	Type       int        `json:"type"`
-	Username   string     `json:"username"`
-	Password   string     `json:"password"`
-	PublicKey  string     `json:"publicKey"`
-	Port       int        `json:"port"`
-	CreateTime util.HTime `json:"createTime"`
-	Remark     string     `json:"remark"`
+	ID           uint        `json:"id"`
+	Name         string      `json:"name"`
+	Type         int         `json:"type"`
+	Username     string      `json:"username"`
+	Password     string      `json:"password"`
+	PublicKey    string      `json:"publicKey"`
+	Port         int         `json:"port"`
+	CreateTime   util.HTime  `json:"createTime"`
+	Remark       string      `json:"remark"`
+	LastUsedTime *util.HTime `json:"lastUsedTime"` // 最后使用时间
+	UseCount     int64       `json:"useCount"`     // 使用次数
+	RefCount     int         `json:"refCount"`     // 引用该凭据的主机数
 }
@@ -1,9 +1,16 @@
 package model
 
 import (
+	"context"
 	"dodevops-api/common/util"
+	"dodevops-api/pkg/envelope"
+	"dodevops-api/pkg/secretstore"
 )
 
+func init() {
+	secretstore.RegisterColumns("config_keymanage", envelope.LegacyCBC, "key_id", "key_secret")
+}
+
 type KeyManage struct {
 	ID        uint      `gorm:"primaryKey" json:"id"`
 	KeyType   int       `gorm:"not null" json:"keyType"`             // 云厂商类型：1=阿里云，2=腾讯云，3=百度云，4=华为云，5=AWS云
@@ -21,11 +28,11 @@ func (KeyManage) TableName() string {
 
 // EncryptKeys 加密密钥信息
 func (k *KeyManage) EncryptKeys() error {
-	encryptedID, err := util.AESEncrypt(k.KeyID)
+	encryptedID, err := secretstore.Seal(context.Background(), k.TableName(), "key_id", k.KeyID)
 	if err != nil {
 		return err
 	}
-	encryptedSecret, err := util.AESEncrypt(k.KeySecret)
+	encryptedSecret, err := secretstore.Seal(context.Background(), k.TableName(), "key_secret", k.KeySecret)
 	if err != nil {
 		return err
 	}
@@ -36,11 +43,11 @@ func (k *KeyManage) EncryptKeys() error {
 
 // DecryptKeys 解密密钥信息
 func (k *KeyManage) DecryptKeys() (string, string, error) {
-	keyID, err := util.AESDecrypt(k.KeyID)
+	keyID, err := secretstore.Open(context.Background(), k.KeyID, envelope.LegacyCBC)
 	if err != nil {
 		return "", "", err
 	}
-	keySecret, err := util.AESDecrypt(k.KeySecret)
+	keySecret, err := secretstore.Open(context.Background(), k.KeySecret, envelope.LegacyCBC)
 	if err != nil {
 		return "", "", err
 	}
@@ -7,12 +7,14 @@ import (
 )
 
 type AccountAuthService struct {
-	dao *dao.AccountAuthDao
+	dao      *dao.AccountAuthDao
+	usageDao dao.CredentialUsageDao
 }
 
 func NewAccountAuthService() *AccountAuthService {
 	return &AccountAuthService{
-		dao: dao.NewAccountAuthDao(),
+		dao:      dao.NewAccountAuthDao(),
+		usageDao: dao.NewCredentialUsageDao(),
 	}
 }
 
@@ -34,11 +36,31 @@ func (s *AccountAuthService) Update(account *model.AccountAuth) error {
 	return s.dao.Update(account)
 }
 
-// Delete 删除账号
-func (s *AccountAuthService) Delete(id uint) error {
+// Delete 删除账号，仍被数据库或Jenkins环境引用时需指定迁移账号
+func (s *AccountAuthService) Delete(id, reassignTo uint) error {
+	if count := s.usageDao.AccountAuthRefCounts()[id]; count > 0 {
+		if reassignTo == 0 {
+			return fmt.Errorf("账号正在被 %d 个数据库或Jenkins环境使用，请指定迁移账号后再删除", count)
+		}
+		if _, err := s.dao.GetByID(reassignTo); err != nil || reassignTo == id {
+			return fmt.Errorf("迁移账号不存在")
+		}
+		if err := s.usageDao.ReassignAccountAuth(id, reassignTo); err != nil {
+			return err
+		}
+	}
 	return s.dao.Delete(id)
 }
 
+// GetUsage 获取账号的引用和使用情况
+func (s *AccountAuthService) GetUsage(id uint) (model.CredentialUsageVo, error) {
+	account, err := s.dao.GetByID(id)
+	if err != nil {
+		return model.CredentialUsageVo{}, err
+	}
+	return buildCredentialUsage(account.ID, account.Alias, account.LastUsedTime, account.UseCount, s.usageDao.AccountAuthReferences(id)), nil
+}
+
 // GetByID 根据ID查询账号
 func (s *AccountAuthService) GetByID(id uint) (*model.AccountAuth, error) {
 	return s.dao.GetByID(id)
@@ -51,7 +73,20 @@ func (s *AccountAuthService) List() ([]model.AccountAuth, error) {
 
 // ListWithPage 获取账号列表（分页）
 func (s *AccountAuthService) ListWithPage(page, pageSize int) ([]model.AccountAuth, int64, error) {
-	return s.dao.ListWithPage(page, pageSize)
+	accounts, total, err := s.dao.ListWithPage(page, pageSize)
+	if err != nil {
+		return nil, 0, err
+	}
+	refCounts := s.usageDao.AccountAuthRefCounts()
+	for i := range accounts {
+		accounts[i].RefCount = refCounts[accounts[i].ID]
+	}
+	return accounts, total, nil
+}
+
+// MarkUsed 记录账号的使用时间和次数
+func (s *AccountAuthService) MarkUsed(id uint) {
+	dao.MarkAccountAuthUsed(id)
 }
 
 // DecryptPassword 解密密码
@@ -6,6 +6,7 @@ import (
 	"dodevops-api/common/constant"
 	"dodevops-api/common/result"
 	"dodevops-api/common/util"
+	"fmt"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -18,15 +19,20 @@ type EcsAuthServiceInterface interface {
 	GetEcsAuthById(c *gin.Context, id uint)                                    // 根据ID获取认证信息
 	CreateEcsAuth(c *gin.Context, dto *model.CreateEcsPasswordAuthDto)          // 创建认证信息
 	UpdateEcsAuth(c *gin.Context, id uint, dto *model.CreateEcsPasswordAuthDto) // 更新认证信息
-	DeleteEcsAuth(c *gin.Context, id uint)                                      // 删除认证信息
+	DeleteEcsAuth(c *gin.Context, dto *model.DeleteEcsAuthDto)                  // 删除认证信息，被主机引用时需指定迁移凭据
+	GenerateEcsKeyAuth(c *gin.Context, dto *model.GenerateEcsKeyAuthDto)        // 生成密钥对并创建密钥认证
+	GetEcsAuthPublicKey(c *gin.Context, id uint)                                // 获取密钥认证的公钥
+	GetEcsAuthUsage(c *gin.Context, id uint)                                    // 获取认证信息的引用和使用情况
 }
 
 type EcsAuthServiceImpl struct {
-	dao dao.EcsAuthDao
+	dao      dao.EcsAuthDao
+	usageDao dao.CredentialUsageDao
 }
 // 获取所有认证信息
 func (s *EcsAuthServiceImpl) GetEcsAuthList(c *gin.Context) {
 	list := s.dao.GetEcsAuthList()
+	refCounts := s.usageDao.EcsAuthRefCounts()
 	var vos []model.EcsAuthVo
 	for _, auth := range list {
 		vos = append(vos, model.EcsAuthVo{
@@ -39,6 +45,9 @@ func (s *EcsAuthServiceImpl) GetEcsAuthList(c *gin.Context) {
 			Port:       auth.Port,
 			CreateTime: auth.CreateTime,
 			Remark:     auth.Remark,
+			LastUsedTime: auth.LastUsedTime,
+			UseCount:     auth.UseCount,
+			RefCount:     refCounts[auth.ID],
 		})
 	}
 	result.Success(c, vos)
@@ -47,6 +56,7 @@ func (s *EcsAuthServiceImpl) GetEcsAuthList(c *gin.Context) {
 // 获取认证信息（分页）
 func (s *EcsAuthServiceImpl) GetEcsAuthListWithPage(c *gin.Context, page, pageSize int) {
 	list, total := s.dao.GetEcsAuthListWithPage(page, pageSize)
+	refCounts := s.usageDao.EcsAuthRefCounts()
 	var vos []model.EcsAuthVo
 	for _, auth := range list {
 		vos = append(vos, model.EcsAuthVo{
@@ -59,6 +69,9 @@ func (s *EcsAuthServiceImpl) GetEcsAuthListWithPage(c *gin.Context, page, pageSi
 			Port:       auth.Port,
 			CreateTime: auth.CreateTime,
 			Remark:     auth.Remark,
+			LastUsedTime: auth.LastUsedTime,
+			UseCount:     auth.UseCount,
+			RefCount:     refCounts[auth.ID],
 		})
 	}
 	
@@ -114,12 +127,28 @@ func (s *EcsAuthServiceImpl) UpdateEcsAuth(c *gin.Context, id uint, dto *model.C
 	result.Success(c, true)
 }
 
-func (s *EcsAuthServiceImpl) DeleteEcsAuth(c *gin.Context, id uint) {
+func (s *EcsAuthServiceImpl) DeleteEcsAuth(c *gin.Context, dto *model.DeleteEcsAuthDto) {
+	id := dto.Id
 	auth, err := s.dao.GetEcsAuthById(id)
 	if err == nil && auth.Name == "免密认证" {
 		result.FailedWithCode(c, constant.ECS_AUTH_DELETE_FAILED, "不允许删除免密认证凭据")
 		return
 	}
+	// 仍被主机引用的凭据需先迁移到其他凭据
+	if count := s.usageDao.EcsAuthRefCounts()[id]; count > 0 {
+		if dto.ReassignTo == 0 {
+			result.FailedWithCode(c, constant.CREDENTIAL_IN_USE, fmt.Sprintf("凭据正在被 %d 台主机使用，请指定迁移凭据后再删除", count))
+			return
+		}
+		if _, err := s.dao.GetEcsAuthById(dto.ReassignTo); err != nil || dto.ReassignTo == id {
+			result.FailedWithCode(c, constant.ECS_AUTH_NOT_FOUND, "迁移凭据不存在")
+			return
+		}
+		if err := s.usageDao.ReassignEcsAuth(id, dto.ReassignTo); err != nil {
+			result.FailedWithCode(c, constant.ECS_AUTH_DELETE_FAILED, err.Error())
+			return
+		}
+	}
 	err = s.dao.DeleteEcsAuth(id)
 	if err != nil {
 		result.FailedWithCode(c, constant.ECS_AUTH_DELETE_FAILED, err.Error())
@@ -134,6 +163,10 @@ func (s *EcsAuthServiceImpl) GetEcsAuthByName(c *gin.Context, name string) {
 		result.FailedWithCode(c, constant.ECS_AUTH_NOT_FOUND, "凭据不存在")
 		return
 	}
+	if err := auth.ResolveSecrets(); err != nil {
+		result.FailedWithCode(c, constant.ECS_AUTH_NOT_FOUND, err.Error())
+		return
+	}
 
 	vo := model.EcsAuthVo{
 		ID:         auth.ID,
@@ -145,6 +178,9 @@ func (s *EcsAuthServiceImpl) GetEcsAuthByName(c *gin.Context, name string) {
 		Port:       auth.Port,
 		CreateTime: auth.CreateTime,
 		Remark:     auth.Remark,
+		LastUsedTime: auth.LastUsedTime,
+		UseCount:     auth.UseCount,
+		RefCount:     s.usageDao.EcsAuthRefCounts()[auth.ID],
 	}
 	result.Success(c, vo)
 }
@@ -156,6 +192,10 @@ func (s *EcsAuthServiceImpl) GetEcsAuthById(c *gin.Context, id uint) {
 		result.FailedWithCode(c, constant.ECS_AUTH_NOT_FOUND, "凭据不存在")
 		return
 	}
+	if err := auth.ResolveSecrets(); err != nil {
+		result.FailedWithCode(c, constant.ECS_AUTH_NOT_FOUND, err.Error())
+		return
+	}
 
 	vo := model.EcsAuthVo{
 		ID:         auth.ID,
@@ -167,12 +207,102 @@ func (s *EcsAuthServiceImpl) GetEcsAuthById(c *gin.Context, id uint) {
 		Port:       auth.Port,
 		CreateTime: auth.CreateTime,
 		Remark:     auth.Remark,
+		LastUsedTime: auth.LastUsedTime,
+		UseCount:     auth.UseCount,
+		RefCount:     s.usageDao.EcsAuthRefCounts()[auth.ID],
 	}
 	result.Success(c, vo)
 }
 
+// 由平台生成密钥对并保存为密钥认证，返回公钥用于分发
+func (s *EcsAuthServiceImpl) GenerateEcsKeyAuth(c *gin.Context, dto *model.GenerateEcsKeyAuthDto) {
+	if dto.Name == "" || dto.Username == "" {
+		result.FailedWithCode(c, constant.ECS_AUTH_CREATE_FAILED, "凭据名称和用户名不能为空")
+		return
+	}
+	if s.dao.CheckNameExists(dto.Name) {
+		result.FailedWithCode(c, constant.ECS_AUTH_NAME_EXISTS, "凭据名称已存在")
+		return
+	}
+	if dto.Port == 0 {
+		dto.Port = 22
+	}
+	privateKey, publicKey, err := util.GenerateSSHKeyPair(dto.KeyType, dto.Bits, "autoops-"+dto.Name)
+	if err != nil {
+		result.FailedWithCode(c, constant.ECS_AUTH_CREATE_FAILED, err.Error())
+		return
+	}
+	auth := model.EcsAuth{
+		Name:       dto.Name,
+		Type:       2,
+		Username:   dto.Username,
+		PublicKey:  privateKey,
+		Port:       dto.Port,
+		CreateTime: util.HTime{Time: time.Now()},
+		Remark:     dto.Remark,
+	}
+	if err := s.dao.CreateEcsAuth(&auth); err != nil {
+		result.FailedWithCode(c, constant.ECS_AUTH_CREATE_FAILED, err.Error())
+		return
+	}
+	result.Success(c, model.EcsAuthPublicKeyVo{ID: auth.ID, Name: auth.Name, PublicKey: publicKey})
+}
+
+// 根据私钥计算密钥认证的公钥
+func (s *EcsAuthServiceImpl) GetEcsAuthPublicKey(c *gin.Context, id uint) {
+	auth, err := s.dao.GetEcsAuthById(id)
+	if err != nil {
+		result.FailedWithCode(c, constant.ECS_AUTH_NOT_FOUND, "凭据不存在")
+		return
+	}
+	if err := auth.ResolveSecrets(); err != nil {
+		result.FailedWithCode(c, constant.ECS_AUTH_NOT_FOUND, err.Error())
+		return
+	}
+	if auth.Type != 2 {
+		result.FailedWithCode(c, constant.ECS_AUTH_NOT_FOUND, "仅密钥认证凭据可以获取公钥")
+		return
+	}
+	publicKey, err := util.SSHPublicKeyFromPrivate(auth.PublicKey)
+	if err != nil {
+		result.FailedWithCode(c, constant.ECS_AUTH_NOT_FOUND, err.Error())
+		return
+	}
+	result.Success(c, model.EcsAuthPublicKeyVo{ID: auth.ID, Name: auth.Name, PublicKey: publicKey})
+}
+
+// 获取认证信息的引用和使用情况
+func (s *EcsAuthServiceImpl) GetEcsAuthUsage(c *gin.Context, id uint) {
+	auth, err := s.dao.GetEcsAuthById(id)
+	if err != nil {
+		result.FailedWithCode(c, constant.ECS_AUTH_NOT_FOUND, "凭据不存在")
+		return
+	}
+	result.Success(c, buildCredentialUsage(auth.ID, auth.Name, auth.LastUsedTime, auth.UseCount, s.usageDao.EcsAuthReferences(id)))
+}
+
+// 汇总凭证的引用明细
+func buildCredentialUsage(id uint, name string, lastUsedTime *util.HTime, useCount int64, refs []model.CredentialReference) model.CredentialUsageVo {
+	usage := model.CredentialUsageVo{
+		ID:           id,
+		Name:         name,
+		LastUsedTime: lastUsedTime,
+		UseCount:     useCount,
+		Counts:       map[string]int{},
+		References:   refs,
+	}
+	for _, ref := range refs {
+		usage.Counts[ref.Type]++
+		if ref.Direct {
+			usage.RefCount++
+		}
+	}
+	return usage
+}
+
 func GetEcsAuthService() EcsAuthServiceInterface {
 	return &EcsAuthServiceImpl{
-		dao: dao.NewEcsAuthDao(),
+		dao:      dao.NewEcsAuthDao(),
+		usageDao: dao.NewCredentialUsageDao(),
 	}
 }
@@ -1,6 +1,7 @@
 package service
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"log"
@@ -14,6 +15,7 @@ import (
 	cmdbModel "dodevops-api/api/cmdb/model"
 	"dodevops-api/common/result"
 	"dodevops-api/common/util"
+	"dodevops-api/pkg/changelog"
 	aliyuncloud "dodevops-api/common/util"
 	tengxuncloud "dodevops-api/common/util"
 	baiducloud "dodevops-api/common/util"
@@ -126,7 +128,7 @@ func (s *KeyManageService) SyncAliyunHosts(c *gin.Context, keyID uint, groupID u
 
 	// 异步处理同步
 	go func() {
-		hostDao := cmdbDao.NewCmdbHostDao()
+		hostDao := cloudSyncHostDao("阿里云同步")
 		
 		hosts, err := s.syncAliyunHosts(keyID, groupID, region)
 		if err != nil {
@@ -158,7 +160,7 @@ func (s *KeyManageService) SyncAliyunHosts(c *gin.Context, keyID uint, groupID u
 func (s *KeyManageService) SyncAliyunHostsBackground(keyID uint, groupID uint, region string) error {
 	log.Printf("开始后台同步阿里云主机: keyID=%d, groupID=%d, region=%s", keyID, groupID, region)
 
-	hostDao := cmdbDao.NewCmdbHostDao()
+	hostDao := cloudSyncHostDao("阿里云定时同步")
 
 	hosts, err := s.syncAliyunHosts(keyID, groupID, region)
 	if err != nil {
@@ -287,7 +289,7 @@ func (s *KeyManageService) SyncTencentHosts(c *gin.Context, keyID uint, groupID
 
 	// 异步处理同步
 	go func() {
-		hostDao := cmdbDao.NewCmdbHostDao()
+		hostDao := cloudSyncHostDao("腾讯云同步")
 		
 		hosts, err := s.syncTencentHosts(keyID, groupID)
 		if err != nil {
@@ -319,7 +321,7 @@ func (s *KeyManageService) SyncTencentHosts(c *gin.Context, keyID uint, groupID
 func (s *KeyManageService) SyncTencentHostsBackground(keyID uint, groupID uint) error {
 	log.Printf("开始后台同步腾讯云主机: keyID=%d, groupID=%d", keyID, groupID)
 
-	hostDao := cmdbDao.NewCmdbHostDao()
+	hostDao := cloudSyncHostDao("腾讯云定时同步")
 
 	hosts, err := s.syncTencentHosts(keyID, groupID)
 	if err != nil {
@@ -474,7 +476,7 @@ func (s *KeyManageService) SyncBaiduHosts(c *gin.Context, keyID uint, groupID ui
 
 	// 异步处理同步
 	go func() {
-		hostDao := cmdbDao.NewCmdbHostDao()
+		hostDao := cloudSyncHostDao("百度云同步")
 
 		hosts, err := s.syncBaiduHosts(keyID, groupID)
 		if err != nil {
@@ -506,7 +508,7 @@ func (s *KeyManageService) SyncBaiduHosts(c *gin.Context, keyID uint, groupID ui
 func (s *KeyManageService) SyncBaiduHostsBackground(keyID uint, groupID uint) error {
 	log.Printf("开始后台同步百度云主机: keyID=%d, groupID=%d", keyID, groupID)
 
-	hostDao := cmdbDao.NewCmdbHostDao()
+	hostDao := cloudSyncHostDao("百度云定时同步")
 
 	hosts, err := s.syncBaiduHosts(keyID, groupID)
 	if err != nil {
@@ -629,4 +631,10 @@ func (s *KeyManageService) syncBaiduHosts(keyID uint, groupID uint) ([]cmdbModel
 
 	fmt.Printf("[INFO] 成功解析 %d 台百度云主机\n", len(hosts))
 	return hosts, nil
-}
\ No newline at end of file
+}
+
+// 云同步写入主机使用的DAO，变更历史记为云平台同步
+func cloudSyncHostDao(operator string) *cmdbDao.CmdbHostDao {
+	hostDao := cmdbDao.NewCmdbHostDao()
+	return hostDao.WithContext(changelog.WithSource(context.Background(), cmdbModel.ChangeSourceCloud, operator))
+}
@@ -6,9 +6,13 @@ import (
 	"log"
 	"net/http"
 	"strconv"
+	"strings"
 
 	"dodevops-api/api/k8s/service"
+	systemmodel "dodevops-api/api/system/model"
+	systemservice "dodevops-api/api/system/service"
 	"dodevops-api/common/result"
+	wsutil "dodevops-api/common/util/websocket"
 
 	"github.com/gin-gonic/gin"
 	"github.com/gorilla/websocket"
@@ -84,14 +88,32 @@ func (ctrl *K8sTerminalController) ConnectPodTerminal(c *gin.Context) {
 	}
 	defer conn.Close()
 
+	// 开始录制终端会话，无法录制时不允许连接
+	recorder, err := systemservice.StartSysTerminalSession(c, systemmodel.SysTerminalSessionStartDto{
+		Kind:       systemmodel.TerminalKindPod,
+		TargetId:   uint(clusterId),
+		TargetName: strings.TrimSuffix(fmt.Sprintf("%s/%s/%s", namespaceName, podName, containerName), "/"),
+		Address:    fmt.Sprintf("cluster-%d", clusterId),
+		Width:      wsutil.DefaultCols,
+		Height:     wsutil.DefaultRows,
+	})
+	if err != nil {
+		log.Printf("Failed to record terminal session: %v", err)
+		conn.WriteMessage(websocket.TextMessage, []byte(fmt.Sprintf("Error: 终端会话录制失败: %v", err)))
+		return
+	}
+	defer recorder.Close()
+	checker := systemservice.NewTerminalCommandChecker(c, 0, fmt.Sprintf("%s/%s", namespaceName, podName))
+
 	// 创建K8s WebSocket流
-	stream, err := ctrl.service.CreateK8sWebSocketStream(uint(clusterId), namespaceName, podName, containerName, command, conn)
+	stream, err := ctrl.service.CreateK8sWebSocketStream(uint(clusterId), namespaceName, podName, containerName, command, conn, recorder, checker)
 	if err != nil {
 		log.Printf("Failed to create K8s WebSocket stream: %v", err)
 		conn.WriteMessage(websocket.TextMessage, []byte(fmt.Sprintf("Error: %v", err)))
 		return
 	}
 	defer stream.Close()
+	recorder.OnTerminate(stream.Terminate)
 
 	// 等待连接关闭
 	select {
@@ -68,6 +68,21 @@ func (ctrl *KubeClusterController) GetCluster(c *gin.Context) {
 	ctrl.service.GetCluster(c, uint(id))
 }
 
+// ClusterScope 集群数据权限校验，用于服务层未绑定请求上下文的集群资源路由
+func (ctrl *KubeClusterController) ClusterScope(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		result.Failed(c, http.StatusBadRequest, "无效的集群ID")
+		c.Abort()
+		return
+	}
+	if !ctrl.service.CheckClusterScope(c, uint(id)) {
+		c.Abort()
+		return
+	}
+	c.Next()
+}
+
 // GetClusterList 获取集群列表
 // @Summary 获取K8s集群列表
 // @Description 分页获取K8s集群列表
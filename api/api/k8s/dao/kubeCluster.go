@@ -1,10 +1,17 @@
 package dao
 
 import (
+	"context"
 	"dodevops-api/api/k8s/model"
+	"dodevops-api/pkg/datascope"
 	"gorm.io/gorm"
 )
 
+func init() {
+	// 集群按归属部门过滤数据权限
+	datascope.RegisterTable(model.KubeCluster{}.TableName(), datascope.ByDept("dept_id"))
+}
+
 type KubeClusterDao struct {
 	DB *gorm.DB
 }
@@ -13,6 +20,11 @@ func NewKubeClusterDao(db *gorm.DB) *KubeClusterDao {
 	return &KubeClusterDao{DB: db}
 }
 
+// WithContext 绑定请求上下文，按当前用户的数据权限过滤集群
+func (d *KubeClusterDao) WithContext(ctx context.Context) *KubeClusterDao {
+	return &KubeClusterDao{DB: d.DB.WithContext(ctx)}
+}
+
 // Create 创建集群
 func (d *KubeClusterDao) Create(cluster *model.KubeCluster) error {
 	return d.DB.Create(cluster).Error
@@ -18,6 +18,7 @@ type KubeCluster struct {
 	MasterNodes int       `gorm:"default:0;comment:'Master节点数'" json:"masterNodes"`
 	WorkerNodes int       `gorm:"default:0;comment:'Worker节点数'" json:"workerNodes"`
 	LastSyncAt  *time.Time `gorm:"comment:'最后同步时间'" json:"lastSyncAt"`
+	DeptId      uint      `gorm:"default:0;index;comment:'归属部门ID'" json:"deptId"` // 归属部门ID，按部门划分数据权限，0 表示未分配
 	CreatedAt   time.Time `gorm:"autoCreateTime" json:"createdAt"`
 	UpdatedAt   time.Time `gorm:"autoUpdateTime" json:"updatedAt"`
 }
@@ -187,6 +188,7 @@ type CreateKubeClusterRequest struct {
 	Name        string `json:"name" binding:"required"`        // 集群名称
 	Description string `json:"description"`                    // 集群描述
 	ClusterType int    `json:"clusterType"`                    // 集群类型:1-自建,2-导入(默认为自建)
+	DeptId      uint   `json:"deptId"`                         // 归属部门ID，默认为当前用户所在部门
 	
 	// 自建集群参数
 	Version           string     `json:"version"`           // K8s版本
@@ -232,6 +234,7 @@ type UpdateKubeClusterRequest struct {
 	Description string `json:"description"` // 集群描述
 	Credential  string `json:"credential"`  // K8s凭证(kubeconfig内容)
 	Version     string `json:"version"`     // 集群版本(可选，同步时会自动更新)
+	DeptId      uint   `json:"deptId"`      // 归属部门ID(可选)
 }
 
 // K8sNode K8s节点信息（扩展版本）
@@ -46,7 +46,7 @@ func NewK8sEventsServiceImpl(db *gorm.DB) *K8sEventsServiceImpl {
 // GetEvents 获取指定命名空间的事件列表
 func (s *K8sEventsServiceImpl) GetEvents(c *gin.Context, clusterId uint, namespaceName string, kind string, name string, limit int) {
 	// 获取集群信息
-	cluster, err := s.clusterDao.GetByID(clusterId)
+	cluster, err := s.clusterDao.WithContext(c).GetByID(clusterId)
 	if err != nil {
 		if err == gorm.ErrRecordNotFound {
 			result.Failed(c, http.StatusNotFound, "集群不存在")
@@ -118,7 +118,7 @@ func (s *K8sEventsServiceImpl) GetEvents(c *gin.Context, clusterId uint, namespa
 // GetClusterEvents 获取整个集群的事件列表
 func (s *K8sEventsServiceImpl) GetClusterEvents(c *gin.Context, clusterId uint, limit int) {
 	// 获取集群信息
-	cluster, err := s.clusterDao.GetByID(clusterId)
+	cluster, err := s.clusterDao.WithContext(c).GetByID(clusterId)
 	if err != nil {
 		if err == gorm.ErrRecordNotFound {
 			result.Failed(c, http.StatusNotFound, "集群不存在")
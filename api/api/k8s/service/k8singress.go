@@ -50,8 +50,8 @@ func NewK8sIngressService(db *gorm.DB) IK8sIngressService {
 }
 
 // getK8sClient 获取K8s客户端
-func (s *K8sIngressServiceImpl) getK8sClient(clusterId uint) (*kubernetes.Clientset, error) {
-	cluster, err := s.clusterDao.GetByID(clusterId)
+func (s *K8sIngressServiceImpl) getK8sClient(ctx context.Context, clusterId uint) (*kubernetes.Clientset, error) {
+	cluster, err := s.clusterDao.WithContext(ctx).GetByID(clusterId)
 	if err != nil {
 		return nil, fmt.Errorf("获取集群信息失败: %v", err)
 	}
@@ -75,7 +75,7 @@ func (s *K8sIngressServiceImpl) getK8sClient(clusterId uint) (*kubernetes.Client
 
 // GetIngresses 获取Ingress列表
 func (s *K8sIngressServiceImpl) GetIngresses(c *gin.Context, clusterId uint, namespaceName string) {
-	clientset, err := s.getK8sClient(clusterId)
+	clientset, err := s.getK8sClient(c, clusterId)
 	if err != nil {
 		result.Failed(c, http.StatusInternalServerError, "连接K8s集群失败: "+err.Error())
 		return
@@ -106,7 +106,7 @@ func (s *K8sIngressServiceImpl) GetIngresses(c *gin.Context, clusterId uint, nam
 
 // GetIngressDetail 获取Ingress详情
 func (s *K8sIngressServiceImpl) GetIngressDetail(c *gin.Context, clusterId uint, namespaceName string, ingressName string) {
-	clientset, err := s.getK8sClient(clusterId)
+	clientset, err := s.getK8sClient(c, clusterId)
 	if err != nil {
 		result.Failed(c, http.StatusInternalServerError, "连接K8s集群失败: "+err.Error())
 		return
@@ -135,7 +135,7 @@ func (s *K8sIngressServiceImpl) GetIngressDetail(c *gin.Context, clusterId uint,
 
 // CreateIngress 创建Ingress
 func (s *K8sIngressServiceImpl) CreateIngress(c *gin.Context, clusterId uint, namespaceName string, req *model.CreateIngressRequest) {
-	clientset, err := s.getK8sClient(clusterId)
+	clientset, err := s.getK8sClient(c, clusterId)
 	if err != nil {
 		result.Failed(c, http.StatusInternalServerError, "连接K8s集群失败: "+err.Error())
 		return
@@ -216,7 +216,7 @@ func (s *K8sIngressServiceImpl) CreateIngress(c *gin.Context, clusterId uint, na
 
 // UpdateIngress 更新Ingress
 func (s *K8sIngressServiceImpl) UpdateIngress(c *gin.Context, clusterId uint, namespaceName string, ingressName string, req *model.UpdateIngressRequest) {
-	clientset, err := s.getK8sClient(clusterId)
+	clientset, err := s.getK8sClient(c, clusterId)
 	if err != nil {
 		result.Failed(c, http.StatusInternalServerError, "连接K8s集群失败: "+err.Error())
 		return
@@ -301,7 +301,7 @@ func (s *K8sIngressServiceImpl) UpdateIngress(c *gin.Context, clusterId uint, na
 
 // DeleteIngress 删除Ingress
 func (s *K8sIngressServiceImpl) DeleteIngress(c *gin.Context, clusterId uint, namespaceName string, ingressName string) {
-	clientset, err := s.getK8sClient(clusterId)
+	clientset, err := s.getK8sClient(c, clusterId)
 	if err != nil {
 		result.Failed(c, http.StatusInternalServerError, "连接K8s集群失败: "+err.Error())
 		return
@@ -318,7 +318,7 @@ func (s *K8sIngressServiceImpl) DeleteIngress(c *gin.Context, clusterId uint, na
 
 // GetIngressYaml 获取Ingress的YAML配置
 func (s *K8sIngressServiceImpl) GetIngressYaml(c *gin.Context, clusterId uint, namespaceName string, ingressName string) {
-	clientset, err := s.getK8sClient(clusterId)
+	clientset, err := s.getK8sClient(c, clusterId)
 	if err != nil {
 		result.Failed(c, http.StatusInternalServerError, "连接K8s集群失败: "+err.Error())
 		return
@@ -361,7 +361,7 @@ func (s *K8sIngressServiceImpl) GetIngressYaml(c *gin.Context, clusterId uint, n
 
 // UpdateIngressYaml 通过YAML更新Ingress
 func (s *K8sIngressServiceImpl) UpdateIngressYaml(c *gin.Context, clusterId uint, namespaceName string, ingressName string, yamlData map[string]interface{}) {
-	clientset, err := s.getK8sClient(clusterId)
+	clientset, err := s.getK8sClient(c, clusterId)
 	if err != nil {
 		result.Failed(c, http.StatusInternalServerError, "连接K8s集群失败: "+err.Error())
 		return
@@ -429,7 +429,7 @@ func (s *K8sIngressServiceImpl) UpdateIngressYaml(c *gin.Context, clusterId uint
 
 // GetIngressEvents 获取Ingress事件
 func (s *K8sIngressServiceImpl) GetIngressEvents(c *gin.Context, clusterId uint, namespaceName string, ingressName string) {
-	clientset, err := s.getK8sClient(clusterId)
+	clientset, err := s.getK8sClient(c, clusterId)
 	if err != nil {
 		result.Failed(c, http.StatusInternalServerError, "连接K8s集群失败: "+err.Error())
 		return
@@ -446,7 +446,7 @@ func (s *K8sIngressServiceImpl) GetIngressEvents(c *gin.Context, clusterId uint,
 
 // GetIngressMonitoring 获取Ingress监控信息
 func (s *K8sIngressServiceImpl) GetIngressMonitoring(c *gin.Context, clusterId uint, namespaceName string, ingressName string) {
-	clientset, err := s.getK8sClient(clusterId)
+	clientset, err := s.getK8sClient(c, clusterId)
 	if err != nil {
 		result.Failed(c, http.StatusInternalServerError, "连接K8s集群失败: "+err.Error())
 		return
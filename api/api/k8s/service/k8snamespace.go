@@ -82,7 +82,7 @@ func (s *K8sNamespaceServiceImpl) GetNamespaces(c *gin.Context, clusterId uint)
 	}
 
 	// 获取集群信息
-	cluster, err := s.clusterDao.GetByID(clusterId)
+	cluster, err := s.clusterDao.WithContext(c).GetByID(clusterId)
 	if err != nil {
 		if err == gorm.ErrRecordNotFound {
 			result.Failed(c, http.StatusNotFound, "集群不存在")
@@ -141,7 +141,7 @@ func (s *K8sNamespaceServiceImpl) GetNamespace(c *gin.Context, clusterId uint, n
 	}
 
 	// 获取集群信息
-	cluster, err := s.clusterDao.GetByID(clusterId)
+	cluster, err := s.clusterDao.WithContext(c).GetByID(clusterId)
 	if err != nil {
 		if err == gorm.ErrRecordNotFound {
 			result.Failed(c, http.StatusNotFound, "集群不存在")
@@ -176,7 +176,7 @@ func (s *K8sNamespaceServiceImpl) GetNamespace(c *gin.Context, clusterId uint, n
 // CreateNamespace 创建命名空间
 func (s *K8sNamespaceServiceImpl) CreateNamespace(c *gin.Context, clusterId uint, req *model.CreateNamespaceRequest) {
 	// 获取集群信息
-	cluster, err := s.clusterDao.GetByID(clusterId)
+	cluster, err := s.clusterDao.WithContext(c).GetByID(clusterId)
 	if err != nil {
 		if err == gorm.ErrRecordNotFound {
 			result.Failed(c, http.StatusNotFound, "集群不存在")
@@ -249,7 +249,7 @@ func (s *K8sNamespaceServiceImpl) CreateNamespace(c *gin.Context, clusterId uint
 // UpdateNamespace 更新命名空间
 func (s *K8sNamespaceServiceImpl) UpdateNamespace(c *gin.Context, clusterId uint, namespaceName string, req *model.UpdateNamespaceRequest) {
 	// 获取集群信息
-	cluster, err := s.clusterDao.GetByID(clusterId)
+	cluster, err := s.clusterDao.WithContext(c).GetByID(clusterId)
 	if err != nil {
 		if err == gorm.ErrRecordNotFound {
 			result.Failed(c, http.StatusNotFound, "集群不存在")
@@ -315,7 +315,7 @@ func (s *K8sNamespaceServiceImpl) UpdateNamespace(c *gin.Context, clusterId uint
 // DeleteNamespace 删除命名空间
 func (s *K8sNamespaceServiceImpl) DeleteNamespace(c *gin.Context, clusterId uint, namespaceName string) {
 	// 获取集群信息
-	cluster, err := s.clusterDao.GetByID(clusterId)
+	cluster, err := s.clusterDao.WithContext(c).GetByID(clusterId)
 	if err != nil {
 		if err == gorm.ErrRecordNotFound {
 			result.Failed(c, http.StatusNotFound, "集群不存在")
@@ -474,7 +474,7 @@ func (s *K8sNamespaceServiceImpl) validateNamespaceName(name string) error {
 // GetResourceQuotas 获取ResourceQuota列表
 func (s *K8sNamespaceServiceImpl) GetResourceQuotas(c *gin.Context, clusterId uint, namespaceName string) {
 	// 获取集群信息
-	cluster, err := s.clusterDao.GetByID(clusterId)
+	cluster, err := s.clusterDao.WithContext(c).GetByID(clusterId)
 	if err != nil {
 		if err == gorm.ErrRecordNotFound {
 			result.Failed(c, http.StatusNotFound, "集群不存在")
@@ -515,7 +515,7 @@ func (s *K8sNamespaceServiceImpl) GetResourceQuotas(c *gin.Context, clusterId ui
 // CreateResourceQuota 创建ResourceQuota
 func (s *K8sNamespaceServiceImpl) CreateResourceQuota(c *gin.Context, clusterId uint, namespaceName string, req *model.CreateResourceQuotaRequest) {
 	// 获取集群信息
-	cluster, err := s.clusterDao.GetByID(clusterId)
+	cluster, err := s.clusterDao.WithContext(c).GetByID(clusterId)
 	if err != nil {
 		if err == gorm.ErrRecordNotFound {
 			result.Failed(c, http.StatusNotFound, "集群不存在")
@@ -574,7 +574,7 @@ func (s *K8sNamespaceServiceImpl) CreateResourceQuota(c *gin.Context, clusterId
 // UpdateResourceQuota 更新ResourceQuota
 func (s *K8sNamespaceServiceImpl) UpdateResourceQuota(c *gin.Context, clusterId uint, namespaceName string, quotaName string, req *model.UpdateResourceQuotaRequest) {
 	// 获取集群信息
-	cluster, err := s.clusterDao.GetByID(clusterId)
+	cluster, err := s.clusterDao.WithContext(c).GetByID(clusterId)
 	if err != nil {
 		if err == gorm.ErrRecordNotFound {
 			result.Failed(c, http.StatusNotFound, "集群不存在")
@@ -625,7 +625,7 @@ func (s *K8sNamespaceServiceImpl) UpdateResourceQuota(c *gin.Context, clusterId
 // DeleteResourceQuota 删除ResourceQuota
 func (s *K8sNamespaceServiceImpl) DeleteResourceQuota(c *gin.Context, clusterId uint, namespaceName string, quotaName string) {
 	// 获取集群信息
-	cluster, err := s.clusterDao.GetByID(clusterId)
+	cluster, err := s.clusterDao.WithContext(c).GetByID(clusterId)
 	if err != nil {
 		if err == gorm.ErrRecordNotFound {
 			result.Failed(c, http.StatusNotFound, "集群不存在")
@@ -676,7 +676,7 @@ func (s *K8sNamespaceServiceImpl) convertToResourceQuotaDetail(quota *corev1.Res
 // GetLimitRanges 获取LimitRange列表
 func (s *K8sNamespaceServiceImpl) GetLimitRanges(c *gin.Context, clusterId uint, namespaceName string) {
 	// 获取集群信息
-	cluster, err := s.clusterDao.GetByID(clusterId)
+	cluster, err := s.clusterDao.WithContext(c).GetByID(clusterId)
 	if err != nil {
 		if err == gorm.ErrRecordNotFound {
 			result.Failed(c, http.StatusNotFound, "集群不存在")
@@ -717,7 +717,7 @@ func (s *K8sNamespaceServiceImpl) GetLimitRanges(c *gin.Context, clusterId uint,
 // CreateLimitRange 创建LimitRange
 func (s *K8sNamespaceServiceImpl) CreateLimitRange(c *gin.Context, clusterId uint, namespaceName string, req *model.CreateLimitRangeRequest) {
 	// 获取集群信息
-	cluster, err := s.clusterDao.GetByID(clusterId)
+	cluster, err := s.clusterDao.WithContext(c).GetByID(clusterId)
 	if err != nil {
 		if err == gorm.ErrRecordNotFound {
 			result.Failed(c, http.StatusNotFound, "集群不存在")
@@ -817,7 +817,7 @@ func (s *K8sNamespaceServiceImpl) CreateLimitRange(c *gin.Context, clusterId uin
 // UpdateLimitRange 更新LimitRange
 func (s *K8sNamespaceServiceImpl) UpdateLimitRange(c *gin.Context, clusterId uint, namespaceName string, limitRangeName string, req *model.UpdateLimitRangeRequest) {
 	// 获取集群信息
-	cluster, err := s.clusterDao.GetByID(clusterId)
+	cluster, err := s.clusterDao.WithContext(c).GetByID(clusterId)
 	if err != nil {
 		if err == gorm.ErrRecordNotFound {
 			result.Failed(c, http.StatusNotFound, "集群不存在")
@@ -909,7 +909,7 @@ func (s *K8sNamespaceServiceImpl) UpdateLimitRange(c *gin.Context, clusterId uin
 // DeleteLimitRange 删除LimitRange
 func (s *K8sNamespaceServiceImpl) DeleteLimitRange(c *gin.Context, clusterId uint, namespaceName string, limitRangeName string) {
 	// 获取集群信息
-	cluster, err := s.clusterDao.GetByID(clusterId)
+	cluster, err := s.clusterDao.WithContext(c).GetByID(clusterId)
 	if err != nil {
 		if err == gorm.ErrRecordNotFound {
 			result.Failed(c, http.StatusNotFound, "集群不存在")
@@ -49,7 +49,7 @@ func NewK8sNodesService(db *gorm.DB) IK8sNodesService {
 // GetNodes 获取集群的所有节点信息
 func (s *K8sNodesServiceImpl) GetNodes(c *gin.Context, clusterId uint) {
 	// 获取集群信息
-	cluster, err := s.clusterDao.GetByID(clusterId)
+	cluster, err := s.clusterDao.WithContext(c).GetByID(clusterId)
 	if err != nil {
 		if err == gorm.ErrRecordNotFound {
 			result.Failed(c, http.StatusNotFound, "集群不存在")
@@ -85,7 +85,7 @@ func (s *K8sNodesServiceImpl) GetNodes(c *gin.Context, clusterId uint) {
 // GetNodeDetail 获取单个节点的详细信息
 func (s *K8sNodesServiceImpl) GetNodeDetail(c *gin.Context, clusterId uint, nodeName string) {
 	// 获取集群信息
-	cluster, err := s.clusterDao.GetByID(clusterId)
+	cluster, err := s.clusterDao.WithContext(c).GetByID(clusterId)
 	if err != nil {
 		if err == gorm.ErrRecordNotFound {
 			result.Failed(c, http.StatusNotFound, "集群不存在")
@@ -659,7 +659,7 @@ func contains(slice []string, item string) bool {
 // AddTaint 为节点添加污点
 func (s *K8sNodesServiceImpl) AddTaint(c *gin.Context, clusterId uint, nodeName string, req *model.AddTaintRequest) {
 	// 获取集群信息
-	cluster, err := s.clusterDao.GetByID(clusterId)
+	cluster, err := s.clusterDao.WithContext(c).GetByID(clusterId)
 	if err != nil {
 		if err == gorm.ErrRecordNotFound {
 			result.Failed(c, http.StatusNotFound, "集群不存在")
@@ -713,7 +713,7 @@ func (s *K8sNodesServiceImpl) AddTaint(c *gin.Context, clusterId uint, nodeName
 // RemoveTaint 移除节点污点
 func (s *K8sNodesServiceImpl) RemoveTaint(c *gin.Context, clusterId uint, nodeName string, req *model.RemoveTaintRequest) {
 	// 获取集群信息
-	cluster, err := s.clusterDao.GetByID(clusterId)
+	cluster, err := s.clusterDao.WithContext(c).GetByID(clusterId)
 	if err != nil {
 		if err == gorm.ErrRecordNotFound {
 			result.Failed(c, http.StatusNotFound, "集群不存在")
@@ -768,7 +768,7 @@ func (s *K8sNodesServiceImpl) RemoveTaint(c *gin.Context, clusterId uint, nodeNa
 // AddLabel 为节点添加标签
 func (s *K8sNodesServiceImpl) AddLabel(c *gin.Context, clusterId uint, nodeName string, req *model.AddLabelRequest) {
 	// 获取集群信息
-	cluster, err := s.clusterDao.GetByID(clusterId)
+	cluster, err := s.clusterDao.WithContext(c).GetByID(clusterId)
 	if err != nil {
 		if err == gorm.ErrRecordNotFound {
 			result.Failed(c, http.StatusNotFound, "集群不存在")
@@ -811,7 +811,7 @@ func (s *K8sNodesServiceImpl) AddLabel(c *gin.Context, clusterId uint, nodeName
 // RemoveLabel 移除节点标签
 func (s *K8sNodesServiceImpl) RemoveLabel(c *gin.Context, clusterId uint, nodeName string, req *model.RemoveLabelRequest) {
 	// 获取集群信息
-	cluster, err := s.clusterDao.GetByID(clusterId)
+	cluster, err := s.clusterDao.WithContext(c).GetByID(clusterId)
 	if err != nil {
 		if err == gorm.ErrRecordNotFound {
 			result.Failed(c, http.StatusNotFound, "集群不存在")
@@ -857,7 +857,7 @@ func (s *K8sNodesServiceImpl) RemoveLabel(c *gin.Context, clusterId uint, nodeNa
 // CordonNode 封锁/解封节点
 func (s *K8sNodesServiceImpl) CordonNode(c *gin.Context, clusterId uint, nodeName string, req *model.CordonNodeRequest) {
 	// 获取集群信息
-	cluster, err := s.clusterDao.GetByID(clusterId)
+	cluster, err := s.clusterDao.WithContext(c).GetByID(clusterId)
 	if err != nil {
 		if err == gorm.ErrRecordNotFound {
 			result.Failed(c, http.StatusNotFound, "集群不存在")
@@ -902,7 +902,7 @@ func (s *K8sNodesServiceImpl) CordonNode(c *gin.Context, clusterId uint, nodeNam
 // DrainNode 驱逐节点
 func (s *K8sNodesServiceImpl) DrainNode(c *gin.Context, clusterId uint, nodeName string, req *model.DrainNodeRequest) {
 	// 获取集群信息
-	cluster, err := s.clusterDao.GetByID(clusterId)
+	cluster, err := s.clusterDao.WithContext(c).GetByID(clusterId)
 	if err != nil {
 		if err == gorm.ErrRecordNotFound {
 			result.Failed(c, http.StatusNotFound, "集群不存在")
@@ -986,7 +986,7 @@ func (s *K8sNodesServiceImpl) DrainNode(c *gin.Context, clusterId uint, nodeName
 // GetNodeResourceAllocation 获取节点资源分配详情
 func (s *K8sNodesServiceImpl) GetNodeResourceAllocation(c *gin.Context, clusterId uint, nodeName string) {
 	// 获取集群信息
-	cluster, err := s.clusterDao.GetByID(clusterId)
+	cluster, err := s.clusterDao.WithContext(c).GetByID(clusterId)
 	if err != nil {
 		if err == gorm.ErrRecordNotFound {
 			result.Failed(c, http.StatusNotFound, "集群不存在")
@@ -1097,7 +1097,7 @@ func (s *K8sNodesServiceImpl) calculateResourceAllocation(node *corev1.Node, pod
 // GetNodeDetailEnhanced 获取增强的节点详细信息
 func (s *K8sNodesServiceImpl) GetNodeDetailEnhanced(c *gin.Context, clusterId uint, nodeName string) {
 	// 获取集群信息
-	cluster, err := s.clusterDao.GetByID(clusterId)
+	cluster, err := s.clusterDao.WithContext(c).GetByID(clusterId)
 	if err != nil {
 		if err == gorm.ErrRecordNotFound {
 			result.Failed(c, http.StatusNotFound, "集群不存在")
@@ -47,8 +47,8 @@ func NewK8sServiceService(db *gorm.DB) IK8sServiceService {
 }
 
 // getK8sClient 获取K8s客户端
-func (s *K8sServiceServiceImpl) getK8sClient(clusterId uint) (*kubernetes.Clientset, error) {
-	cluster, err := s.clusterDao.GetByID(clusterId)
+func (s *K8sServiceServiceImpl) getK8sClient(ctx context.Context, clusterId uint) (*kubernetes.Clientset, error) {
+	cluster, err := s.clusterDao.WithContext(ctx).GetByID(clusterId)
 	if err != nil {
 		return nil, fmt.Errorf("获取集群信息失败: %v", err)
 	}
@@ -72,7 +72,7 @@ func (s *K8sServiceServiceImpl) getK8sClient(clusterId uint) (*kubernetes.Client
 
 // GetServices 获取Service列表
 func (s *K8sServiceServiceImpl) GetServices(c *gin.Context, clusterId uint, namespaceName string) {
-	clientset, err := s.getK8sClient(clusterId)
+	clientset, err := s.getK8sClient(c, clusterId)
 	if err != nil {
 		result.Failed(c, http.StatusInternalServerError, "连接K8s集群失败: "+err.Error())
 		return
@@ -100,7 +100,7 @@ func (s *K8sServiceServiceImpl) GetServices(c *gin.Context, clusterId uint, name
 
 // GetServiceDetail 获取Service详情
 func (s *K8sServiceServiceImpl) GetServiceDetail(c *gin.Context, clusterId uint, namespaceName string, serviceName string) {
-	clientset, err := s.getK8sClient(clusterId)
+	clientset, err := s.getK8sClient(c, clusterId)
 	if err != nil {
 		result.Failed(c, http.StatusInternalServerError, "连接K8s集群失败: "+err.Error())
 		return
@@ -133,7 +133,7 @@ func (s *K8sServiceServiceImpl) GetServiceDetail(c *gin.Context, clusterId uint,
 
 // CreateService 创建Service
 func (s *K8sServiceServiceImpl) CreateService(c *gin.Context, clusterId uint, namespaceName string, req *model.CreateServiceRequest) {
-	clientset, err := s.getK8sClient(clusterId)
+	clientset, err := s.getK8sClient(c, clusterId)
 	if err != nil {
 		result.Failed(c, http.StatusInternalServerError, "连接K8s集群失败: "+err.Error())
 		return
@@ -189,7 +189,7 @@ func (s *K8sServiceServiceImpl) CreateService(c *gin.Context, clusterId uint, na
 
 // UpdateService 更新Service
 func (s *K8sServiceServiceImpl) UpdateService(c *gin.Context, clusterId uint, namespaceName string, serviceName string, req *model.UpdateServiceRequest) {
-	clientset, err := s.getK8sClient(clusterId)
+	clientset, err := s.getK8sClient(c, clusterId)
 	if err != nil {
 		result.Failed(c, http.StatusInternalServerError, "连接K8s集群失败: "+err.Error())
 		return
@@ -254,7 +254,7 @@ func (s *K8sServiceServiceImpl) UpdateService(c *gin.Context, clusterId uint, na
 
 // DeleteService 删除Service
 func (s *K8sServiceServiceImpl) DeleteService(c *gin.Context, clusterId uint, namespaceName string, serviceName string) {
-	clientset, err := s.getK8sClient(clusterId)
+	clientset, err := s.getK8sClient(c, clusterId)
 	if err != nil {
 		result.Failed(c, http.StatusInternalServerError, "连接K8s集群失败: "+err.Error())
 		return
@@ -271,7 +271,7 @@ func (s *K8sServiceServiceImpl) DeleteService(c *gin.Context, clusterId uint, na
 
 // GetServiceYaml 获取Service的YAML配置
 func (s *K8sServiceServiceImpl) GetServiceYaml(c *gin.Context, clusterId uint, namespaceName string, serviceName string) {
-	clientset, err := s.getK8sClient(clusterId)
+	clientset, err := s.getK8sClient(c, clusterId)
 	if err != nil {
 		result.Failed(c, http.StatusInternalServerError, "连接K8s集群失败: "+err.Error())
 		return
@@ -314,7 +314,7 @@ func (s *K8sServiceServiceImpl) GetServiceYaml(c *gin.Context, clusterId uint, n
 
 // UpdateServiceYaml 通过YAML更新Service
 func (s *K8sServiceServiceImpl) UpdateServiceYaml(c *gin.Context, clusterId uint, namespaceName string, serviceName string, yamlData map[string]interface{}) {
-	clientset, err := s.getK8sClient(clusterId)
+	clientset, err := s.getK8sClient(c, clusterId)
 	if err != nil {
 		result.Failed(c, http.StatusInternalServerError, "连接K8s集群失败: "+err.Error())
 		return
@@ -371,7 +371,7 @@ func (s *K8sServiceServiceImpl) UpdateServiceYaml(c *gin.Context, clusterId uint
 
 // GetServiceEvents 获取Service事件
 func (s *K8sServiceServiceImpl) GetServiceEvents(c *gin.Context, clusterId uint, namespaceName string, serviceName string) {
-	clientset, err := s.getK8sClient(clusterId)
+	clientset, err := s.getK8sClient(c, clusterId)
 	if err != nil {
 		result.Failed(c, http.StatusInternalServerError, "连接K8s集群失败: "+err.Error())
 		return
@@ -65,8 +65,8 @@ func NewK8sStorageService(db *gorm.DB) IK8sStorageService {
 }
 
 // getK8sClient 获取K8s客户端
-func (s *K8sStorageServiceImpl) getK8sClient(clusterId uint) (*kubernetes.Clientset, error) {
-	cluster, err := s.clusterDao.GetByID(clusterId)
+func (s *K8sStorageServiceImpl) getK8sClient(ctx context.Context, clusterId uint) (*kubernetes.Clientset, error) {
+	cluster, err := s.clusterDao.WithContext(ctx).GetByID(clusterId)
 	if err != nil {
 		return nil, fmt.Errorf("获取集群信息失败: %v", err)
 	}
@@ -92,7 +92,7 @@ func (s *K8sStorageServiceImpl) getK8sClient(clusterId uint) (*kubernetes.Client
 
 // GetPVCs 获取PVC列表
 func (s *K8sStorageServiceImpl) GetPVCs(c *gin.Context, clusterId uint, namespaceName string) {
-	clientset, err := s.getK8sClient(clusterId)
+	clientset, err := s.getK8sClient(c, clusterId)
 	if err != nil {
 		result.Failed(c, http.StatusInternalServerError, "连接K8s集群失败: "+err.Error())
 		return
@@ -120,7 +120,7 @@ func (s *K8sStorageServiceImpl) GetPVCs(c *gin.Context, clusterId uint, namespac
 
 // GetPVCDetail 获取PVC详情
 func (s *K8sStorageServiceImpl) GetPVCDetail(c *gin.Context, clusterId uint, namespaceName string, pvcName string) {
-	clientset, err := s.getK8sClient(clusterId)
+	clientset, err := s.getK8sClient(c, clusterId)
 	if err != nil {
 		result.Failed(c, http.StatusInternalServerError, "连接K8s集群失败: "+err.Error())
 		return
@@ -148,7 +148,7 @@ func (s *K8sStorageServiceImpl) GetPVCDetail(c *gin.Context, clusterId uint, nam
 
 // CreatePVC 创建PVC
 func (s *K8sStorageServiceImpl) CreatePVC(c *gin.Context, clusterId uint, namespaceName string, req *model.CreatePVCRequest) {
-	clientset, err := s.getK8sClient(clusterId)
+	clientset, err := s.getK8sClient(c, clusterId)
 	if err != nil {
 		result.Failed(c, http.StatusInternalServerError, "连接K8s集群失败: "+err.Error())
 		return
@@ -237,7 +237,7 @@ func (s *K8sStorageServiceImpl) CreatePVC(c *gin.Context, clusterId uint, namesp
 
 // UpdatePVC 更新PVC
 func (s *K8sStorageServiceImpl) UpdatePVC(c *gin.Context, clusterId uint, namespaceName string, pvcName string, req *model.UpdatePVCRequest) {
-	clientset, err := s.getK8sClient(clusterId)
+	clientset, err := s.getK8sClient(c, clusterId)
 	if err != nil {
 		result.Failed(c, http.StatusInternalServerError, "连接K8s集群失败: "+err.Error())
 		return
@@ -280,7 +280,7 @@ func (s *K8sStorageServiceImpl) UpdatePVC(c *gin.Context, clusterId uint, namesp
 
 // DeletePVC 删除PVC
 func (s *K8sStorageServiceImpl) DeletePVC(c *gin.Context, clusterId uint, namespaceName string, pvcName string) {
-	clientset, err := s.getK8sClient(clusterId)
+	clientset, err := s.getK8sClient(c, clusterId)
 	if err != nil {
 		result.Failed(c, http.StatusInternalServerError, "连接K8s集群失败: "+err.Error())
 		return
@@ -297,7 +297,7 @@ func (s *K8sStorageServiceImpl) DeletePVC(c *gin.Context, clusterId uint, namesp
 
 // GetPVCYaml 获取PVC的YAML配置
 func (s *K8sStorageServiceImpl) GetPVCYaml(c *gin.Context, clusterId uint, namespaceName string, pvcName string) {
-	clientset, err := s.getK8sClient(clusterId)
+	clientset, err := s.getK8sClient(c, clusterId)
 	if err != nil {
 		result.Failed(c, http.StatusInternalServerError, "连接K8s集群失败: "+err.Error())
 		return
@@ -340,7 +340,7 @@ func (s *K8sStorageServiceImpl) GetPVCYaml(c *gin.Context, clusterId uint, names
 
 // UpdatePVCYaml 通过YAML更新PVC
 func (s *K8sStorageServiceImpl) UpdatePVCYaml(c *gin.Context, clusterId uint, namespaceName string, pvcName string, yamlData map[string]interface{}) {
-	clientset, err := s.getK8sClient(clusterId)
+	clientset, err := s.getK8sClient(c, clusterId)
 	if err != nil {
 		result.Failed(c, http.StatusInternalServerError, "连接K8s集群失败: "+err.Error())
 		return
@@ -378,7 +378,7 @@ func (s *K8sStorageServiceImpl) UpdatePVCYaml(c *gin.Context, clusterId uint, na
 
 // GetPVs 获取PV列表
 func (s *K8sStorageServiceImpl) GetPVs(c *gin.Context, clusterId uint) {
-	clientset, err := s.getK8sClient(clusterId)
+	clientset, err := s.getK8sClient(c, clusterId)
 	if err != nil {
 		result.Failed(c, http.StatusInternalServerError, "连接K8s集群失败: "+err.Error())
 		return
@@ -406,7 +406,7 @@ func (s *K8sStorageServiceImpl) GetPVs(c *gin.Context, clusterId uint) {
 
 // GetPVDetail 获取PV详情
 func (s *K8sStorageServiceImpl) GetPVDetail(c *gin.Context, clusterId uint, pvName string) {
-	clientset, err := s.getK8sClient(clusterId)
+	clientset, err := s.getK8sClient(c, clusterId)
 	if err != nil {
 		result.Failed(c, http.StatusInternalServerError, "连接K8s集群失败: "+err.Error())
 		return
@@ -434,7 +434,7 @@ func (s *K8sStorageServiceImpl) GetPVDetail(c *gin.Context, clusterId uint, pvNa
 
 // CreatePV 创建PV
 func (s *K8sStorageServiceImpl) CreatePV(c *gin.Context, clusterId uint, req *model.CreatePVRequest) {
-	clientset, err := s.getK8sClient(clusterId)
+	clientset, err := s.getK8sClient(c, clusterId)
 	if err != nil {
 		result.Failed(c, http.StatusInternalServerError, "连接K8s集群失败: "+err.Error())
 		return
@@ -516,7 +516,7 @@ func (s *K8sStorageServiceImpl) CreatePV(c *gin.Context, clusterId uint, req *mo
 
 // UpdatePV 更新PV
 func (s *K8sStorageServiceImpl) UpdatePV(c *gin.Context, clusterId uint, pvName string, req *model.UpdatePVRequest) {
-	clientset, err := s.getK8sClient(clusterId)
+	clientset, err := s.getK8sClient(c, clusterId)
 	if err != nil {
 		result.Failed(c, http.StatusInternalServerError, "连接K8s集群失败: "+err.Error())
 		return
@@ -558,7 +558,7 @@ func (s *K8sStorageServiceImpl) UpdatePV(c *gin.Context, clusterId uint, pvName
 
 // DeletePV 删除PV
 func (s *K8sStorageServiceImpl) DeletePV(c *gin.Context, clusterId uint, pvName string) {
-	clientset, err := s.getK8sClient(clusterId)
+	clientset, err := s.getK8sClient(c, clusterId)
 	if err != nil {
 		result.Failed(c, http.StatusInternalServerError, "连接K8s集群失败: "+err.Error())
 		return
@@ -575,7 +575,7 @@ func (s *K8sStorageServiceImpl) DeletePV(c *gin.Context, clusterId uint, pvName
 
 // GetPVYaml 获取PV的YAML配置
 func (s *K8sStorageServiceImpl) GetPVYaml(c *gin.Context, clusterId uint, pvName string) {
-	clientset, err := s.getK8sClient(clusterId)
+	clientset, err := s.getK8sClient(c, clusterId)
 	if err != nil {
 		result.Failed(c, http.StatusInternalServerError, "连接K8s集群失败: "+err.Error())
 		return
@@ -618,7 +618,7 @@ func (s *K8sStorageServiceImpl) GetPVYaml(c *gin.Context, clusterId uint, pvName
 
 // UpdatePVYaml 通过YAML更新PV
 func (s *K8sStorageServiceImpl) UpdatePVYaml(c *gin.Context, clusterId uint, pvName string, yamlData map[string]interface{}) {
-	clientset, err := s.getK8sClient(clusterId)
+	clientset, err := s.getK8sClient(c, clusterId)
 	if err != nil {
 		result.Failed(c, http.StatusInternalServerError, "连接K8s集群失败: "+err.Error())
 		return
@@ -655,7 +655,7 @@ func (s *K8sStorageServiceImpl) UpdatePVYaml(c *gin.Context, clusterId uint, pvN
 
 // GetStorageClasses 获取存储类列表
 func (s *K8sStorageServiceImpl) GetStorageClasses(c *gin.Context, clusterId uint) {
-	clientset, err := s.getK8sClient(clusterId)
+	clientset, err := s.getK8sClient(c, clusterId)
 	if err != nil {
 		result.Failed(c, http.StatusInternalServerError, "连接K8s集群失败: "+err.Error())
 		return
@@ -683,7 +683,7 @@ func (s *K8sStorageServiceImpl) GetStorageClasses(c *gin.Context, clusterId uint
 
 // GetStorageClassDetail 获取存储类详情
 func (s *K8sStorageServiceImpl) GetStorageClassDetail(c *gin.Context, clusterId uint, storageClassName string) {
-	clientset, err := s.getK8sClient(clusterId)
+	clientset, err := s.getK8sClient(c, clusterId)
 	if err != nil {
 		result.Failed(c, http.StatusInternalServerError, "连接K8s集群失败: "+err.Error())
 		return
@@ -711,7 +711,7 @@ func (s *K8sStorageServiceImpl) GetStorageClassDetail(c *gin.Context, clusterId
 
 // CreateStorageClass 创建存储类
 func (s *K8sStorageServiceImpl) CreateStorageClass(c *gin.Context, clusterId uint, req *model.CreateStorageClassRequest) {
-	clientset, err := s.getK8sClient(clusterId)
+	clientset, err := s.getK8sClient(c, clusterId)
 	if err != nil {
 		result.Failed(c, http.StatusInternalServerError, "连接K8s集群失败: "+err.Error())
 		return
@@ -772,7 +772,7 @@ func (s *K8sStorageServiceImpl) CreateStorageClass(c *gin.Context, clusterId uin
 
 // UpdateStorageClass 更新存储类
 func (s *K8sStorageServiceImpl) UpdateStorageClass(c *gin.Context, clusterId uint, storageClassName string, req *model.UpdateStorageClassRequest) {
-	clientset, err := s.getK8sClient(clusterId)
+	clientset, err := s.getK8sClient(c, clusterId)
 	if err != nil {
 		result.Failed(c, http.StatusInternalServerError, "连接K8s集群失败: "+err.Error())
 		return
@@ -816,7 +816,7 @@ func (s *K8sStorageServiceImpl) UpdateStorageClass(c *gin.Context, clusterId uin
 
 // DeleteStorageClass 删除存储类
 func (s *K8sStorageServiceImpl) DeleteStorageClass(c *gin.Context, clusterId uint, storageClassName string) {
-	clientset, err := s.getK8sClient(clusterId)
+	clientset, err := s.getK8sClient(c, clusterId)
 	if err != nil {
 		result.Failed(c, http.StatusInternalServerError, "连接K8s集群失败: "+err.Error())
 		return
@@ -833,7 +833,7 @@ func (s *K8sStorageServiceImpl) DeleteStorageClass(c *gin.Context, clusterId uin
 
 // GetStorageClassYaml 获取存储类的YAML配置
 func (s *K8sStorageServiceImpl) GetStorageClassYaml(c *gin.Context, clusterId uint, storageClassName string) {
-	clientset, err := s.getK8sClient(clusterId)
+	clientset, err := s.getK8sClient(c, clusterId)
 	if err != nil {
 		result.Failed(c, http.StatusInternalServerError, "连接K8s集群失败: "+err.Error())
 		return
@@ -876,7 +876,7 @@ func (s *K8sStorageServiceImpl) GetStorageClassYaml(c *gin.Context, clusterId ui
 
 // UpdateStorageClassYaml 通过YAML更新存储类
 func (s *K8sStorageServiceImpl) UpdateStorageClassYaml(c *gin.Context, clusterId uint, storageClassName string, yamlData map[string]interface{}) {
-	clientset, err := s.getK8sClient(clusterId)
+	clientset, err := s.getK8sClient(c, clusterId)
 	if err != nil {
 		result.Failed(c, http.StatusInternalServerError, "连接K8s集群失败: "+err.Error())
 		return
@@ -10,6 +10,7 @@ import (
 	"sync"
 
 	"dodevops-api/api/k8s/dao"
+	wsutil "dodevops-api/common/util/websocket"
 
 	"github.com/gorilla/websocket"
 	"gorm.io/gorm"
@@ -21,7 +22,7 @@ import (
 
 // IK8sTerminalService 容器终端服务接口
 type IK8sTerminalService interface {
-	CreateK8sWebSocketStream(clusterId uint, namespaceName, podName, containerName, command string, conn *websocket.Conn) (*K8sWebSocketStream, error)
+	CreateK8sWebSocketStream(clusterId uint, namespaceName, podName, containerName, command string, conn *websocket.Conn, recorder wsutil.SessionRecorder, checker wsutil.CommandChecker) (*K8sWebSocketStream, error)
 	GetPodContainers(clusterId uint, namespaceName, podName string) ([]string, error)
 	GetPodFileList(clusterId uint, namespaceName, podName, containerName, path string) ([]map[string]interface{}, error)
 	GetPodFileContent(clusterId uint, namespaceName, podName, containerName, path string) (string, error)
@@ -51,6 +52,9 @@ type K8sWebSocketStream struct {
 	closed   bool
 	reader   *io.PipeReader
 	writer   *io.PipeWriter
+	recorder wsutil.SessionRecorder
+	guard    *wsutil.CommandGuard
+	writeMu  sync.Mutex
 }
 
 // terminalConn 实现io.ReadWriter接口用于K8s executor
@@ -83,6 +87,12 @@ func (kws *K8sWebSocketStream) Close() error {
 	return nil
 }
 
+// Terminate 向用户显示提示后断开终端
+func (kws *K8sWebSocketStream) Terminate(message string) {
+	kws.WriteToWebSocket([]byte(message))
+	kws.Close()
+}
+
 // IsClosed 检查是否已关闭
 func (kws *K8sWebSocketStream) IsClosed() bool {
 	kws.RLock()
@@ -96,6 +106,11 @@ func (kws *K8sWebSocketStream) WriteToWebSocket(p []byte) (n int, err error) {
 		return 0, io.EOF
 	}
 
+	kws.writeMu.Lock()
+	defer kws.writeMu.Unlock()
+	if kws.recorder != nil {
+		kws.recorder.Output(p)
+	}
 	message := K8sMessage{
 		Operation: "stdout",
 		Data:      string(p),
@@ -140,13 +155,33 @@ func (kws *K8sWebSocketStream) ReadFromWebSocket() {
 					continue
 				}
 
-				_, err := kws.writer.Write([]byte(data))
+				input := []byte(data)
+				// 检查命令，拦截的命令不发送到容器
+				if kws.guard != nil {
+					var notice string
+					input, notice = kws.guard.Filter(input)
+					if notice != "" {
+						if _, err := kws.WriteToWebSocket([]byte(notice)); err != nil {
+							return
+						}
+					}
+					if len(input) == 0 {
+						continue
+					}
+				}
+				if kws.recorder != nil {
+					kws.recorder.Input(input)
+				}
+				_, err := kws.writer.Write(input)
 				if err != nil {
 					return
 				}
 			}
 		case "resize":
-			// 处理终端大小调整，暂时忽略以避免问题
+			// 处理终端大小调整，暂时忽略以避免问题，仅记录到会话录像
+			if kws.recorder != nil {
+				kws.recorder.Resize(message.Cols, message.Rows)
+			}
 			continue
 		default:
 			// 忽略未知操作
@@ -167,7 +202,7 @@ func NewK8sTerminalService(db *gorm.DB) IK8sTerminalService {
 }
 
 // CreateK8sWebSocketStream 创建K8s WebSocket流
-func (s *K8sTerminalService) CreateK8sWebSocketStream(clusterId uint, namespaceName, podName, containerName, command string, conn *websocket.Conn) (*K8sWebSocketStream, error) {
+func (s *K8sTerminalService) CreateK8sWebSocketStream(clusterId uint, namespaceName, podName, containerName, command string, conn *websocket.Conn, recorder wsutil.SessionRecorder, checker wsutil.CommandChecker) (*K8sWebSocketStream, error) {
 	// 获取集群信息
 	cluster, err := s.dao.GetByID(clusterId)
 	if err != nil {
@@ -230,6 +265,10 @@ func (s *K8sTerminalService) CreateK8sWebSocketStream(clusterId uint, namespaceN
 		cancel:   cancel,
 		reader:   reader,
 		writer:   writer,
+		recorder: recorder,
+	}
+	if checker != nil {
+		stream.guard = wsutil.NewCommandGuard(checker)
 	}
 
 	// 创建终端连接
@@ -114,7 +114,7 @@ func NewK8sWorkloadService(db *gorm.DB) IK8sWorkloadService {
 // GetWorkloads 获取工作负载列表
 func (s *K8sWorkloadServiceImpl) GetWorkloads(c *gin.Context, clusterId uint, namespaceName string, workloadType string) {
 	// 获取集群信息
-	cluster, err := s.clusterDao.GetByID(clusterId)
+	cluster, err := s.clusterDao.WithContext(c).GetByID(clusterId)
 	if err != nil {
 		if err == gorm.ErrRecordNotFound {
 			result.Failed(c, http.StatusNotFound, "集群不存在")
@@ -184,7 +184,7 @@ func (s *K8sWorkloadServiceImpl) GetWorkloads(c *gin.Context, clusterId uint, na
 // GetWorkloadDetail 获取工作负载详情
 func (s *K8sWorkloadServiceImpl) GetWorkloadDetail(c *gin.Context, clusterId uint, namespaceName string, workloadType string, workloadName string) {
 	// 获取集群信息
-	cluster, err := s.clusterDao.GetByID(clusterId)
+	cluster, err := s.clusterDao.WithContext(c).GetByID(clusterId)
 	if err != nil {
 		if err == gorm.ErrRecordNotFound {
 			result.Failed(c, http.StatusNotFound, "集群不存在")
@@ -883,7 +883,7 @@ func (s *K8sWorkloadServiceImpl) getCronJobDetail(clientset *kubernetes.Clientse
 
 // GetPods 获取Pod列表
 func (s *K8sWorkloadServiceImpl) GetPods(c *gin.Context, clusterId uint, namespaceName string) {
-	cluster, err := s.clusterDao.GetByID(clusterId)
+	cluster, err := s.clusterDao.WithContext(c).GetByID(clusterId)
 	if err != nil {
 		if err == gorm.ErrRecordNotFound {
 			result.Failed(c, http.StatusNotFound, "集群不存在")
@@ -919,7 +919,7 @@ func (s *K8sWorkloadServiceImpl) GetPods(c *gin.Context, clusterId uint, namespa
 
 // GetPodDetail 获取Pod详情
 func (s *K8sWorkloadServiceImpl) GetPodDetail(c *gin.Context, clusterId uint, namespaceName, podName string) {
-	cluster, err := s.clusterDao.GetByID(clusterId)
+	cluster, err := s.clusterDao.WithContext(c).GetByID(clusterId)
 	if err != nil {
 		if err == gorm.ErrRecordNotFound {
 			result.Failed(c, http.StatusNotFound, "集群不存在")
@@ -961,7 +961,7 @@ func (s *K8sWorkloadServiceImpl) GetPodDetail(c *gin.Context, clusterId uint, na
 
 // DeletePod 删除Pod
 func (s *K8sWorkloadServiceImpl) DeletePod(c *gin.Context, clusterId uint, namespaceName, podName string) {
-	cluster, err := s.clusterDao.GetByID(clusterId)
+	cluster, err := s.clusterDao.WithContext(c).GetByID(clusterId)
 	if err != nil {
 		if err == gorm.ErrRecordNotFound {
 			result.Failed(c, http.StatusNotFound, "集群不存在")
@@ -1002,7 +1002,7 @@ func (s *K8sWorkloadServiceImpl) GetPodLogs(c *gin.Context, clusterId uint, name
 	}
 	defer ws.Close()
 
-	cluster, err := s.clusterDao.GetByID(clusterId)
+	cluster, err := s.clusterDao.WithContext(c).GetByID(clusterId)
 	if err != nil {
 		errMsg := "获取集群信息失败: " + err.Error()
 		if err == gorm.ErrRecordNotFound {
@@ -1053,7 +1053,7 @@ func (s *K8sWorkloadServiceImpl) GetPodLogs(c *gin.Context, clusterId uint, name
 
 // GetPodEvents 获取Pod事件
 func (s *K8sWorkloadServiceImpl) GetPodEvents(c *gin.Context, clusterId uint, namespaceName, podName string) {
-	cluster, err := s.clusterDao.GetByID(clusterId)
+	cluster, err := s.clusterDao.WithContext(c).GetByID(clusterId)
 	if err != nil {
 		if err == gorm.ErrRecordNotFound {
 			result.Failed(c, http.StatusNotFound, "集群不存在")
@@ -1091,7 +1091,7 @@ func (s *K8sWorkloadServiceImpl) GetPodEvents(c *gin.Context, clusterId uint, na
 
 // GetPodYaml 获取Pod的YAML配置
 func (s *K8sWorkloadServiceImpl) GetPodYaml(c *gin.Context, clusterId uint, namespaceName, podName string) {
-	cluster, err := s.clusterDao.GetByID(clusterId)
+	cluster, err := s.clusterDao.WithContext(c).GetByID(clusterId)
 	if err != nil {
 		if err == gorm.ErrRecordNotFound {
 			result.Failed(c, http.StatusNotFound, "集群不存在")
@@ -1121,7 +1121,7 @@ func (s *K8sWorkloadServiceImpl) GetPodYaml(c *gin.Context, clusterId uint, name
 // UpdatePodYaml 更新Pod的YAML配置
 func (s *K8sWorkloadServiceImpl) UpdatePodYaml(c *gin.Context, clusterId uint, namespaceName, podName string, req *model.UpdatePodYAMLRequest) {
 	// 获取集群信息
-	cluster, err := s.clusterDao.GetByID(clusterId)
+	cluster, err := s.clusterDao.WithContext(c).GetByID(clusterId)
 	if err != nil {
 		result.Failed(c, http.StatusNotFound, "集群不存在")
 		return
@@ -1623,7 +1623,7 @@ func (s *K8sWorkloadServiceImpl) CreateDeployment(c *gin.Context, clusterId uint
 // UpdateDeployment 更新Deployment
 func (s *K8sWorkloadServiceImpl) UpdateDeployment(c *gin.Context, clusterId uint, namespaceName string, deploymentName string, req *model.UpdateWorkloadRequest) {
 	// 获取集群信息
-	cluster, err := s.clusterDao.GetByID(clusterId)
+	cluster, err := s.clusterDao.WithContext(c).GetByID(clusterId)
 	if err != nil {
 		if err == gorm.ErrRecordNotFound {
 			result.Failed(c, http.StatusNotFound, "集群不存在")
@@ -1913,7 +1913,7 @@ func (s *K8sWorkloadServiceImpl) UpdateDeployment(c *gin.Context, clusterId uint
 // DeleteDeployment 删除Deployment
 func (s *K8sWorkloadServiceImpl) DeleteDeployment(c *gin.Context, clusterId uint, namespaceName string, deploymentName string) {
 	// 获取集群信息
-	cluster, err := s.clusterDao.GetByID(clusterId)
+	cluster, err := s.clusterDao.WithContext(c).GetByID(clusterId)
 	if err != nil {
 		if err == gorm.ErrRecordNotFound {
 			result.Failed(c, http.StatusNotFound, "集群不存在")
@@ -1980,7 +1980,7 @@ func (s *K8sWorkloadServiceImpl) DeleteDeployment(c *gin.Context, clusterId uint
 // ScaleDeployment 伸缩Deployment
 func (s *K8sWorkloadServiceImpl) ScaleDeployment(c *gin.Context, clusterId uint, namespaceName string, deploymentName string, req *model.ScaleWorkloadRequest) {
 	// 获取集群信息
-	cluster, err := s.clusterDao.GetByID(clusterId)
+	cluster, err := s.clusterDao.WithContext(c).GetByID(clusterId)
 	if err != nil {
 		if err == gorm.ErrRecordNotFound {
 			result.Failed(c, http.StatusNotFound, "集群不存在")
@@ -2050,7 +2050,7 @@ func (s *K8sWorkloadServiceImpl) ScaleDeployment(c *gin.Context, clusterId uint,
 // RestartDeployment 重启Deployment
 func (s *K8sWorkloadServiceImpl) RestartDeployment(c *gin.Context, clusterId uint, namespaceName string, deploymentName string) {
 	// 获取集群信息
-	cluster, err := s.clusterDao.GetByID(clusterId)
+	cluster, err := s.clusterDao.WithContext(c).GetByID(clusterId)
 	if err != nil {
 		if err == gorm.ErrRecordNotFound {
 			result.Failed(c, http.StatusNotFound, "集群不存在")
@@ -2190,7 +2190,7 @@ func (s *K8sWorkloadServiceImpl) DeleteCronJob(c *gin.Context, clusterId uint, n
 // GetPodMetrics 获取Pod监控指标
 func (s *K8sWorkloadServiceImpl) GetPodMetrics(c *gin.Context, clusterId uint, namespaceName string, podName string) {
 	// 获取集群信息
-	cluster, err := s.clusterDao.GetByID(clusterId)
+	cluster, err := s.clusterDao.WithContext(c).GetByID(clusterId)
 	if err != nil {
 		if err == gorm.ErrRecordNotFound {
 			result.Failed(c, http.StatusNotFound, "集群不存在")
@@ -2237,7 +2237,7 @@ func (s *K8sWorkloadServiceImpl) GetPodMetrics(c *gin.Context, clusterId uint, n
 // GetNodeMetrics 获取节点监控指标
 func (s *K8sWorkloadServiceImpl) GetNodeMetrics(c *gin.Context, clusterId uint, nodeName string) {
 	// 获取集群信息
-	cluster, err := s.clusterDao.GetByID(clusterId)
+	cluster, err := s.clusterDao.WithContext(c).GetByID(clusterId)
 	if err != nil {
 		if err == gorm.ErrRecordNotFound {
 			result.Failed(c, http.StatusNotFound, "集群不存在")
@@ -2292,7 +2292,7 @@ func (s *K8sWorkloadServiceImpl) GetNodeMetrics(c *gin.Context, clusterId uint,
 // GetNamespaceMetrics 获取命名空间监控指标
 func (s *K8sWorkloadServiceImpl) GetNamespaceMetrics(c *gin.Context, clusterId uint, namespaceName string) {
 	// 获取集群信息
-	cluster, err := s.clusterDao.GetByID(clusterId)
+	cluster, err := s.clusterDao.WithContext(c).GetByID(clusterId)
 	if err != nil {
 		if err == gorm.ErrRecordNotFound {
 			result.Failed(c, http.StatusNotFound, "集群不存在")
@@ -2684,7 +2684,7 @@ func (s *K8sWorkloadServiceImpl) convertToNamespaceMetricsInfo(namespace *corev1
 // CreatePodFromYAML 通过YAML创建Pod
 func (s *K8sWorkloadServiceImpl) CreatePodFromYAML(c *gin.Context, clusterId uint, namespaceName string, req *model.CreatePodFromYAMLRequest) {
 	// 获取集群信息
-	cluster, err := s.clusterDao.GetByID(clusterId)
+	cluster, err := s.clusterDao.WithContext(c).GetByID(clusterId)
 	if err != nil {
 		if err == gorm.ErrRecordNotFound {
 			result.Failed(c, http.StatusNotFound, "集群不存在")
@@ -3228,7 +3228,7 @@ func (s *K8sWorkloadServiceImpl) parseYAMLToKubernetesObject(yamlContent string)
 // GetWorkloadPods 获取工作负载下的Pod列表
 func (s *K8sWorkloadServiceImpl) GetWorkloadPods(c *gin.Context, clusterId uint, namespaceName string, workloadType string, workloadName string) {
 	// 获取集群信息
-	cluster, err := s.clusterDao.GetByID(clusterId)
+	cluster, err := s.clusterDao.WithContext(c).GetByID(clusterId)
 	if err != nil {
 		if err == gorm.ErrRecordNotFound {
 			result.Failed(c, http.StatusNotFound, "集群不存在")
@@ -3329,7 +3329,7 @@ func (s *K8sWorkloadServiceImpl) GetWorkloadPods(c *gin.Context, clusterId uint,
 
 // GetDeploymentHistory 获取Deployment版本历史
 func (s *K8sWorkloadServiceImpl) GetDeploymentHistory(c *gin.Context, clusterId uint, namespaceName string, deploymentName string) {
-	cluster, err := s.clusterDao.GetByID(clusterId)
+	cluster, err := s.clusterDao.WithContext(c).GetByID(clusterId)
 	if err != nil {
 		result.Failed(c, http.StatusNotFound, "集群不存在")
 		return
@@ -3418,7 +3418,7 @@ func (s *K8sWorkloadServiceImpl) GetDeploymentHistory(c *gin.Context, clusterId
 
 // GetDeploymentRevision 获取Deployment指定版本详情
 func (s *K8sWorkloadServiceImpl) GetDeploymentRevision(c *gin.Context, clusterId uint, namespaceName string, deploymentName string, revision int64) {
-	cluster, err := s.clusterDao.GetByID(clusterId)
+	cluster, err := s.clusterDao.WithContext(c).GetByID(clusterId)
 	if err != nil {
 		result.Failed(c, http.StatusNotFound, "集群不存在")
 		return
@@ -3568,7 +3568,7 @@ func (s *K8sWorkloadServiceImpl) GetDeploymentRevision(c *gin.Context, clusterId
 
 // RollbackDeployment 回滚Deployment到指定版本
 func (s *K8sWorkloadServiceImpl) RollbackDeployment(c *gin.Context, clusterId uint, namespaceName string, deploymentName string, req *model.RollbackDeploymentRequest) {
-	cluster, err := s.clusterDao.GetByID(clusterId)
+	cluster, err := s.clusterDao.WithContext(c).GetByID(clusterId)
 	if err != nil {
 		result.Failed(c, http.StatusNotFound, "集群不存在")
 		return
@@ -3673,7 +3673,7 @@ func (s *K8sWorkloadServiceImpl) RollbackDeployment(c *gin.Context, clusterId ui
 
 // PauseDeployment 暂停Deployment滚动更新
 func (s *K8sWorkloadServiceImpl) PauseDeployment(c *gin.Context, clusterId uint, namespaceName string, deploymentName string) {
-	cluster, err := s.clusterDao.GetByID(clusterId)
+	cluster, err := s.clusterDao.WithContext(c).GetByID(clusterId)
 	if err != nil {
 		result.Failed(c, http.StatusNotFound, "集群不存在")
 		return
@@ -3726,7 +3726,7 @@ func (s *K8sWorkloadServiceImpl) PauseDeployment(c *gin.Context, clusterId uint,
 
 // ResumeDeployment 恢复Deployment滚动更新
 func (s *K8sWorkloadServiceImpl) ResumeDeployment(c *gin.Context, clusterId uint, namespaceName string, deploymentName string) {
-	cluster, err := s.clusterDao.GetByID(clusterId)
+	cluster, err := s.clusterDao.WithContext(c).GetByID(clusterId)
 	if err != nil {
 		result.Failed(c, http.StatusNotFound, "集群不存在")
 		return
@@ -3779,7 +3779,7 @@ func (s *K8sWorkloadServiceImpl) ResumeDeployment(c *gin.Context, clusterId uint
 
 // GetDeploymentRolloutStatus 获取Deployment滚动发布状态
 func (s *K8sWorkloadServiceImpl) GetDeploymentRolloutStatus(c *gin.Context, clusterId uint, namespaceName string, deploymentName string) {
-	cluster, err := s.clusterDao.GetByID(clusterId)
+	cluster, err := s.clusterDao.WithContext(c).GetByID(clusterId)
 	if err != nil {
 		result.Failed(c, http.StatusNotFound, "集群不存在")
 		return
@@ -4157,7 +4157,7 @@ func (s *K8sWorkloadServiceImpl) compareVolumes(volumes1, volumes2 []corev1.Volu
 // GetWorkloadYaml 获取工作负载的YAML配置
 func (s *K8sWorkloadServiceImpl) GetWorkloadYaml(c *gin.Context, clusterId uint, namespaceName string, workloadType string, workloadName string) {
 	// 获取集群信息
-	cluster, err := s.clusterDao.GetByID(clusterId)
+	cluster, err := s.clusterDao.WithContext(c).GetByID(clusterId)
 	if err != nil {
 		result.Failed(c, http.StatusNotFound, "集群不存在")
 		return
@@ -4326,7 +4326,7 @@ func (s *K8sWorkloadServiceImpl) GetWorkloadYaml(c *gin.Context, clusterId uint,
 // UpdateWorkloadYaml 通用工作负载YAML更新
 func (s *K8sWorkloadServiceImpl) UpdateWorkloadYaml(c *gin.Context, clusterId uint, namespaceName string, req *model.UpdateWorkloadYAMLRequest) {
 	// 获取集群信息
-	cluster, err := s.clusterDao.GetByID(clusterId)
+	cluster, err := s.clusterDao.WithContext(c).GetByID(clusterId)
 	if err != nil {
 		result.Failed(c, http.StatusNotFound, "集群不存在")
 		return
@@ -11,13 +11,16 @@ import (
 	"strings"
 	"time"
 
+	cmdbdao "dodevops-api/api/cmdb/dao"
 	cmdbmodel "dodevops-api/api/cmdb/model"
+	configcenterdao "dodevops-api/api/configcenter/dao"
 	configcentermodel "dodevops-api/api/configcenter/model"
 	"dodevops-api/api/k8s/dao"
 	"dodevops-api/api/k8s/model"
 	taskmodel "dodevops-api/api/task/model"
 	taskservice "dodevops-api/api/task/service"
 	"dodevops-api/common/result"
+	"dodevops-api/pkg/datascope"
 
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
@@ -36,6 +39,7 @@ type IKubeClusterService interface {
 	GetClusterStatus(c *gin.Context, id uint)
 	SyncCluster(c *gin.Context, id uint)
 	GetClusterDetail(c *gin.Context, id uint)
+	CheckClusterScope(c *gin.Context, id uint) bool
 }
 
 // KubeClusterServiceImpl K8s集群服务实现
@@ -64,6 +68,15 @@ func (s *KubeClusterServiceImpl) CreateCluster(c *gin.Context, req *model.Create
 		return
 	}
 
+	// 未指定归属部门时归属当前用户所在部门
+	if req.DeptId == 0 {
+		req.DeptId = datascope.CurrentDept(c)
+	}
+	if !datascope.AllowDept(c, req.DeptId) {
+		result.Failed(c, http.StatusForbidden, "没有该部门的数据权限")
+		return
+	}
+
 	// 根据集群类型处理不同逻辑
 	// 默认为自建集群
 	if req.ClusterType == 0 {
@@ -80,9 +93,22 @@ func (s *KubeClusterServiceImpl) CreateCluster(c *gin.Context, req *model.Create
 	}
 }
 
+// CheckClusterScope 校验当前用户是否有集群的数据权限，无权限时直接返回错误响应
+func (s *KubeClusterServiceImpl) CheckClusterScope(c *gin.Context, id uint) bool {
+	if _, err := s.dao.WithContext(c).GetByID(id); err != nil {
+		if err == gorm.ErrRecordNotFound {
+			result.Failed(c, http.StatusNotFound, "集群不存在")
+			return false
+		}
+		result.Failed(c, http.StatusInternalServerError, fmt.Sprintf("获取集群失败: %v", err))
+		return false
+	}
+	return true
+}
+
 // GetCluster 获取集群详情
 func (s *KubeClusterServiceImpl) GetCluster(c *gin.Context, id uint) {
-	cluster, err := s.dao.GetByID(id)
+	cluster, err := s.dao.WithContext(c).GetByID(id)
 	if err != nil {
 		if err == gorm.ErrRecordNotFound {
 			result.Failed(c, http.StatusNotFound, "集群不存在")
@@ -113,7 +139,7 @@ func (s *KubeClusterServiceImpl) GetCluster(c *gin.Context, id uint) {
 
 // GetClusterList 获取集群列表
 func (s *KubeClusterServiceImpl) GetClusterList(c *gin.Context, page, size int) {
-	clusters, total, err := s.dao.List(page, size)
+	clusters, total, err := s.dao.WithContext(c).List(page, size)
 	if err != nil {
 		result.Failed(c, http.StatusInternalServerError, fmt.Sprintf("获取集群列表失败: %v", err))
 		return
@@ -130,7 +156,7 @@ func (s *KubeClusterServiceImpl) GetClusterList(c *gin.Context, page, size int)
 // UpdateCluster 更新集群信息
 func (s *KubeClusterServiceImpl) UpdateCluster(c *gin.Context, id uint, req *model.UpdateKubeClusterRequest) {
 	// 检查集群是否存在
-	_, err := s.dao.GetByID(id)
+	_, err := s.dao.WithContext(c).GetByID(id)
 	if err != nil {
 		if err == gorm.ErrRecordNotFound {
 			result.Failed(c, http.StatusNotFound, "集群不存在")
@@ -163,6 +189,13 @@ func (s *KubeClusterServiceImpl) UpdateCluster(c *gin.Context, id uint, req *mod
 	if req.Version != "" {
 		updates["version"] = req.Version
 	}
+	if req.DeptId != 0 {
+		if !datascope.AllowDept(c, req.DeptId) {
+			result.Failed(c, http.StatusForbidden, "没有该部门的数据权限")
+			return
+		}
+		updates["dept_id"] = req.DeptId
+	}
 
 	// 如果有更新字段，执行更新
 	if len(updates) > 0 {
@@ -186,7 +219,7 @@ func (s *KubeClusterServiceImpl) UpdateCluster(c *gin.Context, id uint, req *mod
 // DeleteCluster 删除集群
 func (s *KubeClusterServiceImpl) DeleteCluster(c *gin.Context, id uint) {
 	// 检查集群是否存在
-	_, err := s.dao.GetByID(id)
+	_, err := s.dao.WithContext(c).GetByID(id)
 	if err != nil {
 		if err == gorm.ErrRecordNotFound {
 			result.Failed(c, http.StatusNotFound, "集群不存在")
@@ -202,12 +235,16 @@ func (s *KubeClusterServiceImpl) DeleteCluster(c *gin.Context, id uint) {
 		return
 	}
 
+	// 删除集群在CMDB中的配置项关系
+	relationDao := cmdbdao.NewCmdbRelationDao()
+	relationDao.DeleteRelationsByCI(cmdbmodel.CIKindCluster, id)
+
 	result.Success(c, "删除成功")
 }
 
 // GetClusterStatus 获取集群状态
 func (s *KubeClusterServiceImpl) GetClusterStatus(c *gin.Context, id uint) {
-	cluster, err := s.dao.GetByID(id)
+	cluster, err := s.dao.WithContext(c).GetByID(id)
 	if err != nil {
 		if err == gorm.ErrRecordNotFound {
 			result.Failed(c, http.StatusNotFound, "集群不存在")
@@ -248,7 +285,7 @@ func (s *KubeClusterServiceImpl) createSelfBuiltCluster(c *gin.Context, req *mod
 	}
 
 	// 验证主机ID并获取主机信息
-	_, err := s.getHostInfos(*req.NodeConfig)
+	_, err := s.getHostInfos(c, *req.NodeConfig)
 	if err != nil {
 		result.Failed(c, http.StatusBadRequest, fmt.Sprintf("获取主机信息失败: %v", err))
 		return
@@ -261,6 +298,7 @@ func (s *KubeClusterServiceImpl) createSelfBuiltCluster(c *gin.Context, req *mod
 		Status:      model.ClusterStatusCreating,
 		Description: req.Description,
 		ClusterType: model.ClusterTypeSelfBuilt,
+		DeptId:      req.DeptId,
 	}
 
 	if err := s.dao.Create(cluster); err != nil {
@@ -352,6 +390,7 @@ func (s *KubeClusterServiceImpl) importCluster(c *gin.Context, req *model.Create
 		Description: req.Description,
 		ClusterType: model.ClusterTypeImported,
 		Credential:  req.Kubeconfig,
+		DeptId:      req.DeptId,
 	}
 
 	if err := s.dao.Create(cluster); err != nil {
@@ -374,7 +413,7 @@ func (s *KubeClusterServiceImpl) importCluster(c *gin.Context, req *model.Create
 }
 
 // getHostInfos 获取主机信息
-func (s *KubeClusterServiceImpl) getHostInfos(nodeConfig model.NodeConfig) (map[uint]cmdbmodel.CmdbHost, error) {
+func (s *KubeClusterServiceImpl) getHostInfos(ctx context.Context, nodeConfig model.NodeConfig) (map[uint]cmdbmodel.CmdbHost, error) {
 	// 收集所有主机ID
 	allHostIDs := make(map[uint]bool)
 	for _, id := range nodeConfig.MasterHostIDs {
@@ -395,7 +434,7 @@ func (s *KubeClusterServiceImpl) getHostInfos(nodeConfig model.NodeConfig) (map[
 
 	// 查询主机信息
 	var hosts []cmdbmodel.CmdbHost
-	if err := s.dao.DB.Where("id IN ?", hostIDs).Find(&hosts).Error; err != nil {
+	if err := s.dao.WithContext(ctx).DB.Where("id IN ?", hostIDs).Find(&hosts).Error; err != nil {
 		return nil, fmt.Errorf("查询主机信息失败: %v", err)
 	}
 
@@ -583,7 +622,11 @@ func (s *KubeClusterServiceImpl) getK8sHostInfoForAsync(req *taskservice.CreateK
 	// 构建SSH凭据映射
 	sshKeyMap := make(map[uint]string)
 	for _, key := range sshKeys {
+		if err := key.ResolveSecrets(); err != nil {
+			return nil, err
+		}
 		sshKeyMap[key.ID] = key.Password
+		configcenterdao.MarkEcsAuthUsed(key.ID)
 	}
 
 	// 按角色分组
@@ -788,7 +831,7 @@ func (s *KubeClusterServiceImpl) generateK8sConfigAsync(projectDir string, req *
 // SyncCluster 同步集群信息
 func (s *KubeClusterServiceImpl) SyncCluster(c *gin.Context, id uint) {
 	// 获取集群信息
-	cluster, err := s.dao.GetByID(id)
+	cluster, err := s.dao.WithContext(c).GetByID(id)
 	if err != nil {
 		if err == gorm.ErrRecordNotFound {
 			result.Failed(c, http.StatusNotFound, "集群不存在")
@@ -1029,7 +1072,7 @@ func (s *KubeClusterServiceImpl) validateKubeconfig(kubeconfig string) error {
 // GetClusterDetail 获取集群详细信息
 func (s *KubeClusterServiceImpl) GetClusterDetail(c *gin.Context, id uint) {
 	// 获取基本集群信息
-	cluster, err := s.dao.GetByID(id)
+	cluster, err := s.dao.WithContext(c).GetByID(id)
 	if err != nil {
 		if err == gorm.ErrRecordNotFound {
 			result.Failed(c, http.StatusNotFound, "集群不存在")
@@ -24,7 +24,7 @@ func NewAgentController() *AgentController {
 
 // DeployAgent 部署agent到指定主机(支持单个或多个)
 // @Summary 部署agent到指定主机(支持单个或多个)
-// @Description 自动编译agent二进制文件，拷贝到目标主机并启动服务，单个主机传[hostId]，多个主机传[hostId1,hostId2,hostId3]
+// @Description 自动编译agent二进制文件，拷贝到目标主机并启动服务，单个主机传[hostId]，多个主机传[hostId1,hostId2,hostId3]，也可传dynamicGroupId按动态分组当前匹配的主机部署
 // @Tags 监控
 // @Accept json
 // @Produce json
@@ -38,6 +38,11 @@ func (c *AgentController) DeployAgent(ctx *gin.Context) {
 		result.Failed(ctx, http.StatusBadRequest, "参数错误："+err.Error())
 		return
 	}
+	// 指定动态分组时按分组当前匹配的主机执行
+	if err := service.ResolveAgentTargets(ctx, &dto); err != nil {
+		result.Failed(ctx, http.StatusBadRequest, err.Error())
+		return
+	}
 
 	// 如果只有一个主机ID，调用单个部署方法
 	if len(dto.HostIDs) == 1 {
@@ -55,7 +60,7 @@ func (c *AgentController) DeployAgent(ctx *gin.Context) {
 // @Tags 监控
 // @Accept json
 // @Produce json
-// @Param request body model.BatchDeployAgentDto true "卸载参数(只需hostIds字段)"
+// @Param request body model.BatchDeployAgentDto true "卸载参数(只需hostIds或dynamicGroupId字段)"
 // @Success 200 {object} result.Result
 // @Router /api/v1/monitor/agent/uninstall [delete]
 // @Security ApiKeyAuth
@@ -65,6 +70,11 @@ func (c *AgentController) UninstallAgent(ctx *gin.Context) {
 		result.Failed(ctx, http.StatusBadRequest, "参数错误："+err.Error())
 		return
 	}
+	// 指定动态分组时按分组当前匹配的主机执行
+	if err := service.ResolveAgentTargets(ctx, &dto); err != nil {
+		result.Failed(ctx, http.StatusBadRequest, err.Error())
+		return
+	}
 
 	// 如果只有一个主机ID，调用单个卸载方法
 	if len(dto.HostIDs) == 1 {
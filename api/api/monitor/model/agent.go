@@ -88,8 +88,9 @@ type CreateAgentDto struct {
 
 // BatchDeployAgentDto 批量部署Agent DTO
 type BatchDeployAgentDto struct {
-	HostIDs []uint `validate:"required" json:"hostIds"` // 主机ID列表
-	Version string `json:"version"`                     // 版本
+	HostIDs        []uint `json:"hostIds"`        // 主机ID列表
+	DynamicGroupID uint   `json:"dynamicGroupId"` // 动态分组ID，与主机ID列表合并，按分组当前匹配的主机部署
+	Version        string `json:"version"`        // 版本
 }
 
 // UpdateAgentDto 更新Agent DTO
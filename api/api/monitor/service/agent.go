@@ -14,6 +14,7 @@ import (
 
 	"dodevops-api/api/cmdb/dao"
 	"dodevops-api/api/cmdb/model"
+	configcenterdao "dodevops-api/api/configcenter/dao"
 	agentDao "dodevops-api/api/monitor/dao"
 	agentModel "dodevops-api/api/monitor/model"
 	"dodevops-api/common"
@@ -21,6 +22,7 @@ import (
 	"dodevops-api/common/config"
 	"dodevops-api/common/util"
 	"dodevops-api/pkg/redis"
+	"dodevops-api/pkg/secretstore"
 
 	"dodevops-api/common/result"
 
@@ -35,8 +37,8 @@ type EcsAuth struct {
 	Name       string     `gorm:"column:name;varchar(64);comment:'凭证名称';NOT NULL" json:"name"`
 	Type       int        `gorm:"column:type;comment:'认证类型:1->密码,2->密钥';NOT NULL" json:"type"`
 	Username   string     `gorm:"column:username;varchar(64);comment:'用户名(type=1时使用)'" json:"username"`
-	Password   string     `gorm:"column:password;varchar(256);comment:'密码(type=1时使用)'" json:"password"`
-	PublicKey  string     `gorm:"column:public_key;type:text;comment:'公钥(type=2时使用)'" json:"publicKey"`
+	Password   string     `gorm:"column:password;varchar(256);serializer:secret;comment:'密码(type=1时使用)'" json:"password"`
+	PublicKey  string     `gorm:"column:public_key;type:text;serializer:secret;comment:'公钥(type=2时使用)'" json:"publicKey"`
 	Port       int        `gorm:"column:port;comment:'端口号';default:22" json:"port"`
 	CreateTime util.HTime `gorm:"column:create_time;comment:'创建时间';NOT NULL" json:"createTime"`
 	Remark     string     `gorm:"column:remark;varchar(500);comment:'备注'" json:"remark"`
@@ -88,6 +90,36 @@ type AgentServiceInterface interface {
 	CheckOfflineAgents()                                                                  // 检查离线agent
 }
 
+// DynamicGroupHosts 按动态分组查询当前匹配的主机，由CMDB模块注册
+var DynamicGroupHosts func(ctx context.Context, groupId uint) ([]model.CmdbHost, error)
+
+// ResolveAgentTargets 将动态分组当前匹配的主机合并到主机ID列表
+func ResolveAgentTargets(ctx context.Context, dto *agentModel.BatchDeployAgentDto) error {
+	if dto.DynamicGroupID > 0 {
+		if DynamicGroupHosts == nil {
+			return fmt.Errorf("动态分组功能未启用")
+		}
+		hosts, err := DynamicGroupHosts(ctx, dto.DynamicGroupID)
+		if err != nil {
+			return err
+		}
+		seen := make(map[uint]bool, len(dto.HostIDs))
+		for _, id := range dto.HostIDs {
+			seen[id] = true
+		}
+		for _, host := range hosts {
+			if !seen[host.ID] {
+				seen[host.ID] = true
+				dto.HostIDs = append(dto.HostIDs, host.ID)
+			}
+		}
+	}
+	if len(dto.HostIDs) == 0 {
+		return fmt.Errorf("主机ID列表不能为空")
+	}
+	return nil
+}
+
 // AgentServiceImpl agent服务实现
 type AgentServiceImpl struct {
 	hostDao    dao.CmdbHostDao
@@ -523,6 +555,14 @@ func (s *AgentServiceImpl) getSSHKeyByID(id uint) (*EcsAuth, error) {
 	if err != nil {
 		return nil, fmt.Errorf("获取SSH密钥失败: %v", err)
 	}
+	// 读库时外部存储中的凭证只保留引用，连接前读取
+	if ecsAuth.Password, err = secretstore.Resolve(context.Background(), ecsAuth.Password); err != nil {
+		return nil, fmt.Errorf("获取SSH密钥失败: %v", err)
+	}
+	if ecsAuth.PublicKey, err = secretstore.Resolve(context.Background(), ecsAuth.PublicKey); err != nil {
+		return nil, fmt.Errorf("获取SSH密钥失败: %v", err)
+	}
+	configcenterdao.MarkEcsAuthUsed(ecsAuth.ID)
 	return &ecsAuth, nil
 }
 
@@ -2006,7 +2046,7 @@ func CopyFileViaSSH(config util.SSHConfig, localPath, remotePath string) error {
 	sshConfig := &ssh.ClientConfig{
 		User:            config.Username,
 		Auth:            []ssh.AuthMethod{},
-		HostKeyCallback: ssh.InsecureIgnoreHostKey(),
+		HostKeyCallback: util.HostKeyCallback(),
 		Timeout:         30 * time.Second,
 	}
 
@@ -2045,9 +2085,8 @@ func CopyFileViaSSH(config util.SSHConfig, localPath, remotePath string) error {
 		return fmt.Errorf("不支持的SSH认证类型: %d", config.Type)
 	}
 
-	// 创建SSH连接
-	addr := fmt.Sprintf("%s:%d", config.IP, config.Port)
-	client, err := ssh.Dial("tcp", addr, sshConfig)
+	// 创建SSH连接，配置了跳板机时经过跳板机连接
+	client, err := util.DialSSH(&config, sshConfig)
 	if err != nil {
 		return fmt.Errorf("创建SSH连接失败: %v", err)
 	}
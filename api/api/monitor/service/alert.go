@@ -38,6 +38,7 @@ type AlertServiceInterface interface {
 	GetAlertRouterById(id int) (*alertModel.AlertRouter, error)
 	GetAlertRecords(query *alertModel.RecordQuery) ([]*alertModel.AlertRecord, int64, error)
 	CleanAlertRecords() error
+	SendEmail(EmailBody, Emails, EmailTitle, logsign string) string
 }
 
 type alertService struct {
@@ -8,6 +8,8 @@ import (
 	"log"
 	"net/http"
 	"net/url"
+	"regexp"
+	"strconv"
 	"strings"
 	"time"
 
@@ -160,20 +162,7 @@ func (s *monitorAlertRuleService) yamlSyncRulesToGroup(group *model.MonitorAlert
 		pr.For = r.ForDuration
 
 		// Constraints处理：将非空的约束条件动态注入到 Expr 中
-		if r.Constraints != "" && r.Constraints != "{}" {
-			var constraintsMap map[string]string
-			if err := json.Unmarshal([]byte(r.Constraints), &constraintsMap); err == nil {
-				validConstraints := make(map[string]string)
-				for k, v := range constraintsMap {
-					if v != "" { // 过滤掉空值
-						validConstraints[k] = v
-					}
-				}
-				if len(validConstraints) > 0 {
-					pr.Expr = modifyPromQL(pr.Expr, validConstraints)
-				}
-			}
-		}
+		pr.Expr = applyConstraints(pr.Expr, r.Constraints)
 
 		// Labels合并与处理（Rule的Labels优先级大于Group的Labels）
 		ruleL := make(map[string]string)
@@ -385,7 +374,56 @@ func walkYAML(node *yaml.Node, newLabels map[string]string) {
 	}
 }
 
-// modifyPromQL 使用 AST 引擎进行纯粹的新增与覆盖
+// DynamicGroupConstraint 约束条件中表示动态分组的键，值为动态分组ID
+const DynamicGroupConstraint = "dynamicGroup"
+
+// applyConstraints 将非空的约束条件注入到 Expr 中
+func applyConstraints(query string, constraintsJSON string) string {
+	if constraintsJSON == "" || constraintsJSON == "{}" {
+		return query
+	}
+	var constraintsMap map[string]string
+	if err := json.Unmarshal([]byte(constraintsJSON), &constraintsMap); err != nil {
+		return query
+	}
+	validConstraints := make(map[string]string)
+	for k, v := range constraintsMap {
+		if v != "" { // 过滤掉空值
+			validConstraints[k] = v
+		}
+	}
+	if len(validConstraints) == 0 {
+		return query
+	}
+	return modifyPromQL(query, validConstraints)
+}
+
+// dynamicGroupMatcher 将动态分组当前匹配的主机展开为 instance 的正则匹配，
+// 分组不存在或没有主机时返回不匹配任何实例的条件，避免约束失效后对全部主机告警
+func dynamicGroupMatcher(value string) *labels.Matcher {
+	var names []string
+	groupId, err := strconv.ParseUint(value, 10, 64)
+	if err == nil && DynamicGroupHosts != nil {
+		hosts, resolveErr := DynamicGroupHosts(context.Background(), uint(groupId))
+		if resolveErr != nil {
+			log.Printf("警告: 展开动态分组约束失败: %v\n", resolveErr)
+		}
+		for _, host := range hosts {
+			if host.Name != "" {
+				names = append(names, regexp.QuoteMeta(host.Name))
+			}
+		}
+	} else {
+		log.Printf("警告: 动态分组约束 %q 无效\n", value)
+	}
+	if len(names) == 0 {
+		names = []string{"__no_host__"}
+	}
+	matcher, _ := labels.NewMatcher(labels.MatchRegexp, "instance", strings.Join(names, "|"))
+	return matcher
+}
+
+// modifyPromQL 使用 AST 引擎进行纯粹的新增与覆盖，动态分组约束与已有的 instance 条件同时生效
 func modifyPromQL(query string, newLabels map[string]string) string {
 	expr, err := parser.NewParser(parser.Options{}).ParseExpr(query)
 	if err != nil {
@@ -393,6 +431,11 @@ func modifyPromQL(query string, newLabels map[string]string) string {
 		return query
 	}
 
+	var groupMatcher *labels.Matcher
+	if groupId, ok := newLabels[DynamicGroupConstraint]; ok {
+		groupMatcher = dynamicGroupMatcher(groupId)
+	}
+
 	parser.Inspect(expr, func(node parser.Node, path []parser.Node) error {
 		if vs, ok := node.(*parser.VectorSelector); ok {
 			var newMatchers []*labels.Matcher
@@ -409,11 +452,17 @@ func modifyPromQL(query string, newLabels map[string]string) string {
 			}
 
 			for k, v := range newLabels {
+				if k == DynamicGroupConstraint {
+					continue
+				}
 				newMatcher, err := labels.NewMatcher(labels.MatchEqual, k, v)
 				if err == nil {
 					newMatchers = append(newMatchers, newMatcher)
 				}
 			}
+			if groupMatcher != nil {
+				newMatchers = append(newMatchers, groupMatcher)
+			}
 
 			vs.LabelMatchers = newMatchers
 		}
@@ -498,21 +547,8 @@ func (s *monitorAlertRuleService) evaluateRulesOnce() {
 					continue
 				}
 
-				evalExpr := r.Expr
-				if r.Constraints != "" && r.Constraints != "{}" {
-					var constraintsMap map[string]string
-					if err := json.Unmarshal([]byte(r.Constraints), &constraintsMap); err == nil {
-						validConstraints := make(map[string]string)
-						for k, v := range constraintsMap {
-							if v != "" {
-								validConstraints[k] = v
-							}
-						}
-						if len(validConstraints) > 0 {
-							evalExpr = modifyPromQL(evalExpr, validConstraints)
-						}
-					}
-				}
+				// 每轮评估重新展开约束，动态分组的主机变化在下一轮生效
+				evalExpr := applyConstraints(r.Expr, r.Constraints)
 
 				// 3. Build query URL directly to data source
 				u, _ := url.Parse(fmt.Sprintf("%s/api/v1/query", strings.TrimRight(ds.ApiUrl, "/")))
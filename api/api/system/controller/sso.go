@@ -0,0 +1,57 @@
+// 单点登录 控制层
+// author xiaoRui
+
+package controller
+
+import (
+	"dodevops-api/api/system/model"
+	"dodevops-api/api/system/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// @Tags System系统管理
+// @Summary 单点登录提供者列表
+// @Produce json
+// @Description 获取已配置的 OIDC / OAuth2 单点登录提供者，用于登录页展示
+// @Success 200 {object} result.Result{data=[]service.SsoProviderVo}
+// @router /api/v1/sso/providers [get]
+func GetSsoProviderList(c *gin.Context) {
+	service.SsoService().GetProviderList(c)
+}
+
+// @Tags System系统管理
+// @Summary 发起单点登录
+// @Description 生成 state 和 PKCE 参数并跳转到提供者授权页
+// @Param provider path string true "提供者标识"
+// @Success 302
+// @router /api/v1/sso/{provider}/login [get]
+func SsoLogin(c *gin.Context) {
+	service.SsoService().Login(c, c.Param("provider"))
+}
+
+// @Tags System系统管理
+// @Summary 单点登录回调
+// @Produce json
+// @Description 使用授权码换取用户信息，自动创建/更新用户后签发本地token，需要二次验证时返回 mfaTicket；配置了 frontendUrl 时跳转到前端并携带一次性登录码 code 参数
+// @Param provider path string true "提供者标识"
+// @Param code query string true "授权码"
+// @Param state query string true "state"
+// @Success 200 {object} result.Result
+// @router /api/v1/sso/{provider}/callback [get]
+func SsoCallback(c *gin.Context) {
+	service.SsoService().Callback(c, c.Param("provider"))
+}
+
+// @Tags System系统管理
+// @Summary 单点登录换取登录结果
+// @Produce json
+// @Description 使用回调跳转前端时携带的一次性登录码换取 token，登录码只能使用一次
+// @Param data body model.SsoExchangeDto true "data"
+// @Success 200 {object} result.Result
+// @router /api/v1/sso/exchange [post]
+func SsoExchange(c *gin.Context) {
+	var dto model.SsoExchangeDto
+	_ = c.BindJSON(&dto)
+	service.SsoService().Exchange(c, dto)
+}
@@ -166,3 +166,18 @@ func UpdatePersonalPassword(c *gin.Context) {
 	_ = c.BindJSON(&dto)
 	service.SysAdminService().UpdatePersonalPassword(c, dto)
 }
+
+// @Tags System系统管理
+// 解除登录锁定
+// @Summary 解除登录锁定接口
+// @Produce json
+// @Description 清除用户的登录失败次数并解除锁定
+// @Param data body model.UnlockSysAdminDto true "data"
+// @Success 200 {object} result.Result
+// @router /api/v1/admin/unlock [put]
+// @Security ApiKeyAuth
+func UnlockSysAdmin(c *gin.Context) {
+	var dto model.UnlockSysAdminDto
+	_ = c.BindJSON(&dto)
+	service.SysAdminService().UnlockSysAdmin(c, dto)
+}
@@ -0,0 +1,101 @@
+// 二次验证 控制层
+// author xiaoRui
+
+package controller
+
+import (
+	"dodevops-api/api/system/model"
+	"dodevops-api/api/system/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// @Tags System系统管理
+// @Summary 登录二次验证
+// @Produce json
+// @Description 登录接口返回 mfaRequired 时，使用返回的 mfaTicket 和认证器验证码（或恢复码）完成登录；首次绑定时同时返回恢复码
+// @Param data body model.MfaLoginDto true "data"
+// @Success 200 {object} result.Result
+// @router /api/v1/login/mfa [post]
+func LoginMfa(c *gin.Context) {
+	var dto model.MfaLoginDto
+	_ = c.BindJSON(&dto)
+	service.SysAdminMfaService().LoginMfa(c, dto)
+}
+
+// @Tags System系统管理
+// @Summary 登录时绑定认证器
+// @Produce json
+// @Description 所属角色强制启用二次验证但尚未绑定时（mfaEnrolled 为 false），使用 mfaTicket 获取绑定二维码
+// @Param data body model.MfaTicketDto true "data"
+// @Success 200 {object} result.Result{data=model.SysAdminMfaEnrollVo}
+// @router /api/v1/login/mfa/enroll [post]
+func EnrollMfaByTicket(c *gin.Context) {
+	var dto model.MfaTicketDto
+	_ = c.BindJSON(&dto)
+	service.SysAdminMfaService().EnrollMfaByTicket(c, dto)
+}
+
+// @Tags System系统管理
+// @Summary 二次验证状态
+// @Produce json
+// @Description 查询当前用户是否已启用二次验证、是否被强制启用以及剩余恢复码数量
+// @Success 200 {object} result.Result{data=model.SysAdminMfaStatusVo}
+// @router /api/v1/admin/mfa/status [get]
+// @Security ApiKeyAuth
+func GetSysAdminMfaStatus(c *gin.Context) {
+	service.SysAdminMfaService().GetSysAdminMfaStatus(c)
+}
+
+// @Tags System系统管理
+// @Summary 绑定认证器
+// @Produce json
+// @Description 生成新的 TOTP 密钥和绑定二维码，调用启用接口校验验证码后生效
+// @Success 200 {object} result.Result{data=model.SysAdminMfaEnrollVo}
+// @router /api/v1/admin/mfa/enroll [post]
+// @Security ApiKeyAuth
+func EnrollSysAdminMfa(c *gin.Context) {
+	service.SysAdminMfaService().EnrollSysAdminMfa(c)
+}
+
+// @Tags System系统管理
+// @Summary 启用二次验证
+// @Produce json
+// @Description 校验认证器验证码并启用二次验证，返回恢复码，恢复码仅显示一次
+// @Param data body model.SysAdminMfaCodeDto true "data"
+// @Success 200 {object} result.Result
+// @router /api/v1/admin/mfa/activate [post]
+// @Security ApiKeyAuth
+func ActivateSysAdminMfa(c *gin.Context) {
+	var dto model.SysAdminMfaCodeDto
+	_ = c.BindJSON(&dto)
+	service.SysAdminMfaService().ActivateSysAdminMfa(c, dto)
+}
+
+// @Tags System系统管理
+// @Summary 停用二次验证
+// @Produce json
+// @Description 校验验证码或恢复码后停用二次验证，所属角色强制启用时不允许停用
+// @Param data body model.SysAdminMfaCodeDto true "data"
+// @Success 200 {object} result.Result
+// @router /api/v1/admin/mfa/disable [post]
+// @Security ApiKeyAuth
+func DisableSysAdminMfa(c *gin.Context) {
+	var dto model.SysAdminMfaCodeDto
+	_ = c.BindJSON(&dto)
+	service.SysAdminMfaService().DisableSysAdminMfa(c, dto)
+}
+
+// @Tags System系统管理
+// @Summary 重新生成恢复码
+// @Produce json
+// @Description 校验认证器验证码后重新生成恢复码，原有恢复码全部失效
+// @Param data body model.SysAdminMfaCodeDto true "data"
+// @Success 200 {object} result.Result
+// @router /api/v1/admin/mfa/recoveryCodes [post]
+// @Security ApiKeyAuth
+func RegenerateRecoveryCodes(c *gin.Context) {
+	var dto model.SysAdminMfaCodeDto
+	_ = c.BindJSON(&dto)
+	service.SysAdminMfaService().RegenerateRecoveryCodes(c, dto)
+}
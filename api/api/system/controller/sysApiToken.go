@@ -0,0 +1,50 @@
+// 个人访问令牌 控制层
+// author xiaoRui
+
+package controller
+
+import (
+	"dodevops-api/api/system/model"
+	"dodevops-api/api/system/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// @Tags System系统管理
+// @Summary 新增个人访问令牌
+// @Produce json
+// @Description 为当前用户创建访问令牌，授权范围必须是当前用户拥有的权限值，明文令牌仅在创建时返回一次；调用接口时使用 Authorization: Bearer {token}
+// @Param data body model.AddSysApiTokenDto true "data"
+// @Success 200 {object} result.Result{data=model.SysApiTokenCreatedVo}
+// @router /api/v1/admin/token/add [post]
+// @Security ApiKeyAuth
+func CreateSysApiToken(c *gin.Context) {
+	var dto model.AddSysApiTokenDto
+	_ = c.BindJSON(&dto)
+	service.SysApiTokenService().CreateSysApiToken(c, dto)
+}
+
+// @Tags System系统管理
+// @Summary 个人访问令牌列表
+// @Produce json
+// @Description 查询当前用户的访问令牌列表，包含授权范围、过期时间和最后使用时间
+// @Success 200 {object} result.Result{data=[]model.SysApiTokenVo}
+// @router /api/v1/admin/token/list [get]
+// @Security ApiKeyAuth
+func GetSysApiTokenList(c *gin.Context) {
+	service.SysApiTokenService().GetSysApiTokenList(c)
+}
+
+// @Tags System系统管理
+// @Summary 吊销个人访问令牌
+// @Produce json
+// @Description 删除当前用户的访问令牌，删除后立即失效
+// @Param data body model.SysApiTokenIdDto true "data"
+// @Success 200 {object} result.Result
+// @router /api/v1/admin/token/delete [delete]
+// @Security ApiKeyAuth
+func DeleteSysApiToken(c *gin.Context) {
+	var dto model.SysApiTokenIdDto
+	_ = c.BindJSON(&dto)
+	service.SysApiTokenService().DeleteSysApiToken(c, dto)
+}
@@ -0,0 +1,163 @@
+// 审批流程 控制层
+// author xiaoRui
+
+package controller
+
+import (
+	"dodevops-api/api/system/model"
+	"dodevops-api/api/system/service"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// @Tags System系统管理
+// @Summary 审批策略列表
+// @Produce json
+// @Description 查询全部审批策略
+// @Success 200 {object} result.Result{data=[]model.SysApprovalPolicy}
+// @router /api/v1/approval/policy/list [get]
+// @Security ApiKeyAuth
+func GetSysApprovalPolicyList(c *gin.Context) {
+	service.SysApprovalService().GetSysApprovalPolicyList(c)
+}
+
+// @Tags System系统管理
+// @Summary 新增审批策略
+// @Produce json
+// @Description 新增审批策略，命中请求方式和路由模板（且满足审批条件）的请求需要审批人审批通过后才会执行
+// @Param data body model.SysApprovalPolicyDto true "data"
+// @Success 200 {object} result.Result{data=model.SysApprovalPolicy}
+// @router /api/v1/approval/policy/add [post]
+// @Security ApiKeyAuth
+func CreateSysApprovalPolicy(c *gin.Context) {
+	var dto model.SysApprovalPolicyDto
+	_ = c.BindJSON(&dto)
+	service.SysApprovalService().CreateSysApprovalPolicy(c, dto)
+}
+
+// @Tags System系统管理
+// @Summary 修改审批策略
+// @Produce json
+// @Description 修改审批策略，已提交的审批单不受影响
+// @Param data body model.SysApprovalPolicyDto true "data"
+// @Success 200 {object} result.Result{data=model.SysApprovalPolicy}
+// @router /api/v1/approval/policy/update [put]
+// @Security ApiKeyAuth
+func UpdateSysApprovalPolicy(c *gin.Context) {
+	var dto model.SysApprovalPolicyDto
+	_ = c.BindJSON(&dto)
+	service.SysApprovalService().UpdateSysApprovalPolicy(c, dto)
+}
+
+// @Tags System系统管理
+// @Summary 删除审批策略
+// @Produce json
+// @Description 删除审批策略
+// @Param data body model.SysApprovalPolicyIdDto true "data"
+// @Success 200 {object} result.Result
+// @router /api/v1/approval/policy/delete [delete]
+// @Security ApiKeyAuth
+func DeleteSysApprovalPolicy(c *gin.Context) {
+	var dto model.SysApprovalPolicyIdDto
+	_ = c.BindJSON(&dto)
+	service.SysApprovalService().DeleteSysApprovalPolicy(c, dto)
+}
+
+// @Tags System系统管理
+// @Summary 审批条件列表
+// @Produce json
+// @Description 查询可在审批策略中使用的审批条件，如 ddl（仅 DDL 语句）、prod_deployment（仅生产环境发布）
+// @Success 200 {object} result.Result{data=[]string}
+// @router /api/v1/approval/conditions [get]
+// @Security ApiKeyAuth
+func GetSysApprovalConditionList(c *gin.Context) {
+	service.SysApprovalService().GetSysApprovalConditionList(c)
+}
+
+// @Tags System系统管理
+// @Summary 审批单列表
+// @Produce json
+// @Description 分页查询审批单，scope：mine->我提交的(默认),todo->待我审批的,all->全部
+// @Param scope query string false "查询范围"
+// @Param status query int false "状态：1->待审批,2->已通过,3->已拒绝,4->已撤销,5->执行成功,6->执行失败"
+// @Param pageSize query int false "每页数"
+// @Param pageNum query int false "分页数"
+// @Success 200 {object} result.Result
+// @router /api/v1/approval/ticket/list [get]
+// @Security ApiKeyAuth
+func GetSysApprovalTicketList(c *gin.Context) {
+	Status, _ := strconv.Atoi(c.Query("status"))
+	PageSize, _ := strconv.Atoi(c.Query("pageSize"))
+	PageNum, _ := strconv.Atoi(c.Query("pageNum"))
+	service.SysApprovalService().GetSysApprovalTicketList(c, c.Query("scope"), Status, PageSize, PageNum)
+}
+
+// @Tags System系统管理
+// @Summary 审批单详情
+// @Produce json
+// @Description 查询审批单详情和审批记录
+// @Param id query int true "审批单id"
+// @Success 200 {object} result.Result{data=model.SysApprovalTicketVo}
+// @router /api/v1/approval/ticket/info [get]
+// @Security ApiKeyAuth
+func GetSysApprovalTicketInfo(c *gin.Context) {
+	Id, _ := strconv.Atoi(c.Query("id"))
+	service.SysApprovalService().GetSysApprovalTicketInfo(c, uint(Id))
+}
+
+// @Tags System系统管理
+// @Summary 同意审批单
+// @Produce json
+// @Description 同意审批单，同意人数达到要求后以申请人身份执行原请求
+// @Param data body model.SysApprovalTicketActionDto true "data"
+// @Success 200 {object} result.Result{data=model.SysApprovalTicket}
+// @router /api/v1/approval/ticket/approve [post]
+// @Security ApiKeyAuth
+func ApproveSysApprovalTicket(c *gin.Context) {
+	var dto model.SysApprovalTicketActionDto
+	_ = c.BindJSON(&dto)
+	service.SysApprovalService().ApproveSysApprovalTicket(c, dto)
+}
+
+// @Tags System系统管理
+// @Summary 拒绝审批单
+// @Produce json
+// @Description 拒绝审批单
+// @Param data body model.SysApprovalTicketActionDto true "data"
+// @Success 200 {object} result.Result{data=model.SysApprovalTicket}
+// @router /api/v1/approval/ticket/reject [post]
+// @Security ApiKeyAuth
+func RejectSysApprovalTicket(c *gin.Context) {
+	var dto model.SysApprovalTicketActionDto
+	_ = c.BindJSON(&dto)
+	service.SysApprovalService().RejectSysApprovalTicket(c, dto)
+}
+
+// @Tags System系统管理
+// @Summary 评论审批单
+// @Produce json
+// @Description 申请人和审批人可以评论审批单
+// @Param data body model.SysApprovalTicketActionDto true "data"
+// @Success 200 {object} result.Result{data=model.SysApprovalComment}
+// @router /api/v1/approval/ticket/comment [post]
+// @Security ApiKeyAuth
+func CommentSysApprovalTicket(c *gin.Context) {
+	var dto model.SysApprovalTicketActionDto
+	_ = c.BindJSON(&dto)
+	service.SysApprovalService().CommentSysApprovalTicket(c, dto)
+}
+
+// @Tags System系统管理
+// @Summary 撤销审批单
+// @Produce json
+// @Description 申请人撤销待审批的审批单
+// @Param data body model.SysApprovalTicketActionDto true "data"
+// @Success 200 {object} result.Result{data=model.SysApprovalTicket}
+// @router /api/v1/approval/ticket/cancel [post]
+// @Security ApiKeyAuth
+func CancelSysApprovalTicket(c *gin.Context) {
+	var dto model.SysApprovalTicketActionDto
+	_ = c.BindJSON(&dto)
+	service.SysApprovalService().CancelSysApprovalTicket(c, dto)
+}
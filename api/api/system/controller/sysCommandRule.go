@@ -0,0 +1,78 @@
+// 终端命令规则 控制层
+// author xiaoRui
+
+package controller
+
+import (
+	"dodevops-api/api/system/model"
+	"dodevops-api/api/system/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// @Tags System系统管理
+// @Summary 终端命令规则列表
+// @Produce json
+// @Description 查询全部终端命令规则，按优先级排列
+// @Success 200 {object} result.Result{data=[]model.SysCommandRule}
+// @router /api/v1/terminal/rule/list [get]
+// @Security ApiKeyAuth
+func GetSysCommandRuleList(c *gin.Context) {
+	service.SysCommandRuleService().GetSysCommandRuleList(c)
+}
+
+// @Tags System系统管理
+// @Summary 新增终端命令规则
+// @Produce json
+// @Description 新增终端命令规则，主机SSH终端和容器终端中输入的命令按优先级匹配规则，命中后放行、拦截或需要用户确认，超级管理员不受限制
+// @Param data body model.SysCommandRuleDto true "data"
+// @Success 200 {object} result.Result{data=model.SysCommandRule}
+// @router /api/v1/terminal/rule/add [post]
+// @Security ApiKeyAuth
+func CreateSysCommandRule(c *gin.Context) {
+	var dto model.SysCommandRuleDto
+	_ = c.BindJSON(&dto)
+	service.SysCommandRuleService().CreateSysCommandRule(c, dto)
+}
+
+// @Tags System系统管理
+// @Summary 修改终端命令规则
+// @Produce json
+// @Description 修改终端命令规则，已连接的终端重新连接后生效
+// @Param data body model.SysCommandRuleDto true "data"
+// @Success 200 {object} result.Result{data=model.SysCommandRule}
+// @router /api/v1/terminal/rule/update [put]
+// @Security ApiKeyAuth
+func UpdateSysCommandRule(c *gin.Context) {
+	var dto model.SysCommandRuleDto
+	_ = c.BindJSON(&dto)
+	service.SysCommandRuleService().UpdateSysCommandRule(c, dto)
+}
+
+// @Tags System系统管理
+// @Summary 删除终端命令规则
+// @Produce json
+// @Description 删除终端命令规则
+// @Param data body model.SysCommandRuleIdDto true "data"
+// @Success 200 {object} result.Result
+// @router /api/v1/terminal/rule/delete [delete]
+// @Security ApiKeyAuth
+func DeleteSysCommandRule(c *gin.Context) {
+	var dto model.SysCommandRuleIdDto
+	_ = c.BindJSON(&dto)
+	service.SysCommandRuleService().DeleteSysCommandRule(c, dto)
+}
+
+// @Tags System系统管理
+// @Summary 检查终端命令
+// @Produce json
+// @Description 检查命令在指定角色和资产分组下命中的规则，用于配置规则时验证
+// @Param data body model.SysCommandRuleCheckDto true "data"
+// @Success 200 {object} result.Result{data=model.SysCommandRuleCheckVo}
+// @router /api/v1/terminal/rule/check [post]
+// @Security ApiKeyAuth
+func CheckSysCommandRule(c *gin.Context) {
+	var dto model.SysCommandRuleCheckDto
+	_ = c.BindJSON(&dto)
+	service.SysCommandRuleService().CheckSysCommandRule(c, dto)
+}
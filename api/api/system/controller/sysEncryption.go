@@ -0,0 +1,34 @@
+// 敏感字段加密 控制层
+// author xiaoRui
+
+package controller
+
+import (
+	"dodevops-api/api/system/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// @Tags System系统管理
+// 敏感字段加密状态
+// @Summary 敏感字段加密状态接口
+// @Produce json
+// @Description 查询主密钥版本及各敏感字段的主密钥版本分布
+// @Success 200 {object} result.Result{data=model.SysEncryptionStatusVo}
+// @router /api/v1/encryption/status [get]
+// @Security ApiKeyAuth
+func GetSysEncryptionStatus(c *gin.Context) {
+	service.SysEncryptionService().GetSysEncryptionStatus(c)
+}
+
+// @Tags System系统管理
+// 重新加密敏感字段
+// @Summary 重新加密敏感字段接口
+// @Produce json
+// @Description 轮换主密钥或切换凭证存储后端后，在后台将全部敏感字段重新加密为当前主密钥版本并迁移到当前后端，执行期间不影响业务读写
+// @Success 200 {object} result.Result
+// @router /api/v1/encryption/rotate [post]
+// @Security ApiKeyAuth
+func RotateSysEncryption(c *gin.Context) {
+	service.SysEncryptionService().RotateSysEncryption(c)
+}
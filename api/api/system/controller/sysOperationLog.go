@@ -74,3 +74,47 @@ func BatchDeleteSysOperationLog(c *gin.Context) {
 func CleanSysOperationLog(c *gin.Context) {
 	service.SysOperationLogService().CleanSysOperationLog(c)
 }
+
+// @Tags System系统管理
+// 校验操作日志哈希链
+// @Summary 校验操作日志哈希链接口
+// @Produce json
+// @Description 校验操作日志哈希链，检测日志缺失或被修改
+// @Success 200 {object} result.Result{data=model.SysOperationLogVerifyVo}
+// @router /api/v1/sysOperationLog/verify [get]
+// @Security ApiKeyAuth
+func VerifySysOperationLog(c *gin.Context) {
+	service.SysOperationLogService().VerifySysOperationLog(c)
+}
+
+// @Tags System系统管理
+// 导出操作日志
+// @Summary 导出操作日志接口
+// @Produce octet-stream
+// @Description 按条件导出操作日志，支持 csv、jsonl 格式
+// @Param username query string false "用户名"
+// @Param method query string false "请求方式"
+// @Param url query string false "URL关键字"
+// @Param beginTime query string false "开始时间"
+// @Param endTime query string false "结束时间"
+// @Param format query string false "导出格式：csv、jsonl，默认 csv"
+// @Success 200 {file} file
+// @router /api/v1/sysOperationLog/export [get]
+// @Security ApiKeyAuth
+func ExportSysOperationLog(c *gin.Context) {
+	var dto model.SysOperationLogQueryDto
+	_ = c.BindQuery(&dto)
+	service.SysOperationLogService().ExportSysOperationLog(c, dto)
+}
+
+// @Tags System系统管理
+// 操作日志归档记录列表
+// @Summary 操作日志归档记录列表接口
+// @Produce json
+// @Description 操作日志归档记录列表接口
+// @Success 200 {object} result.Result{data=[]model.SysOperationLogArchive}
+// @router /api/v1/sysOperationLog/archive/list [get]
+// @Security ApiKeyAuth
+func GetSysOperationLogArchiveList(c *gin.Context) {
+	service.SysOperationLogService().GetSysOperationLogArchiveList(c)
+}
@@ -149,3 +149,32 @@ func AssignPermissions(c *gin.Context) {
 	_ = c.BindJSON(&RoleMenu)
 	service.SysRoleService().AssignPermissions(c, RoleMenu)
 }
+
+// @Tags System系统管理
+// GetSysRoleDataScope 查询角色数据范围
+// @Summary 查询角色数据范围接口
+// @Produce json
+// @Description 查询角色的数据范围及自定义分组
+// @Param id query int true "Id"
+// @Success 200 {object} result.Result{data=model.SysRoleDataScopeVo}
+// @router /api/v1/role/dataScope [get]
+// @Security ApiKeyAuth
+func GetSysRoleDataScope(c *gin.Context) {
+	Id, _ := strconv.Atoi(c.Query("id"))
+	service.SysRoleService().GetSysRoleDataScope(c, uint(Id))
+}
+
+// @Tags System系统管理
+// UpdateSysRoleDataScope 设置角色数据范围
+// @Summary 设置角色数据范围接口
+// @Produce json
+// @Description 设置角色可访问的主机、集群、应用、任务范围：1->全部,2->自定义分组,3->本部门,4->本部门及以下
+// @Param data body model.SysRoleDataScopeDto true "data"
+// @Success 200 {object} result.Result
+// @router /api/v1/role/dataScope [put]
+// @Security ApiKeyAuth
+func UpdateSysRoleDataScope(c *gin.Context) {
+	var dto model.SysRoleDataScopeDto
+	_ = c.BindJSON(&dto)
+	service.SysRoleService().UpdateSysRoleDataScope(c, dto)
+}
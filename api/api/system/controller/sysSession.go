@@ -0,0 +1,88 @@
+// 登录会话 控制层
+// author xiaoRui
+
+package controller
+
+import (
+	"dodevops-api/api/system/model"
+	"dodevops-api/api/system/service"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// @Tags System系统管理
+// @Summary 刷新访问令牌
+// @Produce json
+// @Description 访问令牌过期后使用登录返回的 refreshToken 换取新的访问令牌，刷新令牌同时轮换，旧刷新令牌立即失效
+// @Param data body model.RefreshTokenDto true "data"
+// @Success 200 {object} result.Result
+// @router /api/v1/refreshToken [post]
+func RefreshToken(c *gin.Context) {
+	var dto model.RefreshTokenDto
+	_ = c.BindJSON(&dto)
+	service.SysSessionService().RefreshToken(c, dto)
+}
+
+// @Tags System系统管理
+// @Summary 退出登录
+// @Produce json
+// @Description 吊销当前登录会话，访问令牌和刷新令牌立即失效
+// @Success 200 {object} result.Result
+// @router /api/v1/admin/logout [post]
+// @Security ApiKeyAuth
+func Logout(c *gin.Context) {
+	service.SysSessionService().Logout(c)
+}
+
+// @Tags System系统管理
+// @Summary 我的登录会话
+// @Produce json
+// @Description 查询当前用户的在线会话列表，包含登录IP、地点、浏览器和操作系统
+// @Success 200 {object} result.Result{data=[]model.SysSessionVo}
+// @router /api/v1/admin/session/list [get]
+// @Security ApiKeyAuth
+func GetMySessionList(c *gin.Context) {
+	service.SysSessionService().GetMySessionList(c)
+}
+
+// @Tags System系统管理
+// @Summary 下线我的登录会话
+// @Produce json
+// @Description 下线当前用户的指定会话
+// @Param data body model.SysSessionIdDto true "data"
+// @Success 200 {object} result.Result
+// @router /api/v1/admin/session/delete [delete]
+// @Security ApiKeyAuth
+func DeleteMySession(c *gin.Context) {
+	var dto model.SysSessionIdDto
+	_ = c.BindJSON(&dto)
+	service.SysSessionService().DeleteMySession(c, dto)
+}
+
+// @Tags System系统管理
+// @Summary 用户登录会话列表
+// @Produce json
+// @Description 查询指定用户的在线会话列表
+// @Param adminId query int true "用户id"
+// @Success 200 {object} result.Result{data=[]model.SysSessionVo}
+// @router /api/v1/admin/session/listByAdmin [get]
+// @Security ApiKeyAuth
+func GetSysAdminSessionList(c *gin.Context) {
+	adminId, _ := strconv.Atoi(c.Query("adminId"))
+	service.SysSessionService().GetSysAdminSessionList(c, uint(adminId))
+}
+
+// @Tags System系统管理
+// @Summary 强制下线
+// @Produce json
+// @Description 强制下线指定用户的某个会话，不传会话id时下线该用户全部会话
+// @Param data body model.ForceLogoutDto true "data"
+// @Success 200 {object} result.Result
+// @router /api/v1/admin/session/forceLogout [post]
+// @Security ApiKeyAuth
+func ForceLogout(c *gin.Context) {
+	var dto model.ForceLogoutDto
+	_ = c.BindJSON(&dto)
+	service.SysSessionService().ForceLogout(c, dto)
+}
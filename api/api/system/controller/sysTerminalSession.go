@@ -0,0 +1,141 @@
+// 终端会话审计 控制层
+// author xiaoRui
+
+package controller
+
+import (
+	"dodevops-api/api/system/model"
+	"dodevops-api/api/system/service"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+)
+
+var observeUpgrader = websocket.Upgrader{
+	CheckOrigin: func(r *http.Request) bool {
+		return true
+	},
+}
+
+// @Tags System系统管理
+// 终端会话列表
+// @Summary 终端会话列表接口
+// @Produce json
+// @Description 分页查询主机SSH终端和容器终端的会话记录，可按执行过的命令过滤
+// @Param kind query int false "终端类型:1->主机SSH,2->容器"
+// @Param username query string false "用户账号"
+// @Param targetName query string false "主机名称或Pod名称关键字"
+// @Param command query string false "执行过的命令关键字"
+// @Param status query int false "状态:1->进行中,2->已结束,3->异常中断"
+// @Param beginTime query string false "开始时间"
+// @Param endTime query string false "结束时间"
+// @Param pageNum query int false "分页数"
+// @Param pageSize query int false "每页数"
+// @Success 200 {object} result.Result{data=result.PageResult{list=[]model.SysTerminalSession}}
+// @router /api/v1/terminal/session/list [get]
+// @Security ApiKeyAuth
+func GetSysTerminalSessionList(c *gin.Context) {
+	var dto model.SysTerminalSessionQueryDto
+	_ = c.BindQuery(&dto)
+	service.SysTerminalSessionService().GetSysTerminalSessionList(c, dto)
+}
+
+// @Tags System系统管理
+// 终端会话详情
+// @Summary 终端会话详情接口
+// @Produce json
+// @Description 查询终端会话详情和会话中执行的命令
+// @Param id query int true "终端会话ID"
+// @Success 200 {object} result.Result{data=model.SysTerminalSessionInfoVo}
+// @router /api/v1/terminal/session/info [get]
+// @Security ApiKeyAuth
+func GetSysTerminalSessionInfo(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Query("id"))
+	service.SysTerminalSessionService().GetSysTerminalSessionInfo(c, uint(id))
+}
+
+// @Tags System系统管理
+// 获取终端会话录像
+// @Summary 获取终端会话录像接口
+// @Produce application/x-asciicast
+// @Description 返回 asciicast v2 格式的会话录像，可使用 asciinema-player 回放
+// @Param id query int true "终端会话ID"
+// @Success 200 {file} file
+// @router /api/v1/terminal/session/play [get]
+// @Security ApiKeyAuth
+func PlaySysTerminalSession(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Query("id"))
+	service.SysTerminalSessionService().PlaySysTerminalSession(c, uint(id))
+}
+
+// @Tags System系统管理
+// 检索终端命令
+// @Summary 检索终端命令接口
+// @Produce json
+// @Description 按关键字检索终端会话中执行过的命令，返回命令所在的会话和相对会话开始的时间，可用于定位回放
+// @Param keyword query string true "命令关键字"
+// @Param kind query int false "终端类型:1->主机SSH,2->容器"
+// @Param username query string false "用户账号"
+// @Param targetName query string false "主机名称或Pod名称关键字"
+// @Param beginTime query string false "开始时间"
+// @Param endTime query string false "结束时间"
+// @Param pageNum query int false "分页数"
+// @Param pageSize query int false "每页数"
+// @Success 200 {object} result.Result{data=result.PageResult{list=[]model.SysTerminalCommandVo}}
+// @router /api/v1/terminal/command/search [get]
+// @Security ApiKeyAuth
+func SearchSysTerminalCommand(c *gin.Context) {
+	var dto model.SysTerminalCommandQueryDto
+	_ = c.BindQuery(&dto)
+	service.SysTerminalSessionService().SearchSysTerminalCommand(c, dto)
+}
+
+// @Tags System系统管理
+// 进行中的终端会话
+// @Summary 进行中的终端会话接口
+// @Produce json
+// @Description 查询当前实例上进行中的主机SSH终端和容器终端会话及正在观看的人数
+// @Success 200 {object} result.Result{data=[]model.SysTerminalSessionActiveVo}
+// @router /api/v1/terminal/session/active [get]
+// @Security ApiKeyAuth
+func GetActiveSysTerminalSessionList(c *gin.Context) {
+	service.SysTerminalSessionService().GetActiveSysTerminalSessionList(c)
+}
+
+// @Tags System系统管理
+// 实时观看终端会话
+// @Summary 实时观看终端会话接口
+// @Description 通过WebSocket只读观看进行中的终端会话，先推送会话信息和最近的终端输出，之后实时推送终端输出和窗口大小变化，会话结束时推送 closed 消息
+// @Param id path int true "终端会话ID"
+// @Param token query string false "WebSocket连接时的用户Token"
+// @Success 101 "Switching Protocols"
+// @router /api/v1/terminal/session/observe/{id} [get]
+// @Security ApiKeyAuth
+func ObserveSysTerminalSession(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+	conn, err := observeUpgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		log.Printf("WebSocket升级失败: %v", err)
+		return
+	}
+	defer conn.Close()
+	service.SysTerminalSessionService().ObserveSysTerminalSession(c, conn, uint(id))
+}
+
+// @Tags System系统管理
+// 终止终端会话
+// @Summary 终止终端会话接口
+// @Produce json
+// @Description 终止进行中的终端会话，终止原因会显示给终端用户并记录在会话中
+// @Param data body model.SysTerminalSessionTerminateDto true "data"
+// @Success 200 {object} result.Result
+// @router /api/v1/terminal/session/terminate [post]
+// @Security ApiKeyAuth
+func TerminateSysTerminalSession(c *gin.Context) {
+	var dto model.SysTerminalSessionTerminateDto
+	_ = c.BindJSON(&dto)
+	service.SysTerminalSessionService().TerminateSysTerminalSession(c, dto)
+}
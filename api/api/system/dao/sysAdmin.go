@@ -4,7 +4,10 @@ import (
 	"dodevops-api/api/system/model"
 	"dodevops-api/common/util"
 	. "dodevops-api/pkg/db"
+	"fmt"
 	"time"
+
+	"gorm.io/gorm"
 )
 
 // 用户详情
@@ -34,23 +37,32 @@ type AddSysAdminDto struct {
 	Status   int    `validate:"required"` // 状态：1->启用,2->禁用
 }
 
+// 根据id查询用户
+func GetSysAdminById(id uint) (sysAdmin model.SysAdmin) {
+	Db.Where("id = ?", id).First(&sysAdmin)
+	return sysAdmin
+}
+
 // 新增用户
 func CreateSysAdmin(dto model.AddSysAdminDto) bool {
 	sysAdminByUsername := GetSysAdminByUsername(dto.Username)
 	if sysAdminByUsername.ID > 0 {
 		return false
 	}
+	now := util.HTime{Time: time.Now()}
 	sysAdmin := model.SysAdmin{
-		PostId:     dto.PostId,
-		DeptId:     dto.DeptId,
-		Username:   dto.Username,
-		Nickname:   dto.Nickname,
-		Password:   util.EncryptionMd5(dto.Password),
-		Phone:      dto.Phone,
-		Email:      dto.Email,
-		Note:       dto.Note,
-		Status:     dto.Status,
-		CreateTime: util.HTime{Time: time.Now()},
+		PostId:                dto.PostId,
+		DeptId:                dto.DeptId,
+		Username:              dto.Username,
+		Nickname:              dto.Nickname,
+		Password:              util.EncryptionMd5(dto.Password),
+		Phone:                 dto.Phone,
+		Email:                 dto.Email,
+		Note:                  dto.Note,
+		Status:                dto.Status,
+		CreateTime:            now,
+		PasswordUpdateTime:    &now,
+		PasswordResetRequired: dto.ForceChangePassword,
 	}
 	tx := Db.Create(&sysAdmin)
 	sysAdminExist := GetSysAdminByUsername(dto.Username)
@@ -110,6 +122,9 @@ func DeleteSysAdminById(dto model.SysAdminIdDto) {
 	Db.First(&model.SysAdmin{}, dto.Id)
 	Db.Delete(&model.SysAdmin{}, dto.Id)
 	Db.Where("admin_id = ?", dto.Id).Delete(&model.SysAdminRole{})
+	DeleteSysApiTokenByAdminId(dto.Id)
+	DeleteSysAdminMfa(dto.Id)
+	DeleteSysPasswordHistory(dto.Id)
 }
 
 // 修改用户状态
@@ -125,6 +140,8 @@ func ResetSysAdminPassword(dto model.ResetSysAdminPasswordDto) {
 	var sysAdmin model.SysAdmin
 	Db.First(&sysAdmin, dto.Id)
 	sysAdmin.Password = util.EncryptionMd5(dto.Password) // 密码加密
+	sysAdmin.PasswordUpdateTime = &util.HTime{Time: time.Now()}
+	sysAdmin.PasswordResetRequired = dto.ForceChangePassword
 	Db.Save(&sysAdmin)
 }
 
@@ -176,6 +193,60 @@ func UpdatePersonal(dto model.UpdatePersonalDto) (sysAdmin model.SysAdmin) {
 func UpdatePersonalPassword(dto model.UpdatePersonalPasswordDto) (sysAdmin model.SysAdmin) {
 	Db.First(&sysAdmin, dto.Id)
 	sysAdmin.Password = dto.NewPassword
+	sysAdmin.PasswordUpdateTime = &util.HTime{Time: time.Now()}
+	sysAdmin.PasswordResetRequired = false
 	Db.Save(&sysAdmin)
 	return sysAdmin
 }
+
+// 标记用户下次登录需修改密码
+func UpdateSysAdminPasswordResetRequired(id uint, required bool) {
+	Db.Model(&model.SysAdmin{}).Where("id = ?", id).Update("password_reset_required", required)
+}
+
+// 保存外部认证源（LDAP等）同步的用户：不存在则新建，存在则更新基础信息，并按映射结果重新分配角色
+func SaveExternalSysAdmin(admin model.SysAdmin, roleIds []uint) (sysAdmin model.SysAdmin, err error) {
+	err = Db.Transaction(func(tx *gorm.DB) error {
+		tx.Where("username = ?", admin.Username).First(&sysAdmin)
+		if sysAdmin.ID == 0 {
+			sysAdmin = admin
+			sysAdmin.Status = 1
+			sysAdmin.CreateTime = util.HTime{Time: time.Now()}
+			if err := tx.Create(&sysAdmin).Error; err != nil {
+				return err
+			}
+		} else {
+			// 不允许外部账号覆盖同名的本地账号或其他认证源的账号
+			if sysAdmin.Source != admin.Source {
+				return fmt.Errorf("用户名 %s 已被其他认证源的账号占用", admin.Username)
+			}
+			sysAdmin.Password = admin.Password
+			if admin.Nickname != "" {
+				sysAdmin.Nickname = admin.Nickname
+			}
+			if admin.Email != "" {
+				sysAdmin.Email = admin.Email
+			}
+			if admin.Phone != "" {
+				sysAdmin.Phone = admin.Phone
+			}
+			if admin.DeptId != 0 {
+				sysAdmin.DeptId = admin.DeptId
+			}
+			if err := tx.Save(&sysAdmin).Error; err != nil {
+				return err
+			}
+		}
+		// 角色完全以认证源的映射结果为准，映射为空时清空已有角色，避免撤销的组权限残留
+		if err := tx.Where("admin_id = ?", sysAdmin.ID).Delete(&model.SysAdminRole{}).Error; err != nil {
+			return err
+		}
+		for _, roleId := range roleIds {
+			if err := tx.Create(&model.SysAdminRole{AdminId: sysAdmin.ID, RoleId: roleId}).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return sysAdmin, err
+}
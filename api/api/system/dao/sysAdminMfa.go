@@ -0,0 +1,39 @@
+// 二次验证 数据层
+// author xiaoRui
+
+package dao
+
+import (
+	"dodevops-api/api/system/model"
+	. "dodevops-api/pkg/db"
+)
+
+// 根据用户id查询二次验证配置
+func GetSysAdminMfa(adminId uint) (mfa model.SysAdminMfa) {
+	Db.Where("admin_id = ?", adminId).First(&mfa)
+	return mfa
+}
+
+// 保存二次验证配置，已存在时覆盖
+func SaveSysAdminMfa(mfa *model.SysAdminMfa) error {
+	return Db.Save(mfa).Error
+}
+
+// 删除用户的二次验证配置
+func DeleteSysAdminMfa(adminId uint) {
+	Db.Where("admin_id = ?", adminId).Delete(&model.SysAdminMfa{})
+}
+
+// 更新最后使用的验证码时间窗口，仅当新窗口大于已记录窗口时更新，返回影响行数
+func UpdateSysAdminMfaLastStep(id uint, step int64) int64 {
+	return Db.Model(&model.SysAdminMfa{}).
+		Where("id = ? AND last_step < ?", id, step).
+		Update("last_step", step).RowsAffected
+}
+
+// 更新恢复码，仅当恢复码未被并发修改时更新，返回影响行数
+func UpdateSysAdminMfaRecoveryCodes(id uint, oldCodes, newCodes string) int64 {
+	return Db.Model(&model.SysAdminMfa{}).
+		Where("id = ? AND recovery_codes = ?", id, oldCodes).
+		Update("recovery_codes", newCodes).RowsAffected
+}
@@ -0,0 +1,46 @@
+// 个人访问令牌 数据层
+// author xiaoRui
+
+package dao
+
+import (
+	"dodevops-api/api/system/model"
+	"dodevops-api/common/util"
+	. "dodevops-api/pkg/db"
+	"time"
+)
+
+// 新增令牌
+func CreateSysApiToken(token *model.SysApiToken) error {
+	return Db.Create(token).Error
+}
+
+// 根据令牌哈希查询
+func GetSysApiTokenByHash(tokenHash string) (token model.SysApiToken) {
+	Db.Where("token_hash = ?", tokenHash).First(&token)
+	return token
+}
+
+// 查询用户的令牌列表
+func GetSysApiTokenListByAdminId(adminId uint) (tokens []model.SysApiToken) {
+	Db.Where("admin_id = ?", adminId).Order("create_time desc").Find(&tokens)
+	return tokens
+}
+
+// 删除（吊销）用户的令牌
+func DeleteSysApiToken(adminId, id uint) int64 {
+	return Db.Where("id = ? AND admin_id = ?", id, adminId).Delete(&model.SysApiToken{}).RowsAffected
+}
+
+// 删除用户的全部令牌
+func DeleteSysApiTokenByAdminId(adminId uint) {
+	Db.Where("admin_id = ?", adminId).Delete(&model.SysApiToken{})
+}
+
+// 更新最后使用时间和IP
+func UpdateSysApiTokenLastUsed(id uint, ip string) {
+	Db.Model(&model.SysApiToken{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"last_used_time": util.HTime{Time: time.Now()},
+		"last_used_ip":   ip,
+	})
+}
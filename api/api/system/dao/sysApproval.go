@@ -0,0 +1,172 @@
+// 审批流程 数据层
+// author xiaoRui
+
+package dao
+
+import (
+	"dodevops-api/api/system/model"
+	"dodevops-api/common/util"
+	. "dodevops-api/pkg/db"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 审批人已处理过该审批单
+var ErrApprovalVoted = errors.New("您已审批过该审批单")
+
+// 审批单状态已变化
+var ErrApprovalNotPending = errors.New("审批单不是待审批状态")
+
+// 查询审批策略列表
+func GetSysApprovalPolicyList() (policies []model.SysApprovalPolicy) {
+	Db.Order("id").Find(&policies)
+	return policies
+}
+
+// 查询接口对应的已启用审批策略
+func GetEnabledSysApprovalPolicyList(method, path string) (policies []model.SysApprovalPolicy) {
+	Db.Where("method = ? AND path = ? AND status = ?", method, path, 1).Order("id").Find(&policies)
+	return policies
+}
+
+// 根据id查询审批策略
+func GetSysApprovalPolicyById(id uint) (policy model.SysApprovalPolicy) {
+	Db.First(&policy, id)
+	return policy
+}
+
+// 新增审批策略
+func CreateSysApprovalPolicy(policy *model.SysApprovalPolicy) error {
+	return Db.Create(policy).Error
+}
+
+// 修改审批策略
+func UpdateSysApprovalPolicy(policy model.SysApprovalPolicy) error {
+	return Db.Save(&policy).Error
+}
+
+// 删除审批策略
+func DeleteSysApprovalPolicyById(id uint) {
+	Db.Delete(&model.SysApprovalPolicy{}, id)
+}
+
+// 新增审批单
+func CreateSysApprovalTicket(ticket *model.SysApprovalTicket) error {
+	return Db.Create(ticket).Error
+}
+
+// 根据id查询审批单
+func GetSysApprovalTicketById(id uint) (ticket model.SysApprovalTicket) {
+	Db.First(&ticket, id)
+	return ticket
+}
+
+// 分页查询审批单，applicantId 为 0 时不限申请人
+func GetSysApprovalTicketList(status int, applicantId uint, PageSize, PageNum int) (tickets []model.SysApprovalTicket, count int64) {
+	curDb := Db.Model(&model.SysApprovalTicket{})
+	if status > 0 {
+		curDb = curDb.Where("status = ?", status)
+	}
+	if applicantId > 0 {
+		curDb = curDb.Where("applicant_id = ?", applicantId)
+	}
+	curDb.Count(&count)
+	curDb.Order("id desc").Limit(PageSize).Offset((PageNum - 1) * PageSize).Find(&tickets)
+	return tickets, count
+}
+
+// 查询审批单的审批记录
+func GetSysApprovalCommentList(ticketId uint) (comments []model.SysApprovalComment) {
+	Db.Where("ticket_id = ?", ticketId).Order("id").Find(&comments)
+	return comments
+}
+
+// 新增审批记录
+func CreateSysApprovalComment(comment *model.SysApprovalComment) error {
+	return Db.Create(comment).Error
+}
+
+// 同意审批单，同意人数达到要求时将审批单置为已通过，approved 为 true 表示本次审批使审批单通过
+func ApproveSysApprovalTicket(comment *model.SysApprovalComment) (approved bool, err error) {
+	err = Db.Transaction(func(tx *gorm.DB) error {
+		if err := checkApprovalVote(tx, comment); err != nil {
+			return err
+		}
+		res := tx.Model(&model.SysApprovalTicket{}).Where("id = ? AND status = ?", comment.TicketId, model.ApprovalStatusPending).
+			Update("approved_count", gorm.Expr("approved_count + 1"))
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return ErrApprovalNotPending
+		}
+		res = tx.Model(&model.SysApprovalTicket{}).
+			Where("id = ? AND status = ? AND approved_count >= required_approvals", comment.TicketId, model.ApprovalStatusPending).
+			Update("status", model.ApprovalStatusApproved)
+		if res.Error != nil {
+			return res.Error
+		}
+		approved = res.RowsAffected > 0
+		return tx.Create(comment).Error
+	})
+	return approved, err
+}
+
+// 拒绝审批单
+func RejectSysApprovalTicket(comment *model.SysApprovalComment) error {
+	return Db.Transaction(func(tx *gorm.DB) error {
+		if err := checkApprovalVote(tx, comment); err != nil {
+			return err
+		}
+		if err := updateSysApprovalTicketStatus(tx, comment.TicketId, model.ApprovalStatusRejected); err != nil {
+			return err
+		}
+		return tx.Create(comment).Error
+	})
+}
+
+// 撤销审批单
+func CancelSysApprovalTicket(comment *model.SysApprovalComment) error {
+	return Db.Transaction(func(tx *gorm.DB) error {
+		if err := updateSysApprovalTicketStatus(tx, comment.TicketId, model.ApprovalStatusCanceled); err != nil {
+			return err
+		}
+		return tx.Create(comment).Error
+	})
+}
+
+// 每个审批人只能同意或拒绝一次
+func checkApprovalVote(tx *gorm.DB, comment *model.SysApprovalComment) error {
+	var count int64
+	tx.Model(&model.SysApprovalComment{}).Where("ticket_id = ? AND admin_id = ? AND action IN ?", comment.TicketId, comment.AdminId,
+		[]int{model.ApprovalActionApprove, model.ApprovalActionReject}).Count(&count)
+	if count > 0 {
+		return ErrApprovalVoted
+	}
+	return nil
+}
+
+// 仅待审批的审批单可以变更状态
+func updateSysApprovalTicketStatus(tx *gorm.DB, id uint, status int) error {
+	res := tx.Model(&model.SysApprovalTicket{}).Where("id = ? AND status = ?", id, model.ApprovalStatusPending).Update("status", status)
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ErrApprovalNotPending
+	}
+	return nil
+}
+
+// 记录审批单执行结果
+func FinishSysApprovalTicket(id uint, status, resultCode int, result string) {
+	// 使用结构体更新，执行结果经过加密序列化器保存
+	Db.Model(&model.SysApprovalTicket{}).Where("id = ?", id).Select("status", "result_code", "result", "execute_time").Updates(&model.SysApprovalTicket{
+		Status:      status,
+		ResultCode:  resultCode,
+		Result:      result,
+		ExecuteTime: &util.HTime{Time: time.Now()},
+	})
+}
@@ -0,0 +1,42 @@
+// 终端命令规则 数据层
+// author xiaoRui
+
+package dao
+
+import (
+	"dodevops-api/api/system/model"
+	. "dodevops-api/pkg/db"
+)
+
+// 查询命令规则列表
+func GetSysCommandRuleList() (rules []model.SysCommandRule) {
+	Db.Order("priority, id").Find(&rules)
+	return rules
+}
+
+// 查询已启用的命令规则，按匹配顺序排列
+func GetEnabledSysCommandRuleList() (rules []model.SysCommandRule) {
+	Db.Where("status = ?", 1).Order("priority, id").Find(&rules)
+	return rules
+}
+
+// 根据id查询命令规则
+func GetSysCommandRuleById(id uint) (rule model.SysCommandRule) {
+	Db.First(&rule, id)
+	return rule
+}
+
+// 新增命令规则
+func CreateSysCommandRule(rule *model.SysCommandRule) error {
+	return Db.Create(rule).Error
+}
+
+// 修改命令规则
+func UpdateSysCommandRule(rule model.SysCommandRule) error {
+	return Db.Save(&rule).Error
+}
+
+// 删除命令规则
+func DeleteSysCommandRuleById(id uint) {
+	Db.Delete(&model.SysCommandRule{}, id)
+}
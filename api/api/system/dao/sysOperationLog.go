@@ -4,40 +4,190 @@
 package dao
 
 import (
+	"crypto/sha256"
 	"dodevops-api/api/system/model"
+	"dodevops-api/pkg/log"
+	"encoding/hex"
+	"fmt"
+	"time"
+
 	. "dodevops-api/pkg/db"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
-// 新增操作日志
-func CreateSysOperationLog(log model.SysOperationLog) {
-	Db.Create(&log)
+// 哈希链中日志时间的格式，精确到毫秒与数据库 datetime(3) 保持一致
+const operationLogTimeFormat = "2006-01-02 15:04:05.000"
+
+// 哈希链头的固定主键
+const operationLogChainId = 1
+
+// 计算操作日志哈希：上一条哈希与本条内容共同参与计算，任何一条被修改或删除都会导致链断裂
+func SysOperationLogHash(sysLog model.SysOperationLog) string {
+	var seq uint64
+	if sysLog.Seq != nil {
+		seq = *sysLog.Seq
+	}
+	content := fmt.Sprintf("%d|%s|%d|%s|%s|%s|%s|%s|%s", seq, sysLog.PrevHash, sysLog.AdminId, sysLog.Username,
+		sysLog.Method, sysLog.Ip, sysLog.Url, sysLog.Description, sysLog.CreateTime.In(time.Local).Format(operationLogTimeFormat))
+	sum := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(sum[:])
 }
 
-// 分页查询操作日志列表
-func GetSysOperationLogList(Username, BeginTime, EndTime string, PageSize, PageNum int) (sysOperationLog []model.SysOperationLog, count int64) {
-	curDb := Db.Table("sys_operation_log")
-	if Username != "" {
-		curDb = curDb.Where("username =?", Username)
+// 新增操作日志，追加到哈希链末尾
+// 写入前锁定哈希链头（SELECT ... FOR UPDATE），多个请求或多个实例并发写入时由数据库行锁保证顺序
+func CreateSysOperationLog(sysLog model.SysOperationLog) {
+	sysLog.CreateTime.Time = sysLog.CreateTime.Truncate(time.Millisecond)
+	var err error
+	for i := 0; i < 3; i++ {
+		entry := sysLog
+		err = Db.Transaction(func(tx *gorm.DB) error {
+			head, err := lockSysOperationLogChain(tx)
+			if err != nil {
+				return err
+			}
+			seq := head.LastSeq + 1
+			entry.Seq = &seq
+			entry.PrevHash = head.LastHash
+			entry.Hash = SysOperationLogHash(entry)
+			if err := tx.Create(&entry).Error; err != nil {
+				return err
+			}
+			return tx.Model(&head).Updates(map[string]interface{}{"last_seq": seq, "last_hash": entry.Hash}).Error
+		})
+		if err == nil {
+			return
+		}
+	}
+	log.Log().Errorf("写入操作日志失败: %v", err)
+}
+
+// 锁定哈希链头，不存在时按已有日志或最后一次归档初始化
+// 多个实例同时初始化时主键冲突，事务失败后重试即可读到已创建的链头
+func lockSysOperationLogChain(tx *gorm.DB) (model.SysOperationLogChain, error) {
+	var head model.SysOperationLogChain
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", operationLogChainId).Limit(1).Find(&head).Error
+	if err != nil || head.ID != 0 {
+		return head, err
+	}
+	head.ID = operationLogChainId
+	head.LastSeq, head.LastHash = getSysOperationLogChainTail(tx)
+	return head, tx.Create(&head).Error
+}
+
+// 查询哈希链末尾的序号和哈希，数据库中没有已链接的日志时从最后一次归档处衔接
+func getSysOperationLogChainTail(tx *gorm.DB) (uint64, string) {
+	var last model.SysOperationLog
+	tx.Where("seq IS NOT NULL").Order("seq DESC").Limit(1).Find(&last)
+	if last.Seq != nil {
+		return *last.Seq, last.Hash
+	}
+	var archive model.SysOperationLogArchive
+	tx.Where("end_seq > 0").Order("end_seq DESC").Limit(1).Find(&archive)
+	return archive.EndSeq, archive.LastHash
+}
+
+// 按条件过滤操作日志
+func filterSysOperationLog(dto model.SysOperationLogQueryDto) *gorm.DB {
+	curDb := Db.Model(&model.SysOperationLog{})
+	if dto.Username != "" {
+		curDb = curDb.Where("username = ?", dto.Username)
+	}
+	if dto.Method != "" {
+		curDb = curDb.Where("method = ?", dto.Method)
 	}
-	if BeginTime != "" && EndTime != "" {
-		curDb = curDb.Where("`create_time` BETWEEN ? AND ?", BeginTime, EndTime)
+	if dto.Url != "" {
+		curDb = curDb.Where("url LIKE ?", "%"+dto.Url+"%")
 	}
+	if dto.BeginTime != "" && dto.EndTime != "" {
+		curDb = curDb.Where("`create_time` BETWEEN ? AND ?", dto.BeginTime, dto.EndTime)
+	}
+	return curDb
+}
+
+// 分页查询操作日志列表
+func GetSysOperationLogList(Username, BeginTime, EndTime string, PageSize, PageNum int) (sysOperationLog []model.SysOperationLog, count int64) {
+	curDb := filterSysOperationLog(model.SysOperationLogQueryDto{Username: Username, BeginTime: BeginTime, EndTime: EndTime})
 	curDb.Count(&count)
 	curDb.Limit(PageSize).Offset((PageNum - 1) * PageSize).Order("create_time desc").Find(&sysOperationLog)
 	return sysOperationLog, count
 }
 
-// 根据id删除操作日志
-func DeleteSysOperationLogById(dto model.SysOperationLogIdDto) {
-	Db.Delete(&model.SysOperationLog{}, dto)
+// 按条件分批遍历操作日志，用于导出
+func EachSysOperationLog(dto model.SysOperationLogQueryDto, batchSize int, fn func(logs []model.SysOperationLog) error) error {
+	var lastId uint
+	for {
+		var logs []model.SysOperationLog
+		if err := filterSysOperationLog(dto).Where("id > ?", lastId).Order("id").Limit(batchSize).Find(&logs).Error; err != nil {
+			return err
+		}
+		if len(logs) == 0 {
+			return nil
+		}
+		if err := fn(logs); err != nil {
+			return err
+		}
+		lastId = logs[len(logs)-1].ID
+	}
+}
+
+// 按序号分批遍历已链接的操作日志，用于校验哈希链
+func EachChainedSysOperationLog(afterSeq uint64, batchSize int, fn func(logs []model.SysOperationLog) error) error {
+	for {
+		var logs []model.SysOperationLog
+		if err := Db.Where("seq > ?", afterSeq).Order("seq").Limit(batchSize).Find(&logs).Error; err != nil {
+			return err
+		}
+		if len(logs) == 0 {
+			return nil
+		}
+		if err := fn(logs); err != nil {
+			return err
+		}
+		afterSeq = *logs[len(logs)-1].Seq
+	}
+}
+
+// 统计启用哈希链前的历史日志条数
+func CountUnchainedSysOperationLog() (count int64) {
+	Db.Model(&model.SysOperationLog{}).Where("seq IS NULL").Count(&count)
+	return count
+}
+
+// 查询截止时间前最后一条已链接日志的序号，归档只能截取哈希链的前缀
+func GetSysOperationLogArchiveEndSeq(before time.Time) (seq uint64) {
+	Db.Model(&model.SysOperationLog{}).Select("COALESCE(MAX(seq), 0)").
+		Where("seq IS NOT NULL AND create_time < ?", before).Scan(&seq)
+	return seq
+}
+
+// 查询待归档的操作日志：截止序号前的已链接日志，以及截止时间前的历史日志
+func GetSysOperationLogToArchive(endSeq uint64, before time.Time) (logs []model.SysOperationLog) {
+	Db.Where("seq <= ? OR (seq IS NULL AND create_time < ?)", endSeq, before).Order("id").Find(&logs)
+	return logs
 }
 
-// 批量删除批量操作日志
-func BatchDeleteSysOperationLog(dto model.BatchDeleteSysOperationLogDto) {
-	Db.Where("id in (?)", dto.Ids).Delete(&model.SysOperationLog{})
+// 保存归档记录并删除已归档的日志
+func ArchiveSysOperationLog(archive *model.SysOperationLogArchive, ids []uint) error {
+	return Db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(archive).Error; err != nil {
+			return err
+		}
+		for start := 0; start < len(ids); start += 500 {
+			end := start + 500
+			if end > len(ids) {
+				end = len(ids)
+			}
+			if err := tx.Where("id IN ?", ids[start:end]).Delete(&model.SysOperationLog{}).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
 }
 
-// 清空操作日志
-func CleanSysOperationLog() {
-	Db.Exec("truncate table sys_operation_log")
+// 查询全部归档记录，按序号排序
+func GetSysOperationLogArchiveList() (archives []model.SysOperationLogArchive) {
+	Db.Order("end_seq, id").Find(&archives)
+	return archives
 }
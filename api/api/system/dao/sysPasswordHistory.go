@@ -0,0 +1,31 @@
+// 历史密码 数据层
+// author xiaoRui
+
+package dao
+
+import (
+	"dodevops-api/api/system/model"
+	"dodevops-api/common/util"
+	. "dodevops-api/pkg/db"
+	"time"
+)
+
+// 新增历史密码，并只保留最近 keep 条
+func CreateSysPasswordHistory(adminId uint, password string, keep int) {
+	Db.Create(&model.SysPasswordHistory{AdminId: adminId, Password: password, CreateTime: util.HTime{Time: time.Now()}})
+	histories := GetSysPasswordHistoryList(adminId, keep)
+	if keep > 0 && len(histories) == keep {
+		Db.Where("admin_id = ? AND id < ?", adminId, histories[keep-1].ID).Delete(&model.SysPasswordHistory{})
+	}
+}
+
+// 查询用户最近 limit 条历史密码
+func GetSysPasswordHistoryList(adminId uint, limit int) (histories []model.SysPasswordHistory) {
+	Db.Where("admin_id = ?", adminId).Order("id DESC").Limit(limit).Find(&histories)
+	return histories
+}
+
+// 删除用户的历史密码
+func DeleteSysPasswordHistory(adminId uint) {
+	Db.Where("admin_id = ?", adminId).Delete(&model.SysPasswordHistory{})
+}
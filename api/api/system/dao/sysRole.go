@@ -70,6 +70,7 @@ func UpdateSysRole(dto model.UpdateSysRoleDto) (sysRole model.SysRole) {
 func DeleteSysRoleById(dto model.SysRoleIdDto) {
 	Db.Table("sys_role").Delete(&model.SysRole{}, dto.Id)
 	Db.Table("sys_role_menu").Where("role_id = ?", dto.Id).Delete(&model.SysRoleMenu{})
+	Db.Where("role_id = ?", dto.Id).Delete(&model.SysRoleGroup{})
 }
 
 // 角色状态启用/停用
@@ -141,3 +142,21 @@ func AssignPermissions(menu model.RoleMenu) (err error) {
 	})
 	return err
 }
+
+// 查询用户拥有的已启用角色key列表
+func QueryAdminRoleKeyList(adminId uint) (roleKeys []string) {
+	const status = 1
+	Db.Table("sys_role sr").
+		Select("sr.role_key").
+		Joins("LEFT JOIN sys_admin_role sar ON sar.role_id = sr.id").
+		Where("sr.status = ?", status).
+		Where("sar.admin_id = ?", adminId).
+		Scan(&roleKeys)
+	return roleKeys
+}
+
+// 查询分配了指定角色的用户id列表
+func QueryAdminIdListByRoleId(roleId uint) (adminIds []uint) {
+	Db.Table("sys_admin_role").Select("admin_id").Where("role_id = ?", roleId).Scan(&adminIds)
+	return adminIds
+}
@@ -0,0 +1,77 @@
+// 角色数据权限 数据层
+// author xiaoRui
+
+package dao
+
+import (
+	"dodevops-api/api/system/model"
+	. "dodevops-api/pkg/db"
+
+	"gorm.io/gorm"
+)
+
+// 部门/资产分组的树形节点
+type ScopeTreeNode struct {
+	Id       uint // ID
+	ParentId uint // 父id
+	DeptId   uint // 归属部门id，仅资产分组使用
+}
+
+// 查询用户拥有的已启用角色
+func QueryAdminEnabledRoleList(adminId uint) (roles []model.SysRole) {
+	const status = 1
+	Db.Table("sys_role sr").
+		Select("sr.id, sr.role_key, sr.data_scope").
+		Joins("LEFT JOIN sys_admin_role sar ON sar.role_id = sr.id").
+		Where("sr.status = ?", status).
+		Where("sar.admin_id = ?", adminId).
+		Scan(&roles)
+	return roles
+}
+
+// 查询角色的自定义分组id列表
+func QueryRoleGroupIdList(roleIds ...uint) (groupIds []uint) {
+	if len(roleIds) == 0 {
+		return groupIds
+	}
+	Db.Table("sys_role_group").Select("group_id").Where("role_id IN ?", roleIds).Scan(&groupIds)
+	return groupIds
+}
+
+// 查询用户所属部门id
+func QueryAdminDeptId(adminId uint) (deptId uint) {
+	Db.Table("sys_admin").Select("dept_id").Where("id = ?", adminId).Scan(&deptId)
+	return deptId
+}
+
+// 查询全部部门的树形节点
+func QueryDeptTreeNodeList() (nodes []ScopeTreeNode) {
+	Db.Table("sys_dept").Select("id, parent_id").Scan(&nodes)
+	return nodes
+}
+
+// 查询全部资产分组的树形节点
+func QueryGroupTreeNodeList() (nodes []ScopeTreeNode) {
+	Db.Table("cmdb_group").Select("id, parent_id, dept_id").Scan(&nodes)
+	return nodes
+}
+
+// 设置角色数据范围
+func UpdateSysRoleDataScope(dto model.SysRoleDataScopeDto) error {
+	return Db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&model.SysRole{}).Where("id = ?", dto.Id).Update("data_scope", dto.DataScope).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("role_id = ?", dto.Id).Delete(&model.SysRoleGroup{}).Error; err != nil {
+			return err
+		}
+		if len(dto.GroupIds) == 0 {
+			return nil
+		}
+		entities := make([]model.SysRoleGroup, 0, len(dto.GroupIds))
+		for _, groupId := range dto.GroupIds {
+			entities = append(entities, model.SysRoleGroup{RoleId: dto.Id, GroupId: groupId})
+		}
+		return tx.CreateInBatches(entities, 100).Error
+	})
+}
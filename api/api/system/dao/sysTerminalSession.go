@@ -0,0 +1,107 @@
+// 终端会话审计 数据层
+// author xiaoRui
+
+package dao
+
+import (
+	"dodevops-api/api/system/model"
+	. "dodevops-api/pkg/db"
+
+	"gorm.io/gorm"
+)
+
+// 新增终端会话
+func CreateSysTerminalSession(session *model.SysTerminalSession) error {
+	return Db.Create(session).Error
+}
+
+// 会话结束，保存录像信息和执行的命令
+func FinishSysTerminalSession(session *model.SysTerminalSession, commands []model.SysTerminalCommand) error {
+	return Db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&model.SysTerminalSession{}).Where("id = ?", session.ID).Updates(map[string]interface{}{
+			"backend":          session.Backend,
+			"storage_key":      session.StorageKey,
+			"size":             session.Size,
+			"duration":         session.Duration,
+			"command_count":    session.CommandCount,
+			"status":           session.Status,
+			"end_time":         session.EndTime,
+			"terminated_by":    session.TerminatedBy,
+			"terminate_reason": session.Reason,
+		}).Error; err != nil {
+			return err
+		}
+		if len(commands) == 0 {
+			return nil
+		}
+		return tx.CreateInBatches(commands, 200).Error
+	})
+}
+
+// 根据id查询终端会话
+func GetSysTerminalSessionById(id uint) (session model.SysTerminalSession, err error) {
+	err = Db.First(&session, id).Error
+	return session, err
+}
+
+// 查询指定状态的终端会话
+func GetSysTerminalSessionByStatus(status int) (sessions []model.SysTerminalSession) {
+	Db.Where("status = ?", status).Find(&sessions)
+	return sessions
+}
+
+// 查询终端会话中执行的命令
+func GetSysTerminalCommandList(sessionId uint) (commands []model.SysTerminalCommand) {
+	Db.Where("session_id = ?", sessionId).Order("id").Find(&commands)
+	return commands
+}
+
+// 分页查询终端会话列表
+func GetSysTerminalSessionList(dto model.SysTerminalSessionQueryDto) (sessions []model.SysTerminalSession, count int64) {
+	curDb := Db.Model(&model.SysTerminalSession{})
+	if dto.Kind != 0 {
+		curDb = curDb.Where("kind = ?", dto.Kind)
+	}
+	if dto.Username != "" {
+		curDb = curDb.Where("username = ?", dto.Username)
+	}
+	if dto.TargetName != "" {
+		curDb = curDb.Where("target_name LIKE ?", "%"+dto.TargetName+"%")
+	}
+	if dto.Status != 0 {
+		curDb = curDb.Where("status = ?", dto.Status)
+	}
+	if dto.Command != "" {
+		curDb = curDb.Where("id IN (?)", Db.Model(&model.SysTerminalCommand{}).Select("session_id").
+			Where("command LIKE ?", "%"+dto.Command+"%"))
+	}
+	if dto.BeginTime != "" && dto.EndTime != "" {
+		curDb = curDb.Where("start_time BETWEEN ? AND ?", dto.BeginTime, dto.EndTime)
+	}
+	curDb.Count(&count)
+	curDb.Limit(dto.PageSize).Offset((dto.PageNum - 1) * dto.PageSize).Order("start_time desc").Find(&sessions)
+	return sessions, count
+}
+
+// 按关键字检索终端会话中执行的命令
+func SearchSysTerminalCommand(dto model.SysTerminalCommandQueryDto) (commands []model.SysTerminalCommandVo, count int64) {
+	curDb := Db.Table("sys_terminal_command c").
+		Joins("JOIN sys_terminal_session s ON s.id = c.session_id").
+		Where("c.command LIKE ?", "%"+dto.Keyword+"%")
+	if dto.Kind != 0 {
+		curDb = curDb.Where("s.kind = ?", dto.Kind)
+	}
+	if dto.Username != "" {
+		curDb = curDb.Where("s.username = ?", dto.Username)
+	}
+	if dto.TargetName != "" {
+		curDb = curDb.Where("s.target_name LIKE ?", "%"+dto.TargetName+"%")
+	}
+	if dto.BeginTime != "" && dto.EndTime != "" {
+		curDb = curDb.Where("c.create_time BETWEEN ? AND ?", dto.BeginTime, dto.EndTime)
+	}
+	curDb.Count(&count)
+	curDb.Select("c.*, s.kind, s.target_id, s.target_name, s.username, s.ip").
+		Limit(dto.PageSize).Offset((dto.PageNum - 1) * dto.PageSize).Order("c.create_time desc, c.id desc").Scan(&commands)
+	return commands, count
+}
@@ -8,33 +8,45 @@ import (
 
 // 用户模型对象
 type SysAdmin struct {
-	ID         uint       `gorm:"column:id;comment:'主键';primaryKey;NOT NULL" json:"id"`                        //ID
-	PostId     int        `gorm:"column:post_id;comment:'岗位id'" json:"postId"`                                 // 岗位id
-	DeptId     int        `gorm:"column:dept_id;comment:'部门id'" json:"deptId"`                                 // 部门id
-	Username   string     `gorm:"column:username;varchar(64);comment:'用户账号';NOT NULL" json:"username"`         // 用户账号
-	Password   string     `gorm:"column:password;varchar(64);comment:'密码';NOT NULL" json:"password"`           // 密码
-	Nickname   string     `gorm:"column:nickname;varchar(64);comment:'昵称'" json:"nickname"`                    // 昵称
-	Status     int        `gorm:"column:status;default:1;comment:'帐号启用状态:1->启用,2->禁用';NOT NULL" json:"status"` // 帐号启用状态：1->启用,2->禁用
-	Icon       string     `gorm:"column:icon;varchar(500);comment:'头像'" json:"icon"`                           //  头像
-	Email      string     `gorm:"column:email;varchar(64);comment:'邮箱'" json:"email"`                          // 邮箱
-	Phone      string     `gorm:"column:phone;varchar(64);comment:'电话'" json:"phone"`                          // 电话
-	Note       string     `gorm:"column:note;varchar(500);comment:'备注'" json:"note"`                           // 备注
-	CreateTime util.HTime `gorm:"column:create_time;comment:'创建时间';NOT NULL" json:"createTime"`                // 创建时间
+	ID                    uint        `gorm:"column:id;comment:'主键';primaryKey;NOT NULL" json:"id"`                                              //ID
+	PostId                int         `gorm:"column:post_id;comment:'岗位id'" json:"postId"`                                                       // 岗位id
+	DeptId                int         `gorm:"column:dept_id;comment:'部门id'" json:"deptId"`                                                       // 部门id
+	Username              string      `gorm:"column:username;varchar(64);comment:'用户账号';NOT NULL" json:"username"`                               // 用户账号
+	Password              string      `gorm:"column:password;varchar(64);comment:'密码';NOT NULL" json:"password"`                                 // 密码
+	Nickname              string      `gorm:"column:nickname;varchar(64);comment:'昵称'" json:"nickname"`                                          // 昵称
+	Status                int         `gorm:"column:status;default:1;comment:'帐号启用状态:1->启用,2->禁用';NOT NULL" json:"status"`                       // 帐号启用状态：1->启用,2->禁用
+	Icon                  string      `gorm:"column:icon;varchar(500);comment:'头像'" json:"icon"`                                                 //  头像
+	Email                 string      `gorm:"column:email;varchar(64);comment:'邮箱'" json:"email"`                                                // 邮箱
+	Phone                 string      `gorm:"column:phone;varchar(64);comment:'电话'" json:"phone"`                                                // 电话
+	Note                  string      `gorm:"column:note;varchar(500);comment:'备注'" json:"note"`                                                 // 备注
+	CreateTime            util.HTime  `gorm:"column:create_time;comment:'创建时间';NOT NULL" json:"createTime"`                                      // 创建时间
+	Source                string      `gorm:"column:source;type:varchar(32);default:'local';comment:'账号来源:local,ldap,oidc:{提供者}'" json:"source"` // 账号来源
+	PasswordUpdateTime    *util.HTime `gorm:"column:password_update_time;comment:'密码修改时间'" json:"passwordUpdateTime"`                            // 密码修改时间，用于计算密码过期
+	PasswordResetRequired bool        `gorm:"column:password_reset_required;default:false;comment:'下次登录需修改密码'" json:"passwordResetRequired"`     // 下次登录需修改密码
 }
 
+// 账号来源
+const (
+	SysAdminSourceLocal = "local" // 本地账号
+	SysAdminSourceLdap  = "ldap"  // LDAP/AD 账号
+	SysAdminSourceOidc  = "oidc:" // OIDC 单点登录账号前缀，完整来源为 oidc:{提供者标识}
+)
+
 func (SysAdmin) TableName() string {
 	return "sys_admin"
 }
 
 // 鉴权用户结构体
 type JwtAdmin struct {
-	ID       uint   `json:"id"`      //ID
-	Username string `json:"username"` //用户名
-	Nickname string `json:"nickname"` //昵称
-	Icon     string `json:"icon"`     //头像
-	Email    string `json:"email"`    //邮箱
-	Phone    string `json:"phone"`    //电话
-	Note     string `json:"note"`     //备注
+	ID        uint   `json:"id"`            //ID
+	Username  string `json:"username"`      //用户名
+	Nickname  string `json:"nickname"`      //昵称
+	Icon      string `json:"icon"`          //头像
+	Email     string `json:"email"`         //邮箱
+	Phone     string `json:"phone"`         //电话
+	Note      string `json:"note"`          //备注
+	SessionId string `json:"sid,omitempty"` //登录会话id，个人访问令牌为空
+	PwdChange bool   `json:"pcr,omitempty"` //密码已过期或被要求修改，修改密码前只能访问修改密码和退出登录接口
 }
 
 // 登录对象
@@ -45,18 +57,24 @@ type LoginDto struct {
 	IdKey    string `json:"idKey" validate:"required"`             //uuid
 }
 
+// 单点登录换取登录结果参数
+type SsoExchangeDto struct {
+	Code string `json:"code" validate:"required"` // 单点登录回调跳转前端时携带的一次性登录码
+}
+
 // AddSysAdminDto 新增参数
 type AddSysAdminDto struct {
-	PostId   int    `validate:"required"` // 岗位id
-	RoleId   uint   `validate:"required"` // 角色id
-	DeptId   int    `validate:"required"` // 部门id
-	Username string `validate:"required"` // 用户名
-	Password string `validate:"required"` // 密码
-	Nickname string `validate:"required"` // 昵称
-	Phone    string `validate:"required"` // 手机号
-	Email    string `validate:"required"` // 邮箱
-	Note     string // 备注
-	Status   int    `validate:"required"` // 状态：1->启用,2->禁用
+	PostId              int    `validate:"required"` // 岗位id
+	RoleId              uint   `validate:"required"` // 角色id
+	DeptId              int    `validate:"required"` // 部门id
+	Username            string `validate:"required"` // 用户名
+	Password            string `validate:"required"` // 密码
+	Nickname            string `validate:"required"` // 昵称
+	Phone               string `validate:"required"` // 手机号
+	Email               string `validate:"required"` // 邮箱
+	Note                string // 备注
+	Status              int    `validate:"required"` // 状态：1->启用,2->禁用
+	ForceChangePassword bool   // 首次登录是否强制修改密码
 }
 
 // 详情视图
@@ -100,8 +118,10 @@ type UpdateSysAdminStatusDto struct {
 
 // 重置密码参数
 type ResetSysAdminPasswordDto struct {
-	Id       uint   // ID
-	Password string //密码
+	Id                  uint   // ID
+	Password            string //密码，为空时不修改
+	ResetMfa            bool   // 是否同时重置二次验证，用户丢失认证器时使用
+	ForceChangePassword bool   // 下次登录是否强制修改密码
 }
 
 // 用户列表的vo视图
@@ -0,0 +1,60 @@
+// 二次验证相关模型
+// author xiaoRui
+
+package model
+
+import (
+	"dodevops-api/common/util"
+	"dodevops-api/pkg/envelope"
+)
+
+func init() {
+	envelope.RegisterColumns("sys_admin_mfa", envelope.LegacyCBC, "secret")
+}
+
+// 用户二次验证（TOTP）配置，密钥加密保存，恢复码仅保存哈希值
+type SysAdminMfa struct {
+	ID            uint        `gorm:"column:id;comment:'主键';primaryKey;NOT NULL" json:"id"`                       // ID
+	AdminId       uint        `gorm:"column:admin_id;comment:'用户id';uniqueIndex;NOT NULL" json:"adminId"`         // 用户id
+	Secret        string      `gorm:"column:secret;type:varchar(255);comment:'TOTP密钥(加密)';NOT NULL" json:"-"`     // TOTP密钥
+	Enabled       bool        `gorm:"column:enabled;default:false;comment:'是否已启用';NOT NULL" json:"enabled"`       // 是否已启用，绑定后需校验一次验证码才会启用
+	RecoveryCodes string      `gorm:"column:recovery_codes;type:text;comment:'恢复码SHA256哈希，逗号分隔'" json:"-"`        // 恢复码哈希
+	LastStep      int64       `gorm:"column:last_step;default:0;comment:'最后一次使用的验证码时间窗口，防止重放';NOT NULL" json:"-"` // 最后使用的时间窗口
+	EnableTime    *util.HTime `gorm:"column:enable_time;comment:'启用时间'" json:"enableTime"`                        // 启用时间
+	CreateTime    util.HTime  `gorm:"column:create_time;comment:'创建时间';NOT NULL" json:"createTime"`               // 创建时间
+}
+
+func (SysAdminMfa) TableName() string {
+	return "sys_admin_mfa"
+}
+
+// 验证码参数
+type SysAdminMfaCodeDto struct {
+	Code string `json:"code" validate:"required"` // 认证器中的6位验证码或恢复码
+}
+
+// 登录二次验证参数
+type MfaLoginDto struct {
+	Ticket string `json:"ticket" validate:"required"` // 密码校验通过后返回的二次验证票据
+	Code   string `json:"code" validate:"required"`   // 认证器中的6位验证码或恢复码
+}
+
+// 登录时绑定认证器参数
+type MfaTicketDto struct {
+	Ticket string `json:"ticket" validate:"required"` // 密码校验通过后返回的二次验证票据
+}
+
+// 绑定认证器返回值
+type SysAdminMfaEnrollVo struct {
+	Secret string `json:"secret"` // Base32 密钥，无法扫码时手动输入
+	Url    string `json:"url"`    // otpauth:// 配置地址
+	QrCode string `json:"qrCode"` // 配置地址二维码，data:image/png;base64 格式
+}
+
+// 二次验证状态
+type SysAdminMfaStatusVo struct {
+	Enabled           bool        `json:"enabled"`           // 是否已启用
+	Required          bool        `json:"required"`          // 所属角色是否强制启用
+	RecoveryCodesLeft int         `json:"recoveryCodesLeft"` // 剩余可用恢复码数量
+	EnableTime        *util.HTime `json:"enableTime"`        // 启用时间
+}
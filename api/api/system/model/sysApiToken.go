@@ -0,0 +1,52 @@
+// 个人访问令牌相关模型
+// author xiaoRui
+
+package model
+
+import "dodevops-api/common/util"
+
+// 个人访问令牌，供脚本/CI 调用接口使用，仅保存令牌的哈希值
+type SysApiToken struct {
+	ID           uint        `gorm:"column:id;comment:'主键';primaryKey;NOT NULL" json:"id"`                                  // ID
+	AdminId      uint        `gorm:"column:admin_id;comment:'所属用户id';index;NOT NULL" json:"adminId"`                        // 所属用户id
+	Name         string      `gorm:"column:name;type:varchar(64);comment:'令牌名称';NOT NULL" json:"name"`                      // 令牌名称
+	TokenPrefix  string      `gorm:"column:token_prefix;type:varchar(16);comment:'令牌前缀，用于识别';NOT NULL" json:"tokenPrefix"`  // 令牌前缀
+	TokenHash    string      `gorm:"column:token_hash;type:varchar(64);comment:'令牌SHA256哈希';uniqueIndex;NOT NULL" json:"-"` // 令牌哈希
+	Scopes       string      `gorm:"column:scopes;type:text;comment:'授权范围，逗号分隔的权限值'" json:"-"`                              // 授权范围
+	ExpireTime   *util.HTime `gorm:"column:expire_time;comment:'过期时间，为空表示永不过期'" json:"expireTime"`                          // 过期时间
+	LastUsedTime *util.HTime `gorm:"column:last_used_time;comment:'最后使用时间'" json:"lastUsedTime"`                            // 最后使用时间
+	LastUsedIp   string      `gorm:"column:last_used_ip;type:varchar(64);comment:'最后使用IP'" json:"lastUsedIp"`               // 最后使用IP
+	CreateTime   util.HTime  `gorm:"column:create_time;comment:'创建时间';NOT NULL" json:"createTime"`                          // 创建时间
+}
+
+func (SysApiToken) TableName() string {
+	return "sys_api_token"
+}
+
+// 个人访问令牌前缀，用于和 JWT 区分
+const ApiTokenPrefix = "atk_"
+
+// 新增令牌参数
+type AddSysApiTokenDto struct {
+	Name       string   `json:"name" validate:"required"`         // 令牌名称
+	Scopes     []string `json:"scopes" validate:"required,min=1"` // 授权范围，必须是当前用户拥有的权限值
+	ExpireDays int      `json:"expireDays" validate:"min=0"`      // 有效天数，0 表示永不过期
+}
+
+// 令牌id参数
+type SysApiTokenIdDto struct {
+	Id uint `json:"id" validate:"required"` // ID
+}
+
+// 令牌列表视图
+type SysApiTokenVo struct {
+	SysApiToken
+	Scopes  []string `json:"scopes"`  // 授权范围
+	Expired bool     `json:"expired"` // 是否已过期
+}
+
+// 新建令牌返回值，明文令牌仅在创建时返回一次
+type SysApiTokenCreatedVo struct {
+	SysApiTokenVo
+	Token string `json:"token"` // 明文令牌
+}
@@ -0,0 +1,127 @@
+// 审批流程相关模型
+// author xiaoRui
+
+package model
+
+import (
+	"dodevops-api/common/util"
+	"dodevops-api/pkg/envelope"
+)
+
+func init() {
+	envelope.RegisterColumns("sys_approval_ticket", envelope.LegacyPlain, "body", "result")
+}
+
+// 审批单状态
+const (
+	ApprovalStatusPending  = 1 // 待审批
+	ApprovalStatusApproved = 2 // 已通过，执行中
+	ApprovalStatusRejected = 3 // 已拒绝
+	ApprovalStatusCanceled = 4 // 已撤销
+	ApprovalStatusSuccess  = 5 // 执行成功
+	ApprovalStatusFailed   = 6 // 执行失败
+)
+
+// 审批记录动作
+const (
+	ApprovalActionComment = 1 // 评论
+	ApprovalActionApprove = 2 // 同意
+	ApprovalActionReject  = 3 // 拒绝
+	ApprovalActionCancel  = 4 // 撤销
+)
+
+// 审批策略：命中的接口请求需要指定角色的审批人审批通过后才会执行
+type SysApprovalPolicy struct {
+	ID                uint       `gorm:"column:id;comment:'主键';primaryKey;NOT NULL" json:"id"`                                     // ID
+	Name              string     `gorm:"column:name;type:varchar(64);comment:'策略名称';NOT NULL" json:"name"`                         // 策略名称
+	Method            string     `gorm:"column:method;type:varchar(16);comment:'请求方式';NOT NULL" json:"method"`                     // 请求方式
+	Path              string     `gorm:"column:path;type:varchar(255);comment:'路由模板';NOT NULL" json:"path"`                        // 路由模板，如 /api/v1/k8s/cluster/:id
+	Condition         string     `gorm:"column:condition_name;type:varchar(64);comment:'审批条件'" json:"condition"`                   // 审批条件，为空表示全部请求
+	ApproverRoleIds   string     `gorm:"column:approver_role_ids;type:varchar(255);comment:'审批人角色id，逗号分隔'" json:"approverRoleIds"` // 审批人角色id，逗号分隔，为空时只有超级管理员可以审批
+	RequiredApprovals int        `gorm:"column:required_approvals;default:1;comment:'需要的审批人数'" json:"requiredApprovals"`           // 需要的审批人数
+	DingDingUrl       string     `gorm:"column:dingding_url;type:varchar(500);comment:'钉钉通知地址'" json:"dingDingUrl"`                // 钉钉通知地址
+	FeiShuUrl         string     `gorm:"column:feishu_url;type:varchar(500);comment:'飞书通知地址'" json:"feiShuUrl"`                    // 飞书通知地址
+	Emails            string     `gorm:"column:emails;type:varchar(500);comment:'通知邮箱，逗号分隔'" json:"emails"`                        // 通知邮箱，逗号分隔
+	Status            int        `gorm:"column:status;default:1;comment:'状态：1->启用,2->禁用';NOT NULL" json:"status"`                  // 状态：1->启用,2->禁用
+	Remark            string     `gorm:"column:remark;type:varchar(500);comment:'备注'" json:"remark"`                               // 备注
+	CreateTime        util.HTime `gorm:"column:create_time;comment:'创建时间';NOT NULL" json:"createTime"`                             // 创建时间
+}
+
+func (SysApprovalPolicy) TableName() string {
+	return "sys_approval_policy"
+}
+
+// 审批单：保存被拦截的原始请求，审批通过后以申请人身份重放
+type SysApprovalTicket struct {
+	ID                uint        `gorm:"column:id;comment:'主键';primaryKey;NOT NULL" json:"id"`                                // ID
+	PolicyId          uint        `gorm:"column:policy_id;index;comment:'审批策略id';NOT NULL" json:"policyId"`                    // 审批策略id
+	Title             string      `gorm:"column:title;type:varchar(255);comment:'标题'" json:"title"`                            // 标题
+	Method            string      `gorm:"column:method;type:varchar(16);comment:'请求方式';NOT NULL" json:"method"`                // 请求方式
+	Path              string      `gorm:"column:path;type:varchar(255);comment:'路由模板'" json:"path"`                            // 路由模板
+	Url               string      `gorm:"column:url;type:varchar(1000);comment:'请求地址';NOT NULL" json:"url"`                    // 请求地址，包含查询参数
+	ContentType       string      `gorm:"column:content_type;type:varchar(128);comment:'请求体类型'" json:"contentType"`            // 请求体类型
+	Body              string      `gorm:"column:body;type:longtext;serializer:encrypt;comment:'请求体(加密)'" json:"body"`          // 请求体
+	Reason            string      `gorm:"column:reason;type:varchar(500);comment:'申请原因'" json:"reason"`                        // 申请原因
+	ApplicantId       uint        `gorm:"column:applicant_id;index;comment:'申请人id';NOT NULL" json:"applicantId"`               // 申请人id
+	ApplicantName     string      `gorm:"column:applicant_name;type:varchar(64);comment:'申请人账号'" json:"applicantName"`         // 申请人账号
+	ApproverRoleIds   string      `gorm:"column:approver_role_ids;type:varchar(255);comment:'审批人角色id'" json:"approverRoleIds"` // 审批人角色id，提交时从策略复制
+	RequiredApprovals int         `gorm:"column:required_approvals;comment:'需要的审批人数'" json:"requiredApprovals"`                // 需要的审批人数
+	ApprovedCount     int         `gorm:"column:approved_count;default:0;comment:'已同意人数'" json:"approvedCount"`                // 已同意人数
+	Status            int         `gorm:"column:status;index;default:1;comment:'状态'" json:"status"`                            // 状态：1->待审批,2->已通过,3->已拒绝,4->已撤销,5->执行成功,6->执行失败
+	ResultCode        int         `gorm:"column:result_code;comment:'执行结果状态码'" json:"resultCode"`                              // 执行结果状态码
+	Result            string      `gorm:"column:result;type:longtext;serializer:encrypt;comment:'执行结果(加密)'" json:"result"`     // 执行结果
+	CreateTime        util.HTime  `gorm:"column:create_time;comment:'创建时间';NOT NULL" json:"createTime"`                        // 创建时间
+	ExecuteTime       *util.HTime `gorm:"column:execute_time;comment:'执行时间'" json:"executeTime"`                               // 执行时间
+}
+
+func (SysApprovalTicket) TableName() string {
+	return "sys_approval_ticket"
+}
+
+// 审批记录：审批意见与评论
+type SysApprovalComment struct {
+	ID         uint       `gorm:"column:id;comment:'主键';primaryKey;NOT NULL" json:"id"`            // ID
+	TicketId   uint       `gorm:"column:ticket_id;index;comment:'审批单id';NOT NULL" json:"ticketId"` // 审批单id
+	AdminId    uint       `gorm:"column:admin_id;comment:'用户id';NOT NULL" json:"adminId"`          // 用户id
+	Username   string     `gorm:"column:username;type:varchar(64);comment:'用户账号'" json:"username"` // 用户账号
+	Action     int        `gorm:"column:action;comment:'动作';NOT NULL" json:"action"`               // 动作：1->评论,2->同意,3->拒绝,4->撤销
+	Content    string     `gorm:"column:content;type:varchar(1000);comment:'内容'" json:"content"`   // 内容
+	CreateTime util.HTime `gorm:"column:create_time;comment:'创建时间';NOT NULL" json:"createTime"`    // 创建时间
+}
+
+func (SysApprovalComment) TableName() string {
+	return "sys_approval_comment"
+}
+
+// 新增/修改审批策略参数
+type SysApprovalPolicyDto struct {
+	Id                uint   `json:"id"`                // ID，修改时必填
+	Name              string `json:"name"`              // 策略名称
+	Method            string `json:"method"`            // 请求方式
+	Path              string `json:"path"`              // 路由模板
+	Condition         string `json:"condition"`         // 审批条件
+	ApproverRoleIds   []uint `json:"approverRoleIds"`   // 审批人角色id
+	RequiredApprovals int    `json:"requiredApprovals"` // 需要的审批人数
+	DingDingUrl       string `json:"dingDingUrl"`       // 钉钉通知地址
+	FeiShuUrl         string `json:"feiShuUrl"`         // 飞书通知地址
+	Emails            string `json:"emails"`            // 通知邮箱，逗号分隔
+	Status            int    `json:"status"`            // 状态：1->启用,2->禁用
+	Remark            string `json:"remark"`            // 备注
+}
+
+// 审批策略id参数
+type SysApprovalPolicyIdDto struct {
+	Id uint `json:"id"` // ID
+}
+
+// 审批单操作参数
+type SysApprovalTicketActionDto struct {
+	Id      uint   `json:"id"`      // 审批单id
+	Content string `json:"content"` // 审批意见或评论内容
+}
+
+// 审批单详情
+type SysApprovalTicketVo struct {
+	SysApprovalTicket
+	Comments []SysApprovalComment `json:"comments"` // 审批记录
+}
@@ -0,0 +1,71 @@
+// 终端命令规则相关模型
+// author xiaoRui
+
+package model
+
+import "dodevops-api/common/util"
+
+// 命令匹配方式
+const (
+	CommandMatchPrefix = 1 // 前缀匹配
+	CommandMatchRegex  = 2 // 正则匹配
+)
+
+// 命令规则动作
+const (
+	CommandRuleAllow   = 1 // 放行
+	CommandRuleDeny    = 2 // 拦截
+	CommandRuleConfirm = 3 // 需要确认
+)
+
+// 终端命令规则：Web终端（主机SSH、容器）中执行的命令按优先级依次匹配，命中第一条规则后按规则动作处理，
+// 未命中任何规则的命令放行，超级管理员不受限制
+type SysCommandRule struct {
+	ID         uint       `gorm:"column:id;comment:'主键';primaryKey;NOT NULL" json:"id"`                       // ID
+	Name       string     `gorm:"column:name;type:varchar(64);comment:'规则名称';NOT NULL" json:"name"`           // 规则名称
+	MatchType  int        `gorm:"column:match_type;comment:'匹配方式:1->前缀,2->正则';NOT NULL" json:"matchType"`     // 匹配方式:1->前缀,2->正则
+	Pattern    string     `gorm:"column:pattern;type:varchar(500);comment:'匹配内容';NOT NULL" json:"pattern"`    // 匹配内容
+	Action     int        `gorm:"column:action;comment:'动作:1->放行,2->拦截,3->需要确认';NOT NULL" json:"action"`      // 动作:1->放行,2->拦截,3->需要确认
+	RoleIds    string     `gorm:"column:role_ids;type:varchar(255);comment:'适用角色id，逗号分隔'" json:"roleIds"`     // 适用角色id，逗号分隔，为空表示全部角色
+	GroupIds   string     `gorm:"column:group_ids;type:varchar(255);comment:'适用资产分组id，逗号分隔'" json:"groupIds"` // 适用资产分组id（包含子分组），逗号分隔，为空表示全部主机和容器
+	Priority   int        `gorm:"column:priority;default:100;comment:'优先级，数值小的先匹配'" json:"priority"`          // 优先级，数值小的先匹配
+	Status     int        `gorm:"column:status;default:1;comment:'状态：1->启用,2->禁用';NOT NULL" json:"status"`    // 状态：1->启用,2->禁用
+	Remark     string     `gorm:"column:remark;type:varchar(500);comment:'备注'" json:"remark"`                 // 备注
+	CreateTime util.HTime `gorm:"column:create_time;comment:'创建时间';NOT NULL" json:"createTime"`               // 创建时间
+}
+
+func (SysCommandRule) TableName() string {
+	return "sys_command_rule"
+}
+
+// 新增/修改命令规则参数
+type SysCommandRuleDto struct {
+	Id        uint   `json:"id"`        // ID，修改时必填
+	Name      string `json:"name"`      // 规则名称
+	MatchType int    `json:"matchType"` // 匹配方式:1->前缀,2->正则
+	Pattern   string `json:"pattern"`   // 匹配内容
+	Action    int    `json:"action"`    // 动作:1->放行,2->拦截,3->需要确认
+	RoleIds   []uint `json:"roleIds"`   // 适用角色id，为空表示全部角色
+	GroupIds  []uint `json:"groupIds"`  // 适用资产分组id，为空表示全部主机和容器
+	Priority  int    `json:"priority"`  // 优先级，数值小的先匹配
+	Status    int    `json:"status"`    // 状态：1->启用,2->禁用
+	Remark    string `json:"remark"`    // 备注
+}
+
+// 命令规则id参数
+type SysCommandRuleIdDto struct {
+	Id uint `json:"id"` // ID
+}
+
+// 命令规则检查参数
+type SysCommandRuleCheckDto struct {
+	Command string `json:"command"` // 命令
+	RoleIds []uint `json:"roleIds"` // 用户角色id
+	GroupId uint   `json:"groupId"` // 主机所在分组id，容器终端为0
+}
+
+// 命令规则检查结果
+type SysCommandRuleCheckVo struct {
+	Action int             `json:"action"` // 动作:1->放行,2->拦截,3->需要确认
+	Rule   *SysCommandRule `json:"rule"`   // 命中的规则，未命中时为空
+}
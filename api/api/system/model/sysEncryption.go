@@ -0,0 +1,16 @@
+// 敏感字段加密相关模型
+// author xiaoRui
+
+package model
+
+import "dodevops-api/pkg/envelope"
+
+// 敏感字段加密状态
+type SysEncryptionStatusVo struct {
+	ActiveVersion int                     `json:"activeVersion"` // 当前加密使用的主密钥版本
+	SecretBackend string                  `json:"secretBackend"` // 当前凭证存储后端
+	KeyVersions   []int                   `json:"keyVersions"`   // 已加载的主密钥版本
+	Rotating      bool                    `json:"rotating"`      // 是否正在重新加密
+	LastRotate    *envelope.RotateResult  `json:"lastRotate"`    // 最近一次重新加密结果
+	Columns       []envelope.ColumnStatus `json:"columns"`       // 各字段的主密钥版本分布，版本 0 表示历史数据
+}
@@ -7,14 +7,17 @@ import "dodevops-api/common/util"
 
 // 操作日志
 type SysOperationLog struct {
-	ID          uint       `gorm:"column:id;comment:'主键';primaryKey;NOT NULL" json:"id"`                         // ID
-	AdminId     uint       `gorm:"column:admin_id;comment:'管理员id';NOT NULL" json:"adminId"`                      // 管理员id
-	Username    string     `gorm:"column:username;type:varchar(64);comment:'管理员账号';NOT NULL" json:"username"`    // 管理员账号
-	Method      string     `gorm:"column:method;type:varchar(64);comment:'请求方式';NOT NULL" json:"method"`          // 请求方式
-	Ip          string     `gorm:"column:ip;type:varchar(64);comment:'IP'" json:"ip"`                             // IP
-	Url         string     `gorm:"column:url;type:varchar(500);comment:'URL'" json:"url"`                         // URL
-	Description string     `gorm:"column:description;type:varchar(255);comment:'操作描述'" json:"description"`        // 操作描述
-	CreateTime  util.HTime `gorm:"column:create_time;comment:'创建时间';NOT NULL" json:"createTime"`                 // 创建时间
+	ID          uint       `gorm:"column:id;comment:'主键';primaryKey;NOT NULL" json:"id"`                      // ID
+	AdminId     uint       `gorm:"column:admin_id;comment:'管理员id';NOT NULL" json:"adminId"`                   // 管理员id
+	Username    string     `gorm:"column:username;type:varchar(64);comment:'管理员账号';NOT NULL" json:"username"` // 管理员账号
+	Method      string     `gorm:"column:method;type:varchar(64);comment:'请求方式';NOT NULL" json:"method"`      // 请求方式
+	Ip          string     `gorm:"column:ip;type:varchar(64);comment:'IP'" json:"ip"`                         // IP
+	Url         string     `gorm:"column:url;type:varchar(500);comment:'URL'" json:"url"`                     // URL
+	Description string     `gorm:"column:description;type:varchar(255);comment:'操作描述'" json:"description"`    // 操作描述
+	CreateTime  util.HTime `gorm:"column:create_time;comment:'创建时间';NOT NULL" json:"createTime"`              // 创建时间
+	Seq         *uint64    `gorm:"column:seq;uniqueIndex;comment:'审计序号'" json:"seq"`                          // 审计序号，历史日志为空
+	PrevHash    string     `gorm:"column:prev_hash;type:varchar(64);comment:'上一条日志哈希'" json:"prevHash"`       // 上一条日志哈希
+	Hash        string     `gorm:"column:hash;type:varchar(64);comment:'日志哈希'" json:"hash"`                   // 日志哈希
 }
 
 func (SysOperationLog) TableName() string {
@@ -30,3 +33,57 @@ type SysOperationLogIdDto struct {
 type BatchDeleteSysOperationLogDto struct {
 	Ids []uint //id列表
 }
+
+// 操作日志查询参数
+type SysOperationLogQueryDto struct {
+	Username  string `form:"username"`  // 用户名
+	Method    string `form:"method"`    // 请求方式
+	Url       string `form:"url"`       // URL关键字
+	BeginTime string `form:"beginTime"` // 开始时间
+	EndTime   string `form:"endTime"`   // 结束时间
+	Format    string `form:"format"`    // 导出格式：csv、jsonl，默认 csv
+}
+
+// 操作日志归档记录，保存归档文件与哈希链的衔接信息
+type SysOperationLogArchive struct {
+	ID            uint       `gorm:"column:id;comment:'主键';primaryKey;NOT NULL" json:"id"`                              // ID
+	FileName      string     `gorm:"column:file_name;type:varchar(255);comment:'归档文件';NOT NULL" json:"fileName"`        // 归档文件
+	FileHash      string     `gorm:"column:file_hash;type:varchar(64);comment:'归档文件SHA256';NOT NULL" json:"fileHash"`   // 归档文件SHA256
+	StartSeq      uint64     `gorm:"column:start_seq;comment:'起始审计序号'" json:"startSeq"`                                 // 起始审计序号
+	EndSeq        uint64     `gorm:"column:end_seq;index;comment:'结束审计序号'" json:"endSeq"`                               // 结束审计序号
+	FirstPrevHash string     `gorm:"column:first_prev_hash;type:varchar(64);comment:'起始日志的上一条哈希'" json:"firstPrevHash"` // 起始日志的上一条哈希
+	LastHash      string     `gorm:"column:last_hash;type:varchar(64);comment:'最后一条日志哈希'" json:"lastHash"`              // 最后一条日志哈希
+	Count         int64      `gorm:"column:count;comment:'归档条数'" json:"count"`                                          // 归档条数
+	CreateTime    util.HTime `gorm:"column:create_time;comment:'归档时间';NOT NULL" json:"createTime"`                      // 归档时间
+}
+
+func (SysOperationLogArchive) TableName() string {
+	return "sys_operation_log_archive"
+}
+
+// 操作日志哈希链头，只有一行，追加日志时在事务中锁定该行以串行化写入
+type SysOperationLogChain struct {
+	ID       uint   `gorm:"column:id;comment:'主键';primaryKey;autoIncrement:false;NOT NULL" json:"id"` // ID
+	LastSeq  uint64 `gorm:"column:last_seq;comment:'最后一条日志的审计序号'" json:"lastSeq"`                     // 最后一条日志的审计序号
+	LastHash string `gorm:"column:last_hash;type:varchar(64);comment:'最后一条日志哈希'" json:"lastHash"`     // 最后一条日志哈希
+}
+
+func (SysOperationLogChain) TableName() string {
+	return "sys_operation_log_chain"
+}
+
+// 哈希链校验问题
+type SysOperationLogProblem struct {
+	Seq    uint64 `json:"seq"`    // 审计序号
+	Id     uint   `json:"id"`     // 日志id，归档问题为归档记录id
+	Reason string `json:"reason"` // 问题描述
+}
+
+// 哈希链校验结果
+type SysOperationLogVerifyVo struct {
+	Valid     bool                     `json:"valid"`     // 是否完整
+	Checked   int64                    `json:"checked"`   // 校验的日志条数
+	Archives  int                      `json:"archives"`  // 校验的归档文件数
+	Unchained int64                    `json:"unchained"` // 启用哈希链前的历史日志条数
+	Problems  []SysOperationLogProblem `json:"problems"`  // 发现的问题，最多返回100条
+}
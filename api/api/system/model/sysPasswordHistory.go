@@ -0,0 +1,23 @@
+// 密码策略相关模型
+// author xiaoRui
+
+package model
+
+import "dodevops-api/common/util"
+
+// 历史密码，保存用户最近使用过的密码哈希，用于限制重复使用
+type SysPasswordHistory struct {
+	ID         uint       `gorm:"column:id;comment:'主键';primaryKey;NOT NULL" json:"id"`              // ID
+	AdminId    uint       `gorm:"column:admin_id;comment:'用户id';index;NOT NULL" json:"adminId"`      // 用户id
+	Password   string     `gorm:"column:password;type:varchar(64);comment:'密码哈希';NOT NULL" json:"-"` // 密码哈希
+	CreateTime util.HTime `gorm:"column:create_time;comment:'修改时间';NOT NULL" json:"createTime"`      // 修改时间
+}
+
+func (SysPasswordHistory) TableName() string {
+	return "sys_password_history"
+}
+
+// 解除登录锁定参数
+type UnlockSysAdminDto struct {
+	Id uint `json:"id" validate:"required"` // 用户id
+}
@@ -0,0 +1,28 @@
+// 角色数据权限相关模型
+// author xiaoRui
+
+package model
+
+// SysRoleGroup 角色与资产分组关系模型，数据范围为自定义分组时使用
+type SysRoleGroup struct {
+	RoleId  uint `gorm:"column:role_id;comment:'角色id';index;NOT NULL" json:"roleId"` // 角色id
+	GroupId uint `gorm:"column:group_id;comment:'资产分组id';NOT NULL" json:"groupId"`   // 资产分组id
+}
+
+func (SysRoleGroup) TableName() string {
+	return "sys_role_group"
+}
+
+// 设置角色数据范围参数
+type SysRoleDataScopeDto struct {
+	Id        uint   `json:"id"`        // 角色id
+	DataScope int    `json:"dataScope"` // 数据范围：1->全部,2->自定义分组,3->本部门,4->本部门及以下
+	GroupIds  []uint `json:"groupIds"`  // 自定义分组id列表，数据范围为2时生效
+}
+
+// 角色数据范围视图
+type SysRoleDataScopeVo struct {
+	Id        uint   `json:"id"`        // 角色id
+	DataScope int    `json:"dataScope"` // 数据范围
+	GroupIds  []uint `json:"groupIds"`  // 自定义分组id列表
+}
@@ -0,0 +1,41 @@
+// 登录会话相关模型
+// author xiaoRui
+
+package model
+
+import "dodevops-api/common/util"
+
+// 登录会话，保存在 redis 中，删除后该会话的访问令牌和刷新令牌立即失效
+type SysSession struct {
+	SessionId      string     `json:"sessionId"`      // 会话id
+	AdminId        uint       `json:"adminId"`        // 用户id
+	Username       string     `json:"username"`       // 用户账号
+	IpAddress      string     `json:"ipAddress"`      // 登录IP地址
+	LoginLocation  string     `json:"loginLocation"`  // 登录地点
+	Browser        string     `json:"browser"`        // 浏览器类型
+	Os             string     `json:"os"`             // 操作系统
+	LoginTime      util.HTime `json:"loginTime"`      // 登录时间
+	LastActiveTime util.HTime `json:"lastActiveTime"` // 最后续期时间
+}
+
+// 会话列表视图
+type SysSessionVo struct {
+	SysSession
+	Current bool `json:"current"` // 是否为当前请求使用的会话
+}
+
+// 刷新令牌参数
+type RefreshTokenDto struct {
+	RefreshToken string `json:"refreshToken" validate:"required"` // 登录时返回的刷新令牌
+}
+
+// 会话id参数
+type SysSessionIdDto struct {
+	SessionId string `json:"sessionId" validate:"required"` // 会话id
+}
+
+// 强制下线参数
+type ForceLogoutDto struct {
+	AdminId   uint   `json:"adminId" validate:"required"` // 用户id
+	SessionId string `json:"sessionId"`                   // 会话id，为空时下线该用户全部会话
+}
@@ -0,0 +1,138 @@
+// 终端会话审计相关模型
+// author xiaoRui
+
+package model
+
+import "dodevops-api/common/util"
+
+// 终端类型
+const (
+	TerminalKindHost = 1 // 主机SSH终端
+	TerminalKindPod  = 2 // 容器终端
+)
+
+// 终端会话状态
+const (
+	TerminalStatusActive      = 1 // 进行中
+	TerminalStatusFinished    = 2 // 已结束
+	TerminalStatusInterrupted = 3 // 服务重启等原因异常中断
+	TerminalStatusTerminated  = 4 // 被管理员终止
+)
+
+// 终端会话，录像按 asciicast v2 格式保存在录像存储后端
+type SysTerminalSession struct {
+	ID           uint        `gorm:"column:id;comment:'主键';primaryKey;NOT NULL" json:"id"`                                    // ID
+	SessionId    string      `gorm:"column:session_id;type:varchar(64);uniqueIndex;comment:'会话标识';NOT NULL" json:"sessionId"` // 会话标识
+	Kind         int         `gorm:"column:kind;comment:'终端类型:1->主机SSH,2->容器';NOT NULL" json:"kind"`                          // 终端类型:1->主机SSH,2->容器
+	TargetId     uint        `gorm:"column:target_id;index;comment:'主机ID或集群ID'" json:"targetId"`                              // 主机ID或集群ID
+	TargetName   string      `gorm:"column:target_name;type:varchar(255);comment:'主机名称或命名空间/Pod/容器'" json:"targetName"`       // 主机名称或命名空间/Pod/容器
+	Address      string      `gorm:"column:address;type:varchar(255);comment:'连接地址'" json:"address"`                          // 连接地址
+	AdminId      uint        `gorm:"column:admin_id;index;comment:'用户ID'" json:"adminId"`                                     // 用户ID
+	Username     string      `gorm:"column:username;type:varchar(64);comment:'用户账号'" json:"username"`                         // 用户账号
+	Ip           string      `gorm:"column:ip;type:varchar(64);comment:'客户端IP'" json:"ip"`                                    // 客户端IP
+	Width        int         `gorm:"column:width;comment:'终端宽度'" json:"width"`                                                // 终端宽度
+	Height       int         `gorm:"column:height;comment:'终端高度'" json:"height"`                                              // 终端高度
+	Backend      string      `gorm:"column:backend;type:varchar(16);comment:'录像存储后端'" json:"backend"`                         // 录像存储后端
+	StorageKey   string      `gorm:"column:storage_key;type:varchar(255);comment:'录像路径'" json:"-"`                            // 录像路径
+	Size         int64       `gorm:"column:size;comment:'录像大小(字节)'" json:"size"`                                              // 录像大小(字节)
+	Duration     float64     `gorm:"column:duration;comment:'会话时长(秒)'" json:"duration"`                                       // 会话时长(秒)
+	CommandCount int         `gorm:"column:command_count;comment:'命令数'" json:"commandCount"`                                  // 命令数
+	Status       int         `gorm:"column:status;comment:'状态:1->进行中,2->已结束,3->异常中断,4->被终止';NOT NULL" json:"status"`          // 状态:1->进行中,2->已结束,3->异常中断,4->被终止
+	StartTime    util.HTime  `gorm:"column:start_time;index;comment:'开始时间';NOT NULL" json:"startTime"`                        // 开始时间
+	EndTime      *util.HTime `gorm:"column:end_time;comment:'结束时间'" json:"endTime"`                                           // 结束时间
+	TerminatedBy string      `gorm:"column:terminated_by;type:varchar(64);comment:'终止会话的管理员'" json:"terminatedBy"`            // 终止会话的管理员
+	Reason       string      `gorm:"column:terminate_reason;type:varchar(500);comment:'终止原因'" json:"reason"`                  // 终止原因
+}
+
+func (SysTerminalSession) TableName() string {
+	return "sys_terminal_session"
+}
+
+// 终端会话中执行的命令，用于命令检索
+type SysTerminalCommand struct {
+	ID         uint       `gorm:"column:id;comment:'主键';primaryKey;NOT NULL" json:"id"`                   // ID
+	SessionId  uint       `gorm:"column:session_id;index;comment:'终端会话ID';NOT NULL" json:"sessionId"`     // 终端会话ID
+	Command    string     `gorm:"column:command;type:varchar(1024);comment:'命令';NOT NULL" json:"command"` // 命令
+	Offset     float64    `gorm:"column:time_offset;comment:'相对会话开始的秒数'" json:"offset"`                   // 相对会话开始的秒数
+	CreateTime util.HTime `gorm:"column:create_time;comment:'执行时间';NOT NULL" json:"createTime"`           // 执行时间
+}
+
+func (SysTerminalCommand) TableName() string {
+	return "sys_terminal_command"
+}
+
+// 开始终端会话参数
+type SysTerminalSessionStartDto struct {
+	Kind       int    // 终端类型
+	TargetId   uint   // 主机ID或集群ID
+	TargetName string // 主机名称或命名空间/Pod/容器
+	Address    string // 连接地址
+	Width      int    // 终端宽度
+	Height     int    // 终端高度
+}
+
+// 终端会话查询参数
+type SysTerminalSessionQueryDto struct {
+	Kind       int    `form:"kind"`       // 终端类型
+	Username   string `form:"username"`   // 用户账号
+	TargetName string `form:"targetName"` // 主机名称或Pod名称关键字
+	Command    string `form:"command"`    // 执行过的命令关键字
+	Status     int    `form:"status"`     // 状态
+	BeginTime  string `form:"beginTime"`  // 开始时间
+	EndTime    string `form:"endTime"`    // 结束时间
+	PageNum    int    `form:"pageNum"`    // 分页数
+	PageSize   int    `form:"pageSize"`   // 每页数
+}
+
+// 终端命令检索参数
+type SysTerminalCommandQueryDto struct {
+	Keyword    string `form:"keyword" validate:"required"` // 命令关键字
+	Kind       int    `form:"kind"`                        // 终端类型
+	Username   string `form:"username"`                    // 用户账号
+	TargetName string `form:"targetName"`                  // 主机名称或Pod名称关键字
+	BeginTime  string `form:"beginTime"`                   // 开始时间
+	EndTime    string `form:"endTime"`                     // 结束时间
+	PageNum    int    `form:"pageNum"`                     // 分页数
+	PageSize   int    `form:"pageSize"`                    // 每页数
+}
+
+// 终端会话id参数
+type SysTerminalSessionIdDto struct {
+	Id uint `json:"id" form:"id" validate:"required"` // 终端会话ID
+}
+
+// 终止终端会话参数
+type SysTerminalSessionTerminateDto struct {
+	Id     uint   `json:"id" validate:"required"`     // 终端会话ID
+	Reason string `json:"reason" validate:"required"` // 终止原因，会显示给终端用户
+}
+
+// 进行中的终端会话
+type SysTerminalSessionActiveVo struct {
+	SysTerminalSession
+	Observers int `json:"observers"` // 正在观看的人数
+}
+
+// 实时观看终端会话时推送的消息
+type SysTerminalObserveMessage struct {
+	Type    string              `json:"type"`              // 消息类型:session->会话信息,o->终端输出,r->窗口大小变化,closed->会话结束
+	Time    float64             `json:"time,omitempty"`    // 相对会话开始的秒数
+	Data    string              `json:"data,omitempty"`    // 终端输出或窗口大小（列x行）
+	Session *SysTerminalSession `json:"session,omitempty"` // 会话信息，仅 session 消息
+}
+
+// 终端会话详情
+type SysTerminalSessionInfoVo struct {
+	SysTerminalSession
+	Commands []SysTerminalCommand `json:"commands"` // 执行的命令
+}
+
+// 终端命令检索结果
+type SysTerminalCommandVo struct {
+	SysTerminalCommand
+	Kind       int    `json:"kind"`       // 终端类型
+	TargetId   uint   `json:"targetId"`   // 主机ID或集群ID
+	TargetName string `json:"targetName"` // 主机名称或命名空间/Pod/容器
+	Username   string `json:"username"`   // 用户账号
+	Ip         string `json:"ip"`         // 客户端IP
+}
@@ -0,0 +1,238 @@
+// LDAP / Active Directory 认证 服务层
+// author xiaoRui
+
+package service
+
+import (
+	"crypto/tls"
+	"dodevops-api/api/system/model"
+	"dodevops-api/common/config"
+	"dodevops-api/pkg/log"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/go-ldap/ldap/v3"
+)
+
+// LdapAuthProvider LDAP / AD 认证，首次登录自动创建系统用户，并按组映射分配角色和部门
+type LdapAuthProvider struct {
+	conf config.LdapConfig
+}
+
+func NewLdapAuthProvider(conf config.LdapConfig) *LdapAuthProvider {
+	if conf.UserFilter == "" {
+		conf.UserFilter = "(uid=%s)"
+	}
+	if conf.GroupNameAttr == "" {
+		conf.GroupNameAttr = "cn"
+	}
+	if conf.Attributes.Nickname == "" {
+		conf.Attributes.Nickname = "cn"
+	}
+	if conf.Attributes.Email == "" {
+		conf.Attributes.Email = "mail"
+	}
+	if conf.Attributes.Phone == "" {
+		conf.Attributes.Phone = "telephoneNumber"
+	}
+	if conf.Attributes.MemberOf == "" {
+		conf.Attributes.MemberOf = "memberOf"
+	}
+	return &LdapAuthProvider{conf: conf}
+}
+
+func (p *LdapAuthProvider) Name() string {
+	return AuthProviderLdap
+}
+
+// ldapUser LDAP 中查询到的用户信息
+type ldapUser struct {
+	DN       string
+	Nickname string
+	Email    string
+	Phone    string
+	Groups   []string
+}
+
+// 校验 LDAP 账号密码并同步为系统用户
+func (p *LdapAuthProvider) Authenticate(username, password string) (model.SysAdmin, error) {
+	if username == "" || password == "" {
+		return model.SysAdmin{}, ErrAuthPasswordNotTrue
+	}
+	conn, err := p.dial()
+	if err != nil {
+		log.Log().Errorf("LDAP连接失败: %v", err)
+		return model.SysAdmin{}, ErrAuthProviderNotReady
+	}
+	defer conn.Close()
+
+	user, err := p.searchUser(conn, username)
+	if err != nil {
+		return model.SysAdmin{}, err
+	}
+	// 使用用户DN和密码绑定以校验密码
+	if err := conn.Bind(user.DN, password); err != nil {
+		if ldap.IsErrorWithCode(err, ldap.LDAPResultInvalidCredentials) {
+			return model.SysAdmin{}, ErrAuthPasswordNotTrue
+		}
+		log.Log().Errorf("LDAP用户绑定失败: %v", err)
+		return model.SysAdmin{}, ErrAuthProviderNotReady
+	}
+	// 组查询使用服务账号，避免普通用户无权限读取组信息
+	if err := p.bindService(conn); err != nil {
+		return model.SysAdmin{}, err
+	}
+	groups, err := p.searchGroups(conn, user)
+	if err != nil {
+		log.Log().Warnf("LDAP查询用户组失败: %v", err)
+	}
+	user.Groups = groups
+	return p.syncSysAdmin(username, user)
+}
+
+// 建立连接
+func (p *LdapAuthProvider) dial() (*ldap.Conn, error) {
+	tlsConfig := &tls.Config{InsecureSkipVerify: p.conf.InsecureSkipVerify}
+	conn, err := ldap.DialURL(p.conf.Url, ldap.DialWithTLSConfig(tlsConfig))
+	if err != nil {
+		return nil, err
+	}
+	if p.conf.StartTLS {
+		if err := conn.StartTLS(tlsConfig); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+	return conn, nil
+}
+
+// 使用服务账号绑定，未配置服务账号时匿名查询
+func (p *LdapAuthProvider) bindService(conn *ldap.Conn) error {
+	var err error
+	if p.conf.BindDN == "" {
+		err = conn.UnauthenticatedBind("")
+	} else {
+		err = conn.Bind(p.conf.BindDN, p.conf.BindPassword)
+	}
+	if err != nil {
+		log.Log().Errorf("LDAP服务账号绑定失败: %v", err)
+		return ErrAuthProviderNotReady
+	}
+	return nil
+}
+
+// 查询用户
+func (p *LdapAuthProvider) searchUser(conn *ldap.Conn, username string) (*ldapUser, error) {
+	if err := p.bindService(conn); err != nil {
+		return nil, err
+	}
+	attrs := p.conf.Attributes
+	request := ldap.NewSearchRequest(
+		p.conf.BaseDN,
+		ldap.ScopeWholeSubtree, ldap.NeverDerefAliases, 2, 0, false,
+		fmt.Sprintf(p.conf.UserFilter, ldap.EscapeFilter(username)),
+		[]string{"dn", attrs.Nickname, attrs.Email, attrs.Phone, attrs.MemberOf},
+		nil,
+	)
+	res, err := conn.Search(request)
+	if err != nil {
+		if ldap.IsErrorWithCode(err, ldap.LDAPResultNoSuchObject) {
+			return nil, ErrAuthUserNotFound
+		}
+		log.Log().Errorf("LDAP查询用户失败: %v", err)
+		return nil, ErrAuthProviderNotReady
+	}
+	if len(res.Entries) == 0 {
+		return nil, ErrAuthUserNotFound
+	}
+	if len(res.Entries) > 1 {
+		log.Log().Warnf("LDAP用户过滤条件匹配到多个用户: %s", username)
+		return nil, ErrAuthUserNotFound
+	}
+	entry := res.Entries[0]
+	user := &ldapUser{
+		DN:       entry.DN,
+		Nickname: entry.GetAttributeValue(attrs.Nickname),
+		Email:    entry.GetAttributeValue(attrs.Email),
+		Phone:    entry.GetAttributeValue(attrs.Phone),
+	}
+	for _, groupDN := range entry.GetAttributeValues(attrs.MemberOf) {
+		user.Groups = append(user.Groups, groupDN)
+	}
+	return user, nil
+}
+
+// 查询用户所属组，合并 memberOf 属性和组搜索结果，返回组名列表
+func (p *LdapAuthProvider) searchGroups(conn *ldap.Conn, user *ldapUser) ([]string, error) {
+	names := make([]string, 0)
+	seen := make(map[string]bool)
+	add := func(name string) {
+		if name != "" && !seen[strings.ToLower(name)] {
+			seen[strings.ToLower(name)] = true
+			names = append(names, name)
+		}
+	}
+	for _, groupDN := range user.Groups {
+		add(p.groupNameFromDN(groupDN))
+	}
+	if p.conf.GroupBaseDN == "" || p.conf.GroupFilter == "" {
+		return names, nil
+	}
+	request := ldap.NewSearchRequest(
+		p.conf.GroupBaseDN,
+		ldap.ScopeWholeSubtree, ldap.NeverDerefAliases, 0, 0, false,
+		fmt.Sprintf(p.conf.GroupFilter, ldap.EscapeFilter(user.DN)),
+		[]string{"dn", p.conf.GroupNameAttr},
+		nil,
+	)
+	res, err := conn.Search(request)
+	if err != nil {
+		if ldap.IsErrorWithCode(err, ldap.LDAPResultNoSuchObject) {
+			return names, nil
+		}
+		return names, err
+	}
+	for _, entry := range res.Entries {
+		name := entry.GetAttributeValue(p.conf.GroupNameAttr)
+		if name == "" {
+			name = p.groupNameFromDN(entry.DN)
+		}
+		add(name)
+	}
+	return names, nil
+}
+
+// 从组DN中解析组名，如 cn=ops,ou=groups,dc=example,dc=org -> ops
+func (p *LdapAuthProvider) groupNameFromDN(groupDN string) string {
+	dn, err := ldap.ParseDN(groupDN)
+	if err != nil || len(dn.RDNs) == 0 {
+		return groupDN
+	}
+	for _, attr := range dn.RDNs[0].Attributes {
+		if strings.EqualFold(attr.Type, p.conf.GroupNameAttr) {
+			return attr.Value
+		}
+	}
+	return dn.RDNs[0].Attributes[0].Value
+}
+
+// 同步为系统用户，角色和部门按 LDAP 组映射分配
+func (p *LdapAuthProvider) syncSysAdmin(username string, user *ldapUser) (model.SysAdmin, error) {
+	nickname := user.Nickname
+	if nickname == "" {
+		nickname = username
+	}
+	sysAdmin, err := provisionExternalSysAdmin(model.SysAdmin{
+		Username: username,
+		Nickname: nickname,
+		Email:    user.Email,
+		Phone:    user.Phone,
+		Source:   model.SysAdminSourceLdap,
+	}, user.Groups, p.conf.ProvisionConfig)
+	if err != nil {
+		log.Log().Errorf("同步LDAP用户失败: %v", err)
+		return model.SysAdmin{}, errors.New("同步LDAP用户失败")
+	}
+	return sysAdmin, nil
+}
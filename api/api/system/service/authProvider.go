@@ -0,0 +1,129 @@
+// 登录认证提供者 服务层
+// author xiaoRui
+
+package service
+
+import (
+	"dodevops-api/api/system/dao"
+	"dodevops-api/api/system/model"
+	"dodevops-api/common/config"
+	"dodevops-api/common/util"
+	"errors"
+	"strings"
+)
+
+var (
+	ErrAuthUserNotFound     = errors.New("认证用户不存在")
+	ErrAuthPasswordNotTrue  = errors.New("密码不正确")
+	ErrAuthProviderNotReady = errors.New("认证服务不可用")
+)
+
+// 认证提供者名称
+const (
+	AuthProviderLocal = "local"
+	AuthProviderLdap  = "ldap"
+)
+
+// IAuthProvider 登录认证提供者
+// 用户不存在时返回 ErrAuthUserNotFound，认证链会继续尝试下一个提供者；其他错误直接终止认证
+type IAuthProvider interface {
+	Name() string                                                   // 提供者名称
+	Authenticate(username, password string) (model.SysAdmin, error) // 校验用户名密码并返回对应的系统用户
+}
+
+// LocalAuthProvider 本地账号认证
+type LocalAuthProvider struct{}
+
+func (p LocalAuthProvider) Name() string {
+	return AuthProviderLocal
+}
+
+// 校验本地账号密码，外部认证源同步的账号不允许使用本地密码登录
+func (p LocalAuthProvider) Authenticate(username, password string) (model.SysAdmin, error) {
+	sysAdmin := dao.GetSysAdminByUsername(username)
+	if sysAdmin.ID == 0 || (sysAdmin.Source != "" && sysAdmin.Source != model.SysAdminSourceLocal) {
+		return model.SysAdmin{}, ErrAuthUserNotFound
+	}
+	if sysAdmin.Password != util.EncryptionMd5(password) {
+		return sysAdmin, ErrAuthPasswordNotTrue
+	}
+	return sysAdmin, nil
+}
+
+// 根据配置构建认证链，未配置时仅使用本地认证
+func AuthProviderChain() []IAuthProvider {
+	var providers []IAuthProvider
+	authConfig := config.GetAuthConfig()
+	for _, name := range authConfig.Providers {
+		switch strings.ToLower(strings.TrimSpace(name)) {
+		case AuthProviderLocal:
+			providers = append(providers, LocalAuthProvider{})
+		case AuthProviderLdap:
+			providers = append(providers, NewLdapAuthProvider(authConfig.Ldap))
+		}
+	}
+	if len(providers) == 0 {
+		providers = append(providers, LocalAuthProvider{})
+	}
+	return providers
+}
+
+// 按认证链顺序校验用户名密码
+func Authenticate(username, password string) (model.SysAdmin, error) {
+	for _, provider := range AuthProviderChain() {
+		sysAdmin, err := provider.Authenticate(username, password)
+		if errors.Is(err, ErrAuthUserNotFound) {
+			continue
+		}
+		return sysAdmin, err
+	}
+	return model.SysAdmin{}, ErrAuthUserNotFound
+}
+
+// 按组映射分配角色和部门，创建或更新外部认证源的系统用户
+func provisionExternalSysAdmin(admin model.SysAdmin, groups []string, conf config.ProvisionConfig) (model.SysAdmin, error) {
+	roleIds := make([]uint, 0)
+	deptId := 0
+	for _, group := range groups {
+		for mappingGroup, roleKey := range conf.GroupRoleMapping {
+			if !strings.EqualFold(mappingGroup, group) {
+				continue
+			}
+			if role := dao.GetSysRoleByKey(roleKey); role.ID > 0 && !containsUint(roleIds, role.ID) {
+				roleIds = append(roleIds, role.ID)
+			}
+		}
+		for mappingGroup, deptName := range conf.GroupDeptMapping {
+			if deptId == 0 && strings.EqualFold(mappingGroup, group) {
+				deptId = int(dao.GetSysDeptByName(deptName).ID)
+			}
+		}
+	}
+	if len(roleIds) == 0 && conf.DefaultRoleKey != "" {
+		if role := dao.GetSysRoleByKey(conf.DefaultRoleKey); role.ID > 0 {
+			roleIds = append(roleIds, role.ID)
+		}
+	}
+	if deptId == 0 {
+		deptId = conf.DefaultDeptId
+	}
+	admin.DeptId = deptId
+	admin.PostId = conf.DefaultPostId
+	// 外部账号不使用本地密码，写入随机值避免被本地认证使用
+	admin.Password = util.EncryptionMd5(util.GenerateRandomString(32))
+	sysAdmin, err := dao.SaveExternalSysAdmin(admin, roleIds)
+	if err != nil {
+		return model.SysAdmin{}, err
+	}
+	ClearAdminPermissionCache(sysAdmin.ID)
+	return sysAdmin, nil
+}
+
+func containsUint(list []uint, v uint) bool {
+	for _, item := range list {
+		if item == v {
+			return true
+		}
+	}
+	return false
+}
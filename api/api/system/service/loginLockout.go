@@ -0,0 +1,133 @@
+// 登录失败锁定 服务层
+// author xiaoRui
+
+package service
+
+import (
+	"context"
+	"dodevops-api/api/system/dao"
+	"dodevops-api/api/system/model"
+	"dodevops-api/common/config"
+	"dodevops-api/common/constant"
+	"dodevops-api/common/result"
+	"dodevops-api/pkg/log"
+	"dodevops-api/pkg/redis"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+)
+
+const (
+	defaultLoginMaxFailures   = 5  // 同一用户名默认允许的失败次数
+	defaultLoginIpMaxFailures = 20 // 同一IP默认允许的失败次数
+	defaultLoginWindowMinutes = 15 // 默认失败次数统计窗口(分钟)
+	defaultLoginLockMinutes   = 15 // 默认锁定时长(分钟)
+	defaultLoginMaxDelay      = 60 // 默认单次等待时间上限(秒)
+)
+
+// 读取登录锁定配置并补齐默认值
+func lockoutConfig() config.LockoutConfig {
+	cfg := config.GetAuthConfig().Lockout
+	if cfg.MaxFailures == 0 {
+		cfg.MaxFailures = defaultLoginMaxFailures
+	}
+	if cfg.IpMaxFailures == 0 {
+		cfg.IpMaxFailures = defaultLoginIpMaxFailures
+	}
+	if cfg.WindowMinutes <= 0 {
+		cfg.WindowMinutes = defaultLoginWindowMinutes
+	}
+	if cfg.LockMinutes <= 0 {
+		cfg.LockMinutes = defaultLoginLockMinutes
+	}
+	if cfg.MaxDelaySecond <= 0 {
+		cfg.MaxDelaySecond = defaultLoginMaxDelay
+	}
+	return cfg
+}
+
+// 检查用户名和来源IP是否已被锁定或处于失败后的等待期，返回还需等待的时长，未启用 redis 时不限制
+func checkLoginLocked(username, ip string) time.Duration {
+	if redis.RedisDb == nil {
+		return 0
+	}
+	ctx := context.Background()
+	for _, key := range []string{
+		constant.LOGIN_LOCK_USER_CODE + username,
+		constant.LOGIN_LOCK_IP_CODE + ip,
+		constant.LOGIN_DELAY_CODE + username,
+	} {
+		ttl, err := redis.RedisDb.PTTL(ctx, key).Result()
+		if err == nil && ttl > 0 {
+			return ttl
+		}
+	}
+	return 0
+}
+
+// 记录一次登录失败：累加用户名和IP的失败次数，超过上限后锁定，并按失败次数翻倍设置下次尝试的等待时间
+func recordLoginFailure(username, ip string) {
+	if redis.RedisDb == nil {
+		return
+	}
+	cfg := lockoutConfig()
+	window := time.Duration(cfg.WindowMinutes) * time.Minute
+	lock := time.Duration(cfg.LockMinutes) * time.Minute
+	failures := countLoginFailure(constant.LOGIN_FAIL_USER_CODE+username, constant.LOGIN_LOCK_USER_CODE+username, cfg.MaxFailures, window, lock)
+	countLoginFailure(constant.LOGIN_FAIL_IP_CODE+ip, constant.LOGIN_LOCK_IP_CODE+ip, cfg.IpMaxFailures, window, lock)
+	if cfg.BaseDelaySecond > 0 && failures > 0 {
+		delay := time.Duration(cfg.MaxDelaySecond) * time.Second
+		// 超过 30 次后直接取上限，避免位移溢出
+		if failures <= 30 {
+			if d := time.Duration(cfg.BaseDelaySecond) * time.Second << (failures - 1); d < delay {
+				delay = d
+			}
+		}
+		redis.RedisDb.Set(context.Background(), constant.LOGIN_DELAY_CODE+username, 1, delay)
+	}
+}
+
+// 累加失败次数，首次失败时开始计算统计窗口，达到上限时写入锁定标记并重新计数，返回累加后的次数
+func countLoginFailure(countKey, lockKey string, max int, window, lock time.Duration) int64 {
+	ctx := context.Background()
+	failures, err := redis.RedisDb.Incr(ctx, countKey).Result()
+	if err != nil {
+		log.Log().Errorf("记录登录失败次数失败: %v", err)
+		return 0
+	}
+	if failures == 1 {
+		redis.RedisDb.Expire(ctx, countKey, window)
+	}
+	if max > 0 && failures >= int64(max) {
+		redis.RedisDb.Set(ctx, lockKey, 1, lock)
+		redis.RedisDb.Del(ctx, countKey)
+	}
+	return failures
+}
+
+// 登录成功后清除该用户名的失败次数、等待时间和锁定，IP的失败次数保留至窗口结束
+func clearLoginFailures(username string) {
+	if redis.RedisDb == nil {
+		return
+	}
+	redis.RedisDb.Del(context.Background(),
+		constant.LOGIN_FAIL_USER_CODE+username,
+		constant.LOGIN_DELAY_CODE+username,
+		constant.LOGIN_LOCK_USER_CODE+username)
+}
+
+// 解除用户登录锁定
+func (s SysAdminServiceImpl) UnlockSysAdmin(c *gin.Context, dto model.UnlockSysAdminDto) {
+	if err := validator.New().Struct(dto); err != nil {
+		result.Failed(c, int(result.ApiCode.ValidationParameterError), result.ApiCode.GetMessage(result.ApiCode.ValidationParameterError))
+		return
+	}
+	sysAdmin := dao.GetSysAdminById(dto.Id)
+	if sysAdmin.ID == 0 {
+		result.Failed(c, int(result.ApiCode.FAILED), "用户不存在")
+		return
+	}
+	clearLoginFailures(sysAdmin.Username)
+	result.Success(c, true)
+}
@@ -0,0 +1,96 @@
+// 密码策略 服务层
+// author xiaoRui
+
+package service
+
+import (
+	"dodevops-api/api/system/dao"
+	"dodevops-api/api/system/model"
+	"dodevops-api/common/config"
+	"dodevops-api/common/util"
+	"errors"
+	"fmt"
+	"time"
+	"unicode"
+)
+
+const defaultPasswordMinLength = 8 // 密码默认最小长度
+
+// 读取密码策略并补齐默认值
+func passwordPolicy() config.PasswordPolicy {
+	policy := config.GetAuthConfig().Password
+	if policy.MinLength <= 0 {
+		policy.MinLength = defaultPasswordMinLength
+	}
+	return policy
+}
+
+// 校验明文密码是否符合复杂度要求，adminId 大于0时同时校验不能与当前密码及最近使用过的密码相同
+func validatePassword(adminId uint, password string) error {
+	policy := passwordPolicy()
+	if len([]rune(password)) < policy.MinLength {
+		return fmt.Errorf("密码长度不能少于%d位", policy.MinLength)
+	}
+	var upper, lower, digit, special bool
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsDigit(r):
+			digit = true
+		case unicode.IsPunct(r) || unicode.IsSymbol(r):
+			special = true
+		}
+	}
+	if policy.RequireUpper && !upper {
+		return errors.New("密码必须包含大写字母")
+	}
+	if policy.RequireLower && !lower {
+		return errors.New("密码必须包含小写字母")
+	}
+	if policy.RequireDigit && !digit {
+		return errors.New("密码必须包含数字")
+	}
+	if policy.RequireSpecial && !special {
+		return errors.New("密码必须包含特殊字符")
+	}
+	if adminId == 0 || policy.HistoryCount <= 0 {
+		return nil
+	}
+	hash := util.EncryptionMd5(password)
+	if dao.GetSysAdminById(adminId).Password == hash {
+		return errors.New("新密码不能与当前密码相同")
+	}
+	for _, history := range dao.GetSysPasswordHistoryList(adminId, policy.HistoryCount) {
+		if history.Password == hash {
+			return fmt.Errorf("新密码不能与最近%d次使用过的密码相同", policy.HistoryCount)
+		}
+	}
+	return nil
+}
+
+// 记录修改后的密码哈希，未启用历史密码限制时不记录
+func recordPasswordHistory(adminId uint, hash string) {
+	if count := passwordPolicy().HistoryCount; count > 0 {
+		dao.CreateSysPasswordHistory(adminId, hash, count)
+	}
+}
+
+// 本地账号密码过期时标记为下次登录需修改密码，外部认证源的账号不受密码策略限制
+// 未记录修改时间的历史账号不计算过期
+func markPasswordExpired(sysAdmin model.SysAdmin) model.SysAdmin {
+	if sysAdmin.PasswordResetRequired || (sysAdmin.Source != "" && sysAdmin.Source != model.SysAdminSourceLocal) {
+		return sysAdmin
+	}
+	days := passwordPolicy().ExpireDays
+	if days <= 0 || sysAdmin.PasswordUpdateTime == nil {
+		return sysAdmin
+	}
+	if time.Since(sysAdmin.PasswordUpdateTime.Time) > time.Duration(days)*24*time.Hour {
+		dao.UpdateSysAdminPasswordResetRequired(sysAdmin.ID, true)
+		sysAdmin.PasswordResetRequired = true
+	}
+	return sysAdmin
+}
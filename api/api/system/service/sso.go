@@ -0,0 +1,533 @@
+// OIDC / OAuth2 单点登录 服务层
+// author xiaoRui
+
+package service
+
+import (
+	"context"
+	"dodevops-api/api/system/dao"
+	"dodevops-api/api/system/model"
+	"dodevops-api/common/config"
+	"dodevops-api/common/constant"
+	"dodevops-api/common/result"
+	"dodevops-api/common/util"
+	"dodevops-api/pkg/log"
+	"dodevops-api/pkg/redis"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	goredis "github.com/go-redis/redis/v8"
+	"golang.org/x/oauth2"
+)
+
+// 单点登录 state 有效期
+const ssoStateExpiration = time.Minute * 5
+
+// 一次性登录码有效期，前端拿到后应立即换取登录结果
+const ssoLoginCodeExpiration = time.Minute
+
+// 单点登录提供者类型
+const (
+	SsoTypeOidc     = "oidc"
+	SsoTypeKeycloak = "keycloak"
+	SsoTypeFeishu   = "feishu"
+)
+
+// 飞书网页应用登录端点
+const (
+	feishuAuthUrl     = "https://passport.feishu.cn/suite/passport/oauth/authorize"
+	feishuTokenUrl    = "https://passport.feishu.cn/suite/passport/oauth/token"
+	feishuUserInfoUrl = "https://passport.feishu.cn/suite/passport/oauth/userinfo"
+)
+
+// SsoProviderVo 登录页展示的单点登录提供者
+type SsoProviderVo struct {
+	Name        string `json:"name"`        // 提供者标识
+	DisplayName string `json:"displayName"` // 展示名称
+	Type        string `json:"type"`        // 类型
+	LoginUrl    string `json:"loginUrl"`    // 发起登录地址
+}
+
+// ssoState 发起登录时缓存的 state 信息
+type ssoState struct {
+	Provider string `json:"provider"`
+	Verifier string `json:"verifier"` // PKCE code_verifier
+	Nonce    string `json:"nonce"`
+}
+
+// 定义接口
+type ISsoService interface {
+	GetProviderList(c *gin.Context)                    // 单点登录提供者列表
+	Login(c *gin.Context, provider string)             // 跳转到提供者授权页
+	Callback(c *gin.Context, provider string)          // 授权回调，换取用户信息并签发本地token
+	Exchange(c *gin.Context, dto model.SsoExchangeDto) // 使用一次性登录码换取登录结果
+}
+
+type SsoServiceImpl struct{}
+
+// 单点登录提供者列表
+func (s SsoServiceImpl) GetProviderList(c *gin.Context) {
+	list := make([]SsoProviderVo, 0)
+	for _, conf := range config.GetAuthConfig().Oidc {
+		if conf.Name == "" {
+			continue
+		}
+		displayName := conf.DisplayName
+		if displayName == "" {
+			displayName = conf.Name
+		}
+		list = append(list, SsoProviderVo{
+			Name:        conf.Name,
+			DisplayName: displayName,
+			Type:        ssoType(conf),
+			LoginUrl:    "/api/v1/sso/" + conf.Name + "/login",
+		})
+	}
+	result.Success(c, list)
+}
+
+// 跳转到提供者授权页（authorization code + PKCE）
+func (s SsoServiceImpl) Login(c *gin.Context, provider string) {
+	conf, ok := getSsoConfig(provider)
+	if !ok {
+		result.Failed(c, int(result.ApiCode.FAILED), "单点登录提供者不存在")
+		return
+	}
+	oauthConfig, _, err := newSsoOAuthConfig(c.Request.Context(), conf)
+	if err != nil {
+		log.Log().Errorf("初始化单点登录提供者 %s 失败: %v", provider, err)
+		result.Failed(c, int(result.ApiCode.FAILED), ErrAuthProviderNotReady.Error())
+		return
+	}
+	state := ssoState{
+		Provider: conf.Name,
+		Verifier: oauth2.GenerateVerifier(),
+		Nonce:    util.GenerateRandomString(32),
+	}
+	stateKey := util.GenerateRandomString(32)
+	if err := saveSsoState(stateKey, state); err != nil {
+		log.Log().Errorf("保存单点登录state失败: %v", err)
+		result.Failed(c, int(result.ApiCode.FAILED), ErrAuthProviderNotReady.Error())
+		return
+	}
+	opts := []oauth2.AuthCodeOption{oauth2.S256ChallengeOption(state.Verifier)}
+	if ssoType(conf) != SsoTypeFeishu {
+		opts = append(opts, oidc.Nonce(state.Nonce))
+	}
+	c.Redirect(http.StatusFound, oauthConfig.AuthCodeURL(stateKey, opts...))
+}
+
+// 授权回调，换取用户信息、同步系统用户并签发本地token
+func (s SsoServiceImpl) Callback(c *gin.Context, provider string) {
+	ip := c.ClientIP()
+	if errMsg := c.Query("error"); errMsg != "" {
+		result.Failed(c, int(result.ApiCode.FAILED), "授权失败: "+errMsg)
+		return
+	}
+	state, err := takeSsoState(c.Query("state"))
+	if err != nil || state.Provider != provider {
+		result.Failed(c, int(result.ApiCode.FAILED), "登录请求已失效，请重新登录")
+		return
+	}
+	conf, ok := getSsoConfig(provider)
+	if !ok {
+		result.Failed(c, int(result.ApiCode.FAILED), "单点登录提供者不存在")
+		return
+	}
+	claims, err := exchangeSsoClaims(c.Request.Context(), conf, c.Query("code"), state)
+	if err != nil {
+		log.Log().Errorf("单点登录 %s 换取用户信息失败: %v", provider, err)
+		result.Failed(c, int(result.ApiCode.FAILED), "单点登录失败")
+		return
+	}
+	sysAdmin, err := syncSsoSysAdmin(conf, claims)
+	if err != nil {
+		log.Log().Errorf("同步单点登录用户失败: %v", err)
+		dao.CreateSysLoginInfo(sysAdmin.Username, ip, util.GetRealAddressByIP(ip), util.GetBrowser(c), util.GetOs(c), "单点登录同步用户失败", 2)
+		result.Failed(c, int(result.ApiCode.FAILED), "同步单点登录用户失败")
+		return
+	}
+	const status int = 2
+	if sysAdmin.Status == status {
+		dao.CreateSysLoginInfo(sysAdmin.Username, ip, util.GetRealAddressByIP(ip), util.GetBrowser(c), util.GetOs(c), "账号已停用", 2)
+		result.Failed(c, int(result.ApiCode.STATUSISENABLE), result.ApiCode.GetMessage(result.ApiCode.STATUSISENABLE))
+		return
+	}
+	// 与账号密码登录相同，已启用二次验证或所属角色强制启用时先返回二次验证票据，校验通过后再签发token
+	data, needMfa, err := loginMfaChallenge(sysAdmin)
+	if needMfa && err != nil {
+		log.Log().Errorf("创建二次验证票据失败: %v", err)
+		result.Failed(c, int(result.ApiCode.FAILED), "创建二次验证票据失败")
+		return
+	}
+	if !needMfa {
+		if data, err = loginResult(c, sysAdmin); err != nil {
+			log.Log().Errorf("创建登录会话失败: %v", err)
+			result.Failed(c, int(result.ApiCode.FAILED), "创建登录会话失败")
+			return
+		}
+		dao.CreateSysLoginInfo(sysAdmin.Username, ip, util.GetRealAddressByIP(ip), util.GetBrowser(c), util.GetOs(c), "单点登录成功", 1)
+	}
+	if conf.FrontendUrl != "" {
+		// 令牌不放在跳转地址中，避免出现在浏览器历史、代理日志和 Referer 里
+		code := util.GenerateRandomString(32)
+		if err := saveSsoLoginCode(code, data); err != nil {
+			log.Log().Errorf("保存单点登录登录码失败: %v", err)
+			result.Failed(c, int(result.ApiCode.FAILED), "创建登录会话失败")
+			return
+		}
+		c.Redirect(http.StatusFound, appendUrlQuery(conf.FrontendUrl, "code", code))
+		return
+	}
+	result.Success(c, data)
+}
+
+// 使用一次性登录码换取登录结果
+func (s SsoServiceImpl) Exchange(c *gin.Context, dto model.SsoExchangeDto) {
+	if err := validator.New().Struct(dto); err != nil {
+		result.Failed(c, int(result.ApiCode.MissingLoginParameter), result.ApiCode.GetMessage(result.ApiCode.MissingLoginParameter))
+		return
+	}
+	data, err := takeSsoLoginCode(dto.Code)
+	if err != nil {
+		result.Failed(c, int(result.ApiCode.FAILED), "登录码已失效，请重新登录")
+		return
+	}
+	result.Success(c, data)
+}
+
+var ssoService = SsoServiceImpl{}
+
+func SsoService() ISsoService {
+	return &ssoService
+}
+
+// 按标识查找提供者配置
+func getSsoConfig(name string) (config.OidcConfig, bool) {
+	for _, conf := range config.GetAuthConfig().Oidc {
+		if conf.Name != "" && conf.Name == name {
+			return conf, true
+		}
+	}
+	return config.OidcConfig{}, false
+}
+
+func ssoType(conf config.OidcConfig) string {
+	if conf.Type == "" {
+		return SsoTypeOidc
+	}
+	return strings.ToLower(conf.Type)
+}
+
+// OIDC discovery 结果缓存，避免每次登录都请求 .well-known 配置
+var ssoProviderCache sync.Map
+
+func getOidcProvider(ctx context.Context, issuer string) (*oidc.Provider, error) {
+	if p, ok := ssoProviderCache.Load(issuer); ok {
+		return p.(*oidc.Provider), nil
+	}
+	p, err := oidc.NewProvider(ctx, issuer)
+	if err != nil {
+		return nil, err
+	}
+	ssoProviderCache.Store(issuer, p)
+	return p, nil
+}
+
+// 构建 OAuth2 客户端配置，返回 OIDC provider（未配置 issuer 时为 nil）
+func newSsoOAuthConfig(ctx context.Context, conf config.OidcConfig) (*oauth2.Config, *oidc.Provider, error) {
+	oauthConfig := &oauth2.Config{
+		ClientID:     conf.ClientId,
+		ClientSecret: conf.ClientSecret,
+		RedirectURL:  conf.RedirectUrl,
+		Scopes:       conf.Scopes,
+	}
+	if oauthConfig.RedirectURL == "" {
+		oauthConfig.RedirectURL = strings.TrimRight(config.Config.Server.PublicUrl, "/") + "/api/v1/sso/" + conf.Name + "/callback"
+	}
+	var provider *oidc.Provider
+	if ssoType(conf) == SsoTypeFeishu {
+		oauthConfig.Endpoint = oauth2.Endpoint{AuthURL: feishuAuthUrl, TokenURL: feishuTokenUrl}
+	} else if conf.Issuer != "" {
+		var err error
+		if provider, err = getOidcProvider(ctx, conf.Issuer); err != nil {
+			return nil, nil, err
+		}
+		oauthConfig.Endpoint = provider.Endpoint()
+		if len(oauthConfig.Scopes) == 0 {
+			oauthConfig.Scopes = []string{oidc.ScopeOpenID, "profile", "email"}
+		}
+	}
+	if conf.AuthUrl != "" {
+		oauthConfig.Endpoint.AuthURL = conf.AuthUrl
+	}
+	if conf.TokenUrl != "" {
+		oauthConfig.Endpoint.TokenURL = conf.TokenUrl
+	}
+	if oauthConfig.Endpoint.AuthURL == "" || oauthConfig.Endpoint.TokenURL == "" {
+		return nil, nil, errors.New("未配置 issuer 或授权/令牌地址")
+	}
+	return oauthConfig, provider, nil
+}
+
+// 使用授权码换取令牌，校验 id_token 并合并用户信息接口返回的声明
+func exchangeSsoClaims(ctx context.Context, conf config.OidcConfig, code string, state ssoState) (map[string]interface{}, error) {
+	if code == "" {
+		return nil, errors.New("缺少授权码")
+	}
+	oauthConfig, provider, err := newSsoOAuthConfig(ctx, conf)
+	if err != nil {
+		return nil, err
+	}
+	token, err := oauthConfig.Exchange(ctx, code, oauth2.VerifierOption(state.Verifier))
+	if err != nil {
+		return nil, err
+	}
+	claims := make(map[string]interface{})
+	if provider != nil {
+		rawIdToken, ok := token.Extra("id_token").(string)
+		if !ok || rawIdToken == "" {
+			return nil, errors.New("令牌响应中缺少 id_token")
+		}
+		idToken, err := provider.Verifier(&oidc.Config{ClientID: conf.ClientId}).Verify(ctx, rawIdToken)
+		if err != nil {
+			return nil, err
+		}
+		if idToken.Nonce != state.Nonce {
+			return nil, errors.New("id_token nonce 不匹配")
+		}
+		if err := idToken.Claims(&claims); err != nil {
+			return nil, err
+		}
+	}
+	userInfoUrl := conf.UserInfoUrl
+	if userInfoUrl == "" && ssoType(conf) == SsoTypeFeishu {
+		userInfoUrl = feishuUserInfoUrl
+	}
+	if userInfoUrl == "" && provider != nil {
+		userInfoUrl = provider.UserInfoEndpoint()
+	}
+	if userInfoUrl != "" {
+		userInfo, err := fetchSsoUserInfo(ctx, oauthConfig.Client(ctx, token), userInfoUrl)
+		if err != nil {
+			// 已通过 id_token 获取到声明时用户信息接口仅作补充
+			if provider == nil {
+				return nil, err
+			}
+			log.Log().Warnf("获取单点登录用户信息失败: %v", err)
+		}
+		for k, v := range userInfo {
+			if _, exists := claims[k]; !exists {
+				claims[k] = v
+			}
+		}
+	}
+	if len(claims) == 0 {
+		return nil, errors.New("未获取到用户信息")
+	}
+	return claims, nil
+}
+
+// 请求用户信息接口
+func fetchSsoUserInfo(ctx context.Context, client *http.Client, userInfoUrl string) (map[string]interface{}, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, userInfoUrl, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("用户信息接口返回 %d: %s", resp.StatusCode, string(body))
+	}
+	userInfo := make(map[string]interface{})
+	if err := json.Unmarshal(body, &userInfo); err != nil {
+		return nil, err
+	}
+	return userInfo, nil
+}
+
+// 按声明映射同步为系统用户，角色和部门按 groups 声明映射分配
+func syncSsoSysAdmin(conf config.OidcConfig, claims map[string]interface{}) (model.SysAdmin, error) {
+	mapping := conf.Claims
+	if ssoType(conf) == SsoTypeFeishu {
+		mapping = defaultClaim(mapping, config.OidcClaims{Username: "user_id", Nickname: "name", Email: "email", Phone: "mobile"})
+	}
+	mapping = defaultClaim(mapping, config.OidcClaims{Username: "preferred_username", Nickname: "name", Email: "email", Phone: "phone_number", Groups: "groups"})
+
+	username := claimString(claims, mapping.Username)
+	if username == "" {
+		username = claimString(claims, "email")
+	}
+	if username == "" {
+		username = claimString(claims, "sub")
+	}
+	if username == "" {
+		return model.SysAdmin{}, errors.New("未获取到用户名")
+	}
+	nickname := claimString(claims, mapping.Nickname)
+	if nickname == "" {
+		nickname = username
+	}
+	return provisionExternalSysAdmin(model.SysAdmin{
+		Username: username,
+		Nickname: nickname,
+		Email:    claimString(claims, mapping.Email),
+		Phone:    claimString(claims, mapping.Phone),
+		Source:   model.SysAdminSourceOidc + conf.Name,
+	}, claimStrings(claims, mapping.Groups), conf.ProvisionConfig)
+}
+
+// 未配置的声明使用默认值
+func defaultClaim(mapping, defaults config.OidcClaims) config.OidcClaims {
+	if mapping.Username == "" {
+		mapping.Username = defaults.Username
+	}
+	if mapping.Nickname == "" {
+		mapping.Nickname = defaults.Nickname
+	}
+	if mapping.Email == "" {
+		mapping.Email = defaults.Email
+	}
+	if mapping.Phone == "" {
+		mapping.Phone = defaults.Phone
+	}
+	if mapping.Groups == "" {
+		mapping.Groups = defaults.Groups
+	}
+	return mapping
+}
+
+// 按路径读取声明，支持 realm_access.roles 形式的嵌套路径
+func claimValue(claims map[string]interface{}, path string) interface{} {
+	if path == "" {
+		return nil
+	}
+	if v, ok := claims[path]; ok {
+		return v
+	}
+	var current interface{} = claims
+	for _, key := range strings.Split(path, ".") {
+		m, ok := current.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		current = m[key]
+	}
+	return current
+}
+
+func claimString(claims map[string]interface{}, path string) string {
+	switch v := claimValue(claims, path).(type) {
+	case string:
+		return v
+	case float64:
+		return fmt.Sprintf("%.0f", v)
+	}
+	return ""
+}
+
+func claimStrings(claims map[string]interface{}, path string) []string {
+	var values []string
+	switch v := claimValue(claims, path).(type) {
+	case string:
+		values = append(values, v)
+	case []interface{}:
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				// Keycloak 组路径形如 /ops，映射时使用组名
+				values = append(values, strings.TrimPrefix(s, "/"))
+			}
+		}
+	}
+	return values
+}
+
+// 缓存 state，回调时一次性取出
+func saveSsoState(key string, state ssoState) error {
+	if redis.RedisDb == nil {
+		return errors.New("redis未初始化")
+	}
+	data, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	return redis.RedisDb.Set(context.Background(), constant.SSO_STATE_CODE+key, data, ssoStateExpiration).Err()
+}
+
+func takeSsoState(key string) (ssoState, error) {
+	var state ssoState
+	if key == "" || redis.RedisDb == nil {
+		return state, errors.New("state无效")
+	}
+	// 读取与删除在同一事务中执行，保证 state 只能使用一次
+	ctx := context.Background()
+	var get *goredis.StringCmd
+	_, err := redis.RedisDb.TxPipelined(ctx, func(pipe goredis.Pipeliner) error {
+		get = pipe.Get(ctx, constant.SSO_STATE_CODE+key)
+		pipe.Del(ctx, constant.SSO_STATE_CODE+key)
+		return nil
+	})
+	if err != nil {
+		return state, err
+	}
+	err = json.Unmarshal([]byte(get.Val()), &state)
+	return state, err
+}
+
+// 缓存登录结果，前端使用一次性登录码换取
+func saveSsoLoginCode(code string, data map[string]interface{}) error {
+	if redis.RedisDb == nil {
+		return errors.New("redis未初始化")
+	}
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	return redis.RedisDb.Set(context.Background(), constant.SSO_LOGIN_CODE+code, payload, ssoLoginCodeExpiration).Err()
+}
+
+func takeSsoLoginCode(code string) (map[string]interface{}, error) {
+	if code == "" || redis.RedisDb == nil {
+		return nil, errors.New("登录码无效")
+	}
+	// 读取与删除在同一事务中执行，保证登录码只能使用一次
+	ctx := context.Background()
+	var get *goredis.StringCmd
+	_, err := redis.RedisDb.TxPipelined(ctx, func(pipe goredis.Pipeliner) error {
+		get = pipe.Get(ctx, constant.SSO_LOGIN_CODE+code)
+		pipe.Del(ctx, constant.SSO_LOGIN_CODE+code)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	data := make(map[string]interface{})
+	err = json.Unmarshal([]byte(get.Val()), &data)
+	return data, err
+}
+
+// 在地址后追加查询参数，兼容 hash 路由的前端地址
+func appendUrlQuery(rawUrl, key, value string) string {
+	sep := "?"
+	if strings.Contains(rawUrl, "?") {
+		sep = "&"
+	}
+	return rawUrl + sep + key + "=" + url.QueryEscape(value)
+}
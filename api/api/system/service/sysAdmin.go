@@ -7,8 +7,12 @@ import (
 	"dodevops-api/common/result"
 	"dodevops-api/common/util"
 	"dodevops-api/pkg/jwt"
+	"dodevops-api/pkg/log"
+	"errors"
+	"fmt"
 	"github.com/gin-gonic/gin"
 	"github.com/go-playground/validator/v10"
+	"math"
 )
 
 // 定义接口
@@ -23,6 +27,7 @@ type ISysAminService interface {
 	GetSysAdminList(c *gin.Context, PageSize, PageNum int, Username, Status, BeginTime, EndTime string) // 分页查询用户列表
 	UpdatePersonal(c *gin.Context, dto model.UpdatePersonalDto)                                         // 修改个人信息
 	UpdatePersonalPassword(c *gin.Context, dto model.UpdatePersonalPasswordDto)                         // 修改个人密码
+	UnlockSysAdmin(c *gin.Context, dto model.UnlockSysAdminDto)                                         // 解除登录锁定
 }
 type SysAdminServiceImpl struct{}
 
@@ -49,11 +54,23 @@ func (s SysAdminServiceImpl) Login(c *gin.Context, dto model.LoginDto) {
 		result.Failed(c, int(result.ApiCode.CAPTCHANOTTRUE), result.ApiCode.GetMessage(result.ApiCode.CAPTCHANOTTRUE))
 		return
 	}
-	// 校验
-	sysAdmin := dao.SysAdminDetail(dto)
-	if sysAdmin.Password != util.EncryptionMd5(dto.Password) {
-		dao.CreateSysLoginInfo(dto.Username, ip, util.GetRealAddressByIP(ip), util.GetBrowser(c), util.GetOs(c), "密码不正确", 2)
-		result.Failed(c, int(result.ApiCode.PASSWORDNOTTRUE), result.ApiCode.GetMessage(result.ApiCode.PASSWORDNOTTRUE))
+	// 连续失败次数过多时锁定，或未到失败后的等待时间
+	if wait := checkLoginLocked(dto.Username, ip); wait > 0 {
+		dao.CreateSysLoginInfo(dto.Username, ip, util.GetRealAddressByIP(ip), util.GetBrowser(c), util.GetOs(c), "登录已锁定", 2)
+		result.Failed(c, int(result.ApiCode.LOGINLOCKED), fmt.Sprintf("登录失败次数过多，请%d秒后再试", int(math.Ceil(wait.Seconds()))))
+		return
+	}
+	// 按认证链校验用户名密码（本地 / LDAP）
+	sysAdmin, err := Authenticate(dto.Username, dto.Password)
+	if err != nil {
+		if errors.Is(err, ErrAuthUserNotFound) || errors.Is(err, ErrAuthPasswordNotTrue) {
+			recordLoginFailure(dto.Username, ip)
+			dao.CreateSysLoginInfo(dto.Username, ip, util.GetRealAddressByIP(ip), util.GetBrowser(c), util.GetOs(c), "密码不正确", 2)
+			result.Failed(c, int(result.ApiCode.PASSWORDNOTTRUE), result.ApiCode.GetMessage(result.ApiCode.PASSWORDNOTTRUE))
+			return
+		}
+		dao.CreateSysLoginInfo(dto.Username, ip, util.GetRealAddressByIP(ip), util.GetBrowser(c), util.GetOs(c), err.Error(), 2)
+		result.Failed(c, int(result.ApiCode.FAILED), err.Error())
 		return
 	}
 	const status int = 2
@@ -62,9 +79,36 @@ func (s SysAdminServiceImpl) Login(c *gin.Context, dto model.LoginDto) {
 		result.Failed(c, int(result.ApiCode.STATUSISENABLE), result.ApiCode.GetMessage(result.ApiCode.STATUSISENABLE))
 		return
 	}
-	// 生成token
-	tokenString, _ := jwt.GenerateTokenByAdmin(sysAdmin)
+	// 已启用二次验证或所属角色强制启用时，返回二次验证票据，校验通过后再签发token
+	challenge, needMfa, err := loginMfaChallenge(sysAdmin)
+	if needMfa {
+		if err != nil {
+			log.Log().Errorf("创建二次验证票据失败: %v", err)
+			result.Failed(c, int(result.ApiCode.FAILED), "创建二次验证票据失败")
+			return
+		}
+		result.Success(c, challenge)
+		return
+	}
+	data, err := loginResult(c, sysAdmin)
+	if err != nil {
+		log.Log().Errorf("创建登录会话失败: %v", err)
+		result.Failed(c, int(result.ApiCode.FAILED), "创建登录会话失败")
+		return
+	}
+	clearLoginFailures(dto.Username)
 	dao.CreateSysLoginInfo(dto.Username, ip, util.GetRealAddressByIP(ip), util.GetBrowser(c), util.GetOs(c), "登录成功", 1)
+	result.Success(c, data)
+}
+
+// 创建登录会话并组装登录返回的令牌、菜单和权限信息
+// 密码已过期或被要求修改时，令牌只能用于修改密码和退出登录
+func loginResult(c *gin.Context, sysAdmin model.SysAdmin) (map[string]interface{}, error) {
+	sysAdmin = markPasswordExpired(sysAdmin)
+	tokenString, refreshToken, err := createSession(c, sysAdmin)
+	if err != nil {
+		return nil, err
+	}
 	// 左侧菜单列表
 	var leftMenuVo []model.LeftMenuVo
 	leftMenuList := dao.QueryLeftMenuList(sysAdmin.ID)
@@ -84,7 +128,15 @@ func (s SysAdminServiceImpl) Login(c *gin.Context, dto model.LoginDto) {
 	for _, value := range permissionList {
 		stringList = append(stringList, value.Value)
 	}
-	result.Success(c, map[string]interface{}{"token": tokenString, "sysAdmin": sysAdmin, "leftMenuList": leftMenuVo, "permissionList": stringList})
+	return map[string]interface{}{
+		"token":                  tokenString,
+		"refreshToken":           refreshToken,
+		"expiresIn":              int64(accessTokenExpiration().Seconds()),
+		"sysAdmin":               sysAdmin,
+		"leftMenuList":           leftMenuVo,
+		"permissionList":         stringList,
+		"passwordChangeRequired": sysAdmin.PasswordResetRequired,
+	}, nil
 }
 
 // 新增用户
@@ -94,11 +146,16 @@ func (s SysAdminServiceImpl) CreateSysAdmin(c *gin.Context, dto model.AddSysAdmi
 		result.Failed(c, int(result.ApiCode.MissingNewAdminParameter), result.ApiCode.GetMessage(result.ApiCode.MissingNewAdminParameter))
 		return
 	}
+	if err := validatePassword(0, dto.Password); err != nil {
+		result.Failed(c, int(result.ApiCode.PASSWORDPOLICYERROR), err.Error())
+		return
+	}
 	bool := dao.CreateSysAdmin(dto)
 	if !bool {
 		result.Failed(c, int(result.ApiCode.USERNAMEALREADYEXISTS), result.ApiCode.GetMessage(result.ApiCode.USERNAMEALREADYEXISTS))
 		return
 	}
+	recordPasswordHistory(dao.GetSysAdminByUsername(dto.Username).ID, util.EncryptionMd5(dto.Password))
 	result.Success(c, bool)
 }
 
@@ -109,7 +166,14 @@ func (s SysAdminServiceImpl) GetSysAdminInfo(c *gin.Context, Id int) {
 
 // 修改用户
 func (s SysAdminServiceImpl) UpdateSysAdmin(c *gin.Context, dto model.UpdateSysAdminDto) {
-	result.Success(c, dao.UpdateSysAdmin(dto))
+	sysAdmin := dao.UpdateSysAdmin(dto)
+	ClearAdminPermissionCache(dto.Id)
+	// 停用后立即下线
+	const status int = 2
+	if sysAdmin.Status == status {
+		RevokeAdminSessions(dto.Id)
+	}
+	result.Success(c, sysAdmin)
 }
 
 // 根据id删除用户
@@ -120,18 +184,43 @@ func (s SysAdminServiceImpl) DeleteSysAdminById(c *gin.Context, dto model.SysAdm
 		return
 	}
 	dao.DeleteSysAdminById(dto)
+	ClearAdminPermissionCache(dto.Id)
+	RevokeAdminSessions(dto.Id)
 	result.Success(c, true)
 }
 
 // 修改用户状态
 func (s SysAdminServiceImpl) UpdateSysAdminStatus(c *gin.Context, dto model.UpdateSysAdminStatusDto) {
 	dao.UpdateSysAdminStatus(dto)
+	// 停用后立即下线
+	const status int = 2
+	if dto.Status == status {
+		RevokeAdminSessions(dto.Id)
+	}
 	result.Success(c, true)
 }
 
 // 重置密码
 func (s SysAdminServiceImpl) ResetSysAdminPassword(c *gin.Context, dto model.ResetSysAdminPasswordDto) {
-	dao.ResetSysAdminPassword(dto)
+	if dto.Password == "" && !dto.ResetMfa {
+		result.Failed(c, int(result.ApiCode.ValidationParameterError), result.ApiCode.GetMessage(result.ApiCode.ValidationParameterError))
+		return
+	}
+	// 重置密码后已登录的会话全部下线，并解除登录锁定
+	if dto.Password != "" {
+		if err := validatePassword(dto.Id, dto.Password); err != nil {
+			result.Failed(c, int(result.ApiCode.PASSWORDPOLICYERROR), err.Error())
+			return
+		}
+		dao.ResetSysAdminPassword(dto)
+		recordPasswordHistory(dto.Id, util.EncryptionMd5(dto.Password))
+		RevokeAdminSessions(dto.Id)
+		clearLoginFailures(dao.GetSysAdminById(dto.Id).Username)
+	}
+	// 用户丢失认证器时由管理员重置，下次登录重新绑定
+	if dto.ResetMfa {
+		dao.DeleteSysAdminMfa(dto.Id)
+	}
 	result.Success(c, true)
 }
 
@@ -177,9 +266,16 @@ func (s SysAdminServiceImpl) UpdatePersonalPassword(c *gin.Context, dto model.Up
 		result.Failed(c, int(result.ApiCode.RESETPASSWORD), result.ApiCode.GetMessage(result.ApiCode.RESETPASSWORD))
 		return
 	}
+	if err := validatePassword(sysAdmin.ID, dto.NewPassword); err != nil {
+		result.Failed(c, int(result.ApiCode.PASSWORDPOLICYERROR), err.Error())
+		return
+	}
 	dto.NewPassword = util.EncryptionMd5(dto.NewPassword)
 	sysAdminUpdatePwd := dao.UpdatePersonalPassword(dto)
-	tokenString, _ := jwt.GenerateTokenByAdmin(sysAdminUpdatePwd)
+	recordPasswordHistory(sysAdmin.ID, dto.NewPassword)
+	// 修改密码后保留当前会话，其他会话全部下线
+	revokeAdminSessionsExcept(sysAdmin.ID, sysAdmin.SessionId)
+	tokenString, _ := jwt.GenerateTokenByAdmin(sysAdminUpdatePwd, sysAdmin.SessionId, accessTokenExpiration())
 	result.Success(c, map[string]interface{}{"token": tokenString, "sysAdmin": sysAdminUpdatePwd})
 }
 
@@ -0,0 +1,489 @@
+// 二次验证（TOTP） 服务层
+// author xiaoRui
+
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"dodevops-api/api/system/dao"
+	"dodevops-api/api/system/model"
+	"dodevops-api/common/config"
+	"dodevops-api/common/constant"
+	"dodevops-api/common/result"
+	"dodevops-api/common/util"
+	"dodevops-api/pkg/jwt"
+	"dodevops-api/pkg/log"
+	"dodevops-api/pkg/redis"
+	"encoding/base32"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"image/png"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	goredis "github.com/go-redis/redis/v8"
+	"github.com/pquerna/otp"
+	"github.com/pquerna/otp/totp"
+)
+
+const (
+	mfaPeriod            = 30              // 验证码有效时间窗口(秒)
+	mfaSkew              = 1               // 允许前后偏差的时间窗口数量
+	mfaRecoveryCodeCount = 10              // 恢复码数量
+	mfaTicketExpiration  = 5 * time.Minute // 登录二次验证票据有效期
+	mfaTicketMaxFailures = 5               // 单个票据允许的最大验证失败次数
+)
+
+// 定义接口
+type ISysAdminMfaService interface {
+	GetSysAdminMfaStatus(c *gin.Context)                                  // 当前用户二次验证状态
+	EnrollSysAdminMfa(c *gin.Context)                                     // 绑定认证器
+	ActivateSysAdminMfa(c *gin.Context, dto model.SysAdminMfaCodeDto)     // 校验验证码并启用二次验证
+	DisableSysAdminMfa(c *gin.Context, dto model.SysAdminMfaCodeDto)      // 停用二次验证
+	RegenerateRecoveryCodes(c *gin.Context, dto model.SysAdminMfaCodeDto) // 重新生成恢复码
+	EnrollMfaByTicket(c *gin.Context, dto model.MfaTicketDto)             // 登录时绑定认证器
+	LoginMfa(c *gin.Context, dto model.MfaLoginDto)                       // 登录二次验证
+}
+
+type SysAdminMfaServiceImpl struct{}
+
+// 当前用户二次验证状态
+func (s SysAdminMfaServiceImpl) GetSysAdminMfaStatus(c *gin.Context) {
+	adminId, _ := jwt.GetAdminId(c)
+	mfa := dao.GetSysAdminMfa(adminId)
+	vo := model.SysAdminMfaStatusVo{Enabled: mfa.Enabled, Required: IsMfaRequired(adminId)}
+	if mfa.Enabled {
+		vo.RecoveryCodesLeft = len(splitRecoveryCodes(mfa.RecoveryCodes))
+		vo.EnableTime = mfa.EnableTime
+	}
+	result.Success(c, vo)
+}
+
+// 绑定认证器，生成新密钥，需调用启用接口校验一次验证码后才会生效
+func (s SysAdminMfaServiceImpl) EnrollSysAdminMfa(c *gin.Context) {
+	if IsApiTokenRequest(c) {
+		result.Failed(c, int(result.ApiCode.NOPERMISSION), "不允许使用访问令牌管理二次验证")
+		return
+	}
+	adminId, _ := jwt.GetAdminId(c)
+	sysAdmin := dao.GetSysAdminById(adminId)
+	vo, err := enrollSysAdminMfa(sysAdmin)
+	if err != nil {
+		result.Failed(c, int(result.ApiCode.FAILED), err.Error())
+		return
+	}
+	result.Success(c, vo)
+}
+
+// 校验验证码并启用二次验证，返回恢复码
+func (s SysAdminMfaServiceImpl) ActivateSysAdminMfa(c *gin.Context, dto model.SysAdminMfaCodeDto) {
+	if IsApiTokenRequest(c) {
+		result.Failed(c, int(result.ApiCode.NOPERMISSION), "不允许使用访问令牌管理二次验证")
+		return
+	}
+	if err := validator.New().Struct(dto); err != nil {
+		result.Failed(c, int(result.ApiCode.ValidationParameterError), result.ApiCode.GetMessage(result.ApiCode.ValidationParameterError))
+		return
+	}
+	adminId, _ := jwt.GetAdminId(c)
+	mfa := dao.GetSysAdminMfa(adminId)
+	if mfa.ID == 0 || mfa.Enabled {
+		result.Failed(c, int(result.ApiCode.FAILED), "请先绑定认证器")
+		return
+	}
+	if !verifyTotpCode(&mfa, dto.Code) {
+		result.Failed(c, int(result.ApiCode.MFACODENOTTRUE), result.ApiCode.GetMessage(result.ApiCode.MFACODENOTTRUE))
+		return
+	}
+	recoveryCodes, err := activateSysAdminMfa(&mfa)
+	if err != nil {
+		result.Failed(c, int(result.ApiCode.FAILED), err.Error())
+		return
+	}
+	result.Success(c, map[string]interface{}{"recoveryCodes": recoveryCodes})
+}
+
+// 停用二次验证，所属角色强制启用时不允许停用
+func (s SysAdminMfaServiceImpl) DisableSysAdminMfa(c *gin.Context, dto model.SysAdminMfaCodeDto) {
+	if IsApiTokenRequest(c) {
+		result.Failed(c, int(result.ApiCode.NOPERMISSION), "不允许使用访问令牌管理二次验证")
+		return
+	}
+	if err := validator.New().Struct(dto); err != nil {
+		result.Failed(c, int(result.ApiCode.ValidationParameterError), result.ApiCode.GetMessage(result.ApiCode.ValidationParameterError))
+		return
+	}
+	adminId, _ := jwt.GetAdminId(c)
+	if IsMfaRequired(adminId) {
+		result.Failed(c, int(result.ApiCode.FAILED), "所属角色要求启用二次验证，不能停用")
+		return
+	}
+	mfa := dao.GetSysAdminMfa(adminId)
+	if !mfa.Enabled {
+		result.Failed(c, int(result.ApiCode.FAILED), "未启用二次验证")
+		return
+	}
+	if !verifyMfaCode(&mfa, dto.Code) {
+		result.Failed(c, int(result.ApiCode.MFACODENOTTRUE), result.ApiCode.GetMessage(result.ApiCode.MFACODENOTTRUE))
+		return
+	}
+	dao.DeleteSysAdminMfa(adminId)
+	result.Success(c, true)
+}
+
+// 重新生成恢复码，原有恢复码全部失效
+func (s SysAdminMfaServiceImpl) RegenerateRecoveryCodes(c *gin.Context, dto model.SysAdminMfaCodeDto) {
+	if IsApiTokenRequest(c) {
+		result.Failed(c, int(result.ApiCode.NOPERMISSION), "不允许使用访问令牌管理二次验证")
+		return
+	}
+	if err := validator.New().Struct(dto); err != nil {
+		result.Failed(c, int(result.ApiCode.ValidationParameterError), result.ApiCode.GetMessage(result.ApiCode.ValidationParameterError))
+		return
+	}
+	adminId, _ := jwt.GetAdminId(c)
+	mfa := dao.GetSysAdminMfa(adminId)
+	if !mfa.Enabled {
+		result.Failed(c, int(result.ApiCode.FAILED), "未启用二次验证")
+		return
+	}
+	if !verifyTotpCode(&mfa, dto.Code) {
+		result.Failed(c, int(result.ApiCode.MFACODENOTTRUE), result.ApiCode.GetMessage(result.ApiCode.MFACODENOTTRUE))
+		return
+	}
+	plainCodes, hashedCodes := generateRecoveryCodes()
+	if dao.UpdateSysAdminMfaRecoveryCodes(mfa.ID, mfa.RecoveryCodes, hashedCodes) == 0 {
+		result.Failed(c, int(result.ApiCode.FAILED), "生成恢复码失败，请重试")
+		return
+	}
+	result.Success(c, map[string]interface{}{"recoveryCodes": plainCodes})
+}
+
+// 登录时绑定认证器，用于所属角色强制启用二次验证但尚未绑定的用户
+func (s SysAdminMfaServiceImpl) EnrollMfaByTicket(c *gin.Context, dto model.MfaTicketDto) {
+	if err := validator.New().Struct(dto); err != nil {
+		result.Failed(c, int(result.ApiCode.ValidationParameterError), result.ApiCode.GetMessage(result.ApiCode.ValidationParameterError))
+		return
+	}
+	adminId := getMfaTicket(dto.Ticket)
+	if adminId == 0 {
+		result.Failed(c, int(result.ApiCode.MFATICKETEXPIRED), result.ApiCode.GetMessage(result.ApiCode.MFATICKETEXPIRED))
+		return
+	}
+	vo, err := enrollSysAdminMfa(dao.GetSysAdminById(adminId))
+	if err != nil {
+		result.Failed(c, int(result.ApiCode.FAILED), err.Error())
+		return
+	}
+	result.Success(c, vo)
+}
+
+// 登录二次验证，校验通过后签发token；首次绑定认证器的用户同时启用二次验证并返回恢复码
+func (s SysAdminMfaServiceImpl) LoginMfa(c *gin.Context, dto model.MfaLoginDto) {
+	if err := validator.New().Struct(dto); err != nil {
+		result.Failed(c, int(result.ApiCode.MissingLoginParameter), result.ApiCode.GetMessage(result.ApiCode.MissingLoginParameter))
+		return
+	}
+	adminId := getMfaTicket(dto.Ticket)
+	if adminId == 0 {
+		result.Failed(c, int(result.ApiCode.MFATICKETEXPIRED), result.ApiCode.GetMessage(result.ApiCode.MFATICKETEXPIRED))
+		return
+	}
+	ip := c.ClientIP()
+	sysAdmin := dao.GetSysAdminById(adminId)
+	const status int = 2
+	if sysAdmin.ID == 0 || sysAdmin.Status == status {
+		deleteMfaTicket(dto.Ticket)
+		result.Failed(c, int(result.ApiCode.STATUSISENABLE), result.ApiCode.GetMessage(result.ApiCode.STATUSISENABLE))
+		return
+	}
+	mfa := dao.GetSysAdminMfa(adminId)
+	if mfa.ID == 0 {
+		result.Failed(c, int(result.ApiCode.FAILED), "请先绑定认证器")
+		return
+	}
+	// 未启用时为首次绑定，仅接受认证器验证码
+	var verified bool
+	if mfa.Enabled {
+		verified = verifyMfaCode(&mfa, dto.Code)
+	} else {
+		verified = verifyTotpCode(&mfa, dto.Code)
+	}
+	if !verified {
+		recordMfaTicketFailure(dto.Ticket)
+		recordLoginFailure(sysAdmin.Username, ip)
+		dao.CreateSysLoginInfo(sysAdmin.Username, ip, util.GetRealAddressByIP(ip), util.GetBrowser(c), util.GetOs(c), "二次验证码不正确", 2)
+		result.Failed(c, int(result.ApiCode.MFACODENOTTRUE), result.ApiCode.GetMessage(result.ApiCode.MFACODENOTTRUE))
+		return
+	}
+	// 票据只能使用一次
+	if !deleteMfaTicket(dto.Ticket) {
+		result.Failed(c, int(result.ApiCode.MFATICKETEXPIRED), result.ApiCode.GetMessage(result.ApiCode.MFATICKETEXPIRED))
+		return
+	}
+	data, err := loginResult(c, sysAdmin)
+	if err != nil {
+		log.Log().Errorf("创建登录会话失败: %v", err)
+		result.Failed(c, int(result.ApiCode.FAILED), "创建登录会话失败")
+		return
+	}
+	if !mfa.Enabled {
+		recoveryCodes, err := activateSysAdminMfa(&mfa)
+		if err != nil {
+			result.Failed(c, int(result.ApiCode.FAILED), err.Error())
+			return
+		}
+		data["recoveryCodes"] = recoveryCodes
+	}
+	clearLoginFailures(sysAdmin.Username)
+	dao.CreateSysLoginInfo(sysAdmin.Username, ip, util.GetRealAddressByIP(ip), util.GetBrowser(c), util.GetOs(c), "登录成功", 1)
+	result.Success(c, data)
+}
+
+var sysAdminMfaService = SysAdminMfaServiceImpl{}
+
+func SysAdminMfaService() ISysAdminMfaService {
+	return &sysAdminMfaService
+}
+
+// 判断用户所属角色是否强制启用二次验证
+func IsMfaRequired(adminId uint) bool {
+	requiredRoles := config.GetAuthConfig().Mfa.RequiredRoles
+	if len(requiredRoles) == 0 {
+		return false
+	}
+	for _, roleKey := range dao.QueryAdminRoleKeyList(adminId) {
+		for _, required := range requiredRoles {
+			if roleKey == required {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// 密码校验通过后判断是否需要二次验证，需要时返回二次验证票据信息
+func loginMfaChallenge(sysAdmin model.SysAdmin) (map[string]interface{}, bool, error) {
+	mfa := dao.GetSysAdminMfa(sysAdmin.ID)
+	if !mfa.Enabled && !IsMfaRequired(sysAdmin.ID) {
+		return nil, false, nil
+	}
+	ticket, err := createMfaTicket(sysAdmin.ID)
+	if err != nil {
+		return nil, true, err
+	}
+	return map[string]interface{}{"mfaRequired": true, "mfaEnrolled": mfa.Enabled, "mfaTicket": ticket}, true, nil
+}
+
+// 生成新密钥并保存为未启用状态，已启用时需先停用
+func enrollSysAdminMfa(sysAdmin model.SysAdmin) (model.SysAdminMfaEnrollVo, error) {
+	if sysAdmin.ID == 0 {
+		return model.SysAdminMfaEnrollVo{}, errors.New("用户不存在")
+	}
+	mfa := dao.GetSysAdminMfa(sysAdmin.ID)
+	if mfa.Enabled {
+		return model.SysAdminMfaEnrollVo{}, errors.New("二次验证已启用，如需更换认证器请先停用")
+	}
+	issuer := config.GetAuthConfig().Mfa.Issuer
+	if issuer == "" {
+		issuer = "AutoOps"
+	}
+	key, err := totp.Generate(totp.GenerateOpts{
+		Issuer:      issuer,
+		AccountName: sysAdmin.Username,
+		Period:      mfaPeriod,
+		Digits:      otp.DigitsSix,
+		Algorithm:   otp.AlgorithmSHA1,
+	})
+	if err != nil {
+		log.Log().Errorf("生成TOTP密钥失败: %v", err)
+		return model.SysAdminMfaEnrollVo{}, errors.New("生成密钥失败")
+	}
+	encrypted, err := util.AESEncrypt(key.Secret())
+	if err != nil {
+		log.Log().Errorf("加密TOTP密钥失败: %v", err)
+		return model.SysAdminMfaEnrollVo{}, errors.New("生成密钥失败")
+	}
+	mfa.AdminId = sysAdmin.ID
+	mfa.Secret = encrypted
+	mfa.RecoveryCodes = ""
+	mfa.LastStep = 0
+	mfa.EnableTime = nil
+	mfa.CreateTime = util.HTime{Time: time.Now()}
+	if err := dao.SaveSysAdminMfa(&mfa); err != nil {
+		return model.SysAdminMfaEnrollVo{}, errors.New("保存密钥失败")
+	}
+	vo := model.SysAdminMfaEnrollVo{Secret: key.Secret(), Url: key.URL()}
+	if img, err := key.Image(200, 200); err == nil {
+		var buf bytes.Buffer
+		if png.Encode(&buf, img) == nil {
+			vo.QrCode = "data:image/png;base64," + base64.StdEncoding.EncodeToString(buf.Bytes())
+		}
+	}
+	return vo, nil
+}
+
+// 启用二次验证并生成恢复码
+func activateSysAdminMfa(mfa *model.SysAdminMfa) ([]string, error) {
+	plainCodes, hashedCodes := generateRecoveryCodes()
+	mfa.Enabled = true
+	mfa.RecoveryCodes = hashedCodes
+	mfa.EnableTime = &util.HTime{Time: time.Now()}
+	if err := dao.SaveSysAdminMfa(mfa); err != nil {
+		return nil, errors.New("启用二次验证失败")
+	}
+	return plainCodes, nil
+}
+
+// 校验认证器验证码或恢复码
+func verifyMfaCode(mfa *model.SysAdminMfa, code string) bool {
+	code = strings.TrimSpace(code)
+	if len(code) == int(otp.DigitsSix) {
+		return verifyTotpCode(mfa, code)
+	}
+	return verifyRecoveryCode(mfa, code)
+}
+
+// 校验认证器验证码，同一时间窗口的验证码只能使用一次
+func verifyTotpCode(mfa *model.SysAdminMfa, code string) bool {
+	code = strings.TrimSpace(code)
+	if len(code) != int(otp.DigitsSix) {
+		return false
+	}
+	secret, err := util.AESDecrypt(mfa.Secret)
+	if err != nil {
+		log.Log().Errorf("解密TOTP密钥失败: %v", err)
+		return false
+	}
+	now := time.Now()
+	for i := -mfaSkew; i <= mfaSkew; i++ {
+		t := now.Add(time.Duration(i*mfaPeriod) * time.Second)
+		expected, err := totp.GenerateCodeCustom(secret, t, totp.ValidateOpts{
+			Period:    mfaPeriod,
+			Digits:    otp.DigitsSix,
+			Algorithm: otp.AlgorithmSHA1,
+		})
+		if err != nil || subtle.ConstantTimeCompare([]byte(expected), []byte(code)) != 1 {
+			continue
+		}
+		step := t.Unix() / mfaPeriod
+		if step <= mfa.LastStep || dao.UpdateSysAdminMfaLastStep(mfa.ID, step) == 0 {
+			return false
+		}
+		mfa.LastStep = step
+		return true
+	}
+	return false
+}
+
+// 校验恢复码，每个恢复码只能使用一次
+func verifyRecoveryCode(mfa *model.SysAdminMfa, code string) bool {
+	hashed := hashRecoveryCode(code)
+	codes := splitRecoveryCodes(mfa.RecoveryCodes)
+	for i, item := range codes {
+		if subtle.ConstantTimeCompare([]byte(item), []byte(hashed)) != 1 {
+			continue
+		}
+		remaining := strings.Join(append(codes[:i:i], codes[i+1:]...), ",")
+		if dao.UpdateSysAdminMfaRecoveryCodes(mfa.ID, mfa.RecoveryCodes, remaining) == 0 {
+			return false
+		}
+		mfa.RecoveryCodes = remaining
+		return true
+	}
+	return false
+}
+
+// 生成恢复码，返回明文列表和逗号分隔的哈希值
+func generateRecoveryCodes() ([]string, string) {
+	plainCodes := make([]string, 0, mfaRecoveryCodeCount)
+	hashedCodes := make([]string, 0, mfaRecoveryCodeCount)
+	for i := 0; i < mfaRecoveryCodeCount; i++ {
+		b := make([]byte, 5)
+		if _, err := rand.Read(b); err != nil {
+			panic(err) // 随机数生成失败是严重错误
+		}
+		code := strings.ToLower(base32.StdEncoding.EncodeToString(b))
+		code = code[:4] + "-" + code[4:]
+		plainCodes = append(plainCodes, code)
+		hashedCodes = append(hashedCodes, hashRecoveryCode(code))
+	}
+	return plainCodes, strings.Join(hashedCodes, ",")
+}
+
+// 恢复码哈希，忽略大小写、空格和分隔符
+func hashRecoveryCode(code string) string {
+	code = strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(strings.TrimSpace(code)))
+	sum := sha256.Sum256([]byte(code))
+	return hex.EncodeToString(sum[:])
+}
+
+func splitRecoveryCodes(codes string) []string {
+	list := make([]string, 0)
+	for _, code := range strings.Split(codes, ",") {
+		if code != "" {
+			list = append(list, code)
+		}
+	}
+	return list
+}
+
+// 创建登录二次验证票据
+func createMfaTicket(adminId uint) (string, error) {
+	if redis.RedisDb == nil {
+		return "", errors.New("redis未初始化")
+	}
+	ticket := util.GenerateRandomString(32)
+	key := constant.MFA_TICKET_CODE + ticket
+	ctx := context.Background()
+	_, err := redis.RedisDb.TxPipelined(ctx, func(pipe goredis.Pipeliner) error {
+		pipe.HSet(ctx, key, "adminId", adminId)
+		pipe.Expire(ctx, key, mfaTicketExpiration)
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	return ticket, nil
+}
+
+// 查询票据对应的用户id，票据不存在或已过期时返回0
+func getMfaTicket(ticket string) uint {
+	if redis.RedisDb == nil || ticket == "" {
+		return 0
+	}
+	val, err := redis.RedisDb.HGet(context.Background(), constant.MFA_TICKET_CODE+ticket, "adminId").Result()
+	if err != nil {
+		return 0
+	}
+	adminId, _ := strconv.ParseUint(val, 10, 64)
+	return uint(adminId)
+}
+
+// 删除票据，返回是否由本次调用删除，用于保证票据只能使用一次
+func deleteMfaTicket(ticket string) bool {
+	if redis.RedisDb == nil {
+		return false
+	}
+	n, err := redis.RedisDb.Del(context.Background(), constant.MFA_TICKET_CODE+ticket).Result()
+	return err == nil && n == 1
+}
+
+// 记录票据验证失败次数，超过上限后票据失效，需重新输入密码登录
+func recordMfaTicketFailure(ticket string) {
+	if redis.RedisDb == nil {
+		return
+	}
+	failures, err := redis.RedisDb.HIncrBy(context.Background(), constant.MFA_TICKET_CODE+ticket, "failures", 1).Result()
+	if err != nil || failures >= mfaTicketMaxFailures {
+		deleteMfaTicket(ticket)
+	}
+}
@@ -0,0 +1,194 @@
+// 个人访问令牌 服务层
+// author xiaoRui
+
+package service
+
+import (
+	"crypto/sha256"
+	"dodevops-api/api/system/dao"
+	"dodevops-api/api/system/model"
+	"dodevops-api/common/constant"
+	"dodevops-api/common/result"
+	"dodevops-api/common/util"
+	"dodevops-api/pkg/jwt"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+)
+
+// 最后使用时间的更新间隔，避免每次请求都写库
+const apiTokenTouchInterval = time.Minute
+
+// ApiTokenScopes 个人访问令牌的授权范围
+type ApiTokenScopes []string
+
+// 判断授权范围是否包含指定权限值
+func (s ApiTokenScopes) Has(value string) bool {
+	for _, v := range s {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// 定义接口
+type ISysApiTokenService interface {
+	CreateSysApiToken(c *gin.Context, dto model.AddSysApiTokenDto) // 新增令牌
+	GetSysApiTokenList(c *gin.Context)                             // 当前用户的令牌列表
+	DeleteSysApiToken(c *gin.Context, dto model.SysApiTokenIdDto)  // 吊销令牌
+}
+
+type SysApiTokenServiceImpl struct{}
+
+// 新增令牌，授权范围必须是当前用户拥有的权限子集
+func (s SysApiTokenServiceImpl) CreateSysApiToken(c *gin.Context, dto model.AddSysApiTokenDto) {
+	if IsApiTokenRequest(c) {
+		result.Failed(c, int(result.ApiCode.NOPERMISSION), "不允许使用访问令牌管理访问令牌")
+		return
+	}
+	if err := validator.New().Struct(dto); err != nil {
+		result.Failed(c, int(result.ApiCode.ValidationParameterError), result.ApiCode.GetMessage(result.ApiCode.ValidationParameterError))
+		return
+	}
+	adminId, _ := jwt.GetAdminId(c)
+	permission := GetAdminPermission(adminId)
+	scopes := make([]string, 0, len(dto.Scopes))
+	for _, scope := range dto.Scopes {
+		scope = strings.TrimSpace(scope)
+		if scope == "" || ApiTokenScopes(scopes).Has(scope) {
+			continue
+		}
+		if !permission.Has(scope) {
+			result.Failed(c, int(result.ApiCode.NOPERMISSION), "没有权限授予: "+scope)
+			return
+		}
+		scopes = append(scopes, scope)
+	}
+	if len(scopes) == 0 {
+		result.Failed(c, int(result.ApiCode.ValidationParameterError), "授权范围不能为空")
+		return
+	}
+	plainToken := model.ApiTokenPrefix + util.GenerateRandomString(40)
+	token := model.SysApiToken{
+		AdminId:     adminId,
+		Name:        dto.Name,
+		TokenPrefix: plainToken[:12],
+		TokenHash:   hashApiToken(plainToken),
+		Scopes:      strings.Join(scopes, ","),
+		CreateTime:  util.HTime{Time: time.Now()},
+	}
+	if dto.ExpireDays > 0 {
+		token.ExpireTime = &util.HTime{Time: time.Now().AddDate(0, 0, dto.ExpireDays)}
+	}
+	if err := dao.CreateSysApiToken(&token); err != nil {
+		result.Failed(c, int(result.ApiCode.FAILED), "创建访问令牌失败")
+		return
+	}
+	result.Success(c, model.SysApiTokenCreatedVo{SysApiTokenVo: toSysApiTokenVo(token), Token: plainToken})
+}
+
+// 当前用户的令牌列表
+func (s SysApiTokenServiceImpl) GetSysApiTokenList(c *gin.Context) {
+	adminId, _ := jwt.GetAdminId(c)
+	list := make([]model.SysApiTokenVo, 0)
+	for _, token := range dao.GetSysApiTokenListByAdminId(adminId) {
+		list = append(list, toSysApiTokenVo(token))
+	}
+	result.Success(c, list)
+}
+
+// 吊销令牌
+func (s SysApiTokenServiceImpl) DeleteSysApiToken(c *gin.Context, dto model.SysApiTokenIdDto) {
+	if IsApiTokenRequest(c) {
+		result.Failed(c, int(result.ApiCode.NOPERMISSION), "不允许使用访问令牌管理访问令牌")
+		return
+	}
+	adminId, _ := jwt.GetAdminId(c)
+	if dao.DeleteSysApiToken(adminId, dto.Id) == 0 {
+		result.Failed(c, int(result.ApiCode.FAILED), "访问令牌不存在")
+		return
+	}
+	result.Success(c, true)
+}
+
+var sysApiTokenService = SysApiTokenServiceImpl{}
+
+func SysApiTokenService() ISysApiTokenService {
+	return &sysApiTokenService
+}
+
+// 校验个人访问令牌，返回令牌所属用户和授权范围
+func ValidateApiToken(plainToken, ip string) (*model.JwtAdmin, ApiTokenScopes, error) {
+	if !strings.HasPrefix(plainToken, model.ApiTokenPrefix) {
+		return nil, nil, errors.New("令牌格式错误")
+	}
+	token := dao.GetSysApiTokenByHash(hashApiToken(plainToken))
+	if token.ID == 0 {
+		return nil, nil, errors.New("令牌不存在或已吊销")
+	}
+	if token.ExpireTime != nil && token.ExpireTime.Before(time.Now()) {
+		return nil, nil, errors.New("令牌已过期")
+	}
+	sysAdmin := dao.GetSysAdminById(token.AdminId)
+	const status int = 2
+	if sysAdmin.ID == 0 || sysAdmin.Status == status {
+		return nil, nil, errors.New("令牌所属用户不存在或已停用")
+	}
+	if token.LastUsedTime == nil || time.Since(token.LastUsedTime.Time) > apiTokenTouchInterval || token.LastUsedIp != ip {
+		dao.UpdateSysApiTokenLastUsed(token.ID, ip)
+	}
+	jwtAdmin := &model.JwtAdmin{
+		ID:       sysAdmin.ID,
+		Username: sysAdmin.Username,
+		Nickname: sysAdmin.Nickname,
+		Icon:     sysAdmin.Icon,
+		Email:    sysAdmin.Email,
+		Phone:    sysAdmin.Phone,
+		Note:     sysAdmin.Note,
+	}
+	return jwtAdmin, splitApiTokenScopes(token.Scopes), nil
+}
+
+// 获取当前请求使用的令牌授权范围，ok 为 false 表示不是通过个人访问令牌认证
+func GetApiTokenScopes(c *gin.Context) (ApiTokenScopes, bool) {
+	v, exists := c.Get(constant.ContextKeyApiTokenScopes)
+	if !exists {
+		return nil, false
+	}
+	scopes, ok := v.(ApiTokenScopes)
+	return scopes, ok
+}
+
+// 判断当前请求是否通过个人访问令牌认证
+func IsApiTokenRequest(c *gin.Context) bool {
+	_, ok := GetApiTokenScopes(c)
+	return ok
+}
+
+func hashApiToken(plainToken string) string {
+	sum := sha256.Sum256([]byte(plainToken))
+	return hex.EncodeToString(sum[:])
+}
+
+func splitApiTokenScopes(scopes string) ApiTokenScopes {
+	list := make(ApiTokenScopes, 0)
+	for _, scope := range strings.Split(scopes, ",") {
+		if scope = strings.TrimSpace(scope); scope != "" {
+			list = append(list, scope)
+		}
+	}
+	return list
+}
+
+func toSysApiTokenVo(token model.SysApiToken) model.SysApiTokenVo {
+	return model.SysApiTokenVo{
+		SysApiToken: token,
+		Scopes:      splitApiTokenScopes(token.Scopes),
+		Expired:     token.ExpireTime != nil && token.ExpireTime.Before(time.Now()),
+	}
+}
@@ -0,0 +1,575 @@
+// 审批流程 服务层
+// author xiaoRui
+
+package service
+
+import (
+	"bytes"
+	"context"
+	monitorservice "dodevops-api/api/monitor/service"
+	"dodevops-api/api/system/dao"
+	"dodevops-api/api/system/model"
+	"dodevops-api/common/constant"
+	"dodevops-api/common/result"
+	"dodevops-api/common/util"
+	"dodevops-api/pkg/approval"
+	"dodevops-api/pkg/jwt"
+	"dodevops-api/pkg/log"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 管理审批策略、查看全部审批单所需的权限值
+const approvalManagePermission = "base:approval:policy"
+
+// 审批单保存的请求体和执行结果的最大长度
+const (
+	maxApprovalBodySize   = 1 << 20
+	maxApprovalResultSize = 64 << 10
+)
+
+type ISysApprovalService interface {
+	GetSysApprovalPolicyList(c *gin.Context)                                              // 审批策略列表
+	CreateSysApprovalPolicy(c *gin.Context, dto model.SysApprovalPolicyDto)               // 新增审批策略
+	UpdateSysApprovalPolicy(c *gin.Context, dto model.SysApprovalPolicyDto)               // 修改审批策略
+	DeleteSysApprovalPolicy(c *gin.Context, dto model.SysApprovalPolicyIdDto)             // 删除审批策略
+	GetSysApprovalConditionList(c *gin.Context)                                           // 可用的审批条件
+	GetSysApprovalTicketList(c *gin.Context, scope string, status, PageSize, PageNum int) // 审批单列表
+	GetSysApprovalTicketInfo(c *gin.Context, id uint)                                     // 审批单详情
+	ApproveSysApprovalTicket(c *gin.Context, dto model.SysApprovalTicketActionDto)        // 同意
+	RejectSysApprovalTicket(c *gin.Context, dto model.SysApprovalTicketActionDto)         // 拒绝
+	CommentSysApprovalTicket(c *gin.Context, dto model.SysApprovalTicketActionDto)        // 评论
+	CancelSysApprovalTicket(c *gin.Context, dto model.SysApprovalTicketActionDto)         // 撤销
+}
+
+type SysApprovalServiceImpl struct{}
+
+// 审批策略列表
+func (s SysApprovalServiceImpl) GetSysApprovalPolicyList(c *gin.Context) {
+	result.Success(c, dao.GetSysApprovalPolicyList())
+}
+
+// 校验审批策略参数
+func buildSysApprovalPolicy(dto model.SysApprovalPolicyDto) (model.SysApprovalPolicy, error) {
+	dto.Method = strings.ToUpper(strings.TrimSpace(dto.Method))
+	dto.Path = strings.TrimSpace(dto.Path)
+	if dto.Name == "" || dto.Method == "" || !strings.HasPrefix(dto.Path, "/api/v1/") {
+		return model.SysApprovalPolicy{}, errors.New("策略名称、请求方式不能为空，路由模板需以 /api/v1/ 开头")
+	}
+	if dto.Method == http.MethodGet || dto.Method == http.MethodHead || dto.Method == http.MethodOptions {
+		return model.SysApprovalPolicy{}, errors.New("只读请求不需要审批")
+	}
+	if len(dto.ApproverRoleIds) == 0 {
+		return model.SysApprovalPolicy{}, errors.New("请选择审批人角色")
+	}
+	if dto.RequiredApprovals < 1 {
+		dto.RequiredApprovals = 1
+	}
+	if dto.Status != 2 {
+		dto.Status = 1
+	}
+	roleIds := make([]string, 0, len(dto.ApproverRoleIds))
+	for _, roleId := range dto.ApproverRoleIds {
+		roleIds = append(roleIds, strconv.Itoa(int(roleId)))
+	}
+	return model.SysApprovalPolicy{
+		ID:                dto.Id,
+		Name:              dto.Name,
+		Method:            dto.Method,
+		Path:              dto.Path,
+		Condition:         dto.Condition,
+		ApproverRoleIds:   strings.Join(roleIds, ","),
+		RequiredApprovals: dto.RequiredApprovals,
+		DingDingUrl:       dto.DingDingUrl,
+		FeiShuUrl:         dto.FeiShuUrl,
+		Emails:            dto.Emails,
+		Status:            dto.Status,
+		Remark:            dto.Remark,
+	}, nil
+}
+
+// 新增审批策略
+func (s SysApprovalServiceImpl) CreateSysApprovalPolicy(c *gin.Context, dto model.SysApprovalPolicyDto) {
+	policy, err := buildSysApprovalPolicy(dto)
+	if err != nil {
+		result.Failed(c, int(result.ApiCode.FAILED), err.Error())
+		return
+	}
+	policy.ID = 0
+	policy.CreateTime = util.HTime{Time: time.Now()}
+	if err := dao.CreateSysApprovalPolicy(&policy); err != nil {
+		result.Failed(c, int(result.ApiCode.FAILED), err.Error())
+		return
+	}
+	result.Success(c, policy)
+}
+
+// 修改审批策略
+func (s SysApprovalServiceImpl) UpdateSysApprovalPolicy(c *gin.Context, dto model.SysApprovalPolicyDto) {
+	old := dao.GetSysApprovalPolicyById(dto.Id)
+	if old.ID == 0 {
+		result.Failed(c, int(result.ApiCode.FAILED), "审批策略不存在")
+		return
+	}
+	policy, err := buildSysApprovalPolicy(dto)
+	if err != nil {
+		result.Failed(c, int(result.ApiCode.FAILED), err.Error())
+		return
+	}
+	policy.CreateTime = old.CreateTime
+	if err := dao.UpdateSysApprovalPolicy(policy); err != nil {
+		result.Failed(c, int(result.ApiCode.FAILED), err.Error())
+		return
+	}
+	result.Success(c, policy)
+}
+
+// 删除审批策略，已提交的审批单不受影响
+func (s SysApprovalServiceImpl) DeleteSysApprovalPolicy(c *gin.Context, dto model.SysApprovalPolicyIdDto) {
+	dao.DeleteSysApprovalPolicyById(dto.Id)
+	result.Success(c, true)
+}
+
+// 可用的审批条件
+func (s SysApprovalServiceImpl) GetSysApprovalConditionList(c *gin.Context) {
+	result.Success(c, approval.ConditionNames())
+}
+
+// 审批单列表：mine->我提交的,todo->待我审批的,all->全部（需要审批管理权限）
+func (s SysApprovalServiceImpl) GetSysApprovalTicketList(c *gin.Context, scope string, status, PageSize, PageNum int) {
+	if PageSize < 1 {
+		PageSize = 10
+	}
+	if PageNum < 1 {
+		PageNum = 1
+	}
+	adminId, _ := jwt.GetAdminId(c)
+	switch scope {
+	case "all":
+		if !GetAdminPermission(adminId).Has(approvalManagePermission) {
+			result.Failed(c, int(result.ApiCode.NOPERMISSION), result.ApiCode.GetMessage(result.ApiCode.NOPERMISSION))
+			return
+		}
+		list, count := dao.GetSysApprovalTicketList(status, 0, PageSize, PageNum)
+		result.Success(c, map[string]interface{}{"total": count, "pageSize": PageSize, "pageNum": PageNum, "list": maskApprovalTickets(list)})
+	case "todo":
+		// 待审批的审批单数量有限，全部查出后按审批人过滤再分页
+		pending, _ := dao.GetSysApprovalTicketList(model.ApprovalStatusPending, 0, -1, 1)
+		list := make([]model.SysApprovalTicket, 0)
+		for _, ticket := range pending {
+			if canApproveTicket(adminId, ticket) {
+				list = append(list, ticket)
+			}
+		}
+		count := len(list)
+		start := (PageNum - 1) * PageSize
+		if start > count {
+			start = count
+		}
+		end := start + PageSize
+		if end > count {
+			end = count
+		}
+		result.Success(c, map[string]interface{}{"total": count, "pageSize": PageSize, "pageNum": PageNum, "list": maskApprovalTickets(list[start:end])})
+	default:
+		list, count := dao.GetSysApprovalTicketList(status, adminId, PageSize, PageNum)
+		result.Success(c, map[string]interface{}{"total": count, "pageSize": PageSize, "pageNum": PageNum, "list": maskApprovalTickets(list)})
+	}
+}
+
+// 审批单详情，申请人、审批人和审批管理员可以查看
+func (s SysApprovalServiceImpl) GetSysApprovalTicketInfo(c *gin.Context, id uint) {
+	adminId, _ := jwt.GetAdminId(c)
+	ticket := dao.GetSysApprovalTicketById(id)
+	if ticket.ID == 0 {
+		result.Failed(c, int(result.ApiCode.FAILED), "审批单不存在")
+		return
+	}
+	if ticket.ApplicantId != adminId && !isTicketApprover(adminId, ticket) && !GetAdminPermission(adminId).Has(approvalManagePermission) {
+		result.Failed(c, int(result.ApiCode.NOPERMISSION), result.ApiCode.GetMessage(result.ApiCode.NOPERMISSION))
+		return
+	}
+	result.Success(c, model.SysApprovalTicketVo{SysApprovalTicket: maskApprovalTicket(ticket), Comments: dao.GetSysApprovalCommentList(id)})
+}
+
+// 同意审批单，同意人数达到要求后以申请人身份执行原请求
+func (s SysApprovalServiceImpl) ApproveSysApprovalTicket(c *gin.Context, dto model.SysApprovalTicketActionDto) {
+	ticket, comment, ok := prepareApprovalVote(c, dto, model.ApprovalActionApprove)
+	if !ok {
+		return
+	}
+	approved, err := dao.ApproveSysApprovalTicket(&comment)
+	if err != nil {
+		result.Failed(c, int(result.ApiCode.FAILED), err.Error())
+		return
+	}
+	ticket = dao.GetSysApprovalTicketById(ticket.ID)
+	if approved {
+		go executeApprovalTicket(ticket)
+	}
+	result.Success(c, ticket)
+}
+
+// 拒绝审批单
+func (s SysApprovalServiceImpl) RejectSysApprovalTicket(c *gin.Context, dto model.SysApprovalTicketActionDto) {
+	ticket, comment, ok := prepareApprovalVote(c, dto, model.ApprovalActionReject)
+	if !ok {
+		return
+	}
+	if err := dao.RejectSysApprovalTicket(&comment); err != nil {
+		result.Failed(c, int(result.ApiCode.FAILED), err.Error())
+		return
+	}
+	go notifyApprovalTicket(ticket, "审批已拒绝", fmt.Sprintf("%s 拒绝了审批单：%s", comment.Username, comment.Content))
+	result.Success(c, dao.GetSysApprovalTicketById(ticket.ID))
+}
+
+// 评论审批单
+func (s SysApprovalServiceImpl) CommentSysApprovalTicket(c *gin.Context, dto model.SysApprovalTicketActionDto) {
+	admin, _ := jwt.GetAdmin(c)
+	ticket := dao.GetSysApprovalTicketById(dto.Id)
+	if ticket.ID == 0 || admin == nil {
+		result.Failed(c, int(result.ApiCode.FAILED), "审批单不存在")
+		return
+	}
+	if strings.TrimSpace(dto.Content) == "" {
+		result.Failed(c, int(result.ApiCode.FAILED), "评论内容不能为空")
+		return
+	}
+	if ticket.ApplicantId != admin.ID && !isTicketApprover(admin.ID, ticket) {
+		result.Failed(c, int(result.ApiCode.NOPERMISSION), result.ApiCode.GetMessage(result.ApiCode.NOPERMISSION))
+		return
+	}
+	comment := newApprovalComment(admin, ticket.ID, model.ApprovalActionComment, dto.Content)
+	if err := dao.CreateSysApprovalComment(&comment); err != nil {
+		result.Failed(c, int(result.ApiCode.FAILED), err.Error())
+		return
+	}
+	result.Success(c, comment)
+}
+
+// 申请人撤销待审批的审批单
+func (s SysApprovalServiceImpl) CancelSysApprovalTicket(c *gin.Context, dto model.SysApprovalTicketActionDto) {
+	admin, _ := jwt.GetAdmin(c)
+	ticket := dao.GetSysApprovalTicketById(dto.Id)
+	if ticket.ID == 0 || admin == nil || ticket.ApplicantId != admin.ID {
+		result.Failed(c, int(result.ApiCode.FAILED), "审批单不存在")
+		return
+	}
+	comment := newApprovalComment(admin, ticket.ID, model.ApprovalActionCancel, dto.Content)
+	if err := dao.CancelSysApprovalTicket(&comment); err != nil {
+		result.Failed(c, int(result.ApiCode.FAILED), err.Error())
+		return
+	}
+	result.Success(c, dao.GetSysApprovalTicketById(ticket.ID))
+}
+
+// 校验当前用户能否审批，返回审批单和待保存的审批记录
+func prepareApprovalVote(c *gin.Context, dto model.SysApprovalTicketActionDto, action int) (model.SysApprovalTicket, model.SysApprovalComment, bool) {
+	admin, _ := jwt.GetAdmin(c)
+	ticket := dao.GetSysApprovalTicketById(dto.Id)
+	if ticket.ID == 0 || admin == nil {
+		result.Failed(c, int(result.ApiCode.FAILED), "审批单不存在")
+		return ticket, model.SysApprovalComment{}, false
+	}
+	if ticket.Status != model.ApprovalStatusPending {
+		result.Failed(c, int(result.ApiCode.FAILED), dao.ErrApprovalNotPending.Error())
+		return ticket, model.SysApprovalComment{}, false
+	}
+	if !canApproveTicket(admin.ID, ticket) {
+		result.Failed(c, int(result.ApiCode.NOPERMISSION), "您不是该审批单的审批人")
+		return ticket, model.SysApprovalComment{}, false
+	}
+	return ticket, newApprovalComment(admin, ticket.ID, action, dto.Content), true
+}
+
+func newApprovalComment(admin *model.JwtAdmin, ticketId uint, action int, content string) model.SysApprovalComment {
+	return model.SysApprovalComment{
+		TicketId:   ticketId,
+		AdminId:    admin.ID,
+		Username:   admin.Username,
+		Action:     action,
+		Content:    content,
+		CreateTime: util.HTime{Time: time.Now()},
+	}
+}
+
+// 判断用户是否属于审批单的审批人角色，未指定角色（历史数据）时只有超级管理员可以审批
+func isTicketApprover(adminId uint, ticket model.SysApprovalTicket) bool {
+	roleIds := strings.Split(ticket.ApproverRoleIds, ",")
+	for _, role := range dao.QueryAdminEnabledRoleList(adminId) {
+		if ticket.ApproverRoleIds == "" && role.RoleKey == constant.SUPER_ADMIN_ROLE_KEY {
+			return true
+		}
+		for _, roleId := range roleIds {
+			if roleId == strconv.Itoa(int(role.ID)) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// 申请人不能审批自己的审批单
+func canApproveTicket(adminId uint, ticket model.SysApprovalTicket) bool {
+	return adminId != ticket.ApplicantId && isTicketApprover(adminId, ticket)
+}
+
+// 按审批策略拦截请求：命中策略时保存原始请求并生成审批单，未命中时返回 nil
+// 申请原因可通过请求头 X-Approval-Reason 传入
+func SubmitApproval(c *gin.Context) (*model.SysApprovalTicket, error) {
+	policies := dao.GetEnabledSysApprovalPolicyList(c.Request.Method, c.FullPath())
+	if len(policies) == 0 {
+		return nil, nil
+	}
+	var body []byte
+	if c.Request.Body != nil {
+		var err error
+		body, err = io.ReadAll(io.LimitReader(c.Request.Body, maxApprovalBodySize+1))
+		if err != nil {
+			return nil, err
+		}
+		if len(body) > maxApprovalBodySize {
+			return nil, errors.New("请求体过大，无法提交审批")
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+	}
+	for _, policy := range policies {
+		if !approval.MatchCondition(policy.Condition, c, body) {
+			continue
+		}
+		admin, err := jwt.GetAdmin(c)
+		if err != nil {
+			return nil, err
+		}
+		ticket := model.SysApprovalTicket{
+			PolicyId:          policy.ID,
+			Title:             fmt.Sprintf("%s：%s %s", policy.Name, c.Request.Method, c.Request.URL.Path),
+			Method:            c.Request.Method,
+			Path:              c.FullPath(),
+			Url:               c.Request.URL.RequestURI(),
+			ContentType:       c.ContentType(),
+			Body:              string(body),
+			Reason:            c.GetHeader("X-Approval-Reason"),
+			ApplicantId:       admin.ID,
+			ApplicantName:     admin.Username,
+			ApproverRoleIds:   policy.ApproverRoleIds,
+			RequiredApprovals: policy.RequiredApprovals,
+			Status:            model.ApprovalStatusPending,
+			CreateTime:        util.HTime{Time: time.Now()},
+		}
+		if err := dao.CreateSysApprovalTicket(&ticket); err != nil {
+			return nil, err
+		}
+		go notifyApprovalTicket(ticket, "待审批", fmt.Sprintf("%s 提交了审批单，需要 %d 人审批\n\n申请原因：%s",
+			ticket.ApplicantName, ticket.RequiredApprovals, ticket.Reason))
+		ticket = maskApprovalTicket(ticket)
+		return &ticket, nil
+	}
+	return nil, nil
+}
+
+// 审批单展示时隐藏的敏感字段，字段名忽略大小写、下划线和中划线
+var approvalSecretFields = []string{"password", "passwd", "secret", "privatekey", "passphrase", "token"}
+
+func isApprovalSecretField(name string) bool {
+	name = strings.NewReplacer("_", "", "-", "").Replace(strings.ToLower(name))
+	for _, field := range approvalSecretFields {
+		if strings.Contains(name, field) {
+			return true
+		}
+	}
+	return false
+}
+
+// 隐藏审批单请求体和执行结果中的敏感字段，保存的原始请求不变
+func maskApprovalTicket(ticket model.SysApprovalTicket) model.SysApprovalTicket {
+	ticket.Body = maskApprovalContent(ticket.ContentType, ticket.Body)
+	ticket.Result = maskApprovalContent("application/json", ticket.Result)
+	return ticket
+}
+
+func maskApprovalTickets(tickets []model.SysApprovalTicket) []model.SysApprovalTicket {
+	masked := make([]model.SysApprovalTicket, 0, len(tickets))
+	for _, ticket := range tickets {
+		masked = append(masked, maskApprovalTicket(ticket))
+	}
+	return masked
+}
+
+// 支持 JSON 和表单格式的内容，其他格式原样返回
+func maskApprovalContent(contentType, content string) string {
+	if content == "" {
+		return content
+	}
+	if strings.HasPrefix(contentType, "application/x-www-form-urlencoded") {
+		values, err := url.ParseQuery(content)
+		if err != nil {
+			return content
+		}
+		for key := range values {
+			if isApprovalSecretField(key) {
+				values[key] = []string{"******"}
+			}
+		}
+		return values.Encode()
+	}
+	decoder := json.NewDecoder(strings.NewReader(content))
+	decoder.UseNumber()
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return content
+	}
+	data, err := json.Marshal(maskApprovalValue(value))
+	if err != nil {
+		return content
+	}
+	return string(data)
+}
+
+func maskApprovalValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, item := range v {
+			if isApprovalSecretField(key) {
+				if _, ok := item.(string); ok || item == nil {
+					v[key] = "******"
+					continue
+				}
+			}
+			v[key] = maskApprovalValue(item)
+		}
+	case []interface{}:
+		for i, item := range v {
+			v[i] = maskApprovalValue(item)
+		}
+	}
+	return value
+}
+
+// 记录重放请求的响应
+type approvalResponseRecorder struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (r *approvalResponseRecorder) Header() http.Header {
+	return r.header
+}
+
+func (r *approvalResponseRecorder) Write(data []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	if remain := maxApprovalResultSize - r.body.Len(); remain > 0 {
+		if len(data) > remain {
+			r.body.Write(data[:remain])
+		} else {
+			r.body.Write(data)
+		}
+	}
+	return len(data), nil
+}
+
+func (r *approvalResponseRecorder) WriteHeader(status int) {
+	if r.status == 0 {
+		r.status = status
+	}
+}
+
+// 以申请人身份重放审批通过的请求，重放时仍会校验申请人当前的接口权限
+func executeApprovalTicket(ticket model.SysApprovalTicket) {
+	status, code, message := runApprovalTicket(ticket)
+	dao.FinishSysApprovalTicket(ticket.ID, status, code, message)
+	title := "审批通过，执行成功"
+	if status != model.ApprovalStatusSuccess {
+		title = "审批通过，执行失败"
+	}
+	go notifyApprovalTicket(ticket, title, maskApprovalContent("application/json", message))
+}
+
+func runApprovalTicket(ticket model.SysApprovalTicket) (status, code int, message string) {
+	handler := approval.Handler()
+	if handler == nil {
+		return model.ApprovalStatusFailed, 0, "审批执行器未初始化"
+	}
+	admin := dao.GetSysAdminById(ticket.ApplicantId)
+	if admin.ID == 0 || admin.Status != 1 {
+		return model.ApprovalStatusFailed, 0, "申请人不存在或已停用"
+	}
+	req, err := http.NewRequest(ticket.Method, ticket.Url, strings.NewReader(ticket.Body))
+	if err != nil {
+		return model.ApprovalStatusFailed, 0, err.Error()
+	}
+	if ticket.ContentType != "" {
+		req.Header.Set("Content-Type", ticket.ContentType)
+	}
+	req.RemoteAddr = "127.0.0.1:0"
+	req = req.WithContext(approval.WithExecution(context.Background(), &approval.Execution{
+		TicketId: ticket.ID,
+		Admin:    &model.JwtAdmin{ID: admin.ID, Username: admin.Username, Nickname: admin.Nickname, Email: admin.Email, Phone: admin.Phone},
+	}))
+
+	recorder := &approvalResponseRecorder{header: http.Header{}}
+	func() {
+		defer func() {
+			if r := recover(); r != nil {
+				recorder.status = http.StatusInternalServerError
+				recorder.body.WriteString(fmt.Sprint(r))
+			}
+		}()
+		handler.ServeHTTP(recorder, req)
+	}()
+
+	code = recorder.status
+	message = recorder.body.String()
+	var res result.Result
+	if json.Unmarshal(recorder.body.Bytes(), &res) == nil && res.Code != 0 {
+		code = res.Code
+	}
+	if recorder.status >= 200 && recorder.status < 300 && code == int(result.ApiCode.SUCCESS) {
+		return model.ApprovalStatusSuccess, code, message
+	}
+	return model.ApprovalStatusFailed, code, message
+}
+
+// 通过审批策略配置的钉钉、飞书和邮件发送审批通知
+func notifyApprovalTicket(ticket model.SysApprovalTicket, title, message string) {
+	policy := dao.GetSysApprovalPolicyById(ticket.PolicyId)
+	if policy.ID == 0 {
+		return
+	}
+	logsign := fmt.Sprintf("[approval-%d]", ticket.ID)
+	title = fmt.Sprintf("[%s] %s", title, ticket.Title)
+	text := fmt.Sprintf("### %s\n\n- 申请人：%s\n- 请求：%s %s\n- 审批进度：%d/%d\n\n%s",
+		title, ticket.ApplicantName, ticket.Method, ticket.Url, ticket.ApprovedCount, ticket.RequiredApprovals, message)
+	if policy.DingDingUrl != "" {
+		monitorservice.PostToDingDing(title, text, policy.DingDingUrl, "", logsign)
+	}
+	if policy.FeiShuUrl != "" {
+		monitorservice.PostToFS(title, text, policy.FeiShuUrl, "", logsign)
+	}
+	if policy.Emails != "" {
+		body := strings.ReplaceAll(html.EscapeString(text), "\n", "<br/>")
+		if res := monitorservice.NewAlertService().SendEmail(body, policy.Emails, title, logsign); !strings.HasPrefix(res, "email send ok") {
+			log.Log().Warnf("%s 审批邮件发送失败: %s", logsign, res)
+		}
+	}
+}
+
+var sysApprovalService = SysApprovalServiceImpl{}
+
+func SysApprovalService() ISysApprovalService {
+	return &sysApprovalService
+}
@@ -0,0 +1,328 @@
+// 终端命令规则 服务层
+// author xiaoRui
+
+package service
+
+import (
+	"dodevops-api/api/system/dao"
+	"dodevops-api/api/system/model"
+	"dodevops-api/common/constant"
+	"dodevops-api/common/result"
+	"dodevops-api/common/util"
+	wsutil "dodevops-api/common/util/websocket"
+	"dodevops-api/pkg/jwt"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 操作日志中终端命令记录的请求方式
+const terminalLogMethod = "terminal"
+
+// 操作日志中记录的命令最大长度
+const maxLoggedCommandLength = 160
+
+type ISysCommandRuleService interface {
+	GetSysCommandRuleList(c *gin.Context)                                 // 命令规则列表
+	CreateSysCommandRule(c *gin.Context, dto model.SysCommandRuleDto)     // 新增命令规则
+	UpdateSysCommandRule(c *gin.Context, dto model.SysCommandRuleDto)     // 修改命令规则
+	DeleteSysCommandRule(c *gin.Context, dto model.SysCommandRuleIdDto)   // 删除命令规则
+	CheckSysCommandRule(c *gin.Context, dto model.SysCommandRuleCheckDto) // 检查命令命中的规则
+}
+
+type SysCommandRuleServiceImpl struct{}
+
+// 命令规则列表
+func (s SysCommandRuleServiceImpl) GetSysCommandRuleList(c *gin.Context) {
+	result.Success(c, dao.GetSysCommandRuleList())
+}
+
+// 校验命令规则参数
+func buildSysCommandRule(dto model.SysCommandRuleDto) (model.SysCommandRule, error) {
+	dto.Name = strings.TrimSpace(dto.Name)
+	dto.Pattern = strings.TrimSpace(dto.Pattern)
+	if dto.Name == "" || dto.Pattern == "" {
+		return model.SysCommandRule{}, errors.New("规则名称和匹配内容不能为空")
+	}
+	switch dto.MatchType {
+	case model.CommandMatchPrefix:
+		dto.Pattern = normalizeCommand(dto.Pattern)
+	case model.CommandMatchRegex:
+		if _, err := regexp.Compile(dto.Pattern); err != nil {
+			return model.SysCommandRule{}, fmt.Errorf("正则表达式格式错误: %v", err)
+		}
+	default:
+		return model.SysCommandRule{}, errors.New("匹配方式只能是前缀或正则")
+	}
+	if dto.Action != model.CommandRuleAllow && dto.Action != model.CommandRuleDeny && dto.Action != model.CommandRuleConfirm {
+		return model.SysCommandRule{}, errors.New("规则动作只能是放行、拦截或需要确认")
+	}
+	if dto.Priority == 0 {
+		dto.Priority = 100
+	}
+	if dto.Status != 2 {
+		dto.Status = 1
+	}
+	return model.SysCommandRule{
+		ID:        dto.Id,
+		Name:      dto.Name,
+		MatchType: dto.MatchType,
+		Pattern:   dto.Pattern,
+		Action:    dto.Action,
+		RoleIds:   joinIds(dto.RoleIds),
+		GroupIds:  joinIds(dto.GroupIds),
+		Priority:  dto.Priority,
+		Status:    dto.Status,
+		Remark:    dto.Remark,
+	}, nil
+}
+
+// 新增命令规则
+func (s SysCommandRuleServiceImpl) CreateSysCommandRule(c *gin.Context, dto model.SysCommandRuleDto) {
+	rule, err := buildSysCommandRule(dto)
+	if err != nil {
+		result.Failed(c, int(result.ApiCode.FAILED), err.Error())
+		return
+	}
+	rule.ID = 0
+	rule.CreateTime = util.HTime{Time: time.Now()}
+	if err := dao.CreateSysCommandRule(&rule); err != nil {
+		result.Failed(c, int(result.ApiCode.FAILED), err.Error())
+		return
+	}
+	result.Success(c, rule)
+}
+
+// 修改命令规则，已连接的终端在重新连接后生效
+func (s SysCommandRuleServiceImpl) UpdateSysCommandRule(c *gin.Context, dto model.SysCommandRuleDto) {
+	old := dao.GetSysCommandRuleById(dto.Id)
+	if old.ID == 0 {
+		result.Failed(c, int(result.ApiCode.FAILED), "命令规则不存在")
+		return
+	}
+	rule, err := buildSysCommandRule(dto)
+	if err != nil {
+		result.Failed(c, int(result.ApiCode.FAILED), err.Error())
+		return
+	}
+	rule.CreateTime = old.CreateTime
+	if err := dao.UpdateSysCommandRule(rule); err != nil {
+		result.Failed(c, int(result.ApiCode.FAILED), err.Error())
+		return
+	}
+	result.Success(c, rule)
+}
+
+// 删除命令规则
+func (s SysCommandRuleServiceImpl) DeleteSysCommandRule(c *gin.Context, dto model.SysCommandRuleIdDto) {
+	dao.DeleteSysCommandRuleById(dto.Id)
+	result.Success(c, true)
+}
+
+// 检查命令在指定角色和分组下命中的规则，用于配置规则时验证
+func (s SysCommandRuleServiceImpl) CheckSysCommandRule(c *gin.Context, dto model.SysCommandRuleCheckDto) {
+	rules := loadCommandRules(dto.RoleIds, dto.GroupId)
+	vo := model.SysCommandRuleCheckVo{Action: model.CommandRuleAllow}
+	if rule := matchCommandRule(rules, strings.TrimSpace(dto.Command)); rule != nil {
+		vo.Action, vo.Rule = rule.rule.Action, &rule.rule
+	}
+	result.Success(c, vo)
+}
+
+// 已编译的命令规则
+type commandRule struct {
+	rule  model.SysCommandRule
+	regex *regexp.Regexp
+}
+
+// 前缀规则对命令行中每一段命令分别匹配，忽略前面的 sudo 和多余空白；正则规则匹配整行命令
+func (r commandRule) match(command string) bool {
+	if r.regex != nil {
+		return r.regex.MatchString(command)
+	}
+	for _, segment := range commandSegments(command) {
+		if strings.HasPrefix(segment, r.rule.Pattern) {
+			return true
+		}
+	}
+	return false
+}
+
+// 命令行中的命令分隔符
+var commandSeparator = regexp.MustCompile(`&&|\|\||[;|&\n]`)
+
+// 拆分命令行中的各段命令
+func commandSegments(command string) []string {
+	var segments []string
+	for _, segment := range commandSeparator.Split(command, -1) {
+		segment = normalizeCommand(segment)
+		for strings.HasPrefix(segment, "sudo ") {
+			segment = strings.TrimPrefix(segment, "sudo ")
+		}
+		if segment != "" {
+			segments = append(segments, segment)
+		}
+	}
+	return segments
+}
+
+// 合并连续空白
+func normalizeCommand(command string) string {
+	return strings.Join(strings.Fields(command), " ")
+}
+
+// 查询适用于指定角色和分组的已启用规则，分组规则包含子分组
+func loadCommandRules(roleIds []uint, groupId uint) []commandRule {
+	roleSet := make(map[string]bool, len(roleIds))
+	for _, roleId := range roleIds {
+		roleSet[strconv.Itoa(int(roleId))] = true
+	}
+	groupSet := map[string]bool{}
+	if groupId > 0 {
+		for _, id := range ancestorIds(dao.QueryGroupTreeNodeList(), groupId) {
+			groupSet[strconv.Itoa(int(id))] = true
+		}
+	}
+	var rules []commandRule
+	for _, rule := range dao.GetEnabledSysCommandRuleList() {
+		if !containsAnyId(rule.RoleIds, roleSet) || !containsAnyId(rule.GroupIds, groupSet) {
+			continue
+		}
+		compiled := commandRule{rule: rule}
+		if rule.MatchType == model.CommandMatchRegex {
+			regex, err := regexp.Compile(rule.Pattern)
+			if err != nil {
+				continue
+			}
+			compiled.regex = regex
+		}
+		rules = append(rules, compiled)
+	}
+	return rules
+}
+
+// 返回第一条命中的规则
+func matchCommandRule(rules []commandRule, command string) *commandRule {
+	for i := range rules {
+		if rules[i].match(command) {
+			return &rules[i]
+		}
+	}
+	return nil
+}
+
+// 逗号分隔的id为空表示不限制，否则需包含集合中任一id
+func containsAnyId(ids string, set map[string]bool) bool {
+	if ids == "" {
+		return true
+	}
+	for _, id := range strings.Split(ids, ",") {
+		if set[id] {
+			return true
+		}
+	}
+	return false
+}
+
+func joinIds(ids []uint) string {
+	values := make([]string, 0, len(ids))
+	for _, id := range ids {
+		values = append(values, strconv.Itoa(int(id)))
+	}
+	return strings.Join(values, ",")
+}
+
+// 返回节点及其全部上级节点id
+func ancestorIds(nodes []dao.ScopeTreeNode, id uint) []uint {
+	parents := make(map[uint]uint, len(nodes))
+	for _, node := range nodes {
+		parents[node.Id] = node.ParentId
+	}
+	ids := []uint{id}
+	seen := map[uint]bool{id: true}
+	for parent := parents[id]; parent > 0 && !seen[parent]; parent = parents[parent] {
+		seen[parent] = true
+		ids = append(ids, parent)
+	}
+	return ids
+}
+
+// 终端命令检查，按连接终端时用户的角色和主机分组加载规则
+type terminalCommandChecker struct {
+	adminId  uint
+	username string
+	ip       string
+	url      string
+	target   string
+	rules    []commandRule
+}
+
+// 创建终端命令检查，groupId 为主机所在分组，容器终端为0；超级管理员或没有适用规则时返回 nil
+func NewTerminalCommandChecker(c *gin.Context, groupId uint, target string) wsutil.CommandChecker {
+	checker := &terminalCommandChecker{ip: c.ClientIP(), url: c.Request.URL.Path, target: target}
+	var roleIds []uint
+	if admin, err := jwt.GetAdmin(c); err == nil {
+		checker.adminId, checker.username = admin.ID, admin.Username
+		for _, role := range dao.QueryAdminEnabledRoleList(admin.ID) {
+			if role.RoleKey == constant.SUPER_ADMIN_ROLE_KEY {
+				return nil
+			}
+			roleIds = append(roleIds, role.ID)
+		}
+	}
+	checker.rules = loadCommandRules(roleIds, groupId)
+	if len(checker.rules) == 0 {
+		return nil
+	}
+	return checker
+}
+
+func (t *terminalCommandChecker) Check(command string) wsutil.CommandDecision {
+	rule := matchCommandRule(t.rules, command)
+	if rule == nil {
+		return wsutil.CommandDecision{Action: wsutil.CommandAllow}
+	}
+	decision := wsutil.CommandDecision{Action: wsutil.CommandAllow, Rule: rule.rule.Name}
+	switch rule.rule.Action {
+	case model.CommandRuleDeny:
+		decision.Action = wsutil.CommandDeny
+	case model.CommandRuleConfirm:
+		decision.Action = wsutil.CommandConfirm
+	}
+	return decision
+}
+
+// 拦截、确认执行和取消执行的命令记录到操作日志
+func (t *terminalCommandChecker) Report(command string, decision wsutil.CommandDecision, confirmed bool) {
+	action := "拦截终端命令"
+	if decision.Action == wsutil.CommandConfirm {
+		action = "取消执行终端命令"
+		if confirmed {
+			action = "确认执行终端命令"
+		}
+	}
+	if utf8.RuneCountInString(command) > maxLoggedCommandLength {
+		command = string([]rune(command)[:maxLoggedCommandLength]) + "..."
+	}
+	dao.CreateSysOperationLog(model.SysOperationLog{
+		AdminId:     t.adminId,
+		Username:    t.username,
+		Method:      terminalLogMethod,
+		Ip:          t.ip,
+		Url:         t.url,
+		Description: fmt.Sprintf("%s[%s] %s: %s", action, decision.Rule, t.target, command),
+		CreateTime:  util.HTime{Time: time.Now()},
+	})
+}
+
+var sysCommandRuleService = SysCommandRuleServiceImpl{}
+
+func SysCommandRuleService() ISysCommandRuleService {
+	return &sysCommandRuleService
+}
@@ -0,0 +1,127 @@
+// 数据权限 服务层
+// author xiaoRui
+
+package service
+
+import (
+	"dodevops-api/api/system/dao"
+	"dodevops-api/api/system/model"
+	"dodevops-api/common/constant"
+	"dodevops-api/common/result"
+	"dodevops-api/pkg/datascope"
+
+	"github.com/gin-gonic/gin"
+)
+
+func init() {
+	datascope.Resolver = ResolveDataScope
+}
+
+// 根据用户的角色计算数据范围，多个角色的范围取并集
+// 超级管理员或任一角色为全部数据时不限制；自定义分组包含子分组，部门范围包含归属这些部门的资产分组
+// 集群、任务等按部门归属的资源始终可访问本部门的数据
+func ResolveDataScope(adminId uint) datascope.Scope {
+	deptId := dao.QueryAdminDeptId(adminId)
+	scope := datascope.Scope{DeptId: deptId}
+	roles := dao.QueryAdminEnabledRoleList(adminId)
+	if len(roles) == 0 {
+		scope.DeptIds = []uint{deptId}
+		return scope
+	}
+
+	deptSet := map[uint]bool{deptId: true}
+	groupSet := map[uint]bool{}
+	var customRoleIds []uint
+	deptScoped := false
+	for _, role := range roles {
+		// 超级管理员或未设置数据范围的角色可访问全部数据
+		if role.RoleKey == constant.SUPER_ADMIN_ROLE_KEY || role.DataScope == datascope.ScopeAll || role.DataScope == 0 {
+			return datascope.Scope{All: true, DeptId: deptId}
+		}
+		switch role.DataScope {
+		case datascope.ScopeCustomGroup:
+			customRoleIds = append(customRoleIds, role.ID)
+		case datascope.ScopeDept:
+			deptScoped = true
+		case datascope.ScopeDeptTree:
+			deptScoped = true
+			for _, id := range descendantIds(dao.QueryDeptTreeNodeList(), deptId) {
+				deptSet[id] = true
+			}
+		}
+	}
+
+	groups := dao.QueryGroupTreeNodeList()
+	for _, groupId := range dao.QueryRoleGroupIdList(customRoleIds...) {
+		for _, id := range descendantIds(groups, groupId) {
+			groupSet[id] = true
+		}
+	}
+	for _, group := range groups {
+		if deptScoped && group.DeptId > 0 && deptSet[group.DeptId] {
+			for _, id := range descendantIds(groups, group.Id) {
+				groupSet[id] = true
+			}
+		}
+	}
+	for id := range deptSet {
+		scope.DeptIds = append(scope.DeptIds, id)
+	}
+	for id := range groupSet {
+		scope.GroupIds = append(scope.GroupIds, id)
+	}
+	return scope
+}
+
+// 返回节点及其全部子节点id
+func descendantIds(nodes []dao.ScopeTreeNode, rootId uint) []uint {
+	children := make(map[uint][]uint, len(nodes))
+	for _, node := range nodes {
+		children[node.ParentId] = append(children[node.ParentId], node.Id)
+	}
+	ids := []uint{rootId}
+	visited := map[uint]bool{rootId: true}
+	for i := 0; i < len(ids); i++ {
+		for _, child := range children[ids[i]] {
+			if !visited[child] {
+				visited[child] = true
+				ids = append(ids, child)
+			}
+		}
+	}
+	return ids
+}
+
+// 查询角色数据范围
+func (s SysRoleServiceImpl) GetSysRoleDataScope(c *gin.Context, id uint) {
+	sysRole := dao.GetSysRoleById(int(id))
+	if sysRole.ID == 0 {
+		result.Failed(c, int(result.ApiCode.FAILED), "角色不存在")
+		return
+	}
+	groupIds := dao.QueryRoleGroupIdList(id)
+	if groupIds == nil {
+		groupIds = make([]uint, 0)
+	}
+	result.Success(c, model.SysRoleDataScopeVo{Id: sysRole.ID, DataScope: sysRole.DataScope, GroupIds: groupIds})
+}
+
+// 设置角色数据范围
+func (s SysRoleServiceImpl) UpdateSysRoleDataScope(c *gin.Context, dto model.SysRoleDataScopeDto) {
+	if dto.DataScope < datascope.ScopeAll || dto.DataScope > datascope.ScopeDeptTree {
+		result.Failed(c, int(result.ApiCode.FAILED), "数据范围无效")
+		return
+	}
+	if dao.GetSysRoleById(int(dto.Id)).ID == 0 {
+		result.Failed(c, int(result.ApiCode.FAILED), "角色不存在")
+		return
+	}
+	if dto.DataScope != datascope.ScopeCustomGroup {
+		dto.GroupIds = nil
+	}
+	if err := dao.UpdateSysRoleDataScope(dto); err != nil {
+		result.Failed(c, int(result.ApiCode.FAILED), err.Error())
+		return
+	}
+	result.Success(c, true)
+}
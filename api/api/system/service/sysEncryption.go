@@ -0,0 +1,102 @@
+// 敏感字段加密 服务层
+// author xiaoRui
+
+package service
+
+import (
+	"context"
+	"dodevops-api/api/system/model"
+	"dodevops-api/common/config"
+	"dodevops-api/common/result"
+	. "dodevops-api/pkg/db"
+	"dodevops-api/pkg/envelope"
+	"dodevops-api/pkg/log"
+	"dodevops-api/pkg/secretstore"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ISysEncryptionService interface {
+	GetSysEncryptionStatus(c *gin.Context) // 加密状态
+	RotateSysEncryption(c *gin.Context)    // 重新加密
+}
+
+type SysEncryptionServiceImpl struct{}
+
+// 查询各字段的主密钥版本分布
+func (s SysEncryptionServiceImpl) GetSysEncryptionStatus(c *gin.Context) {
+	active, err := envelope.ActiveVersion()
+	if err != nil {
+		result.Failed(c, int(result.ApiCode.FAILED), err.Error())
+		return
+	}
+	versions, _ := envelope.Versions()
+	columns, err := envelope.Status(Db)
+	if err != nil {
+		result.Failed(c, int(result.ApiCode.FAILED), err.Error())
+		return
+	}
+	rotating, last := envelope.RotateState()
+	result.Success(c, model.SysEncryptionStatusVo{
+		ActiveVersion: active,
+		SecretBackend: secretstore.Active().Name(),
+		KeyVersions:   versions,
+		Rotating:      rotating,
+		LastRotate:    last,
+		Columns:       columns,
+	})
+}
+
+// 后台将全部敏感字段重新加密为当前主密钥版本，并将凭证迁移到当前凭证存储后端
+func (s SysEncryptionServiceImpl) RotateSysEncryption(c *gin.Context) {
+	if rotating, _ := envelope.RotateState(); rotating {
+		result.Failed(c, int(result.ApiCode.FAILED), envelope.ErrRotating.Error())
+		return
+	}
+	if _, err := envelope.ActiveVersion(); err != nil {
+		result.Failed(c, int(result.ApiCode.FAILED), err.Error())
+		return
+	}
+	go rotateSysEncryption()
+	result.Success(c, true)
+}
+
+func rotateSysEncryption() {
+	res, err := envelope.Rotate(Db)
+	if err != nil {
+		log.Log().Errorf("敏感字段重新加密失败: %v", err)
+		return
+	}
+	log.Log().Infof("敏感字段重新加密完成，主密钥版本 %d，重新加密 %d 条，跳过 %d 条，失败 %d 条",
+		res.ActiveVersion, res.Rotated, res.Skipped, res.Failed)
+
+	migrated, err := secretstore.Migrate(context.Background(), Db)
+	if err != nil {
+		log.Log().Errorf("凭证迁移到 %s 失败: %v", migrated.Backend, err)
+		return
+	}
+	if migrated.Migrated > 0 || migrated.Failed > 0 {
+		log.Log().Infof("凭证迁移到 %s 完成，迁移 %d 条，跳过 %d 条，失败 %d 条",
+			migrated.Backend, migrated.Migrated, migrated.Skipped, migrated.Failed)
+	}
+}
+
+// 加载主密钥和凭证存储后端，并按配置在后台重新加密旧版本数据
+func SetupSysEncryption() error {
+	if err := envelope.Load(config.Config.Encryption); err != nil {
+		return err
+	}
+	if err := secretstore.Setup(config.Config.SecretStore); err != nil {
+		return err
+	}
+	if config.Config.Encryption.RotateOnStartup {
+		go rotateSysEncryption()
+	}
+	return nil
+}
+
+var sysEncryptionService = SysEncryptionServiceImpl{}
+
+func SysEncryptionService() ISysEncryptionService {
+	return &sysEncryptionService
+}
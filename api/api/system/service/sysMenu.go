@@ -44,7 +44,10 @@ func (s SysMenuServiceImpl) GetSysMenu(c *gin.Context, Id int) {
 
 // 修改菜单
 func (s SysMenuServiceImpl) UpdateSysMenu(c *gin.Context, menu model.SysMenu) {
-	result.Success(c, dao.UpdateSysMenu(menu))
+	sysMenu := dao.UpdateSysMenu(menu)
+	// 权限值或菜单状态可能发生变化，清除全部用户的权限缓存
+	ClearAllPermissionCache()
+	result.Success(c, sysMenu)
 }
 
 // 删除菜单
@@ -1,12 +1,30 @@
-// 操作日志 数据层
+// 操作日志 服务层
 // author xiaoRui
 
 package service
 
 import (
+	"bufio"
+	"compress/gzip"
+	"crypto/sha256"
 	"dodevops-api/api/system/dao"
 	"dodevops-api/api/system/model"
+	"dodevops-api/common/config"
 	"dodevops-api/common/result"
+	"dodevops-api/common/util"
+	"dodevops-api/pkg/log"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+
 	"github.com/gin-gonic/gin"
 )
 
@@ -15,26 +33,37 @@ type ISysOperationLogService interface {
 	DeleteSysOperationLogById(c *gin.Context, dto model.SysOperationLogIdDto)
 	BatchDeleteSysOperationLog(c *gin.Context, dto model.BatchDeleteSysOperationLogDto)
 	CleanSysOperationLog(c *gin.Context)
+	VerifySysOperationLog(c *gin.Context)                                    // 校验哈希链
+	ExportSysOperationLog(c *gin.Context, dto model.SysOperationLogQueryDto) // 导出操作日志
+	GetSysOperationLogArchiveList(c *gin.Context)                            // 归档记录列表
 }
 
 type SysOperationLogServiceImpl struct{}
 
-// 清空操作日志
+// 审计日志不允许直接删除
+var errOperationLogImmutable = errors.New("操作日志受哈希链保护不允许删除，过期日志请通过归档清理")
+
+// 校验结果最多返回的问题条数
+const maxOperationLogProblems = 100
+
+// 清空操作日志：将全部日志归档后移除
 func (s SysOperationLogServiceImpl) CleanSysOperationLog(c *gin.Context) {
-	dao.CleanSysOperationLog()
-	result.Success(c, true)
+	archive, err := ArchiveSysOperationLog(time.Now())
+	if err != nil {
+		result.Failed(c, int(result.ApiCode.FAILED), err.Error())
+		return
+	}
+	result.Success(c, archive)
 }
 
 // 批量删除操作日志
 func (s SysOperationLogServiceImpl) BatchDeleteSysOperationLog(c *gin.Context, dto model.BatchDeleteSysOperationLogDto) {
-	dao.BatchDeleteSysOperationLog(dto)
-	result.Success(c, true)
+	result.Failed(c, int(result.ApiCode.FAILED), errOperationLogImmutable.Error())
 }
 
 // 根据id删除操作日志
 func (s SysOperationLogServiceImpl) DeleteSysOperationLogById(c *gin.Context, dto model.SysOperationLogIdDto) {
-	dao.DeleteSysOperationLogById(dto)
-	result.Success(c, true)
+	result.Failed(c, int(result.ApiCode.FAILED), errOperationLogImmutable.Error())
 }
 
 // 分页查询操作日志列表
@@ -49,6 +78,257 @@ func (s SysOperationLogServiceImpl) GetSysOperationLogList(c *gin.Context, Usern
 	result.Success(c, map[string]interface{}{"total": count, "pageSize": PageSize, "pageNum": PageNum, "list": sysOperationLog})
 }
 
+// 校验哈希链：先校验归档文件之间的衔接和文件内容，再从最后一次归档处逐条校验数据库中的日志
+func (s SysOperationLogServiceImpl) VerifySysOperationLog(c *gin.Context) {
+	vo, err := VerifySysOperationLogChain()
+	if err != nil {
+		result.Failed(c, int(result.ApiCode.FAILED), err.Error())
+		return
+	}
+	result.Success(c, vo)
+}
+
+// 校验操作日志哈希链
+func VerifySysOperationLogChain() (model.SysOperationLogVerifyVo, error) {
+	vo := model.SysOperationLogVerifyVo{Problems: make([]model.SysOperationLogProblem, 0)}
+	addProblem := func(seq uint64, id uint, reason string) {
+		if len(vo.Problems) < maxOperationLogProblems {
+			vo.Problems = append(vo.Problems, model.SysOperationLogProblem{Seq: seq, Id: id, Reason: reason})
+		}
+	}
+
+	var expectSeq uint64
+	var expectPrev string
+	for _, archive := range dao.GetSysOperationLogArchiveList() {
+		vo.Archives++
+		if fileHash, err := fileSha256(archive.FileName); err != nil {
+			addProblem(archive.EndSeq, archive.ID, "归档文件无法读取: "+err.Error())
+		} else if fileHash != archive.FileHash {
+			addProblem(archive.EndSeq, archive.ID, "归档文件内容被修改")
+		}
+		// 只包含历史日志的归档不参与哈希链
+		if archive.EndSeq == 0 {
+			continue
+		}
+		if archive.StartSeq != expectSeq+1 {
+			addProblem(archive.StartSeq, archive.ID, fmt.Sprintf("归档序号不连续，期望从 %d 开始", expectSeq+1))
+		}
+		if archive.FirstPrevHash != expectPrev {
+			addProblem(archive.StartSeq, archive.ID, "归档与上一段哈希链不衔接")
+		}
+		expectSeq, expectPrev = archive.EndSeq, archive.LastHash
+	}
+
+	err := dao.EachChainedSysOperationLog(expectSeq, 500, func(logs []model.SysOperationLog) error {
+		for _, sysLog := range logs {
+			vo.Checked++
+			seq := *sysLog.Seq
+			if seq != expectSeq+1 {
+				addProblem(seq, sysLog.ID, fmt.Sprintf("缺失序号 %d 至 %d 的日志", expectSeq+1, seq-1))
+			}
+			if sysLog.PrevHash != expectPrev {
+				addProblem(seq, sysLog.ID, "上一条日志哈希不匹配")
+			}
+			if dao.SysOperationLogHash(sysLog) != sysLog.Hash {
+				addProblem(seq, sysLog.ID, "日志内容被修改")
+			}
+			expectSeq, expectPrev = seq, sysLog.Hash
+		}
+		return nil
+	})
+	if err != nil {
+		return vo, err
+	}
+	vo.Unchained = dao.CountUnchainedSysOperationLog()
+	vo.Valid = len(vo.Problems) == 0
+	return vo, nil
+}
+
+// 导出操作日志，支持 csv、jsonl 格式
+func (s SysOperationLogServiceImpl) ExportSysOperationLog(c *gin.Context, dto model.SysOperationLogQueryDto) {
+	if dto.Format == "" {
+		dto.Format = "csv"
+	}
+	if dto.Format != "csv" && dto.Format != "jsonl" {
+		result.Failed(c, int(result.ApiCode.FAILED), "导出格式仅支持 csv、jsonl")
+		return
+	}
+	fileName := fmt.Sprintf("operation_log_%s.%s", time.Now().Format("20060102150405"), dto.Format)
+	c.Header("Content-Disposition", "attachment; filename="+fileName)
+	if dto.Format == "jsonl" {
+		c.Header("Content-Type", "application/x-ndjson")
+	} else {
+		c.Header("Content-Type", "text/csv; charset=utf-8")
+	}
+	c.Status(200)
+	if err := writeSysOperationLog(c.Writer, dto); err != nil {
+		log.Log().Errorf("导出操作日志失败: %v", err)
+	}
+}
+
+func writeSysOperationLog(w io.Writer, dto model.SysOperationLogQueryDto) error {
+	if dto.Format == "jsonl" {
+		encoder := json.NewEncoder(w)
+		return dao.EachSysOperationLog(dto, 500, func(logs []model.SysOperationLog) error {
+			for _, sysLog := range logs {
+				if err := encoder.Encode(sysLog); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	}
+	// 写入 BOM 便于 Excel 正确识别中文
+	if _, err := w.Write([]byte("\xEF\xBB\xBF")); err != nil {
+		return err
+	}
+	writer := csv.NewWriter(w)
+	_ = writer.Write([]string{"ID", "序号", "用户ID", "用户名", "请求方式", "IP", "URL", "操作描述", "时间", "上一条哈希", "哈希"})
+	err := dao.EachSysOperationLog(dto, 500, func(logs []model.SysOperationLog) error {
+		for _, sysLog := range logs {
+			seq := ""
+			if sysLog.Seq != nil {
+				seq = strconv.FormatUint(*sysLog.Seq, 10)
+			}
+			if err := writer.Write([]string{strconv.Itoa(int(sysLog.ID)), seq, strconv.Itoa(int(sysLog.AdminId)), sysLog.Username,
+				sysLog.Method, sysLog.Ip, sysLog.Url, sysLog.Description, sysLog.CreateTime.Format("2006-01-02 15:04:05"),
+				sysLog.PrevHash, sysLog.Hash}); err != nil {
+				return err
+			}
+		}
+		writer.Flush()
+		return writer.Error()
+	})
+	writer.Flush()
+	if err != nil {
+		return err
+	}
+	return writer.Error()
+}
+
+// 归档记录列表
+func (s SysOperationLogServiceImpl) GetSysOperationLogArchiveList(c *gin.Context) {
+	result.Success(c, dao.GetSysOperationLogArchiveList())
+}
+
+// 同一时间只允许一个归档任务
+var operationLogArchiveMutex sync.Mutex
+
+// 将指定时间之前的操作日志归档为 gzip 压缩的 JSONL 文件后从数据库移除，没有可归档的日志时返回 nil
+func ArchiveSysOperationLog(before time.Time) (*model.SysOperationLogArchive, error) {
+	operationLogArchiveMutex.Lock()
+	defer operationLogArchiveMutex.Unlock()
+
+	endSeq := dao.GetSysOperationLogArchiveEndSeq(before)
+	logs := dao.GetSysOperationLogToArchive(endSeq, before)
+	if len(logs) == 0 {
+		return nil, nil
+	}
+
+	archive := &model.SysOperationLogArchive{Count: int64(len(logs)), CreateTime: util.HTime{Time: time.Now()}}
+	for _, sysLog := range logs {
+		if sysLog.Seq == nil {
+			continue
+		}
+		if archive.StartSeq == 0 || *sysLog.Seq < archive.StartSeq {
+			archive.StartSeq, archive.FirstPrevHash = *sysLog.Seq, sysLog.PrevHash
+		}
+		if *sysLog.Seq >= archive.EndSeq {
+			archive.EndSeq, archive.LastHash = *sysLog.Seq, sysLog.Hash
+		}
+	}
+
+	dir := config.Config.Audit.ArchiveDir
+	if dir == "" {
+		dir = "./archive/audit"
+	}
+	if err := os.MkdirAll(dir, 0750); err != nil {
+		return nil, fmt.Errorf("创建归档目录失败: %v", err)
+	}
+	archive.FileName = filepath.Join(dir, fmt.Sprintf("sys_operation_log_%d_%d_%s.jsonl.gz",
+		archive.StartSeq, archive.EndSeq, archive.CreateTime.Format("20060102150405")))
+	if err := writeSysOperationLogArchive(archive.FileName, logs); err != nil {
+		_ = os.Remove(archive.FileName)
+		return nil, fmt.Errorf("写入归档文件失败: %v", err)
+	}
+	fileHash, err := fileSha256(archive.FileName)
+	if err != nil {
+		return nil, err
+	}
+	archive.FileHash = fileHash
+
+	ids := make([]uint, 0, len(logs))
+	for _, sysLog := range logs {
+		ids = append(ids, sysLog.ID)
+	}
+	if err := dao.ArchiveSysOperationLog(archive, ids); err != nil {
+		_ = os.Remove(archive.FileName)
+		return nil, err
+	}
+	return archive, nil
+}
+
+func writeSysOperationLogArchive(fileName string, logs []model.SysOperationLog) error {
+	file, err := os.OpenFile(fileName, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0640)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	gz := gzip.NewWriter(file)
+	buf := bufio.NewWriter(gz)
+	encoder := json.NewEncoder(buf)
+	for _, sysLog := range logs {
+		if err := encoder.Encode(sysLog); err != nil {
+			return err
+		}
+	}
+	if err := buf.Flush(); err != nil {
+		return err
+	}
+	if err := gz.Close(); err != nil {
+		return err
+	}
+	return file.Sync()
+}
+
+func fileSha256(fileName string) (string, error) {
+	file, err := os.Open(fileName)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// 按保留策略定时归档操作日志，未配置保留天数时不启动
+func StartSysOperationLogArchiveJob() {
+	audit := config.Config.Audit
+	if audit.RetentionDays <= 0 {
+		return
+	}
+	interval := time.Duration(audit.ArchiveIntervalHours) * time.Hour
+	if interval <= 0 {
+		interval = 24 * time.Hour
+	}
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			before := time.Now().AddDate(0, 0, -audit.RetentionDays)
+			if archive, err := ArchiveSysOperationLog(before); err != nil {
+				log.Log().Errorf("归档操作日志失败: %v", err)
+			} else if archive != nil {
+				log.Log().Infof("归档操作日志 %d 条: %s", archive.Count, archive.FileName)
+			}
+			<-ticker.C
+		}
+	}()
+}
+
 var sysOperationLogService = SysOperationLogServiceImpl{}
 
 func SysOperationLogService() ISysOperationLogService {
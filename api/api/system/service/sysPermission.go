@@ -0,0 +1,133 @@
+// 权限 服务层
+// author xiaoRui
+
+package service
+
+import (
+	"context"
+	"dodevops-api/api/system/dao"
+	"dodevops-api/common/constant"
+	"dodevops-api/pkg/jwt"
+	"dodevops-api/pkg/redis"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 权限缓存过期时间，角色/菜单变更时会主动清除
+const permissionCacheExpiration = time.Minute * 30
+
+// AdminPermission 用户权限缓存对象
+type AdminPermission struct {
+	SuperAdmin bool     `json:"superAdmin"` // 是否为超级管理员
+	Values     []string `json:"values"`     // 权限值列表
+}
+
+// 判断是否拥有指定权限
+func (p AdminPermission) Has(value string) bool {
+	if p.SuperAdmin || value == "" {
+		return true
+	}
+	for _, v := range p.Values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func permissionCacheKey(adminId uint) string {
+	return fmt.Sprintf("%s%d", constant.PERMISSION_CODE, adminId)
+}
+
+// 获取用户权限，优先读取redis缓存，缓存不存在时从数据库加载
+func GetAdminPermission(adminId uint) AdminPermission {
+	ctx := context.Background()
+	key := permissionCacheKey(adminId)
+	if redis.RedisDb != nil {
+		if val, err := redis.RedisDb.Get(ctx, key).Result(); err == nil {
+			var permission AdminPermission
+			if json.Unmarshal([]byte(val), &permission) == nil {
+				return permission
+			}
+		}
+	}
+	permission := loadAdminPermission(adminId)
+	if redis.RedisDb != nil {
+		if data, err := json.Marshal(permission); err == nil {
+			if err := redis.RedisDb.Set(ctx, key, data, permissionCacheExpiration).Err(); err != nil {
+				log.Println("Redis Set Permission Error:", err)
+			}
+		}
+	}
+	return permission
+}
+
+// 判断当前请求用户是否拥有指定权限，供接口内按操作细分权限时使用，与 PermissionMiddleware 的规则一致
+// 通过个人访问令牌认证的请求还需在令牌授权范围内，没有登录用户时不限制
+func HasRequestPermission(c *gin.Context, value string) bool {
+	adminId, err := jwt.GetAdminId(c)
+	if err != nil {
+		return true
+	}
+	if scopes, isApiToken := GetApiTokenScopes(c); isApiToken && !scopes.Has(value) {
+		return false
+	}
+	return GetAdminPermission(adminId).Has(value)
+}
+
+// 从数据库加载用户权限
+func loadAdminPermission(adminId uint) AdminPermission {
+	permission := AdminPermission{Values: make([]string, 0)}
+	for _, roleKey := range dao.QueryAdminRoleKeyList(adminId) {
+		if roleKey == constant.SUPER_ADMIN_ROLE_KEY {
+			permission.SuperAdmin = true
+		}
+	}
+	for _, value := range dao.QueryPermissionList(adminId) {
+		if value.Value != "" {
+			permission.Values = append(permission.Values, value.Value)
+		}
+	}
+	return permission
+}
+
+// 清除指定用户的权限缓存
+func ClearAdminPermissionCache(adminIds ...uint) {
+	if redis.RedisDb == nil || len(adminIds) == 0 {
+		return
+	}
+	keys := make([]string, 0, len(adminIds))
+	for _, id := range adminIds {
+		keys = append(keys, permissionCacheKey(id))
+	}
+	if err := redis.RedisDb.Del(context.Background(), keys...).Err(); err != nil {
+		log.Println("Redis Del Permission Error:", err)
+	}
+}
+
+// 清除拥有指定角色的所有用户的权限缓存
+func ClearRolePermissionCache(roleId uint) {
+	ClearAdminPermissionCache(dao.QueryAdminIdListByRoleId(roleId)...)
+}
+
+// 清除全部用户的权限缓存（菜单权限值变化时使用）
+func ClearAllPermissionCache() {
+	if redis.RedisDb == nil {
+		return
+	}
+	ctx := context.Background()
+	iter := redis.RedisDb.Scan(ctx, 0, constant.PERMISSION_CODE+"*", 100).Iterator()
+	var keys []string
+	for iter.Next(ctx) {
+		keys = append(keys, iter.Val())
+	}
+	if len(keys) > 0 {
+		if err := redis.RedisDb.Del(ctx, keys...).Err(); err != nil {
+			log.Println("Redis Del Permission Error:", err)
+		}
+	}
+}
@@ -20,6 +20,8 @@ type ISysRoleService interface {
 	QuerySysRoleVoList(c *gin.Context)                                                                 // 查询角色列表
 	QueryRoleMenuIdList(c *gin.Context, Id int)                                                        // 查询角色菜单id列表
 	AssignPermissions(c *gin.Context, menu model.RoleMenu)                                             // 分配权限
+	GetSysRoleDataScope(c *gin.Context, id uint)                                                       // 查询角色数据范围
+	UpdateSysRoleDataScope(c *gin.Context, dto model.SysRoleDataScopeDto)                              // 设置角色数据范围
 }
 
 type SysRoleServiceImpl struct{}
@@ -43,11 +45,13 @@ func (s SysRoleServiceImpl) GetSysRoleById(c *gin.Context, Id int) {
 // 修改角色
 func (s SysRoleServiceImpl) UpdateSysRole(c *gin.Context, dto model.UpdateSysRoleDto) {
 	sysRole := dao.UpdateSysRole(dto)
+	ClearRolePermissionCache(dto.Id)
 	result.Success(c, sysRole)
 }
 
 // 根据id删除角色
 func (s SysRoleServiceImpl) DeleteSysRoleById(c *gin.Context, dto model.SysRoleIdDto) {
+	ClearRolePermissionCache(dto.Id)
 	dao.DeleteSysRoleById(dto)
 	result.Success(c, true)
 }
@@ -58,6 +62,7 @@ func (s SysRoleServiceImpl) UpdateSysRoleStatus(c *gin.Context, dto model.Update
 	if !bool {
 		return
 	}
+	ClearRolePermissionCache(dto.Id)
 	result.Success(c, true)
 }
 
@@ -93,9 +98,11 @@ func (s *SysRoleServiceImpl) AssignPermissions(c *gin.Context, menu model.RoleMe
 	// 立即返回成功响应
 	result.Success(c, true)
 	
-	// 异步处理权限分配
+	// 异步处理权限分配，完成后清除相关用户的权限缓存使其立即生效
 	go func() {
-		dao.AssignPermissions(menu)
+		if err := dao.AssignPermissions(menu); err == nil {
+			ClearRolePermissionCache(menu.Id)
+		}
 	}()
 }
 
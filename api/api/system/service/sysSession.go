@@ -0,0 +1,376 @@
+// 登录会话 服务层
+// author xiaoRui
+
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"dodevops-api/api/system/dao"
+	"dodevops-api/api/system/model"
+	"dodevops-api/common/config"
+	"dodevops-api/common/constant"
+	"dodevops-api/common/result"
+	"dodevops-api/common/util"
+	"dodevops-api/pkg/jwt"
+	"dodevops-api/pkg/log"
+	"dodevops-api/pkg/redis"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	goredis "github.com/go-redis/redis/v8"
+)
+
+const (
+	defaultAccessTokenMinutes = 30 // 访问令牌默认有效期(分钟)
+	defaultRefreshTokenDays   = 7  // 刷新令牌默认有效期(天)
+)
+
+var (
+	ErrSessionInvalid      = errors.New("登录会话已失效，请重新登录")
+	ErrRefreshTokenInvalid = errors.New("刷新令牌无效或已过期，请重新登录")
+)
+
+// 定义接口
+type ISysSessionService interface {
+	RefreshToken(c *gin.Context, dto model.RefreshTokenDto)    // 刷新访问令牌
+	Logout(c *gin.Context)                                     // 退出登录
+	GetMySessionList(c *gin.Context)                           // 当前用户的会话列表
+	DeleteMySession(c *gin.Context, dto model.SysSessionIdDto) // 下线当前用户的指定会话
+	GetSysAdminSessionList(c *gin.Context, adminId uint)       // 查询指定用户的会话列表
+	ForceLogout(c *gin.Context, dto model.ForceLogoutDto)      // 强制下线指定用户
+}
+
+type SysSessionServiceImpl struct{}
+
+// 会话在 redis 中的存储结构，刷新令牌仅保存哈希值
+type sessionData struct {
+	model.SysSession
+	RefreshHash string `json:"refreshHash"`
+}
+
+// 使用刷新令牌签发新的访问令牌，刷新令牌同时轮换，旧刷新令牌被再次使用时视为泄露并吊销会话
+func (s SysSessionServiceImpl) RefreshToken(c *gin.Context, dto model.RefreshTokenDto) {
+	if err := validator.New().Struct(dto); err != nil {
+		result.Failed(c, int(result.ApiCode.ValidationParameterError), result.ApiCode.GetMessage(result.ApiCode.ValidationParameterError))
+		return
+	}
+	data, err := refreshSession(c, dto.RefreshToken)
+	if err != nil {
+		result.Failed(c, int(result.ApiCode.INVALIDTOKEN), err.Error())
+		return
+	}
+	result.Success(c, data)
+}
+
+// 退出登录，吊销当前会话
+func (s SysSessionServiceImpl) Logout(c *gin.Context) {
+	admin, err := jwt.GetAdmin(c)
+	if err != nil || admin.SessionId == "" {
+		result.Failed(c, int(result.ApiCode.FAILED), "当前请求不是登录会话")
+		return
+	}
+	RevokeSession(admin.ID, admin.SessionId)
+	result.Success(c, true)
+}
+
+// 当前用户的会话列表
+func (s SysSessionServiceImpl) GetMySessionList(c *gin.Context) {
+	admin, _ := jwt.GetAdmin(c)
+	result.Success(c, listSessions(admin.ID, admin.SessionId))
+}
+
+// 下线当前用户的指定会话
+func (s SysSessionServiceImpl) DeleteMySession(c *gin.Context, dto model.SysSessionIdDto) {
+	if err := validator.New().Struct(dto); err != nil {
+		result.Failed(c, int(result.ApiCode.ValidationParameterError), result.ApiCode.GetMessage(result.ApiCode.ValidationParameterError))
+		return
+	}
+	adminId, _ := jwt.GetAdminId(c)
+	if !RevokeSession(adminId, dto.SessionId) {
+		result.Failed(c, int(result.ApiCode.FAILED), "会话不存在")
+		return
+	}
+	result.Success(c, true)
+}
+
+// 查询指定用户的会话列表
+func (s SysSessionServiceImpl) GetSysAdminSessionList(c *gin.Context, adminId uint) {
+	if adminId == 0 {
+		result.Failed(c, int(result.ApiCode.ValidationParameterError), result.ApiCode.GetMessage(result.ApiCode.ValidationParameterError))
+		return
+	}
+	current, _ := jwt.GetAdmin(c)
+	result.Success(c, listSessions(adminId, current.SessionId))
+}
+
+// 强制下线指定用户的某个会话或全部会话
+func (s SysSessionServiceImpl) ForceLogout(c *gin.Context, dto model.ForceLogoutDto) {
+	if err := validator.New().Struct(dto); err != nil {
+		result.Failed(c, int(result.ApiCode.ValidationParameterError), result.ApiCode.GetMessage(result.ApiCode.ValidationParameterError))
+		return
+	}
+	if dto.SessionId != "" {
+		if !RevokeSession(dto.AdminId, dto.SessionId) {
+			result.Failed(c, int(result.ApiCode.FAILED), "会话不存在")
+			return
+		}
+		result.Success(c, true)
+		return
+	}
+	RevokeAdminSessions(dto.AdminId)
+	result.Success(c, true)
+}
+
+var sysSessionService = SysSessionServiceImpl{}
+
+func SysSessionService() ISysSessionService {
+	return &sysSessionService
+}
+
+// 访问令牌有效期
+func accessTokenExpiration() time.Duration {
+	minutes := config.GetAuthConfig().Session.AccessTokenMinutes
+	if minutes <= 0 {
+		minutes = defaultAccessTokenMinutes
+	}
+	return time.Duration(minutes) * time.Minute
+}
+
+// 刷新令牌有效期，同时也是会话的空闲过期时间
+func refreshTokenExpiration() time.Duration {
+	days := config.GetAuthConfig().Session.RefreshTokenDays
+	if days <= 0 {
+		days = defaultRefreshTokenDays
+	}
+	return time.Duration(days) * 24 * time.Hour
+}
+
+func sessionKey(sessionId string) string {
+	return constant.SESSION_CODE + sessionId
+}
+
+func adminSessionKey(adminId uint) string {
+	return fmt.Sprintf("%s%d", constant.ADMIN_SESSION_CODE, adminId)
+}
+
+func hashRefreshSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+// 创建登录会话，返回访问令牌和刷新令牌
+// redis 未初始化时退化为无会话的访问令牌，此时不支持刷新和吊销
+func createSession(c *gin.Context, sysAdmin model.SysAdmin) (string, string, error) {
+	if redis.RedisDb == nil {
+		token, err := jwt.GenerateTokenByAdmin(sysAdmin, "", accessTokenExpiration())
+		return token, "", err
+	}
+	ip := c.ClientIP()
+	now := util.HTime{Time: time.Now()}
+	secret := util.GenerateRandomString(43)
+	data := sessionData{
+		SysSession: model.SysSession{
+			SessionId:      util.GenerateRandomString(32),
+			AdminId:        sysAdmin.ID,
+			Username:       sysAdmin.Username,
+			IpAddress:      ip,
+			LoginLocation:  util.GetRealAddressByIP(ip),
+			Browser:        util.GetBrowser(c),
+			Os:             util.GetOs(c),
+			LoginTime:      now,
+			LastActiveTime: now,
+		},
+		RefreshHash: hashRefreshSecret(secret),
+	}
+	value, err := json.Marshal(data)
+	if err != nil {
+		return "", "", err
+	}
+	ctx := context.Background()
+	expiration := refreshTokenExpiration()
+	_, err = redis.RedisDb.TxPipelined(ctx, func(pipe goredis.Pipeliner) error {
+		pipe.Set(ctx, sessionKey(data.SessionId), value, expiration)
+		pipe.SAdd(ctx, adminSessionKey(sysAdmin.ID), data.SessionId)
+		pipe.Expire(ctx, adminSessionKey(sysAdmin.ID), expiration)
+		return nil
+	})
+	if err != nil {
+		return "", "", err
+	}
+	token, err := jwt.GenerateTokenByAdmin(sysAdmin, data.SessionId, accessTokenExpiration())
+	if err != nil {
+		return "", "", err
+	}
+	return token, data.SessionId + "." + secret, nil
+}
+
+// 刷新会话：校验并轮换刷新令牌，重新签发访问令牌
+func refreshSession(c *gin.Context, refreshToken string) (map[string]interface{}, error) {
+	if redis.RedisDb == nil {
+		return nil, ErrRefreshTokenInvalid
+	}
+	sessionId, secret, ok := strings.Cut(refreshToken, ".")
+	if !ok || sessionId == "" || secret == "" {
+		return nil, ErrRefreshTokenInvalid
+	}
+	ctx := context.Background()
+	key := sessionKey(sessionId)
+	newSecret := util.GenerateRandomString(43)
+	var data sessionData
+	var reused bool
+	err := redis.RedisDb.Watch(ctx, func(tx *goredis.Tx) error {
+		value, err := tx.Get(ctx, key).Result()
+		if err != nil {
+			return ErrRefreshTokenInvalid
+		}
+		if err := json.Unmarshal([]byte(value), &data); err != nil {
+			return ErrRefreshTokenInvalid
+		}
+		if subtle.ConstantTimeCompare([]byte(data.RefreshHash), []byte(hashRefreshSecret(secret))) != 1 {
+			reused = true
+			return ErrRefreshTokenInvalid
+		}
+		ip := c.ClientIP()
+		if ip != data.IpAddress {
+			data.IpAddress = ip
+			data.LoginLocation = util.GetRealAddressByIP(ip)
+		}
+		data.Browser = util.GetBrowser(c)
+		data.Os = util.GetOs(c)
+		data.LastActiveTime = util.HTime{Time: time.Now()}
+		data.RefreshHash = hashRefreshSecret(newSecret)
+		updated, err := json.Marshal(data)
+		if err != nil {
+			return err
+		}
+		expiration := refreshTokenExpiration()
+		_, err = tx.TxPipelined(ctx, func(pipe goredis.Pipeliner) error {
+			pipe.Set(ctx, key, updated, expiration)
+			pipe.Expire(ctx, adminSessionKey(data.AdminId), expiration)
+			return nil
+		})
+		return err
+	}, key)
+	if err != nil {
+		if reused {
+			log.Log().Warnf("检测到刷新令牌重复使用，吊销会话: 用户 %s, 会话 %s", data.Username, sessionId)
+			RevokeSession(data.AdminId, sessionId)
+		}
+		if errors.Is(err, goredis.TxFailedErr) {
+			return nil, ErrRefreshTokenInvalid
+		}
+		return nil, err
+	}
+	sysAdmin := dao.GetSysAdminById(data.AdminId)
+	const status int = 2
+	if sysAdmin.ID == 0 || sysAdmin.Status == status {
+		RevokeSession(data.AdminId, sessionId)
+		return nil, ErrSessionInvalid
+	}
+	token, err := jwt.GenerateTokenByAdmin(sysAdmin, sessionId, accessTokenExpiration())
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{
+		"token":        token,
+		"refreshToken": sessionId + "." + newSecret,
+		"expiresIn":    int64(accessTokenExpiration().Seconds()),
+	}, nil
+}
+
+// 校验访问令牌对应的登录会话是否仍然有效
+// redis 未初始化时不校验会话
+func ValidateSession(admin *model.JwtAdmin) error {
+	if redis.RedisDb == nil {
+		return nil
+	}
+	if admin.SessionId == "" {
+		return ErrSessionInvalid
+	}
+	n, err := redis.RedisDb.Exists(context.Background(), sessionKey(admin.SessionId)).Result()
+	if err != nil {
+		log.Log().Errorf("查询登录会话失败: %v", err)
+		return ErrSessionInvalid
+	}
+	if n == 0 {
+		return ErrSessionInvalid
+	}
+	return nil
+}
+
+// 查询用户的会话列表，按最后续期时间倒序，currentSessionId 用于标记当前会话
+func listSessions(adminId uint, currentSessionId string) []model.SysSessionVo {
+	list := make([]model.SysSessionVo, 0)
+	if redis.RedisDb == nil {
+		return list
+	}
+	ctx := context.Background()
+	sessionIds, err := redis.RedisDb.SMembers(ctx, adminSessionKey(adminId)).Result()
+	if err != nil {
+		return list
+	}
+	for _, sessionId := range sessionIds {
+		value, err := redis.RedisDb.Get(ctx, sessionKey(sessionId)).Result()
+		var data sessionData
+		if err != nil || json.Unmarshal([]byte(value), &data) != nil {
+			// 会话已过期，清理集合中的残留id
+			redis.RedisDb.SRem(ctx, adminSessionKey(adminId), sessionId)
+			continue
+		}
+		list = append(list, model.SysSessionVo{SysSession: data.SysSession, Current: sessionId == currentSessionId})
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].LastActiveTime.After(list[j].LastActiveTime.Time)
+	})
+	return list
+}
+
+// 吊销用户的指定会话，会话不属于该用户时返回 false
+func RevokeSession(adminId uint, sessionId string) bool {
+	if redis.RedisDb == nil || sessionId == "" {
+		return false
+	}
+	ctx := context.Background()
+	removed, err := redis.RedisDb.SRem(ctx, adminSessionKey(adminId), sessionId).Result()
+	if err != nil || removed == 0 {
+		return false
+	}
+	if err := redis.RedisDb.Del(ctx, sessionKey(sessionId)).Err(); err != nil {
+		log.Log().Errorf("吊销登录会话失败: %v", err)
+	}
+	return true
+}
+
+// 吊销用户的全部会话，用于停用、删除用户或管理员强制下线
+func RevokeAdminSessions(adminIds ...uint) {
+	for _, adminId := range adminIds {
+		revokeAdminSessionsExcept(adminId, "")
+	}
+}
+
+// 吊销用户除指定会话外的全部会话
+func revokeAdminSessionsExcept(adminId uint, keepSessionId string) {
+	if redis.RedisDb == nil {
+		return
+	}
+	ctx := context.Background()
+	sessionIds, err := redis.RedisDb.SMembers(ctx, adminSessionKey(adminId)).Result()
+	if err != nil {
+		log.Log().Errorf("查询用户会话失败: %v", err)
+		return
+	}
+	for _, sessionId := range sessionIds {
+		if sessionId != keepSessionId {
+			RevokeSession(adminId, sessionId)
+		}
+	}
+}
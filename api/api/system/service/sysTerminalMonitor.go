@@ -0,0 +1,232 @@
+// 终端会话实时观看与终止 服务层
+// author xiaoRui
+
+package service
+
+import (
+	"dodevops-api/api/system/dao"
+	"dodevops-api/api/system/model"
+	"dodevops-api/common/result"
+	"dodevops-api/common/util"
+	"dodevops-api/pkg/jwt"
+	"dodevops-api/pkg/log"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+)
+
+// 开始观看时补发的最近终端输出长度
+const observeTailSize = 64 * 1024
+
+// 观看者的消息缓冲，接收过慢的观看者会被断开，不影响终端会话
+const observerBufferSize = 256
+
+// 本实例进行中的终端会话，多实例部署时只能观看和终止连接在当前实例上的会话
+var terminalRegistry = struct {
+	sync.RWMutex
+	sessions map[uint]*TerminalSession
+}{sessions: map[uint]*TerminalSession{}}
+
+type terminalObserver struct {
+	messages chan model.SysTerminalObserveMessage
+}
+
+func registerTerminalSession(t *TerminalSession) {
+	terminalRegistry.Lock()
+	defer terminalRegistry.Unlock()
+	terminalRegistry.sessions[t.session.ID] = t
+}
+
+func unregisterTerminalSession(t *TerminalSession) {
+	terminalRegistry.Lock()
+	defer terminalRegistry.Unlock()
+	delete(terminalRegistry.sessions, t.session.ID)
+}
+
+func getActiveTerminalSession(id uint) *TerminalSession {
+	terminalRegistry.RLock()
+	defer terminalRegistry.RUnlock()
+	return terminalRegistry.sessions[id]
+}
+
+// OnTerminate 设置会话被终止时的处理，message 为需要显示给终端用户的提示
+func (t *TerminalSession) OnTerminate(terminate func(message string)) {
+	t.monitor.Lock()
+	defer t.monitor.Unlock()
+	t.terminate = terminate
+}
+
+// 录像事件推送给观看者，并保留最近的输出
+func (t *TerminalSession) broadcast(offset float64, kind, data string) {
+	t.monitor.Lock()
+	defer t.monitor.Unlock()
+	switch kind {
+	case "o":
+		t.tail = append(t.tail, data...)
+		if len(t.tail) > observeTailSize {
+			cut := len(t.tail) - observeTailSize
+			for cut < len(t.tail) && !utf8.RuneStart(t.tail[cut]) {
+				cut++
+			}
+			t.tail = append([]byte(nil), t.tail[cut:]...)
+		}
+	case "r":
+		fmt.Sscanf(data, "%dx%d", &t.session.Width, &t.session.Height)
+	}
+	message := model.SysTerminalObserveMessage{Type: kind, Time: offset, Data: data}
+	for observer := range t.observers {
+		select {
+		case observer.messages <- message:
+		default:
+			delete(t.observers, observer)
+			close(observer.messages)
+		}
+	}
+}
+
+// 添加观看者，返回当前的会话信息和最近的输出
+func (t *TerminalSession) observe() (*terminalObserver, model.SysTerminalSession, string) {
+	t.monitor.Lock()
+	defer t.monitor.Unlock()
+	observer := &terminalObserver{messages: make(chan model.SysTerminalObserveMessage, observerBufferSize)}
+	t.observers[observer] = true
+	return observer, t.session, string(t.tail)
+}
+
+func (t *TerminalSession) unobserve(observer *terminalObserver) {
+	t.monitor.Lock()
+	defer t.monitor.Unlock()
+	if t.observers[observer] {
+		delete(t.observers, observer)
+		close(observer.messages)
+	}
+}
+
+// 会话结束时通知并断开全部观看者，调用方需持有 monitor 锁
+func (t *TerminalSession) closeObservers(reason string) {
+	for observer := range t.observers {
+		select {
+		case observer.messages <- model.SysTerminalObserveMessage{Type: "closed", Data: reason}:
+		default:
+		}
+		delete(t.observers, observer)
+		close(observer.messages)
+	}
+}
+
+// 当前会话信息和观看人数
+func (t *TerminalSession) snapshot() model.SysTerminalSessionActiveVo {
+	t.monitor.Lock()
+	defer t.monitor.Unlock()
+	return model.SysTerminalSessionActiveVo{SysTerminalSession: t.session, Observers: len(t.observers)}
+}
+
+// 进行中的终端会话
+func (s SysTerminalSessionServiceImpl) GetActiveSysTerminalSessionList(c *gin.Context) {
+	terminalRegistry.RLock()
+	sessions := make([]*TerminalSession, 0, len(terminalRegistry.sessions))
+	for _, session := range terminalRegistry.sessions {
+		sessions = append(sessions, session)
+	}
+	terminalRegistry.RUnlock()
+	list := make([]model.SysTerminalSessionActiveVo, 0, len(sessions))
+	for _, session := range sessions {
+		list = append(list, session.snapshot())
+	}
+	result.Success(c, list)
+}
+
+// 实时观看终端会话，只推送终端输出，不接收观看者的输入
+func (s SysTerminalSessionServiceImpl) ObserveSysTerminalSession(c *gin.Context, conn *websocket.Conn, id uint) {
+	session := getActiveTerminalSession(id)
+	if session == nil {
+		conn.WriteJSON(model.SysTerminalObserveMessage{Type: "closed", Data: "会话不存在或已结束"})
+		return
+	}
+	observer, info, tail := session.observe()
+	defer session.unobserve(observer)
+	writeTerminalLog(c, "观看终端会话", info)
+
+	if err := conn.WriteJSON(model.SysTerminalObserveMessage{Type: "session", Session: &info}); err != nil {
+		return
+	}
+	if tail != "" {
+		if err := conn.WriteJSON(model.SysTerminalObserveMessage{Type: "o", Data: tail}); err != nil {
+			return
+		}
+	}
+
+	// 观看者只读，读取消息仅用于检测连接断开
+	disconnected := make(chan struct{})
+	go func() {
+		defer close(disconnected)
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+	for {
+		select {
+		case message, ok := <-observer.messages:
+			if !ok {
+				return
+			}
+			if err := conn.WriteJSON(message); err != nil || message.Type == "closed" {
+				return
+			}
+		case <-disconnected:
+			return
+		}
+	}
+}
+
+// 终止终端会话，终止原因显示给终端用户
+func (s SysTerminalSessionServiceImpl) TerminateSysTerminalSession(c *gin.Context, dto model.SysTerminalSessionTerminateDto) {
+	dto.Reason = strings.TrimSpace(dto.Reason)
+	if dto.Reason == "" {
+		result.Failed(c, int(result.ApiCode.FAILED), "请填写终止原因")
+		return
+	}
+	session := getActiveTerminalSession(dto.Id)
+	if session == nil {
+		result.Failed(c, int(result.ApiCode.FAILED), "会话不存在、已结束或不在当前实例上")
+		return
+	}
+	operator, _ := jwt.GetAdminName(c)
+	session.monitor.Lock()
+	terminate := session.terminate
+	if terminate != nil {
+		session.session.TerminatedBy, session.session.Reason = operator, dto.Reason
+	}
+	session.monitor.Unlock()
+	if terminate == nil {
+		result.Failed(c, int(result.ApiCode.FAILED), "该会话不支持终止")
+		return
+	}
+	log.Log().Infof("终端会话 %s 被 %s 终止: %s", session.session.SessionId, operator, dto.Reason)
+	terminate(fmt.Sprintf("\r\n\x1b[31m会话已被管理员 %s 终止：%s\x1b[0m\r\n", operator, dto.Reason))
+	result.Success(c, true)
+}
+
+// 观看终端会话不经过操作日志中间件，单独记录
+func writeTerminalLog(c *gin.Context, action string, session model.SysTerminalSession) {
+	admin, err := jwt.GetAdmin(c)
+	if err != nil {
+		return
+	}
+	dao.CreateSysOperationLog(model.SysOperationLog{
+		AdminId:     admin.ID,
+		Username:    admin.Username,
+		Method:      terminalLogMethod,
+		Ip:          c.ClientIP(),
+		Url:         c.Request.URL.Path,
+		Description: fmt.Sprintf("%s %s(%s)", action, session.TargetName, session.Username),
+		CreateTime:  util.HTime{Time: time.Now()},
+	})
+}
@@ -0,0 +1,233 @@
+// 终端会话审计 服务层
+// author xiaoRui
+
+package service
+
+import (
+	"context"
+	"dodevops-api/api/system/dao"
+	"dodevops-api/api/system/model"
+	"dodevops-api/common/config"
+	"dodevops-api/common/result"
+	"dodevops-api/common/util"
+	"dodevops-api/pkg/jwt"
+	"dodevops-api/pkg/log"
+	"dodevops-api/pkg/recording"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/gorilla/websocket"
+)
+
+type ISysTerminalSessionService interface {
+	GetSysTerminalSessionList(c *gin.Context, dto model.SysTerminalSessionQueryDto)       // 终端会话列表
+	GetSysTerminalSessionInfo(c *gin.Context, id uint)                                    // 终端会话详情
+	PlaySysTerminalSession(c *gin.Context, id uint)                                       // 获取会话录像
+	SearchSysTerminalCommand(c *gin.Context, dto model.SysTerminalCommandQueryDto)        // 检索执行过的命令
+	GetActiveSysTerminalSessionList(c *gin.Context)                                       // 进行中的终端会话
+	ObserveSysTerminalSession(c *gin.Context, conn *websocket.Conn, id uint)              // 实时观看终端会话
+	TerminateSysTerminalSession(c *gin.Context, dto model.SysTerminalSessionTerminateDto) // 终止终端会话
+}
+
+type SysTerminalSessionServiceImpl struct{}
+
+// 终端会话列表
+func (s SysTerminalSessionServiceImpl) GetSysTerminalSessionList(c *gin.Context, dto model.SysTerminalSessionQueryDto) {
+	dto.PageNum, dto.PageSize = terminalPage(dto.PageNum, dto.PageSize)
+	sessions, count := dao.GetSysTerminalSessionList(dto)
+	result.SuccessWithPage(c, sessions, count, dto.PageNum, dto.PageSize)
+}
+
+// 终端会话详情，包含执行的命令
+func (s SysTerminalSessionServiceImpl) GetSysTerminalSessionInfo(c *gin.Context, id uint) {
+	session, err := dao.GetSysTerminalSessionById(id)
+	if err != nil {
+		result.Failed(c, int(result.ApiCode.FAILED), "终端会话不存在")
+		return
+	}
+	result.Success(c, model.SysTerminalSessionInfoVo{SysTerminalSession: session, Commands: dao.GetSysTerminalCommandList(id)})
+}
+
+// 获取会话录像，返回 asciicast v2 文件，可直接用于 asciinema-player 回放
+func (s SysTerminalSessionServiceImpl) PlaySysTerminalSession(c *gin.Context, id uint) {
+	session, err := dao.GetSysTerminalSessionById(id)
+	if err != nil {
+		result.Failed(c, int(result.ApiCode.FAILED), "终端会话不存在")
+		return
+	}
+	if session.Status == model.TerminalStatusActive || session.StorageKey == "" {
+		result.Failed(c, int(result.ApiCode.FAILED), "会话尚未结束，暂无录像")
+		return
+	}
+	storage, err := recording.StorageOf(session.Backend)
+	if err != nil {
+		result.Failed(c, int(result.ApiCode.FAILED), err.Error())
+		return
+	}
+	reader, err := storage.Open(c.Request.Context(), session.StorageKey)
+	if err != nil {
+		result.Failed(c, int(result.ApiCode.FAILED), fmt.Sprintf("读取录像失败: %v", err))
+		return
+	}
+	defer reader.Close()
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%s.cast", session.SessionId))
+	c.Header("Content-Type", "application/x-asciicast")
+	c.Status(200)
+	if _, err := io.Copy(c.Writer, reader); err != nil {
+		log.Log().Errorf("输出终端会话录像失败: %v", err)
+	}
+}
+
+// 检索执行过的命令
+func (s SysTerminalSessionServiceImpl) SearchSysTerminalCommand(c *gin.Context, dto model.SysTerminalCommandQueryDto) {
+	if dto.Keyword == "" {
+		result.Failed(c, int(result.ApiCode.FAILED), "请输入命令关键字")
+		return
+	}
+	dto.PageNum, dto.PageSize = terminalPage(dto.PageNum, dto.PageSize)
+	commands, count := dao.SearchSysTerminalCommand(dto)
+	result.SuccessWithPage(c, commands, count, dto.PageNum, dto.PageSize)
+}
+
+func terminalPage(pageNum, pageSize int) (int, int) {
+	if pageSize < 1 {
+		pageSize = 10
+	}
+	if pageNum < 1 {
+		pageNum = 1
+	}
+	return pageNum, pageSize
+}
+
+// TerminalSession 进行中的终端会话，录制终端输出和执行的命令，结束时保存录像和会话记录；
+// 会话进行中登记在本实例的会话列表中，可实时观看和终止
+type TerminalSession struct {
+	*recording.Recorder
+	session   model.SysTerminalSession
+	once      sync.Once
+	monitor   sync.Mutex
+	observers map[*terminalObserver]bool
+	tail      []byte               // 最近的终端输出，开始观看时补发
+	terminate func(message string) // 向终端用户显示提示并断开连接
+}
+
+// 开始终端会话录制，录像无法创建时返回错误，终端不应在未录制的情况下继续连接
+func StartSysTerminalSession(c *gin.Context, dto model.SysTerminalSessionStartDto) (*TerminalSession, error) {
+	now := time.Now()
+	session := model.SysTerminalSession{
+		SessionId:  uuid.NewString(),
+		Kind:       dto.Kind,
+		TargetId:   dto.TargetId,
+		TargetName: dto.TargetName,
+		Address:    dto.Address,
+		Ip:         c.ClientIP(),
+		Width:      dto.Width,
+		Height:     dto.Height,
+		Status:     model.TerminalStatusActive,
+		StartTime:  util.HTime{Time: now},
+	}
+	if admin, err := jwt.GetAdmin(c); err == nil {
+		session.AdminId, session.Username = admin.ID, admin.Username
+	}
+	session.StorageKey = fmt.Sprintf("%s/%s.cast", now.Format("2006/01/02"), session.SessionId)
+	title := fmt.Sprintf("%s@%s", session.Username, session.TargetName)
+	recorder, err := recording.NewRecorder(session.StorageKey, dto.Width, dto.Height, title)
+	if err != nil {
+		return nil, err
+	}
+	if err := dao.CreateSysTerminalSession(&session); err != nil {
+		recorder.Close(context.Background())
+		return nil, fmt.Errorf("保存终端会话失败: %v", err)
+	}
+	t := &TerminalSession{Recorder: recorder, session: session, observers: map[*terminalObserver]bool{}}
+	recorder.SetEventHandler(t.broadcast)
+	registerTerminalSession(t)
+	return t, nil
+}
+
+// 终端会话ID
+func (t *TerminalSession) ID() uint {
+	return t.session.ID
+}
+
+// 结束会话，保存录像到存储后端并记录执行的命令
+func (t *TerminalSession) Close() {
+	t.once.Do(t.finish)
+}
+
+func (t *TerminalSession) finish() {
+	unregisterTerminalSession(t)
+	res, err := t.Recorder.Close(context.Background())
+	if err != nil {
+		log.Log().Errorf("保存终端会话 %s 录像失败: %v", t.session.SessionId, err)
+	}
+	t.monitor.Lock()
+	defer t.monitor.Unlock()
+	closeReason := "会话已结束"
+	endTime := util.HTime{Time: time.Now()}
+	t.session.Backend = res.Backend
+	t.session.Size = res.Size
+	t.session.Duration = res.Duration
+	t.session.CommandCount = len(res.Commands)
+	t.session.Status = model.TerminalStatusFinished
+	if t.session.TerminatedBy != "" {
+		t.session.Status = model.TerminalStatusTerminated
+		closeReason = fmt.Sprintf("会话已被 %s 终止：%s", t.session.TerminatedBy, t.session.Reason)
+	}
+	t.session.EndTime = &endTime
+	start := t.session.StartTime.Time
+	commands := make([]model.SysTerminalCommand, 0, len(res.Commands))
+	for _, command := range res.Commands {
+		commands = append(commands, model.SysTerminalCommand{
+			SessionId:  t.session.ID,
+			Command:    command.Command,
+			Offset:     command.Offset,
+			CreateTime: util.HTime{Time: start.Add(time.Duration(command.Offset * float64(time.Second)))},
+		})
+	}
+	if err := dao.FinishSysTerminalSession(&t.session, commands); err != nil {
+		log.Log().Errorf("保存终端会话 %s 记录失败: %v", t.session.SessionId, err)
+	}
+	t.closeObservers(closeReason)
+}
+
+// 服务启动时处理上次异常退出遗留的进行中会话：保存已录制的部分并标记为异常中断，
+// 录像不在本机的会话属于其他实例，不做处理
+func RecoverSysTerminalSessions() {
+	for _, session := range dao.GetSysTerminalSessionByStatus(model.TerminalStatusActive) {
+		backend, size, err := recording.SaveOrphan(context.Background(), session.StorageKey)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			log.Log().Warnf("保存异常中断的终端会话 %s 录像失败: %v", session.SessionId, err)
+		}
+		endTime := util.HTime{Time: time.Now()}
+		session.Backend, session.Size = backend, size
+		session.Status = model.TerminalStatusInterrupted
+		session.EndTime = &endTime
+		if err := dao.FinishSysTerminalSession(&session, nil); err != nil {
+			log.Log().Errorf("更新异常中断的终端会话 %s 失败: %v", session.SessionId, err)
+		}
+	}
+}
+
+// 按配置初始化录像存储并处理异常中断的会话
+func SetupSysTerminalRecording() error {
+	if err := recording.Setup(config.Config.Recording); err != nil {
+		return err
+	}
+	RecoverSysTerminalSessions()
+	return nil
+}
+
+var sysTerminalSessionService = SysTerminalSessionServiceImpl{}
+
+func SysTerminalSessionService() ISysTerminalSessionService {
+	return &sysTerminalSessionService
+}
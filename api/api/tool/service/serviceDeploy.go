@@ -211,11 +211,15 @@ func (s ServiceDeployServiceImpl) executeDeploy(
 	// 1. 获取SSH凭据
 	ecsAuthDao := ccDao.NewEcsAuthDao()
 	key, err := ecsAuthDao.GetById(host.SSHKeyID)
+	if err == nil {
+		err = key.ResolveSecrets()
+	}
 	if err != nil {
 		deployLog.WriteString(fmt.Sprintf("[%s] 获取SSH凭据失败: %s\n", time.Now().Format("2006-01-02 15:04:05"), err.Error()))
 		status = 3
 		return
 	}
+	ccDao.MarkEcsAuthUsed(key.ID)
 
 	// 2. 建立SSH连接
 	deployLog.WriteString(fmt.Sprintf("[%s] 连接主机 %s...\n", time.Now().Format("2006-01-02 15:04:05"), host.SSHIP))
@@ -691,6 +695,10 @@ func (s ServiceDeployServiceImpl) DeleteDeploy(c *gin.Context, id uint) {
 		ecsAuthDao := ccDao.NewEcsAuthDao()
 		key, err := ecsAuthDao.GetById(host.SSHKeyID)
 		if err == nil {
+			err = key.ResolveSecrets()
+		}
+		if err == nil {
+			ccDao.MarkEcsAuthUsed(key.ID)
 			sshConfig := &util.SSHConfig{
 				IP:        host.SSHIP,
 				Port:      host.SSHPort,
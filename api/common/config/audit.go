@@ -0,0 +1,11 @@
+// 审计日志配置
+// author xiaoRui
+
+package config
+
+// AuditConfig 操作审计日志配置，日志以哈希链方式写入，过期日志归档为压缩文件后再从数据库移除
+type AuditConfig struct {
+	RetentionDays        int    `yaml:"retentionDays"`        // 数据库中保留的天数，超过后归档，0 表示不自动归档
+	ArchiveDir           string `yaml:"archiveDir"`           // 归档文件目录，默认 ./archive/audit
+	ArchiveIntervalHours int    `yaml:"archiveIntervalHours"` // 自动归档检查间隔(小时)，默认 24
+}
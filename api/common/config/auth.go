@@ -0,0 +1,116 @@
+// 登录认证配置
+// author xiaoRui
+
+package config
+
+// AuthConfig 认证配置
+type AuthConfig struct {
+	Providers []string       `yaml:"providers"` // 认证提供者链，按顺序尝试，可选值: local、ldap，默认仅 local
+	Ldap      LdapConfig     `yaml:"ldap"`      // LDAP / Active Directory 配置
+	Oidc      []OidcConfig   `yaml:"oidc"`      // OIDC / OAuth2 单点登录配置，可配置多个
+	Mfa       MfaConfig      `yaml:"mfa"`       // 二次验证（TOTP）配置
+	Session   SessionConfig  `yaml:"session"`   // 登录会话配置
+	Lockout   LockoutConfig  `yaml:"lockout"`   // 登录失败锁定配置
+	Password  PasswordPolicy `yaml:"password"`  // 本地账号密码策略
+}
+
+// LockoutConfig 登录失败锁定配置，按用户名和来源IP分别统计，次数小于0表示不限制
+type LockoutConfig struct {
+	MaxFailures     int `yaml:"maxFailures"`     // 统计窗口内同一用户名允许的失败次数，超过后锁定账号，默认 5
+	IpMaxFailures   int `yaml:"ipMaxFailures"`   // 统计窗口内同一IP允许的失败次数，超过后锁定该IP，默认 20
+	WindowMinutes   int `yaml:"windowMinutes"`   // 失败次数统计窗口(分钟)，默认 15
+	LockMinutes     int `yaml:"lockMinutes"`     // 锁定时长(分钟)，默认 15
+	BaseDelaySecond int `yaml:"baseDelaySecond"` // 失败后再次尝试的等待时间基数(秒)，每次失败翻倍，0 表示不延迟
+	MaxDelaySecond  int `yaml:"maxDelaySecond"`  // 单次等待时间上限(秒)，默认 60
+}
+
+// PasswordPolicy 本地账号密码策略
+type PasswordPolicy struct {
+	MinLength      int  `yaml:"minLength"`      // 最小长度，默认 8
+	RequireUpper   bool `yaml:"requireUpper"`   // 必须包含大写字母
+	RequireLower   bool `yaml:"requireLower"`   // 必须包含小写字母
+	RequireDigit   bool `yaml:"requireDigit"`   // 必须包含数字
+	RequireSpecial bool `yaml:"requireSpecial"` // 必须包含特殊字符
+	HistoryCount   int  `yaml:"historyCount"`   // 不能与最近 N 次使用过的密码相同，0 表示不限制
+	ExpireDays     int  `yaml:"expireDays"`     // 密码有效期(天)，过期后登录需先修改密码，0 表示永不过期
+}
+
+// SessionConfig 登录会话配置，访问令牌过期后使用刷新令牌续期，会话保存在 redis 中可随时吊销
+type SessionConfig struct {
+	AccessTokenMinutes int `yaml:"accessTokenMinutes"` // 访问令牌有效期(分钟)，默认 30
+	RefreshTokenDays   int `yaml:"refreshTokenDays"`   // 刷新令牌有效期(天)，即会话最长空闲时间，默认 7
+}
+
+// MfaConfig 二次验证（TOTP）配置
+type MfaConfig struct {
+	Issuer        string   `yaml:"issuer"`        // 认证器中显示的签发方名称，默认 AutoOps
+	RequiredRoles []string `yaml:"requiredRoles"` // 强制启用二次验证的角色key，拥有其中任一角色的用户登录时必须完成二次验证
+}
+
+// ProvisionConfig 外部账号自动创建时的角色/部门分配配置
+type ProvisionConfig struct {
+	DefaultRoleKey   string            `yaml:"defaultRoleKey"`   // 未匹配到组映射时分配的角色key
+	DefaultDeptId    int               `yaml:"defaultDeptId"`    // 未匹配到组映射时分配的部门id
+	DefaultPostId    int               `yaml:"defaultPostId"`    // 新建用户默认岗位id
+	GroupRoleMapping map[string]string `yaml:"groupRoleMapping"` // 组名 -> 角色key
+	GroupDeptMapping map[string]string `yaml:"groupDeptMapping"` // 组名 -> 部门名称
+}
+
+// LdapConfig LDAP / Active Directory 认证配置
+type LdapConfig struct {
+	Url                string           `yaml:"url"`                // 服务地址，如 ldap://127.0.0.1:389 或 ldaps://ad.example.com:636
+	StartTLS           bool             `yaml:"startTLS"`           // 是否使用 StartTLS
+	InsecureSkipVerify bool             `yaml:"insecureSkipVerify"` // 是否跳过证书校验
+	BindDN             string           `yaml:"bindDN"`             // 查询用户使用的服务账号DN，为空时匿名查询
+	BindPassword       string           `yaml:"bindPassword"`       // 服务账号密码
+	BaseDN             string           `yaml:"baseDN"`             // 用户搜索基础DN
+	UserFilter         string           `yaml:"userFilter"`         // 用户过滤条件，%s 替换为登录名，如 (uid=%s)、(sAMAccountName=%s)
+	GroupBaseDN        string           `yaml:"groupBaseDN"`        // 组搜索基础DN，为空时仅使用用户的 memberOf 属性
+	GroupFilter        string           `yaml:"groupFilter"`        // 组过滤条件，%s 替换为用户DN，如 (member=%s)
+	GroupNameAttr      string           `yaml:"groupNameAttr"`      // 组名属性，默认 cn
+	Attributes         LdapAttributes   `yaml:"attributes"`         // 用户属性映射
+	ProvisionConfig    `yaml:",inline"` // 角色/部门映射，组名为 LDAP 组名
+}
+
+// LdapAttributes LDAP 用户属性映射
+type LdapAttributes struct {
+	Nickname string `yaml:"nickname"` // 昵称属性，默认 cn
+	Email    string `yaml:"email"`    // 邮箱属性，默认 mail
+	Phone    string `yaml:"phone"`    // 电话属性，默认 telephoneNumber
+	MemberOf string `yaml:"memberOf"` // 用户所属组属性，默认 memberOf
+}
+
+// OidcConfig OIDC / OAuth2 单点登录配置
+type OidcConfig struct {
+	Name            string           `yaml:"name"`         // 提供者标识，用于登录/回调地址 /api/v1/sso/{name}/login
+	DisplayName     string           `yaml:"displayName"`  // 登录页展示名称
+	Type            string           `yaml:"type"`         // 类型: oidc(通用)、keycloak、feishu，feishu 会预置飞书的授权/令牌/用户信息地址
+	Issuer          string           `yaml:"issuer"`       // OIDC Issuer，配置后通过 discovery 获取端点并校验 id_token
+	ClientId        string           `yaml:"clientId"`     // 客户端ID（飞书为 App ID）
+	ClientSecret    string           `yaml:"clientSecret"` // 客户端密钥（飞书为 App Secret）
+	Scopes          []string         `yaml:"scopes"`       // 申请的 scope，OIDC 默认 openid profile email
+	AuthUrl         string           `yaml:"authUrl"`      // 授权地址，为空时使用 discovery 结果
+	TokenUrl        string           `yaml:"tokenUrl"`     // 令牌地址，为空时使用 discovery 结果
+	UserInfoUrl     string           `yaml:"userInfoUrl"`  // 用户信息地址，为空时使用 discovery 结果
+	RedirectUrl     string           `yaml:"redirectUrl"`  // 回调地址，为空时使用 server.publicUrl + api/v1/sso/{name}/callback
+	FrontendUrl     string           `yaml:"frontendUrl"`  // 登录成功后跳转的前端地址，携带一次性登录码 code 参数，前端通过 /api/v1/sso/exchange 换取登录结果；为空时回调直接返回登录结果
+	Claims          OidcClaims       `yaml:"claims"`       // 用户信息字段映射
+	ProvisionConfig `yaml:",inline"` // 角色/部门映射，组名为 groups 声明中的值
+}
+
+// OidcClaims OIDC 声明映射，支持 realm_access.roles 形式的嵌套路径
+type OidcClaims struct {
+	Username string `yaml:"username"` // 登录名，默认 preferred_username，缺失时依次使用 email、sub
+	Nickname string `yaml:"nickname"` // 昵称，默认 name
+	Email    string `yaml:"email"`    // 邮箱，默认 email
+	Phone    string `yaml:"phone"`    // 电话，默认 phone_number
+	Groups   string `yaml:"groups"`   // 组/角色列表，默认 groups
+}
+
+// GetAuthConfig 获取认证配置
+func GetAuthConfig() *AuthConfig {
+	if Config == nil {
+		panic("Config is not initialized")
+	}
+	return &Config.Auth
+}
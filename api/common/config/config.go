@@ -11,12 +11,18 @@ import (
 
 // 总配文件
 type config struct {
-	Server        server        `yaml:"server"`
-	Db            db            `yaml:"db"`
-	Redis         redis         `yaml:"redis"`
-	ImageSettings imageSettings `yaml:"imageSettings"`
-	Log           log           `yaml:"log"`
-	Monitor       monitor       `yaml:"monitor"`
+	Server        server            `yaml:"server"`
+	Db            db                `yaml:"db"`
+	Redis         redis             `yaml:"redis"`
+	ImageSettings imageSettings     `yaml:"imageSettings"`
+	Log           log               `yaml:"log"`
+	Monitor       monitor           `yaml:"monitor"`
+	Auth          AuthConfig        `yaml:"auth"`
+	Audit         AuditConfig       `yaml:"audit"`
+	Encryption    EncryptionConfig  `yaml:"encryption"`
+	SecretStore   SecretStoreConfig `yaml:"secretStore"`
+	HostKey       HostKeyConfig     `yaml:"hostKey"`
+	Recording     RecordingConfig   `yaml:"recording"`
 }
 
 // 监控配置
@@ -47,6 +53,7 @@ type server struct {
 	Address       string `yaml:"address"`
 	Model         string `yaml:"model"`
 	EnableSwagger bool   `yaml:"enableSwagger"` // 是否启用Swagger文档
+	PublicUrl     string `yaml:"publicUrl"`     // 对外访问的基础URL，用于生成单点登录回调地址
 }
 
 // 数据库配置
@@ -0,0 +1,13 @@
+// 敏感字段加密配置
+// author xiaoRui
+
+package config
+
+// EncryptionConfig 敏感字段信封加密配置
+// 主密钥优先从环境变量 AUTOOPS_MASTER_KEYS 读取，其次读取 masterKeyFile，格式均为 "版本:base64密钥"，多个密钥用换行或逗号分隔
+type EncryptionConfig struct {
+	MasterKeyFile     string `yaml:"masterKeyFile"`     // 主密钥文件
+	ActiveKeyVersion  int    `yaml:"activeKeyVersion"`  // 加密使用的主密钥版本，默认使用最大版本，也可通过环境变量 AUTOOPS_MASTER_KEY_VERSION 指定
+	GenerateIfMissing bool   `yaml:"generateIfMissing"` // 未配置任何主密钥时自动生成并写入 masterKeyFile
+	RotateOnStartup   bool   `yaml:"rotateOnStartup"`   // 启动后在后台将旧版本主密钥或历史方式加密的数据重新加密
+}
@@ -0,0 +1,19 @@
+// SSH主机密钥校验配置
+// author xiaoRui
+
+package config
+
+// SSH主机密钥校验策略
+const (
+	HostKeyPolicyTofu   = "tofu"   // 首次连接自动信任并记录，之后密钥变更拒绝连接（默认）
+	HostKeyPolicyStrict = "strict" // 只允许管理员确认或固定过的密钥，首次连接同样拒绝
+	HostKeyPolicyOff    = "off"    // 不校验主机密钥
+)
+
+// HostKeyConfig SSH主机密钥校验配置，主机密钥变更时按配置发送告警
+type HostKeyConfig struct {
+	Policy      string `yaml:"policy"`      // 校验策略：tofu、strict、off，为空时为 tofu
+	DingDingUrl string `yaml:"dingDingUrl"` // 密钥变更告警钉钉机器人地址
+	FeiShuUrl   string `yaml:"feiShuUrl"`   // 密钥变更告警飞书机器人地址
+	Emails      string `yaml:"emails"`      // 密钥变更告警邮箱，多个用逗号分隔
+}
@@ -0,0 +1,23 @@
+// 终端会话录制配置
+// author xiaoRui
+
+package config
+
+// RecordingConfig Web终端会话录制配置，录像为 asciicast v2 格式
+type RecordingConfig struct {
+	Backend string   `yaml:"backend"` // 录像存储后端：local（默认，保存在本地目录）、s3（S3 兼容的对象存储）
+	Dir     string   `yaml:"dir"`     // 本地录像目录，默认 ./data/recordings，s3 后端时作为会话结束前的临时目录
+	S3      S3Config `yaml:"s3"`      // S3 兼容对象存储配置
+}
+
+// S3Config S3 兼容对象存储配置（AWS S3、MinIO、阿里云 OSS 等）
+type S3Config struct {
+	Endpoint       string `yaml:"endpoint"`       // 服务地址，如 https://s3.amazonaws.com、http://127.0.0.1:9000
+	Region         string `yaml:"region"`         // 区域，默认 us-east-1
+	Bucket         string `yaml:"bucket"`         // 存储桶
+	AccessKey      string `yaml:"accessKey"`      // AccessKey，为空时读取环境变量 AWS_ACCESS_KEY_ID
+	SecretKey      string `yaml:"secretKey"`      // SecretKey，为空时读取环境变量 AWS_SECRET_ACCESS_KEY
+	Prefix         string `yaml:"prefix"`         // 对象路径前缀，默认 recordings
+	PathStyle      bool   `yaml:"pathStyle"`      // 使用路径风格访问（MinIO 需要开启）
+	TimeoutSeconds int    `yaml:"timeoutSeconds"` // 请求超时时间，默认 60 秒
+}
@@ -0,0 +1,20 @@
+// 密钥存储配置
+// author xiaoRui
+
+package config
+
+// SecretStoreConfig 凭证密钥存储配置
+type SecretStoreConfig struct {
+	Backend string      `yaml:"backend"` // 新写入凭证的存储后端：db（默认，加密后保存在数据库）、vault
+	Vault   VaultConfig `yaml:"vault"`   // Vault 配置，已有 Vault 引用时即使后端为 db 也需要配置以便读取
+}
+
+// VaultConfig HashiCorp Vault KV v2 配置
+type VaultConfig struct {
+	Address        string `yaml:"address"`        // 地址，为空时读取环境变量 VAULT_ADDR
+	Token          string `yaml:"token"`          // 访问令牌，为空时读取环境变量 VAULT_TOKEN
+	Namespace      string `yaml:"namespace"`      // 命名空间（Vault 企业版）
+	Mount          string `yaml:"mount"`          // KV v2 引擎挂载路径，默认 secret
+	PathPrefix     string `yaml:"pathPrefix"`     // 密钥路径前缀，默认 autoops
+	TimeoutSeconds int    `yaml:"timeoutSeconds"` // 请求超时时间，默认 10 秒
+}
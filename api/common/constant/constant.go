@@ -5,6 +5,8 @@ package constant
 
 const (
 	ContextKeyUserObj      = "authedUserObj"
+	ContextKeyApiTokenScopes = "authedApiTokenScopes" // 通过个人访问令牌认证时的授权范围
+	ContextKeyDataScope      = "authedDataScope"      // 当前请求用户的数据范围
 	LOGIN_CODE             = "login_code:"
 	INVALID_PARAMS         = 400
 	GROUP_EXIST            = 415
@@ -25,7 +27,29 @@ const (
 	CMDB_HOST_SYNC_FAILED   = 428
 	CMDB_IMPORT_TASK_CREATE_FAILED = 429
 	FILE_OPERATION_ERROR    = 430 // 文件操作失败
+	CREDENTIAL_IN_USE       = 439 // 凭证仍被引用，需迁移后删除
+	CMDB_HOST_IN_USE        = 440 // 主机仍被作为跳板机使用
 	
+	// 权限相关常量
+	PERMISSION_CODE       = "sys_permission:" // 用户权限缓存key前缀
+	SUPER_ADMIN_ROLE_KEY  = "admin"           // 超级管理员角色key，拥有全部权限
+
+	// 单点登录相关常量
+	SSO_STATE_CODE = "sso_state:" // OIDC 登录 state 缓存key前缀
+	SSO_LOGIN_CODE = "sso_login:" // 单点登录一次性登录码缓存key前缀
+	MFA_TICKET_CODE = "mfa_ticket:" // 登录二次验证票据缓存key前缀
+
+	// 登录会话相关常量
+	SESSION_CODE       = "sys_session:"       // 登录会话缓存key前缀
+	ADMIN_SESSION_CODE = "sys_admin_session:" // 用户会话id集合key前缀
+
+	// 登录失败锁定相关常量
+	LOGIN_FAIL_USER_CODE = "login_fail:user:" // 用户名登录失败次数key前缀
+	LOGIN_FAIL_IP_CODE   = "login_fail:ip:"   // IP登录失败次数key前缀
+	LOGIN_LOCK_USER_CODE = "login_lock:user:" // 用户名锁定key前缀
+	LOGIN_LOCK_IP_CODE   = "login_lock:ip:"   // IP锁定key前缀
+	LOGIN_DELAY_CODE     = "login_delay:"     // 失败后再次尝试等待key前缀
+
 	// Kubernetes集群相关常量
 	KUBE_CLUSTER_CODE         = "kube_cluster:"
 	KUBE_CLUSTER_CACHE_CODE   = "kube_cluster_cache:"
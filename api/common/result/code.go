@@ -30,6 +30,13 @@ type Codes struct {
 	ValidationParameterError                uint
 	KUBEClUSTERNAMENOTEXIST                 uint
 	WEBSOCKETERROR                          uint
+	NOPERMISSION                            uint
+	MFACODENOTTRUE                          uint
+	MFATICKETEXPIRED                        uint
+	PASSWORDCHANGEREQUIRED                  uint
+	LOGINLOCKED                             uint
+	PASSWORDPOLICYERROR                     uint
+	APPROVALREQUIRED                        uint
 }
 
 // ApiCode 状态码
@@ -61,6 +68,13 @@ var ApiCode = &Codes{
 	KUBEClUSTERNOTEXIST:                     426,
 	KUBEClUSTERNAMENOTEXIST:                 427,
 	WEBSOCKETERROR:                          428,
+	NOPERMISSION:                            436,
+	MFACODENOTTRUE:                          437,
+	MFATICKETEXPIRED:                        438,
+	PASSWORDCHANGEREQUIRED:                  432,
+	LOGINLOCKED:                             433,
+	PASSWORDPOLICYERROR:                     434,
+	APPROVALREQUIRED:                        435,
 }
 
 // 状态信息
@@ -93,6 +107,13 @@ func init() {
 		ApiCode.KUBEClUSTERNOTEXIST:                     "集群ID不存在",
 		ApiCode.KUBEClUSTERNAMENOTEXIST:                 "集群名称不存在",
 		ApiCode.WEBSOCKETERROR:                          "WebSocket连接错误",
+		ApiCode.NOPERMISSION:                            "没有访问权限，请联系管理员分配",
+		ApiCode.MFACODENOTTRUE:                          "二次验证码不正确，请重新输入",
+		ApiCode.MFATICKETEXPIRED:                        "二次验证已过期，请重新登录",
+		ApiCode.PASSWORDCHANGEREQUIRED:                  "密码已过期或需要修改，请先修改密码",
+		ApiCode.LOGINLOCKED:                             "登录失败次数过多，请稍后再试",
+		ApiCode.PASSWORDPOLICYERROR:                     "密码不符合安全策略",
+		ApiCode.APPROVALREQUIRED:                        "该操作需要审批，已提交审批单，审批通过后自动执行",
 	}
 }
 
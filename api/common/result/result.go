@@ -59,6 +59,15 @@ func Failed(c *gin.Context, code int, message string) {
 	c.JSON(http.StatusOK, res)
 }
 
+// 返回失败并携带数据
+func FailedWithData(c *gin.Context, code int, message string, data interface{}) {
+	res := Result{}
+	res.Code = code
+	res.Message = message
+	res.Data = data
+	c.JSON(http.StatusOK, res)
+}
+
 // 新增方法：支持直接传入 code 和 message
 func FailedWithCode(c *gin.Context, code int, message string) {
 	Failed(c, code, message)
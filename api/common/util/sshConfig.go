@@ -11,4 +11,5 @@ type SSHConfig struct {
 	Password  string        // 密码(type=1时使用)
 	PublicKey string        // 私钥内容(type=2时使用) - 注意：字段名为PublicKey但实际存储私钥
 	Timeout   time.Duration // 超时时间
+	Jumps     []SSHConfig   // 跳板机，按连接顺序，为空时按CMDB中主机和分组的跳板机配置自动查询
 }
@@ -0,0 +1,75 @@
+package util
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// HostKeyVerifier 校验SSH主机密钥，addr 为连接地址 ip:port
+type HostKeyVerifier func(addr string, key ssh.PublicKey) error
+
+var (
+	hostKeyVerifierMu sync.RWMutex
+	hostKeyVerifier   HostKeyVerifier
+)
+
+// SetHostKeyVerifier 注册主机密钥校验器，由CMDB模块在启动时注册，未注册时不校验主机密钥
+func SetHostKeyVerifier(verifier HostKeyVerifier) {
+	hostKeyVerifierMu.Lock()
+	defer hostKeyVerifierMu.Unlock()
+	hostKeyVerifier = verifier
+}
+
+// HostKeyCallback 所有SSH连接统一使用的主机密钥校验回调
+func HostKeyCallback() ssh.HostKeyCallback {
+	return func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+		hostKeyVerifierMu.RLock()
+		verifier := hostKeyVerifier
+		hostKeyVerifierMu.RUnlock()
+		if verifier == nil {
+			return nil
+		}
+		return verifier(hostname, key)
+	}
+}
+
+// HostKeyError 主机密钥校验失败
+type HostKeyError struct {
+	Addr        string // 连接地址
+	Fingerprint string // 主机提供的密钥指纹
+	Reason      string // 失败原因
+}
+
+func (e *HostKeyError) Error() string {
+	return fmt.Sprintf("主机 %s 密钥校验失败(%s): %s", e.Addr, e.Fingerprint, e.Reason)
+}
+
+var errHostKeyScanned = errors.New("host key scanned")
+
+// ScanHostKey 获取主机当前的SSH主机密钥，只完成密钥交换，不进行认证
+func ScanHostKey(addr string, timeout time.Duration) (ssh.PublicKey, error) {
+	var hostKey ssh.PublicKey
+	config := &ssh.ClientConfig{
+		HostKeyCallback: func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+			hostKey = key
+			return errHostKeyScanned
+		},
+		Timeout: timeout,
+	}
+	client, err := DialSSHAddr(addr, config)
+	if client != nil {
+		client.Close()
+	}
+	if hostKey != nil {
+		return hostKey, nil
+	}
+	if err == nil {
+		err = errors.New("未获取到主机密钥")
+	}
+	return nil, err
+}
@@ -0,0 +1,118 @@
+package util
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// SSH 密钥类型
+const (
+	SSHKeyTypeEd25519 = "ed25519"
+	SSHKeyTypeRSA     = "rsa"
+)
+
+// GenerateSSHKeyPair 生成 SSH 密钥对，返回 OpenSSH 格式私钥和 authorized_keys 格式公钥
+func GenerateSSHKeyPair(keyType string, bits int, comment string) (privateKey string, publicKey string, err error) {
+	var key crypto.PrivateKey
+	switch keyType {
+	case "", SSHKeyTypeEd25519:
+		_, key, err = ed25519.GenerateKey(rand.Reader)
+	case SSHKeyTypeRSA:
+		if bits == 0 {
+			bits = 4096
+		}
+		if bits < 2048 {
+			return "", "", fmt.Errorf("RSA 密钥长度不能小于 2048")
+		}
+		key, err = rsa.GenerateKey(rand.Reader, bits)
+	default:
+		return "", "", fmt.Errorf("不支持的密钥类型: %s", keyType)
+	}
+	if err != nil {
+		return "", "", err
+	}
+	block, err := ssh.MarshalPrivateKey(key, comment)
+	if err != nil {
+		return "", "", err
+	}
+	privateKey = string(pem.EncodeToMemory(block))
+	publicKey, err = SSHPublicKeyFromPrivate(privateKey)
+	if err != nil {
+		return "", "", err
+	}
+	if comment != "" {
+		publicKey += " " + comment
+	}
+	return privateKey, publicKey, nil
+}
+
+// SSHPublicKeyFromPrivate 根据私钥计算 authorized_keys 格式公钥（不含注释）
+func SSHPublicKeyFromPrivate(privateKey string) (string, error) {
+	signer, err := ssh.ParsePrivateKey([]byte(privateKey))
+	if err != nil {
+		return "", fmt.Errorf("解析私钥失败: %v", err)
+	}
+	return strings.TrimSpace(string(ssh.MarshalAuthorizedKey(signer.PublicKey()))), nil
+}
+
+// GenerateSSHPassword 生成包含大小写字母、数字和符号的随机密码
+func GenerateSSHPassword(length int) (string, error) {
+	if length < 12 {
+		length = 12
+	}
+	groups := []string{"ABCDEFGHJKLMNPQRSTUVWXYZ", "abcdefghijkmnpqrstuvwxyz", "23456789", "@#%^*_+=-"}
+	all := strings.Join(groups, "")
+	password := make([]byte, length)
+	for i := range password {
+		chars := all
+		if i < len(groups) {
+			chars = groups[i] // 保证每类字符至少出现一次
+		}
+		n, err := rand.Int(rand.Reader, big.NewInt(int64(len(chars))))
+		if err != nil {
+			return "", err
+		}
+		password[i] = chars[n.Int64()]
+	}
+	// 打乱顺序
+	for i := len(password) - 1; i > 0; i-- {
+		n, err := rand.Int(rand.Reader, big.NewInt(int64(i+1)))
+		if err != nil {
+			return "", err
+		}
+		j := n.Int64()
+		password[i], password[j] = password[j], password[i]
+	}
+	return string(password), nil
+}
+
+// ShellQuote 将字符串转义为 shell 单引号字符串
+func ShellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// RunCommand 在已建立的 SSH 连接上执行命令，返回标准输出和标准错误
+func (s *SSHUtil) RunCommand(client *ssh.Client, command string) (string, error) {
+	session, err := client.NewSession()
+	if err != nil {
+		return "", fmt.Errorf("failed to create session: %v", err)
+	}
+	defer session.Close()
+
+	var output bytes.Buffer
+	session.Stdout = &output
+	session.Stderr = &output
+	if err := session.Run(command); err != nil {
+		return output.String(), fmt.Errorf("failed to run command: %v: %s", err, strings.TrimSpace(output.String()))
+	}
+	return output.String(), nil
+}
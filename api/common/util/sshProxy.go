@@ -0,0 +1,101 @@
+package util
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"sync"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// SSHProxyResolver 查询连接目标地址需要经过的跳板机，按连接顺序返回，不需要跳板机时返回空
+type SSHProxyResolver func(ip string, port int) ([]SSHConfig, error)
+
+var (
+	sshProxyResolverMu sync.RWMutex
+	sshProxyResolver   SSHProxyResolver
+)
+
+// SetSSHProxyResolver 注册跳板机查询，由CMDB模块在启动时注册，未注册时直接连接
+func SetSSHProxyResolver(resolver SSHProxyResolver) {
+	sshProxyResolverMu.Lock()
+	defer sshProxyResolverMu.Unlock()
+	sshProxyResolver = resolver
+}
+
+// DialSSH 建立SSH连接，auth 未指定跳板机时按注册的跳板机配置透明地经过跳板机连接
+func DialSSH(auth *SSHConfig, config *ssh.ClientConfig) (*ssh.Client, error) {
+	addr := net.JoinHostPort(auth.IP, strconv.Itoa(auth.Port))
+	if len(auth.Jumps) > 0 {
+		return dialThroughJumps(auth.Jumps, addr, config)
+	}
+	return DialSSHAddr(addr, config)
+}
+
+// DialSSHAddr 按地址 ip:port 建立SSH连接，目标配置了跳板机时依次经过跳板机建立隧道
+func DialSSHAddr(addr string, config *ssh.ClientConfig) (*ssh.Client, error) {
+	host, portStr, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+	port, _ := strconv.Atoi(portStr)
+	sshProxyResolverMu.RLock()
+	resolver := sshProxyResolver
+	sshProxyResolverMu.RUnlock()
+	var jumps []SSHConfig
+	if resolver != nil {
+		if jumps, err = resolver(host, port); err != nil {
+			return nil, err
+		}
+	}
+	return dialThroughJumps(jumps, addr, config)
+}
+
+func dialThroughJumps(jumps []SSHConfig, addr string, config *ssh.ClientConfig) (*ssh.Client, error) {
+	if len(jumps) == 0 {
+		return ssh.Dial("tcp", addr, config)
+	}
+	var client *ssh.Client
+	for i := range jumps {
+		jump := jumps[i]
+		jumpConfig, err := NewSSHUtil().getSSHConfig(&jump)
+		if err != nil {
+			if client != nil {
+				client.Close()
+			}
+			return nil, fmt.Errorf("跳板机 %s 认证配置错误: %v", jump.IP, err)
+		}
+		jumpAddr := net.JoinHostPort(jump.IP, strconv.Itoa(jump.Port))
+		if client == nil {
+			client, err = ssh.Dial("tcp", jumpAddr, jumpConfig)
+		} else {
+			client, err = dialViaClient(client, jumpAddr, jumpConfig)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("连接跳板机 %s 失败: %v", jumpAddr, err)
+		}
+	}
+	return dialViaClient(client, addr, config)
+}
+
+// 通过已建立的SSH连接转发到下一跳，下一跳的连接关闭时一并关闭上一跳，失败时关闭上一跳
+func dialViaClient(proxy *ssh.Client, addr string, config *ssh.ClientConfig) (*ssh.Client, error) {
+	conn, err := proxy.Dial("tcp", addr)
+	if err != nil {
+		proxy.Close()
+		return nil, err
+	}
+	c, chans, reqs, err := ssh.NewClientConn(conn, addr, config)
+	if err != nil {
+		conn.Close()
+		proxy.Close()
+		return nil, err
+	}
+	client := ssh.NewClient(c, chans, reqs)
+	go func() {
+		client.Wait()
+		proxy.Close()
+	}()
+	return client, nil
+}
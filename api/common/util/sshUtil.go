@@ -5,7 +5,6 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
-	"net"
 	"os"
 	"time"
 
@@ -27,7 +26,7 @@ func (s *SSHUtil) ExecuteRemoteCommand(auth *SSHConfig, command string) (string,
 	}
 
 	// 建立SSH连接
-	conn, err := ssh.Dial("tcp", fmt.Sprintf("%s:%d", auth.IP, auth.Port), config)
+	conn, err := DialSSH(auth, config)
 	if err != nil {
 		fmt.Printf("SSH连接失败: %v\n", err)
 		return "", fmt.Errorf("failed to dial: %v", err)
@@ -92,10 +91,8 @@ func (s *SSHUtil) getSSHConfig(auth *SSHConfig) (*ssh.ClientConfig, error) {
 	return &ssh.ClientConfig{
 		User: auth.Username,
 		Auth: authMethods,
-		HostKeyCallback: ssh.HostKeyCallback(func(hostname string, remote net.Addr, key ssh.PublicKey) error {
-			return nil // 忽略主机密钥检查
-		}),
-		Timeout: 30 * time.Second,
+		HostKeyCallback: HostKeyCallback(),
+		Timeout:         30 * time.Second,
 	}, nil
 }
 
@@ -187,7 +184,7 @@ func (s *SSHUtil) TerminalLogin(auth *SSHConfig) (*ssh.Client, error) {
 		return nil, fmt.Errorf("failed to create SSH config: %v", err)
 	}
 
-	return ssh.Dial("tcp", fmt.Sprintf("%s:%d", auth.IP, auth.Port), config)
+	return DialSSH(auth, config)
 }
 
 // UploadFile 上传文件到远程主机
@@ -295,7 +292,7 @@ func GetSSHClientByConfig(auth *SSHConfig) (*ssh.Client, error) {
 	}
 
 	// 建立SSH连接
-	client, err := ssh.Dial("tcp", fmt.Sprintf("%s:%d", auth.IP, auth.Port), config)
+	client, err := DialSSH(auth, config)
 	if err != nil {
 		return nil, fmt.Errorf("SSH连接失败: %v", err)
 	}
@@ -0,0 +1,107 @@
+package websocket
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"sync"
+
+	"dodevops-api/pkg/recording"
+)
+
+// 命令检查结果
+const (
+	CommandAllow   = 1 // 放行
+	CommandDeny    = 2 // 拦截
+	CommandConfirm = 3 // 需要用户确认后执行
+)
+
+// CommandDecision 命令检查结果
+type CommandDecision struct {
+	Action int    // 检查结果
+	Rule   string // 命中的规则名称
+}
+
+// CommandChecker 终端命令检查，Report 在命令被拦截、确认执行或取消执行时调用
+type CommandChecker interface {
+	Check(command string) CommandDecision
+	Report(command string, decision CommandDecision, confirmed bool)
+}
+
+// CommandGuard 按行缓冲终端输入，在回车发送到终端之前检查整行命令。
+// 被拦截的命令不发送回车并向终端发送 Ctrl+C 丢弃已输入的内容；需要确认的命令暂缓回车，
+// 用户输入 y 后再发送。通过历史记录、Tab 补全得到的命令无法从输入中还原，只能检查用户实际键入的内容
+type CommandGuard struct {
+	mu      sync.Mutex
+	checker CommandChecker
+	line    recording.LineBuffer
+	pending *pendingCommand // 等待用户确认的命令
+}
+
+type pendingCommand struct {
+	command  string
+	enter    byte
+	decision CommandDecision
+}
+
+func NewCommandGuard(checker CommandChecker) *CommandGuard {
+	return &CommandGuard{checker: checker}
+}
+
+// Filter 过滤用户输入，返回可以发送到终端的数据和需要显示给用户的提示
+func (g *CommandGuard) Filter(p []byte) ([]byte, string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	var forward []byte
+	var notice strings.Builder
+	for len(p) > 0 {
+		if g.pending != nil {
+			pending := g.pending
+			g.pending = nil
+			confirmed := p[0] == 'y' || p[0] == 'Y'
+			p = p[1:]
+			g.checker.Report(pending.command, pending.decision, confirmed)
+			if confirmed {
+				notice.WriteString("y\r\n")
+				forward = append(forward, pending.enter)
+			} else {
+				notice.WriteString("已取消\r\n")
+				forward = append(forward, 0x03)
+			}
+			g.line.Feed(forward[len(forward)-1:])
+			continue
+		}
+
+		i := bytes.IndexAny(p, "\r\n")
+		if i < 0 {
+			g.line.Feed(p)
+			forward = append(forward, p...)
+			break
+		}
+		g.line.Feed(p[:i])
+		forward = append(forward, p[:i]...)
+		command := strings.TrimSpace(g.line.Current())
+		enter := p[i]
+		p = p[i+1:]
+		g.line.Feed([]byte{enter})
+
+		decision := CommandDecision{Action: CommandAllow}
+		if command != "" {
+			decision = g.checker.Check(command)
+		}
+		switch decision.Action {
+		case CommandDeny:
+			g.checker.Report(command, decision, false)
+			notice.WriteString(fmt.Sprintf("\r\n\x1b[31m命令已被拦截（规则：%s）\x1b[0m\r\n", decision.Rule))
+			// 丢弃同一次输入中剩余的内容，避免粘贴的后续命令在未确认的上下文中执行
+			return append(forward, 0x03), notice.String()
+		case CommandConfirm:
+			g.pending = &pendingCommand{command: command, enter: enter, decision: decision}
+			notice.WriteString(fmt.Sprintf("\r\n\x1b[33m该命令需要确认（规则：%s），输入 y 确认执行，其他键取消：\x1b[0m", decision.Rule))
+			return forward, notice.String()
+		default:
+			forward = append(forward, enter)
+		}
+	}
+	return forward, notice.String()
+}
@@ -13,6 +13,7 @@ import (
 	"path/filepath"
 	"time"
 	"unicode/utf8"
+	"dodevops-api/common/util"
 	"github.com/dnsjia/luban/common"
 	"github.com/dnsjia/luban/pkg/utils"
 	"golang.org/x/crypto/ssh"
@@ -184,7 +185,7 @@ func NewTerminal(config Config) (*Terminal, error) {
 
 	sshConfig := &ssh.ClientConfig{
 		User:            config.UserName,
-		HostKeyCallback: ssh.InsecureIgnoreHostKey(),
+		HostKeyCallback: util.HostKeyCallback(),
 		BannerCallback:  ssh.BannerDisplayStderr(),
 		Timeout:         15 * time.Second,
 	}
@@ -206,7 +207,7 @@ func NewTerminal(config Config) (*Terminal, error) {
 
 	// 建立SSH连接
 	addr := net.JoinHostPort(config.IpAddress, config.Port)
-	client, err := ssh.Dial("tcp", addr, sshConfig)
+	client, err := util.DialSSHAddr(addr, sshConfig)
 	if err != nil {
 		return nil, fmt.Errorf("failed to dial SSH server: %v", err)
 	}
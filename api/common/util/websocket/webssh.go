@@ -8,12 +8,28 @@ import (
 	"log"
 	"net"
 	"strconv"
+	"sync"
 	"time"
 
+	"dodevops-api/common/util"
+
 	"github.com/gorilla/websocket"
 	"golang.org/x/crypto/ssh"
 )
 
+// 终端默认窗口大小
+const (
+	DefaultCols = 160
+	DefaultRows = 40
+)
+
+// SessionRecorder 终端会话录制，接收用户输入、终端输出和窗口大小变化
+type SessionRecorder interface {
+	Input(p []byte)
+	Output(p []byte)
+	Resize(cols, rows int)
+}
+
 // WebSSH 简化的WebSSH实现
 type WebSSH struct {
 	conn      *websocket.Conn
@@ -21,6 +37,40 @@ type WebSSH struct {
 	session   *ssh.Session
 	stdinPipe io.WriteCloser
 	cancel    context.CancelFunc
+	recorder  SessionRecorder
+	guard     *CommandGuard
+	writeMu   sync.Mutex
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// SetRecorder 设置会话录制，需在 Connect 之前调用
+func (w *WebSSH) SetRecorder(recorder SessionRecorder) {
+	w.recorder = recorder
+}
+
+// SetCommandChecker 设置命令检查，用户输入的命令在发送到终端前检查，需在 Connect 之前调用
+func (w *WebSSH) SetCommandChecker(checker CommandChecker) {
+	w.guard = NewCommandGuard(checker)
+}
+
+// 写入终端输出，输入和输出两个方向都会写入 WebSocket，需要串行
+func (w *WebSSH) write(data []byte) error {
+	w.writeMu.Lock()
+	defer w.writeMu.Unlock()
+	if w.recorder != nil {
+		w.recorder.Output(data)
+	}
+	return w.conn.WriteMessage(websocket.TextMessage, data)
+}
+
+// Done 终端输入或输出任一方向结束时关闭
+func (w *WebSSH) Done() <-chan struct{} {
+	return w.done
+}
+
+func (w *WebSSH) finish() {
+	w.closeOnce.Do(func() { close(w.done) })
 }
 
 // GetStdinPipe 获取输入管道
@@ -54,7 +104,7 @@ func NewWebSSH(host, port, user, password string) (*WebSSH, error) {
 				return []string{password}, nil
 			}),
 		},
-		HostKeyCallback: ssh.InsecureIgnoreHostKey(),
+		HostKeyCallback: util.HostKeyCallback(),
 		Timeout:         15 * time.Second,
 		Config: ssh.Config{
 			Ciphers: []string{
@@ -79,7 +129,7 @@ func NewWebSSHWithAuth(host, port, user string, authMethod ssh.AuthMethod) (*Web
 	config := &ssh.ClientConfig{
 		User: user,
 		Auth: []ssh.AuthMethod{authMethod},
-		HostKeyCallback: ssh.InsecureIgnoreHostKey(),
+		HostKeyCallback: util.HostKeyCallback(),
 		Timeout:         15 * time.Second,
 		Config: ssh.Config{
 			Ciphers: []string{
@@ -123,7 +173,7 @@ func newWebSSHWithConfig(host, port string, config *ssh.ClientConfig) (*WebSSH,
 	log.Printf("尝试连接SSH服务器: %s", addr)
 	log.Printf("使用的认证方法: %v", config.Auth)
 	
-	client, err := ssh.Dial("tcp", addr, config)
+	client, err := util.DialSSHAddr(addr, config)
 	if err != nil {
 		log.Printf("SSH连接失败详情: %v", err)
 		return nil, fmt.Errorf("failed to dial SSH server: %v", err)
@@ -133,6 +183,7 @@ func newWebSSHWithConfig(host, port string, config *ssh.ClientConfig) (*WebSSH,
 
 	return &WebSSH{
 		client: client,
+		done:   make(chan struct{}),
 	}, nil
 }
 
@@ -171,8 +222,8 @@ func (w *WebSSH) Connect(wsConn *websocket.Conn) error {
 		ssh.ONLRET:        0,      // 允许输出时执行回车
 		ssh.ONOCR:         0,      // 允许在列0时输出回车
 	}
-	width := 160 // 默认宽度
-	height := 40
+	width := DefaultCols // 默认宽度
+	height := DefaultRows
 	if err := session.RequestPty("xterm-256color", height, width, modes); err != nil {
 		return fmt.Errorf("request pty failed: %v", err)
 	}
@@ -193,6 +244,7 @@ func (w *WebSSH) Connect(wsConn *websocket.Conn) error {
 }
 
 func (w *WebSSH) handleInput(ctx context.Context) {
+	defer w.finish()
 	for {
 		select {
 		case <-ctx.Done():
@@ -212,12 +264,33 @@ func (w *WebSSH) handleInput(ctx context.Context) {
 					rows, _ := strconv.Atoi(fmt.Sprintf("%v", msg["rows"]))
 					if cols > 0 && rows > 0 {
 						w.session.WindowChange(rows, cols)
+						if w.recorder != nil {
+							w.recorder.Resize(cols, rows)
+						}
 					}
 					continue
 				}
 			}
 
+			// 检查命令，拦截的命令不发送到SSH
+			if w.guard != nil {
+				var notice string
+				data, notice = w.guard.Filter(data)
+				if notice != "" {
+					if err := w.write([]byte(notice)); err != nil {
+						log.Printf("WebSocket write error: %v", err)
+						return
+					}
+				}
+				if len(data) == 0 {
+					continue
+				}
+			}
+
 			// 发送输入到SSH
+			if w.recorder != nil {
+				w.recorder.Input(data)
+			}
 			if _, err := w.stdinPipe.Write(data); err != nil {
 				log.Printf("SSH write error: %v", err)
 				return
@@ -227,6 +300,7 @@ func (w *WebSSH) handleInput(ctx context.Context) {
 }
 
 func (w *WebSSH) handleOutput(ctx context.Context, stdout io.Reader) {
+	defer w.finish()
 	buf := make([]byte, 1024)
 	for {
 		select {
@@ -241,7 +315,7 @@ func (w *WebSSH) handleOutput(ctx context.Context, stdout io.Reader) {
 				return
 			}
 			if n > 0 {
-				if err := w.conn.WriteMessage(websocket.TextMessage, buf[:n]); err != nil {
+				if err := w.write(buf[:n]); err != nil {
 					log.Printf("WebSocket write error: %v", err)
 					return
 				}
@@ -250,6 +324,17 @@ func (w *WebSSH) handleOutput(ctx context.Context, stdout io.Reader) {
 	}
 }
 
+// Terminate 向用户显示提示后断开终端
+func (w *WebSSH) Terminate(message string) {
+	if w.conn != nil {
+		if err := w.write([]byte(message)); err != nil {
+			log.Printf("WebSocket write error: %v", err)
+		}
+	}
+	w.Close()
+	w.finish()
+}
+
 // Close 关闭连接
 func (w *WebSSH) Close() error {
 	if w.cancel != nil {
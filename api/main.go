@@ -11,6 +11,8 @@ import (
 	"dodevops-api/common/config"
 	_ "dodevops-api/docs"
 	"dodevops-api/api/cmdb/controller"
+	cmdbservice "dodevops-api/api/cmdb/service"
+	systemservice "dodevops-api/api/system/service"
 	"dodevops-api/api/task/service"
 	"dodevops-api/pkg/db"
 	"dodevops-api/pkg/log"
@@ -115,9 +117,32 @@ func initServices() error {
 	}
 	redis.SetupRedisDb()
 
+	// 加载敏感字段加密主密钥和凭证存储后端
+	if err := systemservice.SetupSysEncryption(); err != nil {
+		return err
+	}
+
+	// 初始化终端会话录像存储
+	if err := systemservice.SetupSysTerminalRecording(); err != nil {
+		return err
+	}
+
 	// 初始化SQL记录控制器
 	controller.InitCmdbSQLRecordController(common.GetDB())
 
+	// 按保留策略定时归档操作日志
+	systemservice.StartSysOperationLogArchiveJob()
+
+	// 注册SSH主机密钥校验
+	cmdbservice.InitCmdbHostKeyVerifier(config.Config.HostKey)
+	cmdbservice.InitCmdbHostProxyResolver()
+
+	// 启动主机凭证定时轮换
+	cmdbservice.StartCmdbCredentialRotationScheduler()
+
+	// 结束服务重启前未执行完的批量命令
+	cmdbservice.InitCmdbBatchCommand()
+
 	return nil
 }
 
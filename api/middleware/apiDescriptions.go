@@ -40,12 +40,22 @@ func getDescriptionMap() map[string]string {
 		"/api/v1/admin/resetPwd":       "重置管理员密码",
 		"/api/v1/admin/updateUserInfo": "修改个人信息",
 		"/api/v1/admin/updatePwd":      "修改密码",
+		"/api/v1/admin/token/add":      "新增个人访问令牌",
+		"/api/v1/admin/token/delete":   "吊销个人访问令牌",
+		"/api/v1/admin/mfa/enroll":     "绑定二次验证认证器",
+		"/api/v1/admin/mfa/activate":   "启用二次验证",
+		"/api/v1/admin/mfa/disable":    "停用二次验证",
+		"/api/v1/admin/mfa/recoveryCodes": "重新生成二次验证恢复码",
+		"/api/v1/admin/session/delete":      "下线登录会话",
+		"/api/v1/admin/session/forceLogout": "强制下线用户",
+		"/api/v1/admin/unlock":              "解除用户登录锁定",
 
 		"/api/v1/role/add":          "新增角色",
 		"/api/v1/role/update":       "修改角色",
 		"/api/v1/role/delete":       "删除角色",
 		"/api/v1/role/batchDelete":  "批量删除角色",
 		"/api/v1/role/changeStatus": "修改角色状态",
+		"/api/v1/role/dataScope":    "设置角色数据范围",
 
 		"/api/v1/menu/add":    "新增菜单",
 		"/api/v1/menu/update": "修改菜单",
@@ -65,10 +75,27 @@ func getDescriptionMap() map[string]string {
 		"/api/v1/sysOperationLog/batchDelete": "批量删除操作日志",
 		"/api/v1/sysOperationLog/clean":       "清空操作日志",
 
+		"/api/v1/approval/policy/add":     "新增审批策略",
+		"/api/v1/approval/policy/update":  "修改审批策略",
+		"/api/v1/approval/policy/delete":  "删除审批策略",
+		"/api/v1/approval/ticket/approve": "同意审批单",
+		"/api/v1/approval/ticket/reject":  "拒绝审批单",
+		"/api/v1/approval/ticket/comment": "评论审批单",
+		"/api/v1/approval/ticket/cancel":  "撤销审批单",
+
+		"/api/v1/terminal/session/terminate": "终止终端会话",
+		"/api/v1/terminal/rule/add":          "新增终端命令规则",
+		"/api/v1/terminal/rule/update":       "修改终端命令规则",
+		"/api/v1/terminal/rule/delete":       "删除终端命令规则",
+		"/api/v1/terminal/rule/check":        "检查终端命令",
+
+		"/api/v1/encryption/rotate": "重新加密敏感字段",
+
 		// ========== 配置中心 ==========
-		"/api/v1/config/ecsauthadd":    "新增ECS认证",
-		"/api/v1/config/ecsauthupdate": "修改ECS认证",
-		"/api/v1/config/ecsauthdelete": "删除ECS认证",
+		"/api/v1/config/ecsauthadd":      "新增ECS认证",
+		"/api/v1/config/ecsauthupdate":   "修改ECS认证",
+		"/api/v1/config/ecsauthdelete":   "删除ECS认证",
+		"/api/v1/config/ecsauthgenerate": "生成ECS密钥认证",
 
 		"/api/v1/config/keymanage/sync": "同步云主机",
 
@@ -84,11 +111,41 @@ func getDescriptionMap() map[string]string {
 		"/api/v1/cmdb/hostupdate":  "修改主机",
 		"/api/v1/cmdb/hostdelete":  "删除主机",
 		"/api/v1/cmdb/hostimport":  "导入主机",
+		"/api/v1/cmdb/hosttransfer/import": "导入主机(按实例ID/SSH地址更新)",
 		"/api/v1/cmdb/hostsync":    "同步主机信息",
 
 		"/api/v1/cmdb/hostcloudcreatealiyun":  "创建阿里云主机",
 		"/api/v1/cmdb/hostcloudcreatetencent": "创建腾讯云主机",
 
+		"/api/v1/cmdb/credential/rotation/add":    "新增凭证轮换策略",
+		"/api/v1/cmdb/credential/rotation/update": "修改凭证轮换策略",
+		"/api/v1/cmdb/credential/rotation/delete": "删除凭证轮换策略",
+		"/api/v1/cmdb/credential/rotation/run":    "执行凭证轮换",
+		"/api/v1/cmdb/credential/distribute":      "分发主机公钥",
+
+		"/api/v1/cmdb/hostlabel/update": "修改主机标签",
+		"/api/v1/cmdb/batch/execute":    "批量执行命令",
+		"/api/v1/cmdb/batch/rerun":      "重新执行批量命令",
+
+		"/api/v1/cmdb/dynamicgroup/add":    "新增动态分组",
+		"/api/v1/cmdb/dynamicgroup/update": "修改动态分组",
+		"/api/v1/cmdb/dynamicgroup/delete": "删除动态分组",
+
+		"/api/v1/cmdb/citype/add":          "新增CI类型",
+		"/api/v1/cmdb/citype/update":       "修改CI类型",
+		"/api/v1/cmdb/citype/delete":       "删除CI类型",
+		"/api/v1/cmdb/citype/field/add":    "新增CI类型自定义属性",
+		"/api/v1/cmdb/citype/field/update": "修改CI类型自定义属性",
+		"/api/v1/cmdb/citype/field/delete": "删除CI类型自定义属性",
+
+		"/api/v1/cmdb/relation/add":      "新增配置项关系",
+		"/api/v1/cmdb/relation/delete":   "删除配置项关系",
+		"/api/v1/cmdb/relation/discover": "自动发现配置项关系",
+
+		"/api/v1/cmdb/hostkey/accept": "确认主机密钥",
+		"/api/v1/cmdb/hostkey/pin":    "固定主机密钥",
+		"/api/v1/cmdb/hostkey/delete": "删除主机密钥",
+
 		"/api/v1/cmdb/sqlLog/delete": "删除SQL日志",
 		"/api/v1/cmdb/sqlLog/clean":  "清空SQL日志",
 
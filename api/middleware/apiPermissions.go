@@ -0,0 +1,288 @@
+// API权限映射
+// author xiaoRui
+
+package middleware
+
+import (
+	"strings"
+)
+
+// 权限值为空表示登录后即可访问（例如个人信息相关接口）
+const publicPermission = ""
+
+// GetAPIPermission 根据 HTTP 方法和 gin 路由模板（c.FullPath()）获取访问该接口所需的 sys_menu.value
+// 先精确匹配"方法:路由模板"，再按路由前缀匹配模块级权限；ok 为 false 表示该路由未纳入权限管理
+func GetAPIPermission(method, fullPath string) (value string, ok bool) {
+	key := strings.ToUpper(method) + ":" + fullPath
+	if value, ok = getPermissionMap()[key]; ok {
+		return value, true
+	}
+	for _, rule := range getPrefixPermissionRules() {
+		if rule.method != "" && rule.method != strings.ToUpper(method) {
+			continue
+		}
+		if strings.HasPrefix(fullPath, rule.prefix) {
+			return rule.value, true
+		}
+	}
+	return "", false
+}
+
+// getPermissionMap 返回"方法:路由模板"到权限值的映射
+// 权限值与 sys_menu 表中按钮/菜单的 value 字段保持一致
+func getPermissionMap() map[string]string {
+	return map[string]string{
+		// ========== 个人中心（登录即可） ==========
+		"PUT:/api/v1/admin/updatePersonal":         publicPermission,
+		"PUT:/api/v1/admin/updatePersonalPassword": publicPermission,
+		"POST:/api/v1/admin/token/add":             publicPermission,
+		"GET:/api/v1/admin/token/list":             publicPermission,
+		"DELETE:/api/v1/admin/token/delete":        publicPermission,
+		"GET:/api/v1/admin/mfa/status":             publicPermission,
+		"POST:/api/v1/admin/mfa/enroll":            publicPermission,
+		"POST:/api/v1/admin/mfa/activate":          publicPermission,
+		"POST:/api/v1/admin/mfa/disable":           publicPermission,
+		"POST:/api/v1/admin/mfa/recoveryCodes":     publicPermission,
+		"POST:/api/v1/admin/logout":                publicPermission,
+		"GET:/api/v1/admin/session/list":           publicPermission,
+		"DELETE:/api/v1/admin/session/delete":      publicPermission,
+		"POST:/api/v1/upload":                      publicPermission,
+		"GET:/api/v1/role/vo/list":                 publicPermission,
+		"GET:/api/v1/post/vo/list":                 publicPermission,
+		"GET:/api/v1/dept/vo/list":                 publicPermission,
+		"GET:/api/v1/approval/ticket/list":         publicPermission,
+		"GET:/api/v1/approval/ticket/info":         publicPermission,
+		"POST:/api/v1/approval/ticket/approve":     publicPermission,
+		"POST:/api/v1/approval/ticket/reject":      publicPermission,
+		"POST:/api/v1/approval/ticket/comment":     publicPermission,
+		"POST:/api/v1/approval/ticket/cancel":      publicPermission,
+		"GET:/api/v1/menu/vo/list":                 publicPermission,
+
+		// ========== 系统管理 ==========
+		"POST:/api/v1/admin/add":                 "base:admin:add",
+		"PUT:/api/v1/admin/update":               "base:admin:edit",
+		"PUT:/api/v1/admin/updateStatus":         "base:admin:edit",
+		"DELETE:/api/v1/admin/delete":            "base:admin:delete",
+		"PUT:/api/v1/admin/updatePassword":       "base:admin:reset",
+		"PUT:/api/v1/admin/unlock":               "base:admin:reset",
+		"GET:/api/v1/admin/list":                 "base:admin:list",
+		"GET:/api/v1/admin/info":                 "base:admin:list",
+		"GET:/api/v1/admin/session/listByAdmin":  "base:admin:list",
+		"POST:/api/v1/admin/session/forceLogout": "base:admin:edit",
+		"POST:/api/v1/role/add":                  "base:role:add",
+		"PUT:/api/v1/role/update":                "base:role:edit",
+		"PUT:/api/v1/role/updateStatus":          "base:role:edit",
+		"DELETE:/api/v1/role/delete":             "base:role:delete",
+		"PUT:/api/v1/role/assignPermissions":     "base:role:assign",
+		"GET:/api/v1/role/dataScope":             "base:role:assign",
+		"PUT:/api/v1/role/dataScope":             "base:role:assign",
+		"POST:/api/v1/menu/add":                  "base:menu:add",
+		"PUT:/api/v1/menu/update":                "base:menu:edit",
+		"DELETE:/api/v1/menu/delete":             "base:menu:delete",
+		"POST:/api/v1/post/add":                  "base:post:add",
+		"PUT:/api/v1/post/update":                "base:post:edit",
+		"PUT:/api/v1/post/updateStatus":          "base:post:edit",
+		"DELETE:/api/v1/post/delete":             "base:post:delete",
+		"DELETE:/api/v1/post/batch/delete":       "base:post:delete",
+		"POST:/api/v1/dept/add":                  "base:dept:add",
+		"PUT:/api/v1/dept/update":                "base:dept:edit",
+		"DELETE:/api/v1/dept/delete":             "base:dept:delete",
+
+		// ========== 操作审计 ==========
+		"DELETE:/api/v1/sysLoginInfo/delete":          "monitor:loginLog:delete",
+		"DELETE:/api/v1/sysLoginInfo/batch/delete":    "monitor:loginLog:delete",
+		"DELETE:/api/v1/sysLoginInfo/clean":           "monitor:loginLog:clean",
+		"DELETE:/api/v1/sysOperationLog/delete":       "monitor:operator:delete",
+		"DELETE:/api/v1/sysOperationLog/batch/delete": "monitor:operator:delete",
+		"DELETE:/api/v1/sysOperationLog/clean":        "monitor:operator:clean",
+		"DELETE:/api/v1/cmdb/sqlLog/delete":           "monitor:dblog:list",
+		"DELETE:/api/v1/cmdb/sqlLog/clean":            "monitor:dblog:list",
+		"GET:/api/v1/cmdb/sqlLog/list":                "monitor:dblog:list",
+		"GET:/api/v1/terminal/session/observe/:id":    "monitor:recording:observe",
+		"POST:/api/v1/terminal/session/terminate":     "monitor:recording:terminate",
+
+		// ========== CMDB ==========
+		"POST:/api/v1/cmdb/groupadd":               "cmdb:group:add",
+		"PUT:/api/v1/cmdb/groupupdate":             "cmdb:group:update",
+		"DELETE:/api/v1/cmdb/groupdelete":          "cmdb:group:delete",
+		"POST:/api/v1/cmdb/hostcreate":             "cmdb:ecs:add",
+		"POST:/api/v1/cmdb/hostimport":             "cmdb:ecs:add",
+		"POST:/api/v1/cmdb/hostcloudcreatealiyun":  "cmdb:ecs:add",
+		"POST:/api/v1/cmdb/hostcloudcreatetencent": "cmdb:ecs:add",
+		"POST:/api/v1/cmdb/hostcloudcreatebaidu":   "cmdb:ecs:add",
+		"PUT:/api/v1/cmdb/hostupdate":              "cmdb:ecs:edit",
+		"POST:/api/v1/cmdb/hosttransfer/import":    "cmdb:ecs:edit", // 新增主机的行在服务层另外校验 cmdb:ecs:add
+		"DELETE:/api/v1/cmdb/hostdelete":           "cmdb:ecs:delete",
+		"POST:/api/v1/cmdb/hostsync":               "cmdb:ecs:rsync",
+		"GET:/api/v1/cmdb/hostssh/connect/:id":     "cmdb:ecs:connecthost",
+		"GET:/api/v1/cmdb/hostssh/command/:id":     "cmdb:ecs:shell",
+		"POST:/api/v1/cmdb/hostssh/upload/:id":     "cmdb:ecs:upload",
+		"PUT:/api/v1/cmdb/hostlabel/update":        "cmdb:ecs:edit",
+		"GET:/api/v1/cmdb/citype/list":             "cmdb:ecs:list",
+		"GET:/api/v1/cmdb/relation/graph":          "cmdb:ecs:list",
+		"GET:/api/v1/cmdb/relation/impact":         "cmdb:ecs:list",
+		"GET:/api/v1/cmdb/history/list":            "cmdb:ecs:list",
+		"GET:/api/v1/cmdb/history/asof":            "cmdb:ecs:list",
+		"GET:/api/v1/cmdb/history/diff":            "cmdb:ecs:list",
+		"GET:/api/v1/cmdb/dynamicgroup/list":       "cmdb:ecs:list",
+		"GET:/api/v1/cmdb/dynamicgroup/hosts":      "cmdb:ecs:list",
+		"POST:/api/v1/cmdb/batch/execute":          "cmdb:ecs:shell",
+		"POST:/api/v1/cmdb/batch/rerun":            "cmdb:ecs:shell",
+		"POST:/api/v1/cmdb/sql/select":             "cmdb:db:dbms",
+		"POST:/api/v1/cmdb/sql":                    "cmdb:db:dbms",
+		"PUT:/api/v1/cmdb/sql":                     "cmdb:db:dbms",
+		"DELETE:/api/v1/cmdb/sql":                  "cmdb:db:dbms",
+		"POST:/api/v1/cmdb/sql/execute":            "cmdb:db:dbms",
+		"POST:/api/v1/cmdb/sql/databaselist":       "cmdb:db:dbms",
+		"POST:/api/v1/cmdb/database":               "cmdb:db:add",
+		"PUT:/api/v1/cmdb/database":                "cmdb:db:edit",
+		"DELETE:/api/v1/cmdb/database":             "cmdb:db:delete",
+
+		// ========== 配置中心 ==========
+		"POST:/api/v1/config/ecsauthadd":                  "config:ecs:create",
+		"PUT:/api/v1/config/ecsauthupdate":                "config:ecs:edit",
+		"DELETE:/api/v1/config/ecsauthdelete":             "config:ecs:delete",
+		"POST:/api/v1/config/ecsauthgenerate":             "config:ecs:create",
+		"POST:/api/v1/config/accountauth":                 "config:common:add",
+		"PUT:/api/v1/config/accountauth":                  "config:common:edit",
+		"DELETE:/api/v1/config/accountauth":               "config:common:delete",
+		"POST:/api/v1/config/accountauth/decrypt":         "config:common:decrypt",
+		"POST:/api/v1/config/keymanage":                   "config:keymanage:create",
+		"PUT:/api/v1/config/keymanage":                    "config:keymanage:create",
+		"DELETE:/api/v1/config/keymanage":                 "config:keymanage:delete",
+		"POST:/api/v1/config/keymanage/decrypt":           "config:keymanage:decrypt",
+		"POST:/api/v1/config/keymanage/sync":              "config:keymanage:rsync",
+		"POST:/api/v1/config/sync-schedule":               "config:keymanage:rsync",
+		"PUT:/api/v1/config/sync-schedule":                "config:keymanage:rsync",
+		"DELETE:/api/v1/config/sync-schedule":             "config:keymanage:rsync",
+		"POST:/api/v1/config/sync-schedule/trigger":       "config:keymanage:rsync",
+		"POST:/api/v1/config/sync-schedule/toggle-status": "config:keymanage:rsync",
+
+		// ========== 任务中心 ==========
+		"POST:/api/v1/template/add":           "task:template:add",
+		"PUT:/api/v1/template/update":         "task:template:edit",
+		"DELETE:/api/v1/template/delete":      "task:template:delete",
+		"POST:/api/v1/task/add":               "task:job:add",
+		"PUT:/api/v1/task/update":             "task:job:add",
+		"DELETE:/api/v1/task/delete":          "task:job:delete",
+		"POST:/api/v1/taskjob/start":          "task:job:jobstart",
+		"POST:/api/v1/taskjob/stop":           "task:job:jobstop",
+		"POST:/api/v1/task/ansible":           "task:ansible:create",
+		"POST:/api/v1/task/k8s":               "task:ansible:create",
+		"POST:/api/v1/task/ansible/:id/start": "task:ansible:start",
+
+		// ========== 容器管理 ==========
+		"POST:/api/v1/k8s/cluster":                                                                    "cloud:k8s:add",
+		"PUT:/api/v1/k8s/cluster/:id":                                                                 "cloud:k8s:edit",
+		"DELETE:/api/v1/k8s/cluster/:id":                                                              "cloud:k8s:delete",
+		"POST:/api/v1/k8s/cluster/:id/sync":                                                           "cloud:k8s:rsync",
+		"POST:/api/v1/k8s/cluster/:id/nodes/:nodeName/cordon":                                         "k8s:node:close",
+		"POST:/api/v1/k8s/cluster/:id/nodes/:nodeName/drain":                                          "k8s:node:expel",
+		"POST:/api/v1/k8s/cluster/:id/nodes/:nodeName/taints":                                         "k8s:node:stain",
+		"DELETE:/api/v1/k8s/cluster/:id/nodes/:nodeName/taints":                                       "k8s:node:stain",
+		"POST:/api/v1/k8s/cluster/:id/nodes/:nodeName/labels":                                         "k8s:node:label",
+		"DELETE:/api/v1/k8s/cluster/:id/nodes/:nodeName/labels":                                       "k8s:node:label",
+		"POST:/api/v1/k8s/cluster/:id/namespaces":                                                     "k8s:namespace:add",
+		"DELETE:/api/v1/k8s/cluster/:id/namespaces/:namespaceName":                                    "k8s:namespace:delete",
+		"GET:/api/v1/k8s/cluster/:id/namespaces/:namespaceName/pods/:podName/terminal":                "k8s:workload:terminal",
+		"GET:/api/v1/k8s/cluster/:id/namespaces/:namespaceName/pods/:podName/logs":                    "k8s:workload:podlog",
+		"DELETE:/api/v1/k8s/cluster/:id/namespaces/:namespaceName/pods/:podName":                      "k8s:workload:poddelete",
+		"POST:/api/v1/k8s/cluster/:id/namespaces/:namespaceName/deployments":                          "k8s:workload:add",
+		"DELETE:/api/v1/k8s/cluster/:id/namespaces/:namespaceName/deployments/:deploymentName":        "k8s:workload:delete",
+		"POST:/api/v1/k8s/cluster/:id/namespaces/:namespaceName/deployments/:deploymentName/scale":    "k8s:workload:expandable",
+		"POST:/api/v1/k8s/cluster/:id/namespaces/:namespaceName/deployments/:deploymentName/restart":  "k8s:workload:restart",
+		"POST:/api/v1/k8s/cluster/:id/namespaces/:namespaceName/deployments/:deploymentName/rollback": "k8s:workload:rollback_version",
+		"PUT:/api/v1/k8s/cluster/:id/namespaces/:namespaceName/workload-yaml":                         "k8s:workload:edityaml",
+		"PUT:/api/v1/k8s/cluster/:id/namespaces/:namespaceName/pods/:podName/yaml":                    "k8s:workload:edityaml",
+		"DELETE:/api/v1/k8s/cluster/:id/namespaces/:namespaceName/services/:serviceName":              "k8s:network:deleteservice",
+		"PUT:/api/v1/k8s/cluster/:id/namespaces/:namespaceName/services/:serviceName":                 "k8s:network:editservice",
+		"PUT:/api/v1/k8s/cluster/:id/namespaces/:namespaceName/services/:serviceName/yaml":            "k8s:network:edit_service_yaml",
+		"POST:/api/v1/k8s/cluster/:id/namespaces/:namespaceName/services":                             "k8s:network:addservice",
+		"DELETE:/api/v1/k8s/cluster/:id/namespaces/:namespaceName/ingresses/:ingressName":             "k8s:network:delete_ingress",
+		"PUT:/api/v1/k8s/cluster/:id/namespaces/:namespaceName/ingresses/:ingressName":                "k8s:network:editingress",
+		"PUT:/api/v1/k8s/cluster/:id/namespaces/:namespaceName/ingresses/:ingressName/yaml":           "k8s:network:edit_ingress_yaml",
+		"POST:/api/v1/k8s/cluster/:id/namespaces/:namespaceName/ingresses":                            "k8s:network:addingress",
+
+		// ========== 服务管理 ==========
+		"POST:/api/v1/apps":                                                     "app:application:add",
+		"PUT:/api/v1/apps/:id":                                                  "app:application:edit",
+		"DELETE:/api/v1/apps/:id":                                               "app:application:delete",
+		"POST:/api/v1/apps/:id/jenkins-envs":                                    "app:application:envadd",
+		"PUT:/api/v1/apps/:id/jenkins-envs/:env_id":                             "app:application:envedit",
+		"DELETE:/api/v1/apps/:id/jenkins-envs/:env_id":                          "app:application:envdelete",
+		"POST:/api/v1/apps/deployment/quick":                                    "app:quick-release:add",
+		"POST:/api/v1/apps/deployment/execute":                                  "app:quick-release:start",
+		"DELETE:/api/v1/apps/deployment/:id":                                    "app:quick-release:delete",
+		"POST:/api/v1/jenkins/:serverId/jobs/:jobName/start":                    "app:quick-release:jobstart",
+		"POST:/api/v1/jenkins/:serverId/jobs/:jobName/builds/:buildNumber/stop": "app:quick-release:jobstop",
+
+		// ========== 运维工具 ==========
+		"POST:/api/v1/monitor/agent/deploy":       "ops:agent:create",
+		"DELETE:/api/v1/monitor/agent/uninstall":  "ops:agent:deleteall",
+		"DELETE:/api/v1/monitor/agent/delete/:id": "ops:agent:delete",
+		"POST:/api/v1/monitor/agent/restart/:id":  "ops:agent:create",
+		"GET:/api/v1/monitor/agent/status/:id":    "ops:agent:get",
+	}
+}
+
+// prefixPermissionRule 模块级兜底权限规则
+type prefixPermissionRule struct {
+	method string // 为空表示匹配所有方法
+	prefix string // 路由模板前缀
+	value  string // 权限值
+}
+
+// getPrefixPermissionRules 返回模块级兜底权限，按顺序匹配，越具体的前缀越靠前
+func getPrefixPermissionRules() []prefixPermissionRule {
+	return []prefixPermissionRule{
+		{"", "/api/v1/admin/", "base:admin:list"},
+		{"", "/api/v1/role/", "base:role:list"},
+		{"", "/api/v1/menu/", "base:menu:list"},
+		{"", "/api/v1/post/", "base:post:list"},
+		{"", "/api/v1/dept/", "system:dept"},
+		{"", "/api/v1/sysLoginInfo/", "monitor:loginLog:list"},
+		{"", "/api/v1/sysOperationLog/", "monitor:operator:list"},
+		{"", "/api/v1/terminal/rule/", "monitor:command:rule"},
+		{"", "/api/v1/terminal/", "monitor:recording"},
+		{"", "/api/v1/approval/", "base:approval:policy"},
+		{"", "/api/v1/encryption/", "base:encryption:rotate"},
+		{"", "/api/v1/cmdb/group", "cmdb:group"},
+		{"", "/api/v1/cmdb/hostkey", "cmdb:hostkey:manage"},
+		{"", "/api/v1/cmdb/citype/", "cmdb:citype:manage"},
+		{"", "/api/v1/cmdb/relation/", "cmdb:relation:manage"},
+		{"", "/api/v1/cmdb/dynamicgroup/", "cmdb:dynamicgroup:manage"},
+		{"", "/api/v1/cmdb/host", "cmdb:ecs:list"},
+		{"", "/api/v1/cmdb/batch/", "cmdb:ecs:shell"},
+		{"", "/api/v1/cmdb/credential", "cmdb:credential:rotate"},
+		{"", "/api/v1/cmdb/sql", "cmdb:db"},
+		{"", "/api/v1/cmdb/database", "cmdb:db"},
+		{"", "/api/v1/config/ecsauth", "config:ecs:key"},
+		{"", "/api/v1/config/accountauth", "config:accountauth:key"},
+		{"", "/api/v1/config/keymanage", "config:keymanage:key"},
+		{"", "/api/v1/config/sync-schedule", "config:keymanage:key"},
+		{"", "/api/v1/config/ansible", "task:ansible"},
+		{"", "/api/v1/template/", "task:template"},
+		{"", "/api/v1/task/ansible", "task:ansible"},
+		{"", "/api/v1/task", "task:job"},
+		{"", "/api/v1/k8s/cluster/:id/nodes", "cloud:k8s:node"},
+		{"", "/api/v1/k8s/cluster/:id/namespaces/:namespaceName/services", "k8s:network"},
+		{"", "/api/v1/k8s/cluster/:id/namespaces/:namespaceName/ingresses", "k8s:network"},
+		{"", "/api/v1/k8s/cluster/:id/namespaces/:namespaceName/configmaps", "k8s:config"},
+		{"", "/api/v1/k8s/cluster/:id/namespaces/:namespaceName/secrets", "k8s:config"},
+		{"", "/api/v1/k8s/cluster/:id/namespaces/:namespaceName/pvcs", "k8s:storage"},
+		{"", "/api/v1/k8s/cluster/:id/pvs", "k8s:storage"},
+		{"", "/api/v1/k8s/cluster/:id/storageclasses", "k8s:storage"},
+		{"", "/api/v1/k8s/cluster/:id/namespaces/:namespaceName/", "cloud:k8s:workload"},
+		{"", "/api/v1/k8s/cluster/:id/namespaces", "k8s:namespace"},
+		{"", "/api/v1/k8s/", "cloud:k8s:list"},
+		{"", "/api/v1/apps/deployment", "app:quick-release"},
+		{"", "/api/v1/apps", "app:application"},
+		{"", "/api/v1/jenkins/", "app:quick-release"},
+		{"", "/api/v1/monitor/agent/", "ops:agent"},
+		{"", "/api/v1/monitor/host", "cmdb:ecs:monitor"},
+		{"", "/api/v1/monitor/alert/", "monitor:alert"},
+		{"", "/api/v1/monitor/datasource", "monitor:alert"},
+		{"", "/api/v1/tool", "ops:tools"},
+		{"GET", "/api/v1/dashboard/", publicPermission},
+	}
+}
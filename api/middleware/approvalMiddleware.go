@@ -0,0 +1,35 @@
+// 审批中间件
+// author xiaoRui
+
+package middleware
+
+import (
+	"dodevops-api/api/system/service"
+	"dodevops-api/common/result"
+	"dodevops-api/pkg/approval"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ApprovalMiddleware 拦截命中审批策略的写操作，保存原始请求并生成审批单，审批通过后由审批服务以申请人身份重放执行
+// 需要挂载在 PermissionMiddleware 之后，只有具备接口权限的用户才能提交审批
+func ApprovalMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if isReadOnlyMethod(c.Request.Method) || approval.ExecutionFromRequest(c.Request) != nil {
+			c.Next()
+			return
+		}
+		ticket, err := service.SubmitApproval(c)
+		if err != nil {
+			result.Failed(c, int(result.ApiCode.FAILED), "提交审批失败: "+err.Error())
+			c.Abort()
+			return
+		}
+		if ticket != nil {
+			result.FailedWithData(c, int(result.ApiCode.APPROVALREQUIRED), result.ApiCode.GetMessage(result.ApiCode.APPROVALREQUIRED), ticket)
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
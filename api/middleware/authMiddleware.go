@@ -4,10 +4,13 @@
 package middleware
 
 import (
+	"dodevops-api/api/system/model"
+	"dodevops-api/api/system/service"
 	"dodevops-api/common/constant"
 	"dodevops-api/common/result"
+	"dodevops-api/pkg/approval"
 	"dodevops-api/pkg/jwt"
-	"fmt"
+	"errors"
 	"strings"
 
 	"github.com/gin-gonic/gin"
@@ -15,6 +18,13 @@ import (
 
 func AuthMiddleware() func(c *gin.Context) {
 	return func(c *gin.Context) {
+		// 审批通过后在进程内重放的请求，以申请人身份执行
+		if execution := approval.ExecutionFromRequest(c.Request); execution != nil {
+			c.Set(constant.ContextKeyUserObj, execution.Admin)
+			c.Next()
+			return
+		}
+
 		// 检查是否为websocket升级请求
 		if c.GetHeader("Upgrade") == "websocket" {
 			// 从URL参数获取token
@@ -44,9 +54,7 @@ func AuthMiddleware() func(c *gin.Context) {
 			}
 
 			if token != "" {
-				mc, err := jwt.ValidateToken(token)
-				if err == nil {
-					c.Set(constant.ContextKeyUserObj, mc)
+				if err := authenticateToken(c, token); err == nil {
 					c.Next()
 					return
 				}
@@ -64,17 +72,9 @@ func AuthMiddleware() func(c *gin.Context) {
 		// 如果没有Authorization头，检查是否为SSE连接并从query参数获取token
 		if authHeader == "" {
 			token := c.Query("token")
-			if token != "" {
-				// 验证token
-				mc, err := jwt.ValidateToken(token)
-				if err == nil {
-					c.Set(constant.ContextKeyUserObj, mc)
-					c.Next()
-					return
-				} else {
-					// 添加调试信息
-					fmt.Printf("[DEBUG] Token验证失败: %v, token长度: %d, Secret: %s\n", err, len(token), string(jwt.Secret))
-				}
+			if token != "" && authenticateToken(c, token) == nil {
+				c.Next()
+				return
 			}
 
 			result.Failed(c, int(result.ApiCode.NOAUTH), result.ApiCode.GetMessage(result.ApiCode.NOAUTH))
@@ -87,13 +87,51 @@ func AuthMiddleware() func(c *gin.Context) {
 			c.Abort()
 			return
 		}
-		mc, err := jwt.ValidateToken(parts[1])
-		if err != nil {
+		if err := authenticateToken(c, parts[1]); err != nil {
+			if errors.Is(err, errPasswordChangeRequired) {
+				result.Failed(c, int(result.ApiCode.PASSWORDCHANGEREQUIRED), result.ApiCode.GetMessage(result.ApiCode.PASSWORDCHANGEREQUIRED))
+				c.Abort()
+				return
+			}
 			result.Failed(c, int(result.ApiCode.INVALIDTOKEN), result.ApiCode.GetMessage(result.ApiCode.INVALIDTOKEN))
 			c.Abort()
 			return
 		}
-		c.Set(constant.ContextKeyUserObj, mc)
 		c.Next()
 	}
 }
+
+var errPasswordChangeRequired = errors.New("密码已过期或需要修改")
+
+// 密码已过期或被要求修改时仍允许访问的接口
+var passwordChangeRoutes = map[string]bool{
+	"PUT:/api/v1/admin/updatePersonalPassword": true,
+	"POST:/api/v1/admin/logout":                true,
+}
+
+// 校验 JWT 或个人访问令牌，并将用户信息写入上下文
+// 个人访问令牌的授权范围同时写入上下文，由 PermissionMiddleware 进一步限制
+func authenticateToken(c *gin.Context, token string) error {
+	if strings.HasPrefix(token, model.ApiTokenPrefix) {
+		mc, scopes, err := service.ValidateApiToken(token, c.ClientIP())
+		if err != nil {
+			return err
+		}
+		c.Set(constant.ContextKeyUserObj, mc)
+		c.Set(constant.ContextKeyApiTokenScopes, scopes)
+		return nil
+	}
+	mc, err := jwt.ValidateToken(token)
+	if err != nil {
+		return err
+	}
+	// 会话被吊销（退出登录、强制下线、用户停用）后令牌立即失效
+	if err := service.ValidateSession(mc); err != nil {
+		return err
+	}
+	if mc.PwdChange && !passwordChangeRoutes[c.Request.Method+":"+c.FullPath()] {
+		return errPasswordChangeRequired
+	}
+	c.Set(constant.ContextKeyUserObj, mc)
+	return nil
+}
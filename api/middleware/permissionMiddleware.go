@@ -0,0 +1,60 @@
+// 接口权限中间件
+// author xiaoRui
+
+package middleware
+
+import (
+	"dodevops-api/api/system/service"
+	"dodevops-api/common/result"
+	"dodevops-api/pkg/jwt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// PermissionMiddleware 根据路由所需的菜单/按钮权限值校验当前用户的角色权限
+// 需要挂载在 AuthMiddleware 之后，用户权限由 service.GetAdminPermission 从redis缓存中读取
+// 未纳入权限管理的接口一律拒绝访问，新增接口需要在 apiPermissions.go 中登记权限值
+// 通过个人访问令牌认证的请求还需在令牌授权范围内，登录即可访问的接口仅允许只读访问
+func PermissionMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		value, ok := GetAPIPermission(c.Request.Method, c.FullPath())
+		if !ok {
+			abortNoPermission(c)
+			return
+		}
+		scopes, isApiToken := service.GetApiTokenScopes(c)
+		if value == publicPermission {
+			if isApiToken && !isReadOnlyMethod(c.Request.Method) {
+				abortNoPermission(c)
+				return
+			}
+			c.Next()
+			return
+		}
+		adminId, err := jwt.GetAdminId(c)
+		if err != nil {
+			result.Failed(c, int(result.ApiCode.NOAUTH), result.ApiCode.GetMessage(result.ApiCode.NOAUTH))
+			c.Abort()
+			return
+		}
+		if !service.GetAdminPermission(adminId).Has(value) || (isApiToken && !scopes.Has(value)) {
+			abortNoPermission(c)
+			return
+		}
+		c.Next()
+	}
+}
+
+// abortNoPermission 以 HTTP 403 拒绝请求
+func abortNoPermission(c *gin.Context) {
+	c.AbortWithStatusJSON(http.StatusForbidden, result.Result{
+		Code:    int(result.ApiCode.NOPERMISSION),
+		Message: result.ApiCode.GetMessage(result.ApiCode.NOPERMISSION),
+		Data:    gin.H{},
+	})
+}
+
+func isReadOnlyMethod(method string) bool {
+	return method == http.MethodGet || method == http.MethodHead || method == http.MethodOptions
+}
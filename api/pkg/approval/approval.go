@@ -0,0 +1,85 @@
+// 审批流程
+// author xiaoRui
+
+// Package approval 危险操作审批的公共部分
+// 审批策略可以通过条件名称进一步限定需要审批的请求（如只拦截 DDL 语句、生产环境发布），条件由各业务模块注册；
+// 审批通过后以申请人身份在进程内重放原请求，重放请求的上下文中携带 Execution，用于跳过审批拦截
+package approval
+
+import (
+	"context"
+	"dodevops-api/api/system/model"
+	"net/http"
+	"sort"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Condition 判断请求是否需要审批，body 为请求体原文
+type Condition func(c *gin.Context, body []byte) bool
+
+var (
+	conditionsMu sync.RWMutex
+	conditions   = map[string]Condition{}
+	handler      http.Handler
+)
+
+// 注册审批条件，由业务模块在 init 中调用
+func RegisterCondition(name string, condition Condition) {
+	conditionsMu.Lock()
+	defer conditionsMu.Unlock()
+	conditions[name] = condition
+}
+
+// 已注册的审批条件名称
+func ConditionNames() []string {
+	conditionsMu.RLock()
+	defer conditionsMu.RUnlock()
+	names := make([]string, 0, len(conditions))
+	for name := range conditions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// 判断请求是否满足审批条件，条件为空表示全部请求都需要审批，未注册的条件同样按需要审批处理
+func MatchCondition(name string, c *gin.Context, body []byte) bool {
+	if name == "" {
+		return true
+	}
+	conditionsMu.RLock()
+	condition, ok := conditions[name]
+	conditionsMu.RUnlock()
+	return !ok || condition(c, body)
+}
+
+// Execution 审批通过后重放请求的执行信息
+type Execution struct {
+	TicketId uint            // 审批单id
+	Admin    *model.JwtAdmin // 申请人
+}
+
+type executionKey struct{}
+
+// 将执行信息写入重放请求的上下文，只能在进程内构造，外部请求无法伪造
+func WithExecution(ctx context.Context, execution *Execution) context.Context {
+	return context.WithValue(ctx, executionKey{}, execution)
+}
+
+// 读取重放请求的执行信息，普通请求返回 nil
+func ExecutionFromRequest(r *http.Request) *Execution {
+	execution, _ := r.Context().Value(executionKey{}).(*Execution)
+	return execution
+}
+
+// 设置重放请求使用的路由，在路由初始化完成后调用
+func SetHandler(h http.Handler) {
+	handler = h
+}
+
+// 重放请求使用的路由
+func Handler() http.Handler {
+	return handler
+}
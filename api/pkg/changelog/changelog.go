@@ -0,0 +1,369 @@
+// 资产变更历史
+// author xiaoRui
+
+// Package changelog 记录 CMDB 资产的字段级变更历史
+// 注册的表在新增、修改、删除前后由 gorm 回调比较记录并写入 cmdb_change_log，
+// 变更来源从语句绑定的上下文获取：请求中的登录用户记为用户操作，后台任务通过 WithSource 标记来源（云同步、主机信息采集等），
+// 其余记为系统任务。通过原生 SQL 执行的修改不会被记录
+package changelog
+
+import (
+	"context"
+	"dodevops-api/api/cmdb/model"
+	sysmodel "dodevops-api/api/system/model"
+	"dodevops-api/common/constant"
+	"dodevops-api/common/util"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// 受跟踪的表
+type table struct {
+	recordType string          // 资产类型
+	ignore     map[string]bool // 不记录变更的列，如更新时间
+}
+
+var (
+	tables   = map[string]table{}
+	tablesMu sync.RWMutex
+)
+
+// 注册需要记录变更历史的表，ignoreColumns 中的列不记录变更
+func RegisterTable(name, recordType string, ignoreColumns ...string) {
+	tablesMu.Lock()
+	defer tablesMu.Unlock()
+	t := table{recordType: recordType, ignore: map[string]bool{}}
+	for _, column := range ignoreColumns {
+		t.ignore[column] = true
+	}
+	tables[name] = t
+}
+
+func getTable(name string) (table, bool) {
+	tablesMu.RLock()
+	defer tablesMu.RUnlock()
+	t, ok := tables[name]
+	return t, ok
+}
+
+type sourceKey struct{}
+
+type source struct {
+	source   string
+	operator string
+}
+
+// 标记后台任务的变更来源，operator 为任务说明或触发任务的用户
+func WithSource(ctx context.Context, src, operator string) context.Context {
+	return context.WithValue(ctx, sourceKey{}, source{source: src, operator: operator})
+}
+
+// 从上下文获取变更来源和操作人
+func sourceFromContext(ctx context.Context) (string, string) {
+	if ctx == nil {
+		return model.ChangeSourceSystem, ""
+	}
+	if s, ok := ctx.Value(sourceKey{}).(source); ok {
+		return s.source, s.operator
+	}
+	if admin, ok := ctx.Value(constant.ContextKeyUserObj).(*sysmodel.JwtAdmin); ok && admin != nil {
+		return model.ChangeSourceUser, admin.Username
+	}
+	return model.ChangeSourceSystem, ""
+}
+
+// 注册 gorm 回调，修改、删除前保存原记录，新增、修改、删除后写入变更记录
+func Register(db *gorm.DB) error {
+	callback := db.Callback()
+	if err := callback.Create().After("gorm:create").Register("changelog:create", afterCreate); err != nil {
+		return err
+	}
+	if err := callback.Update().Before("gorm:update").Register("changelog:before_update", beforeChange); err != nil {
+		return err
+	}
+	if err := callback.Update().After("gorm:update").Register("changelog:update", afterUpdate); err != nil {
+		return err
+	}
+	if err := callback.Delete().Before("gorm:delete").Register("changelog:before_delete", beforeChange); err != nil {
+		return err
+	}
+	return callback.Delete().After("gorm:delete").Register("changelog:delete", afterDelete)
+}
+
+const snapshotKey = "changelog:snapshot"
+
+// 一条记录的各列取值
+type row struct {
+	id     uint
+	values map[string]string
+}
+
+// 修改、删除前按语句条件查询将被影响的记录
+func beforeChange(db *gorm.DB) {
+	stmt := db.Statement
+	if db.Error != nil || stmt.Schema == nil || stmt.Schema.PrioritizedPrimaryField == nil {
+		return
+	}
+	if _, ok := getTable(stmt.Table); !ok {
+		return
+	}
+	tx := newSession(db, stmt.Context)
+	hasWhere := false
+	if where, ok := stmt.Clauses["WHERE"]; ok {
+		if w, ok := where.Expression.(clause.Where); ok && len(w.Exprs) > 0 {
+			tx = tx.Clauses(w)
+			hasWhere = true
+		}
+	}
+	// 按模型主键修改、删除时，主键条件在 gorm:update、gorm:delete 中才追加
+	if stmt.ReflectValue.Kind() == reflect.Struct {
+		pk := stmt.Schema.PrioritizedPrimaryField
+		if value, isZero := pk.ValueOf(stmt.Context, stmt.ReflectValue); !isZero {
+			tx = tx.Where(clause.Eq{Column: clause.Column{Name: pk.DBName}, Value: value})
+			hasWhere = true
+		}
+	}
+	// 没有条件的修改、删除会被 gorm 拒绝
+	if !hasWhere {
+		return
+	}
+	db.InstanceSet(snapshotKey, loadRows(tx, stmt.Schema.ModelType, nil))
+}
+
+func afterUpdate(db *gorm.DB) {
+	t, before, ok := snapshotFromStatement(db)
+	if !ok {
+		return
+	}
+	after := make(map[uint]row)
+	for _, r := range loadRows(newSession(db, context.Background()), db.Statement.Schema.ModelType, rowIds(before)) {
+		after[r.id] = r
+	}
+	src, operator := sourceFromContext(db.Statement.Context)
+	now := util.HTime{Time: time.Now()}
+	var logs []model.CmdbChangeLog
+	for _, old := range before {
+		cur, ok := after[old.id]
+		if !ok {
+			continue
+		}
+		for _, column := range sortedColumns(old.values, t.ignore) {
+			if old.values[column] == cur.values[column] {
+				continue
+			}
+			logs = append(logs, model.CmdbChangeLog{RecordType: t.recordType, RecordID: old.id, Action: model.ChangeActionUpdate,
+				Field: column, OldValue: old.values[column], NewValue: cur.values[column], Source: src, Operator: operator, CreateTime: now})
+		}
+	}
+	writeLogs(db, logs)
+}
+
+func afterDelete(db *gorm.DB) {
+	t, before, ok := snapshotFromStatement(db)
+	if !ok {
+		return
+	}
+	src, operator := sourceFromContext(db.Statement.Context)
+	now := util.HTime{Time: time.Now()}
+	var logs []model.CmdbChangeLog
+	for _, old := range before {
+		logs = append(logs, model.CmdbChangeLog{RecordType: t.recordType, RecordID: old.id, Action: model.ChangeActionDelete,
+			OldValue: encodeValues(old.values, t.ignore), Source: src, Operator: operator, CreateTime: now})
+	}
+	writeLogs(db, logs)
+}
+
+func afterCreate(db *gorm.DB) {
+	stmt := db.Statement
+	if db.Error != nil || db.RowsAffected == 0 || stmt.Schema == nil || stmt.Schema.PrioritizedPrimaryField == nil {
+		return
+	}
+	t, ok := getTable(stmt.Table)
+	if !ok {
+		return
+	}
+	pk := stmt.Schema.PrioritizedPrimaryField
+	var ids []uint
+	addId := func(value reflect.Value) {
+		if v, isZero := pk.ValueOf(stmt.Context, value); !isZero {
+			ids = append(ids, toUint(v))
+		}
+	}
+	switch stmt.ReflectValue.Kind() {
+	case reflect.Struct:
+		addId(stmt.ReflectValue)
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < stmt.ReflectValue.Len(); i++ {
+			addId(reflect.Indirect(stmt.ReflectValue.Index(i)))
+		}
+	}
+	if len(ids) == 0 {
+		return
+	}
+	src, operator := sourceFromContext(stmt.Context)
+	now := util.HTime{Time: time.Now()}
+	var logs []model.CmdbChangeLog
+	for _, r := range loadRows(newSession(db, context.Background()), stmt.Schema.ModelType, ids) {
+		logs = append(logs, model.CmdbChangeLog{RecordType: t.recordType, RecordID: r.id, Action: model.ChangeActionCreate,
+			NewValue: encodeValues(r.values, t.ignore), Source: src, Operator: operator, CreateTime: now})
+	}
+	writeLogs(db, logs)
+}
+
+func snapshotFromStatement(db *gorm.DB) (table, []row, bool) {
+	if db.Error != nil || db.RowsAffected == 0 {
+		return table{}, nil, false
+	}
+	t, ok := getTable(db.Statement.Table)
+	if !ok {
+		return table{}, nil, false
+	}
+	value, ok := db.InstanceGet(snapshotKey)
+	if !ok {
+		return table{}, nil, false
+	}
+	rows, _ := value.([]row)
+	return t, rows, len(rows) > 0
+}
+
+// 使用语句所在的连接（包括事务）执行查询，ctx 为空时不按数据权限过滤
+func newSession(db *gorm.DB, ctx context.Context) *gorm.DB {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return db.Session(&gorm.Session{NewDB: true, Context: ctx})
+}
+
+// 查询记录并把各列转换为字符串，ids 不为空时按主键查询
+func loadRows(tx *gorm.DB, modelType reflect.Type, ids []uint) []row {
+	var list []map[string]interface{}
+	tx = tx.Model(reflect.New(modelType).Interface())
+	if ids != nil {
+		tx = tx.Where("id IN ?", ids)
+	}
+	if err := tx.Find(&list).Error; err != nil {
+		return nil
+	}
+	rows := make([]row, 0, len(list))
+	for _, item := range list {
+		r := row{id: toUint(item["id"]), values: make(map[string]string, len(item))}
+		for column, value := range item {
+			r.values[column] = FormatValue(value)
+		}
+		rows = append(rows, r)
+	}
+	return rows
+}
+
+func rowIds(rows []row) []uint {
+	ids := make([]uint, 0, len(rows))
+	for _, r := range rows {
+		ids = append(ids, r.id)
+	}
+	return ids
+}
+
+func sortedColumns(values map[string]string, ignore map[string]bool) []string {
+	columns := make([]string, 0, len(values))
+	for column := range values {
+		if column != "id" && !ignore[column] {
+			columns = append(columns, column)
+		}
+	}
+	sort.Strings(columns)
+	return columns
+}
+
+// 完整记录编码为 JSON，用于新增和删除的变更记录
+func encodeValues(values map[string]string, ignore map[string]bool) string {
+	record := make(map[string]string, len(values))
+	for _, column := range sortedColumns(values, ignore) {
+		record[column] = values[column]
+	}
+	data, _ := json.Marshal(record)
+	return string(data)
+}
+
+func writeLogs(db *gorm.DB, logs []model.CmdbChangeLog) {
+	if len(logs) == 0 {
+		return
+	}
+	if err := newSession(db, context.Background()).CreateInBatches(&logs, 100).Error; err != nil {
+		db.Logger.Error(db.Statement.Context, "写入资产变更记录失败: %v", err)
+	}
+}
+
+// 把数据库中的值转换为字符串，时间精确到秒
+func FormatValue(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case []byte:
+		return string(v)
+	case string:
+		return v
+	case time.Time:
+		if v.IsZero() {
+			return ""
+		}
+		return v.Local().Format("2006-01-02 15:04:05")
+	case *time.Time:
+		if v == nil || v.IsZero() {
+			return ""
+		}
+		return v.Local().Format("2006-01-02 15:04:05")
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+func toUint(value interface{}) uint {
+	switch v := value.(type) {
+	case uint:
+		return v
+	case uint64:
+		return uint(v)
+	case int64:
+		return uint(v)
+	case int:
+		return uint(v)
+	case uint32:
+		return uint(v)
+	case int32:
+		return uint(v)
+	case []byte:
+		var id uint
+		fmt.Sscan(string(v), &id)
+		return id
+	default:
+		var id uint
+		fmt.Sscan(fmt.Sprint(v), &id)
+		return id
+	}
+}
+
+// 查询记录当前各列的值，不存在时返回 false
+func CurrentValues(db *gorm.DB, modelValue interface{}, id uint) (map[string]string, bool) {
+	var list []map[string]interface{}
+	if err := db.Model(modelValue).Where("id = ?", id).Limit(1).Find(&list).Error; err != nil || len(list) == 0 {
+		return nil, false
+	}
+	values := make(map[string]string, len(list[0]))
+	for column, value := range list[0] {
+		values[column] = FormatValue(value)
+	}
+	return values, true
+}
+
+// 已注册表的不记录变更的列
+func IgnoredColumns(name string) map[string]bool {
+	t, _ := getTable(name)
+	return t.ignore
+}
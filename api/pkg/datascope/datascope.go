@@ -0,0 +1,193 @@
+// 数据权限
+// author xiaoRui
+
+// Package datascope 按角色的数据范围过滤主机、集群、应用、任务等资源
+// 业务 DAO 通过 WithContext 绑定请求上下文后，查询、修改和删除语句会自动追加当前用户可访问范围的条件，
+// 未绑定上下文或上下文中没有登录用户的语句（定时任务、后台同步等）不受限制
+package datascope
+
+import (
+	"context"
+	"dodevops-api/api/system/model"
+	"dodevops-api/common/constant"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// 角色数据范围
+const (
+	ScopeAll         = 1 // 全部数据
+	ScopeCustomGroup = 2 // 自定义分组：角色指定的资产分组及其子分组
+	ScopeDept        = 3 // 本部门
+	ScopeDeptTree    = 4 // 本部门及以下
+)
+
+// Scope 当前用户可访问的数据范围
+type Scope struct {
+	All      bool   // 是否可访问全部数据
+	DeptId   uint   // 用户所属部门，新建任务等资源时作为归属部门
+	DeptIds  []uint // 可访问的部门id
+	GroupIds []uint // 可访问的资产分组id，已包含子分组
+}
+
+// 判断是否可访问指定部门的数据
+func (s Scope) HasDept(deptId uint) bool {
+	return s.All || contains(s.DeptIds, deptId)
+}
+
+// 判断是否可访问指定资产分组的数据
+func (s Scope) HasGroup(groupId uint) bool {
+	return s.All || contains(s.GroupIds, groupId)
+}
+
+func contains(ids []uint, id uint) bool {
+	for _, v := range ids {
+		if v == id {
+			return true
+		}
+	}
+	return false
+}
+
+// Rule 根据数据范围生成表的过滤条件
+type Rule func(scope Scope) clause.Expression
+
+// 按资产分组字段过滤
+func ByGroup(column string) Rule {
+	return func(scope Scope) clause.Expression {
+		return in(column, scope.GroupIds)
+	}
+}
+
+// 按部门字段过滤
+func ByDept(column string) Rule {
+	return func(scope Scope) clause.Expression {
+		return in(column, scope.DeptIds)
+	}
+}
+
+// 按资产分组或部门字段过滤，任一字段在范围内即可访问
+func ByGroupOrDept(groupColumn, deptColumn string) Rule {
+	return func(scope Scope) clause.Expression {
+		return clause.Or(in(groupColumn, scope.GroupIds), in(deptColumn, scope.DeptIds))
+	}
+}
+
+func in(column string, ids []uint) clause.Expression {
+	values := make([]interface{}, 0, len(ids))
+	for _, id := range ids {
+		values = append(values, id)
+	}
+	// 范围为空时 IN (NULL) 不匹配任何数据
+	return clause.IN{Column: clause.Column{Table: clause.CurrentTable, Name: column}, Values: values}
+}
+
+// Resolver 根据用户id计算数据范围，由系统模块注册
+var Resolver func(adminId uint) Scope
+
+var (
+	rules   = map[string]Rule{}
+	rulesMu sync.RWMutex
+)
+
+// 注册需要按数据范围过滤的表
+func RegisterTable(table string, rule Rule) {
+	rulesMu.Lock()
+	defer rulesMu.Unlock()
+	rules[table] = rule
+}
+
+func getRule(table string) (Rule, bool) {
+	rulesMu.RLock()
+	defer rulesMu.RUnlock()
+	rule, ok := rules[table]
+	return rule, ok
+}
+
+type adminScopeKey struct{}
+
+// 后台执行用户保存的任务时没有登录用户，按保存任务的用户的数据范围过滤
+func WithAdmin(ctx context.Context, adminId uint) context.Context {
+	scope := Scope{All: true}
+	if Resolver != nil {
+		scope = Resolver(adminId)
+	}
+	return context.WithValue(ctx, adminScopeKey{}, scope)
+}
+
+// 从请求上下文获取当前用户的数据范围，同一请求内只计算一次，没有登录用户时返回 false
+func FromContext(ctx context.Context) (Scope, bool) {
+	if ctx == nil {
+		return Scope{}, false
+	}
+	if scope, ok := ctx.Value(constant.ContextKeyDataScope).(Scope); ok {
+		return scope, true
+	}
+	if scope, ok := ctx.Value(adminScopeKey{}).(Scope); ok {
+		return scope, true
+	}
+	admin, ok := ctx.Value(constant.ContextKeyUserObj).(*model.JwtAdmin)
+	if !ok || admin == nil {
+		return Scope{}, false
+	}
+	// 未注册计算方法时不限制，保持与未启用数据权限时一致
+	scope := Scope{All: true}
+	if Resolver != nil {
+		scope = Resolver(admin.ID)
+	}
+	if c, ok := ctx.Value(gin.ContextKey).(*gin.Context); ok {
+		c.Set(constant.ContextKeyDataScope, scope)
+	}
+	return scope, true
+}
+
+// 注册 gorm 回调，在查询、修改、删除前追加数据范围条件
+func Register(db *gorm.DB) error {
+	callback := db.Callback()
+	if err := callback.Query().Before("gorm:query").Register("datascope:query", apply); err != nil {
+		return err
+	}
+	if err := callback.Row().Before("gorm:row").Register("datascope:row", apply); err != nil {
+		return err
+	}
+	if err := callback.Update().Before("gorm:update").Register("datascope:update", apply); err != nil {
+		return err
+	}
+	return callback.Delete().Before("gorm:delete").Register("datascope:delete", apply)
+}
+
+func apply(db *gorm.DB) {
+	if db.Error != nil || db.Statement.Table == "" {
+		return
+	}
+	rule, ok := getRule(db.Statement.Table)
+	if !ok {
+		return
+	}
+	scope, ok := FromContext(db.Statement.Context)
+	if !ok || scope.All {
+		return
+	}
+	db.Statement.AddClause(clause.Where{Exprs: []clause.Expression{rule(scope)}})
+}
+
+// 判断当前请求用户是否可访问指定资产分组的数据，没有登录用户时不限制
+func AllowGroup(ctx context.Context, groupId uint) bool {
+	scope, ok := FromContext(ctx)
+	return !ok || scope.HasGroup(groupId)
+}
+
+// 判断当前请求用户是否可访问指定部门的数据，没有登录用户时不限制
+func AllowDept(ctx context.Context, deptId uint) bool {
+	scope, ok := FromContext(ctx)
+	return !ok || scope.HasDept(deptId)
+}
+
+// 当前请求用户所属部门，新建资源时作为归属部门，没有登录用户时返回0
+func CurrentDept(ctx context.Context) uint {
+	scope, _ := FromContext(ctx)
+	return scope.DeptId
+}
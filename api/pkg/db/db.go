@@ -4,6 +4,9 @@ package db
 import (
 	"fmt"
 	"dodevops-api/common/config"
+	"dodevops-api/pkg/changelog"
+	"dodevops-api/pkg/datascope"
+	"dodevops-api/pkg/secretstore"
 	"io"
 	"log"
 	"os"
@@ -63,6 +66,19 @@ func SetupDBLink() error {
 		panic(Db.Error)
 	}
 
+	// 按角色数据范围过滤业务数据
+	if err := datascope.Register(Db); err != nil {
+		panic(err)
+	}
+	// 记录资产变更历史
+	if err := changelog.Register(Db); err != nil {
+		panic(err)
+	}
+	// 写库失败时释放已保存到外部存储的凭证
+	if err := secretstore.Register(Db); err != nil {
+		panic(err)
+	}
+
 	// 自动建表
 	if err := AutoMigrate(Db); err != nil {
 		panic(err)
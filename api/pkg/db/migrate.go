@@ -23,6 +23,19 @@ var models = []interface{}{
 	&cmdbmodel.CmdbHost{},
 	&cmdbmodel.CmdbSQLRecord{},
 	&cmdbmodel.CmdbSQL{},
+	&cmdbmodel.CmdbCredentialRotation{},
+	&cmdbmodel.CmdbCredentialRotationRun{},
+	&cmdbmodel.CmdbCredentialRotationHost{},
+	&cmdbmodel.CmdbHostKey{},
+	&cmdbmodel.CmdbHostLabel{},
+	&cmdbmodel.CmdbBatchCommand{},
+	&cmdbmodel.CmdbBatchCommandHost{},
+	&cmdbmodel.CmdbCIType{},
+	&cmdbmodel.CmdbCIField{},
+	&cmdbmodel.CmdbHostAttr{},
+	&cmdbmodel.CmdbRelation{},
+	&cmdbmodel.CmdbChangeLog{},
+	&cmdbmodel.CmdbDynamicGroup{},
 	&ccmodel.AccountAuth{},
 	&taskmodel.TaskTemplate{},
 	&taskmodel.Task{},
@@ -44,12 +57,53 @@ var models = []interface{}{
 	&appmodel.QuickDeployment{},
 	&appmodel.QuickDeploymentTask{},
 	&systemmodel.SysOperationLog{},
+	&systemmodel.SysOperationLogArchive{},
+	&systemmodel.SysOperationLogChain{},
+	&systemmodel.SysApiToken{},
+	&systemmodel.SysAdminMfa{},
+	&systemmodel.SysPasswordHistory{},
+	&systemmodel.SysRoleGroup{},
+	&systemmodel.SysApprovalPolicy{},
+	&systemmodel.SysApprovalTicket{},
+	&systemmodel.SysApprovalComment{},
+	&systemmodel.SysTerminalSession{},
+	&systemmodel.SysTerminalCommand{},
+	&systemmodel.SysCommandRule{},
 	&toolmodel.Tool{},
 	&toolmodel.ServiceDeploy{},
 	// 可以继续添加其他模型...
 }
 
+// 由 sql/autoops.sql 初始化、未纳入自动建表的历史表上新增的字段
+// 仅在表已存在且缺少该字段时补充，避免 AutoMigrate 改写历史字段类型
+var columns = []struct {
+	model interface{}
+	field string
+}{
+	{&systemmodel.SysAdmin{}, "Source"},
+	{&systemmodel.SysAdmin{}, "PasswordUpdateTime"},
+	{&systemmodel.SysAdmin{}, "PasswordResetRequired"},
+	{&systemmodel.SysRole{}, "DataScope"},
+}
+
 // 自动迁移所有模型
 func AutoMigrate(db *gorm.DB) error {
-	return db.AutoMigrate(models...)
+	if err := db.AutoMigrate(models...); err != nil {
+		return err
+	}
+	return migrateColumns(db)
+}
+
+// 为历史表补充新增字段
+func migrateColumns(db *gorm.DB) error {
+	migrator := db.Migrator()
+	for _, column := range columns {
+		if !migrator.HasTable(column.model) || migrator.HasColumn(column.model, column.field) {
+			continue
+		}
+		if err := migrator.AddColumn(column.model, column.field); err != nil {
+			return err
+		}
+	}
+	return nil
 }
@@ -0,0 +1,323 @@
+// 敏感字段信封加密
+// author xiaoRui
+
+// Package envelope 敏感字段的信封加密
+// 每条记录使用随机生成的数据密钥（DEK）以 AES-256-GCM 加密，数据密钥再由主密钥（KEK）加密后与密文一起保存；
+// 密文格式为 "enc:<主密钥版本>:<加密后的数据密钥>:<随机数+密文>"，后两段为 base64 编码。
+// 主密钥按版本保存，轮换时追加新版本并切换激活版本，旧数据通过 Rotate 在线重新加密
+package envelope
+
+import (
+	"bufio"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"dodevops-api/common/config"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	// 密文前缀
+	prefix = "enc:"
+	// 主密钥环境变量，格式 "1:base64密钥,2:base64密钥"
+	EnvMasterKeys = "AUTOOPS_MASTER_KEYS"
+	// 激活主密钥版本环境变量
+	EnvMasterKeyVersion = "AUTOOPS_MASTER_KEY_VERSION"
+	// 主密钥与数据密钥长度
+	keySize = 32
+)
+
+var (
+	ErrNoMasterKey  = errors.New("未配置主密钥")
+	ErrUnknownKey   = errors.New("密文使用的主密钥版本不存在")
+	ErrInvalidValue = errors.New("密文格式错误")
+)
+
+// 主密钥环
+type keyring struct {
+	keys   map[int][]byte
+	active int
+}
+
+var (
+	mu       sync.RWMutex
+	current  *keyring
+	loadOnce sync.Once
+	loadErr  error
+)
+
+// 按配置加载主密钥，服务启动时调用；重复调用会重新加载
+func Load(cfg config.EncryptionConfig) error {
+	ring, err := loadKeyring(cfg)
+	if err != nil {
+		return err
+	}
+	mu.Lock()
+	current = ring
+	mu.Unlock()
+	loadOnce.Do(func() {})
+	return nil
+}
+
+// 获取主密钥环，未显式加载时按全局配置加载一次
+func getKeyring() (*keyring, error) {
+	loadOnce.Do(func() {
+		var cfg config.EncryptionConfig
+		if config.Config != nil {
+			cfg = config.Config.Encryption
+		}
+		var ring *keyring
+		if ring, loadErr = loadKeyring(cfg); loadErr == nil {
+			mu.Lock()
+			current = ring
+			mu.Unlock()
+		}
+	})
+	mu.RLock()
+	defer mu.RUnlock()
+	if current == nil {
+		if loadErr != nil {
+			return nil, loadErr
+		}
+		return nil, ErrNoMasterKey
+	}
+	return current, nil
+}
+
+func loadKeyring(cfg config.EncryptionConfig) (*keyring, error) {
+	ring := &keyring{keys: map[int][]byte{}}
+	if env := os.Getenv(EnvMasterKeys); env != "" {
+		if err := ring.parse(env); err != nil {
+			return nil, fmt.Errorf("解析环境变量 %s 失败: %v", EnvMasterKeys, err)
+		}
+	} else if cfg.MasterKeyFile != "" {
+		content, err := os.ReadFile(cfg.MasterKeyFile)
+		switch {
+		case err == nil:
+			if err := ring.parse(string(content)); err != nil {
+				return nil, fmt.Errorf("解析主密钥文件 %s 失败: %v", cfg.MasterKeyFile, err)
+			}
+		case os.IsNotExist(err) && cfg.GenerateIfMissing:
+			if err := ring.generate(cfg.MasterKeyFile); err != nil {
+				return nil, fmt.Errorf("生成主密钥文件 %s 失败: %v", cfg.MasterKeyFile, err)
+			}
+		default:
+			return nil, fmt.Errorf("读取主密钥文件失败: %v", err)
+		}
+	}
+	if len(ring.keys) == 0 {
+		return nil, ErrNoMasterKey
+	}
+
+	active := cfg.ActiveKeyVersion
+	if env := os.Getenv(EnvMasterKeyVersion); env != "" {
+		version, err := strconv.Atoi(env)
+		if err != nil {
+			return nil, fmt.Errorf("环境变量 %s 格式错误: %v", EnvMasterKeyVersion, err)
+		}
+		active = version
+	}
+	if active == 0 {
+		for version := range ring.keys {
+			if version > active {
+				active = version
+			}
+		}
+	}
+	if _, ok := ring.keys[active]; !ok {
+		return nil, fmt.Errorf("激活的主密钥版本 %d 不存在", active)
+	}
+	ring.active = active
+	return ring, nil
+}
+
+// 解析 "版本:base64密钥" 列表，以换行或逗号分隔，# 开头为注释
+func (r *keyring) parse(content string) error {
+	scanner := bufio.NewScanner(strings.NewReader(strings.ReplaceAll(content, ",", "\n")))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		parts := strings.SplitN(line, ":", 2)
+		if len(parts) != 2 {
+			return fmt.Errorf("无效的主密钥: %q", line)
+		}
+		version, err := strconv.Atoi(strings.TrimSpace(parts[0]))
+		if err != nil || version <= 0 {
+			return fmt.Errorf("无效的主密钥版本: %q", parts[0])
+		}
+		key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(parts[1]))
+		if err != nil || len(key) != keySize {
+			return fmt.Errorf("主密钥版本 %d 必须是 base64 编码的 %d 字节密钥", version, keySize)
+		}
+		r.keys[version] = key
+	}
+	return scanner.Err()
+}
+
+// 生成版本 1 的主密钥并写入文件
+func (r *keyring) generate(path string) error {
+	key, err := GenerateKey()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	content := "# AutoOps 主密钥，格式：版本:base64密钥，轮换时追加新版本，重新加密完成前不要删除旧版本\n1:" + key + "\n"
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		return err
+	}
+	return r.parse(content)
+}
+
+// 生成 base64 编码的随机主密钥
+func GenerateKey() (string, error) {
+	key := make([]byte, keySize)
+	if _, err := io.ReadFull(rand.Reader, key); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(key), nil
+}
+
+// 当前用于加密的主密钥版本
+func ActiveVersion() (int, error) {
+	ring, err := getKeyring()
+	if err != nil {
+		return 0, err
+	}
+	return ring.active, nil
+}
+
+// 已加载的主密钥版本
+func Versions() ([]int, error) {
+	ring, err := getKeyring()
+	if err != nil {
+		return nil, err
+	}
+	versions := make([]int, 0, len(ring.keys))
+	for version := range ring.keys {
+		versions = append(versions, version)
+	}
+	sort.Ints(versions)
+	return versions, nil
+}
+
+// 判断是否为信封加密的密文
+func IsEncrypted(value string) bool {
+	return strings.HasPrefix(value, prefix)
+}
+
+// 密文使用的主密钥版本，非信封加密的历史数据返回 0
+func KeyVersion(value string) int {
+	if !IsEncrypted(value) {
+		return 0
+	}
+	parts := strings.SplitN(value[len(prefix):], ":", 2)
+	version, _ := strconv.Atoi(parts[0])
+	return version
+}
+
+// 使用激活的主密钥加密
+func Encrypt(plaintext string) (string, error) {
+	ring, err := getKeyring()
+	if err != nil {
+		return "", err
+	}
+	dek := make([]byte, keySize)
+	if _, err := io.ReadFull(rand.Reader, dek); err != nil {
+		return "", err
+	}
+	wrapped, err := seal(ring.keys[ring.active], dek)
+	if err != nil {
+		return "", err
+	}
+	sealed, err := seal(dek, []byte(plaintext))
+	if err != nil {
+		return "", err
+	}
+	return prefix + strconv.Itoa(ring.active) + ":" +
+		base64.StdEncoding.EncodeToString(wrapped) + ":" + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// 解密信封加密的密文
+func Decrypt(value string) (string, error) {
+	if !IsEncrypted(value) {
+		return "", ErrInvalidValue
+	}
+	parts := strings.Split(value[len(prefix):], ":")
+	if len(parts) != 3 {
+		return "", ErrInvalidValue
+	}
+	version, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return "", ErrInvalidValue
+	}
+	ring, err := getKeyring()
+	if err != nil {
+		return "", err
+	}
+	kek, ok := ring.keys[version]
+	if !ok {
+		return "", fmt.Errorf("%w: %d", ErrUnknownKey, version)
+	}
+	wrapped, err := base64.StdEncoding.DecodeString(parts[1])
+	if err != nil {
+		return "", ErrInvalidValue
+	}
+	sealed, err := base64.StdEncoding.DecodeString(parts[2])
+	if err != nil {
+		return "", ErrInvalidValue
+	}
+	dek, err := open(kek, wrapped)
+	if err != nil {
+		return "", fmt.Errorf("解密数据密钥失败: %v", err)
+	}
+	plaintext, err := open(dek, sealed)
+	if err != nil {
+		return "", fmt.Errorf("解密失败: %v", err)
+	}
+	return string(plaintext), nil
+}
+
+// AES-GCM 加密，返回随机数+密文
+func seal(key, plaintext []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+	return gcm.Seal(nonce, nonce, plaintext, nil), nil
+}
+
+func open(key, sealed []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(sealed) < gcm.NonceSize() {
+		return nil, ErrInvalidValue
+	}
+	return gcm.Open(nil, sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():], nil)
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
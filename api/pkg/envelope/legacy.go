@@ -0,0 +1,55 @@
+// 历史数据兼容
+// author xiaoRui
+
+package envelope
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/base64"
+	"errors"
+)
+
+// Legacy 信封加密之前的数据保存方式
+type Legacy int
+
+const (
+	LegacyPlain Legacy = iota // 明文保存
+	LegacyCBC                 // 使用内置固定密钥 AES-CBC 加密
+)
+
+// 旧版本内置的固定密钥，仅用于解密历史数据，新数据不再使用
+const legacyKey = "this-is-32-byte-key-for-aes-256!"
+
+// 解密密文，兼容信封加密之前保存的数据
+func DecryptValue(value string, legacy Legacy) (string, error) {
+	if IsEncrypted(value) {
+		return Decrypt(value)
+	}
+	if legacy == LegacyCBC {
+		return decryptLegacyCBC(value)
+	}
+	return value, nil
+}
+
+// 解密旧版本 AES-CBC 密文：base64(IV + 密文)，PKCS7 填充
+func decryptLegacyCBC(value string) (string, error) {
+	data, err := base64.StdEncoding.DecodeString(value)
+	if err != nil {
+		return "", err
+	}
+	if len(data) < 2*aes.BlockSize || len(data)%aes.BlockSize != 0 {
+		return "", errors.New("ciphertext too short")
+	}
+	block, err := aes.NewCipher([]byte(legacyKey))
+	if err != nil {
+		return "", err
+	}
+	plaintext := make([]byte, len(data)-aes.BlockSize)
+	cipher.NewCBCDecrypter(block, data[:aes.BlockSize]).CryptBlocks(plaintext, data[aes.BlockSize:])
+	padding := int(plaintext[len(plaintext)-1])
+	if padding == 0 || padding > aes.BlockSize {
+		return "", errors.New("invalid padding")
+	}
+	return string(plaintext[:len(plaintext)-padding]), nil
+}
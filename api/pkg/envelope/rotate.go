@@ -0,0 +1,221 @@
+// 主密钥轮换
+// author xiaoRui
+
+package envelope
+
+import (
+	"errors"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 批量处理的行数
+const rotateBatchSize = 200
+
+var ErrRotating = errors.New("正在重新加密，请稍后再试")
+
+// Column 保存加密数据的字段
+type Column struct {
+	Table  string `json:"table"`  // 表名
+	Column string `json:"column"` // 字段名
+	Legacy Legacy `json:"-"`      // 信封加密之前的保存方式
+}
+
+// ColumnStatus 字段中各主密钥版本的数据量，版本 0 表示信封加密之前的历史数据
+type ColumnStatus struct {
+	Table    string        `json:"table"`    // 表名
+	Column   string        `json:"column"`   // 字段名
+	Total    int64         `json:"total"`    // 非空数据量
+	External int64         `json:"external"` // 保存在外部密钥存储中、数据库只保存引用的数据量
+	Versions map[int]int64 `json:"versions"` // 主密钥版本 -> 数据量
+}
+
+// RotateResult 重新加密结果
+type RotateResult struct {
+	ActiveVersion int       `json:"activeVersion"` // 目标主密钥版本
+	Rotated       int64     `json:"rotated"`       // 重新加密的数据量
+	Skipped       int64     `json:"skipped"`       // 执行期间被修改而跳过的数据量
+	Failed        int64     `json:"failed"`        // 解密失败的数据量
+	StartTime     time.Time `json:"startTime"`     // 开始时间
+	EndTime       time.Time `json:"endTime"`       // 结束时间
+	Error         string    `json:"error"`         // 错误信息
+}
+
+var (
+	columnsMu sync.RWMutex
+	columns   []Column
+	external  []string
+
+	rotateMu   sync.Mutex
+	rotating   bool
+	lastRotate *RotateResult
+)
+
+// 注册保存加密数据的字段，由各模块在 init 中调用，Rotate 和 Status 只处理已注册的字段
+func RegisterColumns(table string, legacy Legacy, names ...string) {
+	columnsMu.Lock()
+	defer columnsMu.Unlock()
+	for _, name := range names {
+		columns = append(columns, Column{Table: table, Column: name, Legacy: legacy})
+	}
+}
+
+// 注册外部密钥存储的引用前缀，带有该前缀的值不是密文，统计和重新加密时跳过
+func RegisterExternalPrefix(prefix string) {
+	columnsMu.Lock()
+	defer columnsMu.Unlock()
+	external = append(external, prefix)
+}
+
+// 判断是否为外部密钥存储的引用
+func IsExternal(value string) bool {
+	columnsMu.RLock()
+	defer columnsMu.RUnlock()
+	for _, prefix := range external {
+		if strings.HasPrefix(value, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// 已注册的加密字段
+func Columns() []Column {
+	columnsMu.RLock()
+	defer columnsMu.RUnlock()
+	list := append([]Column(nil), columns...)
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Table != list[j].Table {
+			return list[i].Table < list[j].Table
+		}
+		return list[i].Column < list[j].Column
+	})
+	return list
+}
+
+// 是否正在重新加密，以及最近一次重新加密的结果
+func RotateState() (bool, *RotateResult) {
+	rotateMu.Lock()
+	defer rotateMu.Unlock()
+	return rotating, lastRotate
+}
+
+// 统计各字段中每个主密钥版本的数据量
+func Status(db *gorm.DB) ([]ColumnStatus, error) {
+	var list []ColumnStatus
+	for _, column := range Columns() {
+		status := ColumnStatus{Table: column.Table, Column: column.Column, Versions: map[int]int64{}}
+		err := ScanColumn(db, column, func(id uint64, value string) error {
+			status.Total++
+			if IsExternal(value) {
+				status.External++
+			} else {
+				status.Versions[KeyVersion(value)]++
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, status)
+	}
+	return list, nil
+}
+
+// 将所有已注册字段中非激活主密钥版本的数据重新加密为激活版本；
+// 逐行以原值为条件更新，执行期间业务写入的新数据不会被覆盖，无需停机
+func Rotate(db *gorm.DB) (*RotateResult, error) {
+	rotateMu.Lock()
+	if rotating {
+		rotateMu.Unlock()
+		return nil, ErrRotating
+	}
+	rotating = true
+	rotateMu.Unlock()
+
+	res := &RotateResult{StartTime: time.Now()}
+	err := rotate(db, res)
+	res.EndTime = time.Now()
+	if err != nil {
+		res.Error = err.Error()
+	}
+
+	rotateMu.Lock()
+	rotating = false
+	lastRotate = res
+	rotateMu.Unlock()
+	return res, err
+}
+
+func rotate(db *gorm.DB, res *RotateResult) error {
+	active, err := ActiveVersion()
+	if err != nil {
+		return err
+	}
+	res.ActiveVersion = active
+	for _, column := range Columns() {
+		err := ScanColumn(db, column, func(id uint64, value string) error {
+			if KeyVersion(value) == active || IsExternal(value) {
+				return nil
+			}
+			plaintext, err := DecryptValue(value, column.Legacy)
+			if err != nil {
+				res.Failed++
+				return nil
+			}
+			encrypted, err := Encrypt(plaintext)
+			if err != nil {
+				return err
+			}
+			update := db.Table(column.Table).Where("id = ? AND "+quote(column.Column)+" = ?", id, value).
+				UpdateColumn(column.Column, encrypted)
+			if update.Error != nil {
+				return update.Error
+			}
+			if update.RowsAffected == 0 {
+				res.Skipped++
+			} else {
+				res.Rotated++
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// 按主键分批遍历字段的非空值
+func ScanColumn(db *gorm.DB, column Column, fn func(id uint64, value string) error) error {
+	var lastId uint64
+	for {
+		var rows []struct {
+			Id    uint64
+			Value string
+		}
+		err := db.Table(column.Table).Select("id, "+quote(column.Column)+" AS value").
+			Where("id > ? AND "+quote(column.Column)+" IS NOT NULL AND "+quote(column.Column)+" <> ''", lastId).
+			Order("id").Limit(rotateBatchSize).Scan(&rows).Error
+		if err != nil {
+			return err
+		}
+		for _, row := range rows {
+			if err := fn(row.Id, row.Value); err != nil {
+				return err
+			}
+			lastId = row.Id
+		}
+		if len(rows) < rotateBatchSize {
+			return nil
+		}
+	}
+}
+
+func quote(name string) string {
+	return "`" + name + "`"
+}
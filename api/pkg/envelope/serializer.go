@@ -0,0 +1,52 @@
+// GORM 加密字段序列化器
+// author xiaoRui
+
+package envelope
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+
+	"gorm.io/gorm/schema"
+)
+
+// 在模型字段上声明 `gorm:"serializer:encrypt"` 后，写库时自动加密，读库时自动解密；
+// 历史明文数据读取时原样返回，下次保存或执行 Rotate 时加密
+type encryptSerializer struct{}
+
+func init() {
+	schema.RegisterSerializer("encrypt", encryptSerializer{})
+}
+
+func (encryptSerializer) Scan(ctx context.Context, field *schema.Field, dst reflect.Value, dbValue interface{}) error {
+	var value string
+	switch v := dbValue.(type) {
+	case nil:
+	case string:
+		value = v
+	case []byte:
+		value = string(v)
+	default:
+		return fmt.Errorf("字段 %s 不支持加密类型 %T", field.Name, dbValue)
+	}
+	if value != "" {
+		plaintext, err := DecryptValue(value, LegacyPlain)
+		if err != nil {
+			return fmt.Errorf("解密字段 %s 失败: %v", field.Name, err)
+		}
+		value = plaintext
+	}
+	return field.Set(ctx, dst, value)
+}
+
+func (encryptSerializer) Value(ctx context.Context, field *schema.Field, dst reflect.Value, fieldValue interface{}) (interface{}, error) {
+	value, ok := fieldValue.(string)
+	if !ok {
+		return nil, fmt.Errorf("字段 %s 不支持加密类型 %T", field.Name, fieldValue)
+	}
+	if value == "" {
+		return "", nil
+	}
+	return Encrypt(value)
+}
@@ -16,7 +16,7 @@ type userStdClaims struct {
 	jwt.StandardClaims
 }
 
-// token过期时间
+// token默认过期时间，登录时按认证配置中的会话有效期签发
 const TokenExpireDuration = time.Hour * 24
 
 // token密钥
@@ -26,22 +26,27 @@ var (
 	ErrInvalid = "token invalid" //令牌无效
 )
 
-// 根据用户信息生成token
-func GenerateTokenByAdmin(admin model.SysAdmin) (string, error) {
+// 根据用户信息生成token，sessionId 为登录会话id，用于服务端吊销
+func GenerateTokenByAdmin(admin model.SysAdmin, sessionId string, expire time.Duration) (string, error) {
 	var jwtAdmin = model.JwtAdmin{
-		ID:       admin.ID,
-		Username: admin.Username,
-		Nickname: admin.Nickname,
-		Icon:     admin.Icon,
-		Email:    admin.Email,
-		Phone:    admin.Phone,
-		Note:     admin.Note,
+		ID:        admin.ID,
+		Username:  admin.Username,
+		Nickname:  admin.Nickname,
+		Icon:      admin.Icon,
+		Email:     admin.Email,
+		Phone:     admin.Phone,
+		Note:      admin.Note,
+		SessionId: sessionId,
+		PwdChange: admin.PasswordResetRequired,
+	}
+	if expire <= 0 {
+		expire = TokenExpireDuration
 	}
 	c := userStdClaims{
 		jwtAdmin,
 		jwt.StandardClaims{
-			ExpiresAt: time.Now().Add(TokenExpireDuration).Unix(), //过期时间
-			Issuer:    "admin",                                    //签发人
+			ExpiresAt: time.Now().Add(expire).Unix(), //过期时间
+			Issuer:    "admin",                       //签发人
 		},
 	}
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, c)
@@ -89,13 +94,13 @@ func GetAdminId(c *gin.Context) (uint, error) {
 func GetAdminName(c *gin.Context) (string, error) {
 	u, exist := c.Get(constant.ContextKeyUserObj)
 	if !exist {
-		return string(string(0)), errors.New("无法获取用户名")
+		return "", errors.New("无法获取用户名")
 	}
 	admin, ok := u.(*model.JwtAdmin)
 	if ok {
 		return admin.Username, nil
 	}
-	return string(string(0)), errors.New("无法转换为api名称")
+	return "", errors.New("无法转换为api名称")
 }
 
 // 返回admin信息
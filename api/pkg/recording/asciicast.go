@@ -0,0 +1,321 @@
+// asciicast v2 录像
+// author xiaoRui
+
+package recording
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// 单条命令最大长度，超出部分截断
+const maxCommandLength = 1024
+
+// 一次输入多行（如粘贴）时回显在输入之后到达，在该时间内等待回显确认
+const echoWait = 2 * time.Second
+
+// 等待回显确认时保留的输出长度
+const maxEchoBuffer = 4096
+
+// Header asciicast v2 文件头
+type Header struct {
+	Version   int               `json:"version"`
+	Width     int               `json:"width"`
+	Height    int               `json:"height"`
+	Timestamp int64             `json:"timestamp"`
+	Title     string            `json:"title,omitempty"`
+	Env       map[string]string `json:"env,omitempty"`
+}
+
+// Command 会话中执行的命令
+type Command struct {
+	Offset  float64 // 相对会话开始的秒数，可用于回放时定位
+	Command string  // 命令内容
+}
+
+// Result 录像结果
+type Result struct {
+	Backend  string    // 录像所在的存储后端
+	Key      string    // 录像路径
+	Size     int64     // 录像大小(字节)
+	Duration float64   // 会话时长(秒)
+	Commands []Command // 执行的命令
+}
+
+// Recorder 终端会话录像，输出按 asciicast v2 格式写入，输入只用于提取执行的命令，不保存原始按键
+type Recorder struct {
+	mu       sync.Mutex
+	key      string
+	path     string
+	file     *os.File
+	writer   *bufio.Writer
+	start    time.Time
+	pending  []byte // 输出中被截断的不完整 UTF-8 字符
+	line     LineBuffer
+	echoed   bool      // 当前输入行是否有回显，无回显的输入（如密码）不作为命令记录
+	waiting  []Command // 已回车但还未收到回显的命令
+	echo     []byte    // 最近一次回车后的输出，用于确认等待中的命令
+	commands []Command
+	closed   bool
+	handler  func(offset float64, kind, data string) // 录像事件回调，用于实时观看
+}
+
+// 创建录像，key 为录像路径，会话进行中写入本地录像目录
+func NewRecorder(key string, width, height int, title string) (*Recorder, error) {
+	path := localPath(key)
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, fmt.Errorf("创建录像目录失败: %v", err)
+	}
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("创建录像文件失败: %v", err)
+	}
+	r := &Recorder{key: key, path: path, file: file, writer: bufio.NewWriter(file), start: time.Now()}
+	header, _ := json.Marshal(Header{
+		Version:   2,
+		Width:     width,
+		Height:    height,
+		Timestamp: r.start.Unix(),
+		Title:     title,
+		Env:       map[string]string{"TERM": "xterm-256color", "SHELL": "/bin/bash"},
+	})
+	r.writer.Write(append(header, '\n'))
+	return r, nil
+}
+
+// 设置录像事件回调，每写入一条输出或窗口大小事件时调用，回调中不能阻塞
+func (r *Recorder) SetEventHandler(handler func(offset float64, kind, data string)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.handler = handler
+}
+
+// 记录终端输出
+func (r *Recorder) Output(p []byte) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.closed || len(p) == 0 {
+		return
+	}
+	data := append(r.pending, p...)
+	complete := validPrefix(data)
+	r.pending = append([]byte(nil), data[complete:]...)
+	if complete == 0 {
+		return
+	}
+	if r.line.Current() != "" {
+		r.echoed = true
+	}
+	r.confirm(data[:complete])
+	r.event("o", string(data[:complete]))
+}
+
+// 输出中出现等待中的命令时确认为执行的命令，超过等待时间未出现的丢弃
+func (r *Recorder) confirm(output []byte) {
+	if len(r.waiting) == 0 {
+		return
+	}
+	r.echo = append(r.echo, output...)
+	if len(r.echo) > maxEchoBuffer {
+		r.echo = r.echo[len(r.echo)-maxEchoBuffer:]
+	}
+	now := r.offset()
+	waiting := r.waiting[:0]
+	for _, command := range r.waiting {
+		switch {
+		case bytes.Contains(r.echo, []byte(command.Command)):
+			r.commands = append(r.commands, command)
+		case now-command.Offset < echoWait.Seconds():
+			waiting = append(waiting, command)
+		}
+	}
+	r.waiting = waiting
+}
+
+// 记录用户输入，从中提取执行的命令
+func (r *Recorder) Input(p []byte) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.closed {
+		return
+	}
+	for _, line := range r.line.Feed(p) {
+		command := Command{Offset: r.offset(), Command: strings.TrimSpace(line)}
+		if command.Command != "" {
+			if r.echoed {
+				r.commands = append(r.commands, command)
+			} else {
+				r.waiting = append(r.waiting, command)
+				r.echo = r.echo[:0]
+			}
+		}
+		r.echoed = false
+	}
+}
+
+// 记录终端窗口大小变化
+func (r *Recorder) Resize(cols, rows int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.closed || cols <= 0 || rows <= 0 {
+		return
+	}
+	r.event("r", fmt.Sprintf("%dx%d", cols, rows))
+}
+
+// 结束录像并保存到当前的存储后端
+func (r *Recorder) Close(ctx context.Context) (Result, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	res := Result{Key: r.key, Duration: r.offset(), Commands: r.commands}
+	if r.closed {
+		return res, nil
+	}
+	r.closed = true
+	if len(r.pending) > 0 {
+		r.event("o", string(r.pending))
+	}
+	if err := r.writer.Flush(); err != nil {
+		r.file.Close()
+		return res, err
+	}
+	if info, err := r.file.Stat(); err == nil {
+		res.Size = info.Size()
+	}
+	if err := r.file.Close(); err != nil {
+		return res, err
+	}
+	storage := Active()
+	res.Backend = storage.Name()
+	if err := storage.Save(ctx, r.key, r.path); err != nil {
+		// 上传失败时保留在本地，仍可回放
+		res.Backend = BackendLocal
+		return res, err
+	}
+	return res, nil
+}
+
+// 保存服务异常退出时遗留的本地录像，返回录像所在的存储后端和大小
+func SaveOrphan(ctx context.Context, key string) (string, int64, error) {
+	path := localPath(key)
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", 0, err
+	}
+	storage := Active()
+	if err := storage.Save(ctx, key, path); err != nil {
+		return BackendLocal, info.Size(), err
+	}
+	return storage.Name(), info.Size(), nil
+}
+
+func (r *Recorder) offset() float64 {
+	return time.Since(r.start).Seconds()
+}
+
+func (r *Recorder) event(kind, data string) {
+	offset := r.offset()
+	line, _ := json.Marshal([]interface{}{offset, kind, data})
+	r.writer.Write(append(line, '\n'))
+	if r.handler != nil {
+		r.handler(offset, kind, data)
+	}
+}
+
+// 返回 p 中以完整 UTF-8 字符结尾的前缀长度，末尾被截断的字符留到下次输出
+func validPrefix(p []byte) int {
+	for i := len(p) - 1; i >= 0 && i >= len(p)-utf8.UTFMax; i-- {
+		if !utf8.RuneStart(p[i]) {
+			continue
+		}
+		if !utf8.FullRune(p[i:]) {
+			return i
+		}
+		break
+	}
+	return len(p)
+}
+
+// LineBuffer 按终端行编辑规则还原用户输入的命令行，处理退格、清行和方向键等控制序列
+type LineBuffer struct {
+	line    []rune
+	pending []byte // 被截断的 UTF-8 字符
+	escape  int    // 控制序列解析状态：0 普通输入，1 读取到 ESC，2 CSI/SS3 序列中
+}
+
+// 输入数据，返回输入过程中按回车完成的命令行
+func (b *LineBuffer) Feed(p []byte) []string {
+	var lines []string
+	data := append(b.pending, p...)
+	b.pending = nil
+	for len(data) > 0 {
+		c := data[0]
+		switch {
+		case b.escape == 1:
+			data = data[1:]
+			b.escape = 0
+			if c == '[' || c == 'O' {
+				b.escape = 2
+			}
+			continue
+		case b.escape == 2:
+			data = data[1:]
+			if c >= 0x40 && c <= 0x7e {
+				b.escape = 0
+			}
+			continue
+		}
+		if c < utf8.RuneSelf {
+			data = data[1:]
+			switch c {
+			case 0x1b:
+				b.escape = 1
+			case '\r', '\n':
+				lines = append(lines, b.Current())
+				b.line = b.line[:0]
+			case 0x7f, '\b':
+				if len(b.line) > 0 {
+					b.line = b.line[:len(b.line)-1]
+				}
+			case 0x03, 0x15: // Ctrl+C、Ctrl+U 清空当前行
+				b.line = b.line[:0]
+			case 0x17: // Ctrl+W 删除前一个单词
+				for len(b.line) > 0 && b.line[len(b.line)-1] == ' ' {
+					b.line = b.line[:len(b.line)-1]
+				}
+				for len(b.line) > 0 && b.line[len(b.line)-1] != ' ' {
+					b.line = b.line[:len(b.line)-1]
+				}
+			default:
+				if c >= 0x20 && len(b.line) < maxCommandLength {
+					b.line = append(b.line, rune(c))
+				}
+			}
+			continue
+		}
+		if !utf8.FullRune(data) {
+			b.pending = append([]byte(nil), data...)
+			break
+		}
+		r, size := utf8.DecodeRune(data)
+		data = data[size:]
+		if r != utf8.RuneError && len(b.line) < maxCommandLength {
+			b.line = append(b.line, r)
+		}
+	}
+	return lines
+}
+
+// 当前正在输入的命令行
+func (b *LineBuffer) Current() string {
+	return string(b.line)
+}
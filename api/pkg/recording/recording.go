@@ -0,0 +1,155 @@
+// 终端会话录像存储
+// author xiaoRui
+
+// Package recording Web终端会话录制：按 asciicast v2 格式记录终端输出，并从用户输入中提取执行的命令。
+// 录像在会话进行中写入本地目录，会话结束后按配置保留在本地（local）或上传到 S3 兼容的对象存储（s3），
+// 数据库中保存录像所在的后端和路径，切换后端后历史录像仍可回放
+package recording
+
+import (
+	"context"
+	"dodevops-api/common/config"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// 存储后端名称
+const (
+	BackendLocal = "local"
+	BackendS3    = "s3"
+)
+
+// 默认本地录像目录
+const defaultDir = "./data/recordings"
+
+// Storage 录像存储后端
+type Storage interface {
+	// 后端名称
+	Name() string
+	// 会话结束后保存本地录像文件，key 为录像路径
+	Save(ctx context.Context, key, localPath string) error
+	// 读取录像
+	Open(ctx context.Context, key string) (io.ReadCloser, error)
+	// 删除录像
+	Delete(ctx context.Context, key string) error
+}
+
+var ErrS3NotConfigured = errors.New("未配置对象存储，无法读取保存在对象存储中的录像")
+
+var (
+	mu     sync.RWMutex
+	dir    = defaultDir
+	active Storage
+	local  Storage = localStore{dir: defaultDir}
+	s3     Storage
+)
+
+func init() {
+	active = local
+}
+
+// 按配置初始化录像存储，服务启动时调用
+func Setup(cfg config.RecordingConfig) error {
+	d := cfg.Dir
+	if d == "" {
+		d = defaultDir
+	}
+	if err := os.MkdirAll(d, 0700); err != nil {
+		return fmt.Errorf("创建录像目录失败: %v", err)
+	}
+	var o Storage
+	if store, err := newS3Store(cfg.S3); err != nil {
+		return err
+	} else if store != nil {
+		o = store
+	}
+
+	var l Storage = localStore{dir: d}
+	a := l
+	switch cfg.Backend {
+	case "", BackendLocal:
+	case BackendS3:
+		if o == nil {
+			return errors.New("录像存储后端为 s3 时必须配置对象存储地址和存储桶")
+		}
+		a = o
+	default:
+		return fmt.Errorf("不支持的录像存储后端: %s", cfg.Backend)
+	}
+
+	mu.Lock()
+	dir, local, s3, active = d, l, o, a
+	mu.Unlock()
+	return nil
+}
+
+// 当前保存录像使用的存储后端
+func Active() Storage {
+	mu.RLock()
+	defer mu.RUnlock()
+	return active
+}
+
+// 根据录像记录中的后端名称选择存储后端
+func StorageOf(backend string) (Storage, error) {
+	mu.RLock()
+	defer mu.RUnlock()
+	switch backend {
+	case "", BackendLocal:
+		return local, nil
+	case BackendS3:
+		if s3 == nil {
+			return nil, ErrS3NotConfigured
+		}
+		return s3, nil
+	}
+	return nil, fmt.Errorf("不支持的录像存储后端: %s", backend)
+}
+
+// 会话进行中录像文件的本地路径
+func localPath(key string) string {
+	mu.RLock()
+	defer mu.RUnlock()
+	return filepath.Join(dir, filepath.FromSlash(key))
+}
+
+// 本地目录存储，录像直接保留在会话进行中写入的位置
+type localStore struct {
+	dir string
+}
+
+func (s localStore) Name() string {
+	return BackendLocal
+}
+
+func (s localStore) Save(ctx context.Context, key, localPath string) error {
+	target := s.path(key)
+	if target == localPath {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(target), 0700); err != nil {
+		return err
+	}
+	return os.Rename(localPath, target)
+}
+
+func (s localStore) Open(ctx context.Context, key string) (io.ReadCloser, error) {
+	return os.Open(s.path(key))
+}
+
+func (s localStore) Delete(ctx context.Context, key string) error {
+	if err := os.Remove(s.path(key)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// 录像路径只能位于录像目录内
+func (s localStore) path(key string) string {
+	return filepath.Join(s.dir, filepath.FromSlash(strings.TrimLeft(filepath.ToSlash(filepath.Clean("/"+key)), "/")))
+}
@@ -0,0 +1,249 @@
+// 凭证字段注册、GORM 序列化器与后端迁移
+// author xiaoRui
+
+package secretstore
+
+import (
+	"context"
+	"dodevops-api/pkg/envelope"
+	"fmt"
+	"reflect"
+	"sync"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+// MigrateResult 迁移结果
+type MigrateResult struct {
+	Backend  string `json:"backend"`  // 目标存储后端
+	Migrated int64  `json:"migrated"` // 迁移的数据量
+	Skipped  int64  `json:"skipped"`  // 执行期间被修改而跳过的数据量
+	Failed   int64  `json:"failed"`   // 读取失败的数据量
+}
+
+var (
+	columnsMu sync.RWMutex
+	columns   = map[string][]envelope.Column{}
+)
+
+func init() {
+	schema.RegisterSerializer("secret", secretSerializer{})
+}
+
+// 注册凭证字段，同时注册为信封加密字段以便主密钥轮换时重新加密
+func RegisterColumns(table string, legacy envelope.Legacy, names ...string) {
+	envelope.RegisterColumns(table, legacy, names...)
+	columnsMu.Lock()
+	defer columnsMu.Unlock()
+	for _, name := range names {
+		columns[table] = append(columns[table], envelope.Column{Table: table, Column: name, Legacy: legacy})
+	}
+}
+
+// 查询记录的凭证字段中保存的外部存储引用
+func References(db *gorm.DB, table string, id uint) []string {
+	columnsMu.RLock()
+	list := columns[table]
+	columnsMu.RUnlock()
+	if len(list) == 0 {
+		return nil
+	}
+	names := make([]string, 0, len(list))
+	for _, column := range list {
+		names = append(names, column.Column)
+	}
+	row := map[string]interface{}{}
+	if err := db.Table(table).Select(names).Where("id = ?", id).Take(&row).Error; err != nil {
+		return nil
+	}
+	var refs []string
+	for _, name := range names {
+		if value := fmt.Sprint(valueOf(row[name])); IsReference(value) {
+			refs = append(refs, value)
+		}
+	}
+	return refs
+}
+
+// 记录修改后释放不再被引用的外部存储密钥，before 为修改前查询的引用
+func ReleaseUnused(ctx context.Context, db *gorm.DB, table string, id uint, before []string) error {
+	if len(before) == 0 {
+		return nil
+	}
+	current := map[string]bool{}
+	for _, ref := range References(db, table, id) {
+		current[ref] = true
+	}
+	var unused []string
+	for _, ref := range before {
+		if !current[ref] {
+			unused = append(unused, ref)
+		}
+	}
+	return Release(ctx, unused...)
+}
+
+// 将已注册凭证字段中不属于当前存储后端的数据迁移到当前后端；
+// 以原值为条件逐行更新，迁移成功后释放原后端中的密钥
+func Migrate(ctx context.Context, db *gorm.DB) (*MigrateResult, error) {
+	store := Active()
+	res := &MigrateResult{Backend: store.Name()}
+	columnsMu.RLock()
+	var list []envelope.Column
+	for _, table := range columns {
+		list = append(list, table...)
+	}
+	columnsMu.RUnlock()
+
+	for _, column := range list {
+		err := envelope.ScanColumn(db, column, func(id uint64, value string) error {
+			if store.Owns(value) {
+				return nil
+			}
+			plaintext, err := Open(ctx, value, column.Legacy)
+			if err != nil {
+				res.Failed++
+				return nil
+			}
+			stored, err := store.Put(ctx, newPath(column.Table, column.Column), plaintext)
+			if err != nil {
+				return err
+			}
+			update := db.Table(column.Table).Where(map[string]interface{}{"id": id, column.Column: value}).
+				UpdateColumn(column.Column, stored)
+			if update.Error != nil {
+				_ = Release(ctx, stored)
+				return update.Error
+			}
+			if update.RowsAffected == 0 {
+				res.Skipped++
+				return Release(ctx, stored)
+			}
+			res.Migrated++
+			return Release(ctx, value)
+		})
+		if err != nil {
+			return res, err
+		}
+	}
+	return res, nil
+}
+
+func valueOf(value interface{}) interface{} {
+	if data, ok := value.([]byte); ok {
+		return string(data)
+	}
+	if value == nil {
+		return ""
+	}
+	return value
+}
+
+type sealedKey struct{}
+
+// 一条语句写库时保存到存储后端的凭证，按字段和明文缓存，语句失败时释放
+type sealed struct {
+	mu     sync.Mutex
+	values map[string]string
+}
+
+// 注册 GORM 回调：新增、修改语句失败时释放本次写入外部存储的凭证，避免遗留无记录引用的 Vault 密钥
+func Register(db *gorm.DB) error {
+	callback := db.Callback()
+	if err := callback.Create().Before("gorm:create").Register("secretstore:before_create", beforeWrite); err != nil {
+		return err
+	}
+	if err := callback.Create().After("gorm:create").Register("secretstore:create", afterWrite); err != nil {
+		return err
+	}
+	if err := callback.Update().Before("gorm:update").Register("secretstore:before_update", beforeWrite); err != nil {
+		return err
+	}
+	return callback.Update().After("gorm:update").Register("secretstore:update", afterWrite)
+}
+
+func beforeWrite(db *gorm.DB) {
+	if db.Statement.Schema == nil || !hasSecretField(db.Statement.Schema) {
+		return
+	}
+	db.Statement.Context = context.WithValue(db.Statement.Context, sealedKey{}, &sealed{values: map[string]string{}})
+}
+
+func afterWrite(db *gorm.DB) {
+	s, ok := db.Statement.Context.Value(sealedKey{}).(*sealed)
+	if !ok || db.Error == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	refs := make([]string, 0, len(s.values))
+	for _, ref := range s.values {
+		refs = append(refs, ref)
+	}
+	if err := Release(context.Background(), refs...); err != nil {
+		_ = db.AddError(fmt.Errorf("释放外部存储凭证失败: %v", err))
+	}
+}
+
+func hasSecretField(s *schema.Schema) bool {
+	for _, field := range s.Fields {
+		if _, ok := field.Serializer.(secretSerializer); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// 在模型字段上声明 `gorm:"serializer:secret"` 后，写库时保存到当前存储后端、字段中保存密文或引用；
+// 读库时解密数据库中的密文，外部存储的引用原样保留，由使用方通过 Resolve 读取；历史明文数据读取时原样返回
+type secretSerializer struct{}
+
+func (secretSerializer) Scan(ctx context.Context, field *schema.Field, dst reflect.Value, dbValue interface{}) error {
+	var value string
+	switch v := dbValue.(type) {
+	case nil:
+	case string:
+		value = v
+	case []byte:
+		value = string(v)
+	default:
+		return fmt.Errorf("字段 %s 不支持的凭证类型 %T", field.Name, dbValue)
+	}
+	if IsReference(value) {
+		return field.Set(ctx, dst, value)
+	}
+	plaintext, err := Open(ctx, value, envelope.LegacyPlain)
+	if err != nil {
+		return fmt.Errorf("读取字段 %s 失败: %v", field.Name, err)
+	}
+	return field.Set(ctx, dst, plaintext)
+}
+
+func (secretSerializer) Value(ctx context.Context, field *schema.Field, dst reflect.Value, fieldValue interface{}) (interface{}, error) {
+	value, ok := fieldValue.(string)
+	if !ok {
+		return nil, fmt.Errorf("字段 %s 不支持的凭证类型 %T", field.Name, fieldValue)
+	}
+	// 读库时保留的引用原样写回，不重复保存
+	if IsReference(value) {
+		return value, nil
+	}
+	s, ok := ctx.Value(sealedKey{}).(*sealed)
+	if !ok {
+		return Seal(ctx, field.Schema.Table, field.DBName, value)
+	}
+	// 记录 SQL 日志时会再次取值，同一语句中相同的明文只保存一次
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key := field.Schema.Table + "." + field.DBName + "\x00" + value
+	if stored, ok := s.values[key]; ok {
+		return stored, nil
+	}
+	stored, err := Seal(ctx, field.Schema.Table, field.DBName, value)
+	if err != nil {
+		return nil, err
+	}
+	s.values[key] = stored
+	return stored, nil
+}
@@ -0,0 +1,187 @@
+// 凭证密钥存储
+// author xiaoRui
+
+// Package secretstore 凭证类敏感字段（主机密码、私钥、云厂商 AK/SK 等）的存储后端
+// db 后端使用 pkg/envelope 加密后保存在数据库字段中；vault 后端将明文保存到 HashiCorp Vault KV v2，
+// 数据库字段只保存 "vault:<路径>" 形式的引用。读取时按字段值的格式选择后端，切换写入后端后历史数据仍可读取
+package secretstore
+
+import (
+	"context"
+	"crypto/rand"
+	"dodevops-api/common/config"
+	"dodevops-api/pkg/envelope"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// 存储后端名称
+const (
+	BackendDB    = "db"
+	BackendVault = "vault"
+)
+
+// Store 密钥存储后端
+type Store interface {
+	// 后端名称
+	Name() string
+	// 保存密钥，path 为建议的存储路径，返回保存到数据库字段中的值
+	Put(ctx context.Context, path, value string) (string, error)
+	// 根据数据库字段中的值读取密钥
+	Get(ctx context.Context, ref string) (string, error)
+	// 删除密钥
+	Delete(ctx context.Context, ref string) error
+	// 判断数据库字段中的值是否由该后端保存
+	Owns(ref string) bool
+}
+
+var ErrVaultNotConfigured = errors.New("未配置 Vault，无法读取保存在 Vault 中的凭证")
+
+var (
+	mu     sync.RWMutex
+	active Store = dbStore{}
+	vault  Store
+)
+
+func init() {
+	envelope.RegisterExternalPrefix(vaultPrefix)
+}
+
+// 按配置初始化存储后端，服务启动时调用
+func Setup(cfg config.SecretStoreConfig) error {
+	var v Store
+	if store, err := newVaultStore(cfg.Vault); err != nil {
+		return err
+	} else if store != nil {
+		v = store
+	}
+
+	var a Store = dbStore{}
+	switch cfg.Backend {
+	case "", BackendDB:
+	case BackendVault:
+		if v == nil {
+			return errors.New("凭证存储后端为 vault 时必须配置 Vault 地址和令牌")
+		}
+		a = v
+	default:
+		return fmt.Errorf("不支持的凭证存储后端: %s", cfg.Backend)
+	}
+
+	mu.Lock()
+	active, vault = a, v
+	mu.Unlock()
+	return nil
+}
+
+// 当前写入使用的存储后端
+func Active() Store {
+	mu.RLock()
+	defer mu.RUnlock()
+	return active
+}
+
+// 根据数据库字段中的值选择存储后端
+func storeOf(ref string) (Store, error) {
+	if !IsReference(ref) {
+		return dbStore{}, nil
+	}
+	mu.RLock()
+	defer mu.RUnlock()
+	if vault == nil {
+		return nil, ErrVaultNotConfigured
+	}
+	return vault, nil
+}
+
+// 判断数据库字段中的值是否为外部存储的引用
+func IsReference(value string) bool {
+	return strings.HasPrefix(value, vaultPrefix)
+}
+
+// 保存字段的密钥，返回写入数据库字段的值；空值不保存
+func Seal(ctx context.Context, table, column, value string) (string, error) {
+	if value == "" {
+		return "", nil
+	}
+	return Active().Put(ctx, newPath(table, column), value)
+}
+
+// 读取数据库字段中保存的密钥，legacy 为该字段信封加密之前的保存方式
+func Open(ctx context.Context, stored string, legacy envelope.Legacy) (string, error) {
+	if stored == "" {
+		return "", nil
+	}
+	if !IsReference(stored) {
+		return envelope.DecryptValue(stored, legacy)
+	}
+	store, err := storeOf(stored)
+	if err != nil {
+		return "", err
+	}
+	return store.Get(ctx, stored)
+}
+
+// 解析 secret 序列化器读出的字段值：外部存储的引用从对应后端读取，其余值原样返回。
+// 读库时不访问外部存储，避免列表查询逐行请求 Vault，在建立连接等实际使用凭证的地方调用
+func Resolve(ctx context.Context, value string) (string, error) {
+	if !IsReference(value) {
+		return value, nil
+	}
+	store, err := storeOf(value)
+	if err != nil {
+		return "", err
+	}
+	return store.Get(ctx, value)
+}
+
+// 删除外部存储中的密钥，数据库中保存的密文随记录一起删除，无需处理
+func Release(ctx context.Context, refs ...string) error {
+	var errs []error
+	for _, ref := range refs {
+		if !IsReference(ref) {
+			continue
+		}
+		store, err := storeOf(ref)
+		if err == nil {
+			err = store.Delete(ctx, ref)
+		}
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// 生成存储路径：<表名>/<字段名>/<随机串>，同一记录每次保存使用新路径，保存成功后再释放旧路径
+func newPath(table, column string) string {
+	buf := make([]byte, 8)
+	_, _ = rand.Read(buf)
+	return table + "/" + column + "/" + hex.EncodeToString(buf)
+}
+
+// db 后端：使用主密钥信封加密后保存在数据库字段中
+type dbStore struct{}
+
+func (dbStore) Name() string {
+	return BackendDB
+}
+
+func (dbStore) Put(ctx context.Context, path, value string) (string, error) {
+	return envelope.Encrypt(value)
+}
+
+func (dbStore) Get(ctx context.Context, ref string) (string, error) {
+	return envelope.Decrypt(ref)
+}
+
+func (dbStore) Delete(ctx context.Context, ref string) error {
+	return nil
+}
+
+func (dbStore) Owns(ref string) bool {
+	return !IsReference(ref)
+}
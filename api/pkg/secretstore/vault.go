@@ -0,0 +1,146 @@
+// HashiCorp Vault KV v2 存储后端
+// author xiaoRui
+
+package secretstore
+
+import (
+	"bytes"
+	"context"
+	"dodevops-api/common/config"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// Vault 引用前缀，完整格式为 vault:<挂载路径下的密钥路径>
+const vaultPrefix = "vault:"
+
+// 密钥在 Vault 中保存的字段名
+const vaultField = "value"
+
+type vaultStore struct {
+	address    string
+	token      string
+	namespace  string
+	mount      string
+	pathPrefix string
+	client     *http.Client
+}
+
+// 创建 Vault 后端，未配置地址时返回 nil
+func newVaultStore(cfg config.VaultConfig) (*vaultStore, error) {
+	address := cfg.Address
+	if address == "" {
+		address = os.Getenv("VAULT_ADDR")
+	}
+	if address == "" {
+		return nil, nil
+	}
+	token := cfg.Token
+	if token == "" {
+		token = os.Getenv("VAULT_TOKEN")
+	}
+	if token == "" {
+		return nil, fmt.Errorf("未配置 Vault 令牌，请设置 secretStore.vault.token 或环境变量 VAULT_TOKEN")
+	}
+	store := &vaultStore{
+		address:    strings.TrimRight(address, "/"),
+		token:      token,
+		namespace:  cfg.Namespace,
+		mount:      strings.Trim(cfg.Mount, "/"),
+		pathPrefix: strings.Trim(cfg.PathPrefix, "/"),
+		client:     &http.Client{Timeout: 10 * time.Second},
+	}
+	if store.mount == "" {
+		store.mount = "secret"
+	}
+	if store.pathPrefix == "" {
+		store.pathPrefix = "autoops"
+	}
+	if cfg.TimeoutSeconds > 0 {
+		store.client.Timeout = time.Duration(cfg.TimeoutSeconds) * time.Second
+	}
+	return store, nil
+}
+
+func (s *vaultStore) Name() string {
+	return BackendVault
+}
+
+func (s *vaultStore) Put(ctx context.Context, path, value string) (string, error) {
+	path = s.pathPrefix + "/" + path
+	body := map[string]interface{}{"data": map[string]string{vaultField: value}}
+	if err := s.do(ctx, http.MethodPost, "data/"+path, body, nil); err != nil {
+		return "", err
+	}
+	return vaultPrefix + path, nil
+}
+
+func (s *vaultStore) Get(ctx context.Context, ref string) (string, error) {
+	var resp struct {
+		Data struct {
+			Data map[string]string `json:"data"`
+		} `json:"data"`
+	}
+	if err := s.do(ctx, http.MethodGet, "data/"+strings.TrimPrefix(ref, vaultPrefix), nil, &resp); err != nil {
+		return "", err
+	}
+	value, ok := resp.Data.Data[vaultField]
+	if !ok {
+		return "", fmt.Errorf("Vault 密钥 %s 缺少字段 %s", ref, vaultField)
+	}
+	return value, nil
+}
+
+// 删除密钥的全部版本
+func (s *vaultStore) Delete(ctx context.Context, ref string) error {
+	return s.do(ctx, http.MethodDelete, "metadata/"+strings.TrimPrefix(ref, vaultPrefix), nil, nil)
+}
+
+func (s *vaultStore) Owns(ref string) bool {
+	return IsReference(ref)
+}
+
+// 调用 Vault HTTP API
+func (s *vaultStore) do(ctx context.Context, method, path string, body, out interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, s.address+"/v1/"+s.mount+"/"+path, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("X-Vault-Token", s.token)
+	if s.namespace != "" {
+		req.Header.Set("X-Vault-Namespace", s.namespace)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("请求 Vault 失败: %v", err)
+	}
+	defer resp.Body.Close()
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		var vaultErr struct {
+			Errors []string `json:"errors"`
+		}
+		_ = json.Unmarshal(data, &vaultErr)
+		return fmt.Errorf("Vault 返回 %d: %s", resp.StatusCode, strings.Join(vaultErr.Errors, "; "))
+	}
+	if out == nil || len(data) == 0 {
+		return nil
+	}
+	return json.Unmarshal(data, out)
+}
@@ -29,6 +29,58 @@ func RegisterCmdbRoutes(router *gin.RouterGroup) {
 	router.POST("/cmdb/hostimport", controller.NewCmdbHostController().ImportHostsFromExcel)      // 从Excel导入主机
 	router.GET("/cmdb/hosttemplate", controller.NewCmdbHostController().DownloadHostTemplate)     // 下载主机导入模板
 	router.POST("/cmdb/hostsync", controller.NewCmdbHostController().SyncHostInfo)                // 同步主机基本信息
+	router.GET("/cmdb/hostexport", controller.NewCmdbHostController().ExportHosts)                // 按条件导出主机(XLSX/CSV/JSON)
+	router.GET("/cmdb/hostsearch", controller.SearchCmdbHostsByAttrs)                             // 按自定义属性查询主机
+	router.POST("/cmdb/hosttransfer/import", controller.NewCmdbHostController().ImportHosts)      // 导入主机(按实例ID/SSH地址更新)
+	// CI类型（自定义属性模型）
+	router.GET("/cmdb/citype/list", controller.GetCmdbCITypeList)            // 获取CI类型列表
+	router.POST("/cmdb/citype/add", controller.CreateCmdbCIType)             // 新增CI类型
+	router.PUT("/cmdb/citype/update", controller.UpdateCmdbCIType)           // 修改CI类型
+	router.DELETE("/cmdb/citype/delete", controller.DeleteCmdbCIType)        // 删除CI类型
+	router.POST("/cmdb/citype/field/add", controller.CreateCmdbCIField)      // 新增自定义属性
+	router.PUT("/cmdb/citype/field/update", controller.UpdateCmdbCIField)    // 修改自定义属性
+	router.DELETE("/cmdb/citype/field/delete", controller.DeleteCmdbCIField) // 删除自定义属性
+	// 配置项关系
+	router.POST("/cmdb/relation/add", controller.CreateCmdbRelation)         // 新增配置项关系
+	router.DELETE("/cmdb/relation/delete", controller.DeleteCmdbRelation)    // 删除配置项关系
+	router.GET("/cmdb/relation/graph", controller.GetCmdbRelationGraph)      // 查询配置项关系图
+	router.GET("/cmdb/relation/impact", controller.GetCmdbRelationImpact)    // 配置项影响分析
+	router.POST("/cmdb/relation/discover", controller.DiscoverCmdbRelations) // 自动发现配置项关系
+	// 资产变更历史
+	router.GET("/cmdb/history/list", controller.GetCmdbChangeHistory) // 查询资产变更历史
+	router.GET("/cmdb/history/asof", controller.GetCmdbSnapshot)      // 查询资产在某一时刻的状态
+	router.GET("/cmdb/history/diff", controller.GetCmdbSnapshotDiff)  // 对比资产两个时刻的状态
+	// 主机标签
+	router.GET("/cmdb/hostlabel/list", controller.GetCmdbHostLabels)         // 查询主机标签
+	router.PUT("/cmdb/hostlabel/update", controller.UpdateCmdbHostLabels)    // 设置主机标签
+	router.GET("/cmdb/hostlabel/values", controller.GetCmdbHostLabelValues)  // 查询已使用的标签
+	router.GET("/cmdb/hostlabel/select", controller.SelectCmdbHostsByLabels) // 按标签选择器查询主机
+	// 动态主机分组
+	router.GET("/cmdb/dynamicgroup/list", controller.GetCmdbDynamicGroupList)     // 查询动态分组
+	router.POST("/cmdb/dynamicgroup/add", controller.CreateCmdbDynamicGroup)      // 新增动态分组
+	router.PUT("/cmdb/dynamicgroup/update", controller.UpdateCmdbDynamicGroup)    // 修改动态分组
+	router.DELETE("/cmdb/dynamicgroup/delete", controller.DeleteCmdbDynamicGroup) // 删除动态分组
+	router.GET("/cmdb/dynamicgroup/hosts", controller.GetCmdbDynamicGroupHosts)   // 查询动态分组当前匹配的主机
+	// 批量执行命令
+	router.POST("/cmdb/batch/execute", controller.ExecuteCmdbBatchCommand)  // 批量执行命令
+	router.POST("/cmdb/batch/rerun", controller.RerunCmdbBatchCommand)      // 重新执行批量命令
+	router.GET("/cmdb/batch/list", controller.GetCmdbBatchCommandList)      // 获取批量命令执行记录
+	router.GET("/cmdb/batch/info", controller.GetCmdbBatchCommandInfo)      // 获取批量命令执行详情
+	router.GET("/cmdb/batch/stream/:id", controller.StreamCmdbBatchCommand) // 批量命令实时输出(SSE)
+	// 主机凭证轮换
+	router.GET("/cmdb/credential/rotation/list", controller.GetCmdbCredentialRotationList)       // 获取凭证轮换策略列表
+	router.POST("/cmdb/credential/rotation/add", controller.CreateCmdbCredentialRotation)        // 新增凭证轮换策略
+	router.PUT("/cmdb/credential/rotation/update", controller.UpdateCmdbCredentialRotation)      // 修改凭证轮换策略
+	router.DELETE("/cmdb/credential/rotation/delete", controller.DeleteCmdbCredentialRotation)   // 删除凭证轮换策略
+	router.POST("/cmdb/credential/rotation/run", controller.RunCmdbCredentialRotation)           // 立即执行凭证轮换
+	router.GET("/cmdb/credential/rotation/runlist", controller.GetCmdbCredentialRotationRunList) // 获取凭证轮换执行记录
+	router.GET("/cmdb/credential/rotation/runinfo", controller.GetCmdbCredentialRotationRunInfo) // 获取凭证轮换执行详情及主机报告
+	router.POST("/cmdb/credential/distribute", controller.DistributeCmdbCredential)              // 分发公钥到资产分组
+	// 主机SSH密钥
+	router.GET("/cmdb/hostkey/list", controller.GetCmdbHostKeyList)     // 获取主机密钥列表
+	router.POST("/cmdb/hostkey/accept", controller.AcceptCmdbHostKey)   // 确认主机密钥
+	router.POST("/cmdb/hostkey/pin", controller.PinCmdbHostKey)         // 固定主机密钥
+	router.DELETE("/cmdb/hostkey/delete", controller.DeleteCmdbHostKey) // 删除主机密钥
 	// 云主机管理
 	router.POST("/cmdb/hostcloudcreatealiyun", controller.NewCmdbHostCloudController().CreateAliyunHost)                          // 创建阿里云主机
 	router.POST("/cmdb/hostcloudcreatetencent", controller.NewCmdbHostCloudController().CreateTencentHost)                        // 创建腾讯云主机
@@ -22,6 +22,9 @@ func RegisterConfigCenterRoutes(router *gin.RouterGroup) {
 	router.POST("/config/ecsauthadd", ecsAuthCtrl.CreateEcsAuth)      // 创建凭据
 	router.PUT("/config/ecsauthupdate", ecsAuthCtrl.UpdateEcsAuth)    // 更新凭据
 	router.DELETE("/config/ecsauthdelete", ecsAuthCtrl.DeleteEcsAuth) // 删除凭据
+	router.POST("/config/ecsauthgenerate", ecsAuthCtrl.GenerateEcsKeyAuth) // 生成密钥对并创建密钥凭据
+	router.GET("/config/ecsauthpubkey", ecsAuthCtrl.GetEcsAuthPublicKey)   // 获取密钥凭据的公钥
+	router.GET("/config/ecsauthusage", ecsAuthCtrl.GetEcsAuthUsage)        // 获取凭据的引用和使用情况
 	// 账号认证管理
 	router.POST("/config/accountauth", accountAuthCtrl.Create)   // 创建账号
 	router.PUT("/config/accountauth", accountAuthCtrl.Update)   // 更新账号
@@ -31,6 +34,7 @@ func RegisterConfigCenterRoutes(router *gin.RouterGroup) {
 	router.POST("/config/accountauth/decrypt", accountAuthCtrl.DecryptPassword) // 解密密码
 	router.GET("/config/accountauth/type", accountAuthCtrl.GetByType)    // 根据类型查询账号
 	router.GET("/config/accountauth/alias", accountAuthCtrl.GetByAlias) // 根据别名查询账号
+	router.GET("/config/accountauth/usage", accountAuthCtrl.GetUsage)   // 获取账号的引用和使用情况
 
 	// 密钥管理
 	router.POST("/config/keymanage", keyManageCtrl.Create)              // 创建密钥
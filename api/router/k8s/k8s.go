@@ -101,18 +101,18 @@ func RegisterK8sRoutes(router *gin.RouterGroup) {
 	router.GET("/k8s/cluster/:id/namespaces/:namespaceName/events", middleware.AuthMiddleware(), k8sEventsCtrl.GetEvents) // 获取命名空间事件列表
 
 	// K8s容器终端路由
-	router.GET("/k8s/cluster/:id/namespaces/:namespaceName/pods/:podName/terminal", middleware.AuthMiddleware(), k8sTerminalCtrl.ConnectPodTerminal) // 连接容器终端
-	router.GET("/k8s/cluster/:id/namespaces/:namespaceName/pods/:podName/containers", middleware.AuthMiddleware(), k8sTerminalCtrl.GetPodContainers) // 获取Pod容器列表
+	router.GET("/k8s/cluster/:id/namespaces/:namespaceName/pods/:podName/terminal", middleware.AuthMiddleware(), kubeClusterCtrl.ClusterScope, k8sTerminalCtrl.ConnectPodTerminal) // 连接容器终端
+	router.GET("/k8s/cluster/:id/namespaces/:namespaceName/pods/:podName/containers", middleware.AuthMiddleware(), kubeClusterCtrl.ClusterScope, k8sTerminalCtrl.GetPodContainers) // 获取Pod容器列表
 
 	// K8s容器文件管理路由
-	router.GET("/k8s/cluster/:id/namespaces/:namespaceName/pods/:podName/files/list", middleware.AuthMiddleware(), k8sTerminalCtrl.GetPodFileList)           // 获取容器内文件列表
-	router.DELETE("/k8s/cluster/:id/namespaces/:namespaceName/pods/:podName/files", middleware.AuthMiddleware(), k8sTerminalCtrl.DeletePodFile)              // 删除容器内文件
-	router.GET("/k8s/cluster/:id/namespaces/:namespaceName/pods/:podName/files/content", middleware.AuthMiddleware(), k8sTerminalCtrl.GetPodFileContent)     // 获取容器内文件内容
-	router.PUT("/k8s/cluster/:id/namespaces/:namespaceName/pods/:podName/files/content", middleware.AuthMiddleware(), k8sTerminalCtrl.UpdatePodFileContent)  // 更新容器内文件内容
-	router.POST("/k8s/cluster/:id/namespaces/:namespaceName/pods/:podName/files/upload", middleware.AuthMiddleware(), k8sTerminalCtrl.UploadPodFile)         // 上传文件到容器
-	router.GET("/k8s/cluster/:id/namespaces/:namespaceName/pods/:podName/files/download", middleware.AuthMiddleware(), k8sTerminalCtrl.DownloadPodFile)      // 下载容器内的文件
-	router.POST("/k8s/cluster/:id/namespaces/:namespaceName/pods/:podName/files/directory", middleware.AuthMiddleware(), k8sTerminalCtrl.CreatePodDirectory) // 创建容器内目录
-	router.POST("/k8s/cluster/:id/namespaces/:namespaceName/pods/:podName/hot-reload", middleware.AuthMiddleware(), k8sTerminalCtrl.HotReloadPod)            // 发送热加载信号
+	router.GET("/k8s/cluster/:id/namespaces/:namespaceName/pods/:podName/files/list", middleware.AuthMiddleware(), kubeClusterCtrl.ClusterScope, k8sTerminalCtrl.GetPodFileList)           // 获取容器内文件列表
+	router.DELETE("/k8s/cluster/:id/namespaces/:namespaceName/pods/:podName/files", middleware.AuthMiddleware(), kubeClusterCtrl.ClusterScope, k8sTerminalCtrl.DeletePodFile)              // 删除容器内文件
+	router.GET("/k8s/cluster/:id/namespaces/:namespaceName/pods/:podName/files/content", middleware.AuthMiddleware(), kubeClusterCtrl.ClusterScope, k8sTerminalCtrl.GetPodFileContent)     // 获取容器内文件内容
+	router.PUT("/k8s/cluster/:id/namespaces/:namespaceName/pods/:podName/files/content", middleware.AuthMiddleware(), kubeClusterCtrl.ClusterScope, k8sTerminalCtrl.UpdatePodFileContent)  // 更新容器内文件内容
+	router.POST("/k8s/cluster/:id/namespaces/:namespaceName/pods/:podName/files/upload", middleware.AuthMiddleware(), kubeClusterCtrl.ClusterScope, k8sTerminalCtrl.UploadPodFile)         // 上传文件到容器
+	router.GET("/k8s/cluster/:id/namespaces/:namespaceName/pods/:podName/files/download", middleware.AuthMiddleware(), kubeClusterCtrl.ClusterScope, k8sTerminalCtrl.DownloadPodFile)      // 下载容器内的文件
+	router.POST("/k8s/cluster/:id/namespaces/:namespaceName/pods/:podName/files/directory", middleware.AuthMiddleware(), kubeClusterCtrl.ClusterScope, k8sTerminalCtrl.CreatePodDirectory) // 创建容器内目录
+	router.POST("/k8s/cluster/:id/namespaces/:namespaceName/pods/:podName/hot-reload", middleware.AuthMiddleware(), kubeClusterCtrl.ClusterScope, k8sTerminalCtrl.HotReloadPod)            // 发送热加载信号
 
 	// ===================== K8s监控API路由 =====================
 
@@ -205,34 +205,34 @@ func RegisterK8sRoutes(router *gin.RouterGroup) {
 	// ===================== K8s配置管理路由 =====================
 
 	// ConfigMap基础CRUD路由
-	router.GET("/k8s/cluster/:id/namespaces/:namespaceName/configmaps", middleware.AuthMiddleware(), k8sConfigCtrl.GetConfigMaps)                     // 获取ConfigMap列表
-	router.GET("/k8s/cluster/:id/namespaces/:namespaceName/configmaps/:configMapName", middleware.AuthMiddleware(), k8sConfigCtrl.GetConfigMapDetail) // 获取ConfigMap详情
-	router.POST("/k8s/cluster/:id/namespaces/:namespaceName/configmaps", middleware.AuthMiddleware(), k8sConfigCtrl.CreateConfigMap)                  // 创建ConfigMap
-	router.PUT("/k8s/cluster/:id/namespaces/:namespaceName/configmaps/:configMapName", middleware.AuthMiddleware(), k8sConfigCtrl.UpdateConfigMap)    // 更新ConfigMap
-	router.DELETE("/k8s/cluster/:id/namespaces/:namespaceName/configmaps/:configMapName", middleware.AuthMiddleware(), k8sConfigCtrl.DeleteConfigMap) // 删除ConfigMap
+	router.GET("/k8s/cluster/:id/namespaces/:namespaceName/configmaps", middleware.AuthMiddleware(), kubeClusterCtrl.ClusterScope, k8sConfigCtrl.GetConfigMaps)                     // 获取ConfigMap列表
+	router.GET("/k8s/cluster/:id/namespaces/:namespaceName/configmaps/:configMapName", middleware.AuthMiddleware(), kubeClusterCtrl.ClusterScope, k8sConfigCtrl.GetConfigMapDetail) // 获取ConfigMap详情
+	router.POST("/k8s/cluster/:id/namespaces/:namespaceName/configmaps", middleware.AuthMiddleware(), kubeClusterCtrl.ClusterScope, k8sConfigCtrl.CreateConfigMap)                  // 创建ConfigMap
+	router.PUT("/k8s/cluster/:id/namespaces/:namespaceName/configmaps/:configMapName", middleware.AuthMiddleware(), kubeClusterCtrl.ClusterScope, k8sConfigCtrl.UpdateConfigMap)    // 更新ConfigMap
+	router.DELETE("/k8s/cluster/:id/namespaces/:namespaceName/configmaps/:configMapName", middleware.AuthMiddleware(), kubeClusterCtrl.ClusterScope, k8sConfigCtrl.DeleteConfigMap) // 删除ConfigMap
 
 	// ConfigMap YAML管理路由
-	router.GET("/k8s/cluster/:id/namespaces/:namespaceName/configmaps/:configMapName/yaml", middleware.AuthMiddleware(), k8sConfigCtrl.GetConfigMapYaml)    // 获取ConfigMap YAML
-	router.PUT("/k8s/cluster/:id/namespaces/:namespaceName/configmaps/:configMapName/yaml", middleware.AuthMiddleware(), k8sConfigCtrl.UpdateConfigMapYaml) // 更新ConfigMap YAML
+	router.GET("/k8s/cluster/:id/namespaces/:namespaceName/configmaps/:configMapName/yaml", middleware.AuthMiddleware(), kubeClusterCtrl.ClusterScope, k8sConfigCtrl.GetConfigMapYaml)    // 获取ConfigMap YAML
+	router.PUT("/k8s/cluster/:id/namespaces/:namespaceName/configmaps/:configMapName/yaml", middleware.AuthMiddleware(), kubeClusterCtrl.ClusterScope, k8sConfigCtrl.UpdateConfigMapYaml) // 更新ConfigMap YAML
 
 	// Secret基础CRUD路由
-	router.GET("/k8s/cluster/:id/namespaces/:namespaceName/secrets", middleware.AuthMiddleware(), k8sConfigCtrl.GetSecrets)                  // 获取Secret列表
-	router.GET("/k8s/cluster/:id/namespaces/:namespaceName/secrets/:secretName", middleware.AuthMiddleware(), k8sConfigCtrl.GetSecretDetail) // 获取Secret详情
-	router.POST("/k8s/cluster/:id/namespaces/:namespaceName/secrets", middleware.AuthMiddleware(), k8sConfigCtrl.CreateSecret)               // 创建Secret
-	router.PUT("/k8s/cluster/:id/namespaces/:namespaceName/secrets/:secretName", middleware.AuthMiddleware(), k8sConfigCtrl.UpdateSecret)    // 更新Secret
-	router.DELETE("/k8s/cluster/:id/namespaces/:namespaceName/secrets/:secretName", middleware.AuthMiddleware(), k8sConfigCtrl.DeleteSecret) // 删除Secret
+	router.GET("/k8s/cluster/:id/namespaces/:namespaceName/secrets", middleware.AuthMiddleware(), kubeClusterCtrl.ClusterScope, k8sConfigCtrl.GetSecrets)                  // 获取Secret列表
+	router.GET("/k8s/cluster/:id/namespaces/:namespaceName/secrets/:secretName", middleware.AuthMiddleware(), kubeClusterCtrl.ClusterScope, k8sConfigCtrl.GetSecretDetail) // 获取Secret详情
+	router.POST("/k8s/cluster/:id/namespaces/:namespaceName/secrets", middleware.AuthMiddleware(), kubeClusterCtrl.ClusterScope, k8sConfigCtrl.CreateSecret)               // 创建Secret
+	router.PUT("/k8s/cluster/:id/namespaces/:namespaceName/secrets/:secretName", middleware.AuthMiddleware(), kubeClusterCtrl.ClusterScope, k8sConfigCtrl.UpdateSecret)    // 更新Secret
+	router.DELETE("/k8s/cluster/:id/namespaces/:namespaceName/secrets/:secretName", middleware.AuthMiddleware(), kubeClusterCtrl.ClusterScope, k8sConfigCtrl.DeleteSecret) // 删除Secret
 
 	// Secret YAML管理路由
-	router.GET("/k8s/cluster/:id/namespaces/:namespaceName/secrets/:secretName/yaml", middleware.AuthMiddleware(), k8sConfigCtrl.GetSecretYaml)    // 获取Secret YAML
-	router.PUT("/k8s/cluster/:id/namespaces/:namespaceName/secrets/:secretName/yaml", middleware.AuthMiddleware(), k8sConfigCtrl.UpdateSecretYaml) // 更新Secret YAML
+	router.GET("/k8s/cluster/:id/namespaces/:namespaceName/secrets/:secretName/yaml", middleware.AuthMiddleware(), kubeClusterCtrl.ClusterScope, k8sConfigCtrl.GetSecretYaml)    // 获取Secret YAML
+	router.PUT("/k8s/cluster/:id/namespaces/:namespaceName/secrets/:secretName/yaml", middleware.AuthMiddleware(), kubeClusterCtrl.ClusterScope, k8sConfigCtrl.UpdateSecretYaml) // 更新Secret YAML
 
 	// ===================== K8s CRD管理路由 =====================
-	router.GET("/k8s/cluster/:id/crds/groups", middleware.AuthMiddleware(), k8sCRDCtrl.GetCRDGroups)                                                                // 获取CRD API Group列表
-	router.GET("/k8s/cluster/:id/crds", middleware.AuthMiddleware(), k8sCRDCtrl.GetCRDList)                                                                         // 获取CRD列表
-	router.GET("/k8s/cluster/:id/namespaces/:namespaceName/crds/:crdName/resources", middleware.AuthMiddleware(), k8sCRDCtrl.GetCustomResourceList)                 // 获取自定义资源列表
-	router.GET("/k8s/cluster/:id/namespaces/:namespaceName/crds/:crdName/resources/:crName", middleware.AuthMiddleware(), k8sCRDCtrl.GetCustomResourceDetail)       // 获取自定义资源详情
-	router.POST("/k8s/cluster/:id/namespaces/:namespaceName/crds/:crdName/resources", middleware.AuthMiddleware(), k8sCRDCtrl.CreateCustomResource)                 // 创建自定义资源
-	router.DELETE("/k8s/cluster/:id/namespaces/:namespaceName/crds/:crdName/resources/:crName", middleware.AuthMiddleware(), k8sCRDCtrl.DeleteCustomResource)       // 删除自定义资源
-	router.GET("/k8s/cluster/:id/namespaces/:namespaceName/crds/:crdName/resources/:crName/yaml", middleware.AuthMiddleware(), k8sCRDCtrl.GetCustomResourceYaml)    // 获取自定义资源 YAML
-	router.PUT("/k8s/cluster/:id/namespaces/:namespaceName/crds/:crdName/resources/:crName/yaml", middleware.AuthMiddleware(), k8sCRDCtrl.UpdateCustomResourceYaml) // 更新自定义资源 YAML
+	router.GET("/k8s/cluster/:id/crds/groups", middleware.AuthMiddleware(), kubeClusterCtrl.ClusterScope, k8sCRDCtrl.GetCRDGroups)                                                                // 获取CRD API Group列表
+	router.GET("/k8s/cluster/:id/crds", middleware.AuthMiddleware(), kubeClusterCtrl.ClusterScope, k8sCRDCtrl.GetCRDList)                                                                         // 获取CRD列表
+	router.GET("/k8s/cluster/:id/namespaces/:namespaceName/crds/:crdName/resources", middleware.AuthMiddleware(), kubeClusterCtrl.ClusterScope, k8sCRDCtrl.GetCustomResourceList)                 // 获取自定义资源列表
+	router.GET("/k8s/cluster/:id/namespaces/:namespaceName/crds/:crdName/resources/:crName", middleware.AuthMiddleware(), kubeClusterCtrl.ClusterScope, k8sCRDCtrl.GetCustomResourceDetail)       // 获取自定义资源详情
+	router.POST("/k8s/cluster/:id/namespaces/:namespaceName/crds/:crdName/resources", middleware.AuthMiddleware(), kubeClusterCtrl.ClusterScope, k8sCRDCtrl.CreateCustomResource)                 // 创建自定义资源
+	router.DELETE("/k8s/cluster/:id/namespaces/:namespaceName/crds/:crdName/resources/:crName", middleware.AuthMiddleware(), kubeClusterCtrl.ClusterScope, k8sCRDCtrl.DeleteCustomResource)       // 删除自定义资源
+	router.GET("/k8s/cluster/:id/namespaces/:namespaceName/crds/:crdName/resources/:crName/yaml", middleware.AuthMiddleware(), kubeClusterCtrl.ClusterScope, k8sCRDCtrl.GetCustomResourceYaml)    // 获取自定义资源 YAML
+	router.PUT("/k8s/cluster/:id/namespaces/:namespaceName/crds/:crdName/resources/:crName/yaml", middleware.AuthMiddleware(), kubeClusterCtrl.ClusterScope, k8sCRDCtrl.UpdateCustomResourceYaml) // 更新自定义资源 YAML
 }
@@ -21,6 +21,7 @@ func InitMonitorRouter(r *gin.RouterGroup) {
 
 	monitorGroup := r.Group("/monitor")
 	monitorGroup.Use(middleware.AuthMiddleware())
+	monitorGroup.Use(middleware.PermissionMiddleware())
 
 	// PrometheusAlert
 	// Template CRUD
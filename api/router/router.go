@@ -5,6 +5,7 @@ import (
 	"dodevops-api/api/system/controller"
 	"dodevops-api/common/config"
 	"dodevops-api/middleware"
+	"dodevops-api/pkg/approval"
 	"dodevops-api/pkg/log"
 
 	"path/filepath"
@@ -70,6 +71,9 @@ func InitRouter() *gin.Engine {
 	// 单独注册WebSocket路由到根路径
 	registerWebSocketRoutes(router)
 
+	// 审批通过的请求通过路由在进程内重放执行
+	approval.SetHandler(router)
+
 	return router
 }
 
@@ -94,6 +98,13 @@ func register(router *gin.Engine) {
 		// 不需要 JWT 的接口
 		apiGroup.GET("/captcha", controller.Captcha)  // 验证码接口
 		apiGroup.POST("/login", controller.Login)    // 登录接口
+		apiGroup.POST("/login/mfa", controller.LoginMfa)                  // 登录二次验证
+		apiGroup.POST("/login/mfa/enroll", controller.EnrollMfaByTicket)  // 登录时绑定认证器
+		apiGroup.POST("/refreshToken", controller.RefreshToken)           // 刷新访问令牌
+		apiGroup.GET("/sso/providers", controller.GetSsoProviderList)     // 单点登录提供者列表
+		apiGroup.GET("/sso/:provider/login", controller.SsoLogin)         // 发起单点登录
+		apiGroup.GET("/sso/:provider/callback", controller.SsoCallback)   // 单点登录回调
+		apiGroup.POST("/sso/exchange", controller.SsoExchange)            // 使用一次性登录码换取登录结果
 		// Agent心跳接口 - 不需要认证
 		apiGroup.POST("/monitor/agent/heartbeat", agentCtrl.UpdateHeartbeat)
                 
@@ -103,7 +114,9 @@ func register(router *gin.Engine) {
 		// 需要 JWT鉴权 的接口
 		jwtGroup := apiGroup.Group("")
 		jwtGroup.Use(middleware.AuthMiddleware())
+		jwtGroup.Use(middleware.PermissionMiddleware())
 		jwtGroup.Use(middleware.LogMiddleware())
+		jwtGroup.Use(middleware.ApprovalMiddleware())
 		{
 			system.RegisterSystemRoutes(jwtGroup)
 			cmdb.RegisterCmdbRoutes(jwtGroup)
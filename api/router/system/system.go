@@ -43,6 +43,8 @@ func RegisterSystemRoutes(router *gin.RouterGroup) {
 	router.GET("/role/vo/list", controller.QuerySysRoleVoList)
 	router.GET("/role/vo/idList", controller.QueryRoleMenuIdList)
 	router.PUT("/role/assignPermissions", controller.AssignPermissions)
+	router.GET("/role/dataScope", controller.GetSysRoleDataScope)
+	router.PUT("/role/dataScope", controller.UpdateSysRoleDataScope)
 	// 用户
 	router.POST("/admin/add", controller.CreateSysAdmin)
 	router.GET("/admin/info", controller.GetSysAdminInfo)
@@ -50,10 +52,25 @@ func RegisterSystemRoutes(router *gin.RouterGroup) {
 	router.DELETE("/admin/delete", controller.DeleteSysAdminById)
 	router.PUT("/admin/updateStatus", controller.UpdateSysAdminStatus)
 	router.PUT("/admin/updatePassword", controller.ResetSysAdminPassword)
+	router.PUT("/admin/unlock", controller.UnlockSysAdmin)
 	router.GET("/admin/list", controller.GetSysAdminList)
 	router.POST("/upload", controller.Upload)
 	router.PUT("/admin/updatePersonal", controller.UpdatePersonal)
 	router.PUT("/admin/updatePersonalPassword", controller.UpdatePersonalPassword)
+	// 个人访问令牌
+	router.POST("/admin/token/add", controller.CreateSysApiToken)
+	router.GET("/admin/token/list", controller.GetSysApiTokenList)
+	router.DELETE("/admin/token/delete", controller.DeleteSysApiToken)
+	router.GET("/admin/mfa/status", controller.GetSysAdminMfaStatus)
+	router.POST("/admin/mfa/enroll", controller.EnrollSysAdminMfa)
+	router.POST("/admin/mfa/activate", controller.ActivateSysAdminMfa)
+	router.POST("/admin/mfa/disable", controller.DisableSysAdminMfa)
+	router.POST("/admin/mfa/recoveryCodes", controller.RegenerateRecoveryCodes)
+	router.POST("/admin/logout", controller.Logout)
+	router.GET("/admin/session/list", controller.GetMySessionList)
+	router.DELETE("/admin/session/delete", controller.DeleteMySession)
+	router.GET("/admin/session/listByAdmin", controller.GetSysAdminSessionList)
+	router.POST("/admin/session/forceLogout", controller.ForceLogout)
 	// 日志
 	router.GET("/sysLoginInfo/list", controller.GetSysLoginInfoList)
 	router.DELETE("/sysLoginInfo/batch/delete", controller.BatchDeleteSysLoginInfo)
@@ -63,4 +80,35 @@ func RegisterSystemRoutes(router *gin.RouterGroup) {
 	router.DELETE("/sysOperationLog/delete", controller.DeleteSysOperationLogById)
 	router.DELETE("/sysOperationLog/batch/delete", controller.BatchDeleteSysOperationLog)
 	router.DELETE("/sysOperationLog/clean", controller.CleanSysOperationLog)
+	router.GET("/sysOperationLog/verify", controller.VerifySysOperationLog)
+	router.GET("/sysOperationLog/export", controller.ExportSysOperationLog)
+	router.GET("/sysOperationLog/archive/list", controller.GetSysOperationLogArchiveList)
+	// 终端会话审计
+	router.GET("/terminal/session/list", controller.GetSysTerminalSessionList)
+	router.GET("/terminal/session/info", controller.GetSysTerminalSessionInfo)
+	router.GET("/terminal/session/play", controller.PlaySysTerminalSession)
+	router.GET("/terminal/command/search", controller.SearchSysTerminalCommand)
+	router.GET("/terminal/session/active", controller.GetActiveSysTerminalSessionList)
+	router.GET("/terminal/session/observe/:id", controller.ObserveSysTerminalSession)
+	router.POST("/terminal/session/terminate", controller.TerminateSysTerminalSession)
+	router.GET("/terminal/rule/list", controller.GetSysCommandRuleList)
+	router.POST("/terminal/rule/add", controller.CreateSysCommandRule)
+	router.PUT("/terminal/rule/update", controller.UpdateSysCommandRule)
+	router.DELETE("/terminal/rule/delete", controller.DeleteSysCommandRule)
+	router.POST("/terminal/rule/check", controller.CheckSysCommandRule)
+	// 审批
+	router.GET("/approval/policy/list", controller.GetSysApprovalPolicyList)
+	router.POST("/approval/policy/add", controller.CreateSysApprovalPolicy)
+	router.PUT("/approval/policy/update", controller.UpdateSysApprovalPolicy)
+	router.DELETE("/approval/policy/delete", controller.DeleteSysApprovalPolicy)
+	router.GET("/approval/conditions", controller.GetSysApprovalConditionList)
+	router.GET("/approval/ticket/list", controller.GetSysApprovalTicketList)
+	router.GET("/approval/ticket/info", controller.GetSysApprovalTicketInfo)
+	router.POST("/approval/ticket/approve", controller.ApproveSysApprovalTicket)
+	router.POST("/approval/ticket/reject", controller.RejectSysApprovalTicket)
+	router.POST("/approval/ticket/comment", controller.CommentSysApprovalTicket)
+	router.POST("/approval/ticket/cancel", controller.CancelSysApprovalTicket)
+	// 敏感字段加密
+	router.GET("/encryption/status", controller.GetSysEncryptionStatus)
+	router.POST("/encryption/rotate", controller.RotateSysEncryption)
 }
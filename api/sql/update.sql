@@ -18,3 +18,501 @@ ALTER TABLE `monitor_alert_rule` ADD COLUMN IF NOT EXISTS `for_duration` varchar
 ALTER TABLE `monitor_alert_rule` ADD COLUMN IF NOT EXISTS `severity` varchar(64) DEFAULT '' COMMENT '告警等级';
 ALTER TABLE `monitor_alert_rule` ADD COLUMN IF NOT EXISTS `summary` varchar(255) DEFAULT '' COMMENT '告警摘要';
 ALTER TABLE `monitor_alert_rule` ADD COLUMN IF NOT EXISTS `description` text COMMENT '告警详细描述';
+
+-- 告警配置菜单：告警模板、路由、规则和数据源接口使用 monitor:alert 权限
+INSERT IGNORE INTO `sys_menu` (`id`, `parent_id`, `menu_name`, `icon`, `value`, `menu_type`, `url`, `menu_status`, `sort`, `create_time`) VALUES
+(254, 101, '告警配置', 'Bell', 'monitor:alert', 2, 'monitor/alarm/rules', 2, 3, NOW());
+
+-- 用户账号来源（local 本地账号 / ldap LDAP、AD 账号 / oidc:{提供者} 单点登录账号）
+ALTER TABLE `sys_admin` ADD COLUMN IF NOT EXISTS `source` varchar(32) DEFAULT 'local' COMMENT '账号来源:local,ldap,oidc:{提供者}';
+
+-- 个人访问令牌
+CREATE TABLE IF NOT EXISTS `sys_api_token` (
+    `id` bigint unsigned NOT NULL AUTO_INCREMENT COMMENT '主键',
+    `admin_id` bigint unsigned NOT NULL COMMENT '所属用户id',
+    `name` varchar(64) NOT NULL COMMENT '令牌名称',
+    `token_prefix` varchar(16) NOT NULL COMMENT '令牌前缀，用于识别',
+    `token_hash` varchar(64) NOT NULL COMMENT '令牌SHA256哈希',
+    `scopes` text COMMENT '授权范围，逗号分隔的权限值',
+    `expire_time` datetime(3) DEFAULT NULL COMMENT '过期时间，为空表示永不过期',
+    `last_used_time` datetime(3) DEFAULT NULL COMMENT '最后使用时间',
+    `last_used_ip` varchar(64) DEFAULT NULL COMMENT '最后使用IP',
+    `create_time` datetime(3) NOT NULL COMMENT '创建时间',
+    PRIMARY KEY (`id`),
+    UNIQUE KEY `idx_sys_api_token_token_hash` (`token_hash`),
+    KEY `idx_sys_api_token_admin_id` (`admin_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='个人访问令牌';
+
+-- 用户二次验证（TOTP）
+CREATE TABLE IF NOT EXISTS `sys_admin_mfa` (
+    `id` bigint unsigned NOT NULL AUTO_INCREMENT COMMENT '主键',
+    `admin_id` bigint unsigned NOT NULL COMMENT '用户id',
+    `secret` varchar(255) NOT NULL COMMENT 'TOTP密钥(加密)',
+    `enabled` tinyint(1) NOT NULL DEFAULT '0' COMMENT '是否已启用',
+    `recovery_codes` text COMMENT '恢复码SHA256哈希，逗号分隔',
+    `last_step` bigint NOT NULL DEFAULT '0' COMMENT '最后一次使用的验证码时间窗口，防止重放',
+    `enable_time` datetime(3) DEFAULT NULL COMMENT '启用时间',
+    `create_time` datetime(3) NOT NULL COMMENT '创建时间',
+    PRIMARY KEY (`id`),
+    UNIQUE KEY `idx_sys_admin_mfa_admin_id` (`admin_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='用户二次验证';
+
+-- 密码策略：密码修改时间、下次登录强制修改密码
+ALTER TABLE `sys_admin` ADD COLUMN IF NOT EXISTS `password_update_time` datetime(3) DEFAULT NULL COMMENT '密码修改时间';
+ALTER TABLE `sys_admin` ADD COLUMN IF NOT EXISTS `password_reset_required` tinyint(1) NOT NULL DEFAULT '0' COMMENT '下次登录需修改密码';
+
+-- 历史密码，用于限制重复使用最近的密码
+CREATE TABLE IF NOT EXISTS `sys_password_history` (
+    `id` bigint unsigned NOT NULL AUTO_INCREMENT COMMENT '主键',
+    `admin_id` bigint unsigned NOT NULL COMMENT '用户id',
+    `password` varchar(64) NOT NULL COMMENT '密码哈希',
+    `create_time` datetime(3) NOT NULL COMMENT '修改时间',
+    PRIMARY KEY (`id`),
+    KEY `idx_sys_password_history_admin_id` (`admin_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='历史密码';
+
+-- 数据权限：角色数据范围，1->全部,2->自定义分组,3->本部门,4->本部门及以下
+ALTER TABLE `sys_role` ADD COLUMN IF NOT EXISTS `data_scope` int NOT NULL DEFAULT '1' COMMENT '数据范围：1->全部,2->自定义分组,3->本部门,4->本部门及以下';
+
+-- 角色自定义数据范围的资产分组
+CREATE TABLE IF NOT EXISTS `sys_role_group` (
+    `role_id` bigint unsigned NOT NULL COMMENT '角色id',
+    `group_id` bigint unsigned NOT NULL COMMENT '资产分组id',
+    KEY `idx_sys_role_group_role_id` (`role_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='角色资产分组';
+
+-- 数据权限：资产分组、集群、任务的归属部门
+ALTER TABLE `cmdb_group` ADD COLUMN IF NOT EXISTS `dept_id` bigint unsigned DEFAULT '0' COMMENT '归属部门ID';
+ALTER TABLE `k8s_cluster` ADD COLUMN IF NOT EXISTS `dept_id` bigint unsigned DEFAULT '0' COMMENT '归属部门ID';
+ALTER TABLE `k8s_cluster` ADD INDEX `idx_k8s_cluster_dept_id` (`dept_id`);
+ALTER TABLE `task_job` ADD COLUMN IF NOT EXISTS `dept_id` bigint unsigned DEFAULT '0' COMMENT '归属部门ID';
+ALTER TABLE `task_job` ADD INDEX `idx_task_job_dept_id` (`dept_id`);
+ALTER TABLE `task_ansible` ADD COLUMN IF NOT EXISTS `dept_id` bigint unsigned NOT NULL DEFAULT '0' COMMENT '归属部门ID';
+ALTER TABLE `task_ansible` ADD INDEX `idx_task_ansible_dept_id` (`dept_id`);
+
+-- 审计日志：哈希链序号与哈希，历史日志 seq 为空
+ALTER TABLE `sys_operation_log` ADD COLUMN IF NOT EXISTS `seq` bigint unsigned DEFAULT NULL COMMENT '审计序号';
+ALTER TABLE `sys_operation_log` ADD COLUMN IF NOT EXISTS `prev_hash` varchar(64) DEFAULT NULL COMMENT '上一条日志哈希';
+ALTER TABLE `sys_operation_log` ADD COLUMN IF NOT EXISTS `hash` varchar(64) DEFAULT NULL COMMENT '日志哈希';
+ALTER TABLE `sys_operation_log` ADD UNIQUE INDEX `idx_sys_operation_log_seq` (`seq`);
+
+-- 审计日志归档记录
+CREATE TABLE IF NOT EXISTS `sys_operation_log_archive` (
+    `id` bigint unsigned NOT NULL AUTO_INCREMENT COMMENT '主键',
+    `file_name` varchar(255) NOT NULL COMMENT '归档文件',
+    `file_hash` varchar(64) NOT NULL COMMENT '归档文件SHA256',
+    `start_seq` bigint unsigned DEFAULT NULL COMMENT '起始审计序号',
+    `end_seq` bigint unsigned DEFAULT NULL COMMENT '结束审计序号',
+    `first_prev_hash` varchar(64) DEFAULT NULL COMMENT '起始日志的上一条哈希',
+    `last_hash` varchar(64) DEFAULT NULL COMMENT '最后一条日志哈希',
+    `count` bigint DEFAULT NULL COMMENT '归档条数',
+    `create_time` datetime(3) NOT NULL COMMENT '归档时间',
+    PRIMARY KEY (`id`),
+    KEY `idx_sys_operation_log_archive_end_seq` (`end_seq`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='操作日志归档记录';
+
+-- 审批流程：审批策略
+CREATE TABLE IF NOT EXISTS `sys_approval_policy` (
+    `id` bigint unsigned NOT NULL AUTO_INCREMENT COMMENT '主键',
+    `name` varchar(64) NOT NULL COMMENT '策略名称',
+    `method` varchar(16) NOT NULL COMMENT '请求方式',
+    `path` varchar(255) NOT NULL COMMENT '路由模板',
+    `condition_name` varchar(64) DEFAULT NULL COMMENT '审批条件',
+    `approver_role_ids` varchar(255) DEFAULT NULL COMMENT '审批人角色id，逗号分隔',
+    `required_approvals` bigint DEFAULT '1' COMMENT '需要的审批人数',
+    `dingding_url` varchar(500) DEFAULT NULL COMMENT '钉钉通知地址',
+    `feishu_url` varchar(500) DEFAULT NULL COMMENT '飞书通知地址',
+    `emails` varchar(500) DEFAULT NULL COMMENT '通知邮箱，逗号分隔',
+    `status` bigint NOT NULL DEFAULT '1' COMMENT '状态：1->启用,2->禁用',
+    `remark` varchar(500) DEFAULT NULL COMMENT '备注',
+    `create_time` datetime(3) NOT NULL COMMENT '创建时间',
+    PRIMARY KEY (`id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='审批策略';
+
+-- 审批流程：审批单
+CREATE TABLE IF NOT EXISTS `sys_approval_ticket` (
+    `id` bigint unsigned NOT NULL AUTO_INCREMENT COMMENT '主键',
+    `policy_id` bigint unsigned NOT NULL COMMENT '审批策略id',
+    `title` varchar(255) DEFAULT NULL COMMENT '标题',
+    `method` varchar(16) NOT NULL COMMENT '请求方式',
+    `path` varchar(255) DEFAULT NULL COMMENT '路由模板',
+    `url` varchar(1000) NOT NULL COMMENT '请求地址',
+    `content_type` varchar(128) DEFAULT NULL COMMENT '请求体类型',
+    `body` longtext COMMENT '请求体',
+    `reason` varchar(500) DEFAULT NULL COMMENT '申请原因',
+    `applicant_id` bigint unsigned NOT NULL COMMENT '申请人id',
+    `applicant_name` varchar(64) DEFAULT NULL COMMENT '申请人账号',
+    `approver_role_ids` varchar(255) DEFAULT NULL COMMENT '审批人角色id',
+    `required_approvals` bigint DEFAULT NULL COMMENT '需要的审批人数',
+    `approved_count` bigint DEFAULT '0' COMMENT '已同意人数',
+    `status` bigint DEFAULT '1' COMMENT '状态',
+    `result_code` bigint DEFAULT NULL COMMENT '执行结果状态码',
+    `result` text COMMENT '执行结果',
+    `create_time` datetime(3) NOT NULL COMMENT '创建时间',
+    `execute_time` datetime(3) DEFAULT NULL COMMENT '执行时间',
+    PRIMARY KEY (`id`),
+    KEY `idx_sys_approval_ticket_policy_id` (`policy_id`),
+    KEY `idx_sys_approval_ticket_applicant_id` (`applicant_id`),
+    KEY `idx_sys_approval_ticket_status` (`status`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='审批单';
+
+-- 审批流程：审批记录
+CREATE TABLE IF NOT EXISTS `sys_approval_comment` (
+    `id` bigint unsigned NOT NULL AUTO_INCREMENT COMMENT '主键',
+    `ticket_id` bigint unsigned NOT NULL COMMENT '审批单id',
+    `admin_id` bigint unsigned NOT NULL COMMENT '用户id',
+    `username` varchar(64) DEFAULT NULL COMMENT '用户账号',
+    `action` bigint NOT NULL COMMENT '动作：1->评论,2->同意,3->拒绝,4->撤销',
+    `content` varchar(1000) DEFAULT NULL COMMENT '内容',
+    `create_time` datetime(3) NOT NULL COMMENT '创建时间',
+    PRIMARY KEY (`id`),
+    KEY `idx_sys_approval_comment_ticket_id` (`ticket_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='审批记录';
+
+-- 审批权限：审批策略和审批单接口使用 base:approval:policy 权限
+INSERT IGNORE INTO `sys_menu` (`id`, `parent_id`, `menu_name`, `icon`, `value`, `menu_type`, `url`, `menu_status`, `sort`, `create_time`) VALUES
+(255, 4, '审批管理', '', 'base:approval:policy', 3, '', 2, 7, NOW());
+
+-- 加密密钥轮换权限
+INSERT IGNORE INTO `sys_menu` (`id`, `parent_id`, `menu_name`, `icon`, `value`, `menu_type`, `url`, `menu_status`, `sort`, `create_time`) VALUES
+(256, 4, '加密密钥轮换', '', 'base:encryption:rotate', 3, '', 2, 8, NOW());
+
+-- 主机凭证轮换：轮换策略
+CREATE TABLE IF NOT EXISTS `cmdb_credential_rotation` (
+    `id` bigint unsigned NOT NULL AUTO_INCREMENT COMMENT '主键',
+    `name` varchar(64) NOT NULL COMMENT '策略名称',
+    `group_id` bigint unsigned NOT NULL COMMENT '资产分组ID',
+    `mode` bigint NOT NULL COMMENT '轮换方式:1->密码,2->密钥',
+    `key_type` varchar(16) DEFAULT NULL COMMENT '密钥类型',
+    `key_bits` bigint DEFAULT NULL COMMENT 'RSA密钥长度',
+    `password_length` bigint DEFAULT NULL COMMENT '密码长度',
+    `cron_expr` varchar(64) DEFAULT NULL COMMENT 'cron表达式',
+    `status` bigint NOT NULL DEFAULT '1' COMMENT '状态:1->启用,2->禁用',
+    `last_run_time` datetime(3) DEFAULT NULL COMMENT '上次执行时间',
+    `remark` varchar(500) DEFAULT NULL COMMENT '备注',
+    `create_time` datetime(3) NOT NULL COMMENT '创建时间',
+    PRIMARY KEY (`id`),
+    KEY `idx_cmdb_credential_rotation_group_id` (`group_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='凭证轮换策略';
+
+-- 主机凭证轮换：执行记录
+CREATE TABLE IF NOT EXISTS `cmdb_credential_rotation_run` (
+    `id` bigint unsigned NOT NULL AUTO_INCREMENT COMMENT '主键',
+    `rotation_id` bigint unsigned DEFAULT NULL COMMENT '轮换策略ID',
+    `group_id` bigint unsigned NOT NULL COMMENT '资产分组ID',
+    `mode` bigint NOT NULL COMMENT '轮换方式',
+    `trigger_type` bigint DEFAULT NULL COMMENT '触发方式:1->手动,2->定时',
+    `operator` varchar(64) DEFAULT NULL COMMENT '操作人',
+    `status` bigint DEFAULT NULL COMMENT '状态:1->执行中,2->成功,3->部分成功,4->失败',
+    `total` bigint DEFAULT NULL COMMENT '主机数',
+    `success_count` bigint DEFAULT NULL COMMENT '成功数',
+    `failed_count` bigint DEFAULT NULL COMMENT '失败数',
+    `start_time` datetime(3) NOT NULL COMMENT '开始时间',
+    `end_time` datetime(3) DEFAULT NULL COMMENT '结束时间',
+    PRIMARY KEY (`id`),
+    KEY `idx_cmdb_credential_rotation_run_rotation_id` (`rotation_id`),
+    KEY `idx_cmdb_credential_rotation_run_group_id` (`group_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='凭证轮换执行记录';
+
+-- 主机凭证轮换：主机报告
+CREATE TABLE IF NOT EXISTS `cmdb_credential_rotation_host` (
+    `id` bigint unsigned NOT NULL AUTO_INCREMENT COMMENT '主键',
+    `run_id` bigint unsigned NOT NULL COMMENT '执行记录ID',
+    `host_id` bigint unsigned NOT NULL COMMENT '主机ID',
+    `host_name` varchar(64) DEFAULT NULL COMMENT '主机名称',
+    `ssh_ip` varchar(64) DEFAULT NULL COMMENT 'SSH连接IP',
+    `old_auth_id` bigint unsigned DEFAULT NULL COMMENT '原凭证ID',
+    `new_auth_id` bigint unsigned DEFAULT NULL COMMENT '新凭证ID',
+    `status` bigint DEFAULT NULL COMMENT '结果',
+    `message` text COMMENT '执行信息',
+    `duration` bigint DEFAULT NULL COMMENT '耗时(毫秒)',
+    `create_time` datetime(3) NOT NULL COMMENT '创建时间',
+    PRIMARY KEY (`id`),
+    KEY `idx_cmdb_credential_rotation_host_run_id` (`run_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='凭证轮换主机报告';
+
+-- 凭证轮换权限：凭证轮换和公钥分发接口使用 cmdb:credential:rotate 权限
+INSERT IGNORE INTO `sys_menu` (`id`, `parent_id`, `menu_name`, `icon`, `value`, `menu_type`, `url`, `menu_status`, `sort`, `create_time`) VALUES
+(257, 85, '凭证轮换', '', 'cmdb:credential:rotate', 3, '', 2, 4, NOW());
+
+-- 凭证使用记录：最后使用时间和使用次数
+ALTER TABLE `config_ecsauth` ADD COLUMN IF NOT EXISTS `last_used_time` datetime(3) DEFAULT NULL COMMENT '最后使用时间';
+ALTER TABLE `config_ecsauth` ADD COLUMN IF NOT EXISTS `use_count` bigint NOT NULL DEFAULT '0' COMMENT '使用次数';
+ALTER TABLE `config_account` ADD COLUMN IF NOT EXISTS `last_used_time` datetime(3) DEFAULT NULL COMMENT '最后使用时间';
+ALTER TABLE `config_account` ADD COLUMN IF NOT EXISTS `use_count` bigint NOT NULL DEFAULT '0' COMMENT '使用次数';
+
+-- SSH主机密钥：每台主机信任的密钥及待确认的变更密钥
+CREATE TABLE IF NOT EXISTS `cmdb_host_key` (
+    `id` bigint unsigned NOT NULL AUTO_INCREMENT COMMENT '主键',
+    `host_id` bigint unsigned DEFAULT NULL COMMENT '主机ID，0表示不在CMDB中的地址',
+    `address` varchar(128) NOT NULL COMMENT '连接地址',
+    `key_type` varchar(64) DEFAULT NULL COMMENT '密钥类型',
+    `public_key` text COMMENT '信任的公钥',
+    `fingerprint` varchar(128) DEFAULT NULL COMMENT '信任的公钥指纹',
+    `source` bigint DEFAULT NULL COMMENT '来源:1->首次连接,2->管理员确认,3->手动固定',
+    `status` bigint NOT NULL COMMENT '状态:1->已信任,2->密钥变更,3->待确认',
+    `pending_key_type` varchar(64) DEFAULT NULL COMMENT '待确认密钥类型',
+    `pending_public_key` text COMMENT '待确认公钥',
+    `pending_fingerprint` varchar(128) DEFAULT NULL COMMENT '待确认公钥指纹',
+    `mismatch_count` bigint DEFAULT '0' COMMENT '密钥不一致次数',
+    `last_seen_time` datetime(3) DEFAULT NULL COMMENT '最后校验通过时间',
+    `changed_time` datetime(3) DEFAULT NULL COMMENT '最近密钥不一致时间',
+    `operator` varchar(64) DEFAULT NULL COMMENT '确认或固定的操作人',
+    `create_time` datetime(3) NOT NULL COMMENT '创建时间',
+    PRIMARY KEY (`id`),
+    KEY `idx_cmdb_host_key_host_id` (`host_id`),
+    KEY `idx_cmdb_host_key_address` (`address`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='SSH主机密钥';
+
+-- 主机密钥管理权限：主机 SSH 指纹信任和变更确认接口使用 cmdb:hostkey:manage 权限
+INSERT IGNORE INTO `sys_menu` (`id`, `parent_id`, `menu_name`, `icon`, `value`, `menu_type`, `url`, `menu_status`, `sort`, `create_time`) VALUES
+(258, 78, '主机密钥管理', '', 'cmdb:hostkey:manage', 3, '', 2, 11, NOW());
+
+-- 跳板机：主机和分组可指定跳板机主机，主机未指定时使用分组或上级分组的跳板机
+ALTER TABLE `cmdb_host` ADD COLUMN IF NOT EXISTS `proxy_host_id` bigint unsigned DEFAULT '0' COMMENT '跳板机主机ID';
+ALTER TABLE `cmdb_group` ADD COLUMN IF NOT EXISTS `proxy_host_id` bigint unsigned DEFAULT '0' COMMENT '跳板机主机ID';
+
+-- 终端会话审计：主机SSH终端和容器终端的会话录像及执行的命令
+CREATE TABLE IF NOT EXISTS `sys_terminal_session` (
+    `id` bigint unsigned NOT NULL AUTO_INCREMENT COMMENT '主键',
+    `session_id` varchar(64) NOT NULL COMMENT '会话标识',
+    `kind` bigint NOT NULL COMMENT '终端类型:1->主机SSH,2->容器',
+    `target_id` bigint unsigned DEFAULT NULL COMMENT '主机ID或集群ID',
+    `target_name` varchar(255) DEFAULT NULL COMMENT '主机名称或命名空间/Pod/容器',
+    `address` varchar(255) DEFAULT NULL COMMENT '连接地址',
+    `admin_id` bigint unsigned DEFAULT NULL COMMENT '用户ID',
+    `username` varchar(64) DEFAULT NULL COMMENT '用户账号',
+    `ip` varchar(64) DEFAULT NULL COMMENT '客户端IP',
+    `width` bigint DEFAULT NULL COMMENT '终端宽度',
+    `height` bigint DEFAULT NULL COMMENT '终端高度',
+    `backend` varchar(16) DEFAULT NULL COMMENT '录像存储后端',
+    `storage_key` varchar(255) DEFAULT NULL COMMENT '录像路径',
+    `size` bigint DEFAULT NULL COMMENT '录像大小(字节)',
+    `duration` double DEFAULT NULL COMMENT '会话时长(秒)',
+    `command_count` bigint DEFAULT NULL COMMENT '命令数',
+    `status` bigint NOT NULL COMMENT '状态:1->进行中,2->已结束,3->异常中断',
+    `start_time` datetime(3) NOT NULL COMMENT '开始时间',
+    `end_time` datetime(3) DEFAULT NULL COMMENT '结束时间',
+    PRIMARY KEY (`id`),
+    UNIQUE KEY `idx_sys_terminal_session_session_id` (`session_id`),
+    KEY `idx_sys_terminal_session_target_id` (`target_id`),
+    KEY `idx_sys_terminal_session_admin_id` (`admin_id`),
+    KEY `idx_sys_terminal_session_start_time` (`start_time`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='终端会话';
+
+CREATE TABLE IF NOT EXISTS `sys_terminal_command` (
+    `id` bigint unsigned NOT NULL AUTO_INCREMENT COMMENT '主键',
+    `session_id` bigint unsigned NOT NULL COMMENT '终端会话ID',
+    `command` varchar(1024) NOT NULL COMMENT '命令',
+    `time_offset` double DEFAULT NULL COMMENT '相对会话开始的秒数',
+    `create_time` datetime(3) NOT NULL COMMENT '执行时间',
+    PRIMARY KEY (`id`),
+    KEY `idx_sys_terminal_command_session_id` (`session_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='终端会话执行的命令';
+
+-- 终端命令规则：Web终端中输入的命令按优先级匹配，命中后放行、拦截或需要确认，违规记录写入操作日志
+CREATE TABLE IF NOT EXISTS `sys_command_rule` (
+    `id` bigint unsigned NOT NULL AUTO_INCREMENT COMMENT '主键',
+    `name` varchar(64) NOT NULL COMMENT '规则名称',
+    `match_type` bigint NOT NULL COMMENT '匹配方式:1->前缀,2->正则',
+    `pattern` varchar(500) NOT NULL COMMENT '匹配内容',
+    `action` bigint NOT NULL COMMENT '动作:1->放行,2->拦截,3->需要确认',
+    `role_ids` varchar(255) DEFAULT NULL COMMENT '适用角色id，逗号分隔',
+    `group_ids` varchar(255) DEFAULT NULL COMMENT '适用资产分组id，逗号分隔',
+    `priority` bigint DEFAULT '100' COMMENT '优先级，数值小的先匹配',
+    `status` bigint NOT NULL DEFAULT '1' COMMENT '状态：1->启用,2->禁用',
+    `remark` varchar(500) DEFAULT NULL COMMENT '备注',
+    `create_time` datetime(3) NOT NULL COMMENT '创建时间',
+    PRIMARY KEY (`id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='终端命令规则';
+
+INSERT INTO `sys_command_rule` (`name`, `match_type`, `pattern`, `action`, `role_ids`, `group_ids`, `priority`, `status`, `remark`, `create_time`)
+SELECT * FROM (
+    SELECT '禁止删除根目录' AS `name`, 2 AS `match_type`, '\\brm\\s+(-[-a-zA-Z]*\\s+)*-[a-zA-Z]*[rR][a-zA-Z]*\\s+(-[-a-zA-Z]*\\s+)*/(\\*)?(\\s|$)' AS `pattern`, 2 AS `action`, '' AS `role_ids`, '' AS `group_ids`, 10 AS `priority`, 1 AS `status`, '' AS `remark`, NOW(3) AS `create_time`
+    UNION ALL SELECT '禁止格式化磁盘', 1, 'mkfs', 2, '', '', 10, 1, '', NOW(3)
+    UNION ALL SELECT '关机需要确认', 1, 'shutdown', 3, '', '', 20, 1, '', NOW(3)
+    UNION ALL SELECT '重启需要确认', 1, 'reboot', 3, '', '', 20, 1, '', NOW(3)
+) AS `defaults`
+WHERE NOT EXISTS (SELECT 1 FROM `sys_command_rule`);
+
+-- 命令过滤规则权限
+INSERT IGNORE INTO `sys_menu` (`id`, `parent_id`, `menu_name`, `icon`, `value`, `menu_type`, `url`, `menu_status`, `sort`, `create_time`) VALUES
+(259, 247, '命令过滤规则', '', 'monitor:command:rule', 3, '', 2, 1, NOW());
+
+-- 终端会话终止：管理员终止进行中的会话时记录操作人和原因
+ALTER TABLE `sys_terminal_session` ADD COLUMN IF NOT EXISTS `terminated_by` varchar(64) DEFAULT NULL COMMENT '终止会话的管理员';
+ALTER TABLE `sys_terminal_session` ADD COLUMN IF NOT EXISTS `terminate_reason` varchar(500) DEFAULT NULL COMMENT '终止原因';
+
+-- 会话实时观看和强制终止权限，授予安全负责人角色
+INSERT IGNORE INTO `sys_menu` (`id`, `parent_id`, `menu_name`, `icon`, `value`, `menu_type`, `url`, `menu_status`, `sort`, `create_time`) VALUES
+(260, 247, '实时观看会话', '', 'monitor:recording:observe', 3, '', 2, 2, NOW()),
+(261, 247, '终止会话', '', 'monitor:recording:terminate', 3, '', 2, 3, NOW());
+
+-- 批量命令执行：执行记录及每台主机的输出和退出码
+CREATE TABLE IF NOT EXISTS `cmdb_batch_command` (
+    `id` bigint unsigned NOT NULL AUTO_INCREMENT COMMENT '主键',
+    `command` text NOT NULL COMMENT '命令',
+    `host_ids` text COMMENT '主机ID，逗号分隔',
+    `group_ids` varchar(255) DEFAULT NULL COMMENT '资产分组ID，逗号分隔',
+    `concurrency` bigint DEFAULT NULL COMMENT '并发数',
+    `timeout` bigint DEFAULT NULL COMMENT '单台主机超时时间(秒)',
+    `rerun_id` bigint unsigned DEFAULT '0' COMMENT '重新执行的原记录ID',
+    `operator` varchar(64) DEFAULT NULL COMMENT '操作人',
+    `status` bigint DEFAULT NULL COMMENT '状态:1->执行中,2->成功,3->部分成功,4->失败',
+    `total` bigint DEFAULT NULL COMMENT '主机数',
+    `success_count` bigint DEFAULT NULL COMMENT '成功数',
+    `failed_count` bigint DEFAULT NULL COMMENT '失败数',
+    `start_time` datetime(3) NOT NULL COMMENT '开始时间',
+    `end_time` datetime(3) DEFAULT NULL COMMENT '结束时间',
+    PRIMARY KEY (`id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='批量命令执行记录';
+
+CREATE TABLE IF NOT EXISTS `cmdb_batch_command_host` (
+    `id` bigint unsigned NOT NULL AUTO_INCREMENT COMMENT '主键',
+    `command_id` bigint unsigned NOT NULL COMMENT '执行记录ID',
+    `host_id` bigint unsigned NOT NULL COMMENT '主机ID',
+    `host_name` varchar(64) DEFAULT NULL COMMENT '主机名称',
+    `ssh_ip` varchar(64) DEFAULT NULL COMMENT 'SSH连接IP',
+    `status` bigint DEFAULT NULL COMMENT '状态',
+    `exit_code` bigint DEFAULT NULL COMMENT '退出码',
+    `output` mediumtext COMMENT '输出',
+    `message` varchar(500) DEFAULT NULL COMMENT '执行信息',
+    `duration` bigint DEFAULT NULL COMMENT '耗时(毫秒)',
+    `start_time` datetime(3) DEFAULT NULL COMMENT '开始时间',
+    `end_time` datetime(3) DEFAULT NULL COMMENT '结束时间',
+    PRIMARY KEY (`id`),
+    KEY `idx_cmdb_batch_command_host_command_id` (`command_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='批量命令主机执行结果';
+
+-- CI类型：管理员定义的资产模型及其自定义属性，主机按CI类型保存自定义属性值
+CREATE TABLE IF NOT EXISTS `cmdb_ci_type` (
+    `id` bigint unsigned NOT NULL AUTO_INCREMENT COMMENT '主键',
+    `name` varchar(64) NOT NULL COMMENT '类型名称',
+    `code` varchar(64) NOT NULL COMMENT '类型编码',
+    `remark` varchar(500) DEFAULT NULL COMMENT '备注',
+    `create_time` datetime(3) NOT NULL COMMENT '创建时间',
+    `update_time` datetime(3) DEFAULT NULL COMMENT '更新时间',
+    PRIMARY KEY (`id`),
+    UNIQUE KEY `idx_cmdb_ci_type_name` (`name`),
+    UNIQUE KEY `idx_cmdb_ci_type_code` (`code`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='CI类型';
+
+CREATE TABLE IF NOT EXISTS `cmdb_ci_field` (
+    `id` bigint unsigned NOT NULL AUTO_INCREMENT COMMENT '主键',
+    `ci_type_id` bigint unsigned NOT NULL COMMENT 'CI类型ID',
+    `field_key` varchar(64) NOT NULL COMMENT '属性键',
+    `name` varchar(64) NOT NULL COMMENT '属性名称',
+    `type` bigint NOT NULL COMMENT '类型:1->字符串,2->枚举,3->日期,4->引用主机',
+    `required` tinyint(1) DEFAULT '0' COMMENT '是否必填',
+    `options` varchar(1000) DEFAULT NULL COMMENT '枚举可选值，逗号分隔',
+    `ref_ci_type_id` bigint unsigned DEFAULT '0' COMMENT '引用主机的CI类型ID',
+    `sort` bigint DEFAULT '0' COMMENT '排序',
+    `create_time` datetime(3) NOT NULL COMMENT '创建时间',
+    PRIMARY KEY (`id`),
+    UNIQUE KEY `uk_ci_field` (`ci_type_id`, `field_key`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='CI类型自定义属性';
+
+CREATE TABLE IF NOT EXISTS `cmdb_host_attr` (
+    `id` bigint unsigned NOT NULL AUTO_INCREMENT COMMENT '主键',
+    `host_id` bigint unsigned NOT NULL COMMENT '主机ID',
+    `field_id` bigint unsigned NOT NULL COMMENT '属性ID',
+    `value` varchar(500) DEFAULT NULL COMMENT '属性值',
+    PRIMARY KEY (`id`),
+    UNIQUE KEY `uk_host_attr` (`host_id`, `field_id`),
+    KEY `idx_field_value` (`field_id`, `value`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='主机自定义属性值';
+
+ALTER TABLE `cmdb_host` ADD COLUMN IF NOT EXISTS `ci_type_id` bigint unsigned DEFAULT '0' COMMENT 'CI类型ID';
+ALTER TABLE `cmdb_host` ADD INDEX `idx_cmdb_host_ci_type_id` (`ci_type_id`);
+
+-- CI 模型管理权限
+INSERT IGNORE INTO `sys_menu` (`id`, `parent_id`, `menu_name`, `icon`, `value`, `menu_type`, `url`, `menu_status`, `sort`, `create_time`) VALUES
+(262, 80, '模型管理', '', 'cmdb:citype:manage', 3, '', 2, 4, NOW());
+
+CREATE TABLE IF NOT EXISTS `cmdb_relation` (
+    `id` bigint unsigned NOT NULL AUTO_INCREMENT COMMENT '主键',
+    `source_type` varchar(16) NOT NULL COMMENT '源配置项类型',
+    `source_id` bigint unsigned NOT NULL COMMENT '源配置项ID',
+    `target_type` varchar(16) NOT NULL COMMENT '目标配置项类型',
+    `target_id` bigint unsigned NOT NULL COMMENT '目标配置项ID',
+    `relation_type` varchar(32) NOT NULL COMMENT '关系类型',
+    `origin` bigint DEFAULT '1' COMMENT '来源:1->手工,2->自动发现',
+    `remark` varchar(255) DEFAULT NULL COMMENT '备注',
+    `create_time` datetime(3) NOT NULL COMMENT '创建时间',
+    PRIMARY KEY (`id`),
+    UNIQUE KEY `uk_relation` (`source_type`, `source_id`, `target_type`, `target_id`, `relation_type`),
+    KEY `idx_relation_target` (`target_type`, `target_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='CMDB配置项关系';
+
+-- 资产关系管理权限
+INSERT IGNORE INTO `sys_menu` (`id`, `parent_id`, `menu_name`, `icon`, `value`, `menu_type`, `url`, `menu_status`, `sort`, `create_time`) VALUES
+(263, 80, '资产关系', '', 'cmdb:relation:manage', 3, '', 2, 5, NOW());
+
+CREATE TABLE IF NOT EXISTS `cmdb_change_log` (
+    `id` bigint unsigned NOT NULL AUTO_INCREMENT COMMENT '主键',
+    `record_type` varchar(16) NOT NULL COMMENT '资产类型',
+    `record_id` bigint unsigned NOT NULL COMMENT '资产ID',
+    `action` varchar(16) NOT NULL COMMENT '操作:create、update、delete',
+    `field` varchar(64) DEFAULT NULL COMMENT '字段',
+    `old_value` text COMMENT '旧值',
+    `new_value` text COMMENT '新值',
+    `source` varchar(16) DEFAULT NULL COMMENT '来源:user、cloud、agent、system',
+    `operator` varchar(64) DEFAULT NULL COMMENT '操作人',
+    `create_time` datetime(3) NOT NULL COMMENT '变更时间',
+    PRIMARY KEY (`id`),
+    KEY `idx_change_record` (`record_type`, `record_id`),
+    KEY `idx_cmdb_change_log_create_time` (`create_time`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='CMDB资产变更历史';
+
+-- 主机标签：键值对标签，用于按标签选择器选择主机
+CREATE TABLE IF NOT EXISTS `cmdb_host_label` (
+    `id` bigint unsigned NOT NULL AUTO_INCREMENT COMMENT '主键',
+    `host_id` bigint unsigned NOT NULL COMMENT '主机ID',
+    `label_key` varchar(63) NOT NULL COMMENT '标签键',
+    `label_value` varchar(255) DEFAULT NULL COMMENT '标签值',
+    PRIMARY KEY (`id`),
+    UNIQUE KEY `uk_host_label` (`host_id`, `label_key`),
+    KEY `idx_cmdb_host_label_label_value` (`label_value`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='主机标签';
+
+CREATE TABLE IF NOT EXISTS `cmdb_dynamic_group` (
+    `id` bigint unsigned NOT NULL AUTO_INCREMENT COMMENT '主键',
+    `name` varchar(64) NOT NULL COMMENT '分组名称',
+    `selector` varchar(500) NOT NULL COMMENT '标签选择器',
+    `remark` varchar(255) DEFAULT NULL COMMENT '备注',
+    `operator` varchar(64) DEFAULT NULL COMMENT '创建人',
+    `create_time` datetime(3) NOT NULL COMMENT '创建时间',
+    `update_time` datetime(3) DEFAULT NULL COMMENT '更新时间',
+    PRIMARY KEY (`id`),
+    UNIQUE KEY `idx_cmdb_dynamic_group_name` (`name`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='CMDB动态主机分组';
+
+ALTER TABLE `cmdb_batch_command` ADD COLUMN IF NOT EXISTS `selector` varchar(500) DEFAULT NULL COMMENT '标签选择器';
+ALTER TABLE `cmdb_batch_command` ADD COLUMN IF NOT EXISTS `dynamic_group_ids` varchar(255) DEFAULT NULL COMMENT '动态分组ID，逗号分隔';
+ALTER TABLE `task_ansible` ADD COLUMN IF NOT EXISTS `dynamic_groups` text COMMENT '动态分组JSON';
+
+-- Ansible任务记录设置动态分组的用户，执行时按其数据权限选择主机
+ALTER TABLE `task_ansible` ADD COLUMN IF NOT EXISTS `operator_id` bigint unsigned NOT NULL DEFAULT 0 COMMENT '设置动态分组的用户ID';
+
+-- 动态分组管理权限
+INSERT IGNORE INTO `sys_menu` (`id`, `parent_id`, `menu_name`, `icon`, `value`, `menu_type`, `url`, `menu_status`, `sort`, `create_time`) VALUES
+(264, 88, '动态分组', '', 'cmdb:dynamicgroup:manage', 3, '', 2, 1, NOW());
+
+-- 操作日志哈希链头：写入操作日志时锁定该行，由数据库行锁保证多实例并发写入时哈希链的顺序
+CREATE TABLE IF NOT EXISTS `sys_operation_log_chain` (
+    `id` bigint unsigned NOT NULL COMMENT '主键',
+    `last_seq` bigint unsigned DEFAULT NULL COMMENT '最后一条日志的审计序号',
+    `last_hash` varchar(64) DEFAULT NULL COMMENT '最后一条日志哈希',
+    PRIMARY KEY (`id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='操作日志哈希链头';
+
+-- 审批单的请求体和执行结果加密保存，密文长度超过 text 类型上限
+ALTER TABLE `sys_approval_ticket` MODIFY COLUMN `body` longtext COMMENT '请求体(加密)', MODIFY COLUMN `result` longtext COMMENT '执行结果(加密)';
+
+-- 批量命令执行记录按操作人所属部门、主机结果按主机所在资产分组过滤数据权限
+ALTER TABLE `cmdb_batch_command` ADD COLUMN IF NOT EXISTS `dept_id` bigint unsigned NOT NULL DEFAULT '0' COMMENT '归属部门ID';
+ALTER TABLE `cmdb_batch_command` ADD INDEX `idx_cmdb_batch_command_dept_id` (`dept_id`);
+ALTER TABLE `cmdb_batch_command_host` ADD COLUMN IF NOT EXISTS `group_id` bigint unsigned NOT NULL DEFAULT '0' COMMENT '资产分组ID';
+ALTER TABLE `cmdb_batch_command_host` ADD INDEX `idx_cmdb_batch_command_host_group_id` (`group_id`);
+UPDATE `cmdb_batch_command` b JOIN `sys_admin` a ON a.`username` = b.`operator` SET b.`dept_id` = a.`dept_id` WHERE b.`dept_id` = 0 AND a.`dept_id` IS NOT NULL;
+UPDATE `cmdb_batch_command_host` h JOIN `cmdb_host` c ON c.`id` = h.`host_id` SET h.`group_id` = c.`group_id` WHERE h.`group_id` = 0;
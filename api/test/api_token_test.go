@@ -0,0 +1,149 @@
+package test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"dodevops-api/api/system/controller"
+	"dodevops-api/api/system/model"
+	"dodevops-api/common/result"
+	"dodevops-api/common/util"
+	"dodevops-api/middleware"
+	"dodevops-api/pkg/db"
+	"dodevops-api/pkg/jwt"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 初始化拥有 cmdb:ecs:add、cmdb:ecs:delete 权限的用户和带权限校验的路由
+func setupApiToken(t *testing.T) (*gin.Engine, model.SysAdmin) {
+	database := setupSystemDB(t)
+	if err := database.AutoMigrate(&model.SysApiToken{}); err != nil {
+		t.Fatalf("Failed to auto migrate: %v", err)
+	}
+	now := util.HTime{Time: time.Now()}
+	admin := model.SysAdmin{Username: "frank", Password: util.EncryptionMd5("pass"), Status: 1, CreateTime: now}
+	database.Create(&admin)
+	var role model.SysRole
+	database.Where("role_key = ?", "ops").First(&role)
+	database.Create(&model.SysAdminRole{AdminId: admin.ID, RoleId: role.ID})
+	for _, value := range []string{"cmdb:ecs:add", "cmdb:ecs:delete"} {
+		menu := model.SysMenu{Value: value, MenuType: 3, MenuStatus: 2, CreateTime: now}
+		database.Create(&menu)
+		database.Create(&model.SysRoleMenu{RoleId: role.ID, MenuId: menu.ID})
+	}
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	group := router.Group("/api/v1")
+	group.Use(middleware.AuthMiddleware(), middleware.PermissionMiddleware())
+	ok := func(c *gin.Context) { result.Success(c, true) }
+	group.POST("/admin/token/add", controller.CreateSysApiToken)
+	group.DELETE("/admin/token/delete", controller.DeleteSysApiToken)
+	group.POST("/cmdb/hostcreate", ok)
+	group.DELETE("/cmdb/hostdelete", ok)
+	group.GET("/cmdb/hostlist", ok)
+	group.GET("/admin/token/list", controller.GetSysApiTokenList)
+	group.PUT("/admin/updatePersonal", ok)
+	return router, admin
+}
+
+func callApi(router *gin.Engine, method, target, token string, body interface{}) (int, json.RawMessage) {
+	data, _ := json.Marshal(body)
+	req := httptest.NewRequest(method, target, bytes.NewReader(data))
+	req.RemoteAddr = "127.0.0.1:12345"
+	req.Header.Set("Authorization", "Bearer "+token)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	var res struct {
+		Code int             `json:"code"`
+		Data json.RawMessage `json:"data"`
+	}
+	_ = json.Unmarshal(w.Body.Bytes(), &res)
+	return res.Code, res.Data
+}
+
+func TestApiTokenScopes(t *testing.T) {
+	router, admin := setupApiToken(t)
+	jwtToken, _ := jwt.GenerateTokenByAdmin(admin, "", time.Hour)
+
+	// 不能授予自己没有的权限
+	if code, _ := callApi(router, http.MethodPost, "/api/v1/admin/token/add", jwtToken,
+		model.AddSysApiTokenDto{Name: "ci", Scopes: []string{"base:admin:add"}}); code == 200 {
+		t.Fatalf("Expected scope outside role permissions to be rejected")
+	}
+
+	code, data := callApi(router, http.MethodPost, "/api/v1/admin/token/add", jwtToken,
+		model.AddSysApiTokenDto{Name: "ci", Scopes: []string{"cmdb:ecs:add"}, ExpireDays: 30})
+	var created struct {
+		Id    uint   `json:"id"`
+		Token string `json:"token"`
+	}
+	if err := json.Unmarshal(data, &created); code != 200 || err != nil || created.Token == "" {
+		t.Fatalf("Create token failed: %d %s", code, data)
+	}
+	var stored model.SysApiToken
+	db.Db.First(&stored, created.Id)
+	if stored.TokenHash == "" || stored.TokenHash == created.Token {
+		t.Errorf("Token should be stored hashed, got %q", stored.TokenHash)
+	}
+
+	cases := []struct {
+		method, target string
+		allowed        bool
+	}{
+		{http.MethodPost, "/api/v1/cmdb/hostcreate", true},      // 授权范围内
+		{http.MethodDelete, "/api/v1/cmdb/hostdelete", false},   // 角色有权限但不在授权范围内
+		{http.MethodGet, "/api/v1/cmdb/hostlist", false},        // 角色无权限的查询接口
+		{http.MethodGet, "/api/v1/admin/token/list", true},      // 登录即可访问的只读接口
+		{http.MethodPut, "/api/v1/admin/updatePersonal", false}, // 登录即可访问的写接口
+		{http.MethodPost, "/api/v1/admin/token/add", false},     // 令牌不能再创建令牌
+	}
+	for _, tc := range cases {
+		code, _ := callApi(router, tc.method, tc.target, created.Token, model.AddSysApiTokenDto{Name: "x", Scopes: []string{"cmdb:ecs:add"}})
+		if (code == 200) != tc.allowed {
+			t.Errorf("%s %s: expected allowed=%v, got code %d", tc.method, tc.target, tc.allowed, code)
+		}
+	}
+
+	db.Db.First(&stored, created.Id)
+	if stored.LastUsedTime == nil || stored.LastUsedIp != "127.0.0.1" {
+		t.Errorf("Expected last used to be recorded, got %+v", stored)
+	}
+
+	// 吊销后立即失效
+	if code, _ := callApi(router, http.MethodDelete, "/api/v1/admin/token/delete", jwtToken, model.SysApiTokenIdDto{Id: created.Id}); code != 200 {
+		t.Fatalf("Revoke token failed: %d", code)
+	}
+	if code, _ := callApi(router, http.MethodPost, "/api/v1/cmdb/hostcreate", created.Token, nil); code == 200 {
+		t.Errorf("Expected revoked token to be rejected")
+	}
+}
+
+func TestApiTokenExpiredAndDisabledUser(t *testing.T) {
+	router, admin := setupApiToken(t)
+	jwtToken, _ := jwt.GenerateTokenByAdmin(admin, "", time.Hour)
+	_, data := callApi(router, http.MethodPost, "/api/v1/admin/token/add", jwtToken,
+		model.AddSysApiTokenDto{Name: "ci", Scopes: []string{"cmdb:ecs:add"}})
+	var created struct {
+		Id    uint   `json:"id"`
+		Token string `json:"token"`
+	}
+	_ = json.Unmarshal(data, &created)
+
+	db.Db.Model(&model.SysApiToken{}).Where("id = ?", created.Id).
+		Update("expire_time", util.HTime{Time: time.Now().Add(-time.Hour)})
+	if code, _ := callApi(router, http.MethodPost, "/api/v1/cmdb/hostcreate", created.Token, nil); code == 200 {
+		t.Errorf("Expected expired token to be rejected")
+	}
+
+	db.Db.Model(&model.SysApiToken{}).Where("id = ?", created.Id).Update("expire_time", nil)
+	db.Db.Model(&model.SysAdmin{}).Where("id = ?", admin.ID).Update("status", 2)
+	if code, _ := callApi(router, http.MethodPost, "/api/v1/cmdb/hostcreate", created.Token, nil); code == 200 {
+		t.Errorf("Expected token of disabled user to be rejected")
+	}
+}
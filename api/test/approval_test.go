@@ -0,0 +1,255 @@
+package test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	cmdbcontroller "dodevops-api/api/cmdb/controller"
+	"dodevops-api/api/system/controller"
+	"dodevops-api/api/system/model"
+	"dodevops-api/common/constant"
+	"dodevops-api/common/result"
+	"dodevops-api/common/util"
+	"dodevops-api/middleware"
+	"dodevops-api/pkg/approval"
+	"dodevops-api/pkg/jwt"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type approvalFixture struct {
+	database  *gorm.DB
+	router    *gin.Engine
+	tokens    map[string]string
+	approvers model.SysRole
+	executed  []string // 实际执行的请求：执行人 + 路径
+}
+
+// 初始化申请人 grace（运维角色，拥有集群和数据库权限）、审批人 henry、iris（访客角色）以及带审批拦截的路由
+func setupApproval(t *testing.T) *approvalFixture {
+	database := setupSystemDB(t)
+	if err := database.AutoMigrate(&model.SysApprovalPolicy{}, &model.SysApprovalTicket{}, &model.SysApprovalComment{}); err != nil {
+		t.Fatalf("Failed to auto migrate: %v", err)
+	}
+	sqlDB, _ := database.DB()
+	sqlDB.SetMaxOpenConns(1)
+
+	f := &approvalFixture{database: database, tokens: map[string]string{}}
+	now := util.HTime{Time: time.Now()}
+	var ops model.SysRole
+	database.Where("role_key = ?", "ops").First(&ops)
+	database.Where("role_key = ?", "guest").First(&f.approvers)
+	for _, value := range []string{"cloud:k8s:delete", "cmdb:db:dbms"} {
+		menu := model.SysMenu{Value: value, MenuType: 3, MenuStatus: 2, CreateTime: now}
+		database.Create(&menu)
+		database.Create(&model.SysRoleMenu{RoleId: ops.ID, MenuId: menu.ID})
+	}
+	for username, role := range map[string]model.SysRole{"grace": ops, "henry": f.approvers, "iris": f.approvers} {
+		admin := model.SysAdmin{Username: username, Password: util.EncryptionMd5("pass"), Status: 1, CreateTime: now}
+		database.Create(&admin)
+		database.Create(&model.SysAdminRole{AdminId: admin.ID, RoleId: role.ID})
+		f.tokens[username], _ = jwt.GenerateTokenByAdmin(admin, "", time.Hour)
+	}
+
+	gin.SetMode(gin.TestMode)
+	f.router = gin.New()
+	group := f.router.Group("/api/v1")
+	group.Use(middleware.AuthMiddleware(), middleware.PermissionMiddleware(), middleware.ApprovalMiddleware())
+	execute := func(c *gin.Context) {
+		admin, _ := jwt.GetAdmin(c)
+		f.executed = append(f.executed, admin.Username+" "+c.Request.URL.Path)
+		result.Success(c, true)
+	}
+	group.DELETE("/k8s/cluster/:id", execute)
+	group.POST("/cmdb/sql/execute", execute)
+	group.POST("/approval/policy/add", controller.CreateSysApprovalPolicy)
+	group.GET("/approval/ticket/info", controller.GetSysApprovalTicketInfo)
+	group.POST("/approval/ticket/approve", controller.ApproveSysApprovalTicket)
+	group.POST("/approval/ticket/reject", controller.RejectSysApprovalTicket)
+	group.POST("/approval/ticket/comment", controller.CommentSysApprovalTicket)
+	approval.SetHandler(f.router)
+	t.Cleanup(func() { approval.SetHandler(nil) })
+
+	// 策略由超级管理员维护，测试中直接写库
+	database.Create(&model.SysApprovalPolicy{Name: "删除集群", Method: http.MethodDelete, Path: "/api/v1/k8s/cluster/:id",
+		ApproverRoleIds: fmt.Sprint(f.approvers.ID), RequiredApprovals: 2, Status: 1, CreateTime: now})
+	database.Create(&model.SysApprovalPolicy{Name: "执行DDL", Method: http.MethodPost, Path: "/api/v1/cmdb/sql/execute",
+		Condition: cmdbcontroller.DDLCondition, ApproverRoleIds: fmt.Sprint(f.approvers.ID), RequiredApprovals: 1, Status: 1, CreateTime: now})
+	return f
+}
+
+func submitApproval(t *testing.T, f *approvalFixture, method, target string, body interface{}) model.SysApprovalTicket {
+	code, data := callApi(f.router, method, target, f.tokens["grace"], body)
+	if code != int(result.ApiCode.APPROVALREQUIRED) {
+		t.Fatalf("Expected approval to be required, got %d", code)
+	}
+	var ticket model.SysApprovalTicket
+	_ = json.Unmarshal(data, &ticket)
+	if ticket.ID == 0 || ticket.Status != model.ApprovalStatusPending {
+		t.Fatalf("Unexpected ticket: %+v", ticket)
+	}
+	return ticket
+}
+
+// 等待异步执行完成
+func waitApprovalTicket(t *testing.T, f *approvalFixture, id uint) model.SysApprovalTicket {
+	var ticket model.SysApprovalTicket
+	for i := 0; i < 100; i++ {
+		f.database.First(&ticket, id)
+		if ticket.Status != model.ApprovalStatusApproved {
+			return ticket
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	t.Fatalf("Approval ticket %d was not executed", id)
+	return ticket
+}
+
+func TestApprovalDeferredExecution(t *testing.T) {
+	f := setupApproval(t)
+	ticket := submitApproval(t, f, http.MethodDelete, "/api/v1/k8s/cluster/7", nil)
+	if len(f.executed) != 0 {
+		t.Fatalf("Expected request to be deferred, got %v", f.executed)
+	}
+
+	action := func(username, target string) int {
+		code, _ := callApi(f.router, http.MethodPost, target, f.tokens[username], model.SysApprovalTicketActionDto{Id: ticket.ID, Content: "ok"})
+		return code
+	}
+	if action("grace", "/api/v1/approval/ticket/approve") == 200 {
+		t.Errorf("Expected applicant not to approve own ticket")
+	}
+	if action("henry", "/api/v1/approval/ticket/approve") != 200 {
+		t.Fatalf("First approval failed")
+	}
+	if action("henry", "/api/v1/approval/ticket/approve") == 200 {
+		t.Errorf("Expected duplicate approval to be rejected")
+	}
+	if action("grace", "/api/v1/approval/ticket/comment") != 200 {
+		t.Errorf("Expected applicant to comment")
+	}
+	if len(f.executed) != 0 {
+		t.Fatalf("Expected request to wait for second approver, got %v", f.executed)
+	}
+	if action("iris", "/api/v1/approval/ticket/approve") != 200 {
+		t.Fatalf("Second approval failed")
+	}
+
+	ticket = waitApprovalTicket(t, f, ticket.ID)
+	if ticket.Status != model.ApprovalStatusSuccess || ticket.ApprovedCount != 2 {
+		t.Errorf("Expected ticket to be executed, got %+v", ticket)
+	}
+	if len(f.executed) != 1 || f.executed[0] != "grace /api/v1/k8s/cluster/7" {
+		t.Errorf("Expected request to be executed once as applicant, got %v", f.executed)
+	}
+
+	code, data := callApi(f.router, http.MethodGet, "/api/v1/approval/ticket/info?id="+fmt.Sprint(ticket.ID), f.tokens["grace"], nil)
+	var vo model.SysApprovalTicketVo
+	_ = json.Unmarshal(data, &vo)
+	if code != 200 || len(vo.Comments) != 3 {
+		t.Errorf("Expected 2 approvals and 1 comment, got %d %+v", code, vo.Comments)
+	}
+}
+
+func TestApprovalCondition(t *testing.T) {
+	f := setupApproval(t)
+
+	// 非 DDL 语句不需要审批
+	if code, _ := callApi(f.router, http.MethodPost, "/api/v1/cmdb/sql/execute", f.tokens["grace"],
+		map[string]string{"sql": "SELECT 1"}); code != 200 || len(f.executed) != 1 {
+		t.Fatalf("Expected select to execute directly, got %d %v", code, f.executed)
+	}
+
+	ticket := submitApproval(t, f, http.MethodPost, "/api/v1/cmdb/sql/execute", map[string]string{"sql": "DROP TABLE users"})
+	if code, _ := callApi(f.router, http.MethodPost, "/api/v1/approval/ticket/reject", f.tokens["henry"],
+		model.SysApprovalTicketActionDto{Id: ticket.ID, Content: "no"}); code != 200 {
+		t.Fatalf("Reject failed: %d", code)
+	}
+	f.database.First(&ticket, ticket.ID)
+	if ticket.Status != model.ApprovalStatusRejected || len(f.executed) != 1 {
+		t.Errorf("Expected rejected DDL not to execute, got %+v %v", ticket, f.executed)
+	}
+	if code, _ := callApi(f.router, http.MethodPost, "/api/v1/approval/ticket/approve", f.tokens["iris"],
+		model.SysApprovalTicketActionDto{Id: ticket.ID}); code == 200 {
+		t.Errorf("Expected rejected ticket not to be approved")
+	}
+}
+
+func TestApprovalRequiresApproverRoles(t *testing.T) {
+	f := setupApproval(t)
+	now := util.HTime{Time: time.Now()}
+
+	// 策略必须指定审批人角色
+	superRole := model.SysRole{RoleName: "超级管理员", RoleKey: constant.SUPER_ADMIN_ROLE_KEY, Status: 1, CreateTime: now}
+	f.database.Create(&superRole)
+	root := model.SysAdmin{Username: "root", Password: util.EncryptionMd5("pass"), Status: 1, CreateTime: now}
+	f.database.Create(&root)
+	f.database.Create(&model.SysAdminRole{AdminId: root.ID, RoleId: superRole.ID})
+	f.tokens["root"], _ = jwt.GenerateTokenByAdmin(root, "", time.Hour)
+	if code, _ := callApi(f.router, http.MethodPost, "/api/v1/approval/policy/add", f.tokens["root"], model.SysApprovalPolicyDto{
+		Name: "删除节点", Method: http.MethodDelete, Path: "/api/v1/k8s/node/:id", RequiredApprovals: 1, Status: 1}); code == 200 {
+		t.Errorf("Expected policy without approver roles to be rejected")
+	}
+
+	// 历史策略未指定审批人角色时，只有超级管理员可以审批
+	f.database.Model(&model.SysApprovalPolicy{}).Where("path = ?", "/api/v1/k8s/cluster/:id").
+		Updates(map[string]interface{}{"approver_role_ids": "", "required_approvals": 1})
+	ticket := submitApproval(t, f, http.MethodDelete, "/api/v1/k8s/cluster/8", nil)
+	approve := func(username string) int {
+		code, _ := callApi(f.router, http.MethodPost, "/api/v1/approval/ticket/approve", f.tokens[username], model.SysApprovalTicketActionDto{Id: ticket.ID})
+		return code
+	}
+	if approve("henry") == 200 {
+		t.Errorf("Expected ordinary user not to approve ticket without approver roles")
+	}
+	if approve("root") != 200 {
+		t.Fatalf("Expected super admin to approve ticket without approver roles")
+	}
+	// 等待异步执行结束，避免写入后续用例的数据库
+	waitApprovalTicket(t, f, ticket.ID)
+}
+
+func TestApprovalTicketSecrets(t *testing.T) {
+	f := setupApproval(t)
+	ticket := submitApproval(t, f, http.MethodPost, "/api/v1/cmdb/sql/execute",
+		map[string]string{"sql": "DROP TABLE users", "password": "db-pass", "private_key": "key-data"})
+	if strings.Contains(ticket.Body, "db-pass") || strings.Contains(ticket.Body, "key-data") || !strings.Contains(ticket.Body, "DROP TABLE users") {
+		t.Errorf("Expected secret fields masked in submitted ticket, got %s", ticket.Body)
+	}
+
+	// 请求体加密保存，读取时解密为原始请求
+	var raw string
+	f.database.Raw("SELECT body FROM sys_approval_ticket WHERE id = ?", ticket.ID).Scan(&raw)
+	if raw == "" || strings.Contains(raw, "DROP TABLE") {
+		t.Errorf("Expected encrypted body, got %q", raw)
+	}
+	var saved model.SysApprovalTicket
+	f.database.First(&saved, ticket.ID)
+	if !strings.Contains(saved.Body, "db-pass") {
+		t.Errorf("Expected original body after decrypt, got %s", saved.Body)
+	}
+
+	_, data := callApi(f.router, http.MethodGet, fmt.Sprintf("/api/v1/approval/ticket/info?id=%d", ticket.ID), f.tokens["henry"], nil)
+	var info model.SysApprovalTicketVo
+	_ = json.Unmarshal(data, &info)
+	if info.ID != ticket.ID || strings.Contains(info.Body, "db-pass") {
+		t.Errorf("Expected secret fields masked in ticket info, got %+v", info)
+	}
+
+	if code, _ := callApi(f.router, http.MethodPost, "/api/v1/approval/ticket/approve", f.tokens["henry"],
+		model.SysApprovalTicketActionDto{Id: ticket.ID}); code != 200 {
+		t.Fatalf("Approve failed: %d", code)
+	}
+	if saved = waitApprovalTicket(t, f, ticket.ID); saved.Status != model.ApprovalStatusSuccess || saved.Result == "" {
+		t.Fatalf("Expected ticket executed, got %+v", saved)
+	}
+	f.database.Raw("SELECT result FROM sys_approval_ticket WHERE id = ?", ticket.ID).Scan(&raw)
+	if raw == saved.Result {
+		t.Errorf("Expected encrypted result, got %q", raw)
+	}
+}
@@ -0,0 +1,167 @@
+package test
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"dodevops-api/api/system/controller"
+	"dodevops-api/api/system/dao"
+	"dodevops-api/api/system/model"
+	"dodevops-api/api/system/service"
+	"dodevops-api/common/util"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+func setupAuditLog(t *testing.T, count int) (*gorm.DB, *gin.Engine) {
+	database := setupSystemDB(t)
+	if err := database.AutoMigrate(&model.SysOperationLog{}, &model.SysOperationLogArchive{}, &model.SysOperationLogChain{}); err != nil {
+		t.Fatalf("Failed to auto migrate: %v", err)
+	}
+	loadTestConfig(t, fmt.Sprintf("audit:\n  archiveDir: %s\n", t.TempDir()))
+	for i := 1; i <= count; i++ {
+		dao.CreateSysOperationLog(model.SysOperationLog{AdminId: 1, Username: "admin", Method: "post", Ip: "127.0.0.1",
+			Url: fmt.Sprintf("/api/v1/admin/%d", i), Description: "修改用户", CreateTime: util.HTime{Time: time.Now()}})
+	}
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/api/v1/sysOperationLog/verify", controller.VerifySysOperationLog)
+	router.GET("/api/v1/sysOperationLog/export", controller.ExportSysOperationLog)
+	router.DELETE("/api/v1/sysOperationLog/delete", controller.DeleteSysOperationLogById)
+	router.DELETE("/api/v1/sysOperationLog/clean", controller.CleanSysOperationLog)
+	return database, router
+}
+
+func verifyAuditLog(t *testing.T, router *gin.Engine) model.SysOperationLogVerifyVo {
+	code, data := callApi(router, http.MethodGet, "/api/v1/sysOperationLog/verify", "", nil)
+	if code != 200 {
+		t.Fatalf("Verify failed: %d", code)
+	}
+	var vo model.SysOperationLogVerifyVo
+	_ = json.Unmarshal(data, &vo)
+	return vo
+}
+
+func TestAuditLogVerify(t *testing.T) {
+	database, router := setupAuditLog(t, 5)
+
+	if vo := verifyAuditLog(t, router); !vo.Valid || vo.Checked != 5 {
+		t.Fatalf("Expected intact chain of 5 logs, got %+v", vo)
+	}
+	var head model.SysOperationLogChain
+	database.First(&head)
+	var last model.SysOperationLog
+	database.Where("seq = ?", 5).First(&last)
+	if head.LastSeq != 5 || head.LastHash != last.Hash {
+		t.Errorf("Expected chain head to point at seq 5, got %+v", head)
+	}
+	if code, _ := callApi(router, http.MethodDelete, "/api/v1/sysOperationLog/delete", "", model.SysOperationLogIdDto{Id: 1}); code == 200 {
+		t.Errorf("Expected deleting chained log to be rejected")
+	}
+
+	// 直接修改数据库中的日志内容
+	database.Model(&model.SysOperationLog{}).Where("seq = ?", 2).Update("description", "查询用户")
+	vo := verifyAuditLog(t, router)
+	if vo.Valid || len(vo.Problems) != 1 || vo.Problems[0].Seq != 2 {
+		t.Errorf("Expected edited log to be detected, got %+v", vo)
+	}
+
+	// 删除中间的日志
+	database.Where("seq = ?", 4).Delete(&model.SysOperationLog{})
+	vo = verifyAuditLog(t, router)
+	if vo.Valid || len(vo.Problems) != 3 || vo.Problems[1].Seq != 5 || !strings.Contains(vo.Problems[1].Reason, "缺失序号") {
+		t.Errorf("Expected gap to be detected, got %+v", vo)
+	}
+}
+
+func TestAuditLogArchive(t *testing.T) {
+	database, router := setupAuditLog(t, 3)
+	// 启用哈希链前的历史日志
+	database.Create(&model.SysOperationLog{AdminId: 1, Username: "admin", Method: "post", Url: "/api/v1/legacy",
+		CreateTime: util.HTime{Time: time.Now().Add(-time.Hour)}})
+
+	archive, err := service.ArchiveSysOperationLog(time.Now().Add(time.Second))
+	if err != nil || archive == nil || archive.Count != 4 || archive.StartSeq != 1 || archive.EndSeq != 3 {
+		t.Fatalf("Archive failed: %v %+v", err, archive)
+	}
+	var count int64
+	database.Model(&model.SysOperationLog{}).Count(&count)
+	if count != 0 {
+		t.Errorf("Expected archived logs to be removed, got %d", count)
+	}
+	if lines := readArchive(t, archive.FileName); len(lines) != 4 {
+		t.Errorf("Expected 4 archived lines, got %d", len(lines))
+	}
+
+	// 新日志衔接归档的哈希链
+	dao.CreateSysOperationLog(model.SysOperationLog{AdminId: 1, Username: "admin", Method: "put", Url: "/api/v1/role",
+		CreateTime: util.HTime{Time: time.Now()}})
+	vo := verifyAuditLog(t, router)
+	if !vo.Valid || vo.Archives != 1 || vo.Checked != 1 {
+		t.Fatalf("Expected chain to continue after archive, got %+v", vo)
+	}
+
+	// 归档文件被篡改
+	if err := os.WriteFile(archive.FileName, []byte("tampered"), 0640); err != nil {
+		t.Fatalf("Failed to write archive: %v", err)
+	}
+	if vo := verifyAuditLog(t, router); vo.Valid {
+		t.Errorf("Expected tampered archive to be detected")
+	}
+}
+
+func readArchive(t *testing.T, fileName string) []string {
+	file, err := os.Open(fileName)
+	if err != nil {
+		t.Fatalf("Failed to open archive: %v", err)
+	}
+	defer file.Close()
+	gz, err := gzip.NewReader(file)
+	if err != nil {
+		t.Fatalf("Failed to read archive: %v", err)
+	}
+	var lines []string
+	scanner := bufio.NewScanner(gz)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	return lines
+}
+
+func TestAuditLogExport(t *testing.T) {
+	_, router := setupAuditLog(t, 3)
+
+	export := func(query string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/sysOperationLog/export?"+query, nil))
+		return w
+	}
+
+	w := export("format=jsonl&url=/admin/2")
+	lines := strings.Split(strings.TrimSpace(w.Body.String()), "\n")
+	var sysLog model.SysOperationLog
+	if len(lines) != 1 || json.Unmarshal([]byte(lines[0]), &sysLog) != nil || sysLog.Url != "/api/v1/admin/2" || sysLog.Hash == "" {
+		t.Errorf("Unexpected jsonl export: %q", w.Body.String())
+	}
+
+	w = export("username=admin")
+	if !strings.Contains(w.Header().Get("Content-Disposition"), ".csv") {
+		t.Errorf("Expected csv attachment, got %q", w.Header().Get("Content-Disposition"))
+	}
+	if rows := strings.Split(strings.TrimSpace(w.Body.String()), "\n"); len(rows) != 4 {
+		t.Errorf("Expected header and 3 csv rows, got %d", len(rows))
+	}
+	if w = export("username=nobody&format=jsonl"); strings.TrimSpace(w.Body.String()) != "" {
+		t.Errorf("Expected empty export for unknown user, got %q", w.Body.String())
+	}
+}
@@ -0,0 +1,267 @@
+package test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	cmdbcontroller "dodevops-api/api/cmdb/controller"
+	cmdbmodel "dodevops-api/api/cmdb/model"
+	configmodel "dodevops-api/api/configcenter/model"
+	systemcontroller "dodevops-api/api/system/controller"
+	systemmodel "dodevops-api/api/system/model"
+	"dodevops-api/common/util"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+func setupBatchCommand(t *testing.T) (*gorm.DB, *gin.Engine) {
+	database, router := setupCredentialRotation(t)
+	if err := database.AutoMigrate(&cmdbmodel.CmdbHostLabel{}, &cmdbmodel.CmdbBatchCommand{}, &cmdbmodel.CmdbBatchCommandHost{},
+		&systemmodel.SysCommandRule{}, &systemmodel.SysOperationLog{}); err != nil {
+		t.Fatalf("Failed to auto migrate: %v", err)
+	}
+	router.GET("/api/v1/cmdb/hostlabel/list", cmdbcontroller.GetCmdbHostLabels)
+	router.PUT("/api/v1/cmdb/hostlabel/update", cmdbcontroller.UpdateCmdbHostLabels)
+	router.POST("/api/v1/cmdb/batch/execute", cmdbcontroller.ExecuteCmdbBatchCommand)
+	router.POST("/api/v1/cmdb/batch/rerun", cmdbcontroller.RerunCmdbBatchCommand)
+	router.GET("/api/v1/cmdb/batch/info", cmdbcontroller.GetCmdbBatchCommandInfo)
+	router.GET("/api/v1/cmdb/batch/stream/:id", cmdbcontroller.StreamCmdbBatchCommand)
+	router.POST("/api/v1/terminal/rule/add", systemcontroller.CreateSysCommandRule)
+	return database, router
+}
+
+// 创建模拟主机，退出码写在主机的 $HOME/code 文件中
+func addBatchHost(t *testing.T, database *gorm.DB, name string, groupId uint, exitCode int) (cmdbmodel.CmdbHost, *fakeSSHHost) {
+	h := newFakeSSHHost(t, "pass", false)
+	_ = os.WriteFile(filepath.Join(h.home, "code"), []byte(fmt.Sprint(exitCode)), 0644)
+	return addRotationHost(t, database, name, groupId, h, configmodel.EcsAuth{Name: name, Type: 1, Username: "root", Password: "pass", Port: 22}), h
+}
+
+// 执行批量命令并等待执行结束
+func waitBatchCommand(t *testing.T, router *gin.Engine, target string, body interface{}) cmdbmodel.CmdbBatchCommandVo {
+	code, data := callApi(router, http.MethodPost, target, "", body)
+	var record cmdbmodel.CmdbBatchCommand
+	if code != 200 || json.Unmarshal(data, &record) != nil || record.ID == 0 {
+		t.Fatalf("Execute batch command failed: %d %s", code, data)
+	}
+	var info cmdbmodel.CmdbBatchCommandVo
+	for i := 0; i < 200; i++ {
+		_, data = callApi(router, http.MethodGet, fmt.Sprintf("/api/v1/cmdb/batch/info?id=%d", record.ID), "", nil)
+		_ = json.Unmarshal(data, &info)
+		if info.Status != cmdbmodel.BatchCommandRunning {
+			return info
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	t.Fatalf("Batch command %d did not finish", record.ID)
+	return info
+}
+
+func TestHostLabelSelector(t *testing.T) {
+	database, router := setupBatchCommand(t)
+	now := util.HTime{Time: time.Now()}
+	for i, labels := range []map[string]string{
+		{"env": "prod", "role": "web"},
+		{"env": "prod", "role": "api"},
+		{"env": "test", "role": "web"},
+		{"env": "prod"},
+	} {
+		host := cmdbmodel.CmdbHost{HostName: fmt.Sprintf("host-%d", i+1), CreateTime: now}
+		database.Create(&host)
+		if code, data := callApi(router, http.MethodPut, "/api/v1/cmdb/hostlabel/update", "",
+			cmdbmodel.CmdbHostLabelDto{HostID: host.ID, Labels: labels}); code != 200 {
+			t.Fatalf("Update labels failed: %s", data)
+		}
+	}
+	if code, _ := callApi(router, http.MethodPut, "/api/v1/cmdb/hostlabel/update", "",
+		cmdbmodel.CmdbHostLabelDto{HostID: 1, Labels: map[string]string{"env": "a,b"}}); code == 200 {
+		t.Errorf("Expected label value with comma to be rejected")
+	}
+	_, data := callApi(router, http.MethodGet, "/api/v1/cmdb/hostlabel/list?hostId=1", "", nil)
+	var labels map[string]string
+	if json.Unmarshal(data, &labels) != nil || labels["env"] != "prod" || labels["role"] != "web" {
+		t.Errorf("Expected labels of host 1, got %s", data)
+	}
+
+	cases := []struct {
+		selector string
+		total    int
+	}{
+		{"env=prod", 3},
+		{"env==prod,role=web", 1},
+		{"role in (web, api)", 3},
+		{"env=prod,role notin (web)", 2},
+		{"env!=prod", 1},
+		{"role", 3},
+		{"!role", 1},
+	}
+	for _, tc := range cases {
+		// 主机未配置凭据，只校验选中的主机数
+		info := waitBatchCommand(t, router, "/api/v1/cmdb/batch/execute", cmdbmodel.CmdbBatchCommandDto{Command: "true", Selector: tc.selector})
+		if info.Total != tc.total {
+			t.Errorf("Selector %q: expected %d hosts, got %d", tc.selector, tc.total, info.Total)
+		}
+	}
+	for _, selector := range []string{"env in ()", "env=", "bad key=1", "role=web,env notin (a"} {
+		if code, _ := callApi(router, http.MethodPost, "/api/v1/cmdb/batch/execute", "",
+			cmdbmodel.CmdbBatchCommandDto{Command: "true", Selector: selector}); code == 200 {
+			t.Errorf("Expected selector %q to be rejected", selector)
+		}
+	}
+}
+
+func TestBatchCommandExecute(t *testing.T) {
+	database, router := setupBatchCommand(t)
+	now := util.HTime{Time: time.Now()}
+	web := cmdbmodel.CmdbGroup{Name: "web", CreateTime: now}
+	database.Create(&web)
+	nginx := cmdbmodel.CmdbGroup{Name: "nginx", ParentID: web.ID, CreateTime: now}
+	database.Create(&nginx)
+	db := cmdbmodel.CmdbGroup{Name: "db", CreateTime: now}
+	database.Create(&db)
+
+	web1, _ := addBatchHost(t, database, "web-1", web.ID, 0)
+	addBatchHost(t, database, "nginx-1", nginx.ID, 0)
+	db1, _ := addBatchHost(t, database, "db-1", db.ID, 3)
+	broken := cmdbmodel.CmdbHost{HostName: "broken", GroupID: db.ID, CreateTime: now}
+	database.Create(&broken)
+
+	// 分组包含子分组，与主机ID取并集
+	info := waitBatchCommand(t, router, "/api/v1/cmdb/batch/execute", cmdbmodel.CmdbBatchCommandDto{
+		Command: "echo hello; echo line2 >&2; exit $(cat $HOME/code)", GroupIds: []uint{web.ID}, HostIds: []uint{db1.ID, broken.ID, web1.ID}})
+	if info.Status != cmdbmodel.BatchCommandPartial || info.Total != 4 || info.SuccessCount != 2 || info.FailedCount != 2 {
+		t.Fatalf("Expected partial success on 4 hosts, got %+v", info.CmdbBatchCommand)
+	}
+	results := map[string]cmdbmodel.CmdbBatchCommandHost{}
+	for _, host := range info.Hosts {
+		results[host.HostName] = host
+	}
+	if r := results["web-1"]; r.Status != cmdbmodel.BatchCommandHostSuccess || len(r.Output) != 12 ||
+		!strings.Contains(r.Output, "hello\n") || !strings.Contains(r.Output, "line2\n") {
+		t.Errorf("Unexpected result of web-1: %+v", r)
+	}
+	if r := results["db-1"]; r.Status != cmdbmodel.BatchCommandHostFailed || r.ExitCode != 3 {
+		t.Errorf("Expected db-1 to exit with 3, got %+v", r)
+	}
+	if r := results["broken"]; r.Status != cmdbmodel.BatchCommandHostError || r.ExitCode != -1 {
+		t.Errorf("Expected broken host to fail without exit code, got %+v", r)
+	}
+	if len(info.ExitCodes) != 3 || info.ExitCodes[0].ExitCode != -1 || info.ExitCodes[1].ExitCode != 0 ||
+		info.ExitCodes[1].Count != 2 || info.ExitCodes[2].Hosts[0] != "db-1" {
+		t.Errorf("Unexpected exit code summary: %+v", info.ExitCodes)
+	}
+
+	// 已结束的记录补发全部输出
+	req := httptest.NewRequest(http.MethodGet, fmt.Sprintf("/api/v1/cmdb/batch/stream/%d", info.ID), nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	stream := w.Body.String()
+	if strings.Count(stream, "event: output") != 6 || strings.Count(stream, "event: host") != 4 || !strings.HasSuffix(stream, "\n\n") ||
+		!strings.Contains(stream, "event: complete") || !strings.Contains(stream, `"data":"line2"`) {
+		t.Errorf("Unexpected stream of finished command: %s", stream)
+	}
+
+	// 只在未成功的主机上重新执行
+	rerun := waitBatchCommand(t, router, "/api/v1/cmdb/batch/rerun", cmdbmodel.CmdbBatchCommandRerunDto{Id: info.ID, FailedOnly: true})
+	if rerun.Total != 2 || rerun.RerunID != info.ID || rerun.Status != cmdbmodel.BatchCommandFailed {
+		t.Errorf("Expected rerun on 2 failed hosts, got %+v", rerun.CmdbBatchCommand)
+	}
+	rerun = waitBatchCommand(t, router, "/api/v1/cmdb/batch/rerun", cmdbmodel.CmdbBatchCommandRerunDto{Id: info.ID})
+	if rerun.Total != 4 {
+		t.Errorf("Expected full rerun on 4 hosts, got %+v", rerun.CmdbBatchCommand)
+	}
+}
+
+func TestBatchCommandStreamAndTimeout(t *testing.T) {
+	database, router := setupBatchCommand(t)
+	group := cmdbmodel.CmdbGroup{Name: "app", CreateTime: util.HTime{Time: time.Now()}}
+	database.Create(&group)
+	addBatchHost(t, database, "fast", group.ID, 0)
+	slow, h := addBatchHost(t, database, "slow", group.ID, 0)
+
+	// 慢主机超时
+	_ = os.WriteFile(filepath.Join(h.home, "slow"), nil, 0644)
+	code, data := callApi(router, http.MethodPost, "/api/v1/cmdb/batch/execute", "", cmdbmodel.CmdbBatchCommandDto{
+		Command: "echo start; if [ -f $HOME/slow ]; then sleep 10; fi; echo done", GroupIds: []uint{group.ID}, Timeout: 1})
+	var record cmdbmodel.CmdbBatchCommand
+	if code != 200 || json.Unmarshal(data, &record) != nil {
+		t.Fatalf("Execute failed: %d %s", code, data)
+	}
+
+	// 执行中订阅，持续推送到全部主机结束
+	req := httptest.NewRequest(http.MethodGet, fmt.Sprintf("/api/v1/cmdb/batch/stream/%d", record.ID), nil)
+	w := httptest.NewRecorder()
+	finished := make(chan struct{})
+	go func() {
+		router.ServeHTTP(w, req)
+		close(finished)
+	}()
+	select {
+	case <-finished:
+	case <-time.After(10 * time.Second):
+		t.Fatalf("Stream did not finish")
+	}
+	stream := w.Body.String()
+	if !strings.Contains(stream, "event: complete") || strings.Count(stream, `"data":"start"`) != 2 || strings.Count(stream, `"data":"done"`) != 1 {
+		t.Fatalf("Unexpected live stream: %s", stream)
+	}
+
+	_, data = callApi(router, http.MethodGet, fmt.Sprintf("/api/v1/cmdb/batch/info?id=%d", record.ID), "", nil)
+	var info cmdbmodel.CmdbBatchCommandVo
+	_ = json.Unmarshal(data, &info)
+	for _, host := range info.Hosts {
+		expected := cmdbmodel.BatchCommandHostSuccess
+		if host.HostID == slow.ID {
+			expected = cmdbmodel.BatchCommandHostTimeout
+		}
+		if host.Status != expected {
+			t.Errorf("Host %s: expected status %d, got %+v", host.HostName, expected, host)
+		}
+	}
+	if info.Status != cmdbmodel.BatchCommandPartial || info.SuccessCount != 1 {
+		t.Errorf("Expected partial success, got %+v", info.CmdbBatchCommand)
+	}
+}
+
+func TestBatchCommandRules(t *testing.T) {
+	database, router := setupBatchCommand(t)
+	group := cmdbmodel.CmdbGroup{Name: "prod", CreateTime: util.HTime{Time: time.Now()}}
+	database.Create(&group)
+	h := newFakeSSHHost(t, "pass", false)
+	host := addRotationHost(t, database, "prod-1", group.ID, h, configmodel.EcsAuth{Name: "prod", Type: 1, Username: "root", Password: "pass", Port: 22})
+	for _, rule := range []systemmodel.SysCommandRuleDto{
+		{Name: "生产禁止重启", MatchType: systemmodel.CommandMatchPrefix, Pattern: "reboot", Action: systemmodel.CommandRuleDeny, GroupIds: []uint{group.ID}},
+		{Name: "删除需要确认", MatchType: systemmodel.CommandMatchPrefix, Pattern: "rm", Action: systemmodel.CommandRuleConfirm},
+	} {
+		if code, data := callApi(router, http.MethodPost, "/api/v1/terminal/rule/add", "", rule); code != 200 {
+			t.Fatalf("Failed to create rule %s: %s", rule.Name, data)
+		}
+	}
+
+	if code, _ := callApi(router, http.MethodPost, "/api/v1/cmdb/batch/execute", "",
+		cmdbmodel.CmdbBatchCommandDto{Command: "reboot", HostIds: []uint{host.ID}, Confirmed: true}); code == 200 {
+		t.Errorf("Expected denied command to be rejected")
+	}
+	if code, _ := callApi(router, http.MethodPost, "/api/v1/cmdb/batch/execute", "",
+		cmdbmodel.CmdbBatchCommandDto{Command: "rm -f /tmp/none", HostIds: []uint{host.ID}}); code == 200 {
+		t.Errorf("Expected command requiring confirmation to be rejected without confirmed")
+	}
+	info := waitBatchCommand(t, router, "/api/v1/cmdb/batch/execute",
+		cmdbmodel.CmdbBatchCommandDto{Command: "rm -f $HOME/none", HostIds: []uint{host.ID}, Confirmed: true})
+	if info.Status != cmdbmodel.BatchCommandSuccess {
+		t.Errorf("Expected confirmed command to run, got %+v", info.CmdbBatchCommand)
+	}
+	var count int64
+	database.Model(&cmdbmodel.CmdbBatchCommand{}).Count(&count)
+	if count != 1 {
+		t.Errorf("Expected rejected commands not to be recorded, got %d records", count)
+	}
+}
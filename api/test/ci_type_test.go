@@ -0,0 +1,298 @@
+package test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	cmdbcontroller "dodevops-api/api/cmdb/controller"
+	cmdbmodel "dodevops-api/api/cmdb/model"
+	configmodel "dodevops-api/api/configcenter/model"
+	"dodevops-api/common/util"
+
+	"github.com/gin-gonic/gin"
+	"github.com/xuri/excelize/v2"
+	"gorm.io/gorm"
+)
+
+func setupCIType(t *testing.T) (*gorm.DB, *gin.Engine) {
+	database := setupSystemDB(t)
+	if err := database.AutoMigrate(&cmdbmodel.CmdbGroup{}, &cmdbmodel.CmdbHost{}, &configmodel.EcsAuth{}, &cmdbmodel.CmdbHostLabel{},
+		&cmdbmodel.CmdbCIType{}, &cmdbmodel.CmdbCIField{}, &cmdbmodel.CmdbHostAttr{}); err != nil {
+		t.Fatalf("Failed to auto migrate: %v", err)
+	}
+	// 新增主机后异步采集系统信息会并发写库，共享内存库只用一个连接避免表锁
+	sqlDB, _ := database.DB()
+	sqlDB.SetMaxOpenConns(1)
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	hostController := cmdbcontroller.NewCmdbHostController()
+	router.POST("/api/v1/cmdb/hostcreate", hostController.CreateCmdbHost)
+	router.PUT("/api/v1/cmdb/hostupdate", hostController.UpdateCmdbHost)
+	router.DELETE("/api/v1/cmdb/hostdelete", hostController.DeleteCmdbHost)
+	router.GET("/api/v1/cmdb/hostinfo", hostController.GetCmdbHostById)
+	router.POST("/api/v1/cmdb/hostimport", hostController.ImportHostsFromExcel)
+	router.GET("/api/v1/cmdb/hostexport", hostController.ExportHosts)
+	router.GET("/api/v1/cmdb/hostsearch", cmdbcontroller.SearchCmdbHostsByAttrs)
+	router.GET("/api/v1/cmdb/citype/list", cmdbcontroller.GetCmdbCITypeList)
+	router.POST("/api/v1/cmdb/citype/add", cmdbcontroller.CreateCmdbCIType)
+	router.DELETE("/api/v1/cmdb/citype/delete", cmdbcontroller.DeleteCmdbCIType)
+	router.POST("/api/v1/cmdb/citype/field/add", cmdbcontroller.CreateCmdbCIField)
+	router.PUT("/api/v1/cmdb/citype/field/update", cmdbcontroller.UpdateCmdbCIField)
+	return database, router
+}
+
+// 创建CI类型：机柜(必填)、SLA等级(枚举)、保修到期(日期)、上联交换机(引用)
+func createServerCIType(t *testing.T, router *gin.Engine) cmdbmodel.CmdbCIType {
+	code, data := callApi(router, http.MethodPost, "/api/v1/cmdb/citype/add", "", cmdbmodel.CmdbCITypeDto{Name: "物理服务器", Code: "server"})
+	var ciType cmdbmodel.CmdbCIType
+	if code != 200 || json.Unmarshal(data, &ciType) != nil {
+		t.Fatalf("Create CI type failed: %d %s", code, data)
+	}
+	for _, field := range []cmdbmodel.CmdbCIFieldDto{
+		{Key: "rack", Name: "机柜", Type: cmdbmodel.CIFieldString, Required: true},
+		{Key: "sla", Name: "SLA等级", Type: cmdbmodel.CIFieldEnum, Options: []string{"gold", "silver", " gold "}},
+		{Key: "warranty", Name: "保修到期", Type: cmdbmodel.CIFieldDate},
+		{Key: "uplink", Name: "上联交换机", Type: cmdbmodel.CIFieldReference},
+	} {
+		field.CITypeID = ciType.ID
+		if code, data := callApi(router, http.MethodPost, "/api/v1/cmdb/citype/field/add", "", field); code != 200 {
+			t.Fatalf("Create field %s failed: %s", field.Key, data)
+		}
+	}
+	_, data = callApi(router, http.MethodGet, "/api/v1/cmdb/citype/list", "", nil)
+	var list []cmdbmodel.CmdbCIType
+	if json.Unmarshal(data, &list) != nil || len(list) != 1 || len(list[0].Fields) != 4 || list[0].Fields[1].Options != "gold,silver" {
+		t.Fatalf("Unexpected CI type list: %s", data)
+	}
+	return list[0]
+}
+
+func createAttrHost(router *gin.Engine, name, ip string, groupId, ciTypeId uint, attrs map[string]string) (int, json.RawMessage) {
+	return callApi(router, http.MethodPost, "/api/v1/cmdb/hostcreate", "", cmdbmodel.CreateCmdbHostDto{
+		HostName: name, GroupID: groupId, SSHName: "root", SSHIP: ip, SSHPort: 1, SSHKeyID: 1, CITypeID: ciTypeId, Attrs: attrs})
+}
+
+func searchHosts(t *testing.T, router *gin.Engine, query string) []string {
+	code, data := callApi(router, http.MethodGet, "/api/v1/cmdb/hostsearch?"+query, "", nil)
+	var page struct {
+		List []cmdbmodel.CmdbHostVo `json:"list"`
+	}
+	if code != 200 || json.Unmarshal(data, &page) != nil {
+		t.Fatalf("Search %q failed: %d %s", query, code, data)
+	}
+	var names []string
+	for _, host := range page.List {
+		names = append(names, host.HostName)
+	}
+	return names
+}
+
+func TestCITypeHostAttrs(t *testing.T) {
+	database, router := setupCIType(t)
+	group := cmdbmodel.CmdbGroup{Name: "机房", CreateTime: util.HTime{Time: time.Now()}}
+	database.Create(&group)
+
+	if code, _ := callApi(router, http.MethodPost, "/api/v1/cmdb/citype/field/add", "",
+		cmdbmodel.CmdbCIFieldDto{CITypeID: 99, Key: "rack", Name: "机柜", Type: cmdbmodel.CIFieldString}); code == 200 {
+		t.Errorf("Expected field of unknown CI type to be rejected")
+	}
+	ciType := createServerCIType(t, router)
+	for _, field := range []cmdbmodel.CmdbCIFieldDto{
+		{CITypeID: ciType.ID, Key: "remark", Name: "备注", Type: cmdbmodel.CIFieldString},
+		{CITypeID: ciType.ID, Key: "tier", Name: "等级", Type: cmdbmodel.CIFieldEnum},
+		{CITypeID: ciType.ID, Key: "rack", Name: "机柜位置", Type: cmdbmodel.CIFieldString},
+		{CITypeID: ciType.ID, Key: "1bad", Name: "错误", Type: cmdbmodel.CIFieldString},
+	} {
+		if code, _ := callApi(router, http.MethodPost, "/api/v1/cmdb/citype/field/add", "", field); code == 200 {
+			t.Errorf("Expected invalid field %+v to be rejected", field)
+		}
+	}
+
+	// 校验自定义属性
+	if code, _ := createAttrHost(router, "sw-1", "127.0.0.1", group.ID, 0, map[string]string{"rack": "A01"}); code == 200 {
+		t.Errorf("Expected attrs without CI type to be rejected")
+	}
+	if code, _ := createAttrHost(router, "sw-1", "127.0.0.1", group.ID, 0, nil); code != 200 {
+		t.Fatalf("Create switch failed")
+	}
+	for _, attrs := range []map[string]string{
+		{"sla": "gold"},
+		{"rack": "A01", "sla": "bronze"},
+		{"rack": "A01", "warranty": "2026-13-01"},
+		{"rack": "A01", "uplink": "no-such-host"},
+		{"rack": "A01", "owner": "alice"},
+	} {
+		if code, _ := createAttrHost(router, "db-1", "127.0.0.2", group.ID, ciType.ID, attrs); code == 200 {
+			t.Errorf("Expected invalid attrs %v to be rejected", attrs)
+		}
+	}
+	code, data := createAttrHost(router, "db-1", "127.0.0.2", group.ID, ciType.ID,
+		map[string]string{"rack": "A01", "SLA等级": "gold", "warranty": "2026/6/1", "uplink": "sw-1"})
+	var created struct {
+		Id uint `json:"id"`
+	}
+	if code != 200 || json.Unmarshal(data, &created) != nil {
+		t.Fatalf("Create host with attrs failed: %s", data)
+	}
+	if code, _ := createAttrHost(router, "db-2", "127.0.0.3", group.ID, ciType.ID,
+		map[string]string{"rack": "B02", "sla": "silver", "warranty": "2027-01-31"}); code != 200 {
+		t.Fatalf("Create db-2 failed")
+	}
+
+	_, data = callApi(router, http.MethodGet, fmt.Sprintf("/api/v1/cmdb/hostinfo?id=%d", created.Id), "", nil)
+	var info cmdbmodel.CmdbHostVo
+	_ = json.Unmarshal(data, &info)
+	if info.CITypeName != "物理服务器" || info.Attrs["warranty"] != "2026-06-01" || info.Attrs["sla"] != "gold" || info.Attrs["uplink"] != "1" {
+		t.Errorf("Unexpected host detail: %s", data)
+	}
+
+	// 不传自定义属性时保留原有属性
+	update := cmdbmodel.UpdateCmdbHostDto{ID: created.Id, HostName: "db-1", GroupID: group.ID, SSHIP: "127.0.0.2", SSHName: "root",
+		SSHKeyID: 1, SSHPort: 1, CITypeID: ciType.ID}
+	if code, data := callApi(router, http.MethodPut, "/api/v1/cmdb/hostupdate", "", update); code != 200 {
+		t.Fatalf("Update host failed: %s", data)
+	}
+	_, data = callApi(router, http.MethodGet, fmt.Sprintf("/api/v1/cmdb/hostinfo?id=%d", created.Id), "", nil)
+	_ = json.Unmarshal(data, &info)
+	if info.Attrs["rack"] != "A01" {
+		t.Errorf("Expected attrs to be kept, got %v", info.Attrs)
+	}
+	update.Attrs = map[string]string{"rack": "A02", "sla": "gold"}
+	if code, _ := callApi(router, http.MethodPut, "/api/v1/cmdb/hostupdate", "", update); code != 200 {
+		t.Fatalf("Update host attrs failed")
+	}
+
+	cases := []struct {
+		query string
+		hosts string
+	}{
+		{fmt.Sprintf("ciTypeId=%d", ciType.ID), "db-1,db-2"},
+		{fmt.Sprintf("ciTypeId=%d&filter=sla=gold", ciType.ID), "db-1"},
+		{fmt.Sprintf("ciTypeId=%d&filter=sla!=gold", ciType.ID), "db-2"},
+		{fmt.Sprintf("ciTypeId=%d&filter=rack~A0", ciType.ID), "db-1"},
+		{fmt.Sprintf("ciTypeId=%d&filter=warranty>=2026-12-01&filter=rack~B", ciType.ID), "db-2"},
+		{fmt.Sprintf("ciTypeId=%d&filter=warranty<=2026-12-01", ciType.ID), ""},
+		{"keyword=B02", "db-2"},
+		{"keyword=sw", "sw-1"},
+	}
+	for _, tc := range cases {
+		if names := strings.Join(searchHosts(t, router, tc.query), ","); names != tc.hosts {
+			t.Errorf("Search %q: expected %q, got %q", tc.query, tc.hosts, names)
+		}
+	}
+	for _, query := range []string{"filter=sla=gold", fmt.Sprintf("ciTypeId=%d&filter=rack>=A", ciType.ID), fmt.Sprintf("ciTypeId=%d&filter=owner=a", ciType.ID)} {
+		if code, _ := callApi(router, http.MethodGet, "/api/v1/cmdb/hostsearch?"+query, "", nil); code == 200 {
+			t.Errorf("Expected search %q to be rejected", query)
+		}
+	}
+
+	// 已被使用的可选值不能删除，已被使用的CI类型不能删除
+	sla := ciType.Fields[1]
+	if code, _ := callApi(router, http.MethodPut, "/api/v1/cmdb/citype/field/update", "",
+		cmdbmodel.CmdbCIFieldDto{Id: sla.ID, Name: sla.Name, Options: []string{"gold", "bronze"}}); code == 200 {
+		t.Errorf("Expected removing used option to be rejected")
+	}
+	if code, _ := callApi(router, http.MethodPut, "/api/v1/cmdb/citype/field/update", "",
+		cmdbmodel.CmdbCIFieldDto{Id: sla.ID, Name: sla.Name, Options: []string{"gold", "silver", "bronze"}, Type: cmdbmodel.CIFieldString}); code != 200 {
+		t.Errorf("Expected adding option to succeed")
+	}
+	if code, _ := callApi(router, http.MethodDelete, "/api/v1/cmdb/citype/delete", "", cmdbmodel.CmdbCITypeIdDto{Id: ciType.ID}); code == 200 {
+		t.Errorf("Expected CI type in use not to be deleted")
+	}
+}
+
+func TestCITypeExcelExportImport(t *testing.T) {
+	database, router := setupCIType(t)
+	cwd, _ := os.Getwd()
+	_ = os.Chdir(t.TempDir())
+	t.Cleanup(func() { _ = os.Chdir(cwd) })
+
+	group := cmdbmodel.CmdbGroup{Name: "机房", CreateTime: util.HTime{Time: time.Now()}}
+	database.Create(&group)
+	ciType := createServerCIType(t, router)
+	createAttrHost(router, "sw-1", "127.0.0.1", group.ID, 0, nil)
+	if code, data := createAttrHost(router, "db-1", "127.0.0.2", group.ID, ciType.ID,
+		map[string]string{"rack": "A01", "sla": "gold", "warranty": "2026-06-01", "uplink": "sw-1"}); code != 200 {
+		t.Fatalf("Create host failed: %s", data)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, fmt.Sprintf("/api/v1/cmdb/hostexport?ciTypeId=%d", ciType.ID), nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	f, err := excelize.OpenReader(w.Body)
+	if err != nil {
+		t.Fatalf("Export is not a valid xlsx: %v", err)
+	}
+	rows, _ := f.GetRows("Sheet1")
+	if len(rows) != 2 {
+		t.Fatalf("Unexpected export: %v", rows)
+	}
+	exported := map[string]string{}
+	for i, title := range rows[0] {
+		if i < len(rows[1]) {
+			exported[title] = rows[1][i]
+		}
+	}
+	if exported["主机名称"] != "db-1" || exported["分组"] != "机房" || exported["CI类型"] != ciType.Code ||
+		exported["attr.rack"] != "A01" || exported["attr.sla"] != "gold" || exported["attr.warranty"] != "2026-06-01" || exported["attr.uplink"] != "sw-1" {
+		t.Fatalf("Unexpected export: %v", rows)
+	}
+
+	// 按Excel模板导入：一行新主机，一行枚举值错误
+	f = excelize.NewFile()
+	_ = f.SetSheetRow("Sheet1", "A1", &[]interface{}{"主机别名", "SSH地址", "SSH端口", "SSH用户", "备注", "分组", "内网IP", "公网IP", "操作系统", "机柜", "SLA等级", "保修到期", "上联交换机"})
+	_ = f.SetSheetRow("Sheet1", "A2", &[]interface{}{"db-2", "127.0.0.3", 22, "root", "", "机房", "", "", "", "A01", "silver", "2026-06-01", "sw-1"})
+	_ = f.SetSheetRow("Sheet1", "A3", &[]interface{}{"db-3", "127.0.0.4", 1, "root", "", "", "", "", "", "B01", "bronze"})
+	var file bytes.Buffer
+	_ = f.Write(&file)
+
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	part, _ := writer.CreateFormFile("file", "hosts.xlsx")
+	_, _ = part.Write(file.Bytes())
+	_ = writer.WriteField("groupId", fmt.Sprint(group.ID))
+	_ = writer.WriteField("ciTypeId", fmt.Sprint(ciType.ID))
+	_ = writer.Close()
+	req = httptest.NewRequest(http.MethodPost, "/api/v1/cmdb/hostimport", &body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	var res struct {
+		Data struct {
+			Success     int      `json:"success"`
+			Fail        int      `json:"fail"`
+			FailedHosts []string `json:"failedHosts"`
+		} `json:"data"`
+	}
+	_ = json.Unmarshal(w.Body.Bytes(), &res)
+	if res.Data.Success != 1 || res.Data.Fail != 1 || !strings.Contains(strings.Join(res.Data.FailedHosts, ","), "db-3") {
+		t.Fatalf("Unexpected import result: %s", w.Body.String())
+	}
+	var host cmdbmodel.CmdbHost
+	database.Where("host_name = ?", "db-2").First(&host)
+	_, data := callApi(router, http.MethodGet, fmt.Sprintf("/api/v1/cmdb/hostinfo?id=%d", host.ID), "", nil)
+	var info cmdbmodel.CmdbHostVo
+	_ = json.Unmarshal(data, &info)
+	if info.CITypeID != ciType.ID || info.Attrs["sla"] != "silver" || info.Attrs["rack"] != "A01" || info.Attrs["uplink"] != "1" {
+		t.Errorf("Unexpected imported host: %s", data)
+	}
+
+	// 删除被引用的主机时清除引用
+	if code, _ := callApi(router, http.MethodDelete, "/api/v1/cmdb/hostdelete", "", cmdbmodel.CmdbHostIdDto{ID: 1}); code != 200 {
+		t.Fatalf("Delete host failed")
+	}
+	_, data = callApi(router, http.MethodGet, fmt.Sprintf("/api/v1/cmdb/hostinfo?id=%d", host.ID), "", nil)
+	info = cmdbmodel.CmdbHostVo{}
+	_ = json.Unmarshal(data, &info)
+	if _, ok := info.Attrs["uplink"]; ok {
+		t.Errorf("Expected reference to deleted host to be removed, got %v", info.Attrs)
+	}
+}
@@ -0,0 +1,203 @@
+package test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	cmdbcontroller "dodevops-api/api/cmdb/controller"
+	cmdbdao "dodevops-api/api/cmdb/dao"
+	cmdbmodel "dodevops-api/api/cmdb/model"
+	cmdbservice "dodevops-api/api/cmdb/service"
+	"dodevops-api/api/system/model"
+	"dodevops-api/common/constant"
+	"dodevops-api/common/result"
+	"dodevops-api/common/util"
+	"dodevops-api/pkg/changelog"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+func setupChangeLog(t *testing.T) (*gorm.DB, *gin.Engine) {
+	database := setupSystemDB(t)
+	if err := changelog.Register(database); err != nil {
+		t.Fatalf("Failed to register change log: %v", err)
+	}
+	if err := database.AutoMigrate(&model.SysRoleGroup{}, &cmdbmodel.CmdbGroup{}, &cmdbmodel.CmdbHost{}, &cmdbmodel.CmdbSQL{}, &cmdbmodel.CmdbHostLabel{},
+		&cmdbmodel.CmdbCIType{}, &cmdbmodel.CmdbCIField{}, &cmdbmodel.CmdbHostAttr{}, &cmdbmodel.CmdbRelation{}, &cmdbmodel.CmdbChangeLog{}); err != nil {
+		t.Fatalf("Failed to auto migrate: %v", err)
+	}
+	admin := model.SysAdmin{Username: "ivy", Password: util.EncryptionMd5("pass"), DeptId: 1, Status: 1, CreateTime: util.HTime{Time: time.Now()}}
+	database.Create(&admin)
+	var role model.SysRole
+	database.Where("role_key = ?", "ops").First(&role)
+	database.Create(&model.SysAdminRole{AdminId: admin.ID, RoleId: role.ID})
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	// 模拟登录用户
+	router.Use(func(c *gin.Context) {
+		c.Set(constant.ContextKeyUserObj, &model.JwtAdmin{ID: admin.ID, Username: admin.Username})
+	})
+	router.PUT("/api/v1/cmdb/hostupdate", cmdbcontroller.NewCmdbHostController().UpdateCmdbHost)
+	router.DELETE("/api/v1/cmdb/hostdelete", cmdbcontroller.NewCmdbHostController().DeleteCmdbHost)
+	router.PUT("/api/v1/cmdb/groupupdate", cmdbcontroller.UpdateCmdbGroup)
+	router.GET("/api/v1/cmdb/history/list", cmdbcontroller.GetCmdbChangeHistory)
+	router.GET("/api/v1/cmdb/history/asof", cmdbcontroller.GetCmdbSnapshot)
+	router.GET("/api/v1/cmdb/history/diff", cmdbcontroller.GetCmdbSnapshotDiff)
+	return database, router
+}
+
+// 把尚未调整时间的变更记录设为指定时间，便于按时间点查询
+func stampChangeLogs(database *gorm.DB, lastId *uint, at string) {
+	t, _ := time.ParseInLocation("2006-01-02 15:04:05", at, time.Local)
+	database.Model(&cmdbmodel.CmdbChangeLog{}).Where("id > ?", *lastId).Update("create_time", util.HTime{Time: t})
+	var last cmdbmodel.CmdbChangeLog
+	database.Order("id desc").Limit(1).Find(&last)
+	*lastId = last.ID
+}
+
+func getSnapshot(t *testing.T, router *gin.Engine, recordType string, id uint, at string) cmdbmodel.CmdbSnapshotVo {
+	code, data := callApi(router, http.MethodGet, fmt.Sprintf("/api/v1/cmdb/history/asof?type=%s&id=%d&time=%s",
+		recordType, id, url.QueryEscape(at)), "", nil)
+	var snapshot cmdbmodel.CmdbSnapshotVo
+	if code != 200 || json.Unmarshal(data, &snapshot) != nil {
+		t.Fatalf("Snapshot at %s failed: %d %s", at, code, data)
+	}
+	return snapshot
+}
+
+func TestCmdbChangeLogHistory(t *testing.T) {
+	database, router := setupChangeLog(t)
+	var lastId uint
+	group := cmdbmodel.CmdbGroup{Name: "ops", CreateTime: util.HTime{Time: time.Now()}}
+	database.Create(&group)
+	host := cmdbmodel.CmdbHost{HostName: "web-1", GroupID: group.ID, SSHIP: "10.0.0.1", SSHName: "root", SSHKeyID: 1, SSHPort: 22,
+		Vendor: 1, Remark: "a", CreateTime: util.HTime{Time: time.Now()}}
+	database.Create(&host)
+	stampChangeLogs(database, &lastId, "2026-01-01 10:00:00")
+
+	// 用户修改主机
+	dto := cmdbmodel.UpdateCmdbHostDto{ID: host.ID, HostName: "web-01", GroupID: group.ID, SSHIP: "10.0.0.1", SSHName: "root",
+		SSHKeyID: 1, SSHPort: 22, Vendor: 1, Remark: "b"}
+	if code, data := callApi(router, http.MethodPut, "/api/v1/cmdb/hostupdate", "", dto); code != 200 {
+		t.Fatalf("Update host failed: %d %s", code, data)
+	}
+	stampChangeLogs(database, &lastId, "2026-01-01 11:00:00")
+
+	// 主机信息采集，状态和更新时间不记录
+	agentCtx := changelog.WithSource(context.Background(), cmdbmodel.ChangeSourceAgent, "主机信息采集")
+	hostDao := cmdbdao.NewCmdbHostDao()
+	if err := hostDao.WithContext(agentCtx).UpdateCmdbHost(host.ID, &cmdbmodel.CmdbHost{OS: "CentOS 7", Status: 1,
+		UpdateTime: util.HTime{Time: time.Now()}}); err != nil {
+		t.Fatalf("Agent update failed: %v", err)
+	}
+	stampChangeLogs(database, &lastId, "2026-01-01 12:00:00")
+
+	code, data := callApi(router, http.MethodGet, fmt.Sprintf("/api/v1/cmdb/history/list?type=host&id=%d", host.ID), "", nil)
+	var page struct {
+		List  []cmdbmodel.CmdbChangeLog `json:"list"`
+		Total int64                     `json:"total"`
+	}
+	if code != 200 || json.Unmarshal(data, &page) != nil || page.Total != 4 {
+		t.Fatalf("Unexpected history: %s", data)
+	}
+	var timeline []string
+	for _, item := range page.List {
+		timeline = append(timeline, fmt.Sprintf("%s:%s:%s:%s", item.Action, item.Field, item.Source, item.Operator))
+	}
+	if got := strings.Join(timeline, ","); got != "update:os:agent:主机信息采集,update:remark:user:ivy,update:host_name:user:ivy,create::system:" {
+		t.Errorf("Unexpected timeline: %s", got)
+	}
+	_, data = callApi(router, http.MethodGet, fmt.Sprintf("/api/v1/cmdb/history/list?type=host&id=%d&source=user&field=remark", host.ID), "", nil)
+	page.List = nil
+	_ = json.Unmarshal(data, &page)
+	if page.Total != 1 || page.List[0].OldValue != "a" || page.List[0].NewValue != "b" {
+		t.Errorf("Unexpected filtered history: %s", data)
+	}
+
+	// 按时间点还原状态
+	if snapshot := getSnapshot(t, router, "host", host.ID, "2026-01-01 09:00:00"); snapshot.Exists {
+		t.Errorf("Expected host not to exist before creation: %+v", snapshot)
+	}
+	snapshot := getSnapshot(t, router, "host", host.ID, "2026-01-01 10:30:00")
+	if !snapshot.Exists || snapshot.Fields["host_name"] != "web-1" || snapshot.Fields["remark"] != "a" || snapshot.Fields["os"] != "" {
+		t.Errorf("Unexpected snapshot at 10:30: %+v", snapshot)
+	}
+	if _, ok := snapshot.Fields["status"]; ok {
+		t.Errorf("Ignored column should not be in snapshot: %+v", snapshot.Fields)
+	}
+	snapshot = getSnapshot(t, router, "host", host.ID, "2026-01-01 11:00:00")
+	if snapshot.Fields["host_name"] != "web-01" || snapshot.Fields["os"] != "" {
+		t.Errorf("Unexpected snapshot at 11:00: %+v", snapshot)
+	}
+
+	code, data = callApi(router, http.MethodGet, fmt.Sprintf("/api/v1/cmdb/history/diff?type=host&id=%d&from=%s&to=%s", host.ID,
+		url.QueryEscape("2026-01-01 10:30:00"), url.QueryEscape("2026-01-01 12:30:00")), "", nil)
+	var diff cmdbmodel.CmdbSnapshotDiffVo
+	if code != 200 || json.Unmarshal(data, &diff) != nil {
+		t.Fatalf("Diff failed: %s", data)
+	}
+	var changes []string
+	for _, change := range diff.Changes {
+		changes = append(changes, fmt.Sprintf("%s:%s>%s", change.Field, change.From, change.To))
+	}
+	if got := strings.Join(changes, ","); got != "host_name:web-1>web-01,os:>CentOS 7,remark:a>b" {
+		t.Errorf("Unexpected diff: %s", got)
+	}
+
+	// 删除后仍可查询历史状态
+	if code, _ := callApi(router, http.MethodDelete, "/api/v1/cmdb/hostdelete", "", cmdbmodel.CmdbHostIdDto{ID: host.ID}); code != 200 {
+		t.Fatalf("Delete host failed")
+	}
+	stampChangeLogs(database, &lastId, "2026-01-01 13:00:00")
+	snapshot = getSnapshot(t, router, "host", host.ID, "2026-01-01 12:30:00")
+	if !snapshot.Exists || snapshot.Fields["host_name"] != "web-01" || snapshot.Fields["os"] != "CentOS 7" {
+		t.Errorf("Unexpected snapshot before deletion: %+v", snapshot)
+	}
+	if snapshot = getSnapshot(t, router, "host", host.ID, "2026-01-01 13:30:00"); snapshot.Exists {
+		t.Errorf("Expected host not to exist after deletion: %+v", snapshot)
+	}
+	var deleted cmdbmodel.CmdbChangeLog
+	database.Where("record_id = ? AND action = ?", host.ID, cmdbmodel.ChangeActionDelete).First(&deleted)
+	if deleted.Source != cmdbmodel.ChangeSourceUser || deleted.Operator != "ivy" {
+		t.Errorf("Unexpected delete record: %+v", deleted)
+	}
+
+	// 分组和数据库的变更
+	group.Name = "ops-2"
+	if code, data := callApi(router, http.MethodPut, "/api/v1/cmdb/groupupdate", "", group); code != 200 {
+		t.Fatalf("Update group failed: %s", data)
+	}
+	var groupLogs []cmdbmodel.CmdbChangeLog
+	database.Where("record_type = ? AND action = ?", cmdbmodel.ChangeRecordGroup, cmdbmodel.ChangeActionUpdate).Find(&groupLogs)
+	if len(groupLogs) != 1 || groupLogs[0].Field != "name" || groupLogs[0].NewValue != "ops-2" || groupLogs[0].Operator != "ivy" {
+		t.Errorf("Unexpected group change records: %+v", groupLogs)
+	}
+	orderDb := cmdbmodel.CmdbSQL{Name: "order", Type: 1, AccountID: 1, GroupID: group.ID}
+	sqlService := cmdbservice.NewCmdbSQLService(cmdbdao.NewCmdbSQLDao(database))
+	_ = sqlService.CreateDatabase(&orderDb)
+	orderDb.Name = "order-db"
+	orderDb.UpdatedAt = util.HTime{Time: time.Now().Add(time.Hour)}
+	if err := sqlService.UpdateDatabase(&orderDb); err != nil {
+		t.Fatalf("Update database failed: %v", err)
+	}
+	var sqlLogs []cmdbmodel.CmdbChangeLog
+	database.Where("record_type = ? AND record_id = ?", cmdbmodel.ChangeRecordDatabase, orderDb.ID).Order("id").Find(&sqlLogs)
+	if len(sqlLogs) != 2 || sqlLogs[1].Field != "name" || sqlLogs[1].Source != cmdbmodel.ChangeSourceSystem {
+		t.Errorf("Unexpected database change records: %+v", sqlLogs)
+	}
+
+	if code, _ := callApi(router, http.MethodGet, "/api/v1/cmdb/history/list?type=vm&id=1", "", nil); code == int(result.ApiCode.SUCCESS) {
+		t.Errorf("Expected invalid record type to be rejected")
+	}
+	if code, _ := callApi(router, http.MethodGet, fmt.Sprintf("/api/v1/cmdb/history/asof?type=group&id=%d&time=yesterday", group.ID), "", nil); code == 200 {
+		t.Errorf("Expected invalid time to be rejected")
+	}
+}
@@ -0,0 +1,176 @@
+package test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	appmodel "dodevops-api/api/app/model"
+	cmdbcontroller "dodevops-api/api/cmdb/controller"
+	cmdbmodel "dodevops-api/api/cmdb/model"
+	k8smodel "dodevops-api/api/k8s/model"
+	"dodevops-api/common/util"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+func setupRelation(t *testing.T) (*gorm.DB, *gin.Engine) {
+	database := setupSystemDB(t)
+	if err := database.AutoMigrate(&cmdbmodel.CmdbGroup{}, &cmdbmodel.CmdbHost{}, &cmdbmodel.CmdbSQL{}, &cmdbmodel.CmdbHostLabel{},
+		&cmdbmodel.CmdbCIType{}, &cmdbmodel.CmdbCIField{}, &cmdbmodel.CmdbHostAttr{}, &cmdbmodel.CmdbRelation{},
+		&k8smodel.KubeCluster{}, &appmodel.Application{}, &appmodel.QuickDeploymentTask{}); err != nil {
+		t.Fatalf("Failed to auto migrate: %v", err)
+	}
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.DELETE("/api/v1/cmdb/hostdelete", cmdbcontroller.NewCmdbHostController().DeleteCmdbHost)
+	router.POST("/api/v1/cmdb/relation/add", cmdbcontroller.CreateCmdbRelation)
+	router.DELETE("/api/v1/cmdb/relation/delete", cmdbcontroller.DeleteCmdbRelation)
+	router.GET("/api/v1/cmdb/relation/graph", cmdbcontroller.GetCmdbRelationGraph)
+	router.GET("/api/v1/cmdb/relation/impact", cmdbcontroller.GetCmdbRelationImpact)
+	router.POST("/api/v1/cmdb/relation/discover", cmdbcontroller.DiscoverCmdbRelations)
+	return database, router
+}
+
+func getRelationGraph(t *testing.T, router *gin.Engine, query string) cmdbmodel.CmdbRelationGraphVo {
+	code, data := callApi(router, http.MethodGet, "/api/v1/cmdb/relation/graph?"+query, "", nil)
+	var graph cmdbmodel.CmdbRelationGraphVo
+	if code != 200 || json.Unmarshal(data, &graph) != nil {
+		t.Fatalf("Graph %q failed: %d %s", query, code, data)
+	}
+	return graph
+}
+
+func graphNodeNames(graph cmdbmodel.CmdbRelationGraphVo) string {
+	var names []string
+	for _, node := range graph.Nodes {
+		names = append(names, fmt.Sprintf("%s@%d", node.Name, node.Depth))
+	}
+	return strings.Join(names, ",")
+}
+
+func TestCmdbRelationGraphAndImpact(t *testing.T) {
+	database, router := setupRelation(t)
+	now := util.HTime{Time: time.Now()}
+	var hosts []cmdbmodel.CmdbHost
+	for _, name := range []string{"web-1", "web-2"} {
+		host := cmdbmodel.CmdbHost{HostName: name, GroupID: 1, SSHIP: "10.0.0." + name[4:], CreateTime: now}
+		database.Create(&host)
+		hosts = append(hosts, host)
+	}
+	orderDb := cmdbmodel.CmdbSQL{Name: "order-db", Type: 1, AccountID: 1, GroupID: 1}
+	database.Create(&orderDb)
+	prod := k8smodel.KubeCluster{Name: "k8s-prod", Version: "v1.28"}
+	bad := k8smodel.KubeCluster{Name: "k8s-bad", Version: "v1.28", Credential: "not a kubeconfig"}
+	database.Create(&prod)
+	database.Create(&bad)
+	order := appmodel.Application{Name: "order", Code: "order", Hosts: appmodel.ResourceIDs{hosts[0].ID, hosts[1].ID, 99},
+		Databases: appmodel.ResourceIDs{orderDb.ID}}
+	report := appmodel.Application{Name: "report", Code: "report", Hosts: appmodel.ResourceIDs{hosts[1].ID}}
+	gateway := appmodel.Application{Name: "gateway", Code: "gateway"}
+	for _, app := range []*appmodel.Application{&order, &report, &gateway} {
+		database.Create(app)
+	}
+	database.Create(&appmodel.QuickDeploymentTask{DeploymentID: 1, AppID: order.ID, Status: 3, ExecuteOrder: 1})
+
+	// 自动发现：应用关联的主机和数据库，无法连接的集群记录错误
+	code, data := callApi(router, http.MethodPost, "/api/v1/cmdb/relation/discover", "", nil)
+	var discover cmdbmodel.CmdbRelationDiscoverVo
+	if code != 200 || json.Unmarshal(data, &discover) != nil || discover.Created != 4 || discover.Removed != 0 ||
+		len(discover.Errors) != 1 || !strings.Contains(discover.Errors[0], "k8s-bad") {
+		t.Fatalf("Unexpected discover result: %s", data)
+	}
+	var deployed cmdbmodel.CmdbRelation
+	database.Where("source_id = ? AND target_id = ? AND relation_type = ?", order.ID, hosts[0].ID, cmdbmodel.RelationRunsOn).First(&deployed)
+	if deployed.Origin != cmdbmodel.RelationOriginAuto || deployed.Remark != "快速发布目标主机" {
+		t.Errorf("Unexpected discovered relation: %+v", deployed)
+	}
+
+	for _, dto := range []cmdbmodel.CmdbRelationDto{
+		{SourceType: "host", SourceID: hosts[0].ID, TargetType: "cluster", TargetID: prod.ID, RelationType: cmdbmodel.RelationBelongsTo},
+		{SourceType: "app", SourceID: gateway.ID, TargetType: "cluster", TargetID: prod.ID, RelationType: cmdbmodel.RelationRunsOn},
+		{SourceType: "host", SourceID: hosts[0].ID, TargetType: "host", TargetID: hosts[1].ID, RelationType: cmdbmodel.RelationConnectsTo},
+	} {
+		if code, data := callApi(router, http.MethodPost, "/api/v1/cmdb/relation/add", "", dto); code != 200 {
+			t.Fatalf("Create relation failed: %s", data)
+		}
+	}
+	for _, dto := range []cmdbmodel.CmdbRelationDto{
+		{SourceType: "host", SourceID: hosts[0].ID, TargetType: "host", TargetID: hosts[0].ID, RelationType: cmdbmodel.RelationConnectsTo},
+		{SourceType: "host", SourceID: hosts[0].ID, TargetType: "cluster", TargetID: prod.ID, RelationType: "uses"},
+		{SourceType: "vm", SourceID: 1, TargetType: "cluster", TargetID: prod.ID, RelationType: cmdbmodel.RelationBelongsTo},
+		{SourceType: "app", SourceID: 99, TargetType: "host", TargetID: hosts[0].ID, RelationType: cmdbmodel.RelationRunsOn},
+		{SourceType: "app", SourceID: order.ID, TargetType: "host", TargetID: hosts[0].ID, RelationType: cmdbmodel.RelationRunsOn},
+	} {
+		if code, _ := callApi(router, http.MethodPost, "/api/v1/cmdb/relation/add", "", dto); code == 200 {
+			t.Errorf("Expected invalid relation %+v to be rejected", dto)
+		}
+	}
+
+	// 关系图按跳数展开
+	graph := getRelationGraph(t, router, fmt.Sprintf("type=host&id=%d", hosts[1].ID))
+	if names := graphNodeNames(graph); names != "web-2@0,order@1,report@1,web-1@1" {
+		t.Errorf("Unexpected 1 hop graph: %s", names)
+	}
+	if len(graph.Edges) != 3 {
+		t.Errorf("Expected 3 edges, got %+v", graph.Edges)
+	}
+	graph = getRelationGraph(t, router, fmt.Sprintf("type=host&id=%d&depth=2", hosts[1].ID))
+	if names := graphNodeNames(graph); names != "web-2@0,order@1,report@1,web-1@1,k8s-prod@2,order-db@2" {
+		t.Errorf("Unexpected 2 hop graph: %s", names)
+	}
+	graph = getRelationGraph(t, router, fmt.Sprintf("type=host&id=%d&depth=3&relationType=runs-on", hosts[1].ID))
+	if names := graphNodeNames(graph); names != "web-2@0,order@1,report@1,web-1@2" {
+		t.Errorf("Unexpected runs-on graph: %s", names)
+	}
+	if code, _ := callApi(router, http.MethodGet, "/api/v1/cmdb/relation/graph?type=host&id=99", "", nil); code == 200 {
+		t.Errorf("Expected graph of missing host to be rejected")
+	}
+
+	// 影响分析：主机故障影响运行在其上的应用、所属集群以及运行在集群上的应用
+	code, data = callApi(router, http.MethodGet, fmt.Sprintf("/api/v1/cmdb/relation/impact?type=host&id=%d", hosts[0].ID), "", nil)
+	var impact cmdbmodel.CmdbImpactVo
+	if code != 200 || json.Unmarshal(data, &impact) != nil || impact.Root.Name != "web-1" || len(impact.Affected) != 3 || len(impact.Apps) != 2 {
+		t.Fatalf("Unexpected impact: %s", data)
+	}
+	last := impact.Affected[2]
+	if last.Name != "gateway" || last.Depth != 2 || last.Relation != cmdbmodel.RelationRunsOn || strings.Join(last.Path, ">") != "web-1>k8s-prod>gateway" {
+		t.Errorf("Unexpected impact path: %+v", last)
+	}
+	_, data = callApi(router, http.MethodGet, fmt.Sprintf("/api/v1/cmdb/relation/impact?type=database&id=%d", orderDb.ID), "", nil)
+	impact = cmdbmodel.CmdbImpactVo{}
+	_ = json.Unmarshal(data, &impact)
+	if len(impact.Apps) != 1 || impact.Apps[0].Name != "order" {
+		t.Errorf("Unexpected database impact: %s", data)
+	}
+
+	// 重新发现时删除过期的自动关系，保留手工关系和无法连接集群的原有关系
+	database.Create(&cmdbmodel.CmdbRelation{SourceType: "host", SourceID: hosts[1].ID, TargetType: "cluster", TargetID: bad.ID,
+		RelationType: cmdbmodel.RelationBelongsTo, Origin: cmdbmodel.RelationOriginAuto, CreateTime: now})
+	database.Model(&report).Update("hosts", appmodel.ResourceIDs{})
+	_, data = callApi(router, http.MethodPost, "/api/v1/cmdb/relation/discover", "", nil)
+	discover = cmdbmodel.CmdbRelationDiscoverVo{}
+	_ = json.Unmarshal(data, &discover)
+	if discover.Created != 0 || discover.Removed != 1 {
+		t.Errorf("Unexpected rediscover result: %s", data)
+	}
+	var count int64
+	database.Model(&cmdbmodel.CmdbRelation{}).Count(&count)
+	if count != 7 {
+		t.Errorf("Expected 7 relations after rediscover, got %d", count)
+	}
+
+	// 删除主机时删除其关系
+	if code, _ := callApi(router, http.MethodDelete, "/api/v1/cmdb/hostdelete", "", cmdbmodel.CmdbHostIdDto{ID: hosts[0].ID}); code != 200 {
+		t.Fatalf("Delete host failed")
+	}
+	database.Model(&cmdbmodel.CmdbRelation{}).Where("(source_type = 'host' AND source_id = ?) OR (target_type = 'host' AND target_id = ?)",
+		hosts[0].ID, hosts[0].ID).Count(&count)
+	if count != 0 {
+		t.Errorf("Expected relations of deleted host to be removed, got %d", count)
+	}
+}
@@ -0,0 +1,163 @@
+package test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	cmdbmodel "dodevops-api/api/cmdb/model"
+	"dodevops-api/api/system/controller"
+	"dodevops-api/api/system/model"
+	"dodevops-api/api/system/service"
+	"dodevops-api/common/constant"
+	"dodevops-api/common/util"
+	wsutil "dodevops-api/common/util/websocket"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// 与 sql/update.sql 中默认规则相同的正则
+const rmRootPattern = `\brm\s+(-[-a-zA-Z]*\s+)*-[a-zA-Z]*[rR][a-zA-Z]*\s+(-[-a-zA-Z]*\s+)*/(\*)?(\s|$)`
+
+func setupCommandFilter(t *testing.T) (*gorm.DB, *gin.Engine) {
+	database := setupSystemDB(t)
+	if err := database.AutoMigrate(&model.SysCommandRule{}, &model.SysOperationLog{}, &model.SysOperationLogChain{}, &cmdbmodel.CmdbGroup{}); err != nil {
+		t.Fatalf("Failed to auto migrate: %v", err)
+	}
+	now := util.HTime{Time: time.Now()}
+	// 分组：1 开发，2 生产，3 生产/数据库
+	database.Create(&cmdbmodel.CmdbGroup{Name: "开发", CreateTime: now})
+	database.Create(&cmdbmodel.CmdbGroup{Name: "生产", CreateTime: now})
+	database.Create(&cmdbmodel.CmdbGroup{Name: "数据库", ParentID: 2, CreateTime: now})
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/api/v1/terminal/rule/add", controller.CreateSysCommandRule)
+	router.POST("/api/v1/terminal/rule/check", controller.CheckSysCommandRule)
+
+	for _, rule := range []model.SysCommandRuleDto{
+		{Name: "允许清理缓存", MatchType: model.CommandMatchPrefix, Pattern: "rm -rf /tmp/cache", Action: model.CommandRuleAllow, Priority: 1},
+		{Name: "禁止删除根目录", MatchType: model.CommandMatchRegex, Pattern: rmRootPattern, Action: model.CommandRuleDeny, Priority: 10},
+		{Name: "关机需要确认", MatchType: model.CommandMatchPrefix, Pattern: "shutdown", Action: model.CommandRuleConfirm, Priority: 20},
+		{Name: "生产禁止重启", MatchType: model.CommandMatchPrefix, Pattern: "reboot", Action: model.CommandRuleDeny, GroupIds: []uint{2}},
+		{Name: "访客禁止删除", MatchType: model.CommandMatchPrefix, Pattern: "rm", Action: model.CommandRuleDeny, RoleIds: []uint{2}},
+	} {
+		if code, _ := callApi(router, http.MethodPost, "/api/v1/terminal/rule/add", "", rule); code != 200 {
+			t.Fatalf("Failed to create rule %s: %d", rule.Name, code)
+		}
+	}
+	return database, router
+}
+
+func TestCommandRuleCheck(t *testing.T) {
+	_, router := setupCommandFilter(t)
+
+	if code, _ := callApi(router, http.MethodPost, "/api/v1/terminal/rule/add", "", model.SysCommandRuleDto{
+		Name: "错误正则", MatchType: model.CommandMatchRegex, Pattern: "rm (", Action: model.CommandRuleDeny}); code == 200 {
+		t.Errorf("Expected invalid regex to be rejected")
+	}
+
+	cases := []struct {
+		command string
+		roleIds []uint
+		groupId uint
+		action  int
+	}{
+		{"rm -rf /", []uint{1}, 0, model.CommandRuleDeny},
+		{"sudo rm -r -f --no-preserve-root /*", []uint{1}, 0, model.CommandRuleDeny},
+		{"rm -rf /tmp/build", []uint{1}, 0, model.CommandRuleAllow},
+		{"rm  -rf  /tmp/cache/*", []uint{1}, 0, model.CommandRuleAllow},
+		{"rm -f app.log", []uint{2}, 0, model.CommandRuleDeny},
+		{"cd /; sudo shutdown -h now", []uint{1}, 0, model.CommandRuleConfirm},
+		{"reboot", []uint{1}, 3, model.CommandRuleDeny},
+		{"reboot", []uint{1}, 1, model.CommandRuleAllow},
+		{"reboot", []uint{1}, 0, model.CommandRuleAllow},
+	}
+	for _, tc := range cases {
+		code, data := callApi(router, http.MethodPost, "/api/v1/terminal/rule/check", "",
+			model.SysCommandRuleCheckDto{Command: tc.command, RoleIds: tc.roleIds, GroupId: tc.groupId})
+		var vo model.SysCommandRuleCheckVo
+		_ = json.Unmarshal(data, &vo)
+		if code != 200 || vo.Action != tc.action {
+			t.Errorf("Command %q (roles %v, group %d): expected action %d, got %s", tc.command, tc.roleIds, tc.groupId, tc.action, data)
+		}
+	}
+}
+
+func newTerminalContext(admin *model.JwtAdmin) *gin.Context {
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest(http.MethodGet, "/api/v1/cmdb/hostssh/connect/1", nil)
+	c.Set(constant.ContextKeyUserObj, admin)
+	return c
+}
+
+func TestCommandGuard(t *testing.T) {
+	database, _ := setupCommandFilter(t)
+	now := util.HTime{Time: time.Now()}
+	superRole := model.SysRole{RoleName: "超级管理员", RoleKey: constant.SUPER_ADMIN_ROLE_KEY, Status: 1, CreateTime: now}
+	database.Create(&superRole)
+	database.Create(&model.SysAdminRole{AdminId: 1, RoleId: 1})
+	database.Create(&model.SysAdminRole{AdminId: 2, RoleId: superRole.ID})
+
+	if checker := service.NewTerminalCommandChecker(newTerminalContext(&model.JwtAdmin{ID: 2, Username: "root"}), 3, "db-01"); checker != nil {
+		t.Fatalf("Expected super admin to be unrestricted")
+	}
+	checker := service.NewTerminalCommandChecker(newTerminalContext(&model.JwtAdmin{ID: 1, Username: "alice"}), 3, "db-01")
+	if checker == nil {
+		t.Fatalf("Expected command checker for ops role")
+	}
+	guard := wsutil.NewCommandGuard(checker)
+
+	// 逐字输入的普通命令原样发送
+	var forward []byte
+	for _, key := range []string{"l", "s", "\r"} {
+		data, notice := guard.Filter([]byte(key))
+		if notice != "" {
+			t.Fatalf("Unexpected notice: %q", notice)
+		}
+		forward = append(forward, data...)
+	}
+	if string(forward) != "ls\r" {
+		t.Errorf("Expected ls forwarded, got %q", forward)
+	}
+
+	// 被拦截的命令不发送回车，丢弃同一次粘贴中剩余的命令
+	data, notice := guard.Filter([]byte("ls\rreboot\rwhoami\r"))
+	if string(data) != "ls\rreboot\x03" || !strings.Contains(notice, "生产禁止重启") {
+		t.Errorf("Expected reboot blocked, got %q %q", data, notice)
+	}
+
+	// 退格修改后的命令按最终内容检查
+	data, _ = guard.Filter([]byte("rm -rf /tmpx\x7f\x7f\x7f\x7f\r"))
+	if !strings.HasSuffix(string(data), "\x03") {
+		t.Errorf("Expected edited rm -rf / blocked, got %q", data)
+	}
+
+	// 需要确认的命令，取消后发送 Ctrl+C
+	data, notice = guard.Filter([]byte("shutdown -h now\r"))
+	if string(data) != "shutdown -h now" || !strings.Contains(notice, "输入 y 确认执行") {
+		t.Errorf("Expected confirmation prompt, got %q %q", data, notice)
+	}
+	if data, _ = guard.Filter([]byte("n")); string(data) != "\x03" {
+		t.Errorf("Expected cancelled command discarded, got %q", data)
+	}
+	guard.Filter([]byte("shutdown -r now\r"))
+	if data, _ = guard.Filter([]byte("y")); string(data) != "\r" {
+		t.Errorf("Expected confirmed command executed, got %q", data)
+	}
+
+	var logs []model.SysOperationLog
+	database.Order("id").Find(&logs)
+	if len(logs) != 4 {
+		t.Fatalf("Expected 4 operation logs, got %d", len(logs))
+	}
+	for i, prefix := range []string{"拦截终端命令[生产禁止重启] db-01: reboot", "拦截终端命令[禁止删除根目录]", "取消执行终端命令", "确认执行终端命令"} {
+		if !strings.HasPrefix(logs[i].Description, prefix) || logs[i].Username != "alice" || logs[i].Method != "terminal" {
+			t.Errorf("Unexpected operation log %d: %+v", i, logs[i])
+		}
+	}
+}
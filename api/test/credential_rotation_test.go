@@ -0,0 +1,416 @@
+package test
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	cmdbcontroller "dodevops-api/api/cmdb/controller"
+	cmdbmodel "dodevops-api/api/cmdb/model"
+	configcontroller "dodevops-api/api/configcenter/controller"
+	configdao "dodevops-api/api/configcenter/dao"
+	configmodel "dodevops-api/api/configcenter/model"
+	"dodevops-api/common/util"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/crypto/ssh"
+	"gorm.io/gorm"
+)
+
+// 模拟 SSH 主机：密码保存在 passwd 文件中，公钥认证读取 $HOME/.ssh/authorized_keys，
+// exec 请求通过本地 sh 执行，chpasswd 替换为写入 passwd 文件的脚本，direct-tcpip 转发用于模拟跳板机
+type fakeSSHHost struct {
+	dir       string
+	home      string
+	port      int
+	config    *ssh.ServerConfig
+	forwarded int32 // 经过本主机转发的连接数
+}
+
+func newFakeSSHHost(t *testing.T, password string, allowPublicKey bool) *fakeSSHHost {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh not available")
+	}
+	h := &fakeSSHHost{dir: t.TempDir()}
+	h.home = filepath.Join(h.dir, "home")
+	bin := filepath.Join(h.dir, "bin")
+	_ = os.MkdirAll(h.home, 0755)
+	_ = os.MkdirAll(bin, 0755)
+	_ = os.WriteFile(filepath.Join(bin, "chpasswd"), []byte("#!/bin/sh\ncat > \"$PASSWD_FILE\"\n"), 0755)
+	_ = os.WriteFile(h.passwdFile(), []byte("root:"+password+"\n"), 0600)
+
+	_, hostKey, _ := ed25519.GenerateKey(rand.Reader)
+	signer, _ := ssh.NewSignerFromKey(hostKey)
+	serverConfig := &ssh.ServerConfig{
+		PasswordCallback: func(conn ssh.ConnMetadata, pass []byte) (*ssh.Permissions, error) {
+			content, _ := os.ReadFile(h.passwdFile())
+			if strings.TrimSpace(string(content)) == conn.User()+":"+string(pass) {
+				return nil, nil
+			}
+			return nil, fmt.Errorf("password rejected")
+		},
+	}
+	if allowPublicKey {
+		serverConfig.PublicKeyCallback = func(conn ssh.ConnMetadata, key ssh.PublicKey) (*ssh.Permissions, error) {
+			if h.authorized(key) {
+				return nil, nil
+			}
+			return nil, fmt.Errorf("public key rejected")
+		}
+	}
+	serverConfig.AddHostKey(signer)
+	h.config = serverConfig
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	t.Cleanup(func() { _ = listener.Close() })
+	h.port = listener.Addr().(*net.TCPAddr).Port
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go h.serve(conn, serverConfig, bin)
+		}
+	}()
+	return h
+}
+
+// 更换主机密钥，模拟主机重装或连接被中间人劫持
+func (h *fakeSSHHost) rotateHostKey() ssh.PublicKey {
+	_, hostKey, _ := ed25519.GenerateKey(rand.Reader)
+	signer, _ := ssh.NewSignerFromKey(hostKey)
+	h.config.AddHostKey(signer)
+	return signer.PublicKey()
+}
+
+func (h *fakeSSHHost) passwdFile() string {
+	return filepath.Join(h.dir, "passwd")
+}
+
+func (h *fakeSSHHost) authorizedKeys() string {
+	content, _ := os.ReadFile(filepath.Join(h.home, ".ssh", "authorized_keys"))
+	return string(content)
+}
+
+func (h *fakeSSHHost) authorized(key ssh.PublicKey) bool {
+	rest := []byte(h.authorizedKeys())
+	for len(rest) > 0 {
+		authorized, _, _, next, err := ssh.ParseAuthorizedKey(rest)
+		if err != nil {
+			return false
+		}
+		if bytes.Equal(authorized.Marshal(), key.Marshal()) {
+			return true
+		}
+		rest = next
+	}
+	return false
+}
+
+func (h *fakeSSHHost) serve(conn net.Conn, config *ssh.ServerConfig, bin string) {
+	_, channels, requests, err := ssh.NewServerConn(conn, config)
+	if err != nil {
+		return
+	}
+	go ssh.DiscardRequests(requests)
+	for newChannel := range channels {
+		if newChannel.ChannelType() == "direct-tcpip" {
+			go h.forward(newChannel)
+			continue
+		}
+		if newChannel.ChannelType() != "session" {
+			_ = newChannel.Reject(ssh.UnknownChannelType, "unsupported")
+			continue
+		}
+		channel, channelRequests, err := newChannel.Accept()
+		if err != nil {
+			continue
+		}
+		go func() {
+			defer channel.Close()
+			for req := range channelRequests {
+				if req.Type != "exec" || len(req.Payload) < 4 {
+					_ = req.Reply(false, nil)
+					continue
+				}
+				_ = req.Reply(true, nil)
+				cmd := exec.Command("sh", "-c", string(req.Payload[4:]))
+				cmd.Env = []string{"HOME=" + h.home, "PATH=" + bin + ":" + os.Getenv("PATH"), "PASSWD_FILE=" + h.passwdFile()}
+				cmd.Stdout, cmd.Stderr = channel, channel.Stderr()
+				status := uint32(0)
+				if err := cmd.Run(); err != nil {
+					status = 1
+					if exitErr, ok := err.(*exec.ExitError); ok && exitErr.ExitCode() > 0 {
+						status = uint32(exitErr.ExitCode())
+					}
+				}
+				exitStatus := make([]byte, 4)
+				binary.BigEndian.PutUint32(exitStatus, status)
+				_, _ = channel.SendRequest("exit-status", false, exitStatus)
+				return
+			}
+		}()
+	}
+}
+
+// 转发 direct-tcpip 通道到目标地址
+func (h *fakeSSHHost) forward(newChannel ssh.NewChannel) {
+	var target struct {
+		Host       string
+		Port       uint32
+		OriginHost string
+		OriginPort uint32
+	}
+	if err := ssh.Unmarshal(newChannel.ExtraData(), &target); err != nil {
+		_ = newChannel.Reject(ssh.ConnectionFailed, err.Error())
+		return
+	}
+	conn, err := net.Dial("tcp", net.JoinHostPort(target.Host, strconv.Itoa(int(target.Port))))
+	if err != nil {
+		_ = newChannel.Reject(ssh.ConnectionFailed, err.Error())
+		return
+	}
+	channel, requests, err := newChannel.Accept()
+	if err != nil {
+		conn.Close()
+		return
+	}
+	atomic.AddInt32(&h.forwarded, 1)
+	go ssh.DiscardRequests(requests)
+	go func() {
+		_, _ = io.Copy(channel, conn)
+		channel.Close()
+	}()
+	_, _ = io.Copy(conn, channel)
+	conn.Close()
+}
+
+func setupCredentialRotation(t *testing.T) (*gorm.DB, *gin.Engine) {
+	database := setupSystemDB(t)
+	if err := database.AutoMigrate(&cmdbmodel.CmdbGroup{}, &cmdbmodel.CmdbHost{}, &configmodel.EcsAuth{},
+		&cmdbmodel.CmdbCredentialRotation{}, &cmdbmodel.CmdbCredentialRotationRun{}, &cmdbmodel.CmdbCredentialRotationHost{}); err != nil {
+		t.Fatalf("Failed to auto migrate: %v", err)
+	}
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/api/v1/config/ecsauthgenerate", configcontroller.NewEcsAuthController().GenerateEcsKeyAuth)
+	router.GET("/api/v1/config/ecsauthpubkey", configcontroller.NewEcsAuthController().GetEcsAuthPublicKey)
+	router.POST("/api/v1/cmdb/credential/rotation/add", cmdbcontroller.CreateCmdbCredentialRotation)
+	router.POST("/api/v1/cmdb/credential/rotation/run", cmdbcontroller.RunCmdbCredentialRotation)
+	router.POST("/api/v1/cmdb/credential/distribute", cmdbcontroller.DistributeCmdbCredential)
+	router.GET("/api/v1/cmdb/credential/rotation/runinfo", cmdbcontroller.GetCmdbCredentialRotationRunInfo)
+	return database, router
+}
+
+func addRotationHost(t *testing.T, database *gorm.DB, name string, groupId uint, h *fakeSSHHost, auth configmodel.EcsAuth) cmdbmodel.CmdbHost {
+	ecsDao := configdao.NewEcsAuthDao()
+	auth.CreateTime = util.HTime{Time: time.Now()}
+	if err := ecsDao.CreateEcsAuth(&auth); err != nil {
+		t.Fatal(err)
+	}
+	host := cmdbmodel.CmdbHost{HostName: name, GroupID: groupId, SSHIP: "127.0.0.1", SSHPort: h.port, SSHName: "root",
+		SSHKeyID: auth.ID, CreateTime: util.HTime{Time: time.Now()}}
+	database.Create(&host)
+	return host
+}
+
+// 触发执行并等待执行结束
+func waitRotationRun(t *testing.T, router *gin.Engine, target string, body interface{}) cmdbmodel.CmdbCredentialRotationRunVo {
+	code, data := callApi(router, http.MethodPost, target, "", body)
+	var run cmdbmodel.CmdbCredentialRotationRun
+	if code != 200 || json.Unmarshal(data, &run) != nil || run.ID == 0 {
+		t.Fatalf("Start rotation failed: %d %s", code, data)
+	}
+	var info cmdbmodel.CmdbCredentialRotationRunVo
+	for i := 0; i < 200; i++ {
+		_, data = callApi(router, http.MethodGet, fmt.Sprintf("/api/v1/cmdb/credential/rotation/runinfo?id=%d", run.ID), "", nil)
+		_ = json.Unmarshal(data, &info)
+		if info.Status != cmdbmodel.CredentialRotationRunRunning {
+			return info
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	t.Fatalf("Rotation run %d did not finish", run.ID)
+	return info
+}
+
+func hostAuth(t *testing.T, database *gorm.DB, hostId uint) configmodel.EcsAuth {
+	var host cmdbmodel.CmdbHost
+	database.First(&host, hostId)
+	ecsDao := configdao.NewEcsAuthDao()
+	auth, err := ecsDao.GetById(host.SSHKeyID)
+	if err != nil {
+		t.Fatalf("Host auth %d not found: %v", host.SSHKeyID, err)
+	}
+	return auth
+}
+
+func TestCredentialKeyRotation(t *testing.T) {
+	database, router := setupCredentialRotation(t)
+	group := cmdbmodel.CmdbGroup{Name: "web", CreateTime: util.HTime{Time: time.Now()}}
+	database.Create(&group)
+
+	// web-1 支持密钥登录，web-2 只允许密码登录，新密钥验证失败后回滚
+	web1 := newFakeSSHHost(t, "old-pass", true)
+	web2 := newFakeSSHHost(t, "old-pass", false)
+	host1 := addRotationHost(t, database, "web-1", group.ID, web1, configmodel.EcsAuth{Name: "web-root", Type: 1, Username: "root", Password: "old-pass", Port: 22})
+	host2 := addRotationHost(t, database, "web-2", group.ID, web2, configmodel.EcsAuth{Name: "web-root-2", Type: 1, Username: "root", Password: "old-pass", Port: 22})
+
+	code, data := callApi(router, http.MethodPost, "/api/v1/cmdb/credential/rotation/add", "",
+		cmdbmodel.CmdbCredentialRotation{Name: "web-key", GroupID: group.ID, Mode: cmdbmodel.CredentialRotationModeKey})
+	var rotation cmdbmodel.CmdbCredentialRotation
+	if code != 200 || json.Unmarshal(data, &rotation) != nil || rotation.ID == 0 {
+		t.Fatalf("Create rotation failed: %d %s", code, data)
+	}
+
+	info := waitRotationRun(t, router, "/api/v1/cmdb/credential/rotation/run", cmdbmodel.CmdbCredentialRotationIdDto{Id: rotation.ID})
+	if info.Status != cmdbmodel.CredentialRotationRunPartial || info.SuccessCount != 1 || info.FailedCount != 1 || len(info.Hosts) != 2 {
+		t.Fatalf("Expected partial run with per host report, got %+v", info)
+	}
+	for _, report := range info.Hosts {
+		switch report.HostID {
+		case host1.ID:
+			if report.Status != cmdbmodel.CredentialRotationHostSuccess {
+				t.Errorf("Expected web-1 to succeed, got %+v", report)
+			}
+		case host2.ID:
+			if report.Status != cmdbmodel.CredentialRotationHostRolledBack || !strings.Contains(report.Message, "验证失败") {
+				t.Errorf("Expected web-2 to be rolled back, got %+v", report)
+			}
+		}
+	}
+	first := hostAuth(t, database, host1.ID)
+	if first.Type != 2 || !strings.Contains(web1.authorizedKeys(), "autoops-"+first.Name) {
+		t.Fatalf("Expected web-1 to switch to the new key, got %+v", first)
+	}
+	if auth := hostAuth(t, database, host2.ID); auth.Name != "web-root-2" || strings.TrimSpace(web2.authorizedKeys()) != "" {
+		t.Errorf("Expected web-2 to keep its password and no new keys, got %s %q", auth.Name, web2.authorizedKeys())
+	}
+	// 没有主机使用的新凭证被删除
+	var count int64
+	database.Model(&configmodel.EcsAuth{}).Where("name LIKE ?", "web-root-2-rotated-%").Count(&count)
+	if count != 0 {
+		t.Errorf("Expected unused rotated credential to be deleted, got %d", count)
+	}
+
+	// 再次轮换时移除原密钥
+	database.Delete(&cmdbmodel.CmdbHost{}, host2.ID)
+	info = waitRotationRun(t, router, "/api/v1/cmdb/credential/rotation/run", cmdbmodel.CmdbCredentialRotationIdDto{Id: rotation.ID})
+	if info.Status != cmdbmodel.CredentialRotationRunSuccess {
+		t.Fatalf("Expected second rotation to succeed, got %+v", info)
+	}
+	second := hostAuth(t, database, host1.ID)
+	oldPublicKey, _ := util.SSHPublicKeyFromPrivate(first.PublicKey)
+	newPublicKey, _ := util.SSHPublicKeyFromPrivate(second.PublicKey)
+	keys := web1.authorizedKeys()
+	if second.ID == first.ID || strings.Contains(keys, strings.Fields(oldPublicKey)[1]) || !strings.Contains(keys, strings.Fields(newPublicKey)[1]) {
+		t.Errorf("Expected old key to be replaced by new key, got %q", keys)
+	}
+	if second.Name != "web-root-rotated-"+fmt.Sprint(info.ID) {
+		t.Errorf("Unexpected rotated credential name %s", second.Name)
+	}
+}
+
+func TestCredentialPasswordRotationAndDistribute(t *testing.T) {
+	database, router := setupCredentialRotation(t)
+	group := cmdbmodel.CmdbGroup{Name: "db", CreateTime: util.HTime{Time: time.Now()}}
+	database.Create(&group)
+	h := newFakeSSHHost(t, "old-pass", true)
+	host := addRotationHost(t, database, "db-1", group.ID, h, configmodel.EcsAuth{Name: "db-root", Type: 1, Username: "root", Password: "old-pass", Port: 22})
+
+	rotation := cmdbmodel.CmdbCredentialRotation{Name: "db-pass", GroupID: group.ID, Mode: cmdbmodel.CredentialRotationModePassword, PasswordLength: 16}
+	if code, data := callApi(router, http.MethodPost, "/api/v1/cmdb/credential/rotation/add", "", rotation); code != 200 {
+		t.Fatalf("Create rotation failed: %s", data)
+	}
+	database.Where("name = ?", "db-pass").First(&rotation)
+	info := waitRotationRun(t, router, "/api/v1/cmdb/credential/rotation/run", cmdbmodel.CmdbCredentialRotationIdDto{Id: rotation.ID})
+	if info.Status != cmdbmodel.CredentialRotationRunSuccess {
+		t.Fatalf("Expected password rotation to succeed, got %+v", info)
+	}
+	auth := hostAuth(t, database, host.ID)
+	content, _ := os.ReadFile(h.passwdFile())
+	if auth.Type != 1 || len(auth.Password) != 16 || strings.TrimSpace(string(content)) != "root:"+auth.Password {
+		t.Errorf("Expected host password to be rotated, got %+v %q", auth, content)
+	}
+
+	// 生成密钥凭证并分发公钥
+	code, data := callApi(router, http.MethodPost, "/api/v1/config/ecsauthgenerate", "",
+		configmodel.GenerateEcsKeyAuthDto{Name: "deploy", KeyType: util.SSHKeyTypeRSA, Bits: 2048, Username: "root"})
+	var generated configmodel.EcsAuthPublicKeyVo
+	if code != 200 || json.Unmarshal(data, &generated) != nil || !strings.HasPrefix(generated.PublicKey, "ssh-rsa ") {
+		t.Fatalf("Generate key failed: %d %s", code, data)
+	}
+	_, data = callApi(router, http.MethodGet, fmt.Sprintf("/api/v1/config/ecsauthpubkey?id=%d", generated.ID), "", nil)
+	var publicKey configmodel.EcsAuthPublicKeyVo
+	if json.Unmarshal(data, &publicKey) != nil || !strings.HasPrefix(generated.PublicKey, publicKey.PublicKey) {
+		t.Errorf("Expected public key of generated credential, got %s", data)
+	}
+	info = waitRotationRun(t, router, "/api/v1/cmdb/credential/distribute", cmdbmodel.CmdbCredentialDistributeDto{GroupID: group.ID, EcsAuthID: generated.ID})
+	if info.Status != cmdbmodel.CredentialRotationRunSuccess || !strings.Contains(h.authorizedKeys(), strings.Fields(generated.PublicKey)[1]) {
+		t.Fatalf("Expected public key to be distributed, got %+v %q", info, h.authorizedKeys())
+	}
+	if hostAuth(t, database, host.ID).ID != auth.ID {
+		t.Errorf("Expected host credential to be kept without switchAuth")
+	}
+	info = waitRotationRun(t, router, "/api/v1/cmdb/credential/distribute", cmdbmodel.CmdbCredentialDistributeDto{GroupID: group.ID, EcsAuthID: generated.ID, SwitchAuth: true})
+	if info.Status != cmdbmodel.CredentialRotationRunSuccess || hostAuth(t, database, host.ID).ID != generated.ID {
+		t.Errorf("Expected host to switch to distributed key, got %+v", info)
+	}
+	if strings.Count(h.authorizedKeys(), strings.Fields(generated.PublicKey)[1]) != 1 {
+		t.Errorf("Expected public key to be written once, got %q", h.authorizedKeys())
+	}
+}
+
+// 切换主机凭证失败时回滚，主机保留原密钥
+func TestCredentialKeyRotationSwitchFailure(t *testing.T) {
+	database, router := setupCredentialRotation(t)
+	group := cmdbmodel.CmdbGroup{Name: "app", CreateTime: util.HTime{Time: time.Now()}}
+	database.Create(&group)
+	h := newFakeSSHHost(t, "old-pass", true)
+	host := addRotationHost(t, database, "app-1", group.ID, h, configmodel.EcsAuth{Name: "app-root", Type: 1, Username: "root", Password: "old-pass", Port: 22})
+	rotation := cmdbmodel.CmdbCredentialRotation{Name: "app-key", GroupID: group.ID, Mode: cmdbmodel.CredentialRotationModeKey}
+	if code, data := callApi(router, http.MethodPost, "/api/v1/cmdb/credential/rotation/add", "", rotation); code != 200 || json.Unmarshal(data, &rotation) != nil {
+		t.Fatalf("Create rotation failed: %d %s", code, data)
+	}
+	if info := waitRotationRun(t, router, "/api/v1/cmdb/credential/rotation/run", cmdbmodel.CmdbCredentialRotationIdDto{Id: rotation.ID}); info.Status != cmdbmodel.CredentialRotationRunSuccess {
+		t.Fatalf("Expected first rotation to succeed, got %+v", info)
+	}
+	first := hostAuth(t, database, host.ID)
+	oldPublicKey, _ := util.SSHPublicKeyFromPrivate(first.PublicKey)
+
+	// 模拟更新主机凭证时数据库出错
+	if err := database.Callback().Update().Before("gorm:update").Register("test:fail_host_update", func(db *gorm.DB) {
+		if db.Statement.Table == "cmdb_host" {
+			_ = db.AddError(fmt.Errorf("database is down"))
+		}
+	}); err != nil {
+		t.Fatal(err)
+	}
+	info := waitRotationRun(t, router, "/api/v1/cmdb/credential/rotation/run", cmdbmodel.CmdbCredentialRotationIdDto{Id: rotation.ID})
+	if info.Status != cmdbmodel.CredentialRotationRunFailed || len(info.Hosts) != 1 || info.Hosts[0].Status != cmdbmodel.CredentialRotationHostRolledBack ||
+		!strings.Contains(info.Hosts[0].Message, "更新主机凭证失败") {
+		t.Fatalf("Expected rotation to be rolled back, got %+v", info)
+	}
+	keys := h.authorizedKeys()
+	if hostAuth(t, database, host.ID).ID != first.ID || strings.Count(keys, "ssh-") != 1 || !strings.Contains(keys, strings.Fields(oldPublicKey)[1]) {
+		t.Errorf("Expected host to keep its original key, got %q", keys)
+	}
+}
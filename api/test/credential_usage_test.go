@@ -0,0 +1,139 @@
+package test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	appmodel "dodevops-api/api/app/model"
+	cmdbdao "dodevops-api/api/cmdb/dao"
+	cmdbmodel "dodevops-api/api/cmdb/model"
+	configcontroller "dodevops-api/api/configcenter/controller"
+	configdao "dodevops-api/api/configcenter/dao"
+	configmodel "dodevops-api/api/configcenter/model"
+	monitormodel "dodevops-api/api/monitor/model"
+	taskmodel "dodevops-api/api/task/model"
+	"dodevops-api/common/constant"
+	"dodevops-api/common/util"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+func setupCredentialUsage(t *testing.T) (*gorm.DB, *gin.Engine) {
+	database := setupSystemDB(t)
+	if err := database.AutoMigrate(&configmodel.EcsAuth{}, &configmodel.AccountAuth{}, &cmdbmodel.CmdbHost{}, &cmdbmodel.CmdbSQL{},
+		&monitormodel.Agent{}, &taskmodel.Task{}, &taskmodel.TaskAnsible{}, &appmodel.Application{}, &appmodel.JenkinsEnv{}); err != nil {
+		t.Fatalf("Failed to auto migrate: %v", err)
+	}
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	ecsAuthCtrl := configcontroller.NewEcsAuthController()
+	accountAuthCtrl := configcontroller.NewAccountAuthController()
+	router.GET("/api/v1/config/ecsauthlist", ecsAuthCtrl.GetEcsAuthList)
+	router.GET("/api/v1/config/ecsauthusage", ecsAuthCtrl.GetEcsAuthUsage)
+	router.DELETE("/api/v1/config/ecsauthdelete", ecsAuthCtrl.DeleteEcsAuth)
+	router.GET("/api/v1/config/accountauth/usage", accountAuthCtrl.GetUsage)
+	router.DELETE("/api/v1/config/accountauth", accountAuthCtrl.Delete)
+	return database, router
+}
+
+func TestEcsAuthUsageAndDeleteGuard(t *testing.T) {
+	database, router := setupCredentialUsage(t)
+	now := util.HTime{Time: time.Now()}
+	ecsDao := configdao.NewEcsAuthDao()
+	auth := configmodel.EcsAuth{Name: "ops", Type: 1, Username: "root", Password: "pass", Port: 22, CreateTime: now}
+	spare := configmodel.EcsAuth{Name: "spare", Type: 1, Username: "root", Password: "pass", Port: 22, CreateTime: now}
+	_ = ecsDao.CreateEcsAuth(&auth)
+	_ = ecsDao.CreateEcsAuth(&spare)
+	host := cmdbmodel.CmdbHost{HostName: "web-1", SSHIP: "10.0.0.1", SSHKeyID: auth.ID, CreateTime: now}
+	database.Create(&host)
+	database.Create(&monitormodel.Agent{HostID: host.ID, HostName: "web-1", CreateTime: now})
+	database.Create(&taskmodel.Task{Name: "backup", HostIDs: fmt.Sprintf("99,%d", host.ID)})
+	database.Create(&taskmodel.Task{Name: "other", HostIDs: fmt.Sprintf("%d", host.ID+10)})
+	database.Create(&taskmodel.TaskAnsible{Name: "deploy", HostGroups: "{}", AllHostIDs: fmt.Sprintf("[%d]", host.ID)})
+
+	// SSH 连接路径记录使用时间和次数
+	sshDao := cmdbdao.NewCmdbHostSSHDao()
+	for i := 0; i < 2; i++ {
+		if _, err := sshDao.GetSSHCredentials(auth.ID); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	_, data := callApi(router, http.MethodGet, fmt.Sprintf("/api/v1/config/ecsauthusage?id=%d", auth.ID), "", nil)
+	var usage configmodel.CredentialUsageVo
+	if err := json.Unmarshal(data, &usage); err != nil {
+		t.Fatalf("Unexpected usage response %s", data)
+	}
+	if usage.UseCount != 2 || usage.LastUsedTime == nil || usage.RefCount != 1 {
+		t.Errorf("Unexpected usage summary %+v", usage)
+	}
+	for refType, want := range map[string]int{configmodel.CredentialRefHost: 1, configmodel.CredentialRefAgent: 1,
+		configmodel.CredentialRefTask: 1, configmodel.CredentialRefAnsible: 1} {
+		if usage.Counts[refType] != want {
+			t.Errorf("Expected %d %s references, got %v", want, refType, usage.Counts)
+		}
+	}
+	_, data = callApi(router, http.MethodGet, "/api/v1/config/ecsauthlist?page=1&pageSize=10", "", nil)
+	var page struct {
+		List []configmodel.EcsAuthVo `json:"list"`
+	}
+	_ = json.Unmarshal(data, &page)
+	if list := page.List; len(list) != 2 || list[0].RefCount != 1 || list[0].UseCount != 2 || list[1].RefCount != 0 {
+		t.Errorf("Expected reference and usage counts in list, got %s", data)
+	}
+
+	// 被引用时禁止删除，指定迁移凭据后删除
+	if code, _ := callApi(router, http.MethodDelete, "/api/v1/config/ecsauthdelete", "", configmodel.DeleteEcsAuthDto{Id: auth.ID}); code != constant.CREDENTIAL_IN_USE {
+		t.Fatalf("Expected in-use credential to be protected, got %d", code)
+	}
+	if code, _ := callApi(router, http.MethodDelete, "/api/v1/config/ecsauthdelete", "", configmodel.DeleteEcsAuthDto{Id: auth.ID, ReassignTo: auth.ID + 100}); code == 200 {
+		t.Fatalf("Expected unknown reassign target to be rejected")
+	}
+	if code, data := callApi(router, http.MethodDelete, "/api/v1/config/ecsauthdelete", "", configmodel.DeleteEcsAuthDto{Id: auth.ID, ReassignTo: spare.ID}); code != 200 {
+		t.Fatalf("Delete with reassign failed: %d %s", code, data)
+	}
+	database.First(&host, host.ID)
+	if _, err := ecsDao.GetById(auth.ID); err == nil || host.SSHKeyID != spare.ID {
+		t.Errorf("Expected host to be reassigned and credential deleted, got ssh_key_id %d", host.SSHKeyID)
+	}
+}
+
+func TestAccountAuthUsageAndDeleteGuard(t *testing.T) {
+	database, router := setupCredentialUsage(t)
+	accountDao := configdao.NewAccountAuthDao()
+	account := configmodel.AccountAuth{Alias: "mysql-root", Host: "127.0.0.1", Port: 3306, Name: "root", Password: "pass", Type: 1}
+	spare := configmodel.AccountAuth{Alias: "jenkins", Host: "127.0.0.1", Port: 8080, Name: "admin", Password: "pass", Type: 4}
+	_ = accountDao.Create(&account)
+	_ = accountDao.Create(&spare)
+	database.Create(&cmdbmodel.CmdbSQL{Name: "orders", Type: 1, AccountID: account.ID, GroupID: 1})
+	app := appmodel.Application{Name: "shop", Code: "shop"}
+	database.Create(&app)
+	database.Create(&appmodel.JenkinsEnv{AppID: app.ID, EnvName: "prod", JenkinsServerID: &account.ID})
+	configdao.MarkAccountAuthUsed(account.ID)
+
+	_, data := callApi(router, http.MethodGet, fmt.Sprintf("/api/v1/config/accountauth/usage?id=%d", account.ID), "", nil)
+	var usage configmodel.CredentialUsageVo
+	_ = json.Unmarshal(data, &usage)
+	if usage.RefCount != 2 || usage.UseCount != 1 || usage.Counts[configmodel.CredentialRefDatabase] != 1 || len(usage.References) != 2 ||
+		usage.References[1].Name != "shop/prod" {
+		t.Fatalf("Unexpected account usage %s", data)
+	}
+
+	if code, _ := callApi(router, http.MethodDelete, fmt.Sprintf("/api/v1/config/accountauth?id=%d", account.ID), "", nil); code == 200 {
+		t.Fatalf("Expected in-use account to be protected")
+	}
+	if code, data := callApi(router, http.MethodDelete, fmt.Sprintf("/api/v1/config/accountauth?id=%d&reassignTo=%d", account.ID, spare.ID), "", nil); code != 200 {
+		t.Fatalf("Delete with reassign failed: %d %s", code, data)
+	}
+	var env appmodel.JenkinsEnv
+	var sql cmdbmodel.CmdbSQL
+	database.First(&env)
+	database.First(&sql)
+	if *env.JenkinsServerID != spare.ID || sql.AccountID != spare.ID {
+		t.Errorf("Expected references to be reassigned, got %d %d", *env.JenkinsServerID, sql.AccountID)
+	}
+}
@@ -0,0 +1,187 @@
+package test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	cmdbdao "dodevops-api/api/cmdb/dao"
+	cmdbmodel "dodevops-api/api/cmdb/model"
+	k8sdao "dodevops-api/api/k8s/dao"
+	k8smodel "dodevops-api/api/k8s/model"
+	"dodevops-api/api/system/controller"
+	"dodevops-api/api/system/model"
+	"dodevops-api/common/constant"
+	"dodevops-api/common/util"
+	"dodevops-api/pkg/datascope"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// 初始化部门、资产分组、主机和集群：
+// 运维部(1) -> 运维一组(2)，研发部(3)；分组 ops(部门1) -> ops-web(子分组)，dev(部门3)
+func setupDataScope(t *testing.T) (*gorm.DB, *gin.Engine, model.SysAdmin, model.SysRole) {
+	database := setupSystemDB(t)
+	if err := datascope.Register(database); err != nil {
+		t.Fatalf("Failed to register data scope: %v", err)
+	}
+	if err := database.AutoMigrate(&model.SysRoleGroup{}, &cmdbmodel.CmdbGroup{}, &cmdbmodel.CmdbHost{}, &k8smodel.KubeCluster{}); err != nil {
+		t.Fatalf("Failed to auto migrate: %v", err)
+	}
+	now := util.HTime{Time: time.Now()}
+	database.Create(&model.SysDept{ParentId: 1, DeptName: "运维一组", DeptType: 3, DeptStatus: 1, CreateTime: now})
+	database.Create(&model.SysDept{DeptName: "研发部", DeptType: 3, DeptStatus: 1, CreateTime: now})
+
+	ops := cmdbmodel.CmdbGroup{Name: "ops", DeptId: 1, CreateTime: now}
+	database.Create(&ops)
+	web := cmdbmodel.CmdbGroup{Name: "ops-web", ParentID: ops.ID, CreateTime: now}
+	database.Create(&web)
+	dev := cmdbmodel.CmdbGroup{Name: "dev", DeptId: 3, CreateTime: now}
+	database.Create(&dev)
+	for name, groupId := range map[string]uint{"ops-1": ops.ID, "web-1": web.ID, "dev-1": dev.ID} {
+		database.Create(&cmdbmodel.CmdbHost{HostName: name, GroupID: groupId, SSHIP: "10.0.0.1", CreateTime: now})
+	}
+	database.Create(&k8smodel.KubeCluster{Name: "ops-k8s", DeptId: 2})
+	database.Create(&k8smodel.KubeCluster{Name: "dev-k8s", DeptId: 3})
+
+	admin := model.SysAdmin{Username: "ivy", Password: util.EncryptionMd5("pass"), DeptId: 1, Status: 1, CreateTime: now}
+	database.Create(&admin)
+	var role model.SysRole
+	database.Where("role_key = ?", "ops").First(&role)
+	database.Create(&model.SysAdminRole{AdminId: admin.ID, RoleId: role.ID})
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.PUT("/api/v1/role/dataScope", controller.UpdateSysRoleDataScope)
+	return database, router, admin, role
+}
+
+// 模拟登录用户的请求上下文
+func adminContext(admin model.SysAdmin) *gin.Context {
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Set(constant.ContextKeyUserObj, &model.JwtAdmin{ID: admin.ID, Username: admin.Username})
+	return c
+}
+
+func hostNames(c *gin.Context) map[string]bool {
+	dao := cmdbdao.NewCmdbHostDao()
+	names := map[string]bool{}
+	for _, host := range dao.WithContext(c).GetCmdbHostList() {
+		names[host.HostName] = true
+	}
+	return names
+}
+
+func TestDataScopeDeptTree(t *testing.T) {
+	database, router, admin, role := setupDataScope(t)
+
+	// 默认全部数据
+	if names := hostNames(adminContext(admin)); len(names) != 3 {
+		t.Fatalf("Expected all hosts by default, got %v", names)
+	}
+
+	if code, _ := callApi(router, http.MethodPut, "/api/v1/role/dataScope", "",
+		model.SysRoleDataScopeDto{Id: role.ID, DataScope: datascope.ScopeDeptTree}); code != 200 {
+		t.Fatalf("Update data scope failed: %d", code)
+	}
+	c := adminContext(admin)
+	names := hostNames(c)
+	if len(names) != 2 || !names["ops-1"] || !names["web-1"] {
+		t.Errorf("Expected hosts of own department groups and sub groups, got %v", names)
+	}
+	var devHost cmdbmodel.CmdbHost
+	database.Where("host_name = ?", "dev-1").First(&devHost)
+	hostDao := cmdbdao.NewCmdbHostDao()
+	if _, err := hostDao.WithContext(c).GetCmdbHostById(devHost.ID); err == nil {
+		t.Errorf("Expected host of other department to be hidden")
+	}
+
+	clusters, total, _ := k8sdao.NewKubeClusterDao(database).WithContext(c).List(1, 10)
+	if total != 1 || len(clusters) != 1 || clusters[0].Name != "ops-k8s" {
+		t.Errorf("Expected only cluster of sub department, got %d %v", total, clusters)
+	}
+	if !datascope.AllowDept(c, 2) || datascope.AllowDept(c, 3) || datascope.CurrentDept(c) != 1 {
+		t.Errorf("Unexpected department permission check")
+	}
+
+	// 未绑定请求上下文的后台查询不受限制
+	if list := hostDao.GetCmdbHostList(); len(list) != 3 {
+		t.Errorf("Expected unscoped query to return all hosts, got %d", len(list))
+	}
+
+	// 后台执行保存的任务时按保存任务的用户的数据范围过滤
+	if list := hostDao.WithContext(datascope.WithAdmin(context.Background(), admin.ID)).GetCmdbHostList(); len(list) != 2 {
+		t.Errorf("Expected background query of admin to return 2 hosts, got %d", len(list))
+	}
+}
+
+func TestDataScopeCustomGroup(t *testing.T) {
+	database, router, admin, role := setupDataScope(t)
+	var dev cmdbmodel.CmdbGroup
+	database.Where("name = ?", "dev").First(&dev)
+
+	if code, _ := callApi(router, http.MethodPut, "/api/v1/role/dataScope", "",
+		model.SysRoleDataScopeDto{Id: role.ID, DataScope: datascope.ScopeCustomGroup, GroupIds: []uint{dev.ID}}); code != 200 {
+		t.Fatalf("Update data scope failed: %d", code)
+	}
+	c := adminContext(admin)
+	if names := hostNames(c); len(names) != 1 || !names["dev-1"] {
+		t.Errorf("Expected only hosts of custom group, got %v", names)
+	}
+	if !datascope.AllowGroup(c, dev.ID) || datascope.AllowGroup(c, dev.ID+1) {
+		t.Errorf("Unexpected group permission check")
+	}
+
+	// 超级管理员角色不受数据范围限制
+	superRole := model.SysRole{RoleName: "超级管理员", RoleKey: constant.SUPER_ADMIN_ROLE_KEY, Status: 1,
+		DataScope: datascope.ScopeDept, CreateTime: util.HTime{Time: time.Now()}}
+	database.Create(&superRole)
+	database.Create(&model.SysAdminRole{AdminId: admin.ID, RoleId: superRole.ID})
+	if names := hostNames(adminContext(admin)); len(names) != 3 {
+		t.Errorf("Expected super admin to see all hosts, got %v", names)
+	}
+
+	if code, _ := callApi(router, http.MethodPut, "/api/v1/role/dataScope", "",
+		model.SysRoleDataScopeDto{Id: role.ID, DataScope: 9}); code == 200 {
+		t.Errorf("Expected invalid data scope to be rejected")
+	}
+}
+
+func TestDataScopeBatchCommand(t *testing.T) {
+	database, router, admin, role := setupDataScope(t)
+	if err := database.AutoMigrate(&cmdbmodel.CmdbBatchCommand{}, &cmdbmodel.CmdbBatchCommandHost{}); err != nil {
+		t.Fatalf("Failed to auto migrate: %v", err)
+	}
+	var ops, dev cmdbmodel.CmdbGroup
+	database.Where("name = ?", "ops").First(&ops)
+	database.Where("name = ?", "dev").First(&dev)
+	commandDao := cmdbdao.NewCmdbBatchCommandDao()
+	now := util.HTime{Time: time.Now()}
+	own := cmdbmodel.CmdbBatchCommand{Command: "uptime", DeptId: 1, StartTime: now}
+	if err := commandDao.CreateCommand(&own, []*cmdbmodel.CmdbBatchCommandHost{
+		{HostID: 1, GroupID: ops.ID, HostName: "ops-1"}, {HostID: 3, GroupID: dev.ID, HostName: "dev-1"}}); err != nil {
+		t.Fatal(err)
+	}
+	other := cmdbmodel.CmdbBatchCommand{Command: "uptime", DeptId: 3, StartTime: now}
+	if err := commandDao.CreateCommand(&other, []*cmdbmodel.CmdbBatchCommandHost{{HostID: 3, GroupID: dev.ID, HostName: "dev-1"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	if code, _ := callApi(router, http.MethodPut, "/api/v1/role/dataScope", "",
+		model.SysRoleDataScopeDto{Id: role.ID, DataScope: datascope.ScopeDept}); code != 200 {
+		t.Fatalf("Update data scope failed: %d", code)
+	}
+	scoped := commandDao.WithContext(adminContext(admin))
+	if list, total := scoped.GetCommandListWithPage(1, 10); total != 1 || len(list) != 1 || list[0].ID != own.ID {
+		t.Errorf("Expected only command of own department, got %d %v", total, list)
+	}
+	if _, err := scoped.GetCommandById(other.ID); err == nil {
+		t.Errorf("Expected command of other department to be hidden")
+	}
+	if hosts := scoped.GetCommandHostList(own.ID); len(hosts) != 1 || hosts[0].HostName != "ops-1" {
+		t.Errorf("Expected only host results within data scope, got %v", hosts)
+	}
+}
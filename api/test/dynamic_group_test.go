@@ -0,0 +1,162 @@
+package test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	cmdbcontroller "dodevops-api/api/cmdb/controller"
+	cmdbmodel "dodevops-api/api/cmdb/model"
+	agentModel "dodevops-api/api/monitor/model"
+	monitorService "dodevops-api/api/monitor/service"
+	"dodevops-api/common/util"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+func setupDynamicGroup(t *testing.T) (*gorm.DB, *gin.Engine) {
+	database, router := setupBatchCommand(t)
+	if err := database.AutoMigrate(&cmdbmodel.CmdbDynamicGroup{}); err != nil {
+		t.Fatalf("Failed to auto migrate: %v", err)
+	}
+	router.GET("/api/v1/cmdb/hostlabel/values", cmdbcontroller.GetCmdbHostLabelValues)
+	router.GET("/api/v1/cmdb/hostlabel/select", cmdbcontroller.SelectCmdbHostsByLabels)
+	router.GET("/api/v1/cmdb/dynamicgroup/list", cmdbcontroller.GetCmdbDynamicGroupList)
+	router.POST("/api/v1/cmdb/dynamicgroup/add", cmdbcontroller.CreateCmdbDynamicGroup)
+	router.PUT("/api/v1/cmdb/dynamicgroup/update", cmdbcontroller.UpdateCmdbDynamicGroup)
+	router.DELETE("/api/v1/cmdb/dynamicgroup/delete", cmdbcontroller.DeleteCmdbDynamicGroup)
+	router.GET("/api/v1/cmdb/dynamicgroup/hosts", cmdbcontroller.GetCmdbDynamicGroupHosts)
+	return database, router
+}
+
+func setHostLabels(t *testing.T, router *gin.Engine, hostId uint, labels map[string]string) {
+	if code, data := callApi(router, http.MethodPut, "/api/v1/cmdb/hostlabel/update", "",
+		cmdbmodel.CmdbHostLabelDto{HostID: hostId, Labels: labels}); code != 200 {
+		t.Fatalf("Update labels failed: %s", data)
+	}
+}
+
+func dynamicGroupHostNames(t *testing.T, router *gin.Engine, id uint) []string {
+	code, data := callApi(router, http.MethodGet, fmt.Sprintf("/api/v1/cmdb/dynamicgroup/hosts?id=%d", id), "", nil)
+	var hosts []cmdbmodel.CmdbSelectedHostVo
+	if code != 200 || json.Unmarshal(data, &hosts) != nil {
+		t.Fatalf("Get dynamic group hosts failed: %d %s", code, data)
+	}
+	var names []string
+	for _, host := range hosts {
+		names = append(names, host.HostName)
+	}
+	return names
+}
+
+func TestDynamicGroup(t *testing.T) {
+	database, router := setupDynamicGroup(t)
+	now := util.HTime{Time: time.Now()}
+	var hosts []cmdbmodel.CmdbHost
+	for i, labels := range []map[string]string{
+		{"env": "prod", "role": "web"},
+		{"env": "prod", "role": "api"},
+		{"env": "test", "role": "web"},
+		{"env": "prod", "role": "db"},
+	} {
+		host := cmdbmodel.CmdbHost{HostName: fmt.Sprintf("host-%d", i+1), Name: fmt.Sprintf("node-%d", i+1), CreateTime: now}
+		database.Create(&host)
+		setHostLabels(t, router, host.ID, labels)
+		hosts = append(hosts, host)
+	}
+
+	_, data := callApi(router, http.MethodGet, "/api/v1/cmdb/hostlabel/values", "", nil)
+	var values map[string][]string
+	if json.Unmarshal(data, &values) != nil || strings.Join(values["env"], ",") != "prod,test" || len(values["role"]) != 3 {
+		t.Errorf("Unexpected label values: %s", data)
+	}
+	code, data := callApi(router, http.MethodGet, "/api/v1/cmdb/hostlabel/select?selector="+url.QueryEscape("env=prod,role in (web,api)"), "", nil)
+	var selected []cmdbmodel.CmdbSelectedHostVo
+	if code != 200 || json.Unmarshal(data, &selected) != nil || len(selected) != 2 || selected[0].Labels["role"] != "web" {
+		t.Errorf("Unexpected selected hosts: %d %s", code, data)
+	}
+
+	// 新增动态分组，选择器和名称校验
+	for _, dto := range []cmdbmodel.CmdbDynamicGroupDto{
+		{Name: "", Selector: "env=prod"},
+		{Name: "bad", Selector: ""},
+		{Name: "bad", Selector: "env in ()"},
+	} {
+		if code, _ := callApi(router, http.MethodPost, "/api/v1/cmdb/dynamicgroup/add", "", dto); code == 200 {
+			t.Errorf("Expected dynamic group %+v to be rejected", dto)
+		}
+	}
+	code, data = callApi(router, http.MethodPost, "/api/v1/cmdb/dynamicgroup/add", "",
+		cmdbmodel.CmdbDynamicGroupDto{Name: "prod-app", Selector: "env=prod,role in (web,api)"})
+	var group cmdbmodel.CmdbDynamicGroup
+	if code != 200 || json.Unmarshal(data, &group) != nil || group.ID == 0 {
+		t.Fatalf("Create dynamic group failed: %d %s", code, data)
+	}
+	if code, _ := callApi(router, http.MethodPost, "/api/v1/cmdb/dynamicgroup/add", "",
+		cmdbmodel.CmdbDynamicGroupDto{Name: "prod-app", Selector: "env=prod"}); code == 200 {
+		t.Errorf("Expected duplicated dynamic group name to be rejected")
+	}
+	if names := dynamicGroupHostNames(t, router, group.ID); strings.Join(names, ",") != "host-1,host-2" {
+		t.Errorf("Expected host-1,host-2, got %v", names)
+	}
+
+	// 成员随主机标签变化
+	setHostLabels(t, router, hosts[3].ID, map[string]string{"env": "prod", "role": "api"})
+	setHostLabels(t, router, hosts[0].ID, map[string]string{"env": "test", "role": "web"})
+	if names := dynamicGroupHostNames(t, router, group.ID); strings.Join(names, ",") != "host-2,host-4" {
+		t.Errorf("Expected host-2,host-4 after label change, got %v", names)
+	}
+	_, data = callApi(router, http.MethodGet, "/api/v1/cmdb/dynamicgroup/list", "", nil)
+	var list []cmdbmodel.CmdbDynamicGroup
+	if json.Unmarshal(data, &list) != nil || len(list) != 1 || list[0].HostCount != 2 {
+		t.Errorf("Unexpected dynamic group list: %s", data)
+	}
+
+	// 批量命令按动态分组选择主机，重新执行时重新计算成员
+	info := waitBatchCommand(t, router, "/api/v1/cmdb/batch/execute", cmdbmodel.CmdbBatchCommandDto{
+		Command: "true", HostIds: []uint{hosts[1].ID, hosts[2].ID}, DynamicGroupIds: []uint{group.ID}})
+	if info.Total != 3 {
+		t.Errorf("Expected 3 hosts from host ids and dynamic group, got %d", info.Total)
+	}
+	if code, data := callApi(router, http.MethodPut, "/api/v1/cmdb/dynamicgroup/update", "",
+		cmdbmodel.CmdbDynamicGroupDto{Id: group.ID, Name: "prod-app", Selector: "role"}); code != 200 {
+		t.Fatalf("Update dynamic group failed: %s", data)
+	}
+	info = waitBatchCommand(t, router, "/api/v1/cmdb/batch/rerun", cmdbmodel.CmdbBatchCommandRerunDto{Id: info.ID})
+	if info.Total != 4 {
+		t.Errorf("Expected rerun to select 4 hosts, got %d", info.Total)
+	}
+
+	// Agent部署的目标与主机ID合并去重
+	dto := agentModel.BatchDeployAgentDto{HostIDs: []uint{hosts[1].ID}, DynamicGroupID: group.ID}
+	if err := monitorService.ResolveAgentTargets(context.Background(), &dto); err != nil || len(dto.HostIDs) != 4 {
+		t.Errorf("Expected 4 agent targets, got %v %v", dto.HostIDs, err)
+	}
+
+	// 告警规则的动态分组约束展开为 instance 正则匹配
+	rule := monitorService.ProcessRuleYAML("groups:\n- rules:\n  - expr: up{job=\"node\"} == 0\n", "",
+		fmt.Sprintf(`{"dynamicGroup":"%d"}`, group.ID))
+	if !strings.Contains(rule, `up{instance=~"node-1|node-2|node-3|node-4",job="node"} == 0`) {
+		t.Errorf("Expected instance matcher for dynamic group, got %s", rule)
+	}
+
+	// 删除后引用该分组报错
+	if code, data := callApi(router, http.MethodDelete, "/api/v1/cmdb/dynamicgroup/delete", "",
+		cmdbmodel.CmdbDynamicGroupIdDto{Id: group.ID}); code != 200 {
+		t.Fatalf("Delete dynamic group failed: %s", data)
+	}
+	if code, _ := callApi(router, http.MethodPost, "/api/v1/cmdb/batch/execute", "",
+		cmdbmodel.CmdbBatchCommandDto{Command: "true", DynamicGroupIds: []uint{group.ID}}); code == 200 {
+		t.Errorf("Expected deleted dynamic group to be rejected")
+	}
+	rule = monitorService.ProcessRuleYAML("groups:\n- rules:\n  - expr: up == 0\n", "", fmt.Sprintf(`{"dynamicGroup":"%d"}`, group.ID))
+	if !strings.Contains(rule, `instance=~"__no_host__"`) {
+		t.Errorf("Expected deleted dynamic group to match no instance, got %s", rule)
+	}
+}
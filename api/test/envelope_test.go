@@ -0,0 +1,141 @@
+package test
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	configmodel "dodevops-api/api/configcenter/model"
+	"dodevops-api/api/system/model"
+	"dodevops-api/common/config"
+	"dodevops-api/common/util"
+	"dodevops-api/pkg/envelope"
+
+	"gorm.io/gorm"
+)
+
+// 按旧版本方式使用固定密钥 AES-CBC 加密
+func legacyEncrypt(t *testing.T, plaintext string) string {
+	block, _ := aes.NewCipher([]byte("this-is-32-byte-key-for-aes-256!"))
+	padding := aes.BlockSize - len(plaintext)%aes.BlockSize
+	data := append([]byte(plaintext), bytes.Repeat([]byte{byte(padding)}, padding)...)
+	out := make([]byte, aes.BlockSize+len(data))
+	if _, err := rand.Read(out[:aes.BlockSize]); err != nil {
+		t.Fatal(err)
+	}
+	cipher.NewCBCEncrypter(block, out[:aes.BlockSize]).CryptBlocks(out[aes.BlockSize:], data)
+	return base64.StdEncoding.EncodeToString(out)
+}
+
+// 切换主密钥，测试结束后恢复
+func loadMasterKeys(t *testing.T, keys string) {
+	t.Setenv(envelope.EnvMasterKeys, keys)
+	if err := envelope.Load(config.EncryptionConfig{}); err != nil {
+		t.Fatalf("Failed to load master keys: %v", err)
+	}
+	t.Cleanup(func() {
+		os.Setenv(envelope.EnvMasterKeys, testMasterKeys)
+		_ = envelope.Load(config.EncryptionConfig{})
+	})
+}
+
+func rawColumn(database *gorm.DB, table, column string, id uint) (value string) {
+	database.Table(table).Select(column).Where("id = ?", id).Scan(&value)
+	return value
+}
+
+func TestEnvelopeEncryption(t *testing.T) {
+	loadMasterKeys(t, testMasterKeys)
+	encrypted, err := util.AESEncrypt("secret")
+	if err != nil || envelope.KeyVersion(encrypted) != 1 {
+		t.Fatalf("Unexpected ciphertext %q: %v", encrypted, err)
+	}
+	if again, _ := util.AESEncrypt("secret"); again == encrypted {
+		t.Errorf("Expected random data key per value")
+	}
+	if plaintext, err := util.AESDecrypt(encrypted); err != nil || plaintext != "secret" {
+		t.Errorf("Decrypt returned %q, %v", plaintext, err)
+	}
+	if plaintext, err := util.AESDecrypt(legacyEncrypt(t, "legacy")); err != nil || plaintext != "legacy" {
+		t.Errorf("Legacy decrypt returned %q, %v", plaintext, err)
+	}
+	tampered := encrypted[:len(encrypted)-4] + "AAA="
+	if _, err := util.AESDecrypt(tampered); err == nil {
+		t.Errorf("Expected tampered ciphertext to fail")
+	}
+
+	// 缺少密文使用的主密钥版本时无法解密
+	loadMasterKeys(t, "2:"+mustGenerateKey())
+	if _, err := util.AESDecrypt(encrypted); err == nil {
+		t.Errorf("Expected decrypt with unknown key version to fail")
+	}
+}
+
+func TestEnvelopeKeyRotation(t *testing.T) {
+	database := setupSystemDB(t)
+	if err := database.AutoMigrate(&configmodel.KeyManage{}, &configmodel.AccountAuth{}, &configmodel.EcsAuth{}, &model.SysAdminMfa{}, &model.SysApprovalTicket{}); err != nil {
+		t.Fatalf("Failed to auto migrate: %v", err)
+	}
+	loadMasterKeys(t, testMasterKeys)
+
+	// 历史数据：固定密钥加密的云厂商密钥和明文保存的主机凭证
+	legacyKey := configmodel.KeyManage{KeyType: 1, KeyID: legacyEncrypt(t, "ak"), KeySecret: legacyEncrypt(t, "sk")}
+	database.Create(&legacyKey)
+	database.Exec("INSERT INTO config_ecsauth (id, name, type, username, password, port, create_time) VALUES (1, 'legacy', 1, 'root', 'plain-pass', 22, ?)", time.Now())
+	database.Exec("INSERT INTO sys_approval_ticket (id, policy_id, method, url, body, applicant_id, create_time) VALUES (1, 1, 'POST', '/api/v1/cmdb/sql/execute', '{\"password\":\"plain\"}', 1, ?)", time.Now())
+
+	var legacyAuth configmodel.EcsAuth
+	database.First(&legacyAuth, 1)
+	if legacyAuth.Password != "plain-pass" {
+		t.Fatalf("Expected legacy plaintext password, got %q", legacyAuth.Password)
+	}
+
+	auth := configmodel.EcsAuth{Name: "key", Type: 2, Username: "root", PublicKey: "-----BEGIN KEY-----", Port: 22, CreateTime: util.HTime{Time: time.Now()}}
+	database.Create(&auth)
+	if raw := rawColumn(database, "config_ecsauth", "public_key", auth.ID); envelope.KeyVersion(raw) != 1 {
+		t.Fatalf("Expected private key to be encrypted, got %q", raw)
+	}
+	auth.Password = "new-pass"
+	database.Model(&auth).Updates(auth)
+	if raw := rawColumn(database, "config_ecsauth", "password", auth.ID); !envelope.IsEncrypted(raw) || strings.Contains(raw, "new-pass") {
+		t.Fatalf("Expected updated password to be encrypted, got %q", raw)
+	}
+
+	// 追加版本 2 并切换后重新加密
+	loadMasterKeys(t, testMasterKeys+",2:"+mustGenerateKey())
+	res, err := envelope.Rotate(database)
+	if err != nil || res.ActiveVersion != 2 || res.Rotated != 6 || res.Failed != 0 {
+		t.Fatalf("Unexpected rotate result %+v: %v", res, err)
+	}
+	status, err := envelope.Status(database)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, column := range status {
+		if column.Total != column.Versions[2] {
+			t.Errorf("Expected %s.%s to use key version 2, got %v", column.Table, column.Column, column.Versions)
+		}
+	}
+
+	var key configmodel.KeyManage
+	database.First(&key, legacyKey.ID)
+	if id, secret, err := key.DecryptKeys(); err != nil || id != "ak" || secret != "sk" {
+		t.Errorf("DecryptKeys returned %q %q %v", id, secret, err)
+	}
+	var rotated []configmodel.EcsAuth
+	database.Order("id").Find(&rotated)
+	if len(rotated) != 2 || rotated[0].Password != "plain-pass" || rotated[1].Password != "new-pass" || rotated[1].PublicKey != "-----BEGIN KEY-----" {
+		t.Errorf("Unexpected rotated credentials %+v", rotated)
+	}
+
+	// 再次执行不会重复加密
+	if res, _ := envelope.Rotate(database); res.Rotated != 0 {
+		t.Errorf("Expected nothing to rotate, got %+v", res)
+	}
+}
@@ -0,0 +1,168 @@
+package test
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	cmdbcontroller "dodevops-api/api/cmdb/controller"
+	cmdbmodel "dodevops-api/api/cmdb/model"
+	cmdbservice "dodevops-api/api/cmdb/service"
+	configmodel "dodevops-api/api/configcenter/model"
+	"dodevops-api/common/config"
+	"dodevops-api/common/util"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/crypto/ssh"
+	"gorm.io/gorm"
+)
+
+func setupHostKey(t *testing.T, policy string) (*gorm.DB, *gin.Engine) {
+	database := setupSystemDB(t)
+	if err := database.AutoMigrate(&cmdbmodel.CmdbHost{}, &configmodel.EcsAuth{}, &cmdbmodel.CmdbHostKey{}); err != nil {
+		t.Fatalf("Failed to auto migrate: %v", err)
+	}
+	cmdbservice.InitCmdbHostKeyVerifier(config.HostKeyConfig{Policy: policy})
+	t.Cleanup(func() { cmdbservice.InitCmdbHostKeyVerifier(config.HostKeyConfig{Policy: config.HostKeyPolicyOff}) })
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/api/v1/cmdb/hostkey/list", cmdbcontroller.GetCmdbHostKeyList)
+	router.POST("/api/v1/cmdb/hostkey/accept", cmdbcontroller.AcceptCmdbHostKey)
+	router.POST("/api/v1/cmdb/hostkey/pin", cmdbcontroller.PinCmdbHostKey)
+	router.DELETE("/api/v1/cmdb/hostkey/delete", cmdbcontroller.DeleteCmdbHostKey)
+	return database, router
+}
+
+func sshEcho(h *fakeSSHHost) error {
+	_, err := util.SSHExec("127.0.0.1", h.port, "root", "pass", "echo ok")
+	return err
+}
+
+func hostKeyRecord(t *testing.T, database *gorm.DB, hostId uint) cmdbmodel.CmdbHostKey {
+	var hostKey cmdbmodel.CmdbHostKey
+	if err := database.Where("host_id = ?", hostId).First(&hostKey).Error; err != nil {
+		t.Fatalf("Host key record not found: %v", err)
+	}
+	return hostKey
+}
+
+func TestHostKeyTrustOnFirstUse(t *testing.T) {
+	database, router := setupHostKey(t, config.HostKeyPolicyTofu)
+	h := newFakeSSHHost(t, "pass", false)
+	host := addRotationHost(t, database, "web-1", 1, h, configmodel.EcsAuth{Name: "web", Type: 1, Username: "root", Password: "pass", Port: 22})
+
+	// 首次连接自动信任并记录主机密钥
+	if err := sshEcho(h); err != nil {
+		t.Fatalf("First connection failed: %v", err)
+	}
+	trusted := hostKeyRecord(t, database, host.ID)
+	if trusted.Status != cmdbmodel.HostKeyStatusTrusted || trusted.Source != cmdbmodel.HostKeySourceTofu || trusted.Fingerprint == "" {
+		t.Fatalf("Unexpected host key record %+v", trusted)
+	}
+
+	// 主机密钥变更后所有SSH路径拒绝连接，记录待确认的新密钥
+	newKey := h.rotateHostKey()
+	if err := sshEcho(h); err == nil || !strings.Contains(err.Error(), "不一致") {
+		t.Fatalf("Expected changed host key to be rejected, got %v", err)
+	}
+	ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+	ctx.Request = httptest.NewRequest(http.MethodPost, "/", nil)
+	if _, err := cmdbservice.GetCmdbHostSSHService().ExecuteCommand(ctx, host.ID, "echo ok"); err == nil {
+		t.Fatalf("Expected host SSH command to be rejected")
+	}
+	changed := hostKeyRecord(t, database, host.ID)
+	if changed.Status != cmdbmodel.HostKeyStatusChanged || changed.Fingerprint != trusted.Fingerprint ||
+		changed.PendingFingerprint != ssh.FingerprintSHA256(newKey) || changed.MismatchCount != 2 {
+		t.Fatalf("Unexpected changed host key record %+v", changed)
+	}
+	_, data := callApi(router, http.MethodGet, "/api/v1/cmdb/hostkey/list?status=2", "", nil)
+	if !strings.Contains(string(data), `"hostName":"web-1"`) || !strings.Contains(string(data), `"total":1`) {
+		t.Errorf("Expected changed host key in list, got %s", data)
+	}
+
+	// 管理员确认后恢复连接
+	if code, data := callApi(router, http.MethodPost, "/api/v1/cmdb/hostkey/accept", "", cmdbmodel.CmdbHostKeyIdDto{Id: changed.ID}); code != 200 {
+		t.Fatalf("Accept failed: %d %s", code, data)
+	}
+	if err := sshEcho(h); err != nil {
+		t.Fatalf("Connection after accepting new key failed: %v", err)
+	}
+	if accepted := hostKeyRecord(t, database, host.ID); accepted.Source != cmdbmodel.HostKeySourceAccept || accepted.PendingFingerprint != "" {
+		t.Errorf("Unexpected accepted host key record %+v", accepted)
+	}
+
+	// 固定为其他公钥后拒绝连接，不传公钥时固定主机当前的密钥
+	otherPub, _, _ := ed25519.GenerateKey(rand.Reader)
+	otherKey, _ := ssh.NewPublicKey(otherPub)
+	pin := cmdbmodel.CmdbHostKeyPinDto{HostID: host.ID, PublicKey: string(ssh.MarshalAuthorizedKey(otherKey))}
+	if code, data := callApi(router, http.MethodPost, "/api/v1/cmdb/hostkey/pin", "", pin); code != 200 {
+		t.Fatalf("Pin failed: %d %s", code, data)
+	}
+	if err := sshEcho(h); err == nil {
+		t.Fatalf("Expected connection to be rejected by pinned key")
+	}
+	if code, data := callApi(router, http.MethodPost, "/api/v1/cmdb/hostkey/pin", "", cmdbmodel.CmdbHostKeyPinDto{HostID: host.ID}); code != 200 {
+		t.Fatalf("Pin current key failed: %d %s", code, data)
+	}
+	if pinned := hostKeyRecord(t, database, host.ID); pinned.Source != cmdbmodel.HostKeySourcePin || pinned.Fingerprint != ssh.FingerprintSHA256(newKey) {
+		t.Errorf("Unexpected pinned host key record %+v", pinned)
+	}
+	if err := sshEcho(h); err != nil {
+		t.Fatalf("Connection with pinned key failed: %v", err)
+	}
+}
+
+func TestHostKeyStrictPolicyAndKnownHosts(t *testing.T) {
+	database, router := setupHostKey(t, config.HostKeyPolicyStrict)
+	h := newFakeSSHHost(t, "pass", false)
+	host := addRotationHost(t, database, "db-1", 1, h, configmodel.EcsAuth{Name: "db", Type: 1, Username: "root", Password: "pass", Port: 22})
+
+	// 严格模式下首次连接拒绝，确认后允许连接
+	if err := sshEcho(h); err == nil || !strings.Contains(err.Error(), "未经确认") {
+		t.Fatalf("Expected unknown host key to be rejected, got %v", err)
+	}
+	pending := hostKeyRecord(t, database, host.ID)
+	if pending.Status != cmdbmodel.HostKeyStatusPending || pending.Fingerprint != "" || pending.PendingFingerprint == "" {
+		t.Fatalf("Unexpected pending host key record %+v", pending)
+	}
+	if code, data := callApi(router, http.MethodPost, "/api/v1/cmdb/hostkey/accept", "", cmdbmodel.CmdbHostKeyIdDto{Id: pending.ID}); code != 200 {
+		t.Fatalf("Accept failed: %d %s", code, data)
+	}
+	if err := sshEcho(h); err != nil {
+		t.Fatalf("Connection after accepting key failed: %v", err)
+	}
+
+	// ansible 使用的 known_hosts 只包含校验通过的主机密钥
+	dir := t.TempDir()
+	env, warnings, err := cmdbservice.PrepareKnownHosts([]uint{host.ID}, dir)
+	if err != nil || len(warnings) != 0 || len(env) != 2 || !strings.Contains(env[1], "-F "+filepath.Join(dir, "ssh_config")) {
+		t.Fatalf("Unexpected known_hosts result %v %v %v", env, warnings, err)
+	}
+	if sshConfig, _ := os.ReadFile(filepath.Join(dir, "ssh_config")); !strings.Contains(string(sshConfig), "StrictHostKeyChecking yes") {
+		t.Errorf("Unexpected ssh_config content %q", sshConfig)
+	}
+	content, _ := os.ReadFile(filepath.Join(dir, "known_hosts"))
+	if !strings.HasPrefix(string(content), fmt.Sprintf("[127.0.0.1]:%d ssh-ed25519 ", h.port)) {
+		t.Errorf("Unexpected known_hosts content %q", content)
+	}
+	h.rotateHostKey()
+	_, warnings, _ = cmdbservice.PrepareKnownHosts([]uint{host.ID}, dir)
+	content, _ = os.ReadFile(filepath.Join(dir, "known_hosts"))
+	if len(warnings) != 1 || strings.TrimSpace(string(content)) != "" {
+		t.Errorf("Expected changed host to be excluded from known_hosts, got %v %q", warnings, content)
+	}
+
+	// 删除记录后按策略重新处理
+	if code, _ := callApi(router, http.MethodDelete, "/api/v1/cmdb/hostkey/delete", "", cmdbmodel.CmdbHostKeyIdDto{Id: pending.ID}); code != 200 {
+		t.Fatalf("Delete failed")
+	}
+	if err := sshEcho(h); err == nil {
+		t.Fatalf("Expected host to require confirmation again")
+	}
+}
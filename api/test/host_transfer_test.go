@@ -0,0 +1,286 @@
+package test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	cmdbcontroller "dodevops-api/api/cmdb/controller"
+	cmdbmodel "dodevops-api/api/cmdb/model"
+	configmodel "dodevops-api/api/configcenter/model"
+	"dodevops-api/api/system/model"
+	"dodevops-api/common/constant"
+	"dodevops-api/common/util"
+
+	"github.com/gin-gonic/gin"
+	"github.com/xuri/excelize/v2"
+	"gorm.io/gorm"
+)
+
+func exportHosts(t *testing.T, router *gin.Engine, query string) []byte {
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/cmdb/hostexport?"+query, nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != 200 || !strings.Contains(w.Header().Get("Content-Disposition"), "hosts_") {
+		t.Fatalf("Export hosts failed: %d %s", w.Code, w.Body.String())
+	}
+	return w.Body.Bytes()
+}
+
+func importHosts(router *gin.Engine, filename string, content []byte, dryRun bool) (int, cmdbmodel.CmdbHostImportVo) {
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	part, _ := writer.CreateFormFile("file", filename)
+	_, _ = part.Write(content)
+	_ = writer.WriteField("dryRun", fmt.Sprint(dryRun))
+	_ = writer.Close()
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/cmdb/hosttransfer/import", &body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	var res struct {
+		Code int                        `json:"code"`
+		Data cmdbmodel.CmdbHostImportVo `json:"data"`
+	}
+	_ = json.Unmarshal(w.Body.Bytes(), &res)
+	return res.Code, res.Data
+}
+
+func TestHostTransfer(t *testing.T) {
+	database, router := setupCIType(t)
+	hostController := cmdbcontroller.NewCmdbHostController()
+	router.POST("/api/v1/cmdb/hosttransfer/import", hostController.ImportHosts)
+	ciType := createServerCIType(t, router)
+
+	now := util.HTime{Time: time.Now()}
+	root := cmdbmodel.CmdbGroup{Name: "机房", CreateTime: now}
+	database.Create(&root)
+	child := cmdbmodel.CmdbGroup{Name: "A区", ParentID: root.ID, CreateTime: now}
+	database.Create(&child)
+	auth := configmodel.EcsAuth{Name: "root-key", Type: 1, Username: "root", Password: "pass", Port: 22, CreateTime: now}
+	database.Create(&auth)
+	web := cmdbmodel.CmdbHost{HostName: "web-1", Name: "web-1", GroupID: child.ID, SSHIP: "10.0.0.1", SSHPort: 22, SSHName: "root",
+		SSHKeyID: auth.ID, InstanceID: "i-001", Vendor: 2, Status: 1, CITypeID: ciType.ID, CreateTime: now}
+	database.Create(&web)
+	db := cmdbmodel.CmdbHost{HostName: "db-1", Name: "db-1", GroupID: root.ID, SSHIP: "10.0.0.2", SSHPort: 22, ProxyHostID: web.ID, Vendor: 1, Status: 2, CreateTime: now}
+	database.Create(&db)
+	database.Create(&cmdbmodel.CmdbHostLabel{HostID: web.ID, Key: "env", Value: "prod"})
+	database.Create(&cmdbmodel.CmdbHostAttr{HostID: web.ID, FieldID: ciType.Fields[0].ID, Value: "A01"})
+	database.Create(&cmdbmodel.CmdbHostAttr{HostID: web.ID, FieldID: ciType.Fields[1].ID, Value: "gold"})
+
+	// JSON 导出包含分组路径、凭据名称、标签和自定义属性
+	data := exportHosts(t, router, fmt.Sprintf("format=json&groupId=%d", root.ID))
+	var items []map[string]interface{}
+	if json.Unmarshal(data, &items) != nil || len(items) != 2 {
+		t.Fatalf("Unexpected JSON export: %s", data)
+	}
+	item := items[0]
+	labels, _ := item["labels"].(map[string]interface{})
+	attrs, _ := item["attrs"].(map[string]interface{})
+	if item["groupPath"] != "机房/A区" || item["credential"] != "root-key" || item["ciType"] != "server" || item["sshPort"] != float64(22) ||
+		labels["env"] != "prod" || attrs["rack"] != "A01" || attrs["sla"] != "gold" {
+		t.Errorf("Unexpected exported host: %v", item)
+	}
+	if items[1]["proxyHost"] != "10.0.0.1" {
+		t.Errorf("Expected jump host exported as its SSH address, got %v", items[1])
+	}
+	if err := json.Unmarshal(exportHosts(t, router, "format=json&selector="+url.QueryEscape("env=prod")), &items); err != nil || len(items) != 1 {
+		t.Errorf("Expected selector to export 1 host, got %d", len(items))
+	}
+	if err := json.Unmarshal(exportHosts(t, router, "format=json&keyword=db"), &items); err != nil || len(items) != 1 || items[0]["hostName"] != "db-1" {
+		t.Errorf("Expected keyword to export db-1, got %v", items)
+	}
+	csvData := exportHosts(t, router, "format=csv")
+	if !bytes.HasPrefix(csvData, []byte("\xEF\xBB\xBF")) || !strings.Contains(string(csvData), "SSH凭据") || !strings.Contains(string(csvData), "attr.rack") {
+		t.Errorf("Unexpected CSV export: %s", csvData)
+	}
+	f, err := excelize.OpenReader(bytes.NewReader(exportHosts(t, router, "")))
+	if err != nil {
+		t.Fatalf("Open exported XLSX failed: %v", err)
+	}
+	rows, _ := f.GetRows("Sheet1")
+	if len(rows) != 3 || rows[0][1] != "主机名称" || rows[1][3] != "机房/A区" {
+		t.Errorf("Unexpected XLSX export: %v", rows)
+	}
+
+	// 未修改的导出文件重新导入时没有变化
+	for _, file := range []struct {
+		name string
+		data []byte
+	}{{"hosts.json", exportHosts(t, router, "format=json")}, {"hosts.csv", csvData}} {
+		code, vo := importHosts(router, file.name, file.data, true)
+		if code != 200 || vo.Unchanged != 2 || vo.Failed != 0 {
+			t.Errorf("Expected %s round trip to be unchanged, got %+v", file.name, vo)
+		}
+	}
+
+	// 预览：按实例ID和SSH地址匹配，逐行报告错误，不修改数据
+	content := []byte("实例ID,SSH地址,主机名称,分组,标签,attr.sla,SSH凭据\n" +
+		"i-001,10.0.0.1,web-1,机房/A区,\"role=web,env=prod\",silver,\n" +
+		",10.0.0.2,db-01,机房,,,root-key\n" +
+		",10.0.0.9,new-1,机房/A区,team=ops,,\n" +
+		",10.0.0.8,bad-1,不存在,,,\n" +
+		"i-001,,web-1b,机房,,,\n" +
+		",10.0.0.7,lbl,机房,bad label!,,\n" +
+		",10.0.0.6,,机房,,,\n")
+	code, vo := importHosts(router, "hosts.csv", content, true)
+	if code != 200 || !vo.DryRun || vo.Updated != 2 || vo.Created != 1 || vo.Failed != 4 {
+		t.Fatalf("Unexpected dry run: %d %+v", code, vo)
+	}
+	var changed []string
+	for _, change := range vo.Rows[0].Changes {
+		changed = append(changed, change.Field)
+	}
+	if vo.Rows[0].MatchBy != "instanceId" || strings.Join(changed, ",") != "credential,labels,attr.sla" {
+		t.Errorf("Unexpected changes: %+v", vo.Rows[0])
+	}
+	for i, expected := range []string{"分组 不存在 不存在", "与第2行是同一台主机", "标签", "不能为空"} {
+		if row := vo.Rows[i+3]; row.Action != cmdbmodel.HostImportError || !strings.Contains(row.Error, expected) {
+			t.Errorf("Expected row %d error %q, got %+v", row.Row, expected, row)
+		}
+	}
+	var host cmdbmodel.CmdbHost
+	database.First(&host, db.ID)
+	if host.HostName != "db-1" {
+		t.Errorf("Expected dry run not to change hosts, got %s", host.HostName)
+	}
+
+	// 导入：更新文件中出现的列，其他列保持原值
+	if code, vo = importHosts(router, "hosts.csv", content, false); code != 200 || vo.DryRun || vo.Updated != 2 || vo.Created != 1 {
+		t.Fatalf("Unexpected import: %d %+v", code, vo)
+	}
+	host = cmdbmodel.CmdbHost{}
+	database.First(&host, web.ID)
+	if host.SSHKeyID != 0 || host.InstanceID != "i-001" || host.Vendor != 2 || host.CITypeID != ciType.ID {
+		t.Errorf("Unexpected updated host: %+v", host)
+	}
+	host = cmdbmodel.CmdbHost{}
+	database.First(&host, db.ID)
+	if host.HostName != "db-01" || host.SSHKeyID != auth.ID {
+		t.Errorf("Unexpected updated host: %+v", host)
+	}
+	var created cmdbmodel.CmdbHost
+	if database.Where("ssh_ip = ?", "10.0.0.9").First(&created).Error != nil || created.GroupID != child.ID || created.SSHPort != 22 || created.Name != "new-1" {
+		t.Errorf("Unexpected created host: %+v", created)
+	}
+	data = exportHosts(t, router, "format=json&selector="+url.QueryEscape("role=web"))
+	if json.Unmarshal(data, &items) != nil || len(items) != 1 {
+		t.Fatalf("Unexpected export after import: %s", data)
+	}
+	attrs, _ = items[0]["attrs"].(map[string]interface{})
+	if attrs["sla"] != "silver" || attrs["rack"] != "A01" {
+		t.Errorf("Expected sla updated and rack kept, got %v", attrs)
+	}
+	if code, vo = importHosts(router, "hosts.csv", content, true); code != 200 || vo.Unchanged != 3 || vo.Rows[2].MatchBy != "sshIp" {
+		t.Errorf("Expected imported rows to be unchanged, got %+v", vo)
+	}
+
+	// 跳板机按SSH地址导入，校验跳板机链
+	code, vo = importHosts(router, "hosts.csv", []byte("SSH地址,跳板机\n10.0.0.9,10.0.0.2\n10.0.0.1,10.0.0.1\n10.0.0.2,10.0.0.99\n"), false)
+	if code != 200 || vo.Updated != 1 || vo.Failed != 2 || !strings.Contains(vo.Rows[1].Error, "自身") || !strings.Contains(vo.Rows[2].Error, "不存在") {
+		t.Errorf("Unexpected jump host import: %d %+v", code, vo)
+	}
+	host = cmdbmodel.CmdbHost{}
+	if database.First(&host, created.ID); host.ProxyHostID != db.ID {
+		t.Errorf("Expected jump host to be imported, got %+v", host)
+	}
+
+	// 文件级错误
+	if code, _ := importHosts(router, "hosts.csv", []byte("主机名称,未知列\nx,y\n"), true); code == 200 {
+		t.Errorf("Expected unknown column to be rejected")
+	}
+	if code, _ := importHosts(router, "hosts.txt", content, true); code == 200 {
+		t.Errorf("Expected unsupported format to be rejected")
+	}
+
+	// 保存标签失败时整行回滚，不留下没有标签的主机
+	if err := database.Callback().Create().Before("gorm:create").Register("test:fail_label_create", func(db *gorm.DB) {
+		if db.Statement.Table == "cmdb_host_label" {
+			_ = db.AddError(fmt.Errorf("database is down"))
+		}
+	}); err != nil {
+		t.Fatal(err)
+	}
+	code, vo = importHosts(router, "hosts.csv", []byte("SSH地址,主机名称,分组,标签\n10.0.0.5,new-2,机房,team=ops\n"), false)
+	if code != 200 || vo.Failed != 1 || !strings.Contains(vo.Rows[0].Error, "保存标签失败") {
+		t.Errorf("Expected label failure to be reported, got %d %+v", code, vo)
+	}
+	var count int64
+	if database.Model(&cmdbmodel.CmdbHost{}).Where("ssh_ip = ?", "10.0.0.5").Count(&count); count != 0 {
+		t.Errorf("Expected host creation to be rolled back, got %d hosts", count)
+	}
+}
+
+func TestHostImportRequiresAddPermission(t *testing.T) {
+	database, router := setupCIType(t)
+	now := util.HTime{Time: time.Now()}
+	admin := model.SysAdmin{Username: "judy", Password: util.EncryptionMd5("pass"), Status: 1, CreateTime: now}
+	database.Create(&admin)
+	var role model.SysRole
+	database.Where("role_key = ?", "ops").First(&role)
+	database.Create(&model.SysAdminRole{AdminId: admin.ID, RoleId: role.ID})
+	edit := model.SysMenu{Value: "cmdb:ecs:edit", MenuType: 3, MenuStatus: 2, CreateTime: now}
+	database.Create(&edit)
+	database.Create(&model.SysRoleMenu{RoleId: role.ID, MenuId: edit.ID})
+	group := router.Group("/api/v1/cmdb/hosttransfer", func(c *gin.Context) {
+		c.Set(constant.ContextKeyUserObj, &model.JwtAdmin{ID: admin.ID, Username: admin.Username})
+	})
+	group.POST("/import", cmdbcontroller.NewCmdbHostController().ImportHosts)
+
+	root := cmdbmodel.CmdbGroup{Name: "机房", CreateTime: now}
+	database.Create(&root)
+	database.Create(&cmdbmodel.CmdbHost{HostName: "web-1", Name: "web-1", GroupID: root.ID, SSHIP: "10.0.0.1", SSHPort: 22, Status: 2, CreateTime: now})
+	content := []byte("SSH地址,主机名称,分组\n10.0.0.1,web-01,机房\n10.0.0.2,web-2,机房\n")
+
+	// 只有更新主机的权限时，新增主机的行报错，预览和导入一致
+	for _, dryRun := range []bool{true, false} {
+		code, vo := importHosts(router, "hosts.csv", content, dryRun)
+		if code != 200 || vo.Updated != 1 || vo.Created != 0 || vo.Failed != 1 || !strings.Contains(vo.Rows[1].Error, "新增主机的权限") {
+			t.Errorf("Expected create row to be rejected (dryRun=%v), got %d %+v", dryRun, code, vo)
+		}
+	}
+	var count int64
+	if database.Model(&cmdbmodel.CmdbHost{}).Where("ssh_ip = ?", "10.0.0.2").Count(&count); count != 0 {
+		t.Errorf("Expected host not to be created without add permission")
+	}
+
+	add := model.SysMenu{Value: "cmdb:ecs:add", MenuType: 3, MenuStatus: 2, CreateTime: now}
+	database.Create(&add)
+	database.Create(&model.SysRoleMenu{RoleId: role.ID, MenuId: add.ID})
+	if code, vo := importHosts(router, "hosts.csv", content, false); code != 200 || vo.Unchanged != 1 || vo.Created != 1 {
+		t.Errorf("Expected create row to be imported with add permission, got %d %+v", code, vo)
+	}
+}
+
+func TestHostImportCollectsSystemInfo(t *testing.T) {
+	database, router := setupCIType(t)
+	router.POST("/api/v1/cmdb/hosttransfer/import", cmdbcontroller.NewCmdbHostController().ImportHosts)
+	now := util.HTime{Time: time.Now()}
+	database.Create(&cmdbmodel.CmdbGroup{Name: "机房", CreateTime: now})
+	database.Create(&configmodel.EcsAuth{Name: "app-key", Type: 1, Username: "root", Password: "pass", Port: 22, CreateTime: now})
+	h := newFakeSSHHost(t, "pass", false)
+
+	// 新增的主机与手工新增一样在后台采集系统信息
+	content := fmt.Sprintf("SSH地址,SSH端口,SSH用户,主机名称,分组,SSH凭据\n127.0.0.1,%d,root,app-1,机房,app-key\n", h.port)
+	if code, vo := importHosts(router, "hosts.csv", []byte(content), false); code != 200 || vo.Created != 1 {
+		t.Fatalf("Unexpected import: %d %+v", code, vo)
+	}
+	var host cmdbmodel.CmdbHost
+	for i := 0; i < 100; i++ {
+		database.Where("ssh_ip = ?", "127.0.0.1").First(&host)
+		if host.Status != 2 {
+			break
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	if host.Status != 1 {
+		t.Errorf("Expected system info to be collected after import, got %+v", host)
+	}
+}
@@ -0,0 +1,198 @@
+package test
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"dodevops-api/api/system/model"
+	"dodevops-api/api/system/service"
+	"dodevops-api/common/config"
+	"dodevops-api/common/util"
+	"dodevops-api/pkg/db"
+
+	"github.com/jimlambrt/gldap"
+	"github.com/jimlambrt/gldap/testdirectory"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// 初始化系统管理相关表和测试角色、部门
+func setupSystemDB(t *testing.T) *gorm.DB {
+	database, err := gorm.Open(sqlite.Open(fmt.Sprintf("file:%s?mode=memory&cache=shared", t.Name())), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("Failed to connect database: %v", err)
+	}
+	err = database.AutoMigrate(&model.SysAdmin{}, &model.SysRole{}, &model.SysAdminRole{}, &model.SysDept{},
+		&model.SysMenu{}, &model.SysRoleMenu{}, &model.SysLoginInfo{})
+	if err != nil {
+		t.Fatalf("Failed to auto migrate: %v", err)
+	}
+	db.Db = database
+	now := util.HTime{Time: time.Now()}
+	database.Create(&model.SysRole{RoleName: "运维", RoleKey: "ops", Status: 1, CreateTime: now})
+	database.Create(&model.SysRole{RoleName: "访客", RoleKey: "guest", Status: 1, CreateTime: now})
+	database.Create(&model.SysDept{DeptName: "运维部", DeptType: 3, DeptStatus: 1, CreateTime: now})
+	return database
+}
+
+// 使用临时配置文件加载配置
+func loadTestConfig(t *testing.T, configYaml string) {
+	configPath := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(configPath, []byte(configYaml), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	// LoadConfig 会合并到已有配置上，先清空避免其他用例的配置残留
+	config.Config = nil
+	if err := config.LoadConfig(configPath); err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+}
+
+// 启动内嵌 LDAP 服务并初始化系统表和认证配置
+func setupLdapAuth(t *testing.T, providers string) {
+	database := setupSystemDB(t)
+	database.Create(&model.SysAdmin{Username: "bob", Password: util.EncryptionMd5("local-pass"), Status: 1, Source: model.SysAdminSourceLocal, CreateTime: util.HTime{Time: time.Now()}})
+
+	groupDN := "cn=ops,ou=groups,dc=example,dc=org"
+	users := []*gldap.Entry{
+		gldap.NewEntry("cn=alice,ou=people,dc=example,dc=org", map[string][]string{
+			"name":     {"Alice"},
+			"email":    {"alice@example.com"},
+			"password": {"alice-pass"},
+			"memberOf": {groupDN},
+		}),
+		gldap.NewEntry("cn=carol,ou=people,dc=example,dc=org", map[string][]string{
+			"name":     {"Carol"},
+			"password": {"carol-pass"},
+		}),
+		gldap.NewEntry("cn=bob,ou=people,dc=example,dc=org", map[string][]string{
+			"password": {"bob-ldap-pass"},
+		}),
+	}
+	td := testdirectory.Start(t,
+		testdirectory.WithNoTLS(t),
+		testdirectory.WithDefaults(t, &testdirectory.Defaults{
+			AllowAnonymousBind: true,
+			Users:              users,
+		}),
+	)
+
+	configYaml := fmt.Sprintf(`auth:
+  providers: [%s]
+  ldap:
+    url: ldap://%s:%d
+    baseDN: ou=people,dc=example,dc=org
+    userFilter: (cn=%%s)
+    attributes:
+      nickname: name
+      email: email
+    defaultRoleKey: guest
+    groupRoleMapping:
+      ops: ops
+    groupDeptMapping:
+      ops: 运维部
+`, providers, td.Host(), td.Port())
+	loadTestConfig(t, configYaml)
+}
+
+// 查询用户分配的角色key
+func adminRoleKeys(adminId uint) []string {
+	var keys []string
+	db.Db.Table("sys_role").Select("sys_role.role_key").
+		Joins("JOIN sys_admin_role ON sys_admin_role.role_id = sys_role.id").
+		Where("sys_admin_role.admin_id = ?", adminId).Pluck("sys_role.role_key", &keys)
+	return keys
+}
+
+func TestLdapLoginProvisionsUser(t *testing.T) {
+	setupLdapAuth(t, "local, ldap")
+
+	sysAdmin, err := service.Authenticate("alice", "alice-pass")
+	if err != nil {
+		t.Fatalf("LDAP login failed: %v", err)
+	}
+	if sysAdmin.ID == 0 || sysAdmin.Source != model.SysAdminSourceLdap {
+		t.Fatalf("Expected provisioned ldap user, got %+v", sysAdmin)
+	}
+	if sysAdmin.Nickname != "Alice" || sysAdmin.Email != "alice@example.com" {
+		t.Errorf("Unexpected synced attributes: %+v", sysAdmin)
+	}
+	var dept model.SysDept
+	db.Db.Where("dept_name = ?", "运维部").First(&dept)
+	if sysAdmin.DeptId != int(dept.ID) {
+		t.Errorf("Expected dept %d, got %d", dept.ID, sysAdmin.DeptId)
+	}
+	if keys := adminRoleKeys(sysAdmin.ID); len(keys) != 1 || keys[0] != "ops" {
+		t.Errorf("Expected role [ops], got %v", keys)
+	}
+
+	// 再次登录不应重复创建用户
+	again, err := service.Authenticate("alice", "alice-pass")
+	if err != nil || again.ID != sysAdmin.ID {
+		t.Fatalf("Expected same user on second login, got %+v, %v", again, err)
+	}
+	var count int64
+	db.Db.Model(&model.SysAdmin{}).Where("username = ?", "alice").Count(&count)
+	if count != 1 {
+		t.Errorf("Expected 1 user, got %d", count)
+	}
+
+	// 外部账号不能通过本地密码登录
+	if _, err := (service.LocalAuthProvider{}).Authenticate("alice", "alice-pass"); !errors.Is(err, service.ErrAuthUserNotFound) {
+		t.Errorf("Expected local provider to skip ldap user, got %v", err)
+	}
+}
+
+func TestLdapLoginDefaultRole(t *testing.T) {
+	setupLdapAuth(t, "ldap")
+
+	sysAdmin, err := service.Authenticate("carol", "carol-pass")
+	if err != nil {
+		t.Fatalf("LDAP login failed: %v", err)
+	}
+	if keys := adminRoleKeys(sysAdmin.ID); len(keys) != 1 || keys[0] != "guest" {
+		t.Errorf("Expected default role [guest], got %v", keys)
+	}
+}
+
+func TestLdapLoginClearsRevokedRoles(t *testing.T) {
+	setupLdapAuth(t, "ldap")
+
+	sysAdmin, err := service.Authenticate("alice", "alice-pass")
+	if err != nil || len(adminRoleKeys(sysAdmin.ID)) != 1 {
+		t.Fatalf("LDAP login failed: %v", err)
+	}
+	// 组映射和默认角色都不再匹配时，再次登录应清空已有角色
+	config.Config.Auth.Ldap.GroupRoleMapping = nil
+	config.Config.Auth.Ldap.DefaultRoleKey = ""
+	if _, err := service.Authenticate("alice", "alice-pass"); err != nil {
+		t.Fatalf("LDAP login failed: %v", err)
+	}
+	if keys := adminRoleKeys(sysAdmin.ID); len(keys) != 0 {
+		t.Errorf("Expected roles to be cleared, got %v", keys)
+	}
+}
+
+func TestLdapLoginFailures(t *testing.T) {
+	setupLdapAuth(t, "ldap, local")
+
+	if _, err := service.Authenticate("alice", "wrong"); !errors.Is(err, service.ErrAuthPasswordNotTrue) {
+		t.Errorf("Expected wrong password error, got %v", err)
+	}
+	if _, err := service.Authenticate("nobody", "whatever"); !errors.Is(err, service.ErrAuthUserNotFound) {
+		t.Errorf("Expected user not found error, got %v", err)
+	}
+	// LDAP 中的同名账号不能接管本地账号
+	if _, err := service.Authenticate("bob", "bob-ldap-pass"); err == nil {
+		t.Errorf("Expected ldap user to be rejected when local account exists")
+	}
+	var bob model.SysAdmin
+	db.Db.Where("username = ?", "bob").First(&bob)
+	if bob.Source != model.SysAdminSourceLocal || bob.Password != util.EncryptionMd5("local-pass") {
+		t.Errorf("Local account should be untouched, got %+v", bob)
+	}
+}
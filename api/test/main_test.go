@@ -0,0 +1,23 @@
+package test
+
+import (
+	"dodevops-api/pkg/envelope"
+	"os"
+	"testing"
+)
+
+// 测试使用的主密钥
+var testMasterKeys = "1:" + mustGenerateKey()
+
+func mustGenerateKey() string {
+	key, err := envelope.GenerateKey()
+	if err != nil {
+		panic(err)
+	}
+	return key
+}
+
+func TestMain(m *testing.M) {
+	os.Setenv(envelope.EnvMasterKeys, testMasterKeys)
+	os.Exit(m.Run())
+}
@@ -0,0 +1,165 @@
+package test
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"dodevops-api/api/system/controller"
+	"dodevops-api/api/system/model"
+	"dodevops-api/common/constant"
+	"dodevops-api/common/result"
+	"dodevops-api/common/util"
+	"dodevops-api/middleware"
+	"dodevops-api/pkg/db"
+	"dodevops-api/pkg/redis"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gin-gonic/gin"
+	goredis "github.com/go-redis/redis/v8"
+	"github.com/pquerna/otp/totp"
+)
+
+// 初始化强制 ops 角色启用二次验证的登录路由，返回路由和 ops 角色用户
+func setupMfaLogin(t *testing.T) (*gin.Engine, *miniredis.Miniredis, model.SysAdmin) {
+	database := setupSystemDB(t)
+	if err := database.AutoMigrate(&model.SysAdminMfa{}); err != nil {
+		t.Fatalf("Failed to auto migrate: %v", err)
+	}
+	mr := miniredis.RunT(t)
+	redis.RedisDb = goredis.NewClient(&goredis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { redis.RedisDb = nil })
+	loadTestConfig(t, `auth:
+  mfa:
+    issuer: AutoOps
+    requiredRoles: [ops]
+`)
+
+	admin := model.SysAdmin{Username: "grace", Password: util.EncryptionMd5("pass"), Status: 1, CreateTime: util.HTime{Time: time.Now()}}
+	database.Create(&admin)
+	var role model.SysRole
+	database.Where("role_key = ?", "ops").First(&role)
+	database.Create(&model.SysAdminRole{AdminId: admin.ID, RoleId: role.ID})
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	group := router.Group("/api/v1")
+	group.POST("/login", controller.Login)
+	group.POST("/login/mfa", controller.LoginMfa)
+	group.POST("/login/mfa/enroll", controller.EnrollMfaByTicket)
+	authed := group.Group("")
+	authed.Use(middleware.AuthMiddleware())
+	authed.GET("/admin/mfa/status", controller.GetSysAdminMfaStatus)
+	authed.POST("/admin/mfa/disable", controller.DisableSysAdminMfa)
+	authed.PUT("/admin/updatePassword", controller.ResetSysAdminPassword)
+	return router, mr, admin
+}
+
+// 使用用户名密码登录，验证码直接写入redis
+func passwordLogin(t *testing.T, router *gin.Engine, mr *miniredis.Miniredis, username string) map[string]interface{} {
+	idKey := util.GenerateRandomString(8)
+	_ = mr.Set(constant.LOGIN_CODE+idKey, "1234")
+	code, data := callApi(router, http.MethodPost, "/api/v1/login", "",
+		model.LoginDto{Username: username, Password: "pass", Image: "1234", IdKey: idKey})
+	if code != 200 {
+		t.Fatalf("Password login failed: %d %s", code, data)
+	}
+	var res map[string]interface{}
+	_ = json.Unmarshal(data, &res)
+	return res
+}
+
+func mfaLogin(router *gin.Engine, ticket, code string) (int, map[string]interface{}) {
+	status, data := callApi(router, http.MethodPost, "/api/v1/login/mfa", "", model.MfaLoginDto{Ticket: ticket, Code: code})
+	var res map[string]interface{}
+	_ = json.Unmarshal(data, &res)
+	return status, res
+}
+
+func TestMfaEnforcedEnrollmentAndLogin(t *testing.T) {
+	router, mr, admin := setupMfaLogin(t)
+
+	// 强制启用但未绑定：不签发token，返回票据
+	challenge := passwordLogin(t, router, mr, "grace")
+	if challenge["token"] != nil || challenge["mfaRequired"] != true || challenge["mfaEnrolled"] != false {
+		t.Fatalf("Expected enrollment challenge, got %v", challenge)
+	}
+	ticket := challenge["mfaTicket"].(string)
+
+	code, data := callApi(router, http.MethodPost, "/api/v1/login/mfa/enroll", "", model.MfaTicketDto{Ticket: ticket})
+	var enroll model.SysAdminMfaEnrollVo
+	_ = json.Unmarshal(data, &enroll)
+	if code != 200 || !strings.HasPrefix(enroll.Url, "otpauth://totp/AutoOps:grace") || !strings.HasPrefix(enroll.QrCode, "data:image/png;base64,") {
+		t.Fatalf("Unexpected enroll result: %d %s", code, data)
+	}
+	var stored model.SysAdminMfa
+	db.Db.Where("admin_id = ?", admin.ID).First(&stored)
+	if stored.Secret == "" || stored.Secret == enroll.Secret || stored.Enabled {
+		t.Fatalf("Secret should be stored encrypted and pending, got %+v", stored)
+	}
+
+	if status, _ := mfaLogin(router, ticket, "000000"); status != int(result.ApiCode.MFACODENOTTRUE) {
+		t.Errorf("Expected wrong code to be rejected, got %d", status)
+	}
+	totpCode, _ := totp.GenerateCode(enroll.Secret, time.Now())
+	status, res := mfaLogin(router, ticket, totpCode)
+	if status != 200 || res["token"] == nil {
+		t.Fatalf("Expected login after enrollment, got %d %v", status, res)
+	}
+	recoveryCodes, _ := res["recoveryCodes"].([]interface{})
+	if len(recoveryCodes) != 10 {
+		t.Fatalf("Expected 10 recovery codes, got %v", res["recoveryCodes"])
+	}
+	jwtToken := res["token"].(string)
+
+	// 票据只能使用一次
+	if status, _ := mfaLogin(router, ticket, totpCode); status != int(result.ApiCode.MFATICKETEXPIRED) {
+		t.Errorf("Expected used ticket to be rejected, got %d", status)
+	}
+	// 已启用：同一验证码不能重放，恢复码只能使用一次
+	challenge = passwordLogin(t, router, mr, "grace")
+	if challenge["mfaEnrolled"] != true {
+		t.Fatalf("Expected enrolled challenge, got %v", challenge)
+	}
+	ticket = challenge["mfaTicket"].(string)
+	if status, _ := mfaLogin(router, ticket, totpCode); status != int(result.ApiCode.MFACODENOTTRUE) {
+		t.Errorf("Expected replayed code to be rejected, got %d", status)
+	}
+	recoveryCode := strings.ToUpper(recoveryCodes[0].(string))
+	if status, res := mfaLogin(router, ticket, recoveryCode); status != 200 || res["token"] == nil {
+		t.Fatalf("Expected recovery code login, got %d %v", status, res)
+	}
+	ticket = passwordLogin(t, router, mr, "grace")["mfaTicket"].(string)
+	if status, _ := mfaLogin(router, ticket, recoveryCode); status != int(result.ApiCode.MFACODENOTTRUE) {
+		t.Errorf("Expected used recovery code to be rejected, got %d", status)
+	}
+
+	// 强制启用的角色不能自行停用
+	if status, _ := callApi(router, http.MethodPost, "/api/v1/admin/mfa/disable", jwtToken, model.SysAdminMfaCodeDto{Code: recoveryCodes[1].(string)}); status == 200 {
+		t.Errorf("Expected disable to be rejected for required role")
+	}
+
+	// 管理员重置后需重新绑定，密码不变
+	if status, _ := callApi(router, http.MethodPut, "/api/v1/admin/updatePassword", jwtToken, model.ResetSysAdminPasswordDto{Id: admin.ID, ResetMfa: true}); status != 200 {
+		t.Fatalf("Reset mfa failed: %d", status)
+	}
+	challenge = passwordLogin(t, router, mr, "grace")
+	if challenge["mfaEnrolled"] != false {
+		t.Errorf("Expected enrollment challenge after reset, got %v", challenge)
+	}
+}
+
+func TestMfaTicketFailureLimit(t *testing.T) {
+	router, mr, _ := setupMfaLogin(t)
+	ticket := passwordLogin(t, router, mr, "grace")["mfaTicket"].(string)
+	callApi(router, http.MethodPost, "/api/v1/login/mfa/enroll", "", model.MfaTicketDto{Ticket: ticket})
+
+	for i := 0; i < 5; i++ {
+		mfaLogin(router, ticket, "000000")
+	}
+	if status, _ := mfaLogin(router, ticket, "000000"); status != int(result.ApiCode.MFATICKETEXPIRED) {
+		t.Errorf("Expected ticket to expire after repeated failures, got %d", status)
+	}
+}
@@ -0,0 +1,356 @@
+package test
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"dodevops-api/api/system/controller"
+	"dodevops-api/api/system/model"
+	"dodevops-api/common/config"
+	"dodevops-api/pkg/db"
+	"dodevops-api/pkg/jwt"
+	"dodevops-api/pkg/redis"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gin-gonic/gin"
+	jose "github.com/go-jose/go-jose/v4"
+	goredis "github.com/go-redis/redis/v8"
+)
+
+const (
+	mockOidcClientId     = "autoops"
+	mockOidcClientSecret = "autoops-secret"
+	mockOidcPublicUrl    = "http://autoops.test/"
+)
+
+// mockOidcProvider 模拟 OIDC 提供者，实现 discovery、授权、令牌、用户信息和 JWKS 接口
+type mockOidcProvider struct {
+	t      *testing.T
+	server *httptest.Server
+	key    *rsa.PrivateKey
+	claims map[string]interface{} // id_token 中的用户声明
+
+	mu       sync.Mutex
+	requests map[string]url.Values // 授权码 -> 授权请求参数
+}
+
+func newMockOidcProvider(t *testing.T, claims map[string]interface{}) *mockOidcProvider {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	p := &mockOidcProvider{t: t, key: key, claims: claims, requests: make(map[string]url.Values)}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", p.discovery)
+	mux.HandleFunc("/authorize", p.authorize)
+	mux.HandleFunc("/token", p.token)
+	mux.HandleFunc("/userinfo", p.userInfo)
+	mux.HandleFunc("/jwks", p.jwks)
+	p.server = httptest.NewServer(mux)
+	t.Cleanup(p.server.Close)
+	return p
+}
+
+func (p *mockOidcProvider) discovery(w http.ResponseWriter, r *http.Request) {
+	issuer := p.server.URL
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{
+		"issuer":                                issuer,
+		"authorization_endpoint":                issuer + "/authorize",
+		"token_endpoint":                        issuer + "/token",
+		"userinfo_endpoint":                     issuer + "/userinfo",
+		"jwks_uri":                              issuer + "/jwks",
+		"id_token_signing_alg_values_supported": []string{"RS256"},
+		"code_challenge_methods_supported":      []string{"S256"},
+	})
+}
+
+// 授权接口直接视为用户已同意，携带授权码跳回客户端
+func (p *mockOidcProvider) authorize(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	if query.Get("client_id") != mockOidcClientId || query.Get("code_challenge_method") != "S256" || query.Get("code_challenge") == "" {
+		http.Error(w, "invalid_request", http.StatusBadRequest)
+		return
+	}
+	code := fmt.Sprintf("code-%d", time.Now().UnixNano())
+	p.mu.Lock()
+	p.requests[code] = query
+	p.mu.Unlock()
+	redirect, _ := url.Parse(query.Get("redirect_uri"))
+	values := redirect.Query()
+	values.Set("code", code)
+	values.Set("state", query.Get("state"))
+	redirect.RawQuery = values.Encode()
+	http.Redirect(w, r, redirect.String(), http.StatusFound)
+}
+
+// 令牌接口校验客户端凭据和 PKCE code_verifier，签发 id_token
+func (p *mockOidcProvider) token(w http.ResponseWriter, r *http.Request) {
+	_ = r.ParseForm()
+	clientId, clientSecret, ok := r.BasicAuth()
+	if !ok {
+		clientId, clientSecret = r.PostForm.Get("client_id"), r.PostForm.Get("client_secret")
+	}
+	if clientId != mockOidcClientId || clientSecret != mockOidcClientSecret {
+		http.Error(w, `{"error":"invalid_client"}`, http.StatusUnauthorized)
+		return
+	}
+	p.mu.Lock()
+	authRequest, ok := p.requests[r.PostForm.Get("code")]
+	delete(p.requests, r.PostForm.Get("code"))
+	p.mu.Unlock()
+	if !ok || authRequest.Get("redirect_uri") != r.PostForm.Get("redirect_uri") {
+		http.Error(w, `{"error":"invalid_grant"}`, http.StatusBadRequest)
+		return
+	}
+	sum := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+	if base64.RawURLEncoding.EncodeToString(sum[:]) != authRequest.Get("code_challenge") {
+		http.Error(w, `{"error":"invalid_grant","error_description":"pkce"}`, http.StatusBadRequest)
+		return
+	}
+	claims := map[string]interface{}{
+		"iss":   p.server.URL,
+		"aud":   mockOidcClientId,
+		"iat":   time.Now().Unix(),
+		"exp":   time.Now().Add(time.Minute).Unix(),
+		"nonce": authRequest.Get("nonce"),
+	}
+	for k, v := range p.claims {
+		claims[k] = v
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{
+		"access_token": "access-" + claims["sub"].(string),
+		"token_type":   "Bearer",
+		"expires_in":   60,
+		"id_token":     p.sign(claims),
+	})
+}
+
+func (p *mockOidcProvider) userInfo(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("Authorization") != "Bearer access-"+p.claims["sub"].(string) {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{
+		"sub":          p.claims["sub"],
+		"phone_number": "13800000000",
+	})
+}
+
+func (p *mockOidcProvider) jwks(w http.ResponseWriter, r *http.Request) {
+	_ = json.NewEncoder(w).Encode(jose.JSONWebKeySet{Keys: []jose.JSONWebKey{
+		{Key: &p.key.PublicKey, KeyID: "test", Algorithm: "RS256", Use: "sig"},
+	}})
+}
+
+func (p *mockOidcProvider) sign(claims map[string]interface{}) string {
+	signer, err := jose.NewSigner(jose.SigningKey{Algorithm: jose.RS256, Key: p.key},
+		(&jose.SignerOptions{}).WithHeader("kid", "test"))
+	if err != nil {
+		p.t.Fatalf("Failed to create signer: %v", err)
+	}
+	payload, _ := json.Marshal(claims)
+	jws, err := signer.Sign(payload)
+	if err != nil {
+		p.t.Fatalf("Failed to sign id_token: %v", err)
+	}
+	token, _ := jws.CompactSerialize()
+	return token
+}
+
+// 初始化数据库、redis、配置和单点登录路由
+func setupOidcSso(t *testing.T, provider *mockOidcProvider) *gin.Engine {
+	setupSystemDB(t)
+	mr := miniredis.RunT(t)
+	redis.RedisDb = goredis.NewClient(&goredis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { redis.RedisDb = nil })
+
+	loadTestConfig(t, fmt.Sprintf(`server:
+  publicUrl: %s
+auth:
+  oidc:
+    - name: mock
+      issuer: %s
+      clientId: %s
+      clientSecret: %s
+      claims:
+        groups: groups
+      defaultRoleKey: guest
+      groupRoleMapping:
+        ops: ops
+      groupDeptMapping:
+        ops: 运维部
+`, mockOidcPublicUrl, provider.server.URL, mockOidcClientId, mockOidcClientSecret))
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/api/v1/sso/providers", controller.GetSsoProviderList)
+	router.GET("/api/v1/sso/:provider/login", controller.SsoLogin)
+	router.GET("/api/v1/sso/:provider/callback", controller.SsoCallback)
+	router.POST("/api/v1/sso/exchange", controller.SsoExchange)
+	return router
+}
+
+func serveSso(router *gin.Engine, target string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, target, nil)
+	req.RemoteAddr = "127.0.0.1:12345"
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+// 完成一次浏览器登录跳转：发起登录 -> 提供者授权页 -> 返回客户端回调地址
+func ssoAuthorize(t *testing.T, router *gin.Engine) string {
+	w := serveSso(router, "/api/v1/sso/mock/login")
+	if w.Code != http.StatusFound {
+		t.Fatalf("Expected redirect to provider, got %d: %s", w.Code, w.Body.String())
+	}
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+	resp, err := client.Get(w.Header().Get("Location"))
+	if err != nil {
+		t.Fatalf("Authorize request failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusFound {
+		t.Fatalf("Expected provider to redirect back, got %d", resp.StatusCode)
+	}
+	callback, _ := url.Parse(resp.Header.Get("Location"))
+	if callback.Host != "autoops.test" || callback.Path != "/api/v1/sso/mock/callback" {
+		t.Fatalf("Unexpected callback url: %s", callback)
+	}
+	return callback.RequestURI()
+}
+
+func TestOidcSsoRoundTrip(t *testing.T) {
+	provider := newMockOidcProvider(t, map[string]interface{}{
+		"sub":                "user-1",
+		"preferred_username": "dave",
+		"name":               "Dave",
+		"email":              "dave@example.com",
+		"groups":             []string{"/ops"},
+	})
+	router := setupOidcSso(t, provider)
+
+	w := serveSso(router, "/api/v1/sso/providers")
+	var providers struct {
+		Data []map[string]string `json:"data"`
+	}
+	_ = json.Unmarshal(w.Body.Bytes(), &providers)
+	if len(providers.Data) != 1 || providers.Data[0]["name"] != "mock" {
+		t.Fatalf("Unexpected provider list: %s", w.Body.String())
+	}
+
+	callback := ssoAuthorize(t, router)
+	w = serveSso(router, callback)
+	var res struct {
+		Code int `json:"code"`
+		Data struct {
+			Token    string         `json:"token"`
+			SysAdmin model.SysAdmin `json:"sysAdmin"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &res); err != nil || res.Code != 200 {
+		t.Fatalf("SSO callback failed: %s", w.Body.String())
+	}
+	jwtAdmin, err := jwt.ValidateToken(res.Data.Token)
+	if err != nil {
+		t.Fatalf("Issued token is invalid: %v", err)
+	}
+	if jwtAdmin.Username != "dave" || jwtAdmin.ID != res.Data.SysAdmin.ID {
+		t.Errorf("Unexpected token subject: %+v", jwtAdmin)
+	}
+	sysAdmin := res.Data.SysAdmin
+	if sysAdmin.Source != model.SysAdminSourceOidc+"mock" || sysAdmin.Email != "dave@example.com" || sysAdmin.Phone != "13800000000" {
+		t.Errorf("Unexpected provisioned user: %+v", sysAdmin)
+	}
+	if keys := adminRoleKeys(sysAdmin.ID); len(keys) != 1 || keys[0] != "ops" {
+		t.Errorf("Expected role [ops], got %v", keys)
+	}
+
+	// state 只能使用一次
+	w = serveSso(router, callback)
+	if err := json.Unmarshal(w.Body.Bytes(), &res); err != nil || res.Code == 200 {
+		t.Errorf("Expected replayed callback to fail, got %s", w.Body.String())
+	}
+}
+
+func TestOidcSsoFrontendLoginCode(t *testing.T) {
+	provider := newMockOidcProvider(t, map[string]interface{}{"sub": "user-3", "preferred_username": "frank"})
+	router := setupOidcSso(t, provider)
+	config.Config.Auth.Oidc[0].FrontendUrl = "http://web.test/#/sso"
+
+	// 跳转前端时只携带一次性登录码，不携带令牌
+	w := serveSso(router, ssoAuthorize(t, router))
+	location := w.Header().Get("Location")
+	if w.Code != http.StatusFound || !strings.HasPrefix(location, "http://web.test/#/sso?code=") || strings.Contains(location, "token") {
+		t.Fatalf("Unexpected frontend redirect: %d %s", w.Code, location)
+	}
+	code := strings.TrimPrefix(location, "http://web.test/#/sso?code=")
+
+	res, data := callApi(router, http.MethodPost, "/api/v1/sso/exchange", "", model.SsoExchangeDto{Code: code})
+	var login struct {
+		Token string `json:"token"`
+	}
+	if err := json.Unmarshal(data, &login); res != 200 || err != nil {
+		t.Fatalf("Exchange login code failed: %d %s", res, data)
+	}
+	if jwtAdmin, err := jwt.ValidateToken(login.Token); err != nil || jwtAdmin.Username != "frank" {
+		t.Errorf("Unexpected exchanged token: %+v, %v", jwtAdmin, err)
+	}
+	// 登录码只能使用一次
+	if res, _ := callApi(router, http.MethodPost, "/api/v1/sso/exchange", "", model.SsoExchangeDto{Code: code}); res == 200 {
+		t.Errorf("Expected reused login code to be rejected")
+	}
+}
+
+func TestOidcSsoRequiresMfa(t *testing.T) {
+	provider := newMockOidcProvider(t, map[string]interface{}{"sub": "user-4", "preferred_username": "grace", "groups": []string{"ops"}})
+	router := setupOidcSso(t, provider)
+	config.Config.Auth.Mfa.RequiredRoles = []string{"ops"}
+
+	// 所属角色强制二次验证时，单点登录同样只返回二次验证票据
+	w := serveSso(router, ssoAuthorize(t, router))
+	var res struct {
+		Code int                    `json:"code"`
+		Data map[string]interface{} `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &res); err != nil || res.Code != 200 {
+		t.Fatalf("SSO callback failed: %s", w.Body.String())
+	}
+	if res.Data["mfaRequired"] != true || res.Data["mfaTicket"] == nil || res.Data["token"] != nil {
+		t.Errorf("Expected MFA challenge without token, got %v", res.Data)
+	}
+}
+
+func TestOidcSsoRejectsForgedState(t *testing.T) {
+	provider := newMockOidcProvider(t, map[string]interface{}{"sub": "user-2", "preferred_username": "erin"})
+	router := setupOidcSso(t, provider)
+
+	callback, _ := url.Parse(ssoAuthorize(t, router))
+	values := callback.Query()
+	values.Set("state", "forged")
+	callback.RawQuery = values.Encode()
+	w := serveSso(router, callback.RequestURI())
+	var res struct {
+		Code int `json:"code"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &res); err != nil || res.Code == 200 {
+		t.Errorf("Expected forged state to fail, got %s", w.Body.String())
+	}
+	var count int64
+	db.Db.Model(&model.SysAdmin{}).Where("username = ?", "erin").Count(&count)
+	if count != 0 {
+		t.Errorf("User should not be provisioned with forged state")
+	}
+}
@@ -0,0 +1,184 @@
+package test
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"dodevops-api/api/system/controller"
+	"dodevops-api/api/system/model"
+	"dodevops-api/common/constant"
+	"dodevops-api/common/result"
+	"dodevops-api/common/util"
+	"dodevops-api/middleware"
+	"dodevops-api/pkg/redis"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gin-gonic/gin"
+	goredis "github.com/go-redis/redis/v8"
+)
+
+// 初始化登录锁定和密码策略相关路由
+func setupPasswordPolicy(t *testing.T, configYaml string, admin *model.SysAdmin) (*gin.Engine, *miniredis.Miniredis) {
+	database := setupSystemDB(t)
+	if err := database.AutoMigrate(&model.SysAdminMfa{}, &model.SysPasswordHistory{}); err != nil {
+		t.Fatalf("Failed to auto migrate: %v", err)
+	}
+	mr := miniredis.RunT(t)
+	redis.RedisDb = goredis.NewClient(&goredis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { redis.RedisDb = nil })
+	loadTestConfig(t, configYaml)
+	admin.Status = 1
+	admin.CreateTime = util.HTime{Time: time.Now()}
+	database.Create(admin)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	group := router.Group("/api/v1")
+	group.POST("/login", controller.Login)
+	authed := group.Group("")
+	authed.Use(middleware.AuthMiddleware())
+	authed.GET("/ping", func(c *gin.Context) { result.Success(c, true) })
+	authed.PUT("/admin/updatePassword", controller.ResetSysAdminPassword)
+	authed.PUT("/admin/updatePersonalPassword", controller.UpdatePersonalPassword)
+	return router, mr
+}
+
+// 使用指定密码登录，验证码直接写入redis
+func loginWithPassword(router *gin.Engine, mr *miniredis.Miniredis, username, password string) (int, map[string]interface{}) {
+	idKey := util.GenerateRandomString(8)
+	_ = mr.Set(constant.LOGIN_CODE+idKey, "1234")
+	code, data := callApi(router, http.MethodPost, "/api/v1/login", "",
+		model.LoginDto{Username: username, Password: password, Image: "1234", IdKey: idKey})
+	var res map[string]interface{}
+	_ = json.Unmarshal(data, &res)
+	return code, res
+}
+
+func TestLoginLockout(t *testing.T) {
+	router, mr := setupPasswordPolicy(t, `auth:
+  lockout:
+    maxFailures: 3
+    ipMaxFailures: 5
+    lockMinutes: 10
+`, &model.SysAdmin{Username: "ivan", Password: util.EncryptionMd5("pass")})
+
+	// 登录成功后失败次数清零
+	loginWithPassword(router, mr, "ivan", "wrong")
+	loginWithPassword(router, mr, "ivan", "wrong")
+	if code, _ := loginWithPassword(router, mr, "ivan", "pass"); code != 200 {
+		t.Fatalf("Expected login below failure limit, got %d", code)
+	}
+	for i := 0; i < 3; i++ {
+		if code, _ := loginWithPassword(router, mr, "ivan", "wrong"); code != 410 {
+			t.Fatalf("Expected wrong password, got %d", code)
+		}
+	}
+	if code, _ := loginWithPassword(router, mr, "ivan", "pass"); code != 433 {
+		t.Fatalf("Expected account to be locked, got %d", code)
+	}
+	mr.FastForward(10 * time.Minute)
+	if code, _ := loginWithPassword(router, mr, "ivan", "pass"); code != 200 {
+		t.Fatalf("Expected lock to expire, got %d", code)
+	}
+
+	// 同一IP尝试不同用户名累计失败后锁定IP
+	for _, username := range []string{"a", "b", "c", "d", "e"} {
+		loginWithPassword(router, mr, username, "wrong")
+	}
+	if code, _ := loginWithPassword(router, mr, "ivan", "pass"); code != 433 {
+		t.Errorf("Expected ip to be locked, got %d", code)
+	}
+}
+
+func TestLoginFailureDelay(t *testing.T) {
+	router, mr := setupPasswordPolicy(t, `auth:
+  lockout:
+    maxFailures: 10
+    baseDelaySecond: 2
+    maxDelaySecond: 3
+`, &model.SysAdmin{Username: "ivan", Password: util.EncryptionMd5("pass")})
+
+	loginWithPassword(router, mr, "ivan", "wrong")
+	if code, _ := loginWithPassword(router, mr, "ivan", "pass"); code != 433 {
+		t.Fatalf("Expected retry to be delayed, got %d", code)
+	}
+	mr.FastForward(2 * time.Second)
+	if code, _ := loginWithPassword(router, mr, "ivan", "wrong"); code != 410 {
+		t.Fatalf("Expected attempt after delay, got %d", code)
+	}
+	// 第二次失败等待时间翻倍，但不超过上限
+	mr.FastForward(2 * time.Second)
+	if code, _ := loginWithPassword(router, mr, "ivan", "pass"); code != 433 {
+		t.Errorf("Expected doubled delay, got %d", code)
+	}
+	mr.FastForward(time.Second)
+	if code, _ := loginWithPassword(router, mr, "ivan", "pass"); code != 200 {
+		t.Errorf("Expected delay to be capped, got %d", code)
+	}
+}
+
+func TestPasswordExpiryAndPolicy(t *testing.T) {
+	expired := util.HTime{Time: time.Now().AddDate(0, 0, -40)}
+	admin := &model.SysAdmin{Username: "judy", Password: util.EncryptionMd5("pass"), PasswordUpdateTime: &expired}
+	router, mr := setupPasswordPolicy(t, `auth:
+  password:
+    minLength: 8
+    requireUpper: true
+    requireDigit: true
+    historyCount: 2
+    expireDays: 30
+`, admin)
+
+	// 密码过期：登录成功但令牌只能用于修改密码
+	code, login := loginWithPassword(router, mr, "judy", "pass")
+	if code != 200 || login["passwordChangeRequired"] != true {
+		t.Fatalf("Expected password change required, got %d %v", code, login)
+	}
+	token := login["token"].(string)
+	if code, _ := callApi(router, http.MethodGet, "/api/v1/ping", token, nil); code != 432 {
+		t.Fatalf("Expected api access to be blocked, got %d", code)
+	}
+
+	changePassword := func(token, old, new string) (int, string) {
+		code, data := callApi(router, http.MethodPut, "/api/v1/admin/updatePersonalPassword", token,
+			model.UpdatePersonalPasswordDto{Password: old, NewPassword: new, ResetPassword: new})
+		var res map[string]interface{}
+		_ = json.Unmarshal(data, &res)
+		newToken, _ := res["token"].(string)
+		return code, newToken
+	}
+	for _, weak := range []string{"Sh0rt", "nouppercase1", "NoDigitsHere"} {
+		if code, _ := changePassword(token, "pass", weak); code != 434 {
+			t.Errorf("Expected %q to be rejected, got %d", weak, code)
+		}
+	}
+	code, token = changePassword(token, "pass", "Str0ngPass")
+	if code != 200 {
+		t.Fatalf("Change password failed: %d", code)
+	}
+	if code, _ := callApi(router, http.MethodGet, "/api/v1/ping", token, nil); code != 200 {
+		t.Fatalf("Expected api access after password change, got %d", code)
+	}
+
+	// 不能与当前密码及最近使用过的密码相同
+	if code, _ := changePassword(token, "Str0ngPass", "Str0ngPass"); code != 434 {
+		t.Errorf("Expected current password to be rejected, got %d", code)
+	}
+	if code, token = changePassword(token, "Str0ngPass", "An0therPass"); code != 200 {
+		t.Fatalf("Change password failed: %d", code)
+	}
+	if code, _ := changePassword(token, "An0therPass", "Str0ngPass"); code != 434 {
+		t.Errorf("Expected recent password to be rejected, got %d", code)
+	}
+
+	// 管理员重置密码并要求下次登录修改
+	if code, _ := callApi(router, http.MethodPut, "/api/v1/admin/updatePassword", token,
+		model.ResetSysAdminPasswordDto{Id: admin.ID, Password: "Res3tPass", ForceChangePassword: true}); code != 200 {
+		t.Fatalf("Reset password failed: %d", code)
+	}
+	if code, login := loginWithPassword(router, mr, "judy", "Res3tPass"); code != 200 || login["passwordChangeRequired"] != true {
+		t.Errorf("Expected forced change after reset, got %d %v", code, login)
+	}
+}
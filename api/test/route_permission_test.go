@@ -0,0 +1,76 @@
+package test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"dodevops-api/common/config"
+	"dodevops-api/common/result"
+	"dodevops-api/middleware"
+	"dodevops-api/pkg/db"
+	"dodevops-api/router"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// 不需要登录的接口，其余 /api/v1 下的接口都必须登记权限值
+var publicRoutes = map[string]bool{
+	"GET:/api/v1/captcha":                           true,
+	"POST:/api/v1/login":                            true,
+	"POST:/api/v1/login/mfa":                        true,
+	"POST:/api/v1/login/mfa/enroll":                 true,
+	"POST:/api/v1/refreshToken":                     true,
+	"GET:/api/v1/sso/providers":                     true,
+	"GET:/api/v1/sso/:provider/login":               true,
+	"GET:/api/v1/sso/:provider/callback":            true,
+	"POST:/api/v1/sso/exchange":                     true,
+	"POST:/api/v1/monitor/agent/heartbeat":          true,
+	"POST:/api/v1/monitor/alert/webhook/gitlab":     true,
+	"POST:/api/v1/monitor/alert/webhook/zabbix":     true,
+	"POST:/api/v1/monitor/alert/webhook/prometheus": true,
+	"GET:/api/v1/upload/*filepath":                  true,
+	"HEAD:/api/v1/upload/*filepath":                 true,
+}
+
+// 遍历所有已注册的路由，确认需要登录的接口都纳入了权限管理
+func TestAllRoutesHavePermission(t *testing.T) {
+	// 注册路由时各控制器会获取数据库连接
+	database, err := gorm.Open(sqlite.Open("file:routes?mode=memory&cache=shared"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("Failed to connect database: %v", err)
+	}
+	db.Db = database
+	config.Config = nil
+	if err := config.LoadConfig("../config.yaml"); err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	engine := router.InitRouter()
+	for _, route := range engine.Routes() {
+		key := route.Method + ":" + route.Path
+		if !strings.HasPrefix(route.Path, "/api/v1/") || publicRoutes[key] {
+			continue
+		}
+		if _, ok := middleware.GetAPIPermission(route.Method, route.Path); !ok {
+			t.Errorf("Route %s is not mapped to a permission", key)
+		}
+	}
+}
+
+// 未纳入权限管理的接口返回 403
+func TestUnmappedRouteForbidden(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	group := engine.Group("/api/v1")
+	group.Use(middleware.PermissionMiddleware())
+	group.GET("/unmapped", func(c *gin.Context) { result.Success(c, true) })
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/unmapped", nil)
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, req)
+	if w.Code != http.StatusForbidden {
+		t.Errorf("Expected unmapped route to be forbidden, got %d %s", w.Code, w.Body.String())
+	}
+}
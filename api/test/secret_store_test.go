@@ -0,0 +1,200 @@
+package test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	configdao "dodevops-api/api/configcenter/dao"
+	configmodel "dodevops-api/api/configcenter/model"
+	"dodevops-api/api/system/model"
+	"dodevops-api/common/config"
+	"dodevops-api/common/util"
+	"dodevops-api/pkg/envelope"
+	"dodevops-api/pkg/secretstore"
+)
+
+// 模拟 Vault KV v2 接口
+type fakeVault struct {
+	mu      sync.Mutex
+	secrets map[string]string
+	reads   int
+}
+
+func newFakeVault(t *testing.T) (*fakeVault, *httptest.Server) {
+	v := &fakeVault{secrets: map[string]string{}}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Vault-Token") != "test-token" {
+			w.WriteHeader(http.StatusForbidden)
+			_, _ = w.Write([]byte(`{"errors":["permission denied"]}`))
+			return
+		}
+		v.mu.Lock()
+		defer v.mu.Unlock()
+		switch {
+		case r.Method == http.MethodPost && strings.HasPrefix(r.URL.Path, "/v1/kv/data/"):
+			var body struct {
+				Data map[string]string `json:"data"`
+			}
+			_ = json.NewDecoder(r.Body).Decode(&body)
+			v.secrets[strings.TrimPrefix(r.URL.Path, "/v1/kv/data/")] = body.Data["value"]
+			_, _ = w.Write([]byte(`{"data":{"version":1}}`))
+		case r.Method == http.MethodGet && strings.HasPrefix(r.URL.Path, "/v1/kv/data/"):
+			v.reads++
+			value, ok := v.secrets[strings.TrimPrefix(r.URL.Path, "/v1/kv/data/")]
+			if !ok {
+				w.WriteHeader(http.StatusNotFound)
+				_, _ = w.Write([]byte(`{"errors":[]}`))
+				return
+			}
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{"data": map[string]interface{}{"data": map[string]string{"value": value}}})
+		case r.Method == http.MethodDelete && strings.HasPrefix(r.URL.Path, "/v1/kv/metadata/"):
+			delete(v.secrets, strings.TrimPrefix(r.URL.Path, "/v1/kv/metadata/"))
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return v, server
+}
+
+func (v *fakeVault) readCount() int {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	return v.reads
+}
+
+func (v *fakeVault) count() int {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	return len(v.secrets)
+}
+
+func setupSecretStore(t *testing.T, cfg config.SecretStoreConfig) {
+	if err := secretstore.Setup(cfg); err != nil {
+		t.Fatalf("Failed to setup secret store: %v", err)
+	}
+	t.Cleanup(func() { _ = secretstore.Setup(config.SecretStoreConfig{}) })
+}
+
+func TestVaultSecretStore(t *testing.T) {
+	database := setupSystemDB(t)
+	if err := database.AutoMigrate(&configmodel.KeyManage{}, &configmodel.AccountAuth{}, &configmodel.EcsAuth{}, &model.SysAdminMfa{}, &model.SysApprovalTicket{}); err != nil {
+		t.Fatalf("Failed to auto migrate: %v", err)
+	}
+	if err := secretstore.Register(database); err != nil {
+		t.Fatalf("Failed to register secret store callbacks: %v", err)
+	}
+	vault, server := newFakeVault(t)
+	vaultConfig := config.VaultConfig{Address: server.URL, Token: "test-token", Mount: "kv"}
+
+	// 切换前保存在数据库中的凭证
+	setupSecretStore(t, config.SecretStoreConfig{Vault: vaultConfig})
+	ecsDao := configdao.NewEcsAuthDao()
+	old := configmodel.EcsAuth{Name: "old", Type: 1, Username: "root", Password: "old-pass", Port: 22, CreateTime: util.HTime{Time: time.Now()}}
+	if err := ecsDao.CreateEcsAuth(&old); err != nil {
+		t.Fatal(err)
+	}
+	if raw := rawColumn(database, "config_ecsauth", "password", old.ID); !envelope.IsEncrypted(raw) {
+		t.Fatalf("Expected db backend to store ciphertext, got %q", raw)
+	}
+
+	setupSecretStore(t, config.SecretStoreConfig{Backend: secretstore.BackendVault, Vault: vaultConfig})
+	auth := configmodel.EcsAuth{Name: "new", Type: 2, Username: "root", PublicKey: "-----BEGIN KEY-----", Port: 22, CreateTime: util.HTime{Time: time.Now()}}
+	if err := ecsDao.CreateEcsAuth(&auth); err != nil {
+		t.Fatal(err)
+	}
+	raw := rawColumn(database, "config_ecsauth", "public_key", auth.ID)
+	if !secretstore.IsReference(raw) || strings.Contains(raw, "BEGIN") || vault.count() != 1 {
+		t.Fatalf("Expected only a vault reference in the record, got %q", raw)
+	}
+	// 读库时只保留引用，使用时再从 Vault 读取
+	got, _ := ecsDao.GetById(auth.ID)
+	if got.PublicKey != raw || len(ecsDao.GetEcsAuthList()) != 2 || vault.readCount() != 0 {
+		t.Errorf("Expected reads not to call vault, got %q and %d vault reads", got.PublicKey, vault.readCount())
+	}
+	if err := got.ResolveSecrets(); err != nil || got.PublicKey != "-----BEGIN KEY-----" {
+		t.Errorf("Expected private key to be read from vault, got %q: %v", got.PublicKey, err)
+	}
+
+	// 原样提交读出的引用不重复保存，不能引用其他记录的凭证
+	got, _ = ecsDao.GetById(auth.ID)
+	if err := ecsDao.UpdateEcsAuth(auth.ID, &got); err != nil || rawColumn(database, "config_ecsauth", "public_key", auth.ID) != raw || vault.count() != 1 {
+		t.Errorf("Expected unchanged reference to be kept, got %d secrets: %v", vault.count(), err)
+	}
+	if err := ecsDao.UpdateEcsAuth(old.ID, &configmodel.EcsAuth{Password: raw}); err == nil {
+		t.Errorf("Expected reference to another record to be rejected")
+	}
+
+	// 写库失败时释放已保存到 Vault 的凭证
+	if err := ecsDao.CreateEcsAuth(&configmodel.EcsAuth{ID: auth.ID, Name: "dup", Type: 1, Password: "dup-pass", CreateTime: util.HTime{Time: time.Now()}}); err == nil || vault.count() != 1 {
+		t.Errorf("Expected failed insert to release its vault secret, got %d secrets: %v", vault.count(), err)
+	}
+
+	// 修改后旧引用被释放
+	if err := ecsDao.UpdateEcsAuth(auth.ID, &configmodel.EcsAuth{PublicKey: "-----NEW KEY-----"}); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := ecsDao.GetById(auth.ID); got.ResolveSecrets() != nil || got.PublicKey != "-----NEW KEY-----" || vault.count() != 1 {
+		t.Errorf("Expected updated key with one vault secret, got %q, %d secrets", got.PublicKey, vault.count())
+	}
+
+	// 云厂商密钥与账号密码
+	keyDao := configdao.NewKeyManageDao()
+	key := configmodel.KeyManage{KeyType: 1, KeyID: "ak", KeySecret: "sk"}
+	if err := keyDao.Create(&key); err != nil {
+		t.Fatal(err)
+	}
+	stored, _ := keyDao.GetByID(key.ID)
+	if !secretstore.IsReference(stored.KeyID) || !secretstore.IsReference(stored.KeySecret) {
+		t.Fatalf("Expected AK/SK references, got %q %q", stored.KeyID, stored.KeySecret)
+	}
+	if id, secret, err := stored.DecryptKeys(); err != nil || id != "ak" || secret != "sk" {
+		t.Errorf("DecryptKeys returned %q %q %v", id, secret, err)
+	}
+	accountDao := configdao.NewAccountAuthDao()
+	account := configmodel.AccountAuth{Alias: "db", Host: "127.0.0.1", Port: 3306, Name: "root", Password: "db-pass", Type: 1}
+	if err := accountDao.Create(&account); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := accountDao.GetByID(account.ID); !secretstore.IsReference(got.Password) {
+		t.Errorf("Expected account password reference, got %q", got.Password)
+	}
+	if err := accountDao.Delete(account.ID); err != nil || vault.count() != 3 {
+		t.Errorf("Expected account secret to be released, got %d secrets: %v", vault.count(), err)
+	}
+
+	// 已有数据库凭证迁移到 Vault
+	res, err := secretstore.Migrate(context.Background(), database)
+	if err != nil || res.Migrated != 1 {
+		t.Fatalf("Unexpected migrate result %+v: %v", res, err)
+	}
+	if raw := rawColumn(database, "config_ecsauth", "password", old.ID); !secretstore.IsReference(raw) {
+		t.Errorf("Expected migrated password reference, got %q", raw)
+	}
+	if got, _ := ecsDao.GetById(old.ID); got.ResolveSecrets() != nil || got.Password != "old-pass" {
+		t.Errorf("Expected migrated password, got %q", got.Password)
+	}
+
+	// 引用不参与主密钥轮换
+	status, _ := envelope.Status(database)
+	for _, column := range status {
+		if column.Table == "config_ecsauth" && column.Column == "password" && column.External != 1 {
+			t.Errorf("Expected password to be external, got %+v", column)
+		}
+	}
+	if res, err := envelope.Rotate(database); err != nil || res.Rotated != 0 {
+		t.Errorf("Expected references not to be rotated, got %+v: %v", res, err)
+	}
+
+	// 删除记录释放 Vault 中的凭证
+	if err := ecsDao.DeleteEcsAuth(auth.ID); err != nil || vault.count() != 3 {
+		t.Errorf("Expected ecs secret to be released, got %d secrets: %v", vault.count(), err)
+	}
+}
@@ -0,0 +1,154 @@
+package test
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"dodevops-api/api/system/controller"
+	"dodevops-api/api/system/model"
+	"dodevops-api/common/result"
+	"dodevops-api/common/util"
+	"dodevops-api/middleware"
+	"dodevops-api/pkg/redis"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gin-gonic/gin"
+	goredis "github.com/go-redis/redis/v8"
+)
+
+// 初始化登录、刷新和会话管理路由
+func setupSession(t *testing.T) (*gin.Engine, *miniredis.Miniredis, model.SysAdmin) {
+	database := setupSystemDB(t)
+	if err := database.AutoMigrate(&model.SysAdminMfa{}); err != nil {
+		t.Fatalf("Failed to auto migrate: %v", err)
+	}
+	mr := miniredis.RunT(t)
+	redis.RedisDb = goredis.NewClient(&goredis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { redis.RedisDb = nil })
+	loadTestConfig(t, `auth:
+  session:
+    accessTokenMinutes: 5
+    refreshTokenDays: 1
+`)
+	admin := model.SysAdmin{Username: "henry", Password: util.EncryptionMd5("pass"), Status: 1, CreateTime: util.HTime{Time: time.Now()}}
+	database.Create(&admin)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	group := router.Group("/api/v1")
+	group.POST("/login", controller.Login)
+	group.POST("/refreshToken", controller.RefreshToken)
+	authed := group.Group("")
+	authed.Use(middleware.AuthMiddleware())
+	authed.GET("/ping", func(c *gin.Context) { result.Success(c, true) })
+	authed.POST("/admin/logout", controller.Logout)
+	authed.GET("/admin/session/list", controller.GetMySessionList)
+	authed.POST("/admin/session/forceLogout", controller.ForceLogout)
+	authed.PUT("/admin/updateStatus", controller.UpdateSysAdminStatus)
+	return router, mr, admin
+}
+
+func refreshToken(router *gin.Engine, refresh string) (int, map[string]interface{}) {
+	code, data := callApi(router, http.MethodPost, "/api/v1/refreshToken", "", model.RefreshTokenDto{RefreshToken: refresh})
+	var res map[string]interface{}
+	_ = json.Unmarshal(data, &res)
+	return code, res
+}
+
+func TestSessionRefreshRotation(t *testing.T) {
+	router, mr, _ := setupSession(t)
+	login := passwordLogin(t, router, mr, "henry")
+	token, refresh := login["token"].(string), login["refreshToken"].(string)
+	if refresh == "" || login["expiresIn"] != float64(300) {
+		t.Fatalf("Expected refresh token and 5 minute access token, got %v", login)
+	}
+	if code, _ := callApi(router, http.MethodGet, "/api/v1/ping", token, nil); code != 200 {
+		t.Fatalf("Expected access token to work, got %d", code)
+	}
+
+	code, res := refreshToken(router, refresh)
+	if code != 200 || res["refreshToken"] == refresh {
+		t.Fatalf("Expected rotated refresh token, got %d %v", code, res)
+	}
+	newToken := res["token"].(string)
+	if code, _ := callApi(router, http.MethodGet, "/api/v1/ping", newToken, nil); code != 200 {
+		t.Fatalf("Expected refreshed token to work, got %d", code)
+	}
+
+	// 旧刷新令牌再次使用视为泄露，整个会话被吊销
+	if code, _ := refreshToken(router, refresh); code == 200 {
+		t.Fatalf("Expected reused refresh token to be rejected")
+	}
+	if code, _ := callApi(router, http.MethodGet, "/api/v1/ping", newToken, nil); code == 200 {
+		t.Errorf("Expected session to be revoked after refresh token reuse")
+	}
+	if code, _ := refreshToken(router, res["refreshToken"].(string)); code == 200 {
+		t.Errorf("Expected latest refresh token of revoked session to be rejected")
+	}
+}
+
+func TestSessionListAndRevocation(t *testing.T) {
+	router, mr, admin := setupSession(t)
+	first := passwordLogin(t, router, mr, "henry")
+	second := passwordLogin(t, router, mr, "henry")
+	firstToken, secondToken := first["token"].(string), second["token"].(string)
+
+	code, data := callApi(router, http.MethodGet, "/api/v1/admin/session/list", firstToken, nil)
+	var sessions []model.SysSessionVo
+	_ = json.Unmarshal(data, &sessions)
+	if code != 200 || len(sessions) != 2 {
+		t.Fatalf("Expected 2 sessions, got %d %s", code, data)
+	}
+	var current model.SysSessionVo
+	for _, s := range sessions {
+		if s.Current {
+			current = s
+		}
+	}
+	if current.SessionId == "" || current.IpAddress != "127.0.0.1" || current.Username != "henry" {
+		t.Errorf("Expected current session with client info, got %+v", sessions)
+	}
+
+	// 退出登录只影响当前会话
+	if code, _ := callApi(router, http.MethodPost, "/api/v1/admin/logout", firstToken, nil); code != 200 {
+		t.Fatalf("Logout failed: %d", code)
+	}
+	if code, _ := callApi(router, http.MethodGet, "/api/v1/ping", firstToken, nil); code == 200 {
+		t.Errorf("Expected logged out token to be rejected")
+	}
+	if code, _ := callApi(router, http.MethodGet, "/api/v1/ping", secondToken, nil); code != 200 {
+		t.Errorf("Expected other session to stay valid, got %d", code)
+	}
+
+	// 停用用户后令牌和刷新令牌立即失效
+	third := passwordLogin(t, router, mr, "henry")
+	if code, _ := callApi(router, http.MethodPut, "/api/v1/admin/updateStatus", third["token"].(string),
+		model.UpdateSysAdminStatusDto{Id: admin.ID, Status: 2}); code != 200 {
+		t.Fatalf("Disable user failed: %d", code)
+	}
+	if code, _ := callApi(router, http.MethodGet, "/api/v1/ping", secondToken, nil); code == 200 {
+		t.Errorf("Expected token of disabled user to be rejected")
+	}
+	if code, _ := refreshToken(router, second["refreshToken"].(string)); code == 200 {
+		t.Errorf("Expected refresh of disabled user to be rejected")
+	}
+}
+
+func TestForceLogout(t *testing.T) {
+	router, mr, admin := setupSession(t)
+	victim := passwordLogin(t, router, mr, "henry")
+	operator := passwordLogin(t, router, mr, "henry")
+
+	code, _ := callApi(router, http.MethodPost, "/api/v1/admin/session/forceLogout", operator["token"].(string),
+		model.ForceLogoutDto{AdminId: admin.ID})
+	if code != 200 {
+		t.Fatalf("Force logout failed: %d", code)
+	}
+	for _, token := range []string{victim["token"].(string), operator["token"].(string)} {
+		if code, _ := callApi(router, http.MethodGet, "/api/v1/ping", token, nil); code == 200 {
+			t.Errorf("Expected all sessions to be revoked")
+		}
+	}
+}
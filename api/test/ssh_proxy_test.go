@@ -0,0 +1,115 @@
+package test
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	cmdbcontroller "dodevops-api/api/cmdb/controller"
+	cmdbmodel "dodevops-api/api/cmdb/model"
+	cmdbservice "dodevops-api/api/cmdb/service"
+	configmodel "dodevops-api/api/configcenter/model"
+	taskservice "dodevops-api/api/task/service"
+	"dodevops-api/common/constant"
+	"dodevops-api/common/util"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestSSHProxyJumpChain(t *testing.T) {
+	database := setupSystemDB(t)
+	if err := database.AutoMigrate(&cmdbmodel.CmdbGroup{}, &cmdbmodel.CmdbHost{}, &configmodel.EcsAuth{}); err != nil {
+		t.Fatalf("Failed to auto migrate: %v", err)
+	}
+	cmdbservice.InitCmdbHostProxyResolver()
+	t.Cleanup(func() { util.SetSSHProxyResolver(nil) })
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.PUT("/api/v1/cmdb/groupupdate", cmdbcontroller.UpdateCmdbGroup)
+	router.PUT("/api/v1/cmdb/hostupdate", cmdbcontroller.NewCmdbHostController().UpdateCmdbHost)
+	router.DELETE("/api/v1/cmdb/hostdelete", cmdbcontroller.NewCmdbHostController().DeleteCmdbHost)
+
+	group := cmdbmodel.CmdbGroup{Name: "dmz", CreateTime: util.HTime{Time: time.Now()}}
+	database.Create(&group)
+	bastion := newFakeSSHHost(t, "bastion", false)
+	jump := newFakeSSHHost(t, "jump", false)
+	target := newFakeSSHHost(t, "pass", false)
+	bastionHost := addRotationHost(t, database, "bastion", 0, bastion, configmodel.EcsAuth{Name: "bastion", Type: 1, Username: "root", Password: "bastion", Port: 22})
+	jumpHost := addRotationHost(t, database, "jump", group.ID, jump, configmodel.EcsAuth{Name: "jump", Type: 1, Username: "root", Password: "jump", Port: 22})
+	targetHost := addRotationHost(t, database, "app-1", group.ID, target, configmodel.EcsAuth{Name: "app", Type: 1, Username: "root", Password: "pass", Port: 22})
+
+	// 分组配置跳板机后，分组内主机经过跳板机连接，跳板机自身直接连接
+	group.ProxyHostID = jumpHost.ID
+	if code, data := callApi(router, http.MethodPut, "/api/v1/cmdb/groupupdate", "", group); code != 200 {
+		t.Fatalf("Update group proxy failed: %d %s", code, data)
+	}
+	if out, err := util.SSHExec("127.0.0.1", target.port, "root", "pass", "echo ok"); err != nil || strings.TrimSpace(out) != "ok" {
+		t.Fatalf("Connection through jump host failed: %q %v", out, err)
+	}
+	if atomic.LoadInt32(&jump.forwarded) != 1 {
+		t.Fatalf("Expected connection forwarded by jump host, got %d", jump.forwarded)
+	}
+
+	// 跳板机自身配置跳板机时组成多级跳板
+	updateJump := cmdbmodel.UpdateCmdbHostDto{ID: jumpHost.ID, HostName: "jump", GroupID: group.ID, SSHIP: "127.0.0.1",
+		SSHName: "root", SSHKeyID: jumpHost.SSHKeyID, SSHPort: jump.port, ProxyHostID: bastionHost.ID}
+	if code, data := callApi(router, http.MethodPut, "/api/v1/cmdb/hostupdate", "", updateJump); code != 200 {
+		t.Fatalf("Update host proxy failed: %d %s", code, data)
+	}
+	if _, err := util.SSHExec("127.0.0.1", target.port, "root", "pass", "echo ok"); err != nil {
+		t.Fatalf("Connection through jump chain failed: %v", err)
+	}
+	if atomic.LoadInt32(&bastion.forwarded) != 1 || atomic.LoadInt32(&jump.forwarded) != 2 {
+		t.Fatalf("Expected connection forwarded by both jump hosts, got %d %d", bastion.forwarded, jump.forwarded)
+	}
+	proxyJump, err := cmdbservice.HostProxyJump(targetHost)
+	if expected := fmt.Sprintf("root@127.0.0.1:%d,root@127.0.0.1:%d", bastion.port, jump.port); err != nil || proxyJump != expected {
+		t.Fatalf("Expected proxy jump %s, got %s %v", expected, proxyJump, err)
+	}
+	inventory := (&taskservice.HostSSHInfoCollection{Groups: map[string][]taskservice.HostSSHInfo{
+		"app": {{IP: "127.0.0.1", Port: target.port, User: "root", AuthType: 3, ProxyJump: proxyJump}},
+	}}).GenerateInventory()
+	if !strings.Contains(inventory, "ansible_ssh_common_args='-o ProxyJump="+proxyJump+"'") {
+		t.Errorf("Unexpected inventory %q", inventory)
+	}
+
+	// 密码认证的跳板机无法用于 ansible，给出提示
+	dir := t.TempDir()
+	env, warnings, err := cmdbservice.PrepareKnownHosts([]uint{targetHost.ID}, dir)
+	if err != nil || len(warnings) != 2 || len(env) != 0 {
+		t.Fatalf("Unexpected ansible ssh result %v %v %v", env, warnings, err)
+	}
+
+	// 形成循环的跳板机配置被拒绝
+	updateJump.ProxyHostID = targetHost.ID
+	if code, data := callApi(router, http.MethodPut, "/api/v1/cmdb/hostupdate", "", updateJump); code == 200 {
+		t.Fatalf("Expected proxy cycle to be rejected, got %d %s", code, data)
+	}
+	var saved cmdbmodel.CmdbHost
+	database.First(&saved, jumpHost.ID)
+	if saved.ProxyHostID != bastionHost.ID {
+		t.Errorf("Expected proxy unchanged after rejected update, got %d", saved.ProxyHostID)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "ssh_config")); err == nil {
+		t.Errorf("Expected no ssh_config without key jump hosts")
+	}
+
+	// 仍被主机或分组作为跳板机使用的主机不能删除
+	for _, id := range []uint{bastionHost.ID, jumpHost.ID} {
+		if code, _ := callApi(router, http.MethodDelete, "/api/v1/cmdb/hostdelete", "", cmdbmodel.CmdbHostIdDto{ID: id}); code != constant.CMDB_HOST_IN_USE {
+			t.Fatalf("Expected delete of jump host %d rejected, got %d", id, code)
+		}
+	}
+	group.ProxyHostID = 0
+	if code, data := callApi(router, http.MethodPut, "/api/v1/cmdb/groupupdate", "", group); code != 200 {
+		t.Fatalf("Clear group proxy failed: %d %s", code, data)
+	}
+	if code, data := callApi(router, http.MethodDelete, "/api/v1/cmdb/hostdelete", "", cmdbmodel.CmdbHostIdDto{ID: jumpHost.ID}); code != 200 {
+		t.Fatalf("Delete unreferenced jump host failed: %d %s", code, data)
+	}
+}
@@ -0,0 +1,137 @@
+package test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"dodevops-api/api/system/controller"
+	"dodevops-api/api/system/dao"
+	"dodevops-api/api/system/model"
+	"dodevops-api/api/system/service"
+	"dodevops-api/common/config"
+	"dodevops-api/common/constant"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+)
+
+func readObserveMessage(t *testing.T, conn *websocket.Conn) model.SysTerminalObserveMessage {
+	t.Helper()
+	var message model.SysTerminalObserveMessage
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	if err := conn.ReadJSON(&message); err != nil {
+		t.Fatalf("Failed to read observe message: %v", err)
+	}
+	return message
+}
+
+func TestTerminalSessionObserveAndTerminate(t *testing.T) {
+	setupTerminalRecording(t, config.RecordingConfig{})
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Set(constant.ContextKeyUserObj, &model.JwtAdmin{ID: 9, Username: "security"})
+	})
+	router.GET("/api/v1/terminal/session/active", controller.GetActiveSysTerminalSessionList)
+	router.GET("/api/v1/terminal/session/observe/:id", controller.ObserveSysTerminalSession)
+	router.POST("/api/v1/terminal/session/terminate", controller.TerminateSysTerminalSession)
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	session, err := service.StartSysTerminalSession(newTerminalContext(&model.JwtAdmin{ID: 1, Username: "alice"}), model.SysTerminalSessionStartDto{
+		Kind: model.TerminalKindHost, TargetId: 1, TargetName: "web-01", Address: "10.0.0.1:22", Width: 160, Height: 40,
+	})
+	if err != nil {
+		t.Fatalf("Failed to start session: %v", err)
+	}
+	// 模拟终端控制器：终止时向用户输出提示并结束会话
+	shown := make(chan string, 1)
+	session.OnTerminate(func(message string) {
+		session.Output([]byte(message))
+		shown <- message
+		session.Close()
+	})
+	session.Output([]byte("[alice@web-01 ~]$ "))
+
+	code, data := callApi(router, http.MethodGet, "/api/v1/terminal/session/active", "", nil)
+	var active []model.SysTerminalSessionActiveVo
+	_ = json.Unmarshal(data, &active)
+	if code != 200 || len(active) != 1 || active[0].ID != session.ID() || active[0].Username != "alice" {
+		t.Fatalf("Unexpected active sessions: %s", data)
+	}
+
+	wsUrl := "ws" + strings.TrimPrefix(server.URL, "http") + "/api/v1/terminal/session/observe/" + strconv.Itoa(int(active[0].ID))
+	conn, _, err := websocket.DefaultDialer.Dial(wsUrl, nil)
+	if err != nil {
+		t.Fatalf("Failed to observe session: %v", err)
+	}
+	defer conn.Close()
+	if message := readObserveMessage(t, conn); message.Type != "session" || message.Session.TargetName != "web-01" {
+		t.Fatalf("Expected session info, got %+v", message)
+	}
+	if message := readObserveMessage(t, conn); message.Type != "o" || message.Data != "[alice@web-01 ~]$ " {
+		t.Fatalf("Expected recent output, got %+v", message)
+	}
+
+	// 观看者连接后实时推送输出和窗口大小变化
+	session.Output([]byte("uptime\r\n"))
+	session.Resize(120, 30)
+	if message := readObserveMessage(t, conn); message.Type != "o" || message.Data != "uptime\r\n" {
+		t.Errorf("Expected live output, got %+v", message)
+	}
+	if message := readObserveMessage(t, conn); message.Type != "r" || message.Data != "120x30" {
+		t.Errorf("Expected resize event, got %+v", message)
+	}
+	// 观看者只读，发送的内容不会进入终端
+	conn.WriteMessage(websocket.TextMessage, []byte("reboot\r"))
+
+	if code, _ := callApi(router, http.MethodPost, "/api/v1/terminal/session/terminate", "",
+		model.SysTerminalSessionTerminateDto{Id: session.ID(), Reason: " "}); code == 200 {
+		t.Errorf("Expected terminate without reason to be rejected")
+	}
+	if code, _ := callApi(router, http.MethodPost, "/api/v1/terminal/session/terminate", "",
+		model.SysTerminalSessionTerminateDto{Id: session.ID(), Reason: "未经审批访问生产环境"}); code != 200 {
+		t.Fatalf("Terminate session failed: %d", code)
+	}
+	select {
+	case message := <-shown:
+		if !strings.Contains(message, "security") || !strings.Contains(message, "未经审批访问生产环境") {
+			t.Errorf("Unexpected message shown to user: %q", message)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Expected terminate message shown to user")
+	}
+
+	// 观看者收到终止提示和会话结束消息
+	for {
+		message := readObserveMessage(t, conn)
+		if message.Type == "closed" {
+			if !strings.Contains(message.Data, "未经审批访问生产环境") {
+				t.Errorf("Unexpected closed message: %+v", message)
+			}
+			break
+		}
+	}
+
+	stored, _ := dao.GetSysTerminalSessionById(session.ID())
+	if stored.Status != model.TerminalStatusTerminated || stored.TerminatedBy != "security" || stored.Reason != "未经审批访问生产环境" {
+		t.Errorf("Unexpected stored session: %+v", stored)
+	}
+	for _, command := range dao.GetSysTerminalCommandList(session.ID()) {
+		if command.Command == "reboot" {
+			t.Errorf("Observer input should not reach the terminal")
+		}
+	}
+	_, data = callApi(router, http.MethodGet, "/api/v1/terminal/session/active", "", nil)
+	if string(data) != "[]" {
+		t.Errorf("Expected no active sessions, got %s", data)
+	}
+	if code, _ := callApi(router, http.MethodPost, "/api/v1/terminal/session/terminate", "",
+		model.SysTerminalSessionTerminateDto{Id: session.ID(), Reason: "重复终止"}); code == 200 {
+		t.Errorf("Expected terminating finished session to fail")
+	}
+}
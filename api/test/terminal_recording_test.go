@@ -0,0 +1,192 @@
+package test
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"dodevops-api/api/system/controller"
+	"dodevops-api/api/system/model"
+	"dodevops-api/api/system/service"
+	"dodevops-api/common/config"
+	"dodevops-api/common/constant"
+	"dodevops-api/pkg/recording"
+
+	"github.com/gin-gonic/gin"
+)
+
+func setupTerminalRecording(t *testing.T, cfg config.RecordingConfig) *gin.Engine {
+	database := setupSystemDB(t)
+	if err := database.AutoMigrate(&model.SysTerminalSession{}, &model.SysTerminalCommand{}); err != nil {
+		t.Fatalf("Failed to auto migrate: %v", err)
+	}
+	cfg.Dir = t.TempDir()
+	if err := recording.Setup(cfg); err != nil {
+		t.Fatalf("Failed to setup recording: %v", err)
+	}
+	t.Cleanup(func() { recording.Setup(config.RecordingConfig{Dir: t.TempDir()}) })
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/api/v1/terminal/session/list", controller.GetSysTerminalSessionList)
+	router.GET("/api/v1/terminal/session/info", controller.GetSysTerminalSessionInfo)
+	router.GET("/api/v1/terminal/session/play", controller.PlaySysTerminalSession)
+	router.GET("/api/v1/terminal/command/search", controller.SearchSysTerminalCommand)
+	return router
+}
+
+// 模拟一次终端会话：逐字输入带回显的命令、粘贴命令、输入无回显的密码
+func recordTerminalSession(t *testing.T) *service.TerminalSession {
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest(http.MethodGet, "/api/v1/cmdb/hostssh/connect/1", nil)
+	c.Set(constant.ContextKeyUserObj, &model.JwtAdmin{ID: 1, Username: "admin"})
+	session, err := service.StartSysTerminalSession(c, model.SysTerminalSessionStartDto{
+		Kind: model.TerminalKindHost, TargetId: 1, TargetName: "web-01", Address: "10.0.0.1:22", Width: 160, Height: 40,
+	})
+	if err != nil {
+		t.Fatalf("Failed to start session: %v", err)
+	}
+	session.Output([]byte("[root@web-01 ~]# "))
+	for _, key := range []string{"l", "s", " ", "-", "l"} {
+		session.Input([]byte(key))
+		session.Output([]byte(key))
+	}
+	session.Input([]byte("\r"))
+	session.Output([]byte("\r\ntotal 0\r\n[root@web-01 ~]# "))
+
+	// 退格修改后的命令
+	for _, key := range []string{"w", "h", "o", "a", "m", "x", "\x7f", "i"} {
+		session.Input([]byte(key))
+		if key == "\x7f" {
+			session.Output([]byte("\b \b"))
+		} else {
+			session.Output([]byte(key))
+		}
+	}
+	session.Input([]byte("\r"))
+	session.Output([]byte("\r\nroot\r\n[root@web-01 ~]# "))
+
+	// 粘贴的命令，回显在输入之后到达
+	session.Input([]byte("uptime\r"))
+	session.Output([]byte("uptime\r\n 10:00:00 up 1 day\r\n[root@web-01 ~]# "))
+
+	// 无回显的密码不作为命令记录
+	session.Output([]byte("[sudo] password for root: "))
+	session.Input([]byte("S3cret!\r"))
+	session.Output([]byte("\r\n[root@web-01 ~]# 中文"))
+	session.Resize(120, 30)
+	session.Close()
+	return session
+}
+
+func TestTerminalRecordingLocal(t *testing.T) {
+	router := setupTerminalRecording(t, config.RecordingConfig{})
+	session := recordTerminalSession(t)
+
+	code, data := callApi(router, http.MethodGet, "/api/v1/terminal/session/info?id=1", "", nil)
+	if code != 200 {
+		t.Fatalf("Get session info failed: %d", code)
+	}
+	var info model.SysTerminalSessionInfoVo
+	_ = json.Unmarshal(data, &info)
+	if info.ID != session.ID() || info.Status != model.TerminalStatusFinished || info.Backend != recording.BackendLocal || info.Username != "admin" {
+		t.Fatalf("Unexpected session: %+v", info.SysTerminalSession)
+	}
+	var commands []string
+	for _, command := range info.Commands {
+		commands = append(commands, command.Command)
+	}
+	if strings.Join(commands, "|") != "ls -l|whoami|uptime" || info.CommandCount != 3 {
+		t.Errorf("Unexpected commands: %v", commands)
+	}
+
+	// 回放内容为 asciicast v2 格式
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/terminal/session/play?id=1", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	lines := strings.Split(strings.TrimSpace(w.Body.String()), "\n")
+	var header recording.Header
+	if err := json.Unmarshal([]byte(lines[0]), &header); err != nil || header.Version != 2 || header.Width != 160 {
+		t.Fatalf("Unexpected cast header: %s", lines[0])
+	}
+	if !strings.Contains(w.Body.String(), "中文") || !strings.Contains(lines[len(lines)-1], `"r","120x30"`) {
+		t.Errorf("Unexpected cast events: %s", w.Body.String())
+	}
+	if strings.Contains(w.Body.String(), "S3cret!") {
+		t.Errorf("Input keystrokes should not be recorded")
+	}
+
+	// 按命令检索会话
+	code, data = callApi(router, http.MethodGet, "/api/v1/terminal/command/search?keyword=uptime", "", nil)
+	var page struct {
+		List  []model.SysTerminalCommandVo `json:"list"`
+		Total int64                        `json:"total"`
+	}
+	_ = json.Unmarshal(data, &page)
+	if code != 200 || page.Total != 1 || page.List[0].TargetName != "web-01" || page.List[0].Offset <= 0 {
+		t.Errorf("Unexpected search result: %s", data)
+	}
+	_, data = callApi(router, http.MethodGet, "/api/v1/terminal/session/list?command=whoami", "", nil)
+	_ = json.Unmarshal(data, &page)
+	if page.Total != 1 {
+		t.Errorf("Expected session filtered by command, got %s", data)
+	}
+	_, data = callApi(router, http.MethodGet, "/api/v1/terminal/session/list?command=S3cret", "", nil)
+	_ = json.Unmarshal(data, &page)
+	if page.Total != 0 {
+		t.Errorf("Password should not be searchable, got %s", data)
+	}
+}
+
+func TestTerminalRecordingS3(t *testing.T) {
+	var mu sync.Mutex
+	objects := map[string][]byte{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=AKIDTEST/") {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		switch r.Method {
+		case http.MethodPut:
+			objects[r.URL.Path], _ = io.ReadAll(r.Body)
+		case http.MethodGet:
+			body, ok := objects[r.URL.Path]
+			if !ok {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			w.Write(body)
+		}
+	}))
+	defer server.Close()
+
+	router := setupTerminalRecording(t, config.RecordingConfig{Backend: recording.BackendS3, S3: config.S3Config{
+		Endpoint: server.URL, Bucket: "audit", AccessKey: "AKIDTEST", SecretKey: "secret", PathStyle: true,
+	}})
+	recordTerminalSession(t)
+
+	code, data := callApi(router, http.MethodGet, "/api/v1/terminal/session/info?id=1", "", nil)
+	var info model.SysTerminalSessionInfoVo
+	_ = json.Unmarshal(data, &info)
+	if code != 200 || info.Backend != recording.BackendS3 || len(objects) != 1 {
+		t.Fatalf("Expected recording uploaded to s3, got %+v, objects %d", info.SysTerminalSession, len(objects))
+	}
+	for path := range objects {
+		if !strings.HasPrefix(path, "/audit/recordings/") || !strings.HasSuffix(path, info.SessionId+".cast") {
+			t.Errorf("Unexpected object path: %s", path)
+		}
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/terminal/session/play?id=1", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if !strings.HasPrefix(w.Body.String(), `{"version":2`) {
+		t.Errorf("Expected cast read back from s3, got %s", w.Body.String())
+	}
+}